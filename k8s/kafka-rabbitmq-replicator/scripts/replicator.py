@@ -437,16 +437,31 @@ class KafkaRabbitMQReplicator:
             else:
                 body = value or b''
             
+            # Forward the Kafka headers (content-type, schema_version) so consumers
+            # know how the event is encoded, and traceparent/tracestate so they
+            # continue its trace
+            headers = {}
+            content_type = None
+            for header_key, header_value in message.headers or []:
+                if isinstance(header_value, bytes):
+                    header_value = header_value.decode('utf-8', errors='replace')
+                if header_key == 'content-type':
+                    content_type = header_value
+                else:
+                    headers[header_key] = header_value
+            headers.update({
+                'kafka_topic': message.topic,
+                'kafka_partition': message.partition,
+                'kafka_offset': message.offset,
+                'kafka_key': key,
+                'replicator_id': message_id
+            })
+            
             # Prepare properties
             properties = pika.BasicProperties(
                 delivery_mode=2,  # Make message persistent
-                headers={
-                    'kafka_topic': message.topic,
-                    'kafka_partition': message.partition,
-                    'kafka_offset': message.offset,
-                    'kafka_key': key,
-                    'replicator_id': message_id
-                }
+                content_type=content_type,
+                headers=headers
             )
             
             # Publish message
@@ -556,16 +571,28 @@ class KafkaRabbitMQReplicator:
             # Send to Kafka
             kafka_topic = mapping['kafkaTopic']
             
+            headers = [
+                ('rabbitmq_queue', queue_name.encode('utf-8') if queue_name else b''),
+                ('rabbitmq_exchange', (method.exchange or '').encode('utf-8')),
+                ('rabbitmq_routing_key', (method.routing_key or '').encode('utf-8')),
+                ('replicator_id', message_id.encode('utf-8'))
+            ]
+            
+            # Forward the content type and the string headers (schema_version,
+            # traceparent, tracestate) so consumers know how the event is encoded
+            # and continue its trace
+            if properties and properties.content_type:
+                headers.append(('content-type', properties.content_type.encode('utf-8')))
+            if properties and properties.headers:
+                for header_key, header_value in properties.headers.items():
+                    if header_key != 'kafka_key' and isinstance(header_value, (str, bytes)):
+                        headers.append((header_key, header_value if isinstance(header_value, bytes) else header_value.encode('utf-8')))
+            
             future = self.kafka_producer.send(
                 kafka_topic,
                 key=key,
                 value=value,
-                headers=[
-                    ('rabbitmq_queue', queue_name.encode('utf-8') if queue_name else b''),
-                    ('rabbitmq_exchange', (method.exchange or '').encode('utf-8')),
-                    ('rabbitmq_routing_key', (method.routing_key or '').encode('utf-8')),
-                    ('replicator_id', message_id.encode('utf-8'))
-                ]
+                headers=headers
             )
             
             # Wait for completion
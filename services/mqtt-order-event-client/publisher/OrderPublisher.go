@@ -2,7 +2,6 @@ package publisher
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
 	"strings"
@@ -35,6 +34,7 @@ type DamageDetails struct {
 // Publisher wraps a Kafka writer.
 type Publisher struct {
 	writer *kafka.Writer
+	codec  EventCodec
 	Topic  string
 }
 
@@ -44,12 +44,17 @@ type Publisher struct {
 // - KAFKA_TOPIC (default: order-status-events)
 // - KAFKA_SASL_ENABLE (true/false, default: false)
 // - KAFKA_USERNAME, KAFKA_PASSWORD (when SASL enabled)
+// - KAFKA_EVENT_ENCODING (protobuf/json, default: protobuf)
 func NewPublisherFromEnv() (*Publisher, error) {
 	brokers := getEnv("KAFKA_BROKERS", "kafka:9092")
 	topic := getEnv("KAFKA_TOPIC", "order-status-events")
 	saslEnable := strings.ToLower(getEnv("KAFKA_SASL_ENABLE", "false")) == "true"
 	username := getEnv("KAFKA_USERNAME", "")
 	password := getEnv("KAFKA_PASSWORD", "")
+	codec, err := codecFromEncoding(getEnv("KAFKA_EVENT_ENCODING", "protobuf"))
+	if err != nil {
+		return nil, err
+	}
 
 	var transport kafka.RoundTripper
 	if saslEnable && username != "" {
@@ -66,7 +71,7 @@ func NewPublisherFromEnv() (*Publisher, error) {
 		Transport:    transport,
 	}
 
-	return &Publisher{writer: w, Topic: topic}, nil
+	return &Publisher{writer: w, codec: codec, Topic: topic}, nil
 }
 
 // Close closes the underlying Kafka writer.
@@ -100,7 +105,7 @@ func (p *Publisher) PublishOrderDamageFromSensor(ctx context.Context, sensorID,
 		},
 	}
 
-	payload, err := json.Marshal(evt)
+	payload, err := p.codec.EncodeOrderDamageEvent(evt)
 	if err != nil {
 		return err
 	}
@@ -108,6 +113,10 @@ func (p *Publisher) PublishOrderDamageFromSensor(ctx context.Context, sensorID,
 	return p.writer.WriteMessages(ctx, kafka.Message{
 		Key:   []byte(evt.OrderID),
 		Value: payload,
+		Headers: []kafka.Header{
+			{Key: "content-type", Value: []byte(p.codec.ContentType())},
+			{Key: SchemaVersionHeader, Value: []byte(EventSchemaVersion)},
+		},
 	})
 }
 
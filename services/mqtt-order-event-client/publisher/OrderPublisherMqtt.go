@@ -2,7 +2,6 @@ package publisher
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"time"
 
@@ -58,6 +57,8 @@ func NewMqttPublisherFromEnv() (*MqttPublisher, error) {
 }
 
 // PublishOrderDamageFromSensor builds an OrderDamageEvent and publishes it as JSON to MQTT.
+// MQTT 3.1.1 messages have no headers to carry a content type or schema version, so
+// consumers read them as JSON of the current schema version.
 func (p *MqttPublisher) PublishOrderDamageFromSensor(ctx context.Context, sensorID, source string, temperature, humidity float64, status, mqttTopic string) error {
 	if p == nil || p.client == nil {
 		return nil
@@ -80,7 +81,7 @@ func (p *MqttPublisher) PublishOrderDamageFromSensor(ctx context.Context, sensor
 		},
 	}
 
-	payload, err := json.Marshal(evt)
+	payload, err := JSONCodec{}.EncodeOrderDamageEvent(evt)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,70 @@
+package publisher
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"mqtt-order-event-client/publisher/eventspb"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	// EventSchemaVersion is the version of the event schemas (services/schemas) the
+	// published events follow
+	EventSchemaVersion = "1"
+
+	// SchemaVersionHeader carries the schema version of an event in the message headers
+	SchemaVersionHeader = "schema_version"
+)
+
+// EventCodec encodes OrderDamageEvent in one wire format of the event schemas.
+// ContentType identifies the format in the content-type header of a message.
+type EventCodec interface {
+	ContentType() string
+	EncodeOrderDamageEvent(evt OrderDamageEvent) ([]byte, error)
+}
+
+// JSONCodec encodes events as JSON with the field names of the event schemas.
+type JSONCodec struct{}
+
+func (JSONCodec) ContentType() string { return "application/json" }
+
+func (JSONCodec) EncodeOrderDamageEvent(evt OrderDamageEvent) ([]byte, error) {
+	return json.Marshal(evt)
+}
+
+// ProtobufCodec encodes events as medisupply.events.v1 Protobuf messages.
+type ProtobufCodec struct{}
+
+func (ProtobufCodec) ContentType() string { return "application/x-protobuf" }
+
+func (ProtobufCodec) EncodeOrderDamageEvent(evt OrderDamageEvent) ([]byte, error) {
+	return proto.Marshal(&eventspb.OrderDamageEvent{
+		EventId:     evt.EventID,
+		Type:        evt.Type,
+		Source:      evt.Source,
+		OccurredAt:  timestamppb.New(evt.OccurredAt),
+		OrderId:     evt.OrderID,
+		Severity:    evt.Severity,
+		Description: evt.Description,
+		Details: &eventspb.OrderDamageDetails{
+			Temperature: evt.Details.Temperature,
+			Humidity:    evt.Details.Humidity,
+			Status:      evt.Details.Status,
+			MqttTopic:   evt.Details.MqttTopic,
+		},
+	})
+}
+
+// codecFromEncoding returns the codec of a configured encoding: protobuf or json.
+func codecFromEncoding(encoding string) (EventCodec, error) {
+	switch encoding {
+	case "protobuf":
+		return ProtobufCodec{}, nil
+	case "json":
+		return JSONCodec{}, nil
+	}
+	return nil, fmt.Errorf("unknown event encoding %q (expected protobuf or json)", encoding)
+}
@@ -0,0 +1,5 @@
+// Package eventspb holds the Go types generated from the event schemas in
+// services/schemas/proto. Run go generate in this directory after changing a schema.
+package eventspb
+
+//go:generate protoc --proto_path=../../../schemas/proto --go_out=. --go_opt=module=mqtt-order-event-client/publisher/eventspb --go_opt=Mmedisupply/events/v1/order_event.proto=mqtt-order-event-client/publisher/eventspb medisupply/events/v1/order_event.proto
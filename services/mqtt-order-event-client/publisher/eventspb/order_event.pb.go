@@ -0,0 +1,479 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        (unknown)
+// source: medisupply/events/v1/order_event.proto
+
+// Events about orders, published by the order service and by the sensor clients that
+// detect damaged orders.
+
+package eventspb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Order is the state of an order carried by an order event
+type Order struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	CustomerId    string                 `protobuf:"bytes,2,opt,name=customer_id,json=customerId,proto3" json:"customer_id,omitempty"`
+	ProductId     string                 `protobuf:"bytes,3,opt,name=product_id,json=productId,proto3" json:"product_id,omitempty"`
+	Quantity      int64                  `protobuf:"varint,4,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	Status        string                 `protobuf:"bytes,5,opt,name=status,proto3" json:"status,omitempty"`
+	TotalAmount   float64                `protobuf:"fixed64,6,opt,name=total_amount,json=totalAmount,proto3" json:"total_amount,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt     *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Order) Reset() {
+	*x = Order{}
+	mi := &file_medisupply_events_v1_order_event_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Order) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Order) ProtoMessage() {}
+
+func (x *Order) ProtoReflect() protoreflect.Message {
+	mi := &file_medisupply_events_v1_order_event_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Order.ProtoReflect.Descriptor instead.
+func (*Order) Descriptor() ([]byte, []int) {
+	return file_medisupply_events_v1_order_event_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Order) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Order) GetCustomerId() string {
+	if x != nil {
+		return x.CustomerId
+	}
+	return ""
+}
+
+func (x *Order) GetProductId() string {
+	if x != nil {
+		return x.ProductId
+	}
+	return ""
+}
+
+func (x *Order) GetQuantity() int64 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+func (x *Order) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Order) GetTotalAmount() float64 {
+	if x != nil {
+		return x.TotalAmount
+	}
+	return 0
+}
+
+func (x *Order) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Order) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+// OrderEvent reports a change of an order, e.g. order.created or order.shipped
+type OrderEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Identifies the event for duplicate detection
+	EventId       string                 `protobuf:"bytes,1,opt,name=event_id,json=eventId,proto3" json:"event_id,omitempty"`
+	EventType     string                 `protobuf:"bytes,2,opt,name=event_type,json=eventType,proto3" json:"event_type,omitempty"`
+	OrderId       string                 `protobuf:"bytes,3,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	Order         *Order                 `protobuf:"bytes,4,opt,name=order,proto3" json:"order,omitempty"`
+	Timestamp     *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OrderEvent) Reset() {
+	*x = OrderEvent{}
+	mi := &file_medisupply_events_v1_order_event_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OrderEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OrderEvent) ProtoMessage() {}
+
+func (x *OrderEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_medisupply_events_v1_order_event_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OrderEvent.ProtoReflect.Descriptor instead.
+func (*OrderEvent) Descriptor() ([]byte, []int) {
+	return file_medisupply_events_v1_order_event_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *OrderEvent) GetEventId() string {
+	if x != nil {
+		return x.EventId
+	}
+	return ""
+}
+
+func (x *OrderEvent) GetEventType() string {
+	if x != nil {
+		return x.EventType
+	}
+	return ""
+}
+
+func (x *OrderEvent) GetOrderId() string {
+	if x != nil {
+		return x.OrderId
+	}
+	return ""
+}
+
+func (x *OrderEvent) GetOrder() *Order {
+	if x != nil {
+		return x.Order
+	}
+	return nil
+}
+
+func (x *OrderEvent) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+// OrderDamageEvent reports that the sensor data of an order indicates damage
+type OrderDamageEvent struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	EventId    string                 `protobuf:"bytes,1,opt,name=event_id,json=eventId,proto3" json:"event_id,omitempty"`
+	Type       string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Source     string                 `protobuf:"bytes,3,opt,name=source,proto3" json:"source,omitempty"`
+	OccurredAt *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=occurred_at,json=occurredAt,proto3" json:"occurred_at,omitempty"`
+	OrderId    string                 `protobuf:"bytes,5,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	// minor, major or critical
+	Severity      string              `protobuf:"bytes,6,opt,name=severity,proto3" json:"severity,omitempty"`
+	Description   string              `protobuf:"bytes,7,opt,name=description,proto3" json:"description,omitempty"`
+	Details       *OrderDamageDetails `protobuf:"bytes,8,opt,name=details,proto3" json:"details,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OrderDamageEvent) Reset() {
+	*x = OrderDamageEvent{}
+	mi := &file_medisupply_events_v1_order_event_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OrderDamageEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OrderDamageEvent) ProtoMessage() {}
+
+func (x *OrderDamageEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_medisupply_events_v1_order_event_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OrderDamageEvent.ProtoReflect.Descriptor instead.
+func (*OrderDamageEvent) Descriptor() ([]byte, []int) {
+	return file_medisupply_events_v1_order_event_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *OrderDamageEvent) GetEventId() string {
+	if x != nil {
+		return x.EventId
+	}
+	return ""
+}
+
+func (x *OrderDamageEvent) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *OrderDamageEvent) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *OrderDamageEvent) GetOccurredAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.OccurredAt
+	}
+	return nil
+}
+
+func (x *OrderDamageEvent) GetOrderId() string {
+	if x != nil {
+		return x.OrderId
+	}
+	return ""
+}
+
+func (x *OrderDamageEvent) GetSeverity() string {
+	if x != nil {
+		return x.Severity
+	}
+	return ""
+}
+
+func (x *OrderDamageEvent) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *OrderDamageEvent) GetDetails() *OrderDamageDetails {
+	if x != nil {
+		return x.Details
+	}
+	return nil
+}
+
+// OrderDamageDetails contains the sensor data that triggered a damage event
+type OrderDamageDetails struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Degrees Celsius
+	Temperature float64 `protobuf:"fixed64,1,opt,name=temperature,proto3" json:"temperature,omitempty"`
+	// Relative humidity in percent
+	Humidity      float64 `protobuf:"fixed64,2,opt,name=humidity,proto3" json:"humidity,omitempty"`
+	Status        string  `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	MqttTopic     string  `protobuf:"bytes,4,opt,name=mqtt_topic,json=mqttTopic,proto3" json:"mqtt_topic,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OrderDamageDetails) Reset() {
+	*x = OrderDamageDetails{}
+	mi := &file_medisupply_events_v1_order_event_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OrderDamageDetails) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OrderDamageDetails) ProtoMessage() {}
+
+func (x *OrderDamageDetails) ProtoReflect() protoreflect.Message {
+	mi := &file_medisupply_events_v1_order_event_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OrderDamageDetails.ProtoReflect.Descriptor instead.
+func (*OrderDamageDetails) Descriptor() ([]byte, []int) {
+	return file_medisupply_events_v1_order_event_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *OrderDamageDetails) GetTemperature() float64 {
+	if x != nil {
+		return x.Temperature
+	}
+	return 0
+}
+
+func (x *OrderDamageDetails) GetHumidity() float64 {
+	if x != nil {
+		return x.Humidity
+	}
+	return 0
+}
+
+func (x *OrderDamageDetails) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *OrderDamageDetails) GetMqttTopic() string {
+	if x != nil {
+		return x.MqttTopic
+	}
+	return ""
+}
+
+var File_medisupply_events_v1_order_event_proto protoreflect.FileDescriptor
+
+const file_medisupply_events_v1_order_event_proto_rawDesc = "" +
+	"\n" +
+	"&medisupply/events/v1/order_event.proto\x12\x14medisupply.events.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xa4\x02\n" +
+	"\x05Order\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1f\n" +
+	"\vcustomer_id\x18\x02 \x01(\tR\n" +
+	"customerId\x12\x1d\n" +
+	"\n" +
+	"product_id\x18\x03 \x01(\tR\tproductId\x12\x1a\n" +
+	"\bquantity\x18\x04 \x01(\x03R\bquantity\x12\x16\n" +
+	"\x06status\x18\x05 \x01(\tR\x06status\x12!\n" +
+	"\ftotal_amount\x18\x06 \x01(\x01R\vtotalAmount\x129\n" +
+	"\n" +
+	"created_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\"\xce\x01\n" +
+	"\n" +
+	"OrderEvent\x12\x19\n" +
+	"\bevent_id\x18\x01 \x01(\tR\aeventId\x12\x1d\n" +
+	"\n" +
+	"event_type\x18\x02 \x01(\tR\teventType\x12\x19\n" +
+	"\border_id\x18\x03 \x01(\tR\aorderId\x121\n" +
+	"\x05order\x18\x04 \x01(\v2\x1b.medisupply.events.v1.OrderR\x05order\x128\n" +
+	"\ttimestamp\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\"\xb3\x02\n" +
+	"\x10OrderDamageEvent\x12\x19\n" +
+	"\bevent_id\x18\x01 \x01(\tR\aeventId\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x16\n" +
+	"\x06source\x18\x03 \x01(\tR\x06source\x12;\n" +
+	"\voccurred_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"occurredAt\x12\x19\n" +
+	"\border_id\x18\x05 \x01(\tR\aorderId\x12\x1a\n" +
+	"\bseverity\x18\x06 \x01(\tR\bseverity\x12 \n" +
+	"\vdescription\x18\a \x01(\tR\vdescription\x12B\n" +
+	"\adetails\x18\b \x01(\v2(.medisupply.events.v1.OrderDamageDetailsR\adetails\"\x89\x01\n" +
+	"\x12OrderDamageDetails\x12 \n" +
+	"\vtemperature\x18\x01 \x01(\x01R\vtemperature\x12\x1a\n" +
+	"\bhumidity\x18\x02 \x01(\x01R\bhumidity\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\tR\x06status\x12\x1d\n" +
+	"\n" +
+	"mqtt_topic\x18\x04 \x01(\tR\tmqttTopicb\x06proto3"
+
+var (
+	file_medisupply_events_v1_order_event_proto_rawDescOnce sync.Once
+	file_medisupply_events_v1_order_event_proto_rawDescData []byte
+)
+
+func file_medisupply_events_v1_order_event_proto_rawDescGZIP() []byte {
+	file_medisupply_events_v1_order_event_proto_rawDescOnce.Do(func() {
+		file_medisupply_events_v1_order_event_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_medisupply_events_v1_order_event_proto_rawDesc), len(file_medisupply_events_v1_order_event_proto_rawDesc)))
+	})
+	return file_medisupply_events_v1_order_event_proto_rawDescData
+}
+
+var file_medisupply_events_v1_order_event_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_medisupply_events_v1_order_event_proto_goTypes = []any{
+	(*Order)(nil),                 // 0: medisupply.events.v1.Order
+	(*OrderEvent)(nil),            // 1: medisupply.events.v1.OrderEvent
+	(*OrderDamageEvent)(nil),      // 2: medisupply.events.v1.OrderDamageEvent
+	(*OrderDamageDetails)(nil),    // 3: medisupply.events.v1.OrderDamageDetails
+	(*timestamppb.Timestamp)(nil), // 4: google.protobuf.Timestamp
+}
+var file_medisupply_events_v1_order_event_proto_depIdxs = []int32{
+	4, // 0: medisupply.events.v1.Order.created_at:type_name -> google.protobuf.Timestamp
+	4, // 1: medisupply.events.v1.Order.updated_at:type_name -> google.protobuf.Timestamp
+	0, // 2: medisupply.events.v1.OrderEvent.order:type_name -> medisupply.events.v1.Order
+	4, // 3: medisupply.events.v1.OrderEvent.timestamp:type_name -> google.protobuf.Timestamp
+	4, // 4: medisupply.events.v1.OrderDamageEvent.occurred_at:type_name -> google.protobuf.Timestamp
+	3, // 5: medisupply.events.v1.OrderDamageEvent.details:type_name -> medisupply.events.v1.OrderDamageDetails
+	6, // [6:6] is the sub-list for method output_type
+	6, // [6:6] is the sub-list for method input_type
+	6, // [6:6] is the sub-list for extension type_name
+	6, // [6:6] is the sub-list for extension extendee
+	0, // [0:6] is the sub-list for field type_name
+}
+
+func init() { file_medisupply_events_v1_order_event_proto_init() }
+func file_medisupply_events_v1_order_event_proto_init() {
+	if File_medisupply_events_v1_order_event_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_medisupply_events_v1_order_event_proto_rawDesc), len(file_medisupply_events_v1_order_event_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   4,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_medisupply_events_v1_order_event_proto_goTypes,
+		DependencyIndexes: file_medisupply_events_v1_order_event_proto_depIdxs,
+		MessageInfos:      file_medisupply_events_v1_order_event_proto_msgTypes,
+	}.Build()
+	File_medisupply_events_v1_order_event_proto = out.File
+	file_medisupply_events_v1_order_event_proto_goTypes = nil
+	file_medisupply_events_v1_order_event_proto_depIdxs = nil
+}
@@ -44,7 +44,7 @@ func main() {
 
 	fmt.Printf("\n=== Sensor Details ===\n")
 	fmt.Printf("Temperature: %.2f°C\n", damageEvent.Details.Temperature)
-	fmt.Printf("Humidity: %d%%\n", damageEvent.Details.Humidity)
+	fmt.Printf("Humidity: %.2f%%\n", damageEvent.Details.Humidity)
 	fmt.Printf("Status: %s\n", damageEvent.Details.Status)
 	fmt.Printf("MQTT Topic: %s\n", damageEvent.Details.MqttTopic)
 
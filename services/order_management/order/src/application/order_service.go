@@ -13,18 +13,62 @@ import (
 type OrderService struct {
 	orderRepo      domain.OrderRepository
 	eventPublisher domain.OrderEventPublisher
+
+	// tracer records a span for every operation, continuing the trace of traceContext
+	tracer       domain.Tracer
+	traceContext domain.TraceContext
+}
+
+// orderServiceOptions holds the optional collaborators of an OrderService
+type orderServiceOptions struct {
+	tracer domain.Tracer
+}
+
+// OrderServiceOption configures an OrderService
+type OrderServiceOption func(*orderServiceOptions)
+
+// WithTracer records a span for every operation of the service
+func WithTracer(tracer domain.Tracer) OrderServiceOption {
+	return func(o *orderServiceOptions) {
+		o.tracer = tracer
+	}
 }
 
 // NewOrderService creates a new OrderService
-func NewOrderService(orderRepo domain.OrderRepository, eventPublisher domain.OrderEventPublisher) *OrderService {
+func NewOrderService(orderRepo domain.OrderRepository, eventPublisher domain.OrderEventPublisher, opts ...OrderServiceOption) *OrderService {
+	options := orderServiceOptions{tracer: domain.NoopTracer{}}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	return &OrderService{
 		orderRepo:      orderRepo,
 		eventPublisher: eventPublisher,
+		tracer:         options.tracer,
 	}
 }
 
+// TracedFrom returns a service whose operations continue the trace of the given trace
+// context, such as the one of the request they serve
+func (s *OrderService) TracedFrom(parent domain.TraceContext) *OrderService {
+	traced := *s
+	traced.traceContext = parent
+	return &traced
+}
+
+// trace starts the span of an operation in the trace of the service. It returns the
+// service running the operation within the span, so the events it publishes carry its
+// trace, and the function that ends the span.
+func (s *OrderService) trace(name string, attributes map[string]string) (*OrderService, func(error)) {
+	traceContext, end := s.tracer.StartSpan(s.traceContext, "OrderService."+name, attributes)
+	return s.TracedFrom(traceContext), end
+}
+
 // CreateOrder creates a new order and publishes an event
-func (s *OrderService) CreateOrder(customerID, productID string, quantity int, totalAmount float64) (*domain.Order, error) {
+func (s *OrderService) CreateOrder(customerID, productID string, quantity int, totalAmount float64) (_ *domain.Order, err error) {
+	s, end := s.trace("CreateOrder", map[string]string{"customer.id": customerID, "product.id": productID})
+	defer func() { end(err) }()
+
 	// Create new order
 	order := domain.Order{
 		ID:          uuid.New().String(),
@@ -48,6 +92,8 @@ func (s *OrderService) CreateOrder(customerID, productID string, quantity int, t
 		OrderID:   order.ID,
 		Order:     order,
 		Timestamp: time.Now(),
+
+		TraceContext: s.traceContext,
 	}
 
 	if err := s.eventPublisher.PublishOrderEvent(event); err != nil {
@@ -72,7 +118,10 @@ func (s *OrderService) GetAllOrders() ([]domain.Order, error) {
 }
 
 // UpdateOrderStatus updates the status of an order
-func (s *OrderService) UpdateOrderStatus(id, status string) (*domain.Order, error) {
+func (s *OrderService) UpdateOrderStatus(id, status string) (_ *domain.Order, err error) {
+	s, end := s.trace("UpdateOrderStatus", map[string]string{"order.id": id, "order.status": status})
+	defer func() { end(err) }()
+
 	order, err := s.orderRepo.FindByID(id)
 	if err != nil {
 		return nil, fmt.Errorf("order not found: %w", err)
@@ -91,6 +140,8 @@ func (s *OrderService) UpdateOrderStatus(id, status string) (*domain.Order, erro
 		OrderID:   order.ID,
 		Order:     *order,
 		Timestamp: time.Now(),
+
+		TraceContext: s.traceContext,
 	}
 
 	if err := s.eventPublisher.PublishOrderEvent(event); err != nil {
@@ -102,8 +153,12 @@ func (s *OrderService) UpdateOrderStatus(id, status string) (*domain.Order, erro
 	return order, nil
 }
 
-// HandleOrderEvent processes incoming order events
+// HandleOrderEvent processes incoming order events in the trace of the event
 func (s *OrderService) HandleOrderEvent(event domain.OrderEvent) error {
+	_, end := s.TracedFrom(event.TraceContext).trace("HandleOrderEvent",
+		map[string]string{"order.id": event.OrderID, "order.event_type": event.EventType})
+	defer end(nil)
+
 	log.Printf("Processing order event: Type=%s, OrderID=%s, Timestamp=%s", 
 		event.EventType, event.OrderID, event.Timestamp.Format("2006-01-02 15:04:05"))
 	
@@ -122,12 +177,17 @@ func (s *OrderService) HandleOrderEvent(event domain.OrderEvent) error {
 	return nil
 }
 
-// HandleOrderDamageEvent processes incoming order damage events from MQTT
-func (s *OrderService) HandleOrderDamageEvent(event domain.OrderDamageEvent) error {
+// HandleOrderDamageEvent processes incoming order damage events from MQTT in the trace
+// of the event
+func (s *OrderService) HandleOrderDamageEvent(event domain.OrderDamageEvent) (err error) {
+	s, end := s.TracedFrom(event.TraceContext).trace("HandleOrderDamageEvent",
+		map[string]string{"order.id": event.OrderID, "order.damage_severity": event.Severity})
+	defer func() { end(err) }()
+
 	log.Printf("Processing order damage event: EventID=%s, OrderID=%s, Severity=%s, OccurredAt=%s", 
 		event.EventID, event.OrderID, event.Severity, event.OccurredAt.Format("2006-01-02 15:04:05"))
 	
-	log.Printf("Damage details: Temperature=%.2f°C, Humidity=%d%%, Status=%s", 
+	log.Printf("Damage details: Temperature=%.2f°C, Humidity=%.2f%%, Status=%s", 
 		event.Details.Temperature, event.Details.Humidity, event.Details.Status)
 	
 	log.Printf("Damage description: %s", event.Description)
@@ -194,6 +254,8 @@ func (s *OrderService) HandleOrderDamageEvent(event domain.OrderDamageEvent) err
 		OrderID:   order.ID,
 		Order:     *order,
 		Timestamp: time.Now(),
+
+		TraceContext: s.traceContext,
 	}
 	
 	if err := s.eventPublisher.PublishOrderEvent(orderEvent); err != nil {
@@ -3,12 +3,14 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
 )
 
 // Config holds all configuration for the application
 type Config struct {
 	RabbitMQ RabbitMQConfig
 	HTTP     HTTPConfig
+	Tracing  TracingConfig
 }
 
 // RabbitMQConfig holds RabbitMQ-specific configuration
@@ -21,6 +23,8 @@ type RabbitMQConfig struct {
 	// Publisher configuration (for publishing order events)
 	PublisherQueueName   string
 	PublisherRoutingKey  string
+	// EventEncoding is how published order events are encoded: "protobuf" or "json"
+	EventEncoding        string
 }
 
 // HTTPConfig holds HTTP server configuration
@@ -28,6 +32,16 @@ type HTTPConfig struct {
 	Port string
 }
 
+// TracingConfig holds the configuration of the export of spans over OTLP
+type TracingConfig struct {
+	Enabled     bool
+	ServiceName string
+	// Endpoint is the base URL of the OTLP/HTTP collector
+	Endpoint string
+	// SampleRatio is the fraction of new traces that are sampled
+	SampleRatio float64
+}
+
 // LoadConfig loads configuration from environment variables
 func LoadConfig() *Config {
 	// Construct RabbitMQ URL from components if individual parts are provided
@@ -54,10 +68,17 @@ func LoadConfig() *Config {
 			// Publisher configuration (for publishing order events)
 			PublisherQueueName:   getEnv("RABBITMQ_PUBLISHER_QUEUE", "order-events-queue"),
 			PublisherRoutingKey:  getEnv("RABBITMQ_PUBLISHER_ROUTING_KEY", "order.events"),
+			EventEncoding:        getEnv("RABBITMQ_EVENT_ENCODING", "protobuf"),
 		},
 		HTTP: HTTPConfig{
 			Port: getEnv("HTTP_PORT", "8081"),
 		},
+		Tracing: TracingConfig{
+			Enabled:     getEnv("TRACING_ENABLED", "false") == "true",
+			ServiceName: getEnv("OTEL_SERVICE_NAME", "order-management"),
+			Endpoint:    getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://localhost:4318"),
+			SampleRatio: getEnvFloat("TRACING_SAMPLE_RATIO", 1),
+		},
 	}
 }
 
@@ -67,4 +88,12 @@ func getEnv(key, defaultValue string) string {
 		return value
 	}
 	return defaultValue
+}
+
+// getEnvFloat returns environment variable value as a float or default if not set or invalid
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil {
+		return value
+	}
+	return defaultValue
 }
\ No newline at end of file
@@ -22,6 +22,9 @@ type OrderEvent struct {
 	OrderID   string    `json:"order_id"`
 	Order     Order     `json:"order"`
 	Timestamp time.Time `json:"timestamp"`
+
+	// TraceContext is the trace the event belongs to; it travels in the message headers
+	TraceContext TraceContext `json:"-"`
 }
 
 // OrderDamageEvent represents an order damage event from MQTT
@@ -34,12 +37,15 @@ type OrderDamageEvent struct {
 	Severity    string                 `json:"severity"`
 	Description string                 `json:"description"`
 	Details     OrderDamageDetails     `json:"details"`
+
+	// TraceContext is the trace the event belongs to; it travels in the message headers
+	TraceContext TraceContext `json:"-"`
 }
 
 // OrderDamageDetails contains the sensor data that triggered the damage event
 type OrderDamageDetails struct {
 	Temperature float64 `json:"temperature"`
-	Humidity    int     `json:"humidity"`
+	Humidity    float64 `json:"humidity"`
 	Status      string  `json:"status"`
 	MqttTopic   string  `json:"mqttTopic"`
 }
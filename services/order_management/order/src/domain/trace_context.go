@@ -0,0 +1,37 @@
+package domain
+
+// W3C Trace Context headers that carry the trace of a message across services
+const (
+	TraceParentHeader = "traceparent"
+	TraceStateHeader  = "tracestate"
+)
+
+// TraceContext is the W3C trace context of the operation that caused an event, so its
+// trace continues wherever the event is published or handled. A zero trace context
+// starts a new trace.
+type TraceContext struct {
+	TraceParent string
+	TraceState  string
+}
+
+// IsZero reports whether the trace context carries no trace
+func (tc TraceContext) IsZero() bool {
+	return tc.TraceParent == ""
+}
+
+// Tracer records the spans of the application services; it is implemented by the
+// tracing infrastructure
+type Tracer interface {
+	// StartSpan starts a span as a child of the span of the parent trace context and
+	// returns the trace context of the new span and the function that ends it with the
+	// outcome of the operation
+	StartSpan(parent TraceContext, name string, attributes map[string]string) (TraceContext, func(err error))
+}
+
+// NoopTracer records no spans; the trace context of the parent is passed on unchanged
+type NoopTracer struct{}
+
+// StartSpan returns the parent trace context
+func (NoopTracer) StartSpan(parent TraceContext, name string, attributes map[string]string) (TraceContext, func(err error)) {
+	return parent, func(error) {}
+}
@@ -0,0 +1,90 @@
+package drivenadapters
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/oder/src/domain"
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/oder/src/infrastructure/eventspb"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ProtobufEventCodec implements domain.EventCodec with the Protobuf event schemas
+type ProtobufEventCodec struct{}
+
+// ParseEventEncoding returns the codec of a configured event encoding, "protobuf" or "json"
+func ParseEventEncoding(value string) (domain.EventCodec, error) {
+	switch value {
+	case "protobuf":
+		return ProtobufEventCodec{}, nil
+	case "json":
+		return domain.JSONEventCodec{}, nil
+	}
+	return nil, fmt.Errorf("unknown event encoding %q (expected %q or %q)", value, "protobuf", "json")
+}
+
+// ContentType returns the Protobuf content type
+func (ProtobufEventCodec) ContentType() string {
+	return domain.ProtobufContentType
+}
+
+// EncodeOrderEvent encodes an order event as a medisupply.events.v1.OrderEvent
+func (ProtobufEventCodec) EncodeOrderEvent(event domain.OrderEvent) ([]byte, error) {
+	return proto.Marshal(&eventspb.OrderEvent{
+		EventType: event.EventType,
+		OrderId:   event.OrderID,
+		Order: &eventspb.Order{
+			Id:          event.Order.ID,
+			CustomerId:  event.Order.CustomerID,
+			ProductId:   event.Order.ProductID,
+			Quantity:    int64(event.Order.Quantity),
+			Status:      event.Order.Status,
+			TotalAmount: event.Order.TotalAmount,
+			CreatedAt:   toTimestamp(event.Order.CreatedAt),
+			UpdatedAt:   toTimestamp(event.Order.UpdatedAt),
+		},
+		Timestamp: toTimestamp(event.Timestamp),
+	})
+}
+
+// DecodeOrderDamageEvent decodes a medisupply.events.v1.OrderDamageEvent
+func (ProtobufEventCodec) DecodeOrderDamageEvent(data []byte) (domain.OrderDamageEvent, error) {
+	var message eventspb.OrderDamageEvent
+	if err := proto.Unmarshal(data, &message); err != nil {
+		return domain.OrderDamageEvent{}, fmt.Errorf("failed to decode Protobuf order damage event: %w", err)
+	}
+
+	details := message.GetDetails()
+	return domain.OrderDamageEvent{
+		EventID:     message.GetEventId(),
+		Type:        message.GetType(),
+		Source:      message.GetSource(),
+		OccurredAt:  fromTimestamp(message.GetOccurredAt()),
+		OrderID:     message.GetOrderId(),
+		Severity:    message.GetSeverity(),
+		Description: message.GetDescription(),
+		Details: domain.OrderDamageDetails{
+			Temperature: details.GetTemperature(),
+			Humidity:    details.GetHumidity(),
+			Status:      details.GetStatus(),
+			MqttTopic:   details.GetMqttTopic(),
+		},
+	}, nil
+}
+
+// toTimestamp converts a time to a Protobuf timestamp, leaving the zero time unset
+func toTimestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+// fromTimestamp converts a Protobuf timestamp to a UTC time, the zero time if unset
+func fromTimestamp(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
@@ -0,0 +1,65 @@
+package drivenadapters
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/oder/src/domain"
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/oder/src/infrastructure/eventspb"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestProtobufEventCodec_DecodeOrderDamageEvent(t *testing.T) {
+	// Written with the released v1 schema; it must stay readable, so it is never regenerated
+	data, err := os.ReadFile("testdata/order_damage_event.v1.binpb")
+	if err != nil {
+		t.Fatalf("Failed to read v1 order damage event: %v", err)
+	}
+
+	event, err := ProtobufEventCodec{}.DecodeOrderDamageEvent(data)
+	if err != nil {
+		t.Fatalf("Failed to decode v1 order damage event: %v", err)
+	}
+	expected := domain.OrderDamageEvent{
+		EventID:     "evt_1759555253",
+		Type:        "order.damage",
+		Source:      "mqtt-order-event-client",
+		OccurredAt:  time.Date(2025, 10, 4, 5, 20, 53, 0, time.UTC),
+		OrderID:     "order-1",
+		Severity:    "minor",
+		Description: "Potential damage detected: temp=9.23C, humidity=58.50%",
+		Details: domain.OrderDamageDetails{
+			Temperature: 9.25,
+			Humidity:    58.5,
+			Status:      "active",
+			MqttTopic:   "events/sensor",
+		},
+	}
+	if event != expected {
+		t.Errorf("Expected %+v, got %+v", expected, event)
+	}
+}
+
+func TestProtobufEventCodec_EncodeOrderEvent(t *testing.T) {
+	at := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	event := domain.OrderEvent{
+		EventType: "order.created",
+		OrderID:   "order-1",
+		Order:     domain.Order{ID: "order-1", ProductID: "product-1", Quantity: 3, TotalAmount: 42.5, CreatedAt: at},
+		Timestamp: at,
+	}
+
+	data, err := ProtobufEventCodec{}.EncodeOrderEvent(event)
+	if err != nil {
+		t.Fatalf("Failed to encode order event: %v", err)
+	}
+	var message eventspb.OrderEvent
+	if err := proto.Unmarshal(data, &message); err != nil {
+		t.Fatalf("Failed to decode order event: %v", err)
+	}
+	if message.GetOrderId() != "order-1" || message.GetOrder().GetQuantity() != 3 || message.GetOrder().GetTotalAmount() != 42.5 ||
+		!message.GetTimestamp().AsTime().Equal(at) || message.GetOrder().GetUpdatedAt() != nil {
+		t.Errorf("Expected the order event, got %v", &message)
+	}
+}
@@ -2,7 +2,6 @@ package drivenadapters
 
 import (
 	"context"
-	"encoding/json"
 	"log"
 
 	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/oder/src/domain"
@@ -16,10 +15,11 @@ type RabbitMQPublisher struct {
 	exchangeName string
 	queueName    string
 	routingKey   string
+	codec        domain.EventCodec
 }
 
-// NewRabbitMQPublisher creates a new RabbitMQPublisher
-func NewRabbitMQPublisher(rabbitMQURL, exchangeName, queueName, routingKey string) (*RabbitMQPublisher, error) {
+// NewRabbitMQPublisher creates a new RabbitMQPublisher that encodes order events with the codec
+func NewRabbitMQPublisher(rabbitMQURL, exchangeName, queueName, routingKey string, codec domain.EventCodec) (*RabbitMQPublisher, error) {
 	conn, err := amqp.Dial(rabbitMQURL)
 	if err != nil {
 		return nil, err
@@ -85,13 +85,14 @@ func NewRabbitMQPublisher(rabbitMQURL, exchangeName, queueName, routingKey strin
 		exchangeName: exchangeName,
 		queueName:    queueName,
 		routingKey:   routingKey,
+		codec:        codec,
 	}, nil
 }
 
 // PublishOrderEvent publishes an order event to RabbitMQ
 func (p *RabbitMQPublisher) PublishOrderEvent(event domain.OrderEvent) error {
-	// Marshal the event to JSON
-	body, err := json.Marshal(event)
+	// Encode the event with the configured codec
+	body, err := p.codec.EncodeOrderEvent(event)
 	if err != nil {
 		return err
 	}
@@ -104,7 +105,8 @@ func (p *RabbitMQPublisher) PublishOrderEvent(event domain.OrderEvent) error {
 		false,          // mandatory
 		false,          // immediate
 		amqp.Publishing{
-			ContentType: "application/json",
+			ContentType: p.codec.ContentType(),
+			Headers:     eventHeaders(event.TraceContext),
 			Body:        body,
 		},
 	)
@@ -118,6 +120,19 @@ func (p *RabbitMQPublisher) PublishOrderEvent(event domain.OrderEvent) error {
 	return nil
 }
 
+// eventHeaders returns the headers of an order event message: the schema version and the
+// trace context of the event, if any
+func eventHeaders(traceContext domain.TraceContext) amqp.Table {
+	headers := amqp.Table{domain.SchemaVersionHeader: domain.EventSchemaVersion}
+	if !traceContext.IsZero() {
+		headers[domain.TraceParentHeader] = traceContext.TraceParent
+		if traceContext.TraceState != "" {
+			headers[domain.TraceStateHeader] = traceContext.TraceState
+		}
+	}
+	return headers
+}
+
 // PublishMessage publishes a simple message to RabbitMQ (for demo purposes)
 func (p *RabbitMQPublisher) PublishMessage(ctx context.Context, message string) error {
 	err := p.channel.PublishWithContext(ctx,
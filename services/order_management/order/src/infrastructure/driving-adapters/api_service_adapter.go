@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/oder/src/application"
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/oder/src/infrastructure/tracing"
 	"github.com/gin-gonic/gin"
 )
 
@@ -66,8 +67,8 @@ func (adapter *ApiServiceAdapter) setupRoutes() {
 	// Health check endpoint
 	adapter.router.GET("/health", adapter.healthHandler)
 	
-	// Order management endpoints
-	v1 := adapter.router.Group("/api/v1")
+	// Order management endpoints, each request in a span of the trace of its caller
+	v1 := adapter.router.Group("/api/v1", tracing.Middleware())
 	{
 		v1.POST("/orders", adapter.createOrderHandler)
 		v1.GET("/orders", adapter.getAllOrdersHandler)
@@ -76,6 +77,11 @@ func (adapter *ApiServiceAdapter) setupRoutes() {
 	}
 }
 
+// ordersFor returns the order service serving a request in the trace of its span
+func (adapter *ApiServiceAdapter) ordersFor(c *gin.Context) *application.OrderService {
+	return adapter.orderService.TracedFrom(tracing.TraceContextFromContext(c.Request.Context()))
+}
+
 // healthHandler handles health check requests
 func (adapter *ApiServiceAdapter) healthHandler(c *gin.Context) {
 	response := gin.H{
@@ -95,7 +101,7 @@ func (adapter *ApiServiceAdapter) createOrderHandler(c *gin.Context) {
 		return
 	}
 
-	order, err := adapter.orderService.CreateOrder(req.CustomerID, req.ProductID, req.Quantity, req.TotalAmount)
+	order, err := adapter.ordersFor(c).CreateOrder(req.CustomerID, req.ProductID, req.Quantity, req.TotalAmount)
 	if err != nil {
 		log.Printf("Error creating order: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create order"})
@@ -141,7 +147,7 @@ func (adapter *ApiServiceAdapter) updateOrderStatusHandler(c *gin.Context) {
 		return
 	}
 
-	order, err := adapter.orderService.UpdateOrderStatus(id, req.Status)
+	order, err := adapter.ordersFor(c).UpdateOrderStatus(id, req.Status)
 	if err != nil {
 		log.Printf("Error updating order status %s: %v", id, err)
 		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
@@ -19,10 +19,12 @@ type OrderConsumerAdapter struct {
 	exchangeName string
 	routingKey   string
 	eventHandler domain.OrderEventHandler
+	codecs       domain.EventCodecs
 }
 
-// NewOrderConsumerAdapter creates a new OrderConsumerAdapter
-func NewOrderConsumerAdapter(rabbitMQURL, exchangeName, queueName, routingKey string, eventHandler domain.OrderEventHandler) (*OrderConsumerAdapter, error) {
+// NewOrderConsumerAdapter creates a new OrderConsumerAdapter that decodes messages with
+// the codec of their content type. JSON is always accepted.
+func NewOrderConsumerAdapter(rabbitMQURL, exchangeName, queueName, routingKey string, eventHandler domain.OrderEventHandler, codecs domain.EventCodecs) (*OrderConsumerAdapter, error) {
 	conn, err := amqp.Dial(rabbitMQURL)
 	if err != nil {
 		return nil, err
@@ -86,6 +88,7 @@ func NewOrderConsumerAdapter(rabbitMQURL, exchangeName, queueName, routingKey st
 		exchangeName: exchangeName,
 		routingKey:   routingKey,
 		eventHandler: eventHandler,
+		codecs:       codecs,
 	}, nil
 }
 
@@ -121,7 +124,7 @@ func (adapter *OrderConsumerAdapter) Start(ctx context.Context) {
 			}
 
 			// Translate RabbitMQ message to domain event
-			event, err := adapter.translateMessage(delivery.Body)
+			event, err := adapter.translateMessage(delivery)
 			if err != nil {
 				log.Printf("Error translating message: %v", err)
 				delivery.Nack(false, false) // Reject and don't requeue
@@ -151,8 +154,43 @@ func (adapter *OrderConsumerAdapter) Start(ctx context.Context) {
 	}
 }
 
-// translateMessage converts a RabbitMQ message to a domain order event
-func (adapter *OrderConsumerAdapter) translateMessage(body []byte) (interface{}, error) {
+// translateMessage converts a RabbitMQ message to a domain order event in the trace
+// carried by its headers
+func (adapter *OrderConsumerAdapter) translateMessage(delivery amqp.Delivery) (interface{}, error) {
+	event, err := adapter.decodeMessage(delivery)
+	if err != nil {
+		return nil, err
+	}
+
+	traceContext := traceContextFromHeaders(delivery.Headers)
+	switch e := event.(type) {
+	case domain.OrderDamageEvent:
+		e.TraceContext = traceContext
+		return e, nil
+	case domain.OrderEvent:
+		e.TraceContext = traceContext
+		return e, nil
+	}
+	return event, nil
+}
+
+// decodeMessage decodes the body of a RabbitMQ message. JSON messages are MQTT-wrapped
+// order damage events or order events; messages in another encoding of the event schemas
+// are order damage events.
+func (adapter *OrderConsumerAdapter) decodeMessage(delivery amqp.Delivery) (interface{}, error) {
+	version, _ := delivery.Headers[domain.SchemaVersionHeader].(string)
+	if err := domain.CheckSchemaVersion(version); err != nil {
+		return nil, err
+	}
+	codec, err := adapter.codecs.ForContentType(delivery.ContentType)
+	if err != nil {
+		return nil, err
+	}
+	if codec.ContentType() != domain.JSONContentType {
+		return codec.DecodeOrderDamageEvent(delivery.Body)
+	}
+
+	body := delivery.Body
 	// First try to unmarshal as MQTT order event (for order damage events)
 	var mqttEvent domain.MQTTOrderEvent
 	if err := json.Unmarshal(body, &mqttEvent); err == nil {
@@ -179,6 +217,13 @@ func (adapter *OrderConsumerAdapter) translateMessage(body []byte) (interface{},
 	return event, nil
 }
 
+// traceContextFromHeaders reads the trace context of a message from its headers
+func traceContextFromHeaders(headers amqp.Table) domain.TraceContext {
+	traceParent, _ := headers[domain.TraceParentHeader].(string)
+	traceState, _ := headers[domain.TraceStateHeader].(string)
+	return domain.TraceContext{TraceParent: traceParent, TraceState: traceState}
+}
+
 // handleOrderDamageEvent processes order damage events from MQTT
 func (adapter *OrderConsumerAdapter) handleOrderDamageEvent(mqttEvent domain.MQTTOrderEvent) (domain.OrderDamageEvent, error) {
 	var damageEvent domain.OrderDamageEvent
@@ -0,0 +1,66 @@
+package drivingadapters
+
+import (
+	"testing"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/oder/src/domain"
+	drivenadapters "github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/oder/src/infrastructure/driven-adapters"
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/oder/src/infrastructure/eventspb"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestOrderConsumerAdapter_TranslateMessage(t *testing.T) {
+	adapter := &OrderConsumerAdapter{codecs: domain.EventCodecs{drivenadapters.ProtobufEventCodec{}}}
+	protobufBody, _ := proto.Marshal(&eventspb.OrderDamageEvent{
+		OrderId: "order-1",
+		Details: &eventspb.OrderDamageDetails{Humidity: 58.5},
+	})
+	mqttBody := []byte(`{"mqtt_topic":"events/order-damage","payload":"{\"orderId\":\"order-1\",\"details\":{\"humidity\":58.5}}"}`)
+
+	for name, delivery := range map[string]amqp.Delivery{
+		"Protobuf": {
+			ContentType: domain.ProtobufContentType,
+			Headers:     amqp.Table{domain.SchemaVersionHeader: domain.EventSchemaVersion},
+			Body:        protobufBody,
+		},
+		"MQTT-wrapped JSON": {Body: mqttBody},
+	} {
+		event, err := adapter.translateMessage(delivery)
+		if err != nil {
+			t.Fatalf("Failed to translate %s message: %v", name, err)
+		}
+		damageEvent, ok := event.(domain.OrderDamageEvent)
+		if !ok || damageEvent.OrderID != "order-1" || damageEvent.Details.Humidity != 58.5 {
+			t.Errorf("Expected the order damage event from the %s message, got %+v", name, event)
+		}
+	}
+
+	for name, delivery := range map[string]amqp.Delivery{
+		"newer schema version":     {Headers: amqp.Table{domain.SchemaVersionHeader: "2"}, Body: mqttBody},
+		"unsupported content type": {ContentType: "application/avro", Body: protobufBody},
+	} {
+		if _, err := adapter.translateMessage(delivery); err == nil {
+			t.Errorf("Expected an error for %s", name)
+		}
+	}
+}
+
+func TestOrderConsumerAdapter_TranslateMessageTraceContext(t *testing.T) {
+	adapter := &OrderConsumerAdapter{}
+	traceContext := domain.TraceContext{
+		TraceParent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		TraceState:  "vendor=value",
+	}
+
+	event, err := adapter.translateMessage(amqp.Delivery{
+		Headers: amqp.Table{domain.TraceParentHeader: traceContext.TraceParent, domain.TraceStateHeader: traceContext.TraceState},
+		Body:    []byte(`{"event_type":"order.created","order_id":"order-1"}`),
+	})
+	if err != nil {
+		t.Fatalf("Failed to translate message: %v", err)
+	}
+	if orderEvent, ok := event.(domain.OrderEvent); !ok || orderEvent.TraceContext != traceContext {
+		t.Errorf("Expected the order event in the trace of the message headers, got %+v", event)
+	}
+}
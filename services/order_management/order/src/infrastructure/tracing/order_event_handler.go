@@ -0,0 +1,63 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/oder/src/domain"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracedOrderEventHandler handles every event in a consumer span of the trace of the
+// message it was consumed from
+type tracedOrderEventHandler struct {
+	handler domain.OrderEventHandler
+	queue   string
+}
+
+// InstrumentOrderEventHandler wraps an order event handler so every event consumed from
+// the queue is handled in a consumer span continuing the trace of its message. The
+// handler receives the event in the trace of the consumer span.
+func InstrumentOrderEventHandler(handler domain.OrderEventHandler, queue string) domain.OrderEventHandler {
+	return &tracedOrderEventHandler{handler: handler, queue: queue}
+}
+
+// HandleOrderEvent handles the event with the wrapped handler in a consumer span
+func (h *tracedOrderEventHandler) HandleOrderEvent(event domain.OrderEvent) error {
+	span := h.start(&event.TraceContext,
+		attribute.String("order.id", event.OrderID),
+		attribute.String("order.event_type", event.EventType),
+	)
+	err := h.handler.HandleOrderEvent(event)
+	endSpan(span, err)
+	return err
+}
+
+// HandleOrderDamageEvent handles the event with the wrapped handler in a consumer span
+func (h *tracedOrderEventHandler) HandleOrderDamageEvent(event domain.OrderDamageEvent) error {
+	span := h.start(&event.TraceContext,
+		semconv.MessagingMessageID(event.EventID),
+		attribute.String("order.id", event.OrderID),
+		attribute.String("order.damage_severity", event.Severity),
+	)
+	err := h.handler.HandleOrderDamageEvent(event)
+	endSpan(span, err)
+	return err
+}
+
+// start starts the consumer span of an event and moves the event into its trace
+func (h *tracedOrderEventHandler) start(traceContext *domain.TraceContext, attributes ...attribute.KeyValue) trace.Span {
+	ctx, span := tracer().Start(ContextWithTraceContext(context.Background(), *traceContext), "process "+h.queue,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			semconv.MessagingSystemRabbitMQ,
+			semconv.MessagingOperationTypeProcess,
+			semconv.MessagingOperationName("process"),
+			semconv.MessagingDestinationSubscriptionName(h.queue),
+		),
+		trace.WithAttributes(attributes...),
+	)
+	*traceContext = TraceContextFromContext(ctx)
+	return span
+}
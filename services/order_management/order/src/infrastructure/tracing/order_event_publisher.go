@@ -0,0 +1,47 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/oder/src/domain"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracedOrderEventPublisher publishes every order event in a producer span of the trace
+// of the operation that raised it
+type tracedOrderEventPublisher struct {
+	publisher  domain.OrderEventPublisher
+	exchange   string
+	routingKey string
+}
+
+// InstrumentOrderEventPublisher wraps an order event publisher so every event is
+// published to the exchange with the routing key in a producer span continuing the trace
+// of the event. The wrapped publisher receives the event in the trace of the producer
+// span, which it carries in the message headers.
+func InstrumentOrderEventPublisher(publisher domain.OrderEventPublisher, exchange, routingKey string) domain.OrderEventPublisher {
+	return &tracedOrderEventPublisher{publisher: publisher, exchange: exchange, routingKey: routingKey}
+}
+
+// PublishOrderEvent publishes the event with the wrapped publisher in a producer span
+func (p *tracedOrderEventPublisher) PublishOrderEvent(event domain.OrderEvent) error {
+	ctx, span := tracer().Start(ContextWithTraceContext(context.Background(), event.TraceContext), "send "+p.exchange,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystemRabbitMQ,
+			semconv.MessagingOperationTypeSend,
+			semconv.MessagingOperationName("send"),
+			semconv.MessagingDestinationName(p.exchange),
+			semconv.MessagingRabbitMQDestinationRoutingKey(p.routingKey),
+			attribute.String("order.id", event.OrderID),
+			attribute.String("order.event_type", event.EventType),
+		),
+	)
+	event.TraceContext = TraceContextFromContext(ctx)
+
+	err := p.publisher.PublishOrderEvent(event)
+	endSpan(span, err)
+	return err
+}
@@ -0,0 +1,126 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/oder/src/domain"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName names the tracer of the service
+const instrumentationName = "github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/oder"
+
+// tracesPath is the path of the OTLP/HTTP collector spans are sent to
+const tracesPath = "/v1/traces"
+
+// Config configures the export of the spans of the service
+type Config struct {
+	// Enabled exports spans; without it the trace context is still propagated
+	Enabled bool
+
+	// ServiceName is the service.name of the exported spans
+	ServiceName string
+
+	// Endpoint is the base URL of the OTLP/HTTP collector, e.g. http://otel-collector:4318;
+	// spans are sent to its /v1/traces path, without TLS for an http URL
+	Endpoint string
+
+	// SampleRatio is the fraction of new traces that are sampled; traces continued from
+	// another service follow the sampling decision of the caller
+	SampleRatio float64
+}
+
+// Setup installs the W3C Trace Context propagator and, when tracing is enabled, a tracer
+// provider that exports the spans in batches over OTLP/HTTP. The returned function
+// exports the spans still buffered and stops the provider.
+func Setup(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	if !cfg.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	endpoint := strings.TrimSuffix(cfg.Endpoint, "/") + tracesPath
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+	}
+	provider := newTracerProvider(cfg.ServiceName, cfg.SampleRatio, sdktrace.WithBatcher(exporter))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// newTracerProvider creates a tracer provider for the service with the given exporting
+// options
+func newTracerProvider(serviceName string, sampleRatio float64, opts ...sdktrace.TracerProviderOption) *sdktrace.TracerProvider {
+	service, _ := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(serviceName)))
+	return sdktrace.NewTracerProvider(append([]sdktrace.TracerProviderOption{
+		sdktrace.WithResource(service),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	}, opts...)...)
+}
+
+// tracer returns the tracer of the service from the installed tracer provider
+func tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// ContextWithTraceContext returns a context carrying the span of a trace context as its
+// remote parent
+func ContextWithTraceContext(ctx context.Context, traceContext domain.TraceContext) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier{
+		domain.TraceParentHeader: traceContext.TraceParent,
+		domain.TraceStateHeader:  traceContext.TraceState,
+	})
+}
+
+// TraceContextFromContext returns the trace context of the span of a context, or a zero
+// trace context if the context carries no span
+func TraceContextFromContext(ctx context.Context) domain.TraceContext {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	return domain.TraceContext{
+		TraceParent: carrier[domain.TraceParentHeader],
+		TraceState:  carrier[domain.TraceStateHeader],
+	}
+}
+
+// Tracer records the spans of the application services with the installed tracer
+// provider; it implements domain.Tracer
+type Tracer struct{}
+
+// StartSpan starts an internal span as a child of the span of the parent trace context
+func (Tracer) StartSpan(parent domain.TraceContext, name string, attributes map[string]string) (domain.TraceContext, func(err error)) {
+	ctx, span := tracer().Start(ContextWithTraceContext(context.Background(), parent), name,
+		trace.WithAttributes(stringAttributes(attributes)...))
+	return TraceContextFromContext(ctx), func(err error) {
+		endSpan(span, err)
+	}
+}
+
+// endSpan ends a span, recording the error an operation failed with
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// stringAttributes converts string attributes into span attributes
+func stringAttributes(attributes map[string]string) []attribute.KeyValue {
+	converted := make([]attribute.KeyValue, 0, len(attributes))
+	for key, value := range attributes {
+		converted = append(converted, attribute.String(key, value))
+	}
+	return converted
+}
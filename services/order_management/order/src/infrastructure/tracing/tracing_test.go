@@ -0,0 +1,146 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/oder/src/domain"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// remoteParent is the trace context of a span of another service
+var remoteParent = domain.TraceContext{TraceParent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}
+
+// stubOrderEventHandler records the damage event it handles and fails with err
+type stubOrderEventHandler struct {
+	handled domain.OrderDamageEvent
+	err     error
+}
+
+func (h *stubOrderEventHandler) HandleOrderEvent(event domain.OrderEvent) error {
+	return h.err
+}
+
+func (h *stubOrderEventHandler) HandleOrderDamageEvent(event domain.OrderDamageEvent) error {
+	h.handled = event
+	return h.err
+}
+
+// stubOrderEventPublisher records the event it publishes and fails with err
+type stubOrderEventPublisher struct {
+	published domain.OrderEvent
+	err       error
+}
+
+func (p *stubOrderEventPublisher) PublishOrderEvent(event domain.OrderEvent) error {
+	p.published = event
+	return p.err
+}
+
+func TestTracer_StartSpan(t *testing.T) {
+	exporter, restore := InstallInMemoryExporter()
+	defer restore()
+
+	child, end := Tracer{}.StartSpan(remoteParent, "OrderService.UpdateOrderStatus", map[string]string{"order.id": "order-1"})
+	end(errors.New("order not found"))
+
+	span := onlySpan(t, exporter)
+	if span.Name != "OrderService.UpdateOrderStatus" || span.Parent.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("Expected a child of the remote span, got %s in trace %s", span.Name, span.Parent.TraceID())
+	}
+	if span.Status.Code != codes.Error {
+		t.Errorf("Expected the span to end with the error, got status %v", span.Status)
+	}
+	if got := TraceContextFromContext(trace.ContextWithSpanContext(context.Background(), span.SpanContext)); got != child {
+		t.Errorf("Expected the trace context %+v of the span, got %+v", got, child)
+	}
+}
+
+func TestInstrumentOrderEventHandler(t *testing.T) {
+	exporter, restore := InstallInMemoryExporter()
+	defer restore()
+
+	handler := &stubOrderEventHandler{err: errors.New("repository unavailable")}
+	event := domain.OrderDamageEvent{EventID: "event-1", OrderID: "order-1", Severity: "major", TraceContext: remoteParent}
+	if err := InstrumentOrderEventHandler(handler, "order-damage-queue").HandleOrderDamageEvent(event); err != handler.err {
+		t.Errorf("Expected the handler error to be returned, got %v", err)
+	}
+
+	span := onlySpan(t, exporter)
+	if span.Name != "process order-damage-queue" || span.SpanKind != trace.SpanKindConsumer || span.Status.Code != codes.Error {
+		t.Errorf("Expected a failed consumer span, got %s (%v, %v)", span.Name, span.SpanKind, span.Status)
+	}
+	if span.Parent.TraceID() != span.SpanContext.TraceID() || !span.Parent.IsRemote() {
+		t.Error("Expected the consumer span to continue the trace of the message")
+	}
+	if handler.handled.TraceContext != TraceContextFromContext(trace.ContextWithSpanContext(context.Background(), span.SpanContext)) {
+		t.Errorf("Expected the event to be handled in the trace of the consumer span, got %+v", handler.handled.TraceContext)
+	}
+}
+
+func TestInstrumentOrderEventPublisher(t *testing.T) {
+	exporter, restore := InstallInMemoryExporter()
+	defer restore()
+
+	publisher := &stubOrderEventPublisher{}
+	event := domain.OrderEvent{EventType: "order.created", OrderID: "order-1", TraceContext: remoteParent}
+	if err := InstrumentOrderEventPublisher(publisher, "events", "order.events").PublishOrderEvent(event); err != nil {
+		t.Fatalf("Failed to publish the event: %v", err)
+	}
+
+	span := onlySpan(t, exporter)
+	if span.Name != "send events" || span.SpanKind != trace.SpanKindProducer || span.Status.Code == codes.Error {
+		t.Errorf("Expected a producer span, got %s (%v, %v)", span.Name, span.SpanKind, span.Status)
+	}
+	if span.Parent.TraceID() != span.SpanContext.TraceID() {
+		t.Error("Expected the producer span to continue the trace of the event")
+	}
+	if publisher.published.TraceContext != TraceContextFromContext(trace.ContextWithSpanContext(context.Background(), span.SpanContext)) {
+		t.Errorf("Expected the event to be published in the trace of the producer span, got %+v", publisher.published.TraceContext)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	exporter, restore := InstallInMemoryExporter()
+	defer restore()
+
+	var requestContext domain.TraceContext
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware())
+	router.PUT("/api/v1/orders/:id/status", func(c *gin.Context) {
+		requestContext = TraceContextFromContext(c.Request.Context())
+		c.Status(http.StatusInternalServerError)
+	})
+
+	request := httptest.NewRequest(http.MethodPut, "/api/v1/orders/order-1/status", nil)
+	request.Header.Set("traceparent", remoteParent.TraceParent)
+	router.ServeHTTP(httptest.NewRecorder(), request)
+
+	span := onlySpan(t, exporter)
+	if span.Name != "PUT /api/v1/orders/:id/status" || span.SpanKind != trace.SpanKindServer || span.Status.Code != codes.Error {
+		t.Errorf("Expected a failed server span named by route, got %s (%v, %v)", span.Name, span.SpanKind, span.Status)
+	}
+	if span.Parent.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("Expected the server span to continue the trace of the caller, got trace %s", span.SpanContext.TraceID())
+	}
+	if requestContext.IsZero() || requestContext == remoteParent {
+		t.Errorf("Expected the request to be handled in the server span, got %+v", requestContext)
+	}
+}
+
+// onlySpan returns the only span recorded by the exporter
+func onlySpan(t *testing.T, exporter *tracetest.InMemoryExporter) tracetest.SpanStub {
+	t.Helper()
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(spans))
+	}
+	return spans[0]
+}
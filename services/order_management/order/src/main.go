@@ -11,8 +11,10 @@ import (
 	"github.com/joho/godotenv"
 	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/oder/src/application"
 	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/oder/src/config"
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/oder/src/domain"
 	drivingadapters "github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/oder/src/infrastructure/driving-adapters"
 	drivenadapters "github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/oder/src/infrastructure/driven-adapters"
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/oder/src/infrastructure/tracing"
 )
 
 func main() {
@@ -33,16 +35,34 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Trace orders across the services; spans are exported over OTLP when enabled
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Config{
+		Enabled:     cfg.Tracing.Enabled,
+		ServiceName: cfg.Tracing.ServiceName,
+		Endpoint:    cfg.Tracing.Endpoint,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		log.Fatalf("Failed to set up tracing: %v", err)
+	}
+
 	// Initialize driven adapters (infrastructure)
 	// Order repository for data persistence
 	orderRepo := drivenadapters.NewMemoryOrderRepository()
 	
+	// Codec of the published order events
+	eventCodec, err := drivenadapters.ParseEventEncoding(cfg.RabbitMQ.EventEncoding)
+	if err != nil {
+		log.Fatalf("Invalid RABBITMQ_EVENT_ENCODING: %v", err)
+	}
+
 	// RabbitMQ publisher for event publishing
 	eventPublisher, err := drivenadapters.NewRabbitMQPublisher(
 		cfg.RabbitMQ.URL,
 		cfg.RabbitMQ.ExchangeName,
 		cfg.RabbitMQ.PublisherQueueName,
 		cfg.RabbitMQ.PublisherRoutingKey,
+		eventCodec,
 	)
 	if err != nil {
 		log.Fatalf("Failed to create RabbitMQ publisher: %v", err)
@@ -50,7 +70,11 @@ func main() {
 	defer eventPublisher.Close()
 
 	// Initialize application layer (business logic)
-	orderService := application.NewOrderService(orderRepo, eventPublisher)
+	orderService := application.NewOrderService(
+		orderRepo,
+		tracing.InstrumentOrderEventPublisher(eventPublisher, cfg.RabbitMQ.ExchangeName, cfg.RabbitMQ.PublisherRoutingKey),
+		application.WithTracer(tracing.Tracer{}),
+	)
 
 	// Initialize driving adapters
 	// Order consumer adapter for async event processing
@@ -59,7 +83,8 @@ func main() {
 		cfg.RabbitMQ.ExchangeName,
 		cfg.RabbitMQ.ConsumerQueueName,
 		cfg.RabbitMQ.ConsumerRoutingKey,
-		orderService,
+		tracing.InstrumentOrderEventHandler(orderService, cfg.RabbitMQ.ConsumerQueueName),
+		domain.EventCodecs{drivenadapters.ProtobufEventCodec{}},
 	)
 	if err != nil {
 		log.Fatalf("Failed to create order consumer adapter: %v", err)
@@ -78,6 +103,13 @@ func main() {
 	// Set up graceful shutdown
 	setupGracefulShutdown(cancel)
 
+	// Export the spans still buffered
+	tracingCtx, tracingCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer tracingCancel()
+	if err := shutdownTracing(tracingCtx); err != nil {
+		log.Printf("Failed to shut down tracing: %v", err)
+	}
+
 	log.Println("Order management application shut down gracefully.")
 }
 
@@ -0,0 +1,51 @@
+package application
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// BatchClosingScheduler periodically closes pending batches that are full or too old
+// according to the closing policy of the batch service
+type BatchClosingScheduler struct {
+	batchService *BatchService
+	interval     time.Duration
+}
+
+// NewBatchClosingScheduler creates a new BatchClosingScheduler
+func NewBatchClosingScheduler(batchService *BatchService, interval time.Duration) *BatchClosingScheduler {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	return &BatchClosingScheduler{
+		batchService: batchService,
+		interval:     interval,
+	}
+}
+
+// Start checks the pending batches on every interval until the context is cancelled
+func (s *BatchClosingScheduler) Start(ctx context.Context) {
+	log.Printf("Starting batch closing scheduler (interval: %s)", s.interval)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Batch closing scheduler stopping...")
+			return
+		case <-ticker.C:
+			closed, err := s.batchService.CloseDueBatches()
+			if err != nil {
+				log.Printf("Error closing due batches: %v", err)
+				continue
+			}
+			if closed > 0 {
+				log.Printf("Closed %d batches and started processing them", closed)
+			}
+		}
+	}
+}
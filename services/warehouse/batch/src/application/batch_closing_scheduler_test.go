@@ -0,0 +1,92 @@
+package application
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+	drivenadapters "github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/infrastructure/driven-adapters"
+)
+
+func TestBatchService_ClosesFullBatchOnNextOrder(t *testing.T) {
+	repo := drivenadapters.NewBatchMemoryRepository()
+	mockPublisher := domain.NewMockBatchEventPublisher()
+	service := NewBatchService(repo, mockPublisher, WithClosingPolicy(domain.BatchClosingPolicy{MaxItems: 2}))
+
+	first, _ := service.AddOrderToBatch("order-1", "product-1", 1, "allocated")
+	service.AddOrderToBatch("order-2", "product-1", 1, "allocated")
+
+	next, err := service.AddOrderToBatch("order-3", "product-1", 1, "allocated")
+	if err != nil {
+		t.Fatalf("Failed to add order: %v", err)
+	}
+	if next.ID == first.ID {
+		t.Fatal("Expected the third order to start a new batch")
+	}
+
+	closed, err := repo.FindByID(first.ID)
+	if err != nil {
+		t.Fatalf("Failed to find closed batch: %v", err)
+	}
+	if closed.Status != domain.BatchStatusProcessing || len(closed.Items) != 2 {
+		t.Errorf("Expected full batch to be processing with 2 items, got %s with %d items", closed.Status, len(closed.Items))
+	}
+}
+
+func TestBatchService_CloseDueBatches(t *testing.T) {
+	repo := drivenadapters.NewBatchMemoryRepository()
+	mockPublisher := domain.NewMockBatchEventPublisher()
+	service := NewBatchService(repo, mockPublisher, WithClosingPolicy(domain.BatchClosingPolicy{MaxAge: time.Hour}))
+
+	oldBatch, _ := service.AddOrderToBatch("order-1", "product-1", 1, "allocated")
+	oldBatch.CreatedAt = time.Now().Add(-2 * time.Hour)
+	if err := repo.Save(oldBatch); err != nil {
+		t.Fatalf("Failed to save batch: %v", err)
+	}
+	freshBatch, _ := service.AddOrderToBatch("order-2", "product-2", 1, "allocated")
+
+	service.OutboxRelay().RelayPending()
+	mockPublisher.Reset()
+
+	closed, err := service.CloseDueBatches()
+	if err != nil {
+		t.Fatalf("Failed to close due batches: %v", err)
+	}
+	if closed != 1 {
+		t.Fatalf("Expected 1 closed batch, got %d", closed)
+	}
+
+	if batch, _ := repo.FindByID(oldBatch.ID); batch.Status != domain.BatchStatusProcessing {
+		t.Errorf("Expected old batch to be processing, got %s", batch.Status)
+	}
+	if batch, _ := repo.FindByID(freshBatch.ID); batch.Status != domain.BatchStatusPending {
+		t.Errorf("Expected fresh batch to stay pending, got %s", batch.Status)
+	}
+
+	service.OutboxRelay().RelayPending()
+	events := mockPublisher.GetEventsByType(domain.BatchEventProcessing)
+	if len(events) != 1 || events[0].BatchID != oldBatch.ID {
+		t.Errorf("Expected one processing_started event for the old batch, got %+v", events)
+	}
+}
+
+func TestBatchClosingScheduler_Start(t *testing.T) {
+	repo := drivenadapters.NewBatchMemoryRepository()
+	service := NewBatchService(repo, domain.NewMockBatchEventPublisher(), WithClosingPolicy(domain.BatchClosingPolicy{MaxTotalQuantity: 5}))
+
+	batch, _ := service.AddOrderToBatch("order-1", "product-1", 5, "allocated")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go NewBatchClosingScheduler(service, time.Millisecond).Start(ctx)
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if stored, _ := repo.FindByID(batch.ID); stored.Status == domain.BatchStatusProcessing {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Error("Expected the scheduler to close the full batch")
+}
@@ -1,6 +1,9 @@
 package application
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"log"
 	"time"
@@ -8,244 +11,880 @@ import (
 	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
 )
 
-// BatchService handles business logic for batch operations
+// BatchService handles business logic for batch operations.
+// Batch events are stored in the repository outbox together with the batch
+// change and delivered to the event publisher by the outbox relay.
 type BatchService struct {
-	batchRepo      domain.BatchRepository
-	eventPublisher domain.BatchEventPublisher
+	batchRepo     domain.BatchRepository
+	outboxRelay   *OutboxRelay
+	closingPolicy domain.BatchClosingPolicy
+
+	// conflictRetries is how many times a command is retried after a concurrency conflict
+	conflictRetries int
+
+	// expiryWarningWindow is how close to its expiration a lot is reported as expiring
+	expiryWarningWindow time.Duration
+
+	// inventoryService writes off the stock of orders written off or destroyed in
+	// quarantine; without it the stock ledger is not adjusted
+	inventoryService *InventoryService
+
+	// issuer is recorded in the audit trail of the batches this service changes
+	issuer domain.BatchIssuer
+
+	// tracer records a span for every command, continuing the trace of the issuer
+	tracer domain.Tracer
+}
+
+// ClosingPolicyActor is the actor recorded for the batches closed by the closing policy
+const ClosingPolicyActor = "closing-policy"
+
+// DefaultConflictRetries is how many times a command is retried by default when the
+// batch it changes was modified concurrently
+const DefaultConflictRetries = 5
+
+// MaxBatchHistoryPageSize is the largest page of the audit trail of a batch returned at once
+const MaxBatchHistoryPageSize = 200
+
+// DefaultExpiryWarningWindow is how close to its expiration a lot is reported as
+// expiring by default
+const DefaultExpiryWarningWindow = 30 * 24 * time.Hour
+
+// BatchServiceOption configures optional BatchService behaviour
+type BatchServiceOption func(*batchServiceOptions)
+
+type batchServiceOptions struct {
+	outboxRelayConfig   OutboxRelayConfig
+	closingPolicy       domain.BatchClosingPolicy
+	conflictRetries     int
+	expiryWarningWindow time.Duration
+	inventoryService    *InventoryService
+	tracer              domain.Tracer
+}
+
+// WithOutboxRelayConfig overrides the default outbox relay settings
+func WithOutboxRelayConfig(config OutboxRelayConfig) BatchServiceOption {
+	return func(o *batchServiceOptions) {
+		o.outboxRelayConfig = config
+	}
+}
+
+// WithClosingPolicy limits how many orders a pending batch accepts before it is closed
+func WithClosingPolicy(policy domain.BatchClosingPolicy) BatchServiceOption {
+	return func(o *batchServiceOptions) {
+		o.closingPolicy = policy
+	}
+}
+
+// WithConflictRetries sets how many times a command is retried when the batch it
+// changes was modified concurrently
+func WithConflictRetries(retries int) BatchServiceOption {
+	return func(o *batchServiceOptions) {
+		o.conflictRetries = retries
+	}
+}
+
+// WithExpiryWarningWindow sets how close to its expiration a lot is reported as expiring
+func WithExpiryWarningWindow(window time.Duration) BatchServiceOption {
+	return func(o *batchServiceOptions) {
+		o.expiryWarningWindow = window
+	}
+}
+
+// WithStockLedger writes off the reserved stock of the orders written off or destroyed
+// when the quarantine of a batch is resolved
+func WithStockLedger(inventoryService *InventoryService) BatchServiceOption {
+	return func(o *batchServiceOptions) {
+		o.inventoryService = inventoryService
+	}
+}
+
+// WithTracer records a span for every command of the service
+func WithTracer(tracer domain.Tracer) BatchServiceOption {
+	return func(o *batchServiceOptions) {
+		o.tracer = tracer
+	}
 }
 
 // NewBatchService creates a new BatchService
-func NewBatchService(batchRepo domain.BatchRepository, eventPublisher domain.BatchEventPublisher) *BatchService {
+func NewBatchService(batchRepo domain.BatchRepository, eventPublisher domain.BatchEventPublisher, opts ...BatchServiceOption) *BatchService {
+	options := batchServiceOptions{
+		outboxRelayConfig:   DefaultOutboxRelayConfig(),
+		conflictRetries:     DefaultConflictRetries,
+		expiryWarningWindow: DefaultExpiryWarningWindow,
+		tracer:              domain.NoopTracer{},
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	return &BatchService{
-		batchRepo:      batchRepo,
-		eventPublisher: eventPublisher,
+		batchRepo:           batchRepo,
+		outboxRelay:         NewOutboxRelay(batchRepo, eventPublisher, options.outboxRelayConfig),
+		closingPolicy:       options.closingPolicy,
+		conflictRetries:     options.conflictRetries,
+		expiryWarningWindow: options.expiryWarningWindow,
+		inventoryService:    options.inventoryService,
+		tracer:              options.tracer,
 	}
 }
 
+// OutboxRelay returns the relay that delivers the batch events stored by this service
+func (s *BatchService) OutboxRelay() *OutboxRelay {
+	return s.outboxRelay
+}
+
+// IssuedBy returns a service whose commands are recorded in the audit trail of the
+// batches as issued by the given actor or source event
+func (s *BatchService) IssuedBy(issuer domain.BatchIssuer) BatchServiceInterface {
+	issued := *s
+	issued.issuer = issuer
+	return &issued
+}
+
+// command describes a command of this service for the audit trail
+func (s *BatchService) command(name domain.BatchCommandName) domain.BatchCommand {
+	return domain.BatchCommand{Name: name, BatchIssuer: s.issuer}
+}
+
+// trace starts the span of a command in the trace of the issuer. It returns the service
+// issuing the command within the span, so the events of the command carry its trace, and
+// the function that ends the span.
+func (s *BatchService) trace(name string, attributes map[string]string) (*BatchService, func(error)) {
+	traceContext, end := s.tracer.StartSpan(s.issuer.TraceContext, "BatchService."+name, attributes)
+	traced := *s
+	traced.issuer.TraceContext = traceContext
+	return &traced, end
+}
+
+// CreateLotBatch registers a manufacturing lot of a product as a new pending batch,
+// so orders for the product are allocated from it first-expired-first-out
+func (s *BatchService) CreateLotBatch(productID, lotNumber string, manufacturedAt, expiresAt time.Time) (_ *domain.Batch, err error) {
+	s, end := s.trace("CreateLotBatch", map[string]string{"product.id": productID, "batch.lot_number": lotNumber})
+	defer func() { end(err) }()
+
+	log.Printf("Creating batch for lot %s of product %s (expires: %s)",
+		lotNumber, productID, expiresAt.Format(time.RFC3339))
+
+	existing, err := s.batchRepo.FindByProductID(productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find batches for product %s: %w", productID, err)
+	}
+	for _, batch := range existing {
+		if batch.LotNumber != "" && batch.LotNumber == lotNumber && batch.Status != domain.BatchStatusCancelled {
+			return nil, domain.NewAlreadyExistsError("lot %s of product %s is already registered as batch %s", lotNumber, productID, batch.ID)
+		}
+	}
+
+	batch, err := domain.NewLotBatch(s.generateBatchID(productID), productID, lotNumber, manufacturedAt, expiresAt, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.batchRepo.SaveWithEvents(batch, s.command(domain.BatchCommandCreateLot), domain.NewBatchCreatedEvent(batch)); err != nil {
+		return nil, fmt.Errorf("failed to save batch: %w", err)
+	}
+	s.outboxRelay.Notify()
+
+	log.Printf("Created batch %s for lot %s of product %s", batch.ID, lotNumber, productID)
+	return batch, nil
+}
+
 // AddOrderToBatch adds an order to an appropriate batch
-func (s *BatchService) AddOrderToBatch(orderID, productID string, quantity int, status string) (*domain.Batch, error) {
+func (s *BatchService) AddOrderToBatch(orderID, productID string, quantity int, status domain.ItemStatus) (*domain.Batch, error) {
+	return s.AddOrderToBatchForCustomer(orderID, "", productID, quantity, status)
+}
+
+// AddOrderToBatchForCustomer adds an order to an appropriate batch, recording the
+// customer of the order so that recalls can reach it
+func (s *BatchService) AddOrderToBatchForCustomer(orderID, customerID, productID string, quantity int, status domain.ItemStatus) (_ *domain.Batch, err error) {
+	s, end := s.trace("AddOrderToBatchForCustomer", map[string]string{"order.id": orderID, "product.id": productID})
+	defer func() { end(err) }()
+
 	log.Printf("Adding order %s to batch for product %s (quantity: %d, status: %s)", 
 		orderID, productID, quantity, status)
 
-	// Try to find an existing pending batch for this product
-	batch, err := s.batchRepo.FindPendingBatchForProduct(productID)
-	isNewBatch := false
+	var batch *domain.Batch
+	err = s.retryOnConflict(fmt.Sprintf("add order %s", orderID), func() error {
+		var err error
+		batch, err = s.addOrderToBatch(orderID, customerID, productID, quantity, status)
+		return err
+	})
 	if err != nil {
-		// No pending batch found, create a new one
+		return nil, err
+	}
+	s.outboxRelay.Notify()
+
+	log.Printf("Successfully added order %s to batch %s", orderID, batch.ID)
+	return batch, nil
+}
+
+// addOrderToBatch allocates the order to a pending batch of the product and saves
+// the batch with its events
+func (s *BatchService) addOrderToBatch(orderID, customerID, productID string, quantity int, status domain.ItemStatus) (*domain.Batch, error) {
+	now := time.Now()
+	batch, err := s.batchRepo.FindByOrderID(orderID)
+	switch {
+	case err == nil:
+		// The order keeps its batch and its line is updated in place, even beyond the
+		// closing limits: moving it would leave the order in two batches
+		log.Printf("Updating order %s in its batch %s", orderID, batch.ID)
+	case errors.Is(err, domain.ErrNotFound):
+		candidates, err := s.batchRepo.FindPendingBatchesForProduct(productID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find pending batches for product %s: %w", productID, err)
+		}
+		if batch, err = s.allocateBatch(candidates, orderID, productID, quantity, now); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("failed to find batch for order %s: %w", orderID, err)
+	}
+
+	isNewBatch := false
+	if batch == nil {
+		// No pending batch can take the order, create a new one
 		batchID := s.generateBatchID(productID)
 		batch = domain.NewBatch(batchID, productID)
 		isNewBatch = true
 		log.Printf("Created new batch %s for product %s", batchID, productID)
-	} else {
+	} else if !batch.HasOrder(orderID) {
 		log.Printf("Found existing pending batch %s for product %s", batch.ID, productID)
 	}
 
+	if batch.ExpiresWithin(now, s.expiryWarningWindow) {
+		log.Printf("Warning: allocating order %s from lot %s of batch %s, which expires on %s",
+			orderID, batch.LotNumber, batch.ID, batch.ExpiresAt.Format(time.RFC3339))
+	}
+
 	// Add the order to the batch
 	if err := batch.AddItem(orderID, productID, quantity, status); err != nil {
 		return nil, fmt.Errorf("failed to add order to batch: %w", err)
 	}
-
-	// Save the batch
-	if err := s.batchRepo.Save(batch); err != nil {
-		return nil, fmt.Errorf("failed to save batch: %w", err)
+	if err := batch.SetItemCustomer(orderID, customerID); err != nil {
+		return nil, fmt.Errorf("failed to record customer of order: %w", err)
 	}
 
-	// Publish events
+	// Collect the events to store with the batch
+	var events []*domain.BatchEvent
 	if isNewBatch {
-		// Publish batch created event
-		if err := s.eventPublisher.PublishBatchEvent(domain.NewBatchCreatedEvent(batch)); err != nil {
-			log.Printf("Failed to publish batch created event: %v", err)
-		}
+		events = append(events, domain.NewBatchCreatedEvent(batch))
 	}
 
-	// Get the added item for the event
 	item, err := batch.GetItemByOrderID(orderID)
 	if err != nil {
-		log.Printf("Failed to get item for event publishing: %v", err)
-	} else {
-		// Publish item added event
-		if err := s.eventPublisher.PublishBatchEvent(domain.NewBatchItemAddedEvent(batch, orderID, item)); err != nil {
-			log.Printf("Failed to publish batch item added event: %v", err)
-		}
+		return nil, fmt.Errorf("failed to get added item: %w", err)
+	}
+	events = append(events, domain.NewBatchItemAddedEvent(batch, orderID, item))
+
+	// Save the batch together with its events
+	if err := s.batchRepo.SaveWithEvents(batch, s.command(domain.BatchCommandAddOrder), events...); err != nil {
+		return nil, fmt.Errorf("failed to save batch: %w", err)
 	}
 
-	log.Printf("Successfully added order %s to batch %s", orderID, batch.ID)
 	return batch, nil
 }
 
+// allocateBatch picks the pending batch a new order is allocated to: the unexpired
+// lot that expires first (first-expired-first-out). Expired lots are skipped and
+// batches that cannot accept the order are closed on the way. It returns nil when a
+// new batch has to be created, e.g. when the only lots left for the product are expired.
+func (s *BatchService) allocateBatch(candidates []*domain.Batch, orderID, productID string, quantity int, now time.Time) (*domain.Batch, error) {
+	for _, batch := range candidates {
+		if batch.Status != domain.BatchStatusPending {
+			continue
+		}
+		if batch.IsExpired(now) {
+			log.Printf("Skipping expired lot %s of batch %s for order %s", batch.LotNumber, batch.ID, orderID)
+			continue
+		}
+		if !s.closingPolicy.CanAccept(batch, orderID, quantity, now) {
+			// The batch is full or too old: close it and try the next one
+			if err := s.closeBatch(batch, "cannot accept order "+orderID, s.command(domain.BatchCommandClose)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		return batch, nil
+	}
+	return nil, nil
+}
+
 // RemoveOrderFromBatch removes an order from its batch
-func (s *BatchService) RemoveOrderFromBatch(orderID string) error {
+func (s *BatchService) RemoveOrderFromBatch(orderID string) (err error) {
+	s, end := s.trace("RemoveOrderFromBatch", map[string]string{"order.id": orderID})
+	defer func() { end(err) }()
+
 	log.Printf("Removing order %s from batch", orderID)
 
-	// Find the batch containing this order
-	batch, err := s.batchRepo.FindByOrderID(orderID)
+	var batch *domain.Batch
+	err = s.retryOnConflict(fmt.Sprintf("remove order %s", orderID), func() error {
+		// Find the batch containing this order
+		var err error
+		batch, err = s.batchRepo.FindByOrderID(orderID)
+		if err != nil {
+			return fmt.Errorf("failed to find batch for order %s: %w", orderID, err)
+		}
+
+		// Remove the order from the batch
+		if err := batch.RemoveItem(orderID); err != nil {
+			return fmt.Errorf("failed to remove order from batch: %w", err)
+		}
+
+		event := domain.NewBatchItemRemovedEvent(batch, orderID)
+
+		// If batch is empty, delete it; otherwise save the updated batch
+		if batch.IsEmpty() {
+			log.Printf("Batch %s is now empty, deleting it", batch.ID)
+			if err := s.batchRepo.DeleteWithEvents(batch, s.command(domain.BatchCommandRemoveOrder), event); err != nil {
+				return fmt.Errorf("failed to delete empty batch: %w", err)
+			}
+		} else {
+			if err := s.batchRepo.SaveWithEvents(batch, s.command(domain.BatchCommandRemoveOrder), event); err != nil {
+				return fmt.Errorf("failed to save updated batch: %w", err)
+			}
+		}
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("failed to find batch for order %s: %w", orderID, err)
+		return err
 	}
+	s.outboxRelay.Notify()
 
-	// Remove the order from the batch
-	if err := batch.RemoveItem(orderID); err != nil {
-		return fmt.Errorf("failed to remove order from batch: %w", err)
-	}
+	log.Printf("Successfully removed order %s from batch %s", orderID, batch.ID)
+	return nil
+}
 
-	// Publish item removed event
-	if err := s.eventPublisher.PublishBatchEvent(domain.NewBatchItemRemovedEvent(batch, orderID)); err != nil {
-		log.Printf("Failed to publish batch item removed event: %v", err)
-	}
+// UpdateOrderStatus updates the status of an order within its batch
+func (s *BatchService) UpdateOrderStatus(orderID string, status domain.ItemStatus) (err error) {
+	s, end := s.trace("UpdateOrderStatus", map[string]string{"order.id": orderID, "batch.item_status": string(status)})
+	defer func() { end(err) }()
 
-	// If batch is empty, delete it; otherwise save the updated batch
-	if batch.IsEmpty() {
-		log.Printf("Batch %s is now empty, deleting it", batch.ID)
-		if err := s.batchRepo.Delete(batch.ID); err != nil {
-			return fmt.Errorf("failed to delete empty batch: %w", err)
+	log.Printf("Updating order %s status to %s", orderID, status)
+
+	var batch *domain.Batch
+	err = s.retryOnConflict(fmt.Sprintf("update order %s", orderID), func() error {
+		// Find the batch containing this order
+		var err error
+		batch, err = s.batchRepo.FindByOrderID(orderID)
+		if err != nil {
+			return fmt.Errorf("failed to find batch for order %s: %w", orderID, err)
 		}
-	} else {
-		if err := s.batchRepo.Save(batch); err != nil {
+
+		// Update the order status
+		if err := batch.UpdateItemStatus(orderID, status); err != nil {
+			return fmt.Errorf("failed to update order status: %w", err)
+		}
+
+		item, err := batch.GetItemByOrderID(orderID)
+		if err != nil {
+			return fmt.Errorf("failed to get updated item: %w", err)
+		}
+
+		// Save the updated batch together with the item updated event
+		if err := s.batchRepo.SaveWithEvents(batch, s.command(domain.BatchCommandUpdateOrder), domain.NewBatchItemUpdatedEvent(batch, orderID, item)); err != nil {
 			return fmt.Errorf("failed to save updated batch: %w", err)
 		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
+	s.outboxRelay.Notify()
 
-	log.Printf("Successfully removed order %s from batch %s", orderID, batch.ID)
+	log.Printf("Successfully updated order %s status to %s in batch %s", orderID, status, batch.ID)
 	return nil
 }
 
-// UpdateOrderStatus updates the status of an order within its batch
-func (s *BatchService) UpdateOrderStatus(orderID, status string) error {
-	log.Printf("Updating order %s status to %s", orderID, status)
+// ProcessBatch starts processing a batch
+func (s *BatchService) ProcessBatch(batchID string) (err error) {
+	s, end := s.trace("ProcessBatch", map[string]string{"batch.id": batchID})
+	defer func() { end(err) }()
 
-	// Find the batch containing this order
-	batch, err := s.batchRepo.FindByOrderID(orderID)
+	log.Printf("Starting to process batch %s", batchID)
+
+	err = s.retryOnConflict(fmt.Sprintf("process batch %s", batchID), func() error {
+		batch, err := s.batchRepo.FindByID(batchID)
+		if err != nil {
+			return fmt.Errorf("failed to find batch %s: %w", batchID, err)
+		}
+
+		if err := batch.StartProcessing(); err != nil {
+			return fmt.Errorf("failed to start processing batch: %w", err)
+		}
+
+		if err := s.batchRepo.SaveWithEvents(batch, s.command(domain.BatchCommandProcess), domain.NewBatchProcessingStartedEvent(batch)); err != nil {
+			return fmt.Errorf("failed to save batch: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("failed to find batch for order %s: %w", orderID, err)
+		return err
 	}
+	s.outboxRelay.Notify()
 
-	// Update the order status
-	if err := batch.UpdateItemStatus(orderID, status); err != nil {
-		return fmt.Errorf("failed to update order status: %w", err)
-	}
+	log.Printf("Successfully started processing batch %s", batchID)
+	return nil
+}
 
-	// Save the updated batch
-	if err := s.batchRepo.Save(batch); err != nil {
-		return fmt.Errorf("failed to save updated batch: %w", err)
-	}
+// CompleteBatch marks a batch as completed
+func (s *BatchService) CompleteBatch(batchID string) (err error) {
+	s, end := s.trace("CompleteBatch", map[string]string{"batch.id": batchID})
+	defer func() { end(err) }()
 
-	// Get the updated item for the event
-	item, err := batch.GetItemByOrderID(orderID)
-	if err != nil {
-		log.Printf("Failed to get updated item for event publishing: %v", err)
-	} else {
-		// Publish item updated event
-		if err := s.eventPublisher.PublishBatchEvent(domain.NewBatchItemUpdatedEvent(batch, orderID, item)); err != nil {
-			log.Printf("Failed to publish batch item updated event: %v", err)
+	log.Printf("Completing batch %s", batchID)
+
+	err = s.retryOnConflict(fmt.Sprintf("complete batch %s", batchID), func() error {
+		batch, err := s.batchRepo.FindByID(batchID)
+		if err != nil {
+			return fmt.Errorf("failed to find batch %s: %w", batchID, err)
 		}
+
+		if err := batch.Complete(); err != nil {
+			return fmt.Errorf("failed to complete batch: %w", err)
+		}
+
+		if err := s.batchRepo.SaveWithEvents(batch, s.command(domain.BatchCommandComplete), domain.NewBatchCompletedEvent(batch)); err != nil {
+			return fmt.Errorf("failed to save batch: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
+	s.outboxRelay.Notify()
 
-	log.Printf("Successfully updated order %s status to %s in batch %s", orderID, status, batch.ID)
+	log.Printf("Successfully completed batch %s", batchID)
 	return nil
 }
 
-// ProcessBatch starts processing a batch
-func (s *BatchService) ProcessBatch(batchID string) error {
-	log.Printf("Starting to process batch %s", batchID)
+// CancelBatch cancels a batch
+func (s *BatchService) CancelBatch(batchID string) (err error) {
+	s, end := s.trace("CancelBatch", map[string]string{"batch.id": batchID})
+	defer func() { end(err) }()
 
-	batch, err := s.batchRepo.FindByID(batchID)
+	log.Printf("Cancelling batch %s", batchID)
+
+	err = s.retryOnConflict(fmt.Sprintf("cancel batch %s", batchID), func() error {
+		batch, err := s.batchRepo.FindByID(batchID)
+		if err != nil {
+			return fmt.Errorf("failed to find batch %s: %w", batchID, err)
+		}
+
+		if err := batch.Cancel(); err != nil {
+			return fmt.Errorf("failed to cancel batch: %w", err)
+		}
+
+		if err := s.batchRepo.SaveWithEvents(batch, s.command(domain.BatchCommandCancel), domain.NewBatchCancelledEvent(batch)); err != nil {
+			return fmt.Errorf("failed to save batch: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("failed to find batch %s: %w", batchID, err)
+		return err
 	}
+	s.outboxRelay.Notify()
 
-	if err := batch.StartProcessing(); err != nil {
-		return fmt.Errorf("failed to start processing batch: %w", err)
+	log.Printf("Successfully cancelled batch %s", batchID)
+	return nil
+}
+
+// MarkBatchAsDamaged marks a batch as damaged
+func (s *BatchService) MarkBatchAsDamaged(batchID string) (err error) {
+	s, end := s.trace("MarkBatchAsDamaged", map[string]string{"batch.id": batchID})
+	defer func() { end(err) }()
+
+	log.Printf("Marking batch %s as damaged", batchID)
+
+	err = s.retryOnConflict(fmt.Sprintf("mark batch %s as damaged", batchID), func() error {
+		batch, err := s.batchRepo.FindByID(batchID)
+		if err != nil {
+			return fmt.Errorf("failed to find batch %s: %w", batchID, err)
+		}
+
+		if err := batch.MarkAsDamaged(); err != nil {
+			return fmt.Errorf("failed to mark batch as damaged: %w", err)
+		}
+
+		if err := s.batchRepo.SaveWithEvents(batch, s.command(domain.BatchCommandMarkDamaged), domain.NewBatchDamagedEvent(batch)); err != nil {
+			return fmt.Errorf("failed to save batch: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
+	s.outboxRelay.Notify()
 
-	if err := s.batchRepo.Save(batch); err != nil {
-		return fmt.Errorf("failed to save batch: %w", err)
+	log.Printf("Successfully marked batch %s as damaged", batchID)
+	return nil
+}
+
+// InspectBatch records the findings of the inspection of a quarantined batch
+func (s *BatchService) InspectBatch(batchID, inspector, findings string) (err error) {
+	s, end := s.trace("InspectBatch", map[string]string{"batch.id": batchID})
+	defer func() { end(err) }()
+
+	log.Printf("Recording inspection of batch %s by %s", batchID, inspector)
+
+	err = s.retryOnConflict(fmt.Sprintf("inspect batch %s", batchID), func() error {
+		batch, err := s.batchRepo.FindByID(batchID)
+		if err != nil {
+			return fmt.Errorf("failed to find batch %s: %w", batchID, err)
+		}
+
+		if err := batch.RecordInspection(inspector, findings, time.Now()); err != nil {
+			return fmt.Errorf("failed to record inspection: %w", err)
+		}
+
+		if err := s.batchRepo.SaveWithEvents(batch, s.command(domain.BatchCommandInspect), domain.NewBatchInspectedEvent(batch)); err != nil {
+			return fmt.Errorf("failed to save batch: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
+	s.outboxRelay.Notify()
+
+	log.Printf("Successfully recorded inspection of batch %s", batchID)
+	return nil
+}
+
+// ReleaseBatch returns an inspected batch to stock
+func (s *BatchService) ReleaseBatch(batchID, decidedBy, reason string) (err error) {
+	s, end := s.trace("ReleaseBatch", map[string]string{"batch.id": batchID})
+	defer func() { end(err) }()
+	return s.disposeBatch(batchID, domain.QuarantineOutcomeReleased, domain.BatchCommandRelease, func(batch *domain.Batch, now time.Time) ([]string, error) {
+		return batch.ReleaseFromQuarantine(decidedBy, reason, now)
+	})
+}
 
-	// Publish processing started event
-	if err := s.eventPublisher.PublishBatchEvent(domain.NewBatchProcessingStartedEvent(batch)); err != nil {
-		log.Printf("Failed to publish batch processing started event: %v", err)
+// WriteOffBatchItems writes off the given orders of an inspected batch and returns the
+// rest of the batch to stock
+func (s *BatchService) WriteOffBatchItems(batchID string, orderIDs []string, decidedBy, reason string) (err error) {
+	s, end := s.trace("WriteOffBatchItems", map[string]string{"batch.id": batchID})
+	defer func() { end(err) }()
+	return s.disposeBatch(batchID, domain.QuarantineOutcomePartialWriteOff, domain.BatchCommandWriteOff, func(batch *domain.Batch, now time.Time) ([]string, error) {
+		return batch.WriteOff(orderIDs, decidedBy, reason, now)
+	})
+}
+
+// DestroyBatch destroys an inspected batch
+func (s *BatchService) DestroyBatch(batchID, decidedBy, reason string) (err error) {
+	s, end := s.trace("DestroyBatch", map[string]string{"batch.id": batchID})
+	defer func() { end(err) }()
+	return s.disposeBatch(batchID, domain.QuarantineOutcomeDestroyed, domain.BatchCommandDestroy, func(batch *domain.Batch, now time.Time) ([]string, error) {
+		return batch.Destroy(decidedBy, reason, now)
+	})
+}
+
+// disposeBatch ends the quarantine of a batch with the given outcome, storing the
+// outcome event together with an item updated event for every changed order. The
+// stock of the orders written off or destroyed is then written off the stock ledger;
+// a write-off that fails there is finished later by ReconcileDisposedStock.
+func (s *BatchService) disposeBatch(batchID string, outcome domain.QuarantineOutcome, command domain.BatchCommandName, dispose func(*domain.Batch, time.Time) ([]string, error)) error {
+	log.Printf("Resolving quarantine of batch %s: %s", batchID, outcome)
+
+	var (
+		batch   *domain.Batch
+		changed []string
+	)
+	err := s.retryOnConflict(fmt.Sprintf("resolve quarantine of batch %s", batchID), func() error {
+		var err error
+		batch, err = s.batchRepo.FindByID(batchID)
+		if err != nil {
+			return fmt.Errorf("failed to find batch %s: %w", batchID, err)
+		}
+
+		changed, err = dispose(batch, time.Now())
+		if err != nil {
+			return fmt.Errorf("failed to resolve quarantine: %w", err)
+		}
+
+		events := []*domain.BatchEvent{domain.NewBatchDispositionEvent(batch, outcome)}
+		for _, orderID := range changed {
+			item, err := batch.GetItemByOrderID(orderID)
+			if err != nil {
+				return fmt.Errorf("failed to get updated item: %w", err)
+			}
+			events = append(events, domain.NewBatchItemUpdatedEvent(batch, orderID, item))
+		}
+
+		if err := s.batchRepo.SaveWithEvents(batch, s.command(command), events...); err != nil {
+			return fmt.Errorf("failed to save batch: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
+	s.outboxRelay.Notify()
 
-	log.Printf("Successfully started processing batch %s", batchID)
+	s.writeOffDisposedStock(batch, changed)
+
+	log.Printf("Successfully resolved quarantine of batch %s: %s", batchID, outcome)
 	return nil
 }
 
-// CompleteBatch marks a batch as completed
-func (s *BatchService) CompleteBatch(batchID string) error {
-	log.Printf("Completing batch %s", batchID)
+// writeOffDisposedStock writes off the reserved stock of the changed orders that were
+// written off or destroyed. The disposition is already stored, so a failed write-off
+// is only logged and the stock stays reserved until ReconcileDisposedStock writes it off.
+func (s *BatchService) writeOffDisposedStock(batch *domain.Batch, changed []string) {
+	if s.inventoryService == nil {
+		return
+	}
 
-	batch, err := s.batchRepo.FindByID(batchID)
+	for _, orderID := range changed {
+		item, err := batch.GetItemByOrderID(orderID)
+		if err != nil || !isDisposedItem(item) {
+			continue
+		}
+
+		if err := s.writeOffDisposedOrder(batch, orderID); err != nil {
+			log.Printf("Quarantine of batch %s was resolved but the stock of order %s was not written off, leaving it to the reconciliation: %v",
+				batch.ID, orderID, err)
+		}
+	}
+}
+
+// ReconcileDisposedStock writes off the stock still reserved for orders that were
+// written off or destroyed in quarantine, finishing the write-offs that failed after
+// their disposition was stored. It returns how many orders were written off.
+func (s *BatchService) ReconcileDisposedStock() (int, error) {
+	if s.inventoryService == nil {
+		return 0, nil
+	}
+
+	batches, err := s.batchRepo.GetAll()
 	if err != nil {
-		return fmt.Errorf("failed to find batch %s: %w", batchID, err)
+		return 0, fmt.Errorf("failed to get batches: %w", err)
 	}
 
-	if err := batch.Complete(); err != nil {
-		return fmt.Errorf("failed to complete batch: %w", err)
+	writtenOff := 0
+	for _, batch := range batches {
+		if batch.Disposition == nil {
+			continue
+		}
+
+		for _, item := range batch.Items {
+			if !isDisposedItem(&item) {
+				continue
+			}
+
+			reservation, err := s.inventoryService.GetReservation(item.OrderID)
+			if errors.Is(err, domain.ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return writtenOff, fmt.Errorf("failed to find stock reservation of order %s: %w", item.OrderID, err)
+			}
+			if reservation.Status != domain.StockReservationActive {
+				continue
+			}
+
+			if err := s.writeOffDisposedOrder(batch, item.OrderID); err != nil {
+				return writtenOff, err
+			}
+			log.Printf("Wrote off the stock left reserved for order %s disposed in batch %s", item.OrderID, batch.ID)
+			writtenOff++
+		}
 	}
+	return writtenOff, nil
+}
 
-	if err := s.batchRepo.Save(batch); err != nil {
-		return fmt.Errorf("failed to save batch: %w", err)
+// writeOffDisposedOrder writes off the reserved stock of an order disposed in the
+// quarantine of the batch. Orders without a reservation hold no stock of the ledger.
+func (s *BatchService) writeOffDisposedOrder(batch *domain.Batch, orderID string) error {
+	reason := fmt.Sprintf("%s in quarantine of batch %s: %s", batch.Disposition.Outcome, batch.ID, batch.Disposition.Reason)
+	if _, err := s.inventoryService.WriteOffStock(orderID, reason); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			log.Printf("No stock reserved for disposed order %s", orderID)
+			return nil
+		}
+		return fmt.Errorf("failed to write off stock of order %s disposed in batch %s: %w", orderID, batch.ID, err)
 	}
+	return nil
+}
 
-	// Publish batch completed event
-	if err := s.eventPublisher.PublishBatchEvent(domain.NewBatchCompletedEvent(batch)); err != nil {
-		log.Printf("Failed to publish batch completed event: %v", err)
+// isDisposedItem reports whether the item was written off or destroyed in quarantine
+func isDisposedItem(item *domain.BatchItem) bool {
+	return item.Status == domain.ItemStatusWrittenOff || item.Status == domain.ItemStatusDestroyed
+}
+
+// HoldBatch puts a batch on hold so that it is no longer allocated, processed or shipped
+func (s *BatchService) HoldBatch(batchID, reason string) (err error) {
+	s, end := s.trace("HoldBatch", map[string]string{"batch.id": batchID})
+	defer func() { end(err) }()
+
+	log.Printf("Putting batch %s on hold: %s", batchID, reason)
+
+	err = s.retryOnConflict(fmt.Sprintf("hold batch %s", batchID), func() error {
+		batch, err := s.batchRepo.FindByID(batchID)
+		if err != nil {
+			return fmt.Errorf("failed to find batch %s: %w", batchID, err)
+		}
+
+		if err := batch.PlaceOnHold(reason, time.Now()); err != nil {
+			return fmt.Errorf("failed to put batch on hold: %w", err)
+		}
+
+		if err := s.batchRepo.SaveWithEvents(batch, s.command(domain.BatchCommandHold), domain.NewBatchOnHoldEvent(batch)); err != nil {
+			return fmt.Errorf("failed to save batch: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
+	s.outboxRelay.Notify()
 
-	log.Printf("Successfully completed batch %s", batchID)
+	log.Printf("Successfully put batch %s on hold", batchID)
 	return nil
 }
 
-// CancelBatch cancels a batch
-func (s *BatchService) CancelBatch(batchID string) error {
-	log.Printf("Cancelling batch %s", batchID)
+// CloseDueBatches closes the pending batches that the closing policy considers
+// full or too old and starts processing them. It returns how many batches were closed.
+func (s *BatchService) CloseDueBatches() (int, error) {
+	if !s.closingPolicy.IsEnabled() {
+		return 0, nil
+	}
 
-	batch, err := s.batchRepo.FindByID(batchID)
+	batches, err := s.batchRepo.FindByStatus(domain.BatchStatusPending)
 	if err != nil {
-		return fmt.Errorf("failed to find batch %s: %w", batchID, err)
+		return 0, fmt.Errorf("failed to find pending batches: %w", err)
+	}
+
+	closed := 0
+	now := time.Now()
+	for _, batch := range batches {
+		if shouldClose, _ := s.closingPolicy.ShouldClose(batch, now); !shouldClose {
+			continue
+		}
+
+		// Reload the batch on every attempt, an order may have been added or the
+		// batch processed since it was listed
+		wasClosed := false
+		closing, end := s.trace("CloseBatch", map[string]string{"batch.id": batch.ID})
+		err := closing.retryOnConflict(fmt.Sprintf("close batch %s", batch.ID), func() error {
+			current, err := closing.batchRepo.FindByID(batch.ID)
+			if err != nil {
+				return fmt.Errorf("failed to find batch %s: %w", batch.ID, err)
+			}
+
+			shouldClose, reason := s.closingPolicy.ShouldClose(current, now)
+			if !shouldClose {
+				return nil
+			}
+
+			issuer := domain.BatchIssuer{Actor: ClosingPolicyActor, TraceContext: closing.issuer.TraceContext}
+			command := domain.BatchCommand{Name: domain.BatchCommandClose, BatchIssuer: issuer}
+			if err := closing.closeBatch(current, reason, command); err != nil {
+				return err
+			}
+			wasClosed = true
+			return nil
+		})
+		end(err)
+		if err != nil {
+			log.Printf("Failed to close batch %s: %v", batch.ID, err)
+			continue
+		}
+		if wasClosed {
+			closed++
+		}
 	}
 
-	if err := batch.Cancel(); err != nil {
-		return fmt.Errorf("failed to cancel batch: %w", err)
+	return closed, nil
+}
+
+// retryOnConflict runs the command again with freshly loaded state when the batch
+// was modified concurrently between loading and saving it
+func (s *BatchService) retryOnConflict(command string, fn func() error) error {
+	return retryOnConflict(command, s.conflictRetries, fn)
+}
+
+// retryOnConflict runs fn up to retries more times while it fails with a concurrency conflict
+func retryOnConflict(command string, retries int, fn func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if !errors.Is(err, domain.ErrConcurrencyConflict) || attempt >= retries {
+			return err
+		}
+		log.Printf("Concurrent modification during %s, retrying (%d/%d): %v", command, attempt+1, retries, err)
 	}
+}
 
-	if err := s.batchRepo.Save(batch); err != nil {
-		return fmt.Errorf("failed to save batch: %w", err)
+// closeBatch starts processing a pending batch and stores the processing started event
+func (s *BatchService) closeBatch(batch *domain.Batch, reason string, command domain.BatchCommand) error {
+	log.Printf("Closing batch %s: %s", batch.ID, reason)
+
+	if err := batch.StartProcessing(); err != nil {
+		return fmt.Errorf("failed to close batch %s: %w", batch.ID, err)
 	}
 
-	// Publish batch cancelled event
-	if err := s.eventPublisher.PublishBatchEvent(domain.NewBatchCancelledEvent(batch)); err != nil {
-		log.Printf("Failed to publish batch cancelled event: %v", err)
+	if err := s.batchRepo.SaveWithEvents(batch, command, domain.NewBatchProcessingStartedEvent(batch)); err != nil {
+		return fmt.Errorf("failed to save closed batch %s: %w", batch.ID, err)
 	}
+	s.outboxRelay.Notify()
 
-	log.Printf("Successfully cancelled batch %s", batchID)
 	return nil
 }
 
-// MarkBatchAsDamaged marks a batch as damaged
-func (s *BatchService) MarkBatchAsDamaged(batchID string) error {
-	log.Printf("Marking batch %s as damaged", batchID)
+// GetBatchByID retrieves a batch by its ID
+func (s *BatchService) GetBatchByID(batchID string) (*domain.Batch, error) {
+	return s.batchRepo.FindByID(batchID)
+}
 
-	batch, err := s.batchRepo.FindByID(batchID)
-	if err != nil {
-		return fmt.Errorf("failed to find batch %s: %w", batchID, err)
+// GetBatchAsOf returns a batch as it was at the given time, rebuilt from its history of
+// changes. It needs a batch repository that keeps the change history.
+func (s *BatchService) GetBatchAsOf(batchID string, at time.Time) (*domain.Batch, error) {
+	store, ok := s.batchRepo.(domain.BatchChangeHistory)
+	if !ok {
+		return nil, domain.NewValidationError("the batch repository keeps no history for as-of queries")
 	}
+	return store.FindByIDAt(batchID, at)
+}
 
-	if err := batch.MarkAsDamaged(); err != nil {
-		return fmt.Errorf("failed to mark batch as damaged: %w", err)
+// GetBatchHistory returns a page of the audit trail of a batch, oldest entry first, and
+// the total number of entries. The history of a deleted batch remains available.
+func (s *BatchService) GetBatchHistory(batchID string, offset, limit int) ([]*domain.BatchAuditEntry, int, error) {
+	if offset < 0 {
+		return nil, 0, domain.NewValidationError("offset must not be negative")
+	}
+	if limit < 1 || limit > MaxBatchHistoryPageSize {
+		return nil, 0, domain.NewValidationError("limit must be between 1 and %d", MaxBatchHistoryPageSize)
 	}
 
-	if err := s.batchRepo.Save(batch); err != nil {
-		return fmt.Errorf("failed to save batch: %w", err)
+	entries, total, err := s.batchRepo.FindBatchHistory(batchID, offset, limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to find history of batch %s: %w", batchID, err)
+	}
+	if total == 0 {
+		return nil, 0, domain.NewNotFoundError("no history recorded for batch %s", batchID)
 	}
+	return entries, total, nil
+}
 
-	// Publish batch damaged event
-	if err := s.eventPublisher.PublishBatchEvent(domain.NewBatchDamagedEvent(batch)); err != nil {
-		log.Printf("Failed to publish batch damaged event: %v", err)
+// GetAllowedActions returns the actions the current status of a batch allows
+func (s *BatchService) GetAllowedActions(batchID string) ([]domain.BatchAction, error) {
+	batch, err := s.batchRepo.FindByID(batchID)
+	if err != nil {
+		return nil, err
 	}
+	return batch.AllowedActions(), nil
+}
 
-	log.Printf("Successfully marked batch %s as damaged", batchID)
-	return nil
+// GetBatchesNearExpiry reports the open batches whose lot expires within the window,
+// including the ones already expired. A zero window uses the configured warning window.
+func (s *BatchService) GetBatchesNearExpiry(within time.Duration) ([]*domain.Batch, error) {
+	if within < 0 {
+		return nil, domain.NewValidationError("expiry window cannot be negative: %s", within)
+	}
+	if within == 0 {
+		within = s.expiryWarningWindow
+	}
+	return s.batchRepo.FindExpiringBefore(time.Now().Add(within))
 }
 
 // GetBatchByOrderID retrieves the batch containing a specific order
@@ -268,8 +907,14 @@ func (s *BatchService) GetAllBatches() ([]*domain.Batch, error) {
 	return s.batchRepo.GetAll()
 }
 
-// generateBatchID generates a unique batch ID
+// generateBatchID generates a unique batch ID. A random suffix keeps IDs unique
+// when a batch is closed and replaced within the same second.
 func (s *BatchService) generateBatchID(productID string) string {
 	timestamp := time.Now().Format("20060102150405")
-	return fmt.Sprintf("BATCH-%s-%s", productID, timestamp)
+
+	suffix := make([]byte, 3)
+	if _, err := rand.Read(suffix); err != nil {
+		return fmt.Sprintf("BATCH-%s-%s-%d", productID, timestamp, time.Now().UnixNano()%1000000)
+	}
+	return fmt.Sprintf("BATCH-%s-%s-%s", productID, timestamp, hex.EncodeToString(suffix))
 }
\ No newline at end of file
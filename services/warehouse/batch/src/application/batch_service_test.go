@@ -1,7 +1,11 @@
 package application
 
 import (
+	"errors"
+	"fmt"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
 	drivenadapters "github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/infrastructure/driven-adapters"
@@ -17,7 +21,7 @@ func TestBatchService_AddOrderToBatch(t *testing.T) {
 	orderID := "order-123"
 	productID := "product-456"
 	quantity := 10
-	status := "allocated"
+	status := domain.ItemStatusAllocated
 
 	// Execute
 	batch, err := service.AddOrderToBatch(orderID, productID, quantity, status)
@@ -133,7 +137,7 @@ func TestBatchService_UpdateOrderStatus(t *testing.T) {
 	}
 
 	// Update order status
-	newStatus := "shipped"
+	newStatus := domain.ItemStatusShipped
 	err = service.UpdateOrderStatus(orderID, newStatus)
 	if err != nil {
 		t.Fatalf("Failed to update order status: %v", err)
@@ -220,4 +224,477 @@ func TestBatchService_CompleteBatch(t *testing.T) {
 	if updatedBatch.ProcessedAt == nil {
 		t.Error("Expected ProcessedAt to be set")
 	}
-}
\ No newline at end of file
+}
+func TestBatchService_ConcurrentOrdersDoNotLoseItems(t *testing.T) {
+	repo := drivenadapters.NewBatchMemoryRepository()
+	service := NewBatchService(repo, domain.NewMockBatchEventPublisher(), WithConflictRetries(100))
+
+	const orders = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, orders)
+	for i := 0; i < orders; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := service.AddOrderToBatch(fmt.Sprintf("order-%d", i), "product-1", 1, "allocated"); err != nil {
+				errs <- err
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("Failed to add order: %v", err)
+	}
+
+	batches, _ := repo.FindByProductID("product-1")
+	items := 0
+	for _, batch := range batches {
+		items += len(batch.Items)
+	}
+	if items != orders {
+		t.Errorf("Expected %d items across %d batches, got %d", orders, len(batches), items)
+	}
+}
+
+func TestBatchService_GivesUpAfterConflictRetries(t *testing.T) {
+	repo := &conflictingBatchRepository{BatchMemoryRepository: drivenadapters.NewBatchMemoryRepository()}
+	service := NewBatchService(repo, domain.NewMockBatchEventPublisher(), WithConflictRetries(2))
+
+	_, err := service.AddOrderToBatch("order-1", "product-1", 1, "allocated")
+	if !errors.Is(err, domain.ErrConcurrencyConflict) {
+		t.Fatalf("Expected concurrency conflict, got %v", err)
+	}
+	if repo.saves != 3 {
+		t.Errorf("Expected 1 attempt and 2 retries, got %d saves", repo.saves)
+	}
+}
+
+// conflictingBatchRepository rejects every save as if another writer always won
+type conflictingBatchRepository struct {
+	*drivenadapters.BatchMemoryRepository
+	saves int
+}
+
+func (r *conflictingBatchRepository) SaveWithEvents(batch *domain.Batch, command domain.BatchCommand, events ...*domain.BatchEvent) error {
+	r.saves++
+	return domain.NewConflictError("batch %s was modified concurrently", batch.ID)
+}
+func TestBatchService_AllocatesFirstExpiredFirstOut(t *testing.T) {
+	repo := drivenadapters.NewBatchMemoryRepository()
+	service := NewBatchService(repo, domain.NewMockBatchEventPublisher(),
+		WithClosingPolicy(domain.BatchClosingPolicy{MaxItems: 1}))
+
+	now := time.Now()
+	late, err := service.CreateLotBatch("product-1", "LOT-LATE", now.AddDate(-1, 0, 0), now.AddDate(1, 0, 0))
+	if err != nil {
+		t.Fatalf("Failed to create lot batch: %v", err)
+	}
+	soon, err := service.CreateLotBatch("product-1", "LOT-SOON", now.AddDate(-1, 0, 0), now.AddDate(0, 2, 0))
+	if err != nil {
+		t.Fatalf("Failed to create lot batch: %v", err)
+	}
+
+	if _, err := service.CreateLotBatch("product-1", "LOT-SOON", now.AddDate(-1, 0, 0), now.AddDate(0, 3, 0)); !errors.Is(err, domain.ErrAlreadyExists) {
+		t.Errorf("Expected ErrAlreadyExists for a lot registered twice, got %v", err)
+	}
+
+	first, err := service.AddOrderToBatch("order-1", "product-1", 1, domain.ItemStatusAllocated)
+	if err != nil {
+		t.Fatalf("Failed to add order: %v", err)
+	}
+	if first.ID != soon.ID || first.Items[0].LotNumber != "LOT-SOON" {
+		t.Errorf("Expected order-1 to be allocated from LOT-SOON, got batch %s", first.ID)
+	}
+
+	// LOT-SOON is full, so the next order closes it and moves on to the next lot
+	second, err := service.AddOrderToBatch("order-2", "product-1", 1, domain.ItemStatusAllocated)
+	if err != nil {
+		t.Fatalf("Failed to add order: %v", err)
+	}
+	if second.ID != late.ID {
+		t.Errorf("Expected order-2 to be allocated from LOT-LATE, got batch %s", second.ID)
+	}
+
+	closed, err := service.GetBatchByID(soon.ID)
+	if err != nil {
+		t.Fatalf("Failed to get batch: %v", err)
+	}
+	if closed.Status != domain.BatchStatusProcessing {
+		t.Errorf("Expected full lot to be closed, got %s", closed.Status)
+	}
+}
+
+func TestBatchService_UpdatedOrderStaysInItsBatch(t *testing.T) {
+	repo := drivenadapters.NewBatchMemoryRepository()
+	service := NewBatchService(repo, domain.NewMockBatchEventPublisher(),
+		WithClosingPolicy(domain.BatchClosingPolicy{MaxTotalQuantity: 10}))
+
+	batch, err := service.AddOrderToBatch("order-1", "product-1", 4, domain.ItemStatusAllocated)
+	if err != nil {
+		t.Fatalf("Failed to add order: %v", err)
+	}
+	if _, err := service.AddOrderToBatch("order-2", "product-1", 4, domain.ItemStatusAllocated); err != nil {
+		t.Fatalf("Failed to add order: %v", err)
+	}
+
+	// The new quantity exceeds the batch limit; the line is updated in place
+	updated, err := service.AddOrderToBatch("order-1", "product-1", 8, domain.ItemStatusAllocated)
+	if err != nil {
+		t.Fatalf("Failed to update order: %v", err)
+	}
+	if updated.ID != batch.ID || updated.Status != domain.BatchStatusPending {
+		t.Errorf("Expected order-1 to stay in pending batch %s, got %s (%s)", batch.ID, updated.ID, updated.Status)
+	}
+
+	batches, _ := service.GetAllBatches()
+	holding := 0
+	for _, candidate := range batches {
+		if item, err := candidate.GetItemByOrderID("order-1"); err == nil {
+			holding++
+			if item.Quantity != 8 {
+				t.Errorf("Expected order-1 quantity 8, got %d", item.Quantity)
+			}
+		}
+	}
+	if len(batches) != 1 || holding != 1 {
+		t.Errorf("Expected order-1 in exactly one of 1 batches, got %d of %d", holding, len(batches))
+	}
+}
+
+func TestBatchService_SkipsExpiredLots(t *testing.T) {
+	repo := drivenadapters.NewBatchMemoryRepository()
+	service := NewBatchService(repo, domain.NewMockBatchEventPublisher())
+
+	now := time.Now()
+	expired, err := domain.NewLotBatch("batch-expired", "product-1", "LOT-OLD", time.Time{}, now.Add(-time.Hour), now.Add(-2*time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to create lot batch: %v", err)
+	}
+	if err := repo.Save(expired); err != nil {
+		t.Fatalf("Failed to save batch: %v", err)
+	}
+
+	// With only the expired lot left, new orders go to a new batch
+	created, err := service.AddOrderToBatch("order-1", "product-1", 1, domain.ItemStatusAllocated)
+	if err != nil {
+		t.Fatalf("Expected order to skip the expired lot, got %v", err)
+	}
+	if created.ID == expired.ID || created.ExpiresAt != nil {
+		t.Errorf("Expected order-1 in a new batch, got batch %s", created.ID)
+	}
+	batch, err := service.AddOrderToBatch("order-2", "product-1", 1, domain.ItemStatusAllocated)
+	if err != nil || batch.ID != created.ID {
+		t.Fatalf("Expected order-2 in the new batch %s, got %v (%v)", created.ID, batch, err)
+	}
+	if stored, _ := repo.FindByID(expired.ID); len(stored.Items) != 0 {
+		t.Errorf("Expected the expired lot to stay empty, got %+v", stored.Items)
+	}
+
+	fresh, err := service.CreateLotBatch("product-1", "LOT-NEW", now.AddDate(0, -1, 0), now.AddDate(1, 0, 0))
+	if err != nil {
+		t.Fatalf("Failed to create lot batch: %v", err)
+	}
+	batch, err = service.AddOrderToBatch("order-3", "product-1", 1, domain.ItemStatusAllocated)
+	if err != nil {
+		t.Fatalf("Expected order to skip the expired lot, got %v", err)
+	}
+	if batch.ID != fresh.ID {
+		t.Errorf("Expected order to be allocated from LOT-NEW, got batch %s", batch.ID)
+	}
+}
+
+func TestBatchService_GetBatchesNearExpiry(t *testing.T) {
+	repo := drivenadapters.NewBatchMemoryRepository()
+	service := NewBatchService(repo, domain.NewMockBatchEventPublisher(),
+		WithExpiryWarningWindow(14*24*time.Hour))
+
+	now := time.Now()
+	soon, err := service.CreateLotBatch("product-1", "LOT-SOON", time.Time{}, now.AddDate(0, 0, 7))
+	if err != nil {
+		t.Fatalf("Failed to create lot batch: %v", err)
+	}
+	if _, err := service.CreateLotBatch("product-2", "LOT-LATE", time.Time{}, now.AddDate(0, 2, 0)); err != nil {
+		t.Fatalf("Failed to create lot batch: %v", err)
+	}
+	if _, err := service.AddOrderToBatch("order-1", "product-3", 1, domain.ItemStatusAllocated); err != nil {
+		t.Fatalf("Failed to add order: %v", err)
+	}
+
+	expiring, err := service.GetBatchesNearExpiry(0)
+	if err != nil {
+		t.Fatalf("GetBatchesNearExpiry failed: %v", err)
+	}
+	if len(expiring) != 1 || expiring[0].ID != soon.ID {
+		t.Errorf("Expected only LOT-SOON within the warning window, got %+v", expiring)
+	}
+
+	expiring, err = service.GetBatchesNearExpiry(90 * 24 * time.Hour)
+	if err != nil {
+		t.Fatalf("GetBatchesNearExpiry failed: %v", err)
+	}
+	if len(expiring) != 2 || expiring[0].ID != soon.ID {
+		t.Errorf("Expected both lots within 90 days, earliest first, got %+v", expiring)
+	}
+
+	if _, err := service.GetBatchesNearExpiry(-time.Hour); !errors.Is(err, domain.ErrValidation) {
+		t.Errorf("Expected validation error for a negative window, got %v", err)
+	}
+}
+
+func TestBatchService_QuarantineWorkflow(t *testing.T) {
+	repo := drivenadapters.NewBatchMemoryRepository()
+	service := NewBatchService(repo, domain.NewMockBatchEventPublisher())
+
+	batch, err := service.AddOrderToBatch("order-1", "product-1", 2, domain.ItemStatusAllocated)
+	if err != nil {
+		t.Fatalf("Failed to add order: %v", err)
+	}
+	if _, err := service.AddOrderToBatch("order-2", "product-1", 3, domain.ItemStatusAllocated); err != nil {
+		t.Fatalf("Failed to add order: %v", err)
+	}
+	if err := service.MarkBatchAsDamaged(batch.ID); err != nil {
+		t.Fatalf("Failed to mark batch as damaged: %v", err)
+	}
+
+	if err := service.WriteOffBatchItems(batch.ID, []string{"order-2"}, "qa-lead", "crushed"); !errors.Is(err, domain.ErrInvalidTransition) {
+		t.Fatalf("Expected ErrInvalidTransition writing off before inspection, got %v", err)
+	}
+	if err := service.InspectBatch(batch.ID, "qa-1", "cold-chain excursion of 4h at 14C"); err != nil {
+		t.Fatalf("Failed to inspect batch: %v", err)
+	}
+	if err := service.WriteOffBatchItems(batch.ID, []string{"order-2"}, "qa-lead", "temperature logger out of range"); err != nil {
+		t.Fatalf("Failed to write off: %v", err)
+	}
+
+	updated, err := service.GetBatchByID(batch.ID)
+	if err != nil {
+		t.Fatalf("Failed to get batch: %v", err)
+	}
+	if updated.Status != domain.BatchStatusPending || updated.Disposition == nil || updated.Inspection.Inspector != "qa-1" {
+		t.Errorf("Expected pending batch with inspection and disposition, got %+v", updated)
+	}
+
+	pending, _ := repo.PendingOutboxMessages(0, 0)
+	var eventTypes []domain.BatchEventType
+	for _, message := range pending[len(pending)-4:] {
+		eventTypes = append(eventTypes, message.EventType)
+	}
+	expected := []domain.BatchEventType{
+		domain.BatchEventInspected, domain.BatchEventWrittenOff, domain.BatchEventItemUpdated, domain.BatchEventItemUpdated,
+	}
+	if fmt.Sprint(eventTypes) != fmt.Sprint(expected) {
+		t.Errorf("Expected events %v, got %v", expected, eventTypes)
+	}
+
+	// The released rest of the batch is allocated again
+	again, err := service.AddOrderToBatch("order-3", "product-1", 1, domain.ItemStatusAllocated)
+	if err != nil {
+		t.Fatalf("Failed to add order: %v", err)
+	}
+	if again.ID != batch.ID {
+		t.Errorf("Expected order-3 to join the released batch %s, got %s", batch.ID, again.ID)
+	}
+}
+
+func TestBatchService_DispositionWritesOffReservedStock(t *testing.T) {
+	inventoryService := NewInventoryService(drivenadapters.NewInventoryMemoryRepository(), domain.NewMockInventoryEventPublisher())
+	service := NewBatchService(drivenadapters.NewBatchMemoryRepository(), domain.NewMockBatchEventPublisher(),
+		WithStockLedger(inventoryService))
+
+	if _, err := inventoryService.ReceiveStock("product-1", 10, ""); err != nil {
+		t.Fatalf("Failed to receive stock: %v", err)
+	}
+	if _, err := inventoryService.ReserveStock("order-1", "product-1", 2); err != nil {
+		t.Fatalf("Failed to reserve stock: %v", err)
+	}
+	if _, err := inventoryService.ReserveStock("order-2", "product-1", 3); err != nil {
+		t.Fatalf("Failed to reserve stock: %v", err)
+	}
+	batch, err := service.AddOrderToBatch("order-1", "product-1", 2, domain.ItemStatusAllocated)
+	if err != nil {
+		t.Fatalf("Failed to add order: %v", err)
+	}
+	if _, err := service.AddOrderToBatch("order-2", "product-1", 3, domain.ItemStatusAllocated); err != nil {
+		t.Fatalf("Failed to add order: %v", err)
+	}
+	// order-3 was batched for damage processing without a reservation
+	if _, err := service.AddOrderToBatch("order-3", "product-1", 1, domain.ItemStatusDamageMajor); err != nil {
+		t.Fatalf("Failed to add order: %v", err)
+	}
+
+	if err := service.MarkBatchAsDamaged(batch.ID); err != nil {
+		t.Fatalf("Failed to mark batch as damaged: %v", err)
+	}
+	if err := service.InspectBatch(batch.ID, "qa-1", "cold-chain excursion of 4h at 14C"); err != nil {
+		t.Fatalf("Failed to inspect batch: %v", err)
+	}
+	if err := service.WriteOffBatchItems(batch.ID, []string{"order-2", "order-3"}, "qa-lead", "crushed"); err != nil {
+		t.Fatalf("Failed to write off: %v", err)
+	}
+
+	stock, _ := inventoryService.GetStock("product-1")
+	if stock.OnHand != 7 || stock.Reserved != 2 {
+		t.Errorf("Expected 7 on hand and order-1 still reserved, got %+v", stock)
+	}
+	movements, _ := inventoryService.GetMovements("product-1")
+	last := movements[len(movements)-1]
+	if last.Type != domain.StockMovementAdjustment || last.OrderID != "order-2" || last.Quantity != -3 {
+		t.Errorf("Expected an adjustment writing off order-2, got %+v", last)
+	}
+
+	// Destroying the batch writes off the stock of order-1
+	if err := service.MarkBatchAsDamaged(batch.ID); err != nil {
+		t.Fatalf("Failed to mark batch as damaged: %v", err)
+	}
+	if err := service.InspectBatch(batch.ID, "qa-1", "second excursion"); err != nil {
+		t.Fatalf("Failed to inspect batch: %v", err)
+	}
+	if err := service.DestroyBatch(batch.ID, "qa-lead", "unsafe"); err != nil {
+		t.Fatalf("Failed to destroy batch: %v", err)
+	}
+	stock, _ = inventoryService.GetStock("product-1")
+	if stock.OnHand != 5 || stock.Reserved != 0 {
+		t.Errorf("Expected the destroyed order-1 to be written off, got %+v", stock)
+	}
+}
+
+func TestBatchService_ReconcilesFailedStockWriteOff(t *testing.T) {
+	inventoryRepo := &failingInventoryRepository{InventoryMemoryRepository: drivenadapters.NewInventoryMemoryRepository()}
+	inventoryService := NewInventoryService(inventoryRepo, domain.NewMockInventoryEventPublisher())
+	service := NewBatchService(drivenadapters.NewBatchMemoryRepository(), domain.NewMockBatchEventPublisher(),
+		WithStockLedger(inventoryService))
+
+	if _, err := inventoryService.ReceiveStock("product-1", 10, ""); err != nil {
+		t.Fatalf("Failed to receive stock: %v", err)
+	}
+	if _, err := inventoryService.ReserveStock("order-1", "product-1", 2); err != nil {
+		t.Fatalf("Failed to reserve stock: %v", err)
+	}
+	batch, err := service.AddOrderToBatch("order-1", "product-1", 2, domain.ItemStatusAllocated)
+	if err != nil {
+		t.Fatalf("Failed to add order: %v", err)
+	}
+	if err := service.MarkBatchAsDamaged(batch.ID); err != nil {
+		t.Fatalf("Failed to mark batch as damaged: %v", err)
+	}
+	if err := service.InspectBatch(batch.ID, "qa-1", "water damage"); err != nil {
+		t.Fatalf("Failed to inspect batch: %v", err)
+	}
+
+	// The stock ledger is down while the batch is destroyed
+	inventoryRepo.fail = true
+	if err := service.DestroyBatch(batch.ID, "qa-lead", "unsafe"); err != nil {
+		t.Fatalf("Expected the stored disposition to succeed, got %v", err)
+	}
+	if destroyed, _ := service.GetBatchByID(batch.ID); destroyed.Status != domain.BatchStatusDestroyed {
+		t.Fatalf("Expected the batch to be destroyed, got %s", destroyed.Status)
+	}
+	if writtenOff, err := service.ReconcileDisposedStock(); err == nil || writtenOff != 0 {
+		t.Errorf("Expected the reconciliation to fail while the ledger is down, got %d, %v", writtenOff, err)
+	}
+	stock, _ := inventoryService.GetStock("product-1")
+	if stock.OnHand != 10 || stock.Reserved != 2 {
+		t.Errorf("Expected the stock of order-1 to stay reserved, got %+v", stock)
+	}
+
+	// Once the ledger is back the reconciliation writes off the reserved stock, once
+	inventoryRepo.fail = false
+	writtenOff, err := service.ReconcileDisposedStock()
+	if err != nil {
+		t.Fatalf("Failed to reconcile disposed stock: %v", err)
+	}
+	if writtenOff != 1 {
+		t.Errorf("Expected 1 order written off, got %d", writtenOff)
+	}
+	stock, _ = inventoryService.GetStock("product-1")
+	if stock.OnHand != 8 || stock.Reserved != 0 {
+		t.Errorf("Expected the destroyed order-1 to be written off, got %+v", stock)
+	}
+	if writtenOff, err := service.ReconcileDisposedStock(); err != nil || writtenOff != 0 {
+		t.Errorf("Expected nothing left to reconcile, got %d, %v", writtenOff, err)
+	}
+}
+
+// failingInventoryRepository fails every stock change while fail is set, as if the
+// database were down
+type failingInventoryRepository struct {
+	*drivenadapters.InventoryMemoryRepository
+	fail bool
+}
+
+func (r *failingInventoryRepository) SaveStock(stock *domain.StockLevel, reservation *domain.StockReservation, movement *domain.StockMovement, events ...*domain.InventoryEvent) error {
+	if r.fail {
+		return errors.New("database unavailable")
+	}
+	return r.InventoryMemoryRepository.SaveStock(stock, reservation, movement, events...)
+}
+
+func TestBatchService_GetBatchAsOf(t *testing.T) {
+	repo := drivenadapters.NewBatchMemoryRepository(drivenadapters.WithChangeHistory(10))
+	service := NewBatchService(repo, domain.NewMockBatchEventPublisher())
+
+	batch, err := service.AddOrderToBatch("order-1", "product-1", 2, domain.ItemStatusAllocated)
+	if err != nil {
+		t.Fatalf("Failed to add order: %v", err)
+	}
+	time.Sleep(time.Millisecond)
+	beforeDamage := time.Now().UTC()
+	time.Sleep(time.Millisecond)
+	if err := service.MarkBatchAsDamaged(batch.ID); err != nil {
+		t.Fatalf("Failed to mark batch as damaged: %v", err)
+	}
+
+	// What the batch looked like when the damage was reported
+	asOf, err := service.GetBatchAsOf(batch.ID, beforeDamage)
+	if err != nil {
+		t.Fatalf("Failed to get batch as of %s: %v", beforeDamage, err)
+	}
+	if asOf.Status != domain.BatchStatusPending || len(asOf.Items) != 1 {
+		t.Errorf("Expected the pending batch with order-1, got %+v", asOf)
+	}
+
+	plain := NewBatchService(drivenadapters.NewBatchMemoryRepository(), domain.NewMockBatchEventPublisher())
+	if _, err := plain.GetBatchAsOf(batch.ID, beforeDamage); !errors.Is(err, domain.ErrValidation) {
+		t.Errorf("Expected ErrValidation without the change history, got %v", err)
+	}
+}
+
+func TestBatchService_GetBatchHistory(t *testing.T) {
+	service := NewBatchService(drivenadapters.NewBatchMemoryRepository(), domain.NewMockBatchEventPublisher())
+
+	batch, err := service.IssuedBy(domain.BatchIssuer{Actor: "alice"}).AddOrderToBatch("order-1", "product-1", 2, domain.ItemStatusAllocated)
+	if err != nil {
+		t.Fatalf("Failed to add order: %v", err)
+	}
+	if err := service.ProcessBatch(batch.ID); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	history, total, err := service.GetBatchHistory(batch.ID, 0, 10)
+	if err != nil {
+		t.Fatalf("Failed to get history: %v", err)
+	}
+	if total != 2 || len(history) != 2 {
+		t.Fatalf("Expected 2 entries, got %d of %d", len(history), total)
+	}
+	if history[0].Command.Name != domain.BatchCommandAddOrder || history[0].Command.Actor != "alice" {
+		t.Errorf("Expected alice to add the order, got %+v", history[0].Command)
+	}
+	// IssuedBy does not change the issuer of the service it is called on
+	if history[1].Command.Name != domain.BatchCommandProcess || history[1].Command.Actor != "" ||
+		history[1].StatusAfter != domain.BatchStatusProcessing {
+		t.Errorf("Expected an anonymous process command, got %+v", history[1])
+	}
+
+	if _, _, err := service.GetBatchHistory(batch.ID, -1, 10); !errors.Is(err, domain.ErrValidation) {
+		t.Errorf("Expected ErrValidation for a negative offset, got %v", err)
+	}
+	if _, _, err := service.GetBatchHistory(batch.ID, 0, MaxBatchHistoryPageSize+1); !errors.Is(err, domain.ErrValidation) {
+		t.Errorf("Expected ErrValidation for an oversized page, got %v", err)
+	}
+	if _, _, err := service.GetBatchHistory("missing", 0, 10); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for a batch without history, got %v", err)
+	}
+}
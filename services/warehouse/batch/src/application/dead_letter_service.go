@@ -0,0 +1,202 @@
+package application
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+)
+
+// DeadLetterService keeps order events that could not be handled so operators can
+// inspect them, fix their payload and replay them through the order event handler.
+// It implements domain.DeadLetterPublisher and forwards parked messages to the
+// dead-letter topic once they are stored.
+type DeadLetterService struct {
+	store             domain.DeadLetterStore
+	forwarder         domain.DeadLetterPublisher
+	orderEventHandler domain.OrderEventHandler
+	codecs            domain.EventCodecs
+	mutex             sync.Mutex
+
+	// replaying holds the IDs of the dead letters being replayed, which cannot be
+	// edited or replayed again until their replay is recorded
+	replaying map[string]bool
+}
+
+// DeadLetterServiceOption configures optional DeadLetterService behaviour
+type DeadLetterServiceOption func(*DeadLetterService)
+
+// WithDeadLetterCodecs sets the codecs that decode replayed order events. JSON is always
+// accepted.
+func WithDeadLetterCodecs(codecs domain.EventCodecs) DeadLetterServiceOption {
+	return func(s *DeadLetterService) {
+		s.codecs = codecs
+	}
+}
+
+// NewDeadLetterService creates a new DeadLetterService. The forwarder is optional.
+func NewDeadLetterService(store domain.DeadLetterStore, orderEventHandler domain.OrderEventHandler, forwarder domain.DeadLetterPublisher, opts ...DeadLetterServiceOption) *DeadLetterService {
+	service := &DeadLetterService{
+		store:             store,
+		forwarder:         forwarder,
+		orderEventHandler: orderEventHandler,
+		replaying:         make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(service)
+	}
+	return service
+}
+
+// PublishDeadLetter stores the dead letter, unless it was already parked by an earlier
+// delivery of the same message, and forwards it to the dead-letter topic
+func (s *DeadLetterService) PublishDeadLetter(deadLetter *domain.DeadLetter) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	deadLetter.ID = domain.DeadLetterID(deadLetter.Topic, deadLetter.Partition, deadLetter.Offset)
+	if _, err := s.store.FindByID(deadLetter.ID); err != nil {
+		if !errors.Is(err, domain.ErrNotFound) {
+			return fmt.Errorf("failed to check dead letter %s: %w", deadLetter.ID, err)
+		}
+
+		deadLetter.Status = domain.DeadLetterStatusParked
+		if err := s.store.Save(deadLetter); err != nil {
+			return fmt.Errorf("failed to store dead letter %s: %w", deadLetter.ID, err)
+		}
+		log.Printf("Parked dead letter %s (%s error): %s", deadLetter.ID, deadLetter.ErrorClass, deadLetter.Reason)
+	}
+
+	if s.forwarder != nil {
+		return s.forwarder.PublishDeadLetter(deadLetter)
+	}
+	return nil
+}
+
+// GetDeadLetters retrieves the dead letters in the given status, or all of them if the status is empty
+func (s *DeadLetterService) GetDeadLetters(status domain.DeadLetterStatus) ([]*domain.DeadLetter, error) {
+	switch status {
+	case "":
+		return s.store.FindAll()
+	case domain.DeadLetterStatusParked, domain.DeadLetterStatusReplayed:
+		return s.store.FindByStatus(status)
+	default:
+		return nil, domain.NewValidationError("invalid dead letter status: %s", status)
+	}
+}
+
+// GetDeadLetter retrieves a dead letter by its ID
+func (s *DeadLetterService) GetDeadLetter(id string) (*domain.DeadLetter, error) {
+	return s.store.FindByID(id)
+}
+
+// UpdateDeadLetterPayload replaces the payload of a parked dead letter before it is replayed
+func (s *DeadLetterService) UpdateDeadLetterPayload(id string, payload []byte) (*domain.DeadLetter, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.replaying[id] {
+		return nil, newDeadLetterReplayingError(id)
+	}
+
+	deadLetter, err := s.store.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := deadLetter.EditPayload(payload, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+
+	if err := s.store.Save(deadLetter); err != nil {
+		return nil, fmt.Errorf("failed to store dead letter %s: %w", id, err)
+	}
+
+	log.Printf("Edited payload of dead letter %s", id)
+	return deadLetter, nil
+}
+
+// ReplayDeadLetter decodes the payload of a parked dead letter and hands it to the order
+// event handler. The outcome is recorded on the dead letter, which is returned in both
+// cases; an error is only returned if the dead letter cannot be replayed or stored.
+// The order event handler runs without holding the service lock, so a slow replay does
+// not hold up parking and editing other dead letters.
+func (s *DeadLetterService) ReplayDeadLetter(id string) (*domain.DeadLetter, error) {
+	deadLetter, err := s.startReplay(id)
+	if err != nil {
+		return nil, err
+	}
+
+	deadLetter, replay, err := s.finishReplay(id, s.handle(deadLetter))
+	if err != nil {
+		return nil, err
+	}
+
+	if replay.Succeeded {
+		log.Printf("Replayed dead letter %s", id)
+	} else {
+		log.Printf("Replay of dead letter %s failed: %s", id, replay.Error)
+	}
+	return deadLetter, nil
+}
+
+// startReplay loads a parked dead letter and reserves it for a replay
+func (s *DeadLetterService) startReplay(id string) (*domain.DeadLetter, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.replaying[id] {
+		return nil, newDeadLetterReplayingError(id)
+	}
+
+	deadLetter, err := s.store.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := deadLetter.CanReplay(); err != nil {
+		return nil, err
+	}
+
+	s.replaying[id] = true
+	return deadLetter, nil
+}
+
+// finishReplay records the outcome of a replay on the stored dead letter and releases it
+func (s *DeadLetterService) finishReplay(id string, replayErr error) (*domain.DeadLetter, domain.DeadLetterReplay, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	defer delete(s.replaying, id)
+
+	deadLetter, err := s.store.FindByID(id)
+	if err != nil {
+		return nil, domain.DeadLetterReplay{}, fmt.Errorf("failed to reload dead letter %s: %w", id, err)
+	}
+
+	replay := deadLetter.RecordReplay(time.Now().UTC(), replayErr)
+	if err := s.store.Save(deadLetter); err != nil {
+		return nil, domain.DeadLetterReplay{}, fmt.Errorf("failed to store replay of dead letter %s: %w", id, err)
+	}
+	return deadLetter, replay, nil
+}
+
+// newDeadLetterReplayingError reports that a dead letter is being replayed by another request
+func newDeadLetterReplayingError(id string) error {
+	return domain.NewConflictError("dead letter %s is being replayed", id)
+}
+
+// handle decodes the dead letter payload and hands the order event to the handler
+func (s *DeadLetterService) handle(deadLetter *domain.DeadLetter) error {
+	orderEvent, err := s.codecs.DecodeOrderEvent(deadLetter.Payload, deadLetter.Headers)
+	if err != nil {
+		return fmt.Errorf("failed to decode order event: %w", err)
+	}
+
+	if orderEvent.EventID == "" {
+		orderEvent.EventID = deadLetter.EventID()
+	}
+	return s.orderEventHandler.HandleOrderEvent(orderEvent)
+}
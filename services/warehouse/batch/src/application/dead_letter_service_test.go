@@ -0,0 +1,167 @@
+package application
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+	drivenadapters "github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/infrastructure/driven-adapters"
+)
+
+// countingDeadLetterPublisher counts the dead letters forwarded to it
+type countingDeadLetterPublisher struct {
+	published int
+}
+
+func (p *countingDeadLetterPublisher) PublishDeadLetter(deadLetter *domain.DeadLetter) error {
+	p.published++
+	return nil
+}
+
+func newTestDeadLetter(payload string) *domain.DeadLetter {
+	return &domain.DeadLetter{
+		Topic:      "order-events",
+		Partition:  0,
+		Offset:     42,
+		Key:        "order-1",
+		Payload:    []byte(payload),
+		ErrorClass: "decode",
+		Reason:     "failed to decode order event",
+		Attempts:   1,
+		FailedAt:   time.Now().UTC(),
+	}
+}
+
+func TestDeadLetterService_ParksEachMessageOnce(t *testing.T) {
+	store := drivenadapters.NewDeadLetterMemoryStore()
+	forwarder := &countingDeadLetterPublisher{}
+	service := NewDeadLetterService(store, &countingOrderEventHandler{}, forwarder)
+
+	if err := service.PublishDeadLetter(newTestDeadLetter(`{not json`)); err != nil {
+		t.Fatalf("Failed to park dead letter: %v", err)
+	}
+	if _, err := service.ReplayDeadLetter("order-events-0-42"); err != nil {
+		t.Fatalf("Failed to replay dead letter: %v", err)
+	}
+
+	// A redelivery of the same message must not reset the replay history
+	if err := service.PublishDeadLetter(newTestDeadLetter(`{not json`)); err != nil {
+		t.Fatalf("Failed to park dead letter again: %v", err)
+	}
+
+	deadLetter, err := service.GetDeadLetter("order-events-0-42")
+	if err != nil {
+		t.Fatalf("Failed to get dead letter: %v", err)
+	}
+	if deadLetter.Status != domain.DeadLetterStatusParked || len(deadLetter.Replays) != 1 {
+		t.Errorf("Expected parked dead letter with one replay, got %s with %d replays", deadLetter.Status, len(deadLetter.Replays))
+	}
+	if forwarder.published != 2 {
+		t.Errorf("Expected both deliveries to be forwarded, got %d", forwarder.published)
+	}
+}
+
+func TestDeadLetterService_EditAndReplay(t *testing.T) {
+	repo := drivenadapters.NewBatchMemoryRepository()
+	batchService := NewBatchService(repo, domain.NewMockBatchEventPublisher())
+	service := NewDeadLetterService(drivenadapters.NewDeadLetterMemoryStore(), NewOrderService(batchService), nil)
+
+	if err := service.PublishDeadLetter(newTestDeadLetter(`{"event_type":"order.created","order_id":"order-1"`)); err != nil {
+		t.Fatalf("Failed to park dead letter: %v", err)
+	}
+
+	// The truncated payload cannot be decoded
+	deadLetter, err := service.ReplayDeadLetter("order-events-0-42")
+	if err != nil {
+		t.Fatalf("Failed to replay dead letter: %v", err)
+	}
+	if deadLetter.Status != domain.DeadLetterStatusParked || len(deadLetter.Replays) != 1 || deadLetter.Replays[0].Succeeded {
+		t.Fatalf("Expected a failed replay to be recorded, got %+v", deadLetter.Replays)
+	}
+
+	fixed := `{"event_type":"order.created","order_id":"order-1","order":{"id":"order-1","product_id":"product-1","quantity":3,"status":"created"}}`
+	if _, err := service.UpdateDeadLetterPayload("order-events-0-42", []byte(fixed)); err != nil {
+		t.Fatalf("Failed to edit payload: %v", err)
+	}
+
+	deadLetter, err = service.ReplayDeadLetter("order-events-0-42")
+	if err != nil {
+		t.Fatalf("Failed to replay dead letter: %v", err)
+	}
+	if deadLetter.Status != domain.DeadLetterStatusReplayed || len(deadLetter.Replays) != 2 || !deadLetter.Replays[1].Succeeded {
+		t.Fatalf("Expected a successful replay to settle the dead letter, got %+v", deadLetter)
+	}
+
+	if _, err := batchService.GetBatchByOrderID("order-1"); err != nil {
+		t.Errorf("Expected the replayed order to be batched: %v", err)
+	}
+
+	// A replayed dead letter is settled
+	if _, err := service.ReplayDeadLetter("order-events-0-42"); !errors.Is(err, domain.ErrInvalidTransition) {
+		t.Errorf("Expected ErrInvalidTransition replaying twice, got %v", err)
+	}
+	if _, err := service.UpdateDeadLetterPayload("order-events-0-42", []byte(fixed)); !errors.Is(err, domain.ErrInvalidTransition) {
+		t.Errorf("Expected ErrInvalidTransition editing a replayed dead letter, got %v", err)
+	}
+
+	if _, err := service.GetDeadLetters("unknown"); !errors.Is(err, domain.ErrValidation) {
+		t.Errorf("Expected ErrValidation for unknown status, got %v", err)
+	}
+}
+
+// blockingOrderEventHandler blocks every event until release is closed
+type blockingOrderEventHandler struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (h *blockingOrderEventHandler) HandleOrderEvent(event domain.OrderEvent) error {
+	h.started <- struct{}{}
+	<-h.release
+	return nil
+}
+
+func TestDeadLetterService_ReplayDoesNotHoldTheLock(t *testing.T) {
+	handler := &blockingOrderEventHandler{started: make(chan struct{}, 1), release: make(chan struct{})}
+	service := NewDeadLetterService(drivenadapters.NewDeadLetterMemoryStore(), handler, nil)
+
+	payload := `{"event_type":"order.created","order_id":"order-1"}`
+	if err := service.PublishDeadLetter(newTestDeadLetter(payload)); err != nil {
+		t.Fatalf("Failed to park dead letter: %v", err)
+	}
+
+	replayed := make(chan *domain.DeadLetter)
+	go func() {
+		deadLetter, err := service.ReplayDeadLetter("order-events-0-42")
+		if err != nil {
+			t.Errorf("Failed to replay dead letter: %v", err)
+		}
+		replayed <- deadLetter
+	}()
+	<-handler.started
+
+	// Other dead letters are parked while the replay is running
+	other := newTestDeadLetter(payload)
+	other.Offset = 43
+	if err := service.PublishDeadLetter(other); err != nil {
+		t.Fatalf("Failed to park dead letter during a replay: %v", err)
+	}
+
+	// The dead letter being replayed is left alone
+	if _, err := service.ReplayDeadLetter("order-events-0-42"); !errors.Is(err, domain.ErrConcurrencyConflict) {
+		t.Errorf("Expected ErrConcurrencyConflict replaying concurrently, got %v", err)
+	}
+	if _, err := service.UpdateDeadLetterPayload("order-events-0-42", []byte(payload)); !errors.Is(err, domain.ErrConcurrencyConflict) {
+		t.Errorf("Expected ErrConcurrencyConflict editing during a replay, got %v", err)
+	}
+
+	close(handler.release)
+	deadLetter := <-replayed
+	if deadLetter == nil || deadLetter.Status != domain.DeadLetterStatusReplayed {
+		t.Fatalf("Expected the replay to be recorded, got %+v", deadLetter)
+	}
+	if _, err := service.UpdateDeadLetterPayload("order-events-0-42", []byte(payload)); !errors.Is(err, domain.ErrInvalidTransition) {
+		t.Errorf("Expected ErrInvalidTransition editing a replayed dead letter, got %v", err)
+	}
+}
@@ -0,0 +1,51 @@
+package application
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// DisposedStockReconciler periodically writes off the stock still reserved for orders
+// that were written off or destroyed in quarantine
+type DisposedStockReconciler struct {
+	batchService *BatchService
+	interval     time.Duration
+}
+
+// NewDisposedStockReconciler creates a new DisposedStockReconciler
+func NewDisposedStockReconciler(batchService *BatchService, interval time.Duration) *DisposedStockReconciler {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	return &DisposedStockReconciler{
+		batchService: batchService,
+		interval:     interval,
+	}
+}
+
+// Start reconciles the disposed stock on every interval until the context is cancelled
+func (r *DisposedStockReconciler) Start(ctx context.Context) {
+	log.Printf("Starting disposed stock reconciler (interval: %s)", r.interval)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Disposed stock reconciler stopping...")
+			return
+		case <-ticker.C:
+			writtenOff, err := r.batchService.ReconcileDisposedStock()
+			if err != nil {
+				log.Printf("Error reconciling disposed stock: %v", err)
+				continue
+			}
+			if writtenOff > 0 {
+				log.Printf("Wrote off the stock of %d disposed orders", writtenOff)
+			}
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+)
+
+// DefaultEventClaimTimeout is how long an event claimed by a worker is not handed to
+// another one; after it the worker is assumed to have died while handling the event
+const DefaultEventClaimTimeout = 5 * time.Minute
+
+// IdempotentOrderEventHandler decorates an OrderEventHandler and skips events that
+// have already been handled, so redelivered Kafka messages are not applied twice.
+// An event is claimed before it is handed to the wrapped handler, so two workers
+// receiving the same event cannot both handle it. The claim is released when the
+// handler fails, and completed when it succeeds.
+type IdempotentOrderEventHandler struct {
+	handler      domain.OrderEventHandler
+	store        domain.ProcessedEventStore
+	retention    time.Duration
+	claimTimeout time.Duration
+}
+
+// NewIdempotentOrderEventHandler creates a new IdempotentOrderEventHandler that
+// remembers processed events for the given retention
+func NewIdempotentOrderEventHandler(handler domain.OrderEventHandler, store domain.ProcessedEventStore, retention time.Duration) *IdempotentOrderEventHandler {
+	return &IdempotentOrderEventHandler{
+		handler:      handler,
+		store:        store,
+		retention:    retention,
+		claimTimeout: DefaultEventClaimTimeout,
+	}
+}
+
+// HandleOrderEvent hands the event to the wrapped handler unless it was already processed
+func (h *IdempotentOrderEventHandler) HandleOrderEvent(event domain.OrderEvent) error {
+	if event.EventID == "" {
+		log.Printf("Order event %s for order %s has no event ID, duplicates cannot be detected",
+			event.EventType, event.OrderID)
+		return h.handler.HandleOrderEvent(event)
+	}
+
+	now := time.Now()
+	claimed, err := h.store.ClaimEvent(event.EventID, now, now.Add(-h.claimTimeout))
+	if err != nil {
+		return fmt.Errorf("failed to claim event %s: %w", event.EventID, err)
+	}
+	if !claimed {
+		log.Printf("Skipping duplicate order event %s (Type=%s, OrderID=%s)", event.EventID, event.EventType, event.OrderID)
+		return nil
+	}
+
+	if err := h.handler.HandleOrderEvent(event); err != nil {
+		if releaseErr := h.store.ReleaseEvent(event.EventID); releaseErr != nil {
+			log.Printf("Failed to release claim of event %s: %v", event.EventID, releaseErr)
+		}
+		return err
+	}
+
+	if err := h.store.MarkProcessed(event.EventID, time.Now()); err != nil {
+		return fmt.Errorf("failed to mark event %s as processed: %w", event.EventID, err)
+	}
+	return nil
+}
+
+// PurgeExpired forgets the processed events older than the retention
+func (h *IdempotentOrderEventHandler) PurgeExpired() (int, error) {
+	return h.store.PurgeProcessedBefore(time.Now().Add(-h.retention))
+}
+
+// StartPurging purges expired processed events on every interval until the context is cancelled.
+// A non-positive interval disables purging.
+func (h *IdempotentOrderEventHandler) StartPurging(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		log.Printf("Processed event purge disabled (interval: %s)", interval)
+		return
+	}
+
+	log.Printf("Starting processed event purge (retention: %s, interval: %s)", h.retention, interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Processed event purge stopping...")
+			return
+		case <-ticker.C:
+			purged, err := h.PurgeExpired()
+			if err != nil {
+				log.Printf("Error purging processed events: %v", err)
+				continue
+			}
+			if purged > 0 {
+				log.Printf("Purged %d processed events older than %s", purged, h.retention)
+			}
+		}
+	}
+}
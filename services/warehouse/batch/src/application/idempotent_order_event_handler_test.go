@@ -0,0 +1,161 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+	drivenadapters "github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/infrastructure/driven-adapters"
+)
+
+// countingOrderEventHandler counts the events it receives and fails while err is set
+type countingOrderEventHandler struct {
+	handled int
+	err     error
+}
+
+func (h *countingOrderEventHandler) HandleOrderEvent(event domain.OrderEvent) error {
+	h.handled++
+	return h.err
+}
+
+func TestIdempotentOrderEventHandler_SkipsDuplicateReturn(t *testing.T) {
+	repo := drivenadapters.NewBatchMemoryRepository()
+	batchService := NewBatchService(repo, domain.NewMockBatchEventPublisher())
+	returnRepo := drivenadapters.NewReturnMemoryRepository()
+	orderService := NewOrderService(batchService, WithReturnService(NewReturnService(batchService, returnRepo)))
+	handler := NewIdempotentOrderEventHandler(orderService, drivenadapters.NewProcessedEventMemoryStore(), time.Hour)
+
+	if _, err := batchService.AddOrderToBatch("order-1", "product-1", 2, "delivered"); err != nil {
+		t.Fatalf("Failed to add order: %v", err)
+	}
+
+	event := domain.OrderEvent{
+		EventID:   "order-events/0/42",
+		EventType: "order.returned",
+		OrderID:   "order-1",
+		Order: domain.Order{
+			ID:        "order-1",
+			ProductID: "product-1",
+			Quantity:  2,
+			Status:    "returned",
+		},
+	}
+
+	if err := handler.HandleOrderEvent(event); err != nil {
+		t.Fatalf("Failed to handle event: %v", err)
+	}
+	afterFirst, _ := repo.PendingOutboxMessages(0, 0)
+
+	// The same message is delivered again
+	if err := handler.HandleOrderEvent(event); err != nil {
+		t.Fatalf("Failed to handle redelivered event: %v", err)
+	}
+	afterSecond, _ := repo.PendingOutboxMessages(0, 0)
+	if len(afterSecond) != len(afterFirst) {
+		t.Errorf("Expected redelivery not to change any batch, got %d new events", len(afterSecond)-len(afterFirst))
+	}
+
+	returns, _ := returnRepo.GetAll()
+	if len(returns) != 1 || returns[0].OrderID != "order-1" {
+		t.Errorf("Expected exactly one return for order-1, got %d", len(returns))
+	}
+}
+
+func TestIdempotentOrderEventHandler_RetriesFailedEvents(t *testing.T) {
+	inner := &countingOrderEventHandler{err: errors.New("batch repository unavailable")}
+	store := drivenadapters.NewProcessedEventMemoryStore()
+	handler := NewIdempotentOrderEventHandler(inner, store, time.Hour)
+
+	event := domain.OrderEvent{EventID: "evt-1", EventType: "order.created", OrderID: "order-1"}
+
+	if err := handler.HandleOrderEvent(event); err == nil {
+		t.Fatal("Expected the handler error to be returned")
+	}
+	if processed, _ := store.IsProcessed("evt-1"); processed {
+		t.Fatal("Expected failed event not to be marked as processed")
+	}
+
+	inner.err = nil
+	handler.HandleOrderEvent(event)
+	handler.HandleOrderEvent(event)
+	if inner.handled != 2 {
+		t.Errorf("Expected the failed delivery and one retry to reach the handler, got %d", inner.handled)
+	}
+
+	// Events without an ID cannot be deduplicated and are always handled
+	handler.HandleOrderEvent(domain.OrderEvent{EventType: "order.created", OrderID: "order-2"})
+	handler.HandleOrderEvent(domain.OrderEvent{EventType: "order.created", OrderID: "order-2"})
+	if inner.handled != 4 {
+		t.Errorf("Expected events without ID to be handled every time, got %d", inner.handled)
+	}
+}
+
+// slowOrderEventHandler counts the events it receives, taking a while to handle each
+type slowOrderEventHandler struct {
+	handled int32
+}
+
+func (h *slowOrderEventHandler) HandleOrderEvent(event domain.OrderEvent) error {
+	atomic.AddInt32(&h.handled, 1)
+	time.Sleep(10 * time.Millisecond)
+	return nil
+}
+
+func TestIdempotentOrderEventHandler_ConcurrentDuplicatesAreHandledOnce(t *testing.T) {
+	inner := &slowOrderEventHandler{}
+	handler := NewIdempotentOrderEventHandler(inner, drivenadapters.NewProcessedEventMemoryStore(), time.Hour)
+	event := domain.OrderEvent{EventID: "evt-1", EventType: "order.created", OrderID: "order-1"}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := handler.HandleOrderEvent(event); err != nil {
+				t.Errorf("Failed to handle event: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if handled := atomic.LoadInt32(&inner.handled); handled != 1 {
+		t.Errorf("Expected the event to be handled once by concurrent workers, got %d", handled)
+	}
+}
+
+func TestIdempotentOrderEventHandler_PurgeExpired(t *testing.T) {
+	store := drivenadapters.NewProcessedEventMemoryStore()
+	handler := NewIdempotentOrderEventHandler(&countingOrderEventHandler{}, store, time.Hour)
+
+	store.MarkProcessed("old", time.Now().Add(-2*time.Hour))
+	store.MarkProcessed("recent", time.Now())
+
+	purged, err := handler.PurgeExpired()
+	if err != nil {
+		t.Fatalf("Failed to purge: %v", err)
+	}
+	if purged != 1 {
+		t.Errorf("Expected 1 purged event, got %d", purged)
+	}
+}
+
+func TestIdempotentOrderEventHandler_StartPurgingWithoutInterval(t *testing.T) {
+	handler := NewIdempotentOrderEventHandler(&countingOrderEventHandler{}, drivenadapters.NewProcessedEventMemoryStore(), time.Hour)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.StartPurging(context.Background(), 0)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected purging to be disabled for a zero interval")
+	}
+}
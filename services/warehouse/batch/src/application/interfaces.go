@@ -8,37 +8,94 @@ import (
 
 // BatchServiceInterface defines the contract for batch operations
 type BatchServiceInterface interface {
-	AddOrderToBatch(orderID, productID string, quantity int, status string) (*domain.Batch, error)
+	CreateLotBatch(productID, lotNumber string, manufacturedAt, expiresAt time.Time) (*domain.Batch, error)
+	AddOrderToBatch(orderID, productID string, quantity int, status domain.ItemStatus) (*domain.Batch, error)
+	AddOrderToBatchForCustomer(orderID, customerID, productID string, quantity int, status domain.ItemStatus) (*domain.Batch, error)
 	RemoveOrderFromBatch(orderID string) error
-	UpdateOrderStatus(orderID, status string) error
+	UpdateOrderStatus(orderID string, status domain.ItemStatus) error
 	ProcessBatch(batchID string) error
 	CompleteBatch(batchID string) error
 	CancelBatch(batchID string) error
 	MarkBatchAsDamaged(batchID string) error
+	InspectBatch(batchID, inspector, findings string) error
+	ReleaseBatch(batchID, decidedBy, reason string) error
+	WriteOffBatchItems(batchID string, orderIDs []string, decidedBy, reason string) error
+	DestroyBatch(batchID, decidedBy, reason string) error
+	HoldBatch(batchID, reason string) error
+	GetBatchByID(batchID string) (*domain.Batch, error)
+	GetBatchAsOf(batchID string, at time.Time) (*domain.Batch, error)
+	GetBatchHistory(batchID string, offset, limit int) ([]*domain.BatchAuditEntry, int, error)
+	GetAllowedActions(batchID string) ([]domain.BatchAction, error)
 	GetBatchByOrderID(orderID string) (*domain.Batch, error)
 	GetBatchesByProductID(productID string) ([]*domain.Batch, error)
 	GetBatchesByStatus(status domain.BatchStatus) ([]*domain.Batch, error)
 	GetAllBatches() ([]*domain.Batch, error)
+	GetBatchesNearExpiry(within time.Duration) ([]*domain.Batch, error)
+	IssuedBy(issuer domain.BatchIssuer) BatchServiceInterface
+}
+
+// DeadLetterServiceInterface defines the contract for inspecting and replaying dead letters
+type DeadLetterServiceInterface interface {
+	GetDeadLetters(status domain.DeadLetterStatus) ([]*domain.DeadLetter, error)
+	GetDeadLetter(id string) (*domain.DeadLetter, error)
+	UpdateDeadLetterPayload(id string, payload []byte) (*domain.DeadLetter, error)
+	ReplayDeadLetter(id string) (*domain.DeadLetter, error)
+}
+
+// InventoryServiceInterface defines the contract for stock operations
+type InventoryServiceInterface interface {
+	ReceiveStock(productID string, quantity int, reason string) (*domain.StockLevel, error)
+	AdjustStock(productID string, delta int, reason string) (*domain.StockLevel, error)
+	GetStock(productID string) (*domain.StockLevel, error)
+	GetAllStock() ([]*domain.StockLevel, error)
+	GetMovements(productID string) ([]*domain.StockMovement, error)
+}
+
+// RecallServiceInterface defines the contract for product recalls
+type RecallServiceInterface interface {
+	InitiateRecall(productID, lotNumber, reason, initiatedBy string) (*domain.RecallCase, error)
+	ResumeRecall(recallID string) (*domain.RecallCase, error)
+	ResolveRecallOrder(recallID, orderID string) (*domain.RecallCase, error)
+	GetRecall(recallID string) (*domain.RecallCase, error)
+	GetAllRecalls() ([]*domain.RecallCase, error)
+}
+
+// ReturnServiceInterface defines the contract for customer returns
+type ReturnServiceInterface interface {
+	OpenReturn(orderID string, quantity int, reason string, condition domain.ReturnCondition) (*domain.ReturnAuthorization, error)
+	ResolveReturn(returnID string, disposition domain.ReturnDisposition, condition domain.ReturnCondition, decidedBy string) (*domain.ReturnAuthorization, error)
+	GetReturn(returnID string) (*domain.ReturnAuthorization, error)
+	GetReturnsByBatchID(batchID string) ([]*domain.ReturnAuthorization, error)
+	GetAllReturns() ([]*domain.ReturnAuthorization, error)
 }
 
 // BatchDTO represents a batch for API responses
 type BatchDTO struct {
-	ID          string        `json:"id"`
-	ProductID   string        `json:"product_id"`
-	Status      string        `json:"status"`
-	Items       []BatchItemDTO `json:"items"`
-	TotalItems  int           `json:"total_items"`
-	CreatedAt   time.Time     `json:"created_at"`
-	UpdatedAt   time.Time     `json:"updated_at"`
-	ProcessedAt *time.Time    `json:"processed_at,omitempty"`
+	ID             string                        `json:"id"`
+	ProductID      string                        `json:"product_id"`
+	LotNumber      string                        `json:"lot_number,omitempty"`
+	ManufacturedAt *time.Time                    `json:"manufactured_at,omitempty"`
+	ExpiresAt      *time.Time                    `json:"expires_at,omitempty"`
+	Status         string                        `json:"status"`
+	Items          []BatchItemDTO                `json:"items"`
+	TotalItems     int                           `json:"total_items"`
+	CreatedAt      time.Time                     `json:"created_at"`
+	UpdatedAt      time.Time                     `json:"updated_at"`
+	ProcessedAt    *time.Time                    `json:"processed_at,omitempty"`
+	Inspection     *domain.BatchInspection       `json:"inspection,omitempty"`
+	Disposition    *domain.QuarantineDisposition `json:"disposition,omitempty"`
+	HoldReason     string                        `json:"hold_reason,omitempty"`
+	Version        int64                         `json:"version"`
 }
 
 // BatchItemDTO represents an item within a batch for API responses
 type BatchItemDTO struct {
 	OrderID     string     `json:"order_id"`
+	CustomerID  string     `json:"customer_id,omitempty"`
 	ProductID   string     `json:"product_id"`
 	Quantity    int        `json:"quantity"`
 	Status      string     `json:"status"`
+	LotNumber   string     `json:"lot_number,omitempty"`
 	AddedAt     time.Time  `json:"added_at"`
 	ProcessedAt *time.Time `json:"processed_at,omitempty"`
 }
@@ -49,23 +106,32 @@ func ToBatchDTO(batch *domain.Batch) *BatchDTO {
 	for i, item := range batch.Items {
 		items[i] = BatchItemDTO{
 			OrderID:     item.OrderID,
+			CustomerID:  item.CustomerID,
 			ProductID:   item.ProductID,
 			Quantity:    item.Quantity,
-			Status:      item.Status,
+			Status:      string(item.Status),
+			LotNumber:   item.LotNumber,
 			AddedAt:     item.AddedAt,
 			ProcessedAt: item.ProcessedAt,
 		}
 	}
 
 	return &BatchDTO{
-		ID:          batch.ID,
-		ProductID:   batch.ProductID,
-		Status:      string(batch.Status),
-		Items:       items,
-		TotalItems:  batch.TotalItems,
-		CreatedAt:   batch.CreatedAt,
-		UpdatedAt:   batch.UpdatedAt,
-		ProcessedAt: batch.ProcessedAt,
+		ID:             batch.ID,
+		ProductID:      batch.ProductID,
+		LotNumber:      batch.LotNumber,
+		ManufacturedAt: batch.ManufacturedAt,
+		ExpiresAt:      batch.ExpiresAt,
+		Status:         string(batch.Status),
+		Items:          items,
+		TotalItems:     batch.TotalItems,
+		CreatedAt:      batch.CreatedAt,
+		UpdatedAt:      batch.UpdatedAt,
+		ProcessedAt:    batch.ProcessedAt,
+		Inspection:     batch.Inspection,
+		Disposition:    batch.Disposition,
+		HoldReason:     batch.HoldReason,
+		Version:        batch.Version,
 	}
 }
 
@@ -76,4 +142,235 @@ func ToBatchDTOs(batches []*domain.Batch) []*BatchDTO {
 		dtos[i] = ToBatchDTO(batch)
 	}
 	return dtos
-}
\ No newline at end of file
+}
+
+// BatchAuditEntryDTO represents an entry of the audit trail of a batch for API responses
+type BatchAuditEntryDTO struct {
+	ID           int64                `json:"id"`
+	Version      int64                `json:"version"`
+	Command      string               `json:"command"`
+	Actor        string               `json:"actor,omitempty"`
+	SourceEvent  string               `json:"source_event,omitempty"`
+	EventTypes   []string             `json:"event_types"`
+	StatusBefore string               `json:"status_before,omitempty"`
+	StatusAfter  string               `json:"status_after,omitempty"`
+	Items        domain.BatchItemDiff `json:"items"`
+	RecordedAt   time.Time            `json:"recorded_at"`
+}
+
+// ToBatchAuditEntryDTO converts a domain audit entry to a DTO
+func ToBatchAuditEntryDTO(entry *domain.BatchAuditEntry) *BatchAuditEntryDTO {
+	eventTypes := make([]string, len(entry.EventTypes))
+	for i, eventType := range entry.EventTypes {
+		eventTypes[i] = string(eventType)
+	}
+
+	return &BatchAuditEntryDTO{
+		ID:           entry.ID,
+		Version:      entry.Version,
+		Command:      string(entry.Command.Name),
+		Actor:        entry.Command.Actor,
+		SourceEvent:  entry.Command.SourceEvent,
+		EventTypes:   eventTypes,
+		StatusBefore: string(entry.StatusBefore),
+		StatusAfter:  string(entry.StatusAfter),
+		Items:        entry.Items,
+		RecordedAt:   entry.RecordedAt,
+	}
+}
+
+// ToBatchAuditEntryDTOs converts a slice of domain audit entries to DTOs
+func ToBatchAuditEntryDTOs(entries []*domain.BatchAuditEntry) []*BatchAuditEntryDTO {
+	dtos := make([]*BatchAuditEntryDTO, len(entries))
+	for i, entry := range entries {
+		dtos[i] = ToBatchAuditEntryDTO(entry)
+	}
+	return dtos
+}
+
+// DeadLetterDTO represents a dead letter for API responses. The payload is returned
+// as text since it may not be valid JSON.
+type DeadLetterDTO struct {
+	ID         string                    `json:"id"`
+	Topic      string                    `json:"topic"`
+	Partition  int                       `json:"partition"`
+	Offset     int64                     `json:"offset"`
+	Key        string                    `json:"key"`
+	Payload    string                    `json:"payload"`
+	Headers    []domain.MessageHeader    `json:"headers"`
+	ErrorClass string                    `json:"error_class"`
+	Reason     string                    `json:"reason"`
+	Attempts   int                       `json:"attempts"`
+	FailedAt   time.Time                 `json:"failed_at"`
+	Status     string                    `json:"status"`
+	EditedAt   *time.Time                `json:"edited_at,omitempty"`
+	Replays    []domain.DeadLetterReplay `json:"replays"`
+}
+
+// ToDeadLetterDTO converts a domain dead letter to a DTO
+func ToDeadLetterDTO(deadLetter *domain.DeadLetter) *DeadLetterDTO {
+	headers := deadLetter.Headers
+	if headers == nil {
+		headers = []domain.MessageHeader{}
+	}
+	replays := deadLetter.Replays
+	if replays == nil {
+		replays = []domain.DeadLetterReplay{}
+	}
+
+	return &DeadLetterDTO{
+		ID:         deadLetter.ID,
+		Topic:      deadLetter.Topic,
+		Partition:  deadLetter.Partition,
+		Offset:     deadLetter.Offset,
+		Key:        deadLetter.Key,
+		Payload:    string(deadLetter.Payload),
+		Headers:    headers,
+		ErrorClass: deadLetter.ErrorClass,
+		Reason:     deadLetter.Reason,
+		Attempts:   deadLetter.Attempts,
+		FailedAt:   deadLetter.FailedAt,
+		Status:     string(deadLetter.Status),
+		EditedAt:   deadLetter.EditedAt,
+		Replays:    replays,
+	}
+}
+
+// ToDeadLetterDTOs converts a slice of domain dead letters to DTOs
+func ToDeadLetterDTOs(deadLetters []*domain.DeadLetter) []*DeadLetterDTO {
+	dtos := make([]*DeadLetterDTO, len(deadLetters))
+	for i, deadLetter := range deadLetters {
+		dtos[i] = ToDeadLetterDTO(deadLetter)
+	}
+	return dtos
+}
+
+// StockLevelDTO represents the stock of a product for API responses
+type StockLevelDTO struct {
+	ProductID string    `json:"product_id"`
+	OnHand    int       `json:"on_hand"`
+	Reserved  int       `json:"reserved"`
+	Available int       `json:"available"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Version   int64     `json:"version"`
+}
+
+// ToStockLevelDTO converts a domain stock level to a DTO
+func ToStockLevelDTO(stock *domain.StockLevel) *StockLevelDTO {
+	return &StockLevelDTO{
+		ProductID: stock.ProductID,
+		OnHand:    stock.OnHand,
+		Reserved:  stock.Reserved,
+		Available: stock.Available(),
+		UpdatedAt: stock.UpdatedAt,
+		Version:   stock.Version,
+	}
+}
+
+// ToStockLevelDTOs converts a slice of domain stock levels to DTOs
+func ToStockLevelDTOs(stocks []*domain.StockLevel) []*StockLevelDTO {
+	dtos := make([]*StockLevelDTO, len(stocks))
+	for i, stock := range stocks {
+		dtos[i] = ToStockLevelDTO(stock)
+	}
+	return dtos
+}
+
+// RecallCaseDTO represents a recall case for API responses, with the affected customers
+// and the progress of the recall
+type RecallCaseDTO struct {
+	ID          string                `json:"id"`
+	ProductID   string                `json:"product_id"`
+	LotNumber   string                `json:"lot_number,omitempty"`
+	Reason      string                `json:"reason"`
+	InitiatedBy string                `json:"initiated_by"`
+	Status      string                `json:"status"`
+	Batches     []domain.RecallBatch  `json:"batches"`
+	Orders      []domain.RecallOrder  `json:"orders"`
+	CustomerIDs []string              `json:"customer_ids"`
+	Progress    domain.RecallProgress `json:"progress"`
+	CreatedAt   time.Time             `json:"created_at"`
+	UpdatedAt   time.Time             `json:"updated_at"`
+	ClosedAt    *time.Time            `json:"closed_at,omitempty"`
+	Version     int64                 `json:"version"`
+}
+
+// ToRecallCaseDTO converts a domain recall case to a DTO
+func ToRecallCaseDTO(recall *domain.RecallCase) *RecallCaseDTO {
+	return &RecallCaseDTO{
+		ID:          recall.ID,
+		ProductID:   recall.ProductID,
+		LotNumber:   recall.LotNumber,
+		Reason:      recall.Reason,
+		InitiatedBy: recall.InitiatedBy,
+		Status:      string(recall.Status),
+		Batches:     recall.Batches,
+		Orders:      recall.Orders,
+		CustomerIDs: recall.CustomerIDs(),
+		Progress:    recall.Progress(),
+		CreatedAt:   recall.CreatedAt,
+		UpdatedAt:   recall.UpdatedAt,
+		ClosedAt:    recall.ClosedAt,
+		Version:     recall.Version,
+	}
+}
+
+// ToRecallCaseDTOs converts a slice of domain recall cases to DTOs
+func ToRecallCaseDTOs(recalls []*domain.RecallCase) []*RecallCaseDTO {
+	dtos := make([]*RecallCaseDTO, len(recalls))
+	for i, recall := range recalls {
+		dtos[i] = ToRecallCaseDTO(recall)
+	}
+	return dtos
+}
+
+// ReturnDTO represents a customer return for API responses
+type ReturnDTO struct {
+	ID          string     `json:"id"`
+	OrderID     string     `json:"order_id"`
+	CustomerID  string     `json:"customer_id,omitempty"`
+	ProductID   string     `json:"product_id"`
+	BatchID     string     `json:"batch_id"`
+	LotNumber   string     `json:"lot_number,omitempty"`
+	Quantity    int        `json:"quantity"`
+	Reason      string     `json:"reason"`
+	Condition   string     `json:"condition"`
+	Status      string     `json:"status"`
+	Disposition string     `json:"disposition,omitempty"`
+	DecidedBy   string     `json:"decided_by,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	ResolvedAt  *time.Time `json:"resolved_at,omitempty"`
+	Version     int64      `json:"version"`
+}
+
+// ToReturnDTO converts a domain return to a DTO
+func ToReturnDTO(rma *domain.ReturnAuthorization) *ReturnDTO {
+	return &ReturnDTO{
+		ID:          rma.ID,
+		OrderID:     rma.OrderID,
+		CustomerID:  rma.CustomerID,
+		ProductID:   rma.ProductID,
+		BatchID:     rma.BatchID,
+		LotNumber:   rma.LotNumber,
+		Quantity:    rma.Quantity,
+		Reason:      rma.Reason,
+		Condition:   string(rma.Condition),
+		Status:      string(rma.Status),
+		Disposition: string(rma.Disposition),
+		DecidedBy:   rma.DecidedBy,
+		CreatedAt:   rma.CreatedAt,
+		UpdatedAt:   rma.UpdatedAt,
+		ResolvedAt:  rma.ResolvedAt,
+		Version:     rma.Version,
+	}
+}
+
+// ToReturnDTOs converts a slice of domain returns to DTOs
+func ToReturnDTOs(returns []*domain.ReturnAuthorization) []*ReturnDTO {
+	dtos := make([]*ReturnDTO, len(returns))
+	for i, rma := range returns {
+		dtos[i] = ToReturnDTO(rma)
+	}
+	return dtos
+}
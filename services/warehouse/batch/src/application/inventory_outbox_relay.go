@@ -0,0 +1,129 @@
+package application
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+)
+
+// InventoryOutboxRelay drains the inventory outbox and delivers inventory events to the
+// publisher. Each event reports a separate allocation failure, so a failing event does
+// not hold back the others: it is retried at the capped backoff until it is delivered.
+// Only an event that cannot be decoded is marked dead.
+type InventoryOutboxRelay struct {
+	store     domain.InventoryOutboxStore
+	publisher domain.InventoryEventPublisher
+	config    OutboxRelayConfig
+	notify    chan struct{}
+	mutex     sync.Mutex
+}
+
+// NewInventoryOutboxRelay creates a new InventoryOutboxRelay
+func NewInventoryOutboxRelay(store domain.InventoryOutboxStore, publisher domain.InventoryEventPublisher, config OutboxRelayConfig) *InventoryOutboxRelay {
+	return &InventoryOutboxRelay{
+		store:     store,
+		publisher: publisher,
+		config:    config.withDefaults(),
+		notify:    make(chan struct{}, 1),
+	}
+}
+
+// Start drains the outbox until the context is cancelled
+func (r *InventoryOutboxRelay) Start(ctx context.Context) {
+	log.Printf("Starting inventory outbox relay (poll interval: %s, batch size: %d)", r.config.PollInterval, r.config.BatchSize)
+
+	ticker := time.NewTicker(r.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Inventory outbox relay stopping...")
+			return
+		case <-ticker.C:
+		case <-r.notify:
+		}
+
+		if _, err := r.RelayPending(); err != nil {
+			log.Printf("Error relaying inventory outbox messages: %v", err)
+		}
+	}
+}
+
+// Notify wakes up the relay so newly stored events are delivered without waiting for the next poll
+func (r *InventoryOutboxRelay) Notify() {
+	select {
+	case r.notify <- struct{}{}:
+	default:
+	}
+}
+
+// RelayPending delivers all due outbox messages and returns how many were published
+func (r *InventoryOutboxRelay) RelayPending() (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := time.Now().UTC()
+	published := 0
+	var afterID int64
+	for {
+		messages, err := r.store.PendingInventoryOutboxMessages(afterID, r.config.BatchSize)
+		if err != nil {
+			return published, err
+		}
+
+		for _, message := range messages {
+			if r.relayMessage(message, now) {
+				published++
+			}
+		}
+
+		if len(messages) < r.config.BatchSize {
+			return published, nil
+		}
+		afterID = messages[len(messages)-1].ID
+	}
+}
+
+// relayMessage publishes a due message and reports whether it was delivered
+func (r *InventoryOutboxRelay) relayMessage(message *domain.InventoryOutboxMessage, now time.Time) bool {
+	if message.NextAttemptAt.After(now) {
+		return false
+	}
+
+	event, err := message.Event()
+	if err != nil {
+		// Retrying cannot fix the payload
+		r.markDead(message, err)
+		return false
+	}
+
+	if err := r.publisher.PublishInventoryEvent(event); err != nil {
+		nextAttemptAt := now.Add(r.config.backoff(message.Attempts + 1))
+		log.Printf("Failed to publish inventory outbox message %d (%s for product %s, attempt %d), retrying at %s: %v",
+			message.ID, message.EventType, message.ProductID, message.Attempts+1, nextAttemptAt.Format(time.RFC3339), err)
+		if markErr := r.store.MarkInventoryOutboxMessageFailed(message.ID, err.Error(), nextAttemptAt); markErr != nil {
+			log.Printf("Failed to record inventory outbox failure for message %d: %v", message.ID, markErr)
+		}
+		return false
+	}
+
+	if err := r.store.MarkInventoryOutboxMessagePublished(message.ID); err != nil {
+		// The event will be delivered again; consumers must tolerate duplicates
+		log.Printf("Failed to mark inventory outbox message %d as published: %v", message.ID, err)
+		return false
+	}
+	return true
+}
+
+// markDead stops retrying a message that cannot be decoded
+func (r *InventoryOutboxRelay) markDead(message *domain.InventoryOutboxMessage, cause error) {
+	log.Printf("Giving up on undecodable inventory outbox message %d (%s for product %s): %v",
+		message.ID, message.EventType, message.ProductID, cause)
+	if err := r.store.MarkInventoryOutboxMessageDead(message.ID, cause.Error()); err != nil {
+		log.Printf("Failed to mark inventory outbox message %d as dead: %v", message.ID, err)
+	}
+}
@@ -0,0 +1,283 @@
+package application
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+)
+
+// InventoryService keeps the stock ledger of the warehouse: operators receive and adjust
+// stock, and orders reserve it until they are shipped or cancelled. Every change is
+// stored together with its ledger entry. Inventory events are stored in the repository
+// outbox and delivered to the event publisher by the inventory outbox relay.
+type InventoryService struct {
+	inventoryRepo domain.InventoryRepository
+	outboxRelay   *InventoryOutboxRelay
+
+	// conflictRetries is how many times a command is retried after a concurrency conflict
+	conflictRetries int
+}
+
+// InventoryServiceOption configures optional InventoryService behaviour
+type InventoryServiceOption func(*inventoryServiceOptions)
+
+type inventoryServiceOptions struct {
+	outboxRelayConfig OutboxRelayConfig
+}
+
+// WithInventoryOutboxRelayConfig overrides the default inventory outbox relay settings
+func WithInventoryOutboxRelayConfig(config OutboxRelayConfig) InventoryServiceOption {
+	return func(o *inventoryServiceOptions) {
+		o.outboxRelayConfig = config
+	}
+}
+
+// NewInventoryService creates a new InventoryService
+func NewInventoryService(inventoryRepo domain.InventoryRepository, eventPublisher domain.InventoryEventPublisher, opts ...InventoryServiceOption) *InventoryService {
+	options := inventoryServiceOptions{
+		outboxRelayConfig: DefaultOutboxRelayConfig(),
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return &InventoryService{
+		inventoryRepo:   inventoryRepo,
+		outboxRelay:     NewInventoryOutboxRelay(inventoryRepo, eventPublisher, options.outboxRelayConfig),
+		conflictRetries: DefaultConflictRetries,
+	}
+}
+
+// OutboxRelay returns the relay that delivers the inventory events stored by this service
+func (s *InventoryService) OutboxRelay() *InventoryOutboxRelay {
+	return s.outboxRelay
+}
+
+// ReceiveStock adds received units of a product to its on-hand quantity
+func (s *InventoryService) ReceiveStock(productID string, quantity int, reason string) (*domain.StockLevel, error) {
+	log.Printf("Receiving %d units of product %s", quantity, productID)
+
+	return s.changeStock(fmt.Sprintf("receive product %s", productID), productID, func(stock *domain.StockLevel, now time.Time) (*domain.StockMovement, error) {
+		return stock.Receive(quantity, reason, now)
+	})
+}
+
+// AdjustStock corrects the on-hand quantity of a product after a count
+func (s *InventoryService) AdjustStock(productID string, delta int, reason string) (*domain.StockLevel, error) {
+	log.Printf("Adjusting product %s by %d: %s", productID, delta, reason)
+
+	return s.changeStock(fmt.Sprintf("adjust product %s", productID), productID, func(stock *domain.StockLevel, now time.Time) (*domain.StockMovement, error) {
+		return stock.Adjust(delta, reason, now)
+	})
+}
+
+// changeStock applies a receipt or adjustment to the stock of a product, creating
+// the stock level the first time the product is stocked
+func (s *InventoryService) changeStock(command, productID string, change func(*domain.StockLevel, time.Time) (*domain.StockMovement, error)) (*domain.StockLevel, error) {
+	var stock *domain.StockLevel
+	err := retryOnConflict(command, s.conflictRetries, func() error {
+		var err error
+		stock, err = s.loadStock(productID)
+		if err != nil {
+			return err
+		}
+
+		movement, err := change(stock, time.Now())
+		if err != nil {
+			return err
+		}
+
+		if err := s.inventoryRepo.SaveStock(stock, nil, movement); err != nil {
+			return fmt.Errorf("failed to save stock of product %s: %w", productID, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stock, nil
+}
+
+// ReserveStock sets units of a product aside for an order. Reserving an order that is
+// already reserved returns its reservation, and an order whose reservation was rolled
+// back is reserved again. When the product does not have enough
+// available stock an inventory.allocation_failed event is stored in the outbox together
+// with the stock level it was decided on, and an ErrInsufficientStock error is returned.
+func (s *InventoryService) ReserveStock(orderID, productID string, quantity int) (*domain.StockReservation, error) {
+	log.Printf("Reserving %d units of product %s for order %s", quantity, productID, orderID)
+
+	var (
+		reservation *domain.StockReservation
+		available   int
+	)
+	err := retryOnConflict(fmt.Sprintf("reserve stock for order %s", orderID), s.conflictRetries, func() error {
+		existing, err := s.inventoryRepo.FindReservation(orderID)
+		if err == nil && existing.Status == domain.StockReservationActive {
+			reservation = existing
+			return nil
+		}
+		if err == nil && existing.Status != domain.StockReservationRolledBack {
+			return domain.NewTransitionError("reservation of order %s is already %s", orderID, existing.Status)
+		}
+		if err != nil && !errors.Is(err, domain.ErrNotFound) {
+			return fmt.Errorf("failed to find reservation of order %s: %w", orderID, err)
+		}
+
+		stock, err := s.loadStock(productID)
+		if err != nil {
+			return err
+		}
+		available = stock.Available()
+
+		var movement *domain.StockMovement
+		reservation, movement, err = stock.Reserve(orderID, quantity, time.Now())
+		if errors.Is(err, domain.ErrInsufficientStock) {
+			// Saving the unchanged stock fails if it changed since it was loaded, so the
+			// failure is only recorded while the shortage holds
+			event := domain.NewAllocationFailedEvent(orderID, productID, quantity, available, err.Error())
+			if saveErr := s.inventoryRepo.SaveStock(stock, nil, nil, event); saveErr != nil {
+				return fmt.Errorf("failed to record allocation failure of order %s: %w", orderID, saveErr)
+			}
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := s.inventoryRepo.SaveStock(stock, reservation, movement); err != nil {
+			return fmt.Errorf("failed to save stock of product %s: %w", productID, err)
+		}
+		return nil
+	})
+	if errors.Is(err, domain.ErrInsufficientStock) {
+		s.outboxRelay.Notify()
+		log.Printf("Allocation failed for order %s: %v", orderID, err)
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Reserved %d units of product %s for order %s", reservation.Quantity, productID, orderID)
+	return reservation, nil
+}
+
+// ReleaseStock returns the stock reserved for a cancelled order. Releasing a released
+// reservation again is a no-op.
+func (s *InventoryService) ReleaseStock(orderID string) (*domain.StockReservation, error) {
+	log.Printf("Releasing stock reserved for order %s", orderID)
+
+	return s.settleReservation(fmt.Sprintf("release stock for order %s", orderID), orderID, domain.StockReservationReleased,
+		func(stock *domain.StockLevel, reservation *domain.StockReservation, now time.Time) (*domain.StockMovement, error) {
+			return stock.Release(reservation, now)
+		})
+}
+
+// ShipStock removes the stock reserved for a shipped order from the warehouse. Shipping
+// a shipped reservation again is a no-op.
+func (s *InventoryService) ShipStock(orderID string) (*domain.StockReservation, error) {
+	log.Printf("Shipping stock reserved for order %s", orderID)
+
+	return s.settleReservation(fmt.Sprintf("ship stock for order %s", orderID), orderID, domain.StockReservationShipped,
+		func(stock *domain.StockLevel, reservation *domain.StockReservation, now time.Time) (*domain.StockMovement, error) {
+			return stock.Ship(reservation, now)
+		})
+}
+
+// WriteOffStock removes the stock reserved for an order written off or destroyed in
+// quarantine from the warehouse. Writing off a written off reservation again is a no-op.
+func (s *InventoryService) WriteOffStock(orderID, reason string) (*domain.StockReservation, error) {
+	log.Printf("Writing off stock reserved for order %s: %s", orderID, reason)
+
+	return s.settleReservation(fmt.Sprintf("write off stock for order %s", orderID), orderID, domain.StockReservationWrittenOff,
+		func(stock *domain.StockLevel, reservation *domain.StockReservation, now time.Time) (*domain.StockMovement, error) {
+			return stock.WriteOff(reservation, reason, now)
+		})
+}
+
+// RollBackReservation returns the stock reserved for an order that could not be batched.
+// Rolling back a rolled back reservation again is a no-op.
+func (s *InventoryService) RollBackReservation(orderID, reason string) (*domain.StockReservation, error) {
+	log.Printf("Rolling back stock reserved for order %s: %s", orderID, reason)
+
+	return s.settleReservation(fmt.Sprintf("roll back stock for order %s", orderID), orderID, domain.StockReservationRolledBack,
+		func(stock *domain.StockLevel, reservation *domain.StockReservation, now time.Time) (*domain.StockMovement, error) {
+			return stock.RollBack(reservation, reason, now)
+		})
+}
+
+// settleReservation moves the reservation of an order to its final status and stores
+// the stock change. A rolled back reservation holds no stock, so it is reported with
+// an ErrNotFound error like an order that was never reserved.
+func (s *InventoryService) settleReservation(command, orderID string, status domain.StockReservationStatus,
+	settle func(*domain.StockLevel, *domain.StockReservation, time.Time) (*domain.StockMovement, error)) (*domain.StockReservation, error) {
+	var reservation *domain.StockReservation
+	err := retryOnConflict(command, s.conflictRetries, func() error {
+		var err error
+		reservation, err = s.inventoryRepo.FindReservation(orderID)
+		if err != nil {
+			return err
+		}
+		if reservation.Status == status {
+			return nil
+		}
+		if reservation.Status == domain.StockReservationRolledBack {
+			return domain.NewNotFoundError("no stock reserved for order %s", orderID)
+		}
+
+		stock, err := s.loadStock(reservation.ProductID)
+		if err != nil {
+			return err
+		}
+
+		movement, err := settle(stock, reservation, time.Now())
+		if err != nil {
+			return err
+		}
+
+		if err := s.inventoryRepo.SaveStock(stock, reservation, movement); err != nil {
+			return fmt.Errorf("failed to save stock of product %s: %w", stock.ProductID, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reservation, nil
+}
+
+// loadStock retrieves the stock of a product, starting from an empty stock level
+// if the product has never been stocked
+func (s *InventoryService) loadStock(productID string) (*domain.StockLevel, error) {
+	stock, err := s.inventoryRepo.FindStock(productID)
+	if errors.Is(err, domain.ErrNotFound) {
+		return domain.NewStockLevel(productID), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find stock of product %s: %w", productID, err)
+	}
+	return stock, nil
+}
+
+// GetReservation retrieves the stock reservation of an order
+func (s *InventoryService) GetReservation(orderID string) (*domain.StockReservation, error) {
+	return s.inventoryRepo.FindReservation(orderID)
+}
+
+// GetStock retrieves the stock level of a product
+func (s *InventoryService) GetStock(productID string) (*domain.StockLevel, error) {
+	return s.inventoryRepo.FindStock(productID)
+}
+
+// GetAllStock retrieves the stock levels of all products
+func (s *InventoryService) GetAllStock() ([]*domain.StockLevel, error) {
+	return s.inventoryRepo.GetAllStock()
+}
+
+// GetMovements retrieves the stock ledger of a product
+func (s *InventoryService) GetMovements(productID string) ([]*domain.StockMovement, error) {
+	return s.inventoryRepo.FindMovements(productID)
+}
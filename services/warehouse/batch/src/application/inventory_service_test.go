@@ -0,0 +1,138 @@
+package application
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+	drivenadapters "github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/infrastructure/driven-adapters"
+)
+
+func TestInventoryService_ReserveStock(t *testing.T) {
+	publisher := domain.NewMockInventoryEventPublisher()
+	service := NewInventoryService(drivenadapters.NewInventoryMemoryRepository(), publisher)
+
+	if _, err := service.ReceiveStock("product-1", 5, "purchase order PO-1"); err != nil {
+		t.Fatalf("Failed to receive stock: %v", err)
+	}
+
+	reservation, err := service.ReserveStock("order-1", "product-1", 3)
+	if err != nil {
+		t.Fatalf("Failed to reserve stock: %v", err)
+	}
+	if reservation.Status != domain.StockReservationActive {
+		t.Errorf("Expected active reservation, got %s", reservation.Status)
+	}
+
+	// Redelivered order events reserve once
+	if _, err := service.ReserveStock("order-1", "product-1", 3); err != nil {
+		t.Fatalf("Failed to reserve stock again: %v", err)
+	}
+
+	_, err = service.ReserveStock("order-2", "product-1", 3)
+	if !errors.Is(err, domain.ErrInsufficientStock) {
+		t.Fatalf("Expected ErrInsufficientStock, got %v", err)
+	}
+
+	// The allocation failure is published by the outbox relay
+	if len(publisher.PublishedEvents) != 0 {
+		t.Fatalf("Expected no events before relaying, got %d", len(publisher.PublishedEvents))
+	}
+	if _, err := service.OutboxRelay().RelayPending(); err != nil {
+		t.Fatalf("Failed to relay inventory outbox: %v", err)
+	}
+	if len(publisher.PublishedEvents) != 1 {
+		t.Fatalf("Expected 1 published event, got %d", len(publisher.PublishedEvents))
+	}
+	event := publisher.PublishedEvents[0]
+	if event.EventType != domain.InventoryEventAllocationFailed || event.OrderID != "order-2" ||
+		event.RequestedQuantity != 3 || event.AvailableQuantity != 2 {
+		t.Errorf("Unexpected allocation failed event: %+v", event)
+	}
+
+	stock, err := service.GetStock("product-1")
+	if err != nil {
+		t.Fatalf("Failed to get stock: %v", err)
+	}
+	if stock.OnHand != 5 || stock.Reserved != 3 {
+		t.Errorf("Expected 5 on hand and 3 reserved, got %+v", stock)
+	}
+}
+
+func TestInventoryService_AllocationFailureSurvivesPublisherOutage(t *testing.T) {
+	repo := drivenadapters.NewInventoryMemoryRepository()
+	publisher := domain.NewMockInventoryEventPublisher()
+	publisher.ShouldFail = true
+	service := NewInventoryService(repo, publisher, WithInventoryOutboxRelayConfig(OutboxRelayConfig{
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+	}))
+
+	if _, err := service.ReserveStock("order-1", "product-1", 1); !errors.Is(err, domain.ErrInsufficientStock) {
+		t.Fatalf("Expected ErrInsufficientStock, got %v", err)
+	}
+
+	// The failure stays in the outbox until the publisher recovers
+	if published, _ := service.OutboxRelay().RelayPending(); published != 0 {
+		t.Fatalf("Expected nothing published during the outage, got %d", published)
+	}
+	pending, _ := repo.PendingInventoryOutboxMessages(0, 0)
+	if len(pending) != 1 || pending[0].Attempts != 1 {
+		t.Fatalf("Expected the failed event to wait in the outbox, got %+v", pending)
+	}
+
+	publisher.ShouldFail = false
+	time.Sleep(5 * time.Millisecond)
+	if published, _ := service.OutboxRelay().RelayPending(); published != 1 {
+		t.Fatalf("Expected the event to be published after the outage, got %d", published)
+	}
+	if publisher.PublishedEvents[0].OrderID != "order-1" {
+		t.Errorf("Expected the allocation failure of order-1, got %+v", publisher.PublishedEvents[0])
+	}
+}
+
+func TestInventoryService_ReleaseAndShipStock(t *testing.T) {
+	service := NewInventoryService(drivenadapters.NewInventoryMemoryRepository(), domain.NewMockInventoryEventPublisher())
+
+	if _, err := service.ReceiveStock("product-1", 10, ""); err != nil {
+		t.Fatalf("Failed to receive stock: %v", err)
+	}
+	for _, orderID := range []string{"order-1", "order-2"} {
+		if _, err := service.ReserveStock(orderID, "product-1", 4); err != nil {
+			t.Fatalf("Failed to reserve stock: %v", err)
+		}
+	}
+
+	if _, err := service.ReleaseStock("order-1"); err != nil {
+		t.Fatalf("Failed to release stock: %v", err)
+	}
+	if _, err := service.ReleaseStock("order-1"); err != nil {
+		t.Errorf("Expected releasing twice to be a no-op, got %v", err)
+	}
+	if _, err := service.ShipStock("order-2"); err != nil {
+		t.Fatalf("Failed to ship stock: %v", err)
+	}
+	if _, err := service.ShipStock("order-1"); !errors.Is(err, domain.ErrInvalidTransition) {
+		t.Errorf("Expected ErrInvalidTransition shipping a released reservation, got %v", err)
+	}
+	if _, err := service.ReserveStock("order-1", "product-1", 4); !errors.Is(err, domain.ErrInvalidTransition) {
+		t.Errorf("Expected ErrInvalidTransition reserving a released order again, got %v", err)
+	}
+	if _, err := service.ReleaseStock("order-3"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for an order without reservation, got %v", err)
+	}
+
+	stock, _ := service.GetStock("product-1")
+	if stock.OnHand != 6 || stock.Reserved != 0 {
+		t.Errorf("Expected 6 on hand and nothing reserved, got %+v", stock)
+	}
+
+	movements, err := service.GetMovements("product-1")
+	if err != nil {
+		t.Fatalf("Failed to get movements: %v", err)
+	}
+	if len(movements) != 5 {
+		t.Errorf("Expected 5 movements, got %d", len(movements))
+	}
+}
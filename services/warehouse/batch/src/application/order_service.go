@@ -1,6 +1,7 @@
 package application
 
 import (
+	"errors"
 	"fmt"
 	"log"
 
@@ -10,17 +11,75 @@ import (
 // OrderService handles business logic for order events
 type OrderService struct {
 	batchService *BatchService
+
+	// inventoryService reserves stock for orders; without it orders are batched
+	// without checking stock
+	inventoryService *InventoryService
+
+	// returnService opens a return authorization for returned orders; without it the
+	// order is only marked as returned in its batch
+	returnService *ReturnService
+}
+
+// OrderEventActor is the actor recorded in the audit trail of the batches for the
+// commands of order events, together with the ID of the event
+const OrderEventActor = "order-events"
+
+// OrderServiceOption configures optional OrderService behaviour
+type OrderServiceOption func(*OrderService)
+
+// WithInventoryService reserves stock for created orders and releases it for
+// cancelled ones. Orders that cannot be filled are not batched.
+func WithInventoryService(inventoryService *InventoryService) OrderServiceOption {
+	return func(s *OrderService) {
+		s.inventoryService = inventoryService
+	}
+}
+
+// WithReturnService opens a return authorization for every returned order
+func WithReturnService(returnService *ReturnService) OrderServiceOption {
+	return func(s *OrderService) {
+		s.returnService = returnService
+	}
 }
 
 // NewOrderService creates a new OrderService
-func NewOrderService(batchService *BatchService) *OrderService {
-	return &OrderService{
+func NewOrderService(batchService *BatchService, opts ...OrderServiceOption) *OrderService {
+	service := &OrderService{
 		batchService: batchService,
 	}
+	for _, opt := range opts {
+		opt(service)
+	}
+	return service
+}
+
+// batchesFor returns the batch service issuing commands on behalf of an order event,
+// in the trace of the event
+func (s *OrderService) batchesFor(event domain.OrderEvent) BatchServiceInterface {
+	return s.batchService.IssuedBy(domain.BatchIssuer{
+		Actor:        OrderEventActor,
+		SourceEvent:  event.EventID,
+		TraceContext: event.TraceContext,
+	})
 }
 
-// HandleOrderEvent processes the received order event
+// HandleOrderEvent processes the received order event in a span of the trace of the event
 func (s *OrderService) HandleOrderEvent(event domain.OrderEvent) error {
+	traceContext, end := s.batchService.tracer.StartSpan(event.TraceContext, "OrderService.HandleOrderEvent", map[string]string{
+		"order.id":         event.OrderID,
+		"order.event_id":   event.EventID,
+		"order.event_type": event.EventType,
+	})
+	event.TraceContext = traceContext
+
+	err := s.handleOrderEvent(event)
+	end(err)
+	return err
+}
+
+// handleOrderEvent processes the order event with the action it calls for
+func (s *OrderService) handleOrderEvent(event domain.OrderEvent) error {
 	log.Printf("Received order event: Type=%s, OrderID=%s, Status=%s", 
 		event.EventType, event.OrderID, event.Order.Status)
 
@@ -62,20 +121,26 @@ func (s *OrderService) HandleOrderEvent(event domain.OrderEvent) error {
 func (s *OrderService) processDamage(event domain.OrderEvent) error {
 	log.Printf("Processing damage for order %s: Status=%s, Quantity=%d", 
 		event.OrderID, event.Order.Status, event.Order.Quantity)
+	batches := s.batchesFor(event)
 	
 	// Business logic for damage processing
 	switch event.Order.Status {
 	case "damage_detected_minor":
 		log.Printf("Minor damage detected for order %s - marking for inspection", event.OrderID)
 		// Try to update order status in batch, if not found create new batch
-		if err := s.batchService.UpdateOrderStatus(event.OrderID, "damage_minor"); err != nil {
+		if err := batches.UpdateOrderStatus(event.OrderID, domain.ItemStatusDamageMinor); err != nil {
+			if !errors.Is(err, domain.ErrNotFound) {
+				log.Printf("Failed to update order status in batch: %v", err)
+				return err
+			}
 			log.Printf("Order not found in existing batch, creating new batch for damage processing: %v", err)
 			// Create new batch with the order for damage processing
-			_, err := s.batchService.AddOrderToBatch(
+			_, err := batches.AddOrderToBatchForCustomer(
 				event.OrderID,
+				event.Order.CustomerID,
 				event.Order.ProductID,
 				event.Order.Quantity,
-				"damage_minor",
+				domain.ItemStatusDamageMinor,
 			)
 			if err != nil {
 				log.Printf("Failed to create batch for damage processing: %v", err)
@@ -86,14 +151,19 @@ func (s *OrderService) processDamage(event domain.OrderEvent) error {
 	case "damage_detected_major":
 		log.Printf("Major damage detected for order %s - marking as damaged", event.OrderID)
 		// Try to update order status in batch, if not found create new batch
-		if err := s.batchService.UpdateOrderStatus(event.OrderID, "damage_major"); err != nil {
+		if err := batches.UpdateOrderStatus(event.OrderID, domain.ItemStatusDamageMajor); err != nil {
+			if !errors.Is(err, domain.ErrNotFound) {
+				log.Printf("Failed to update order status in batch: %v", err)
+				return err
+			}
 			log.Printf("Order not found in existing batch, creating new batch for damage processing: %v", err)
 			// Create new batch with the order for damage processing
-			batch, err := s.batchService.AddOrderToBatch(
+			batch, err := batches.AddOrderToBatchForCustomer(
 				event.OrderID,
+				event.Order.CustomerID,
 				event.Order.ProductID,
 				event.Order.Quantity,
-				"damage_major",
+				domain.ItemStatusDamageMajor,
 			)
 			if err != nil {
 				log.Printf("Failed to create batch for damage processing: %v", err)
@@ -101,14 +171,15 @@ func (s *OrderService) processDamage(event domain.OrderEvent) error {
 			}
 			log.Printf("Created new batch %s for order %s with major damage status", batch.ID, event.OrderID)
 			// Mark the entire batch as damaged since it's major damage
-			if err := s.batchService.MarkBatchAsDamaged(batch.ID); err != nil {
+			if err := batches.MarkBatchAsDamaged(batch.ID); err != nil {
 				log.Printf("Failed to mark batch as damaged: %v", err)
 			}
 		} else {
-			// Order was found and updated, now mark the batch as damaged
-			batch, err := s.batchService.GetBatchByOrderID(event.OrderID)
-			if err == nil {
-				if err := s.batchService.MarkBatchAsDamaged(batch.ID); err != nil {
+			// Order was found and updated, now mark the batch as damaged unless its status
+			// does not allow it (e.g. it is already damaged or completed)
+			batch, err := batches.GetBatchByOrderID(event.OrderID)
+			if err == nil && batch.Can(domain.BatchActionMarkDamaged) == nil {
+				if err := batches.MarkBatchAsDamaged(batch.ID); err != nil {
 					log.Printf("Failed to mark batch as damaged: %v", err)
 				}
 			}
@@ -116,14 +187,19 @@ func (s *OrderService) processDamage(event domain.OrderEvent) error {
 	case "damage_processed":
 		log.Printf("Damage processing completed for order %s", event.OrderID)
 		// Try to update order status to processed, if not found create new batch
-		if err := s.batchService.UpdateOrderStatus(event.OrderID, "damage_processed"); err != nil {
+		if err := batches.UpdateOrderStatus(event.OrderID, domain.ItemStatusDamageProcessed); err != nil {
+			if !errors.Is(err, domain.ErrNotFound) {
+				log.Printf("Failed to update order status in batch: %v", err)
+				return err
+			}
 			log.Printf("Order not found in existing batch, creating new batch for damage processing: %v", err)
 			// Create new batch with the order for damage processing completion
-			_, err := s.batchService.AddOrderToBatch(
+			_, err := batches.AddOrderToBatchForCustomer(
 				event.OrderID,
+				event.Order.CustomerID,
 				event.Order.ProductID,
 				event.Order.Quantity,
-				"damage_processed",
+				domain.ItemStatusDamageProcessed,
 			)
 			if err != nil {
 				log.Printf("Failed to create batch for damage processing: %v", err)
@@ -138,20 +214,42 @@ func (s *OrderService) processDamage(event domain.OrderEvent) error {
 	return nil
 }
 
-// allocateInventory handles inventory allocation for new orders
+// allocateInventory handles inventory allocation for new orders. Stock is reserved
+// before the order is batched; an order that cannot be filled is reported by the
+// inventory service and left out of the batches. If the order cannot be batched its
+// reservation is rolled back, so no stock stays reserved for an order that is not in
+// a batch; the retried event reserves it again.
 func (s *OrderService) allocateInventory(event domain.OrderEvent) error {
 	log.Printf("Allocating inventory for order %s: ProductID=%s, Quantity=%d", 
 		event.OrderID, event.Order.ProductID, event.Order.Quantity)
+
+	if s.inventoryService != nil {
+		_, err := s.inventoryService.ReserveStock(event.OrderID, event.Order.ProductID, event.Order.Quantity)
+		if errors.Is(err, domain.ErrInsufficientStock) {
+			log.Printf("Order %s is not batched: %v", event.OrderID, err)
+			return nil
+		}
+		if err != nil {
+			log.Printf("Failed to reserve stock: %v", err)
+			return err
+		}
+	}
 	
 	// Add order to batch for processing
-	batch, err := s.batchService.AddOrderToBatch(
+	batch, err := s.batchesFor(event).AddOrderToBatchForCustomer(
 		event.OrderID, 
+		event.Order.CustomerID,
 		event.Order.ProductID, 
 		event.Order.Quantity, 
-		"allocated",
+		domain.ItemStatusAllocated,
 	)
 	if err != nil {
 		log.Printf("Failed to add order to batch: %v", err)
+		if s.inventoryService != nil {
+			if _, rollBackErr := s.inventoryService.RollBackReservation(event.OrderID, "order could not be batched"); rollBackErr != nil {
+				log.Printf("Failed to roll back stock reserved for order %s: %v", event.OrderID, rollBackErr)
+			}
+		}
 		return err
 	}
 	
@@ -163,9 +261,26 @@ func (s *OrderService) allocateInventory(event domain.OrderEvent) error {
 func (s *OrderService) releaseInventory(event domain.OrderEvent) error {
 	log.Printf("Releasing inventory for cancelled order %s: ProductID=%s, Quantity=%d", 
 		event.OrderID, event.Order.ProductID, event.Order.Quantity)
+
+	reserved := true
+	if s.inventoryService != nil {
+		if _, err := s.inventoryService.ReleaseStock(event.OrderID); err != nil {
+			if !errors.Is(err, domain.ErrNotFound) {
+				log.Printf("Failed to release stock: %v", err)
+				return err
+			}
+			log.Printf("No stock reserved for order %s", event.OrderID)
+			reserved = false
+		}
+	}
 	
 	// Remove order from batch since it's cancelled
-	if err := s.batchService.RemoveOrderFromBatch(event.OrderID); err != nil {
+	if err := s.batchesFor(event).RemoveOrderFromBatch(event.OrderID); err != nil {
+		if !reserved && errors.Is(err, domain.ErrNotFound) {
+			// The order was never allocated, e.g. its stock was short
+			log.Printf("Order %s was never allocated, nothing to release", event.OrderID)
+			return nil
+		}
 		log.Printf("Failed to remove order from batch: %v", err)
 		return err
 	}
@@ -180,10 +295,21 @@ func (s *OrderService) updateInventory(event domain.OrderEvent) error {
 		event.OrderID, event.Order.ProductID, event.Order.Quantity)
 	
 	// Update order status to shipped in batch
-	if err := s.batchService.UpdateOrderStatus(event.OrderID, "shipped"); err != nil {
+	if err := s.batchesFor(event).UpdateOrderStatus(event.OrderID, domain.ItemStatusShipped); err != nil {
 		log.Printf("Failed to update order status in batch: %v", err)
 		return err
 	}
+
+	// The reserved units leave the warehouse
+	if s.inventoryService != nil {
+		if _, err := s.inventoryService.ShipStock(event.OrderID); err != nil {
+			if !errors.Is(err, domain.ErrNotFound) {
+				log.Printf("Failed to ship stock: %v", err)
+				return err
+			}
+			log.Printf("No stock reserved for order %s", event.OrderID)
+		}
+	}
 	
 	log.Printf("Order %s status updated to shipped in batch", event.OrderID)
 	return nil
@@ -194,7 +320,7 @@ func (s *OrderService) confirmDelivery(event domain.OrderEvent) error {
 	log.Printf("Confirming delivery for order %s", event.OrderID)
 	
 	// Update order status to delivered in batch
-	if err := s.batchService.UpdateOrderStatus(event.OrderID, "delivered"); err != nil {
+	if err := s.batchesFor(event).UpdateOrderStatus(event.OrderID, domain.ItemStatusDelivered); err != nil {
 		log.Printf("Failed to update order status in batch: %v", err)
 		return err
 	}
@@ -208,25 +334,28 @@ func (s *OrderService) processReturn(event domain.OrderEvent) error {
 	log.Printf("Processing return for order %s: ProductID=%s, Quantity=%d", 
 		event.OrderID, event.Order.ProductID, event.Order.Quantity)
 	
-	// Update order status to returned in batch
-	if err := s.batchService.UpdateOrderStatus(event.OrderID, "returned"); err != nil {
-		log.Printf("Failed to update order status in batch: %v", err)
-		return err
+	if s.returnService == nil {
+		// Update order status to returned in batch
+		if err := s.batchesFor(event).UpdateOrderStatus(event.OrderID, domain.ItemStatusReturned); err != nil {
+			log.Printf("Failed to update order status in batch: %v", err)
+			return err
+		}
+		log.Printf("Order %s marked as returned", event.OrderID)
+		return nil
 	}
 	
-	// Add returned item back to inventory by creating a new batch entry
-	_, err := s.batchService.AddOrderToBatch(
-		event.OrderID+"-return", 
-		event.Order.ProductID, 
-		event.Order.Quantity, 
-		"returned",
-	)
+	// The goods wait for inspection before their disposition is decided
+	rma, err := s.returnService.OpenReturn(event.OrderID, event.Order.Quantity, "returned by customer", domain.ReturnConditionUninspected)
 	if err != nil {
-		log.Printf("Failed to add returned item to batch: %v", err)
+		if errors.Is(err, domain.ErrAlreadyExists) {
+			log.Printf("Return for order %s already opened: %v", event.OrderID, err)
+			return nil
+		}
+		log.Printf("Failed to open return for order: %v", err)
 		return err
 	}
 	
-	log.Printf("Order %s processed as return and added back to inventory", event.OrderID)
+	log.Printf("Order %s processed as return %s", event.OrderID, rma.ID)
 	return nil
 }
 
@@ -235,7 +364,7 @@ func (s *OrderService) confirmAllocation(event domain.OrderEvent) error {
 	log.Printf("Confirming inventory allocation for order %s", event.OrderID)
 	
 	// Update order status to allocation confirmed in batch
-	if err := s.batchService.UpdateOrderStatus(event.OrderID, "allocation_confirmed"); err != nil {
+	if err := s.batchesFor(event).UpdateOrderStatus(event.OrderID, domain.ItemStatusAllocationConfirmed); err != nil {
 		log.Printf("Failed to update order status in batch: %v", err)
 		return err
 	}
@@ -249,7 +378,7 @@ func (s *OrderService) confirmRelease(event domain.OrderEvent) error {
 	log.Printf("Confirming inventory release for order %s", event.OrderID)
 	
 	// Update order status to release confirmed in batch
-	if err := s.batchService.UpdateOrderStatus(event.OrderID, "release_confirmed"); err != nil {
+	if err := s.batchesFor(event).UpdateOrderStatus(event.OrderID, domain.ItemStatusReleaseConfirmed); err != nil {
 		log.Printf("Failed to update order status in batch: %v", err)
 		return err
 	}
@@ -2,6 +2,7 @@ package application
 
 import (
 	"encoding/json"
+	"errors"
 	"testing"
 
 	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
@@ -136,7 +137,7 @@ func TestOrderService_ProcessDamage_CreatesBatchWhenNotExists(t *testing.T) {
 	tests := []struct {
 		name           string
 		damageStatus   string
-		expectedStatus string
+		expectedStatus domain.ItemStatus
 		shouldMarkDamaged bool
 	}{
 		{
@@ -272,4 +273,229 @@ func TestOrderService_ProcessDamage_UpdatesExistingBatch(t *testing.T) {
 	if batch.Status != domain.BatchStatusDamaged {
 		t.Errorf("Expected batch to be marked as damaged, got status '%s'", batch.Status)
 	}
-}
\ No newline at end of file
+}
+func TestOrderService_ProcessDamage_RejectsIllegalTransition(t *testing.T) {
+	repo := drivenadapters.NewBatchMemoryRepository()
+	batchService := NewBatchService(repo, domain.NewMockBatchEventPublisher())
+	service := NewOrderService(batchService)
+
+	if _, err := batchService.AddOrderToBatch("order-1", "product-1", 1, domain.ItemStatusDamageProcessed); err != nil {
+		t.Fatalf("Failed to add order: %v", err)
+	}
+
+	// A processed damage cannot be reported again, and must not be added to a second batch
+	err := service.HandleOrderEvent(domain.OrderEvent{
+		EventType: "order.damage_processed",
+		OrderID:   "order-1",
+		Order:     domain.Order{ID: "order-1", ProductID: "product-1", Quantity: 1, Status: "damage_detected_minor"},
+	})
+	if !errors.Is(err, domain.ErrInvalidTransition) {
+		t.Fatalf("Expected ErrInvalidTransition, got %v", err)
+	}
+
+	batches, _ := batchService.GetAllBatches()
+	if len(batches) != 1 || len(batches[0].Items) != 1 || batches[0].Items[0].Status != domain.ItemStatusDamageProcessed {
+		t.Errorf("Expected the order to stay damage_processed in its only batch, got %+v", batches)
+	}
+}
+
+func TestOrderService_ReservesStockBeforeBatching(t *testing.T) {
+	inventoryPublisher := domain.NewMockInventoryEventPublisher()
+	inventoryService := NewInventoryService(drivenadapters.NewInventoryMemoryRepository(), inventoryPublisher)
+	batchService := NewBatchService(drivenadapters.NewBatchMemoryRepository(), domain.NewMockBatchEventPublisher())
+	service := NewOrderService(batchService, WithInventoryService(inventoryService))
+
+	if _, err := inventoryService.ReceiveStock("product-1", 5, ""); err != nil {
+		t.Fatalf("Failed to receive stock: %v", err)
+	}
+
+	created := func(orderID string, quantity int) domain.OrderEvent {
+		return domain.OrderEvent{
+			EventType: "order.created",
+			OrderID:   orderID,
+			Order:     domain.Order{ID: orderID, ProductID: "product-1", Quantity: quantity, Status: "pending"},
+		}
+	}
+
+	if err := service.HandleOrderEvent(created("order-1", 3)); err != nil {
+		t.Fatalf("Failed to handle order created: %v", err)
+	}
+	if _, err := batchService.GetBatchByOrderID("order-1"); err != nil {
+		t.Errorf("Expected order-1 to be batched, got %v", err)
+	}
+
+	// order-2 cannot be filled: it is reported and not batched
+	if err := service.HandleOrderEvent(created("order-2", 3)); err != nil {
+		t.Fatalf("Failed to handle order created: %v", err)
+	}
+	if _, err := batchService.GetBatchByOrderID("order-2"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("Expected order-2 not to be batched, got %v", err)
+	}
+	if _, err := inventoryService.OutboxRelay().RelayPending(); err != nil {
+		t.Fatalf("Failed to relay inventory outbox: %v", err)
+	}
+	if len(inventoryPublisher.PublishedEvents) != 1 || inventoryPublisher.PublishedEvents[0].OrderID != "order-2" {
+		t.Errorf("Expected an allocation failed event for order-2, got %+v", inventoryPublisher.PublishedEvents)
+	}
+
+	// Cancelling releases the stock of order-1; order-2 has nothing to release
+	for _, orderID := range []string{"order-1", "order-2"} {
+		event := created(orderID, 3)
+		event.EventType = "order.cancelled"
+		if err := service.HandleOrderEvent(event); err != nil {
+			t.Fatalf("Failed to handle cancellation of %s: %v", orderID, err)
+		}
+	}
+
+	stock, _ := inventoryService.GetStock("product-1")
+	if stock.Reserved != 0 || stock.Available() != 5 {
+		t.Errorf("Expected all 5 units available after cancellation, got %+v", stock)
+	}
+}
+
+func TestOrderService_RollsBackReservationWhenBatchingFails(t *testing.T) {
+	inventoryService := NewInventoryService(drivenadapters.NewInventoryMemoryRepository(), domain.NewMockInventoryEventPublisher())
+	repo := &failingBatchRepository{BatchMemoryRepository: drivenadapters.NewBatchMemoryRepository(), fail: true}
+	batchService := NewBatchService(repo, domain.NewMockBatchEventPublisher())
+	service := NewOrderService(batchService, WithInventoryService(inventoryService))
+
+	if _, err := inventoryService.ReceiveStock("product-1", 5, ""); err != nil {
+		t.Fatalf("Failed to receive stock: %v", err)
+	}
+
+	event := domain.OrderEvent{
+		EventType: "order.created",
+		OrderID:   "order-1",
+		Order:     domain.Order{ID: "order-1", ProductID: "product-1", Quantity: 3, Status: "pending"},
+	}
+	if err := service.HandleOrderEvent(event); err == nil {
+		t.Fatal("Expected the batch write to fail")
+	}
+
+	stock, _ := inventoryService.GetStock("product-1")
+	if stock.Reserved != 0 || stock.Available() != 5 {
+		t.Errorf("Expected all 5 units available after the failed batching, got %+v", stock)
+	}
+
+	// The retried event reserves the stock again
+	repo.fail = false
+	if err := service.HandleOrderEvent(event); err != nil {
+		t.Fatalf("Failed to handle the retried order created: %v", err)
+	}
+	stock, _ = inventoryService.GetStock("product-1")
+	if stock.Reserved != 3 {
+		t.Errorf("Expected 3 units reserved for the batched order, got %+v", stock)
+	}
+	if _, err := batchService.GetBatchByOrderID("order-1"); err != nil {
+		t.Errorf("Expected order-1 to be batched, got %v", err)
+	}
+}
+
+// failingBatchRepository fails every save while fail is set, as if the database were down
+type failingBatchRepository struct {
+	*drivenadapters.BatchMemoryRepository
+	fail bool
+}
+
+func (r *failingBatchRepository) SaveWithEvents(batch *domain.Batch, command domain.BatchCommand, events ...*domain.BatchEvent) error {
+	if r.fail {
+		return errors.New("database unavailable")
+	}
+	return r.BatchMemoryRepository.SaveWithEvents(batch, command, events...)
+}
+
+func TestOrderService_RecordsSourceEventInBatchHistory(t *testing.T) {
+	batchService := NewBatchService(drivenadapters.NewBatchMemoryRepository(), domain.NewMockBatchEventPublisher())
+	service := NewOrderService(batchService)
+
+	event := domain.OrderEvent{
+		EventID:   "event-1",
+		EventType: "order.created",
+		OrderID:   "order-1",
+		Order:     domain.Order{ID: "order-1", ProductID: "product-1", Quantity: 2, Status: "pending"},
+	}
+	if err := service.HandleOrderEvent(event); err != nil {
+		t.Fatalf("Failed to handle order created: %v", err)
+	}
+
+	batch, err := batchService.GetBatchByOrderID("order-1")
+	if err != nil {
+		t.Fatalf("Failed to find batch of order-1: %v", err)
+	}
+	history, _, err := batchService.GetBatchHistory(batch.ID, 0, 10)
+	if err != nil {
+		t.Fatalf("Failed to get history: %v", err)
+	}
+	if len(history) != 1 || history[0].Command.Actor != OrderEventActor || history[0].Command.SourceEvent != "event-1" {
+		t.Errorf("Expected the order event to be recorded as the source, got %+v", history)
+	}
+}
+
+// recordedSpan is a span started by a recordingTracer
+type recordedSpan struct {
+	name   string
+	parent domain.TraceContext
+	ended  bool
+	err    error
+}
+
+// recordingTracer records the spans it starts; the trace parent of every span is its name
+type recordingTracer struct {
+	spans []*recordedSpan
+}
+
+func (t *recordingTracer) StartSpan(parent domain.TraceContext, name string, attributes map[string]string) (domain.TraceContext, func(err error)) {
+	span := &recordedSpan{name: name, parent: parent}
+	t.spans = append(t.spans, span)
+	return domain.TraceContext{TraceParent: name}, func(err error) {
+		span.ended = true
+		span.err = err
+	}
+}
+
+func TestOrderService_TracesOrderEventIntoBatchEvents(t *testing.T) {
+	tracer := &recordingTracer{}
+	publisher := domain.NewMockBatchEventPublisher()
+	batchService := NewBatchService(drivenadapters.NewBatchMemoryRepository(), publisher, WithTracer(tracer))
+	service := NewOrderService(batchService)
+
+	consumed := domain.TraceContext{TraceParent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}
+	event := domain.OrderEvent{
+		EventID:      "event-1",
+		EventType:    "order.created",
+		OrderID:      "order-1",
+		Order:        domain.Order{ID: "order-1", ProductID: "product-1", Quantity: 2, Status: "pending"},
+		TraceContext: consumed,
+	}
+	if err := service.HandleOrderEvent(event); err != nil {
+		t.Fatalf("Failed to handle order created: %v", err)
+	}
+
+	if len(tracer.spans) != 2 {
+		t.Fatalf("Expected spans for the order event and the batch command, got %d", len(tracer.spans))
+	}
+	handled, added := tracer.spans[0], tracer.spans[1]
+	if handled.name != "OrderService.HandleOrderEvent" || handled.parent != consumed || !handled.ended {
+		t.Errorf("Expected the order event to be handled in the trace of its message, got %+v", handled)
+	}
+	if added.name != "BatchService.AddOrderToBatchForCustomer" || added.parent.TraceParent != handled.name || !added.ended {
+		t.Errorf("Expected the batch command to be a child of the order event span, got %+v", added)
+	}
+
+	if _, err := batchService.OutboxRelay().RelayPending(); err != nil {
+		t.Fatalf("Failed to relay the outbox: %v", err)
+	}
+	for _, published := range publisher.GetPublishedEvents() {
+		if published.TraceContext.TraceParent != added.name {
+			t.Errorf("Expected %s to carry the trace of the batch command, got %+v", published.EventType, published.TraceContext)
+		}
+	}
+
+	// A failed command ends its span with the error
+	if err := batchService.ProcessBatch("missing"); err == nil {
+		t.Fatal("Expected processing a missing batch to fail")
+	}
+	if failed := tracer.spans[len(tracer.spans)-1]; failed.name != "BatchService.ProcessBatch" || !errors.Is(failed.err, domain.ErrNotFound) {
+		t.Errorf("Expected the span of the failed command to end with its error, got %+v", failed)
+	}
+}
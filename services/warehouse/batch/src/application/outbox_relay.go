@@ -0,0 +1,282 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+)
+
+// OutboxRelayConfig holds the polling and retry settings of the outbox relay
+type OutboxRelayConfig struct {
+	PollInterval   time.Duration
+	BatchSize      int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// DefaultOutboxRelayConfig returns the default outbox relay settings
+func DefaultOutboxRelayConfig() OutboxRelayConfig {
+	return OutboxRelayConfig{
+		PollInterval:   time.Second,
+		BatchSize:      100,
+		InitialBackoff: time.Second,
+		MaxBackoff:     time.Minute,
+	}
+}
+
+// OutboxRelay drains the transactional outbox and delivers batch events to the publisher.
+// Events of the same batch are delivered strictly in the order they were stored: when
+// an event fails, later events of that batch wait until it has been delivered, so a
+// failed event is retried at the capped backoff for as long as the publisher is down.
+// Only an event that cannot be decoded is marked dead, as retrying cannot deliver it.
+// Dead events are not delivered and no longer hold back their batch.
+//
+// With an asynchronous publisher the due events of a page are queued together, so the
+// publisher writes them in batches, and their outcomes are recorded once all of them
+// were reported.
+type OutboxRelay struct {
+	store     domain.OutboxStore
+	publisher domain.BatchEventPublisher
+	async     domain.AsyncBatchEventPublisher
+	config    OutboxRelayConfig
+	notify    chan struct{}
+	mutex     sync.Mutex
+}
+
+// withDefaults replaces the unset settings with the default ones
+func (config OutboxRelayConfig) withDefaults() OutboxRelayConfig {
+	defaults := DefaultOutboxRelayConfig()
+	if config.PollInterval <= 0 {
+		config.PollInterval = defaults.PollInterval
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaults.BatchSize
+	}
+	if config.InitialBackoff <= 0 {
+		config.InitialBackoff = defaults.InitialBackoff
+	}
+	if config.MaxBackoff < config.InitialBackoff {
+		config.MaxBackoff = config.InitialBackoff
+	}
+	return config
+}
+
+// backoff returns the delay before the given delivery attempt
+func (config OutboxRelayConfig) backoff(attempt int) time.Duration {
+	delay := config.InitialBackoff
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= config.MaxBackoff {
+			return config.MaxBackoff
+		}
+	}
+	return delay
+}
+
+// NewOutboxRelay creates a new OutboxRelay
+func NewOutboxRelay(store domain.OutboxStore, publisher domain.BatchEventPublisher, config OutboxRelayConfig) *OutboxRelay {
+	async, _ := publisher.(domain.AsyncBatchEventPublisher)
+	return &OutboxRelay{
+		store:     store,
+		publisher: publisher,
+		async:     async,
+		config:    config.withDefaults(),
+		notify:    make(chan struct{}, 1),
+	}
+}
+
+// Start drains the outbox until the context is cancelled
+func (r *OutboxRelay) Start(ctx context.Context) {
+	log.Printf("Starting outbox relay (poll interval: %s, batch size: %d)", r.config.PollInterval, r.config.BatchSize)
+
+	ticker := time.NewTicker(r.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Outbox relay stopping...")
+			return
+		case <-ticker.C:
+		case <-r.notify:
+		}
+
+		if _, err := r.RelayPending(); err != nil {
+			log.Printf("Error relaying outbox messages: %v", err)
+		}
+	}
+}
+
+// Notify wakes up the relay so newly stored events are delivered without waiting for the next poll
+func (r *OutboxRelay) Notify() {
+	select {
+	case r.notify <- struct{}{}:
+	default:
+	}
+}
+
+// RelayPending delivers all due outbox messages and returns how many were published.
+// The outbox is read page by page, so messages of blocked batches do not keep the
+// messages of other batches behind them from being delivered.
+func (r *OutboxRelay) RelayPending() (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := time.Now().UTC()
+	blocked := make(map[string]bool)
+	published := 0
+	var afterID int64
+	for {
+		messages, err := r.store.PendingOutboxMessages(afterID, r.config.BatchSize)
+		if err != nil {
+			return published, err
+		}
+
+		published += r.relayMessages(messages, blocked, now)
+
+		if len(messages) < r.config.BatchSize {
+			return published, nil
+		}
+		afterID = messages[len(messages)-1].ID
+	}
+}
+
+// relayMessages publishes the given messages in order and returns how many were delivered.
+// Batches in blocked have an earlier message waiting; they are added to it as messages fail.
+func (r *OutboxRelay) relayMessages(messages []*domain.OutboxMessage, blocked map[string]bool, now time.Time) int {
+	if r.async != nil {
+		return r.relayMessagesAsync(messages, blocked, now)
+	}
+
+	delivered := 0
+	for _, message := range messages {
+		event, due := r.dueEvent(message, blocked, now)
+		if !due {
+			continue
+		}
+		if r.recordDelivery(message, r.publisher.PublishBatchEvent(event), blocked, now) {
+			delivered++
+		}
+	}
+	return delivered
+}
+
+// relayMessagesAsync queues the due messages on the asynchronous publisher, waits until
+// their outcomes were reported and records them in order. The publisher fails the events
+// queued after a failed event of the same batch, so they wait for its retry.
+func (r *OutboxRelay) relayMessagesAsync(messages []*domain.OutboxMessage, blocked map[string]bool, now time.Time) int {
+	var mutex sync.Mutex
+	outcomes := make(map[int64]error, len(messages))
+
+	var queued []*domain.OutboxMessage
+	for _, message := range messages {
+		event, due := r.dueEvent(message, blocked, now)
+		if !due {
+			continue
+		}
+
+		id := message.ID
+		err := r.async.PublishBatchEventAsync(context.Background(), event, func(_ *domain.BatchEvent, err error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			outcomes[id] = err
+		})
+		if err != nil {
+			// The event was not queued, e.g. because the publisher is closed
+			r.recordDelivery(message, err, blocked, now)
+			continue
+		}
+		queued = append(queued, message)
+	}
+	if len(queued) == 0 {
+		return 0
+	}
+
+	if err := r.async.Flush(context.Background()); err != nil {
+		log.Printf("Failed to flush batch events: %v", err)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	delivered := 0
+	for _, message := range queued {
+		if blocked[message.BatchID] {
+			// An earlier event of the batch failed, so this one was held back
+			continue
+		}
+		err, reported := outcomes[message.ID]
+		if !reported {
+			err = errors.New("batch event delivery outcome not reported")
+		}
+		if r.recordDelivery(message, err, blocked, now) {
+			delivered++
+		}
+	}
+	return delivered
+}
+
+// dueEvent decodes a message that is due for delivery. Messages that must wait for an
+// earlier message or for their retry are skipped and block their batch; messages that
+// cannot be decoded are marked dead.
+func (r *OutboxRelay) dueEvent(message *domain.OutboxMessage, blocked map[string]bool, now time.Time) (*domain.BatchEvent, bool) {
+	if blocked[message.BatchID] {
+		return nil, false
+	}
+
+	if message.NextAttemptAt.After(now) {
+		// Waiting for a retry keeps later events of the same batch behind this one
+		blocked[message.BatchID] = true
+		return nil, false
+	}
+
+	event, err := message.Event()
+	if err != nil {
+		// Retrying cannot fix the payload
+		if !r.markDead(message, err) {
+			blocked[message.BatchID] = true
+		}
+		return nil, false
+	}
+	return event, true
+}
+
+// recordDelivery records the outcome of publishing a message and reports whether it was
+// delivered. A failed message blocks its batch until its retry; publish errors are
+// transient, so it is never given up on.
+func (r *OutboxRelay) recordDelivery(message *domain.OutboxMessage, err error, blocked map[string]bool, now time.Time) bool {
+	if err != nil {
+		blocked[message.BatchID] = true
+		nextAttemptAt := now.Add(r.config.backoff(message.Attempts + 1))
+		log.Printf("Failed to publish outbox message %d (%s for batch %s, attempt %d), retrying at %s: %v",
+			message.ID, message.EventType, message.BatchID, message.Attempts+1, nextAttemptAt.Format(time.RFC3339), err)
+		if markErr := r.store.MarkOutboxMessageFailed(message.ID, err.Error(), nextAttemptAt); markErr != nil {
+			log.Printf("Failed to record outbox failure for message %d: %v", message.ID, markErr)
+		}
+		return false
+	}
+
+	if err := r.store.MarkOutboxMessagePublished(message.ID); err != nil {
+		// The event will be delivered again; consumers must tolerate duplicates
+		log.Printf("Failed to mark outbox message %d as published: %v", message.ID, err)
+		blocked[message.BatchID] = true
+		return false
+	}
+	return true
+}
+
+// markDead stops retrying a message that cannot be decoded and reports whether it was
+// marked. A message that could not be marked stays pending and keeps blocking its batch.
+func (r *OutboxRelay) markDead(message *domain.OutboxMessage, cause error) bool {
+	log.Printf("Giving up on undecodable outbox message %d (%s for batch %s): %v",
+		message.ID, message.EventType, message.BatchID, cause)
+	if err := r.store.MarkOutboxMessageDead(message.ID, cause.Error()); err != nil {
+		log.Printf("Failed to mark outbox message %d as dead: %v", message.ID, err)
+		return false
+	}
+	return true
+}
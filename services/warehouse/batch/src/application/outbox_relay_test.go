@@ -0,0 +1,391 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+	drivenadapters "github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/infrastructure/driven-adapters"
+)
+
+// flakyBatchPublisher fails every publish for the batches listed in failBatches
+type flakyBatchPublisher struct {
+	failBatches map[string]bool
+	published   []*domain.BatchEvent
+}
+
+func (p *flakyBatchPublisher) PublishBatchEvent(event *domain.BatchEvent) error {
+	if p.failBatches[event.BatchID] {
+		return errors.New("broker unavailable")
+	}
+	p.published = append(p.published, event)
+	return nil
+}
+
+func TestBatchService_StoresEventsInOutbox(t *testing.T) {
+	repo := drivenadapters.NewBatchMemoryRepository()
+	mockPublisher := domain.NewMockBatchEventPublisher()
+	service := NewBatchService(repo, mockPublisher)
+
+	batch, err := service.AddOrderToBatch("order-1", "product-1", 5, "allocated")
+	if err != nil {
+		t.Fatalf("Failed to add order: %v", err)
+	}
+
+	// Nothing is published until the relay runs
+	if mockPublisher.GetEventCount() != 0 {
+		t.Fatalf("Expected no events before relaying, got %d", mockPublisher.GetEventCount())
+	}
+
+	pending, err := repo.PendingOutboxMessages(0, 0)
+	if err != nil {
+		t.Fatalf("Failed to read outbox: %v", err)
+	}
+	if len(pending) != 2 {
+		t.Fatalf("Expected 2 outbox messages, got %d", len(pending))
+	}
+
+	published, err := service.OutboxRelay().RelayPending()
+	if err != nil {
+		t.Fatalf("Failed to relay outbox: %v", err)
+	}
+	if published != 2 {
+		t.Errorf("Expected 2 published events, got %d", published)
+	}
+
+	events := mockPublisher.GetPublishedEvents()
+	if events[0].EventType != domain.BatchEventCreated || events[1].EventType != domain.BatchEventItemAdded {
+		t.Errorf("Expected created then item_added, got %s then %s", events[0].EventType, events[1].EventType)
+	}
+	if events[1].BatchID != batch.ID || events[1].Batch == nil || len(events[1].Batch.Items) != 1 {
+		t.Errorf("Expected item_added event to carry the batch snapshot, got %+v", events[1])
+	}
+
+	pending, _ = repo.PendingOutboxMessages(0, 0)
+	if len(pending) != 0 {
+		t.Errorf("Expected outbox to be drained, got %d messages", len(pending))
+	}
+}
+
+func TestBatchService_EventsSurvivePublisherOutage(t *testing.T) {
+	repo := drivenadapters.NewBatchMemoryRepository()
+	mockPublisher := domain.NewMockBatchEventPublisher()
+	service := NewBatchService(repo, mockPublisher, WithOutboxRelayConfig(OutboxRelayConfig{
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+	}))
+
+	if _, err := service.AddOrderToBatch("order-1", "product-1", 5, "allocated"); err != nil {
+		t.Fatalf("Failed to add order: %v", err)
+	}
+
+	mockPublisher.SetShouldFail(true, errors.New("kafka down"))
+	if published, _ := service.OutboxRelay().RelayPending(); published != 0 {
+		t.Fatalf("Expected nothing to be published while Kafka is down, got %d", published)
+	}
+
+	pending, _ := repo.PendingOutboxMessages(0, 0)
+	if len(pending) != 2 {
+		t.Fatalf("Expected events to stay in the outbox, got %d", len(pending))
+	}
+	if pending[0].Attempts != 1 || pending[0].LastError != "kafka down" {
+		t.Errorf("Expected failed attempt to be recorded, got attempts=%d error=%q", pending[0].Attempts, pending[0].LastError)
+	}
+
+	mockPublisher.SetShouldFail(false, nil)
+	time.Sleep(5 * time.Millisecond)
+
+	if published, err := service.OutboxRelay().RelayPending(); err != nil || published != 2 {
+		t.Fatalf("Expected 2 events after recovery, got %d (err: %v)", published, err)
+	}
+}
+
+func TestOutboxRelay_PreservesOrderPerBatch(t *testing.T) {
+	repo := drivenadapters.NewBatchMemoryRepository()
+
+	batchA := domain.NewBatch("batch-a", "product-a")
+	batchB := domain.NewBatch("batch-b", "product-b")
+	for _, batch := range []*domain.Batch{batchA, batchB} {
+		if err := repo.SaveWithEvents(batch, domain.BatchCommand{}, domain.NewBatchCreatedEvent(batch)); err != nil {
+			t.Fatalf("Failed to save batch: %v", err)
+		}
+	}
+	if err := repo.SaveWithEvents(batchA, domain.BatchCommand{}, domain.NewBatchProcessingStartedEvent(batchA)); err != nil {
+		t.Fatalf("Failed to save batch: %v", err)
+	}
+
+	publisher := &flakyBatchPublisher{failBatches: map[string]bool{"batch-a": true}}
+	relay := NewOutboxRelay(repo, publisher, OutboxRelayConfig{
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+	})
+
+	if _, err := relay.RelayPending(); err != nil {
+		t.Fatalf("Failed to relay: %v", err)
+	}
+
+	// batch-b is not held back by the failing batch-a
+	if len(publisher.published) != 1 || publisher.published[0].BatchID != "batch-b" {
+		t.Fatalf("Expected only batch-b to be published, got %+v", publisher.published)
+	}
+
+	pending, _ := repo.PendingOutboxMessages(0, 0)
+	if len(pending) != 2 {
+		t.Fatalf("Expected 2 pending messages for batch-a, got %d", len(pending))
+	}
+	if pending[0].Attempts != 1 {
+		t.Errorf("Expected first batch-a message to record the failure, got %d attempts", pending[0].Attempts)
+	}
+	if pending[1].Attempts != 0 {
+		t.Errorf("Expected second batch-a message not to be attempted before the first, got %d attempts", pending[1].Attempts)
+	}
+
+	publisher.failBatches = nil
+	time.Sleep(5 * time.Millisecond)
+
+	if _, err := relay.RelayPending(); err != nil {
+		t.Fatalf("Failed to relay: %v", err)
+	}
+
+	if len(publisher.published) != 3 {
+		t.Fatalf("Expected 3 published events, got %d", len(publisher.published))
+	}
+	if publisher.published[1].EventType != domain.BatchEventCreated || publisher.published[2].EventType != domain.BatchEventProcessing {
+		t.Errorf("Expected batch-a events in stored order, got %s then %s",
+			publisher.published[1].EventType, publisher.published[2].EventType)
+	}
+}
+
+// bufferingBatchPublisher is an asynchronous publisher that delivers the queued events on
+// Flush, failing the events of the batches listed in failBatches
+type bufferingBatchPublisher struct {
+	flakyBatchPublisher
+	queue   []*domain.BatchEvent
+	reports []domain.BatchEventDeliveryCallback
+	flushes int
+}
+
+func (p *bufferingBatchPublisher) PublishBatchEventAsync(ctx context.Context, event *domain.BatchEvent, callback domain.BatchEventDeliveryCallback) error {
+	p.queue = append(p.queue, event)
+	p.reports = append(p.reports, callback)
+	return nil
+}
+
+func (p *bufferingBatchPublisher) Flush(ctx context.Context) error {
+	p.flushes++
+	failed := make(map[string]bool)
+	for i, event := range p.queue {
+		var err error
+		if failed[event.BatchID] {
+			err = errors.New("held back")
+		} else if err = p.PublishBatchEvent(event); err != nil {
+			failed[event.BatchID] = true
+		}
+		p.reports[i](event, err)
+	}
+	p.queue, p.reports = nil, nil
+	return nil
+}
+
+func TestOutboxRelay_AsyncPublisher(t *testing.T) {
+	repo := drivenadapters.NewBatchMemoryRepository()
+
+	batchA := domain.NewBatch("batch-a", "product-a")
+	if err := repo.SaveWithEvents(batchA, domain.BatchCommand{}, domain.NewBatchCreatedEvent(batchA), domain.NewBatchProcessingStartedEvent(batchA)); err != nil {
+		t.Fatalf("Failed to save batch: %v", err)
+	}
+	batchB := domain.NewBatch("batch-b", "product-b")
+	if err := repo.SaveWithEvents(batchB, domain.BatchCommand{}, domain.NewBatchCreatedEvent(batchB)); err != nil {
+		t.Fatalf("Failed to save batch: %v", err)
+	}
+
+	publisher := &bufferingBatchPublisher{flakyBatchPublisher: flakyBatchPublisher{failBatches: map[string]bool{"batch-a": true}}}
+	relay := NewOutboxRelay(repo, publisher, OutboxRelayConfig{
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+	})
+
+	published, err := relay.RelayPending()
+	if err != nil {
+		t.Fatalf("Failed to relay: %v", err)
+	}
+	if published != 1 || publisher.flushes != 1 || len(publisher.published) != 1 || publisher.published[0].BatchID != "batch-b" {
+		t.Fatalf("Expected the events to be flushed at once with only batch-b delivered, got %d published in %d flushes",
+			published, publisher.flushes)
+	}
+
+	// The held back batch-a event does not count as an attempt
+	pending, _ := repo.PendingOutboxMessages(0, 0)
+	if len(pending) != 2 || pending[0].Attempts != 1 || pending[1].Attempts != 0 {
+		t.Fatalf("Expected only the first batch-a message to be attempted, got %+v", pending)
+	}
+
+	publisher.failBatches = nil
+	time.Sleep(5 * time.Millisecond)
+	if published, err := relay.RelayPending(); err != nil || published != 2 {
+		t.Fatalf("Expected the batch-a events after recovery, got %d (err: %v)", published, err)
+	}
+	if publisher.published[1].EventType != domain.BatchEventCreated || publisher.published[2].EventType != domain.BatchEventProcessing {
+		t.Errorf("Expected batch-a events in stored order, got %s then %s",
+			publisher.published[1].EventType, publisher.published[2].EventType)
+	}
+}
+
+func TestOutboxRelay_PagesPastBlockedBatches(t *testing.T) {
+	repo := drivenadapters.NewBatchMemoryRepository()
+
+	// A full page of events of a failing batch, followed by an event of another batch
+	blockedBatch := domain.NewBatch("batch-a", "product-a")
+	for i := 0; i < 3; i++ {
+		if err := repo.SaveWithEvents(blockedBatch, domain.BatchCommand{}, domain.NewBatchCreatedEvent(blockedBatch)); err != nil {
+			t.Fatalf("Failed to save batch: %v", err)
+		}
+	}
+	deliverable := domain.NewBatch("batch-b", "product-b")
+	if err := repo.SaveWithEvents(deliverable, domain.BatchCommand{}, domain.NewBatchCreatedEvent(deliverable)); err != nil {
+		t.Fatalf("Failed to save batch: %v", err)
+	}
+
+	publisher := &flakyBatchPublisher{failBatches: map[string]bool{"batch-a": true}}
+	relay := NewOutboxRelay(repo, publisher, OutboxRelayConfig{
+		BatchSize:      2,
+		InitialBackoff: time.Hour,
+		MaxBackoff:     time.Hour,
+	})
+
+	for run := 0; run < 2; run++ {
+		if _, err := relay.RelayPending(); err != nil {
+			t.Fatalf("Failed to relay: %v", err)
+		}
+	}
+
+	if len(publisher.published) != 1 || publisher.published[0].BatchID != "batch-b" {
+		t.Fatalf("Expected batch-b to be published past the blocked batch-a, got %+v", publisher.published)
+	}
+	pending, _ := repo.PendingOutboxMessages(0, 0)
+	if len(pending) != 3 || pending[0].Attempts != 1 || pending[1].Attempts != 0 {
+		t.Errorf("Expected only the first batch-a message to be attempted, got %+v", pending)
+	}
+}
+
+// corruptingOutboxStore returns the payload of the listed messages corrupted
+type corruptingOutboxStore struct {
+	domain.OutboxStore
+	corrupt map[int64]bool
+}
+
+func (s *corruptingOutboxStore) PendingOutboxMessages(afterID int64, limit int) ([]*domain.OutboxMessage, error) {
+	messages, err := s.OutboxStore.PendingOutboxMessages(afterID, limit)
+	for _, message := range messages {
+		if s.corrupt[message.ID] {
+			message.Payload = []byte(`{"event_type":`)
+		}
+	}
+	return messages, err
+}
+
+func TestOutboxRelay_MarksUndeliverableMessagesDead(t *testing.T) {
+	repo := drivenadapters.NewBatchMemoryRepository()
+
+	batchA := domain.NewBatch("batch-a", "product-a")
+	if err := repo.SaveWithEvents(batchA, domain.BatchCommand{}, domain.NewBatchCreatedEvent(batchA), domain.NewBatchProcessingStartedEvent(batchA)); err != nil {
+		t.Fatalf("Failed to save batch: %v", err)
+	}
+	batchB := domain.NewBatch("batch-b", "product-b")
+	if err := repo.SaveWithEvents(batchB, domain.BatchCommand{}, domain.NewBatchCreatedEvent(batchB), domain.NewBatchProcessingStartedEvent(batchB)); err != nil {
+		t.Fatalf("Failed to save batch: %v", err)
+	}
+	pending, _ := repo.PendingOutboxMessages(0, 0)
+
+	publisher := &flakyBatchPublisher{failBatches: map[string]bool{"batch-b": true}}
+	store := &corruptingOutboxStore{OutboxStore: repo, corrupt: map[int64]bool{pending[0].ID: true}}
+	relay := NewOutboxRelay(store, publisher, OutboxRelayConfig{
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+	})
+
+	// The undecodable first event of batch-a does not hold back the second one
+	if _, err := relay.RelayPending(); err != nil {
+		t.Fatalf("Failed to relay: %v", err)
+	}
+	if len(publisher.published) != 1 || publisher.published[0].EventType != domain.BatchEventProcessing {
+		t.Fatalf("Expected the second batch-a event to be published, got %+v", publisher.published)
+	}
+
+	// Publish failures of batch-b are retried instead of being given up on
+	for run := 0; run < 4; run++ {
+		time.Sleep(5 * time.Millisecond)
+		if _, err := relay.RelayPending(); err != nil {
+			t.Fatalf("Failed to relay: %v", err)
+		}
+	}
+
+	pending, _ = repo.PendingOutboxMessages(0, 0)
+	if len(pending) != 2 || pending[0].BatchID != "batch-b" || pending[1].BatchID != "batch-b" {
+		t.Errorf("Expected only the batch-b events to stay pending, got %+v", pending)
+	}
+	if len(publisher.published) != 1 {
+		t.Errorf("Expected dead messages not to be published, got %d events", len(publisher.published))
+	}
+}
+
+func TestOutboxRelay_DeliversInOrderAfterLongOutage(t *testing.T) {
+	repo := drivenadapters.NewBatchMemoryRepository()
+
+	batch := domain.NewBatch("batch-1", "product-1")
+	events := []*domain.BatchEvent{
+		domain.NewBatchCreatedEvent(batch),
+		domain.NewBatchProcessingStartedEvent(batch),
+		domain.NewBatchCompletedEvent(batch),
+	}
+	for _, event := range events {
+		if err := repo.SaveWithEvents(batch, domain.BatchCommand{}, event); err != nil {
+			t.Fatalf("Failed to save batch: %v", err)
+		}
+	}
+
+	publisher := &flakyBatchPublisher{failBatches: map[string]bool{"batch-1": true}}
+	relay := NewOutboxRelay(repo, publisher, OutboxRelayConfig{
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+	})
+
+	// The broker stays down for more attempts than any retry budget would allow
+	for run := 0; run < 30; run++ {
+		time.Sleep(2 * time.Millisecond)
+		if _, err := relay.RelayPending(); err != nil {
+			t.Fatalf("Failed to relay: %v", err)
+		}
+	}
+	pending, _ := repo.PendingOutboxMessages(0, 0)
+	if len(pending) != len(events) || pending[0].Attempts < 20 {
+		t.Fatalf("Expected every event to stay pending through the outage, got %+v", pending)
+	}
+
+	delete(publisher.failBatches, "batch-1")
+	time.Sleep(2 * time.Millisecond)
+	if published, err := relay.RelayPending(); err != nil || published != len(events) {
+		t.Fatalf("Expected %d events after recovery, got %d (err: %v)", len(events), published, err)
+	}
+	for i, event := range events {
+		if publisher.published[i].EventType != event.EventType {
+			t.Errorf("Expected event %d to be %s, got %s", i, event.EventType, publisher.published[i].EventType)
+		}
+	}
+}
+
+func TestOutboxRelay_Backoff(t *testing.T) {
+	relay := NewOutboxRelay(nil, nil, OutboxRelayConfig{
+		InitialBackoff: time.Second,
+		MaxBackoff:     5 * time.Second,
+	})
+
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, want := range expected {
+		if got := relay.config.backoff(i + 1); got != want {
+			t.Errorf("Attempt %d: expected backoff %s, got %s", i+1, want, got)
+		}
+	}
+}
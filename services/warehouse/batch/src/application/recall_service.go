@@ -0,0 +1,203 @@
+package application
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+)
+
+// RecallService runs product recalls: it finds the batches holding the recalled product
+// or lot, puts their stock on hold and tracks the affected orders until they are resolved
+// with their customers. The recall events are stored in the batch outbox together with
+// the hold of each batch, so downstream services are notified through the outbox relay.
+type RecallService struct {
+	batchRepo   domain.BatchRepository
+	recallRepo  domain.RecallRepository
+	outboxRelay *OutboxRelay
+
+	// conflictRetries is how many times a command is retried after a concurrency conflict
+	conflictRetries int
+}
+
+// NewRecallService creates a new RecallService. The outbox relay is the one delivering
+// the events of the batch repository.
+func NewRecallService(batchRepo domain.BatchRepository, recallRepo domain.RecallRepository, outboxRelay *OutboxRelay) *RecallService {
+	return &RecallService{
+		batchRepo:       batchRepo,
+		recallRepo:      recallRepo,
+		outboxRelay:     outboxRelay,
+		conflictRetries: DefaultConflictRetries,
+	}
+}
+
+// InitiateRecall opens the recall of a product, or of one lot of it when a lot number
+// is given, and puts every affected batch on hold. It fails with an ErrNotFound error
+// when no batch holds the product or lot.
+func (s *RecallService) InitiateRecall(productID, lotNumber, reason, initiatedBy string) (*domain.RecallCase, error) {
+	log.Printf("Initiating recall of product %s (lot: %q) by %s: %s", productID, lotNumber, initiatedBy, reason)
+
+	now := time.Now()
+	recall, err := domain.NewRecallCase(s.generateRecallID(productID), productID, lotNumber, reason, initiatedBy, now)
+	if err != nil {
+		return nil, err
+	}
+
+	batches, err := s.batchRepo.FindByProductID(productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find batches for product %s: %w", productID, err)
+	}
+	for _, batch := range batches {
+		if !recall.Covers(batch) {
+			continue
+		}
+		if err := recall.AddBatch(batch, now); err != nil {
+			return nil, err
+		}
+	}
+	if len(recall.Batches) == 0 {
+		return nil, domain.NewNotFoundError("no batches found for product %s lot %q", productID, lotNumber)
+	}
+
+	if err := s.recallRepo.Save(recall); err != nil {
+		return nil, fmt.Errorf("failed to save recall %s: %w", recall.ID, err)
+	}
+
+	if err := s.containRecall(recall); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Recall %s affects %d batches, %d orders and %d customers",
+		recall.ID, len(recall.Batches), len(recall.Orders), len(recall.CustomerIDs()))
+	return recall, nil
+}
+
+// ResumeRecall puts on hold the batches of a recall that were left pending, for
+// instance because the service stopped while the recall was being initiated
+func (s *RecallService) ResumeRecall(recallID string) (*domain.RecallCase, error) {
+	log.Printf("Resuming recall %s", recallID)
+
+	recall, err := s.recallRepo.FindByID(recallID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.containRecall(recall); err != nil {
+		return nil, err
+	}
+	return recall, nil
+}
+
+// containRecall puts the pending batches of the recall on hold one by one, saving the
+// recall after each batch so an interrupted recall can be resumed
+func (s *RecallService) containRecall(recall *domain.RecallCase) error {
+	for _, batchID := range recall.PendingBatchIDs() {
+		status, err := s.holdRecalledBatch(recall, batchID)
+		if err != nil {
+			return err
+		}
+
+		if err := recall.RecordBatchOutcome(batchID, status, time.Now()); err != nil {
+			return err
+		}
+		if err := s.recallRepo.Save(recall); err != nil {
+			return fmt.Errorf("failed to save recall %s: %w", recall.ID, err)
+		}
+	}
+	return nil
+}
+
+// holdRecalledBatch puts a batch on hold and stores the recall event announcing its
+// affected orders. A batch with no stock left keeps its status but still announces
+// its orders.
+func (s *RecallService) holdRecalledBatch(recall *domain.RecallCase, batchID string) (domain.RecallBatchStatus, error) {
+	status := domain.RecallBatchHeld
+	err := retryOnConflict(fmt.Sprintf("hold batch %s for recall %s", batchID, recall.ID), s.conflictRetries, func() error {
+		batch, err := s.batchRepo.FindByID(batchID)
+		if errors.Is(err, domain.ErrNotFound) {
+			// The batch was emptied and deleted since the recall was initiated
+			status = domain.RecallBatchNotInStock
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to find batch %s: %w", batchID, err)
+		}
+
+		var events []*domain.BatchEvent
+		if batch.Can(domain.BatchActionHold) == nil {
+			status = domain.RecallBatchHeld
+			if err := batch.PlaceOnHold(fmt.Sprintf("recall %s: %s", recall.ID, recall.Reason), time.Now()); err != nil {
+				return fmt.Errorf("failed to put batch on hold: %w", err)
+			}
+			events = append(events, domain.NewBatchOnHoldEvent(batch))
+		} else {
+			status = domain.RecallBatchNotInStock
+		}
+		events = append(events, domain.NewBatchRecalledEvent(batch, recall))
+
+		command := domain.BatchCommand{Name: domain.BatchCommandRecallHold, BatchIssuer: domain.BatchIssuer{Actor: recall.InitiatedBy}}
+		if err := s.batchRepo.SaveWithEvents(batch, command, events...); err != nil {
+			return fmt.Errorf("failed to save batch: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	s.outboxRelay.Notify()
+
+	log.Printf("Batch %s of recall %s: %s", batchID, recall.ID, status)
+	return status, nil
+}
+
+// ResolveRecallOrder records that an affected order was settled with its customer
+func (s *RecallService) ResolveRecallOrder(recallID, orderID string) (*domain.RecallCase, error) {
+	log.Printf("Resolving order %s of recall %s", orderID, recallID)
+
+	var recall *domain.RecallCase
+	err := retryOnConflict(fmt.Sprintf("resolve order %s of recall %s", orderID, recallID), s.conflictRetries, func() error {
+		var err error
+		recall, err = s.recallRepo.FindByID(recallID)
+		if err != nil {
+			return err
+		}
+
+		if err := recall.ResolveOrder(orderID, time.Now()); err != nil {
+			return err
+		}
+
+		if err := s.recallRepo.Save(recall); err != nil {
+			return fmt.Errorf("failed to save recall %s: %w", recallID, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return recall, nil
+}
+
+// GetRecall retrieves a recall case by its ID
+func (s *RecallService) GetRecall(recallID string) (*domain.RecallCase, error) {
+	return s.recallRepo.FindByID(recallID)
+}
+
+// GetAllRecalls retrieves all recall cases
+func (s *RecallService) GetAllRecalls() ([]*domain.RecallCase, error) {
+	return s.recallRepo.GetAll()
+}
+
+// generateRecallID generates a unique recall ID in the style of the batch IDs
+func (s *RecallService) generateRecallID(productID string) string {
+	timestamp := time.Now().Format("20060102150405")
+
+	suffix := make([]byte, 3)
+	if _, err := rand.Read(suffix); err != nil {
+		return fmt.Sprintf("RECALL-%s-%s-%d", productID, timestamp, time.Now().UnixNano()%1000000)
+	}
+	return fmt.Sprintf("RECALL-%s-%s-%s", productID, timestamp, hex.EncodeToString(suffix))
+}
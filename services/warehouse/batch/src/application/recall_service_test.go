@@ -0,0 +1,130 @@
+package application
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+	drivenadapters "github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/infrastructure/driven-adapters"
+)
+
+func TestRecallService_InitiateRecall(t *testing.T) {
+	repo := drivenadapters.NewBatchMemoryRepository()
+	mockPublisher := domain.NewMockBatchEventPublisher()
+	batchService := NewBatchService(repo, mockPublisher)
+	recallService := NewRecallService(repo, drivenadapters.NewRecallMemoryRepository(), batchService.OutboxRelay())
+
+	manufactured := time.Now().AddDate(0, -1, 0)
+	recalled, err := batchService.CreateLotBatch("product-1", "LOT-A", manufactured, time.Now().AddDate(0, 6, 0))
+	if err != nil {
+		t.Fatalf("Failed to create lot batch: %v", err)
+	}
+	other, err := batchService.CreateLotBatch("product-1", "LOT-B", manufactured, time.Now().AddDate(1, 0, 0))
+	if err != nil {
+		t.Fatalf("Failed to create lot batch: %v", err)
+	}
+	if _, err := batchService.AddOrderToBatchForCustomer("order-1", "customer-1", "product-1", 2, domain.ItemStatusAllocated); err != nil {
+		t.Fatalf("Failed to add order: %v", err)
+	}
+
+	if _, err := recallService.InitiateRecall("product-1", "LOT-Z", "contamination", "qa-lead"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for an unknown lot, got %v", err)
+	}
+
+	recall, err := recallService.InitiateRecall("product-1", "LOT-A", "contamination", "qa-lead")
+	if err != nil {
+		t.Fatalf("Failed to initiate recall: %v", err)
+	}
+	if recall.Status != domain.RecallStatusContained || len(recall.Batches) != 1 || recall.Batches[0].BatchID != recalled.ID {
+		t.Fatalf("Expected the LOT-A batch to be contained, got %+v", recall)
+	}
+	if len(recall.Orders) != 1 || recall.Orders[0].CustomerID != "customer-1" {
+		t.Errorf("Expected order-1 of customer-1 to be affected, got %+v", recall.Orders)
+	}
+
+	held, _ := batchService.GetBatchByID(recalled.ID)
+	if held.Status != domain.BatchStatusOnHold {
+		t.Errorf("Expected recalled batch on hold, got %s", held.Status)
+	}
+	untouched, _ := batchService.GetBatchByID(other.ID)
+	if untouched.Status != domain.BatchStatusPending {
+		t.Errorf("Expected other lot to stay pending, got %s", untouched.Status)
+	}
+
+	// Stock on hold is no longer allocated
+	next, err := batchService.AddOrderToBatch("order-2", "product-1", 1, domain.ItemStatusAllocated)
+	if err != nil {
+		t.Fatalf("Failed to add order: %v", err)
+	}
+	if next.ID != other.ID {
+		t.Errorf("Expected order-2 to be allocated from LOT-B, got batch %s", next.ID)
+	}
+
+	if _, err := batchService.OutboxRelay().RelayPending(); err != nil {
+		t.Fatalf("Failed to relay events: %v", err)
+	}
+	var recallEvent *domain.BatchEvent
+	for _, event := range mockPublisher.GetPublishedEvents() {
+		if event.EventType == domain.BatchEventRecalled {
+			recallEvent = event
+		}
+	}
+	if recallEvent == nil || recallEvent.Recall == nil || recallEvent.Recall.RecallID != recall.ID {
+		t.Fatalf("Expected a recall event for %s, got %+v", recall.ID, recallEvent)
+	}
+
+	resolved, err := recallService.ResolveRecallOrder(recall.ID, "order-1")
+	if err != nil {
+		t.Fatalf("Failed to resolve order: %v", err)
+	}
+	if resolved.Status != domain.RecallStatusClosed {
+		t.Errorf("Expected recall to be closed, got %s", resolved.Status)
+	}
+
+	stored, err := recallService.GetRecall(recall.ID)
+	if err != nil || stored.Status != domain.RecallStatusClosed {
+		t.Errorf("Expected the closed recall to be stored, got %+v (%v)", stored, err)
+	}
+}
+
+func TestRecallService_ResumeRecall(t *testing.T) {
+	repo := drivenadapters.NewBatchMemoryRepository()
+	recallRepo := drivenadapters.NewRecallMemoryRepository()
+	batchService := NewBatchService(repo, domain.NewMockBatchEventPublisher())
+	recallService := NewRecallService(repo, recallRepo, batchService.OutboxRelay())
+
+	pending, err := batchService.AddOrderToBatch("order-1", "product-1", 2, domain.ItemStatusAllocated)
+	if err != nil {
+		t.Fatalf("Failed to add order: %v", err)
+	}
+
+	// A recall saved before its batches were put on hold, as left by a crash
+	recall, _ := domain.NewRecallCase("recall-1", "product-1", "", "label error", "qa-lead", time.Now())
+	recall.AddBatch(pending, time.Now())
+	recall.AddBatch(domain.NewBatch("BATCH-gone", "product-1"), time.Now())
+	if err := recallRepo.Save(recall); err != nil {
+		t.Fatalf("Failed to save recall: %v", err)
+	}
+
+	resumed, err := recallService.ResumeRecall("recall-1")
+	if err != nil {
+		t.Fatalf("Failed to resume recall: %v", err)
+	}
+	if resumed.Status != domain.RecallStatusContained {
+		t.Errorf("Expected recall to be contained, got %s", resumed.Status)
+	}
+	for _, batch := range resumed.Batches {
+		expected := domain.RecallBatchHeld
+		if batch.BatchID == "BATCH-gone" {
+			expected = domain.RecallBatchNotInStock
+		}
+		if batch.Status != expected {
+			t.Errorf("Expected batch %s to be %s, got %s", batch.BatchID, expected, batch.Status)
+		}
+	}
+
+	if _, err := recallService.ResumeRecall("recall-9"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for an unknown recall, got %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+package application
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+)
+
+// ReturnService handles customer returns: it opens a return merchandise authorization
+// (RMA) for a shipped or delivered order, marks the order as returned in its batch and
+// records the disposition of the returned goods.
+type ReturnService struct {
+	batchService *BatchService
+	returnRepo   domain.ReturnRepository
+
+	// inventoryService puts restocked goods back into the stock ledger; without it the
+	// disposition is only recorded
+	inventoryService *InventoryService
+
+	// conflictRetries is how many times a command is retried after a concurrency conflict
+	conflictRetries int
+}
+
+// ReturnServiceOption configures optional ReturnService behaviour
+type ReturnServiceOption func(*ReturnService)
+
+// WithRestockLedger receives the goods of restocked returns into the stock ledger
+func WithRestockLedger(inventoryService *InventoryService) ReturnServiceOption {
+	return func(s *ReturnService) {
+		s.inventoryService = inventoryService
+	}
+}
+
+// NewReturnService creates a new ReturnService
+func NewReturnService(batchService *BatchService, returnRepo domain.ReturnRepository, opts ...ReturnServiceOption) *ReturnService {
+	service := &ReturnService{
+		batchService:    batchService,
+		returnRepo:      returnRepo,
+		conflictRetries: DefaultConflictRetries,
+	}
+	for _, opt := range opts {
+		opt(service)
+	}
+	return service
+}
+
+// OpenReturn opens the return of an order and marks the order as returned in its batch.
+// A zero quantity returns the whole order. An order can only be returned once; a second
+// return fails with an ErrAlreadyExists error.
+func (s *ReturnService) OpenReturn(orderID string, quantity int, reason string, condition domain.ReturnCondition) (*domain.ReturnAuthorization, error) {
+	log.Printf("Opening return for order %s (quantity: %d, condition: %s): %s", orderID, quantity, condition, reason)
+
+	if existing, err := s.returnRepo.FindByOrderID(orderID); err == nil {
+		return nil, domain.NewAlreadyExistsError("order %s already has return %s", orderID, existing.ID)
+	} else if !errors.Is(err, domain.ErrNotFound) {
+		return nil, fmt.Errorf("failed to find return for order %s: %w", orderID, err)
+	}
+
+	batch, err := s.batchService.GetBatchByOrderID(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	rma, err := domain.NewReturnAuthorization(s.generateReturnID(orderID), batch, orderID, quantity, reason, condition, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	// The audit trail of the batch names the return that changed the order
+	batches := s.batchService.IssuedBy(domain.BatchIssuer{Actor: "return " + rma.ID})
+	if err := batches.UpdateOrderStatus(orderID, domain.ItemStatusReturned); err != nil {
+		return nil, err
+	}
+
+	if err := s.returnRepo.Save(rma); err != nil {
+		return nil, fmt.Errorf("failed to save return %s: %w", rma.ID, err)
+	}
+
+	log.Printf("Return %s opened for order %s of batch %s", rma.ID, orderID, rma.BatchID)
+	return rma, nil
+}
+
+// ResolveReturn records the disposition of the goods of a return. Restocked goods are
+// received back into the stock ledger. An empty condition keeps the recorded condition.
+func (s *ReturnService) ResolveReturn(returnID string, disposition domain.ReturnDisposition, condition domain.ReturnCondition, decidedBy string) (*domain.ReturnAuthorization, error) {
+	log.Printf("Resolving return %s with disposition %s by %s", returnID, disposition, decidedBy)
+
+	var rma *domain.ReturnAuthorization
+	err := retryOnConflict(fmt.Sprintf("resolve return %s", returnID), s.conflictRetries, func() error {
+		var err error
+		rma, err = s.returnRepo.FindByID(returnID)
+		if err != nil {
+			return err
+		}
+
+		if err := rma.Resolve(disposition, condition, decidedBy, time.Now()); err != nil {
+			return err
+		}
+
+		if err := s.returnRepo.Save(rma); err != nil {
+			return fmt.Errorf("failed to save return %s: %w", returnID, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if disposition == domain.ReturnDispositionRestock && s.inventoryService != nil {
+		reason := fmt.Sprintf("restock of return %s for order %s", rma.ID, rma.OrderID)
+		if _, err := s.inventoryService.ReceiveStock(rma.ProductID, rma.Quantity, reason); err != nil {
+			return nil, fmt.Errorf("return %s was resolved but its stock was not restocked: %w", rma.ID, err)
+		}
+	}
+
+	log.Printf("Return %s is %s (%s)", rma.ID, rma.Status, rma.Disposition)
+	return rma, nil
+}
+
+// GetReturn retrieves a return by its ID
+func (s *ReturnService) GetReturn(returnID string) (*domain.ReturnAuthorization, error) {
+	return s.returnRepo.FindByID(returnID)
+}
+
+// GetReturnsByBatchID retrieves the returns of the items of a batch
+func (s *ReturnService) GetReturnsByBatchID(batchID string) ([]*domain.ReturnAuthorization, error) {
+	if _, err := s.batchService.GetBatchByID(batchID); err != nil {
+		return nil, err
+	}
+	return s.returnRepo.FindByBatchID(batchID)
+}
+
+// GetAllReturns retrieves all returns
+func (s *ReturnService) GetAllReturns() ([]*domain.ReturnAuthorization, error) {
+	return s.returnRepo.GetAll()
+}
+
+// generateReturnID generates a unique return ID in the style of the batch IDs
+func (s *ReturnService) generateReturnID(orderID string) string {
+	timestamp := time.Now().Format("20060102150405")
+
+	suffix := make([]byte, 3)
+	if _, err := rand.Read(suffix); err != nil {
+		return fmt.Sprintf("RMA-%s-%s-%d", orderID, timestamp, time.Now().UnixNano()%1000000)
+	}
+	return fmt.Sprintf("RMA-%s-%s-%s", orderID, timestamp, hex.EncodeToString(suffix))
+}
@@ -0,0 +1,111 @@
+package application
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+	drivenadapters "github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/infrastructure/driven-adapters"
+)
+
+func TestReturnService_OpenAndResolveReturn(t *testing.T) {
+	repo := drivenadapters.NewBatchMemoryRepository()
+	batchService := NewBatchService(repo, domain.NewMockBatchEventPublisher())
+	inventoryService := NewInventoryService(drivenadapters.NewInventoryMemoryRepository(), domain.NewMockInventoryEventPublisher())
+	returnService := NewReturnService(batchService, drivenadapters.NewReturnMemoryRepository(), WithRestockLedger(inventoryService))
+
+	batch, err := batchService.AddOrderToBatchForCustomer("order-1", "customer-1", "product-1", 3, domain.ItemStatusDelivered)
+	if err != nil {
+		t.Fatalf("Failed to add order: %v", err)
+	}
+	if _, err := batchService.AddOrderToBatch("order-2", "product-1", 1, domain.ItemStatusAllocated); err != nil {
+		t.Fatalf("Failed to add order: %v", err)
+	}
+
+	if _, err := returnService.OpenReturn("order-9", 0, "wrong item", ""); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for an unknown order, got %v", err)
+	}
+	if _, err := returnService.OpenReturn("order-2", 0, "wrong item", ""); !errors.Is(err, domain.ErrInvalidTransition) {
+		t.Errorf("Expected ErrInvalidTransition for an order still in the warehouse, got %v", err)
+	}
+
+	rma, err := returnService.OpenReturn("order-1", 2, "wrong item", "")
+	if err != nil {
+		t.Fatalf("Failed to open return: %v", err)
+	}
+	if rma.BatchID != batch.ID || rma.CustomerID != "customer-1" || rma.Quantity != 2 {
+		t.Errorf("Expected return of 2 units linked to the batch item, got %+v", rma)
+	}
+	returned, _ := batchService.GetBatchByID(batch.ID)
+	item, _ := returned.GetItemByOrderID("order-1")
+	if item.Status != domain.ItemStatusReturned {
+		t.Errorf("Expected order to be marked as returned, got %s", item.Status)
+	}
+	if _, err := returnService.OpenReturn("order-1", 1, "wrong item", ""); !errors.Is(err, domain.ErrAlreadyExists) {
+		t.Errorf("Expected ErrAlreadyExists for a second return, got %v", err)
+	}
+
+	if _, err := returnService.ResolveReturn(rma.ID, domain.ReturnDispositionRestock, "", "qa-lead"); !errors.Is(err, domain.ErrValidation) {
+		t.Errorf("Expected ErrValidation restocking uninspected goods, got %v", err)
+	}
+	rma, err = returnService.ResolveReturn(rma.ID, domain.ReturnDispositionRestock, domain.ReturnConditionSealed, "qa-lead")
+	if err != nil {
+		t.Fatalf("Failed to resolve return: %v", err)
+	}
+	if rma.Status != domain.ReturnStatusResolved || rma.Disposition != domain.ReturnDispositionRestock {
+		t.Errorf("Expected restocked return, got %+v", rma)
+	}
+
+	stock, err := inventoryService.GetStock("product-1")
+	if err != nil {
+		t.Fatalf("Failed to get stock: %v", err)
+	}
+	if stock.OnHand != 2 {
+		t.Errorf("Expected the returned units back on hand, got %d", stock.OnHand)
+	}
+
+	returns, _ := returnService.GetReturnsByBatchID(batch.ID)
+	if len(returns) != 1 || returns[0].ID != rma.ID {
+		t.Errorf("Expected the return listed under its batch, got %d returns", len(returns))
+	}
+	if _, err := returnService.GetReturnsByBatchID("batch-9"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for an unknown batch, got %v", err)
+	}
+}
+
+func TestOrderService_ReturnedOrderOpensReturn(t *testing.T) {
+	repo := drivenadapters.NewBatchMemoryRepository()
+	batchService := NewBatchService(repo, domain.NewMockBatchEventPublisher())
+	returnService := NewReturnService(batchService, drivenadapters.NewReturnMemoryRepository())
+	orderService := NewOrderService(batchService, WithReturnService(returnService))
+
+	if _, err := batchService.AddOrderToBatch("order-1", "product-1", 2, domain.ItemStatusShipped); err != nil {
+		t.Fatalf("Failed to add order: %v", err)
+	}
+
+	event := domain.OrderEvent{
+		EventType: "order.returned",
+		OrderID:   "order-1",
+		Order:     domain.Order{ID: "order-1", ProductID: "product-1", Quantity: 2, Status: "returned"},
+	}
+	for i := 0; i < 2; i++ {
+		if err := orderService.HandleOrderEvent(event); err != nil {
+			t.Fatalf("Failed to handle return event: %v", err)
+		}
+	}
+
+	returns, _ := returnService.GetAllReturns()
+	if len(returns) != 1 || returns[0].Condition != domain.ReturnConditionUninspected || returns[0].Status != domain.ReturnStatusOpen {
+		t.Fatalf("Expected one open return awaiting inspection, got %d returns", len(returns))
+	}
+
+	// No synthetic item is added for the returned goods
+	batches, _ := repo.GetAll()
+	items := 0
+	for _, batch := range batches {
+		items += len(batch.Items)
+	}
+	if items != 1 {
+		t.Errorf("Expected only the original item, got %d items", items)
+	}
+}
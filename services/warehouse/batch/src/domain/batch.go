@@ -1,7 +1,6 @@
 package domain
 
 import (
-	"fmt"
 	"time"
 )
 
@@ -14,28 +13,47 @@ const (
 	BatchStatusCompleted  BatchStatus = "completed"
 	BatchStatusCancelled  BatchStatus = "cancelled"
 	BatchStatusDamaged    BatchStatus = "damaged"
+	BatchStatusInspected  BatchStatus = "inspected"
+	BatchStatusDestroyed  BatchStatus = "destroyed"
+	BatchStatusOnHold     BatchStatus = "on_hold"
 )
 
 // BatchItem represents an item within a batch
 type BatchItem struct {
-	OrderID     string    `json:"order_id"`
-	ProductID   string    `json:"product_id"`
-	Quantity    int       `json:"quantity"`
-	Status      string    `json:"status"`
-	AddedAt     time.Time `json:"added_at"`
+	OrderID     string     `json:"order_id"`
+	CustomerID  string     `json:"customer_id,omitempty"`
+	ProductID   string     `json:"product_id"`
+	Quantity    int        `json:"quantity"`
+	Status      ItemStatus `json:"status"`
+	LotNumber   string     `json:"lot_number,omitempty"`
+	AddedAt     time.Time  `json:"added_at"`
 	ProcessedAt *time.Time `json:"processed_at,omitempty"`
 }
 
-// Batch represents a batch aggregate in the warehouse domain
+// Batch represents a batch aggregate in the warehouse domain.
+// A batch may be allocated from a manufacturing lot, in which case it carries the
+// lot number and its manufacture and expiration dates.
+// A damaged batch is in quarantine: it carries the findings of its inspection and,
+// once decided, the disposition of the quarantine.
+// A batch put on hold, for instance by a product recall, carries the reason of the hold.
+// Version is the number of times the batch has been saved; repositories use it
+// to reject changes made on a stale copy.
 type Batch struct {
-	ID          string      `json:"id"`
-	ProductID   string      `json:"product_id"`
-	Status      BatchStatus `json:"status"`
-	Items       []BatchItem `json:"items"`
-	TotalItems  int         `json:"total_items"`
-	CreatedAt   time.Time   `json:"created_at"`
-	UpdatedAt   time.Time   `json:"updated_at"`
-	ProcessedAt *time.Time  `json:"processed_at,omitempty"`
+	ID             string                 `json:"id"`
+	ProductID      string                 `json:"product_id"`
+	LotNumber      string                 `json:"lot_number,omitempty"`
+	ManufacturedAt *time.Time             `json:"manufactured_at,omitempty"`
+	ExpiresAt      *time.Time             `json:"expires_at,omitempty"`
+	Status         BatchStatus            `json:"status"`
+	Items          []BatchItem            `json:"items"`
+	TotalItems     int                    `json:"total_items"`
+	CreatedAt      time.Time              `json:"created_at"`
+	UpdatedAt      time.Time              `json:"updated_at"`
+	ProcessedAt    *time.Time             `json:"processed_at,omitempty"`
+	Inspection     *BatchInspection       `json:"inspection,omitempty"`
+	Disposition    *QuarantineDisposition `json:"disposition,omitempty"`
+	HoldReason     string                 `json:"hold_reason,omitempty"`
+	Version        int64                  `json:"version"`
 }
 
 // NewBatch creates a new batch with the given product ID
@@ -52,19 +70,32 @@ func NewBatch(id, productID string) *Batch {
 	}
 }
 
-// AddItem adds an order item to the batch
-func (b *Batch) AddItem(orderID, productID string, quantity int, status string) error {
+// AddItem adds an order item to the batch. New items may start in any status but a
+// quarantine disposition and are refused once the lot of the batch is expired;
+// updating an existing item follows the item status transitions.
+func (b *Batch) AddItem(orderID, productID string, quantity int, status ItemStatus) error {
 	if b.ProductID != productID {
-		return fmt.Errorf("product ID mismatch: batch is for %s, item is for %s", b.ProductID, productID)
+		return NewValidationError("product ID mismatch: batch is for %s, item is for %s", b.ProductID, productID)
 	}
 
-	if b.Status == BatchStatusCompleted || b.Status == BatchStatusCancelled {
-		return fmt.Errorf("cannot add items to batch with status %s", b.Status)
+	if err := ValidateItemStatus(status); err != nil {
+		return err
+	}
+	if status.IsQuarantineDisposition() {
+		return NewValidationError("order %s cannot be allocated with status %s: it is set by the disposition of a quarantined batch", orderID, status)
+	}
+
+	if err := b.Can(BatchActionAddItem); err != nil {
+		return err
 	}
 
 	// Check if order already exists in batch
 	for i, item := range b.Items {
 		if item.OrderID == orderID {
+			if !item.Status.CanTransitionTo(status) {
+				return NewTransitionError("cannot change order %s from %s to %s", orderID, item.Status, status)
+			}
+
 			// Update existing item
 			b.Items[i].Quantity = quantity
 			b.Items[i].Status = status
@@ -74,12 +105,17 @@ func (b *Batch) AddItem(orderID, productID string, quantity int, status string)
 		}
 	}
 
+	if b.IsExpired(time.Now()) {
+		return NewValidationError("cannot allocate order %s from expired lot %s of batch %s", orderID, b.LotNumber, b.ID)
+	}
+
 	// Add new item
 	item := BatchItem{
 		OrderID:   orderID,
 		ProductID: productID,
 		Quantity:  quantity,
 		Status:    status,
+		LotNumber: b.LotNumber,
 		AddedAt:   time.Now(),
 	}
 
@@ -90,10 +126,24 @@ func (b *Batch) AddItem(orderID, productID string, quantity int, status string)
 	return nil
 }
 
+// SetItemCustomer records the customer of an order item. An empty customer ID keeps
+// the one already recorded.
+func (b *Batch) SetItemCustomer(orderID, customerID string) error {
+	for i, item := range b.Items {
+		if item.OrderID == orderID {
+			if customerID != "" {
+				b.Items[i].CustomerID = customerID
+			}
+			return nil
+		}
+	}
+	return NewNotFoundError("order %s not found in batch", orderID)
+}
+
 // RemoveItem removes an order item from the batch
 func (b *Batch) RemoveItem(orderID string) error {
-	if b.Status == BatchStatusCompleted {
-		return fmt.Errorf("cannot remove items from completed batch")
+	if err := b.Can(BatchActionRemoveItem); err != nil {
+		return err
 	}
 
 	for i, item := range b.Items {
@@ -106,15 +156,30 @@ func (b *Batch) RemoveItem(orderID string) error {
 		}
 	}
 
-	return fmt.Errorf("order %s not found in batch", orderID)
+	return NewNotFoundError("order %s not found in batch", orderID)
 }
 
-// UpdateItemStatus updates the status of a specific item in the batch
-func (b *Batch) UpdateItemStatus(orderID, status string) error {
+// UpdateItemStatus moves a specific item in the batch to a new status. Quarantine
+// dispositions are only reached while the batch is quarantined.
+func (b *Batch) UpdateItemStatus(orderID string, status ItemStatus) error {
+	if err := ValidateItemStatus(status); err != nil {
+		return err
+	}
+
 	for i, item := range b.Items {
 		if item.OrderID == orderID {
+			if !item.Status.CanTransitionTo(status) {
+				return NewTransitionError("cannot change order %s from %s to %s", orderID, item.Status, status)
+			}
+			if b.Status == BatchStatusOnHold && !item.Status.IsProcessed() && status.IsProcessed() {
+				return NewTransitionError("cannot change order %s to %s: batch %s is on hold", orderID, status, b.ID)
+			}
+			if item.Status != status && status.IsQuarantineDisposition() && !b.IsQuarantined() {
+				return NewTransitionError("cannot change order %s to %s: batch %s is not in quarantine", orderID, status, b.ID)
+			}
+
 			b.Items[i].Status = status
-			if status == "processed" || status == "shipped" || status == "delivered" {
+			if status.IsProcessed() {
 				now := time.Now()
 				b.Items[i].ProcessedAt = &now
 			}
@@ -123,27 +188,25 @@ func (b *Batch) UpdateItemStatus(orderID, status string) error {
 		}
 	}
 
-	return fmt.Errorf("order %s not found in batch", orderID)
+	return NewNotFoundError("order %s not found in batch", orderID)
 }
 
 // StartProcessing changes the batch status to processing
 func (b *Batch) StartProcessing() error {
-	if b.Status != BatchStatusPending {
-		return fmt.Errorf("cannot start processing batch with status %s", b.Status)
+	if err := b.apply(BatchActionProcess); err != nil {
+		return err
 	}
 
-	b.Status = BatchStatusProcessing
 	b.UpdatedAt = time.Now()
 	return nil
 }
 
 // Complete marks the batch as completed
 func (b *Batch) Complete() error {
-	if b.Status != BatchStatusProcessing {
-		return fmt.Errorf("cannot complete batch with status %s", b.Status)
+	if err := b.apply(BatchActionComplete); err != nil {
+		return err
 	}
 
-	b.Status = BatchStatusCompleted
 	now := time.Now()
 	b.ProcessedAt = &now
 	b.UpdatedAt = now
@@ -152,22 +215,42 @@ func (b *Batch) Complete() error {
 
 // Cancel marks the batch as cancelled
 func (b *Batch) Cancel() error {
-	if b.Status == BatchStatusCompleted {
-		return fmt.Errorf("cannot cancel completed batch")
+	if err := b.apply(BatchActionCancel); err != nil {
+		return err
 	}
 
-	b.Status = BatchStatusCancelled
 	b.UpdatedAt = time.Now()
 	return nil
 }
 
-// MarkAsDamaged marks the batch as damaged
+// MarkAsDamaged marks the batch as damaged, putting it in quarantine until it is
+// inspected. Findings and dispositions of an earlier quarantine are cleared.
 func (b *Batch) MarkAsDamaged() error {
-	b.Status = BatchStatusDamaged
+	if err := b.apply(BatchActionMarkDamaged); err != nil {
+		return err
+	}
+
+	b.Inspection = nil
+	b.Disposition = nil
 	b.UpdatedAt = time.Now()
 	return nil
 }
 
+// PlaceOnHold stops the batch from being allocated, processed or shipped. Holding a
+// batch that is already on hold replaces the reason of the hold.
+func (b *Batch) PlaceOnHold(reason string, at time.Time) error {
+	if reason == "" {
+		return NewValidationError("hold of batch %s requires a reason", b.ID)
+	}
+	if err := b.apply(BatchActionHold); err != nil {
+		return err
+	}
+
+	b.HoldReason = reason
+	b.UpdatedAt = at
+	return nil
+}
+
 // GetItemByOrderID returns the batch item for a specific order ID
 func (b *Batch) GetItemByOrderID(orderID string) (*BatchItem, error) {
 	for _, item := range b.Items {
@@ -175,7 +258,7 @@ func (b *Batch) GetItemByOrderID(orderID string) (*BatchItem, error) {
 			return &item, nil
 		}
 	}
-	return nil, fmt.Errorf("order %s not found in batch", orderID)
+	return nil, NewNotFoundError("order %s not found in batch", orderID)
 }
 
 // HasOrder checks if the batch contains a specific order
@@ -0,0 +1,107 @@
+package domain
+
+import (
+	"time"
+)
+
+// BatchCommandName names a command that changes a batch
+type BatchCommandName string
+
+const (
+	BatchCommandCreateLot   BatchCommandName = "create_lot"
+	BatchCommandAddOrder    BatchCommandName = "add_order"
+	BatchCommandRemoveOrder BatchCommandName = "remove_order"
+	BatchCommandUpdateOrder BatchCommandName = "update_order"
+	BatchCommandProcess     BatchCommandName = "process"
+	BatchCommandComplete    BatchCommandName = "complete"
+	BatchCommandCancel      BatchCommandName = "cancel"
+	BatchCommandMarkDamaged BatchCommandName = "mark_damaged"
+	BatchCommandInspect     BatchCommandName = "inspect"
+	BatchCommandRelease     BatchCommandName = "release"
+	BatchCommandWriteOff    BatchCommandName = "write_off"
+	BatchCommandDestroy     BatchCommandName = "destroy"
+	BatchCommandHold        BatchCommandName = "hold"
+	BatchCommandRecallHold  BatchCommandName = "recall_hold"
+	// BatchCommandClose starts processing a batch the closing policy considers full or too old
+	BatchCommandClose BatchCommandName = "close"
+)
+
+// BatchIssuer identifies who or what issued a batch command: a user of the API, the
+// event that triggered it, or a component of the service
+type BatchIssuer struct {
+	Actor       string `json:"actor,omitempty"`
+	SourceEvent string `json:"source_event,omitempty"`
+
+	// TraceContext is the trace of the command, passed on to the events it raises; it is
+	// not recorded in the audit trail
+	TraceContext TraceContext `json:"-"`
+}
+
+// BatchCommand is a command that changes a batch, as recorded in its audit trail
+type BatchCommand struct {
+	Name BatchCommandName `json:"name"`
+	BatchIssuer
+}
+
+// BatchItemDiff lists the items a command added to, updated in and removed from a batch
+type BatchItemDiff struct {
+	Added   []BatchItem       `json:"added,omitempty"`
+	Updated []BatchItemChange `json:"updated,omitempty"`
+	Removed []BatchItem       `json:"removed,omitempty"`
+}
+
+// BatchAuditEntry is an entry of the audit trail of a batch: a command that changed the
+// batch, who issued it, the events it published, and how it changed the status and the
+// items of the batch. The status before is empty for a created batch and the status
+// after is empty for a deleted one.
+type BatchAuditEntry struct {
+	ID           int64            `json:"id"`
+	BatchID      string           `json:"batch_id"`
+	Version      int64            `json:"version"`
+	Command      BatchCommand     `json:"command"`
+	EventTypes   []BatchEventType `json:"event_types,omitempty"`
+	StatusBefore BatchStatus      `json:"status_before,omitempty"`
+	StatusAfter  BatchStatus      `json:"status_after,omitempty"`
+	Items        BatchItemDiff    `json:"items"`
+	RecordedAt   time.Time        `json:"recorded_at"`
+}
+
+// BatchAuditLog is the audit trail the batch repository records with every change of a batch
+type BatchAuditLog interface {
+	// FindBatchHistory returns up to limit audit entries of a batch in the order they
+	// were recorded, skipping the first offset ones, and the total number of entries
+	FindBatchHistory(batchID string, offset, limit int) ([]*BatchAuditEntry, int, error)
+}
+
+// NewBatchAuditEntry records a command that changed a batch from its stored state, nil
+// for a new batch, to its new state, nil when the command deleted it
+func NewBatchAuditEntry(command BatchCommand, before, after *Batch, events []*BatchEvent, recordedAt time.Time) *BatchAuditEntry {
+	entry := &BatchAuditEntry{
+		Command:    command,
+		RecordedAt: recordedAt,
+	}
+	for _, event := range events {
+		entry.EventTypes = append(entry.EventTypes, event.EventType)
+	}
+
+	switch {
+	case after == nil:
+		entry.BatchID = before.ID
+		entry.Version = before.Version + 1
+		entry.StatusBefore = before.Status
+		entry.Items.Removed = append([]BatchItem(nil), before.Items...)
+	case before == nil:
+		entry.BatchID = after.ID
+		entry.Version = after.Version
+		entry.StatusAfter = after.Status
+		entry.Items.Added = append([]BatchItem(nil), after.Items...)
+	default:
+		delta := DiffBatch(before, after)
+		entry.BatchID = after.ID
+		entry.Version = after.Version
+		entry.StatusBefore = before.Status
+		entry.StatusAfter = after.Status
+		entry.Items = BatchItemDiff{Added: delta.ItemsAdded, Updated: delta.ItemsUpdated, Removed: delta.ItemsRemoved}
+	}
+	return entry
+}
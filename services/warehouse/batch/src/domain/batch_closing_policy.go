@@ -0,0 +1,72 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// BatchClosingPolicy decides when a pending batch stops accepting orders and
+// moves on to processing. A zero value disables the corresponding limit.
+type BatchClosingPolicy struct {
+	MaxItems         int
+	MaxTotalQuantity int
+	MaxAge           time.Duration
+}
+
+// IsEnabled returns true if at least one limit is configured
+func (p BatchClosingPolicy) IsEnabled() bool {
+	return p.MaxItems > 0 || p.MaxTotalQuantity > 0 || p.MaxAge > 0
+}
+
+// ShouldClose reports whether a pending batch is full or old enough to be closed,
+// together with the reason
+func (p BatchClosingPolicy) ShouldClose(batch *Batch, now time.Time) (bool, string) {
+	if batch.Status != BatchStatusPending || batch.IsEmpty() {
+		return false, ""
+	}
+
+	if p.MaxItems > 0 && len(batch.Items) >= p.MaxItems {
+		return true, fmt.Sprintf("reached %d items (max %d)", len(batch.Items), p.MaxItems)
+	}
+
+	if p.MaxTotalQuantity > 0 && batch.GetTotalQuantity() >= p.MaxTotalQuantity {
+		return true, fmt.Sprintf("reached total quantity %d (max %d)", batch.GetTotalQuantity(), p.MaxTotalQuantity)
+	}
+
+	if p.MaxAge > 0 && now.Sub(batch.CreatedAt) >= p.MaxAge {
+		return true, fmt.Sprintf("open for %s (max %s)", now.Sub(batch.CreatedAt).Round(time.Second), p.MaxAge)
+	}
+
+	return false, ""
+}
+
+// CanAccept reports whether the order can be added to the batch without exceeding
+// the limits. An empty batch always accepts its first order, and an order that is
+// already in the batch can always be updated.
+func (p BatchClosingPolicy) CanAccept(batch *Batch, orderID string, quantity int, now time.Time) bool {
+	if batch.IsEmpty() {
+		return true
+	}
+
+	existing, err := batch.GetItemByOrderID(orderID)
+	if err == nil {
+		if p.MaxTotalQuantity > 0 && batch.GetTotalQuantity()-existing.Quantity+quantity > p.MaxTotalQuantity {
+			return false
+		}
+		return true
+	}
+
+	if p.MaxItems > 0 && len(batch.Items)+1 > p.MaxItems {
+		return false
+	}
+
+	if p.MaxTotalQuantity > 0 && batch.GetTotalQuantity()+quantity > p.MaxTotalQuantity {
+		return false
+	}
+
+	if p.MaxAge > 0 && now.Sub(batch.CreatedAt) >= p.MaxAge {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,77 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBatchClosingPolicy_ShouldClose(t *testing.T) {
+	now := time.Now()
+
+	newBatchWithItems := func(quantities ...int) *Batch {
+		batch := NewBatch("batch-1", "product-1")
+		for i, quantity := range quantities {
+			batch.AddItem(string(rune('a'+i)), "product-1", quantity, "allocated")
+		}
+		return batch
+	}
+
+	oldBatch := newBatchWithItems(1)
+	oldBatch.CreatedAt = now.Add(-2 * time.Hour)
+
+	processingBatch := newBatchWithItems(1, 1, 1)
+	processingBatch.StartProcessing()
+
+	testCases := []struct {
+		name     string
+		policy   BatchClosingPolicy
+		batch    *Batch
+		expected bool
+	}{
+		{"disabled policy", BatchClosingPolicy{}, newBatchWithItems(1, 1, 1), false},
+		{"below max items", BatchClosingPolicy{MaxItems: 3}, newBatchWithItems(1, 1), false},
+		{"max items reached", BatchClosingPolicy{MaxItems: 3}, newBatchWithItems(1, 1, 1), true},
+		{"max total quantity reached", BatchClosingPolicy{MaxTotalQuantity: 10}, newBatchWithItems(4, 6), true},
+		{"max age reached", BatchClosingPolicy{MaxAge: time.Hour}, oldBatch, true},
+		{"empty batch", BatchClosingPolicy{MaxItems: 1}, newBatchWithItems(), false},
+		{"batch not pending", BatchClosingPolicy{MaxItems: 1}, processingBatch, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			shouldClose, reason := tc.policy.ShouldClose(tc.batch, now)
+			if shouldClose != tc.expected {
+				t.Errorf("Expected ShouldClose %v, got %v (reason: %q)", tc.expected, shouldClose, reason)
+			}
+			if shouldClose && reason == "" {
+				t.Error("Expected a reason when the batch should close")
+			}
+		})
+	}
+}
+
+func TestBatchClosingPolicy_CanAccept(t *testing.T) {
+	now := time.Now()
+	policy := BatchClosingPolicy{MaxItems: 2, MaxTotalQuantity: 10}
+
+	batch := NewBatch("batch-1", "product-1")
+	if !policy.CanAccept(batch, "order-1", 50, now) {
+		t.Error("Expected an empty batch to accept its first order even above the limits")
+	}
+
+	batch.AddItem("order-1", "product-1", 4, "allocated")
+	if !policy.CanAccept(batch, "order-2", 6, now) {
+		t.Error("Expected order within the limits to be accepted")
+	}
+	if policy.CanAccept(batch, "order-2", 7, now) {
+		t.Error("Expected order exceeding the total quantity to be rejected")
+	}
+
+	batch.AddItem("order-2", "product-1", 1, "allocated")
+	if policy.CanAccept(batch, "order-3", 1, now) {
+		t.Error("Expected order exceeding the max items to be rejected")
+	}
+	if !policy.CanAccept(batch, "order-1", 9, now) {
+		t.Error("Expected an existing order to be updated within the total quantity")
+	}
+}
@@ -0,0 +1,220 @@
+package domain
+
+import (
+	"time"
+)
+
+// BatchDelta is the change between two states of a batch: the items added, updated
+// and removed, the status transition and the changed attributes, each with its old
+// and new values. Applying the delta to the old state gives the new state, so
+// consumers can follow a batch from a snapshot without receiving all its items again.
+type BatchDelta struct {
+	Status       *BatchStatusChange     `json:"status,omitempty"`
+	ItemsAdded   []BatchItem            `json:"items_added,omitempty"`
+	ItemsUpdated []BatchItemChange      `json:"items_updated,omitempty"`
+	ItemsRemoved []BatchItem            `json:"items_removed,omitempty"`
+	Attributes   *BatchAttributesChange `json:"attributes,omitempty"`
+	UpdatedAt    time.Time              `json:"updated_at"`
+	Version      int64                  `json:"version"`
+}
+
+// BatchStatusChange is a status transition of a batch
+type BatchStatusChange struct {
+	Old BatchStatus `json:"old"`
+	New BatchStatus `json:"new"`
+}
+
+// BatchItemChange is the old and new value of an updated batch item
+type BatchItemChange struct {
+	Old BatchItem `json:"old"`
+	New BatchItem `json:"new"`
+}
+
+// BatchAttributes are the attributes of a batch that change besides its status and
+// items: its lot, the end of its processing, its quarantine and its hold
+type BatchAttributes struct {
+	LotNumber      string                 `json:"lot_number,omitempty"`
+	ManufacturedAt *time.Time             `json:"manufactured_at,omitempty"`
+	ExpiresAt      *time.Time             `json:"expires_at,omitempty"`
+	ProcessedAt    *time.Time             `json:"processed_at,omitempty"`
+	Inspection     *BatchInspection       `json:"inspection,omitempty"`
+	Disposition    *QuarantineDisposition `json:"disposition,omitempty"`
+	HoldReason     string                 `json:"hold_reason,omitempty"`
+}
+
+// BatchAttributesChange is the old and new value of the attributes of a batch
+type BatchAttributesChange struct {
+	Old BatchAttributes `json:"old"`
+	New BatchAttributes `json:"new"`
+}
+
+// DiffBatch returns the delta that turns the state of a batch before a change into its
+// state after it. Items are matched by order ID.
+func DiffBatch(before, after *Batch) *BatchDelta {
+	delta := &BatchDelta{
+		UpdatedAt: after.UpdatedAt,
+		Version:   after.Version,
+	}
+
+	if before.Status != after.Status {
+		delta.Status = &BatchStatusChange{Old: before.Status, New: after.Status}
+	}
+
+	oldItems := make(map[string]BatchItem, len(before.Items))
+	for _, item := range before.Items {
+		oldItems[item.OrderID] = item
+	}
+	for _, item := range after.Items {
+		oldItem, exists := oldItems[item.OrderID]
+		switch {
+		case !exists:
+			delta.ItemsAdded = append(delta.ItemsAdded, item)
+		case !sameItem(oldItem, item):
+			delta.ItemsUpdated = append(delta.ItemsUpdated, BatchItemChange{Old: oldItem, New: item})
+		}
+		delete(oldItems, item.OrderID)
+	}
+	for _, item := range before.Items {
+		if _, removed := oldItems[item.OrderID]; removed {
+			delta.ItemsRemoved = append(delta.ItemsRemoved, item)
+		}
+	}
+
+	if oldAttributes, newAttributes := before.attributes(), after.attributes(); !sameAttributes(oldAttributes, newAttributes) {
+		delta.Attributes = &BatchAttributesChange{Old: oldAttributes, New: newAttributes}
+	}
+	return delta
+}
+
+// IsEmpty reports whether the delta changes nothing but the update time and version
+func (d *BatchDelta) IsEmpty() bool {
+	return d.Status == nil && len(d.ItemsAdded) == 0 && len(d.ItemsUpdated) == 0 &&
+		len(d.ItemsRemoved) == 0 && d.Attributes == nil
+}
+
+// Apply returns the state of the batch after the delta, leaving the given state
+// unchanged. The old values of the delta must match the batch; otherwise the delta
+// was taken from another state and a conflict error is returned.
+func (d *BatchDelta) Apply(batch *Batch) (*Batch, error) {
+	next := batch.Clone()
+
+	if d.Status != nil {
+		if next.Status != d.Status.Old {
+			return nil, NewConflictError("batch %s is %s, the delta changes it from %s", batch.ID, next.Status, d.Status.Old)
+		}
+		next.Status = d.Status.New
+	}
+
+	for _, removed := range d.ItemsRemoved {
+		index := next.itemIndex(removed.OrderID)
+		if index < 0 {
+			return nil, NewConflictError("order %s to remove is not in batch %s", removed.OrderID, batch.ID)
+		}
+		next.Items = append(next.Items[:index], next.Items[index+1:]...)
+	}
+	for _, change := range d.ItemsUpdated {
+		index := next.itemIndex(change.Old.OrderID)
+		if index < 0 || !sameItem(next.Items[index], change.Old) {
+			return nil, NewConflictError("order %s of batch %s does not match the delta", change.Old.OrderID, batch.ID)
+		}
+		next.Items[index] = change.New
+	}
+	for _, added := range d.ItemsAdded {
+		if next.itemIndex(added.OrderID) >= 0 {
+			return nil, NewConflictError("order %s to add is already in batch %s", added.OrderID, batch.ID)
+		}
+		next.Items = append(next.Items, added)
+	}
+	next.TotalItems = len(next.Items)
+
+	if d.Attributes != nil {
+		if !sameAttributes(next.attributes(), d.Attributes.Old) {
+			return nil, NewConflictError("attributes of batch %s do not match the delta", batch.ID)
+		}
+		next.setAttributes(d.Attributes.New)
+	}
+
+	next.UpdatedAt = d.UpdatedAt
+	next.Version = d.Version
+	return next, nil
+}
+
+// Clone returns a copy of the batch that shares no items with it
+func (b *Batch) Clone() *Batch {
+	clone := *b
+	clone.Items = make([]BatchItem, len(b.Items))
+	copy(clone.Items, b.Items)
+	return &clone
+}
+
+// itemIndex returns the index of the item of an order, or -1 if the order is not in the batch
+func (b *Batch) itemIndex(orderID string) int {
+	for i, item := range b.Items {
+		if item.OrderID == orderID {
+			return i
+		}
+	}
+	return -1
+}
+
+// attributes returns the attributes of the batch
+func (b *Batch) attributes() BatchAttributes {
+	return BatchAttributes{
+		LotNumber:      b.LotNumber,
+		ManufacturedAt: b.ManufacturedAt,
+		ExpiresAt:      b.ExpiresAt,
+		ProcessedAt:    b.ProcessedAt,
+		Inspection:     b.Inspection,
+		Disposition:    b.Disposition,
+		HoldReason:     b.HoldReason,
+	}
+}
+
+// setAttributes replaces the attributes of the batch
+func (b *Batch) setAttributes(attributes BatchAttributes) {
+	b.LotNumber = attributes.LotNumber
+	b.ManufacturedAt = attributes.ManufacturedAt
+	b.ExpiresAt = attributes.ExpiresAt
+	b.ProcessedAt = attributes.ProcessedAt
+	b.Inspection = attributes.Inspection
+	b.Disposition = attributes.Disposition
+	b.HoldReason = attributes.HoldReason
+}
+
+// sameItem reports whether two items hold the same values
+func sameItem(a, b BatchItem) bool {
+	return a.OrderID == b.OrderID && a.CustomerID == b.CustomerID && a.ProductID == b.ProductID &&
+		a.Quantity == b.Quantity && a.Status == b.Status && a.LotNumber == b.LotNumber &&
+		a.AddedAt.Equal(b.AddedAt) && sameTime(a.ProcessedAt, b.ProcessedAt)
+}
+
+// sameAttributes reports whether two sets of batch attributes hold the same values
+func sameAttributes(a, b BatchAttributes) bool {
+	if a.LotNumber != b.LotNumber || a.HoldReason != b.HoldReason ||
+		!sameTime(a.ManufacturedAt, b.ManufacturedAt) || !sameTime(a.ExpiresAt, b.ExpiresAt) ||
+		!sameTime(a.ProcessedAt, b.ProcessedAt) {
+		return false
+	}
+
+	switch {
+	case (a.Inspection == nil) != (b.Inspection == nil):
+		return false
+	case a.Inspection != nil && (a.Inspection.Inspector != b.Inspection.Inspector ||
+		a.Inspection.Findings != b.Inspection.Findings || !a.Inspection.InspectedAt.Equal(b.Inspection.InspectedAt)):
+		return false
+	case (a.Disposition == nil) != (b.Disposition == nil):
+		return false
+	case a.Disposition != nil && (a.Disposition.Outcome != b.Disposition.Outcome || a.Disposition.DecidedBy != b.Disposition.DecidedBy ||
+		a.Disposition.Reason != b.Disposition.Reason || !a.Disposition.DecidedAt.Equal(b.Disposition.DecidedAt)):
+		return false
+	}
+	return true
+}
+
+// sameTime reports whether two optional times are both unset or the same instant
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
@@ -0,0 +1,113 @@
+package domain
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiffBatch_ApplyRebuildsBatch(t *testing.T) {
+	at := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	batch := NewBatch("batch-1", "product-1")
+	for _, orderID := range []string{"order-1", "order-2", "order-3"} {
+		if err := batch.AddItem(orderID, "product-1", 2, ItemStatusAllocated); err != nil {
+			t.Fatalf("Failed to add %s: %v", orderID, err)
+		}
+	}
+
+	testCases := []struct {
+		name   string
+		change func(batch *Batch) error
+		check  func(t *testing.T, delta *BatchDelta)
+	}{
+		{"item added", func(batch *Batch) error {
+			return batch.AddItem("order-4", "product-1", 1, ItemStatusAllocated)
+		}, func(t *testing.T, delta *BatchDelta) {
+			if len(delta.ItemsAdded) != 1 || delta.ItemsAdded[0].OrderID != "order-4" || len(delta.ItemsUpdated) != 0 {
+				t.Errorf("Expected only order-4 to be added, got %+v", delta)
+			}
+		}},
+		{"item updated", func(batch *Batch) error {
+			return batch.UpdateItemStatus("order-2", ItemStatusShipped)
+		}, func(t *testing.T, delta *BatchDelta) {
+			if len(delta.ItemsUpdated) != 1 || delta.ItemsUpdated[0].Old.Status != ItemStatusAllocated ||
+				delta.ItemsUpdated[0].New.Status != ItemStatusShipped {
+				t.Errorf("Expected order-2 to move from allocated to shipped, got %+v", delta.ItemsUpdated)
+			}
+		}},
+		{"item removed", func(batch *Batch) error {
+			return batch.RemoveItem("order-1")
+		}, func(t *testing.T, delta *BatchDelta) {
+			if len(delta.ItemsRemoved) != 1 || delta.ItemsRemoved[0].OrderID != "order-1" {
+				t.Errorf("Expected order-1 to be removed, got %+v", delta.ItemsRemoved)
+			}
+		}},
+		{"status transition", func(batch *Batch) error {
+			if err := batch.StartProcessing(); err != nil {
+				return err
+			}
+			return batch.Complete()
+		}, func(t *testing.T, delta *BatchDelta) {
+			if delta.Status == nil || delta.Status.Old != BatchStatusPending || delta.Status.New != BatchStatusCompleted {
+				t.Errorf("Expected a transition from pending to completed, got %+v", delta.Status)
+			}
+			if delta.Attributes == nil || delta.Attributes.Old.ProcessedAt != nil || delta.Attributes.New.ProcessedAt == nil {
+				t.Errorf("Expected the batch to become processed, got %+v", delta.Attributes)
+			}
+		}},
+		{"placed on hold", func(batch *Batch) error {
+			return batch.PlaceOnHold("recall", at)
+		}, func(t *testing.T, delta *BatchDelta) {
+			if delta.Attributes == nil || delta.Attributes.New.HoldReason != "recall" || len(delta.ItemsUpdated) != 0 {
+				t.Errorf("Expected only the hold reason to change, got %+v", delta)
+			}
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			before := batch.Clone()
+			after := batch.Clone()
+			if err := tc.change(after); err != nil {
+				t.Fatalf("Failed to change batch: %v", err)
+			}
+			after.Version = before.Version + 1
+
+			delta := DiffBatch(before, after)
+			tc.check(t, delta)
+
+			rebuilt, err := delta.Apply(before)
+			if err != nil {
+				t.Fatalf("Failed to apply delta: %v", err)
+			}
+			if !reflect.DeepEqual(rebuilt, after) {
+				t.Errorf("Expected %+v, got %+v", after, rebuilt)
+			}
+			if len(before.Items) != 3 || before.Status != BatchStatusPending {
+				t.Errorf("Expected the old state to be left unchanged, got %+v", before)
+			}
+		})
+	}
+}
+
+func TestBatchDelta_ApplyRejectsAnotherBase(t *testing.T) {
+	base := NewBatch("batch-1", "product-1")
+	if err := base.AddItem("order-1", "product-1", 2, ItemStatusAllocated); err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+
+	after := base.Clone()
+	if err := after.UpdateItemStatus("order-1", ItemStatusShipped); err != nil {
+		t.Fatalf("Failed to update item: %v", err)
+	}
+	delta := DiffBatch(base, after)
+
+	// The delta was taken from another state: the item is already shipped
+	if _, err := delta.Apply(after); !errors.Is(err, ErrConcurrencyConflict) {
+		t.Errorf("Expected a conflict, got %v", err)
+	}
+	if !DiffBatch(after, after).IsEmpty() {
+		t.Error("Expected no change between equal states")
+	}
+}
@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"context"
 	"time"
 )
 
@@ -16,17 +17,43 @@ const (
 	BatchEventCompleted     BatchEventType = "batch.completed"
 	BatchEventCancelled     BatchEventType = "batch.cancelled"
 	BatchEventDamaged       BatchEventType = "batch.marked_damaged"
+	BatchEventInspected     BatchEventType = "batch.inspected"
+	BatchEventReleased      BatchEventType = "batch.quarantine_released"
+	BatchEventWrittenOff    BatchEventType = "batch.partially_written_off"
+	BatchEventDestroyed     BatchEventType = "batch.destroyed"
+	BatchEventOnHold        BatchEventType = "batch.placed_on_hold"
+	BatchEventRecalled      BatchEventType = "batch.recalled"
 )
 
-// BatchEvent represents a domain event for batch operations
+// BatchEvent represents a domain event for batch operations.
+// Sequence orders the events of a batch once they are stored in the outbox. A
+// compact event carries the Delta from the state of the batch at BaseSequence
+// instead of the whole Batch.
 type BatchEvent struct {
-	EventType   BatchEventType `json:"event_type"`
-	BatchID     string         `json:"batch_id"`
-	ProductID   string         `json:"product_id"`
-	Batch       *Batch         `json:"batch"`
-	OrderID     *string        `json:"order_id,omitempty"`     // For item-specific events
-	ItemDetails *BatchItem     `json:"item_details,omitempty"` // For item-specific events
-	Timestamp   time.Time      `json:"timestamp"`
+	EventType    BatchEventType `json:"event_type"`
+	BatchID      string         `json:"batch_id"`
+	ProductID    string         `json:"product_id"`
+	Batch        *Batch         `json:"batch"`
+	OrderID      *string        `json:"order_id,omitempty"`     // For item-specific events
+	ItemDetails  *BatchItem     `json:"item_details,omitempty"` // For item-specific events
+	Recall       *RecallNotice  `json:"recall,omitempty"`       // For recall events
+	Timestamp    time.Time      `json:"timestamp"`
+	Sequence     int64          `json:"sequence,omitempty"`
+	BaseSequence int64          `json:"base_sequence,omitempty"` // For delta events
+	Delta        *BatchDelta    `json:"delta,omitempty"`         // For delta events
+
+	// TraceContext is the trace of the command that raised the event. It is stored with
+	// the event in the outbox and published in the message headers, not in the payload.
+	TraceContext TraceContext `json:"trace_context,omitzero"`
+}
+
+// RecallNotice tells downstream services which orders and customers of a batch are
+// affected by a recall, so they can be notified
+type RecallNotice struct {
+	RecallID       string        `json:"recall_id"`
+	LotNumber      string        `json:"lot_number,omitempty"`
+	Reason         string        `json:"reason"`
+	AffectedOrders []RecallOrder `json:"affected_orders"`
 }
 
 // NewBatchCreatedEvent creates a new batch created event
@@ -122,7 +149,86 @@ func NewBatchDamagedEvent(batch *Batch) *BatchEvent {
 	}
 }
 
+// NewBatchInspectedEvent creates a new batch inspected event
+func NewBatchInspectedEvent(batch *Batch) *BatchEvent {
+	return &BatchEvent{
+		EventType: BatchEventInspected,
+		BatchID:   batch.ID,
+		ProductID: batch.ProductID,
+		Batch:     batch,
+		Timestamp: time.Now().UTC(),
+	}
+}
+
+// NewBatchOnHoldEvent creates a new batch placed on hold event
+func NewBatchOnHoldEvent(batch *Batch) *BatchEvent {
+	return &BatchEvent{
+		EventType: BatchEventOnHold,
+		BatchID:   batch.ID,
+		ProductID: batch.ProductID,
+		Batch:     batch,
+		Timestamp: time.Now().UTC(),
+	}
+}
+
+// NewBatchRecalledEvent creates the event announcing the orders of a batch affected by a recall
+func NewBatchRecalledEvent(batch *Batch, recall *RecallCase) *BatchEvent {
+	orders := recall.OrdersOfBatch(batch.ID)
+	if orders == nil {
+		orders = []RecallOrder{}
+	}
+
+	return &BatchEvent{
+		EventType: BatchEventRecalled,
+		BatchID:   batch.ID,
+		ProductID: batch.ProductID,
+		Batch:     batch,
+		Recall: &RecallNotice{
+			RecallID:       recall.ID,
+			LotNumber:      recall.LotNumber,
+			Reason:         recall.Reason,
+			AffectedOrders: orders,
+		},
+		Timestamp: time.Now().UTC(),
+	}
+}
+
+// NewBatchDispositionEvent creates the event of the quarantine outcome of a batch:
+// batch.quarantine_released, batch.partially_written_off or batch.destroyed
+func NewBatchDispositionEvent(batch *Batch, outcome QuarantineOutcome) *BatchEvent {
+	eventType := map[QuarantineOutcome]BatchEventType{
+		QuarantineOutcomeReleased:        BatchEventReleased,
+		QuarantineOutcomePartialWriteOff: BatchEventWrittenOff,
+		QuarantineOutcomeDestroyed:       BatchEventDestroyed,
+	}[outcome]
+
+	return &BatchEvent{
+		EventType: eventType,
+		BatchID:   batch.ID,
+		ProductID: batch.ProductID,
+		Batch:     batch,
+		Timestamp: time.Now().UTC(),
+	}
+}
+
 // BatchEventPublisher defines the interface for publishing batch events
 type BatchEventPublisher interface {
 	PublishBatchEvent(event *BatchEvent) error
+}
+
+// BatchEventDeliveryCallback receives the outcome of a batch event published
+// asynchronously: nil once it was delivered, or why it was not
+type BatchEventDeliveryCallback func(event *BatchEvent, err error)
+
+// AsyncBatchEventPublisher is a BatchEventPublisher that buffers events and delivers them
+// in the background. Events of the same batch are delivered in the order they were
+// published; once one of them fails, the ones published after it fail as well.
+type AsyncBatchEventPublisher interface {
+	BatchEventPublisher
+	// PublishBatchEventAsync buffers an event and later calls the callback with its
+	// delivery outcome. It blocks while the buffer is full, until the context is done.
+	PublishBatchEventAsync(ctx context.Context, event *BatchEvent, callback BatchEventDeliveryCallback) error
+	// Flush delivers the buffered events and returns once the outcomes of all events
+	// published before it were reported
+	Flush(ctx context.Context) error
 }
\ No newline at end of file
@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"sort"
+	"time"
+)
+
+// NewLotBatch creates a new pending batch for a manufacturing lot of the product.
+// The lot must not be expired at the given time.
+func NewLotBatch(id, productID, lotNumber string, manufacturedAt, expiresAt, now time.Time) (*Batch, error) {
+	if lotNumber == "" {
+		return nil, NewValidationError("lot number of product %s is required", productID)
+	}
+	if expiresAt.IsZero() {
+		return nil, NewValidationError("expiration date of lot %s is required", lotNumber)
+	}
+	if !manufacturedAt.IsZero() && !expiresAt.After(manufacturedAt) {
+		return nil, NewValidationError("lot %s expires on %s, before it was manufactured on %s",
+			lotNumber, expiresAt.Format(time.RFC3339), manufacturedAt.Format(time.RFC3339))
+	}
+	if !now.Before(expiresAt) {
+		return nil, NewValidationError("lot %s of product %s expired on %s",
+			lotNumber, productID, expiresAt.Format(time.RFC3339))
+	}
+
+	batch := NewBatch(id, productID)
+	batch.LotNumber = lotNumber
+	if !manufacturedAt.IsZero() {
+		batch.ManufacturedAt = &manufacturedAt
+	}
+	batch.ExpiresAt = &expiresAt
+	return batch, nil
+}
+
+// IsExpired reports whether the lot of the batch is expired at the given time.
+// Batches without an expiration date never expire.
+func (b *Batch) IsExpired(at time.Time) bool {
+	return b.ExpiresAt != nil && !at.Before(*b.ExpiresAt)
+}
+
+// ExpiresWithin reports whether the lot of the batch expires within the window
+// starting at the given time, or is already expired
+func (b *Batch) ExpiresWithin(at time.Time, window time.Duration) bool {
+	return b.ExpiresAt != nil && b.ExpiresAt.Before(at.Add(window))
+}
+
+// SortFirstExpiredFirstOut orders batches by expiration date, earliest first.
+// Batches without an expiration date come last; ties keep the oldest batch first.
+func SortFirstExpiredFirstOut(batches []*Batch) {
+	sort.SliceStable(batches, func(i, j int) bool {
+		a, b := batches[i], batches[j]
+		switch {
+		case a.ExpiresAt != nil && b.ExpiresAt != nil && !a.ExpiresAt.Equal(*b.ExpiresAt):
+			return a.ExpiresAt.Before(*b.ExpiresAt)
+		case a.ExpiresAt != nil && b.ExpiresAt == nil:
+			return true
+		case a.ExpiresAt == nil && b.ExpiresAt != nil:
+			return false
+		case !a.CreatedAt.Equal(b.CreatedAt):
+			return a.CreatedAt.Before(b.CreatedAt)
+		default:
+			return a.ID < b.ID
+		}
+	})
+}
@@ -0,0 +1,117 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNewLotBatch_Validation(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name           string
+		lotNumber      string
+		manufacturedAt time.Time
+		expiresAt      time.Time
+	}{
+		{"missing lot number", "", now.AddDate(0, -1, 0), now.AddDate(1, 0, 0)},
+		{"missing expiration date", "LOT-1", now.AddDate(0, -1, 0), time.Time{}},
+		{"expires before manufacture", "LOT-1", now.AddDate(0, 1, 0), now.AddDate(0, 0, 15)},
+		{"already expired", "LOT-1", now.AddDate(-2, 0, 0), now.Add(-time.Minute)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewLotBatch("batch-1", "product-1", tt.lotNumber, tt.manufacturedAt, tt.expiresAt, now); !errors.Is(err, ErrValidation) {
+				t.Errorf("Expected validation error, got %v", err)
+			}
+		})
+	}
+
+	batch, err := NewLotBatch("batch-1", "product-1", "LOT-1", time.Time{}, now.AddDate(1, 0, 0), now)
+	if err != nil {
+		t.Fatalf("Expected lot without manufacture date to be valid, got %v", err)
+	}
+	if batch.LotNumber != "LOT-1" || batch.ManufacturedAt != nil || batch.Status != BatchStatusPending {
+		t.Errorf("Unexpected lot batch %+v", batch)
+	}
+}
+
+func TestBatch_Expiry(t *testing.T) {
+	now := time.Now()
+	batch, err := NewLotBatch("batch-1", "product-1", "LOT-1", now.AddDate(-1, 0, 0), now.AddDate(0, 0, 10), now)
+	if err != nil {
+		t.Fatalf("Failed to create lot batch: %v", err)
+	}
+
+	if batch.IsExpired(now) {
+		t.Error("Expected lot not to be expired yet")
+	}
+	if !batch.ExpiresWithin(now, 30*24*time.Hour) {
+		t.Error("Expected lot to expire within 30 days")
+	}
+	if batch.ExpiresWithin(now, 24*time.Hour) {
+		t.Error("Expected lot not to expire within a day")
+	}
+
+	later := now.AddDate(0, 0, 10)
+	if !batch.IsExpired(later) {
+		t.Error("Expected lot to be expired on its expiration date")
+	}
+	if err := batch.AddItem("order-1", "product-1", 1, ItemStatusAllocated); err != nil {
+		t.Fatalf("Expected unexpired lot to accept orders, got %v", err)
+	}
+
+	untracked := NewBatch("batch-2", "product-1")
+	if untracked.IsExpired(later) || untracked.ExpiresWithin(later, time.Hour) {
+		t.Error("Expected batch without lot never to expire")
+	}
+}
+
+func TestBatch_AddItemRefusesExpiredLot(t *testing.T) {
+	now := time.Now()
+	batch, err := NewLotBatch("batch-1", "product-1", "LOT-1", time.Time{}, now.Add(time.Hour), now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to create lot batch: %v", err)
+	}
+	if err := batch.AddItem("order-1", "product-1", 1, ItemStatusAllocated); err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+
+	expired := now.Add(-time.Minute)
+	batch.ExpiresAt = &expired
+
+	if err := batch.AddItem("order-2", "product-1", 1, ItemStatusAllocated); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected validation error for expired lot, got %v", err)
+	}
+	if err := batch.AddItem("order-1", "product-1", 1, ItemStatusShipped); err != nil {
+		t.Errorf("Expected orders already allocated from the lot to be updatable, got %v", err)
+	}
+	if batch.Items[0].LotNumber != "LOT-1" {
+		t.Errorf("Expected item to carry lot LOT-1, got %q", batch.Items[0].LotNumber)
+	}
+}
+
+func TestSortFirstExpiredFirstOut(t *testing.T) {
+	now := time.Now()
+	expiresAt := func(days int) *time.Time {
+		at := now.AddDate(0, 0, days)
+		return &at
+	}
+
+	batches := []*Batch{
+		{ID: "untracked", CreatedAt: now.Add(-time.Hour)},
+		{ID: "late", ExpiresAt: expiresAt(90), CreatedAt: now},
+		{ID: "soon-b", ExpiresAt: expiresAt(10), CreatedAt: now},
+		{ID: "soon-a", ExpiresAt: expiresAt(10), CreatedAt: now},
+		{ID: "untracked-old", CreatedAt: now.Add(-2 * time.Hour)},
+	}
+	SortFirstExpiredFirstOut(batches)
+
+	expected := []string{"soon-a", "soon-b", "late", "untracked-old", "untracked"}
+	for i, id := range expected {
+		if batches[i].ID != id {
+			t.Fatalf("Expected order %v, got batch %s at position %d", expected, batches[i].ID, i)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package domain
+
+import (
+	"time"
+)
+
+// QuarantineOutcome represents how the quarantine of a damaged batch was resolved
+type QuarantineOutcome string
+
+const (
+	// QuarantineOutcomeReleased returns the whole batch to stock
+	QuarantineOutcomeReleased QuarantineOutcome = "released"
+
+	// QuarantineOutcomePartialWriteOff writes off some orders and returns the rest to stock
+	QuarantineOutcomePartialWriteOff QuarantineOutcome = "partially_written_off"
+
+	// QuarantineOutcomeDestroyed destroys the whole batch
+	QuarantineOutcomeDestroyed QuarantineOutcome = "destroyed"
+)
+
+// BatchInspection holds the findings recorded by the inspector of a quarantined batch
+type BatchInspection struct {
+	Inspector   string    `json:"inspector"`
+	Findings    string    `json:"findings"`
+	InspectedAt time.Time `json:"inspected_at"`
+}
+
+// QuarantineDisposition records the decision that ended the quarantine of a batch.
+// The written off orders are the batch items with status written_off.
+type QuarantineDisposition struct {
+	Outcome   QuarantineOutcome `json:"outcome"`
+	DecidedBy string            `json:"decided_by"`
+	Reason    string            `json:"reason,omitempty"`
+	DecidedAt time.Time         `json:"decided_at"`
+}
+
+// IsQuarantined reports whether the batch is damaged or inspected and still waiting
+// for its disposition
+func (b *Batch) IsQuarantined() bool {
+	return b.Status == BatchStatusDamaged || b.Status == BatchStatusInspected
+}
+
+// RecordInspection records the findings of the inspection of a quarantined batch.
+// Inspecting an inspected batch again replaces its findings.
+func (b *Batch) RecordInspection(inspector, findings string, at time.Time) error {
+	if inspector == "" {
+		return NewValidationError("inspection of batch %s requires an inspector", b.ID)
+	}
+	if findings == "" {
+		return NewValidationError("inspection of batch %s requires findings", b.ID)
+	}
+	if err := b.apply(BatchActionInspect); err != nil {
+		return err
+	}
+
+	b.Inspection = &BatchInspection{
+		Inspector:   inspector,
+		Findings:    findings,
+		InspectedAt: at,
+	}
+	b.UpdatedAt = at
+	return nil
+}
+
+// ReleaseFromQuarantine returns the inspected batch to stock. Its items still on the
+// warehouse floor become quarantine_released; the IDs of the changed orders are returned.
+func (b *Batch) ReleaseFromQuarantine(decidedBy, reason string, at time.Time) ([]string, error) {
+	if err := b.checkDisposition(BatchActionRelease, decidedBy); err != nil {
+		return nil, err
+	}
+
+	released := b.settleQuarantinedItems(nil, at)
+	b.dispose(BatchActionRelease, QuarantineOutcomeReleased, decidedBy, reason, at)
+	return released, nil
+}
+
+// WriteOff writes off the given orders of the inspected batch and returns the rest of
+// it to stock. Every order must be in the batch and still on the warehouse floor. The
+// IDs of the changed orders are returned.
+func (b *Batch) WriteOff(orderIDs []string, decidedBy, reason string, at time.Time) ([]string, error) {
+	if err := b.checkDisposition(BatchActionWriteOff, decidedBy); err != nil {
+		return nil, err
+	}
+	if len(orderIDs) == 0 {
+		return nil, NewValidationError("write-off of batch %s requires at least one order", b.ID)
+	}
+	if reason == "" {
+		return nil, NewValidationError("write-off of batch %s requires a reason", b.ID)
+	}
+
+	writtenOff := make(map[string]bool, len(orderIDs))
+	for _, orderID := range orderIDs {
+		item, err := b.GetItemByOrderID(orderID)
+		if err != nil {
+			return nil, err
+		}
+		if !item.Status.CanTransitionTo(ItemStatusWrittenOff) {
+			return nil, NewTransitionError("cannot write off order %s with status %s", orderID, item.Status)
+		}
+		writtenOff[orderID] = true
+	}
+
+	changed := b.settleQuarantinedItems(writtenOff, at)
+	b.dispose(BatchActionWriteOff, QuarantineOutcomePartialWriteOff, decidedBy, reason, at)
+	return changed, nil
+}
+
+// Destroy destroys the inspected batch together with its items still on the warehouse
+// floor. The IDs of the destroyed orders are returned.
+func (b *Batch) Destroy(decidedBy, reason string, at time.Time) ([]string, error) {
+	if err := b.checkDisposition(BatchActionDestroy, decidedBy); err != nil {
+		return nil, err
+	}
+	if reason == "" {
+		return nil, NewValidationError("destruction of batch %s requires a reason", b.ID)
+	}
+
+	var destroyed []string
+	for i, item := range b.Items {
+		if item.Status != ItemStatusDestroyed && item.Status.CanTransitionTo(ItemStatusDestroyed) {
+			b.Items[i].Status = ItemStatusDestroyed
+			b.Items[i].ProcessedAt = &at
+			destroyed = append(destroyed, item.OrderID)
+		}
+	}
+
+	b.dispose(BatchActionDestroy, QuarantineOutcomeDestroyed, decidedBy, reason, at)
+	b.ProcessedAt = &at
+	return destroyed, nil
+}
+
+// checkDisposition verifies that the batch may be disposed with the given action
+func (b *Batch) checkDisposition(action BatchAction, decidedBy string) error {
+	if err := b.Can(action); err != nil {
+		return err
+	}
+	if decidedBy == "" {
+		return NewValidationError("disposition of batch %s requires who decided it", b.ID)
+	}
+	return nil
+}
+
+// settleQuarantinedItems writes off the listed orders and releases the other items
+// still on the warehouse floor, returning the IDs of the changed orders
+func (b *Batch) settleQuarantinedItems(writtenOff map[string]bool, at time.Time) []string {
+	var changed []string
+	for i, item := range b.Items {
+		status := ItemStatusQuarantineReleased
+		if writtenOff[item.OrderID] {
+			status = ItemStatusWrittenOff
+		}
+		if item.Status == status || !item.Status.CanTransitionTo(status) {
+			continue
+		}
+
+		b.Items[i].Status = status
+		if status == ItemStatusWrittenOff {
+			b.Items[i].ProcessedAt = &at
+		}
+		changed = append(changed, item.OrderID)
+	}
+	return changed
+}
+
+// dispose moves the batch out of quarantine with the checked action and records the decision
+func (b *Batch) dispose(action BatchAction, outcome QuarantineOutcome, decidedBy, reason string, at time.Time) {
+	b.Status = batchTransitions[action].to
+
+	b.Disposition = &QuarantineDisposition{
+		Outcome:   outcome,
+		DecidedBy: decidedBy,
+		Reason:    reason,
+		DecidedAt: at,
+	}
+	b.UpdatedAt = at
+}
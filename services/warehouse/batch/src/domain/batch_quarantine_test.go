@@ -0,0 +1,172 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// newQuarantinedBatch returns a damaged batch with one allocated, one damaged and one
+// shipped order
+func newQuarantinedBatch(t *testing.T) *Batch {
+	t.Helper()
+
+	batch := NewBatch("batch-1", "product-1")
+	for orderID, status := range map[string]ItemStatus{
+		"order-1": ItemStatusAllocated,
+		"order-2": ItemStatusDamageMajor,
+		"order-3": ItemStatusShipped,
+	} {
+		if err := batch.AddItem(orderID, "product-1", 1, status); err != nil {
+			t.Fatalf("Failed to add %s: %v", orderID, err)
+		}
+	}
+	if err := batch.MarkAsDamaged(); err != nil {
+		t.Fatalf("Failed to mark batch as damaged: %v", err)
+	}
+	return batch
+}
+
+func itemStatus(t *testing.T, batch *Batch, orderID string) ItemStatus {
+	t.Helper()
+
+	item, err := batch.GetItemByOrderID(orderID)
+	if err != nil {
+		t.Fatalf("Failed to get %s: %v", orderID, err)
+	}
+	return item.Status
+}
+
+func TestBatch_DispositionRequiresInspection(t *testing.T) {
+	batch := newQuarantinedBatch(t)
+	at := time.Now()
+
+	if _, err := batch.ReleaseFromQuarantine("qa-lead", "", at); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("Expected ErrInvalidTransition releasing an uninspected batch, got %v", err)
+	}
+	if err := batch.RecordInspection("qa-1", "", at); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected ErrValidation for an inspection without findings, got %v", err)
+	}
+	if err := batch.RecordInspection("qa-1", "excursion of 2h at 11C", at); err != nil {
+		t.Fatalf("Failed to record inspection: %v", err)
+	}
+	if batch.Status != BatchStatusInspected || batch.Inspection.Inspector != "qa-1" {
+		t.Errorf("Expected inspected batch with the findings of qa-1, got %s %+v", batch.Status, batch.Inspection)
+	}
+	if _, err := batch.Destroy("qa-lead", "", at); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected ErrValidation destroying without a reason, got %v", err)
+	}
+	if _, err := batch.ReleaseFromQuarantine("", "", at); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected ErrValidation releasing without who decided it, got %v", err)
+	}
+}
+
+func TestBatch_ReleaseFromQuarantine(t *testing.T) {
+	batch := newQuarantinedBatch(t)
+	at := time.Now()
+	if err := batch.RecordInspection("qa-1", "packaging intact", at); err != nil {
+		t.Fatalf("Failed to record inspection: %v", err)
+	}
+
+	changed, err := batch.ReleaseFromQuarantine("qa-lead", "within stability data", at)
+	if err != nil {
+		t.Fatalf("Failed to release batch: %v", err)
+	}
+	if len(changed) != 2 {
+		t.Errorf("Expected 2 released orders, got %v", changed)
+	}
+	if batch.Status != BatchStatusPending || batch.Disposition.Outcome != QuarantineOutcomeReleased {
+		t.Errorf("Expected released pending batch, got %s %+v", batch.Status, batch.Disposition)
+	}
+	if itemStatus(t, batch, "order-1") != ItemStatusQuarantineReleased || itemStatus(t, batch, "order-2") != ItemStatusQuarantineReleased {
+		t.Errorf("Expected orders on the floor to be released, got %+v", batch.Items)
+	}
+	if itemStatus(t, batch, "order-3") != ItemStatusShipped {
+		t.Errorf("Expected shipped order to be untouched, got %s", itemStatus(t, batch, "order-3"))
+	}
+
+	// A released batch can be quarantined again, starting a new inspection
+	if err := batch.MarkAsDamaged(); err != nil {
+		t.Fatalf("Failed to mark released batch as damaged: %v", err)
+	}
+	if batch.Inspection != nil || batch.Disposition != nil {
+		t.Errorf("Expected a new quarantine to clear the previous one, got %+v %+v", batch.Inspection, batch.Disposition)
+	}
+}
+
+func TestBatch_WriteOff(t *testing.T) {
+	batch := newQuarantinedBatch(t)
+	at := time.Now()
+	if err := batch.RecordInspection("qa-1", "two cartons crushed", at); err != nil {
+		t.Fatalf("Failed to record inspection: %v", err)
+	}
+
+	if _, err := batch.WriteOff([]string{"order-3"}, "qa-lead", "crushed", at); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("Expected ErrInvalidTransition writing off a shipped order, got %v", err)
+	}
+	if _, err := batch.WriteOff([]string{"order-9"}, "qa-lead", "crushed", at); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound writing off an unknown order, got %v", err)
+	}
+	if batch.Status != BatchStatusInspected {
+		t.Fatalf("Expected a refused write-off to leave the batch inspected, got %s", batch.Status)
+	}
+
+	if _, err := batch.WriteOff([]string{"order-2"}, "qa-lead", "crushed", at); err != nil {
+		t.Fatalf("Failed to write off: %v", err)
+	}
+	if batch.Status != BatchStatusPending || batch.Disposition.Outcome != QuarantineOutcomePartialWriteOff {
+		t.Errorf("Expected partially written off pending batch, got %s %+v", batch.Status, batch.Disposition)
+	}
+	if itemStatus(t, batch, "order-1") != ItemStatusQuarantineReleased || itemStatus(t, batch, "order-2") != ItemStatusWrittenOff {
+		t.Errorf("Expected order-1 released and order-2 written off, got %+v", batch.Items)
+	}
+}
+
+func TestBatch_Destroy(t *testing.T) {
+	batch := newQuarantinedBatch(t)
+	at := time.Now()
+	if err := batch.RecordInspection("qa-1", "excursion of 30h at 25C", at); err != nil {
+		t.Fatalf("Failed to record inspection: %v", err)
+	}
+
+	changed, err := batch.Destroy("qa-lead", "outside stability data", at)
+	if err != nil {
+		t.Fatalf("Failed to destroy batch: %v", err)
+	}
+	if len(changed) != 2 || batch.Status != BatchStatusDestroyed || batch.ProcessedAt == nil {
+		t.Errorf("Expected destroyed batch with 2 destroyed orders, got %s %v", batch.Status, changed)
+	}
+	if itemStatus(t, batch, "order-1") != ItemStatusDestroyed || itemStatus(t, batch, "order-3") != ItemStatusShipped {
+		t.Errorf("Expected orders on the floor to be destroyed, got %+v", batch.Items)
+	}
+	if err := batch.AddItem("order-4", "product-1", 1, ItemStatusAllocated); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("Expected ErrInvalidTransition adding to a destroyed batch, got %v", err)
+	}
+}
+
+func TestBatch_QuarantineStatusesOutsideQuarantine(t *testing.T) {
+	batch := NewBatch("batch-1", "product-1")
+
+	for _, status := range []ItemStatus{ItemStatusQuarantineReleased, ItemStatusWrittenOff, ItemStatusDestroyed} {
+		if err := batch.AddItem("order-1", "product-1", 1, status); !errors.Is(err, ErrValidation) {
+			t.Errorf("Expected ErrValidation adding an order %s, got %v", status, err)
+		}
+	}
+
+	if err := batch.AddItem("order-1", "product-1", 1, ItemStatusAllocated); err != nil {
+		t.Fatalf("Failed to add order: %v", err)
+	}
+	if err := batch.UpdateItemStatus("order-1", ItemStatusWrittenOff); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("Expected ErrInvalidTransition writing off an order of a pending batch, got %v", err)
+	}
+	if itemStatus(t, batch, "order-1") != ItemStatusAllocated {
+		t.Errorf("Expected order-1 to stay allocated, got %s", itemStatus(t, batch, "order-1"))
+	}
+
+	if err := batch.MarkAsDamaged(); err != nil {
+		t.Fatalf("Failed to mark batch as damaged: %v", err)
+	}
+	if err := batch.UpdateItemStatus("order-1", ItemStatusWrittenOff); err != nil {
+		t.Errorf("Expected a quarantined order to be written off, got %v", err)
+	}
+}
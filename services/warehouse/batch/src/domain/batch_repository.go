@@ -1,28 +1,61 @@
 package domain
 
+import "time"
+
 // BatchRepository defines the contract for batch persistence
 type BatchRepository interface {
-	// Save stores or updates a batch
+	// Save stores or updates a batch. The batch version must match the stored one
+	// (zero for a new batch), otherwise an ErrConcurrencyConflict error is returned.
+	// On success the version of the given batch is incremented. The change is recorded
+	// in the audit trail without a command.
 	Save(batch *Batch) error
-	
+
+	// SaveWithEvents stores or updates a batch, records the command in its audit trail and
+	// enqueues its events in the outbox atomically, with the same version check as Save
+	SaveWithEvents(batch *Batch, command BatchCommand, events ...*BatchEvent) error
+
 	// FindByID retrieves a batch by its ID
 	FindByID(id string) (*Batch, error)
-	
+
 	// FindByProductID retrieves all batches for a specific product
 	FindByProductID(productID string) ([]*Batch, error)
-	
+
 	// FindByStatus retrieves all batches with a specific status
 	FindByStatus(status BatchStatus) ([]*Batch, error)
-	
+
 	// FindByOrderID finds the batch containing a specific order
 	FindByOrderID(orderID string) (*Batch, error)
-	
-	// FindPendingBatchForProduct finds a pending batch for a product (for adding new orders)
-	FindPendingBatchForProduct(productID string) (*Batch, error)
-	
+
+	// FindPendingBatchesForProduct retrieves the pending batches of a product (for adding
+	// new orders) in first-expired-first-out order
+	FindPendingBatchesForProduct(productID string) ([]*Batch, error)
+
+	// FindExpiringBefore retrieves the batches that are not completed, cancelled or destroyed
+	// and whose lot expires before the cutoff, earliest expiration first
+	FindExpiringBefore(cutoff time.Time) ([]*Batch, error)
+
 	// Delete removes a batch from the repository
 	Delete(id string) error
-	
+
+	// DeleteWithEvents removes a batch, records the command in its audit trail and
+	// enqueues its events in the outbox atomically. The batch version must match the
+	// stored one.
+	DeleteWithEvents(batch *Batch, command BatchCommand, events ...*BatchEvent) error
+
 	// GetAll retrieves all batches
 	GetAll() ([]*Batch, error)
+
+	// The repository also owns the transactional outbox of batch events
+	OutboxStore
+
+	// and the audit trail of the batches
+	BatchAuditLog
+}
+
+// BatchChangeHistory is implemented by batch repositories that can keep the history of
+// changes of every batch
+type BatchChangeHistory interface {
+	// FindByIDAt rebuilds a batch from the changes recorded up to the given time.
+	// An ErrValidation error is returned when the change history is disabled.
+	FindByIDAt(id string, at time.Time) (*Batch, error)
 }
\ No newline at end of file
@@ -0,0 +1,195 @@
+package domain
+
+// ItemStatus represents the status of an order item within a batch
+type ItemStatus string
+
+const (
+	ItemStatusAllocated           ItemStatus = "allocated"
+	ItemStatusAllocationConfirmed ItemStatus = "allocation_confirmed"
+	ItemStatusProcessed           ItemStatus = "processed"
+	ItemStatusShipped             ItemStatus = "shipped"
+	ItemStatusDelivered           ItemStatus = "delivered"
+	ItemStatusReturned            ItemStatus = "returned"
+	ItemStatusReleaseConfirmed    ItemStatus = "release_confirmed"
+	ItemStatusDamageMinor         ItemStatus = "damage_minor"
+	ItemStatusDamageMajor         ItemStatus = "damage_major"
+	ItemStatusDamageProcessed     ItemStatus = "damage_processed"
+	ItemStatusQuarantineReleased  ItemStatus = "quarantine_released"
+	ItemStatusWrittenOff          ItemStatus = "written_off"
+	ItemStatusDestroyed           ItemStatus = "destroyed"
+)
+
+// itemTransitions lists the statuses an item may move to from each status.
+// An item may be added in any status, and setting the status it already has is always
+// allowed so that redelivered events are harmless. The quarantine outcomes only apply
+// to items still on the warehouse floor.
+var itemTransitions = map[ItemStatus][]ItemStatus{
+	ItemStatusAllocated: {
+		ItemStatusAllocationConfirmed, ItemStatusProcessed, ItemStatusShipped,
+		ItemStatusReleaseConfirmed, ItemStatusDamageMinor, ItemStatusDamageMajor,
+		ItemStatusQuarantineReleased, ItemStatusWrittenOff, ItemStatusDestroyed,
+	},
+	ItemStatusAllocationConfirmed: {
+		ItemStatusProcessed, ItemStatusShipped, ItemStatusReleaseConfirmed,
+		ItemStatusDamageMinor, ItemStatusDamageMajor,
+		ItemStatusQuarantineReleased, ItemStatusWrittenOff, ItemStatusDestroyed,
+	},
+	ItemStatusProcessed: {ItemStatusShipped, ItemStatusDamageMinor, ItemStatusDamageMajor},
+	ItemStatusShipped:   {ItemStatusDelivered, ItemStatusReturned, ItemStatusDamageMinor, ItemStatusDamageMajor},
+	ItemStatusDelivered: {ItemStatusReturned, ItemStatusDamageMinor, ItemStatusDamageMajor},
+	ItemStatusReturned: {
+		ItemStatusDamageMinor, ItemStatusDamageMajor,
+		ItemStatusQuarantineReleased, ItemStatusWrittenOff, ItemStatusDestroyed,
+	},
+	ItemStatusDamageMinor: {
+		ItemStatusDamageMajor, ItemStatusDamageProcessed,
+		ItemStatusQuarantineReleased, ItemStatusWrittenOff, ItemStatusDestroyed,
+	},
+	ItemStatusDamageMajor: {
+		ItemStatusDamageProcessed,
+		ItemStatusQuarantineReleased, ItemStatusWrittenOff, ItemStatusDestroyed,
+	},
+	ItemStatusQuarantineReleased: {
+		ItemStatusProcessed, ItemStatusShipped, ItemStatusReleaseConfirmed,
+		ItemStatusDamageMinor, ItemStatusDamageMajor,
+		ItemStatusWrittenOff, ItemStatusDestroyed,
+	},
+	ItemStatusDamageProcessed:  {},
+	ItemStatusReleaseConfirmed: {},
+	ItemStatusWrittenOff:       {},
+	ItemStatusDestroyed:        {},
+}
+
+// ValidateItemStatus returns an ErrValidation error if the status is unknown
+func ValidateItemStatus(status ItemStatus) error {
+	if _, known := itemTransitions[status]; !known {
+		return NewValidationError("unknown item status: %s", status)
+	}
+	return nil
+}
+
+// CanTransitionTo reports whether an item in this status may move to the next status
+func (s ItemStatus) CanTransitionTo(next ItemStatus) bool {
+	if s == next {
+		return true
+	}
+	for _, allowed := range itemTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// IsProcessed reports whether the item has left the warehouse floor
+func (s ItemStatus) IsProcessed() bool {
+	return s == ItemStatusProcessed || s == ItemStatusShipped || s == ItemStatusDelivered
+}
+
+// IsQuarantineDisposition reports whether the status is only reached through the
+// disposition of a quarantined batch
+func (s ItemStatus) IsQuarantineDisposition() bool {
+	return s == ItemStatusQuarantineReleased || s == ItemStatusWrittenOff || s == ItemStatusDestroyed
+}
+
+// BatchAction is a command that can be applied to a batch
+type BatchAction string
+
+const (
+	BatchActionProcess     BatchAction = "process"
+	BatchActionComplete    BatchAction = "complete"
+	BatchActionCancel      BatchAction = "cancel"
+	BatchActionMarkDamaged BatchAction = "damage"
+	BatchActionAddItem     BatchAction = "add_item"
+	BatchActionRemoveItem  BatchAction = "remove_item"
+	BatchActionInspect     BatchAction = "inspect"
+	BatchActionRelease     BatchAction = "release"
+	BatchActionWriteOff    BatchAction = "write_off"
+	BatchActionDestroy     BatchAction = "destroy"
+	BatchActionHold        BatchAction = "hold"
+)
+
+// batchTransition describes from which statuses an action is allowed and the status it
+// leads to; actions without a target status leave the status unchanged
+type batchTransition struct {
+	from []BatchStatus
+	to   BatchStatus
+}
+
+// batchActions lists the batch actions in the order they are reported
+var batchActions = []BatchAction{
+	BatchActionProcess,
+	BatchActionComplete,
+	BatchActionCancel,
+	BatchActionMarkDamaged,
+	BatchActionAddItem,
+	BatchActionRemoveItem,
+	BatchActionInspect,
+	BatchActionRelease,
+	BatchActionWriteOff,
+	BatchActionDestroy,
+	BatchActionHold,
+}
+
+// batchTransitions is the state machine of a batch. A damaged batch is quarantined
+// until it is inspected; the inspected batch is then released back to pending, partly
+// written off (the rest going back to pending) or destroyed. A batch on hold can only
+// be cancelled once the reason of the hold is resolved.
+var batchTransitions = map[BatchAction]batchTransition{
+	BatchActionProcess:  {from: []BatchStatus{BatchStatusPending}, to: BatchStatusProcessing},
+	BatchActionComplete: {from: []BatchStatus{BatchStatusProcessing}, to: BatchStatusCompleted},
+	BatchActionCancel: {from: []BatchStatus{
+		BatchStatusPending, BatchStatusProcessing, BatchStatusDamaged, BatchStatusInspected, BatchStatusOnHold,
+	}, to: BatchStatusCancelled},
+	BatchActionMarkDamaged: {from: []BatchStatus{BatchStatusPending, BatchStatusProcessing}, to: BatchStatusDamaged},
+	BatchActionAddItem:     {from: []BatchStatus{BatchStatusPending, BatchStatusProcessing, BatchStatusDamaged}},
+	BatchActionRemoveItem: {from: []BatchStatus{
+		BatchStatusPending, BatchStatusProcessing, BatchStatusDamaged, BatchStatusInspected,
+		BatchStatusCancelled, BatchStatusDestroyed, BatchStatusOnHold,
+	}},
+	BatchActionInspect:  {from: []BatchStatus{BatchStatusDamaged, BatchStatusInspected}, to: BatchStatusInspected},
+	BatchActionRelease:  {from: []BatchStatus{BatchStatusInspected}, to: BatchStatusPending},
+	BatchActionWriteOff: {from: []BatchStatus{BatchStatusInspected}, to: BatchStatusPending},
+	BatchActionDestroy:  {from: []BatchStatus{BatchStatusInspected}, to: BatchStatusDestroyed},
+	BatchActionHold: {from: []BatchStatus{
+		BatchStatusPending, BatchStatusProcessing, BatchStatusDamaged, BatchStatusInspected, BatchStatusOnHold,
+	}, to: BatchStatusOnHold},
+}
+
+// Can returns an ErrInvalidTransition error if the batch status does not allow the action
+func (b *Batch) Can(action BatchAction) error {
+	transition, known := batchTransitions[action]
+	if !known {
+		return NewValidationError("unknown batch action: %s", action)
+	}
+
+	for _, status := range transition.from {
+		if b.Status == status {
+			return nil
+		}
+	}
+	return NewTransitionError("cannot %s batch with status %s", action, b.Status)
+}
+
+// AllowedActions returns the actions the current batch status allows
+func (b *Batch) AllowedActions() []BatchAction {
+	allowed := make([]BatchAction, 0, len(batchActions))
+	for _, action := range batchActions {
+		if b.Can(action) == nil {
+			allowed = append(allowed, action)
+		}
+	}
+	return allowed
+}
+
+// apply checks the action against the state machine and moves the batch to its target status
+func (b *Batch) apply(action BatchAction) error {
+	if err := b.Can(action); err != nil {
+		return err
+	}
+
+	if to := batchTransitions[action].to; to != "" {
+		b.Status = to
+	}
+	return nil
+}
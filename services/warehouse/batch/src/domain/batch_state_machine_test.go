@@ -0,0 +1,107 @@
+package domain
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestBatch_AllowedActions(t *testing.T) {
+	testCases := []struct {
+		status   BatchStatus
+		expected []BatchAction
+	}{
+		{BatchStatusPending, []BatchAction{BatchActionProcess, BatchActionCancel, BatchActionMarkDamaged, BatchActionAddItem, BatchActionRemoveItem, BatchActionHold}},
+		{BatchStatusProcessing, []BatchAction{BatchActionComplete, BatchActionCancel, BatchActionMarkDamaged, BatchActionAddItem, BatchActionRemoveItem, BatchActionHold}},
+		{BatchStatusDamaged, []BatchAction{BatchActionCancel, BatchActionAddItem, BatchActionRemoveItem, BatchActionInspect, BatchActionHold}},
+		{BatchStatusInspected, []BatchAction{
+			BatchActionCancel, BatchActionRemoveItem, BatchActionInspect,
+			BatchActionRelease, BatchActionWriteOff, BatchActionDestroy, BatchActionHold,
+		}},
+		{BatchStatusOnHold, []BatchAction{BatchActionCancel, BatchActionRemoveItem, BatchActionHold}},
+		{BatchStatusCancelled, []BatchAction{BatchActionRemoveItem}},
+		{BatchStatusDestroyed, []BatchAction{BatchActionRemoveItem}},
+		{BatchStatusCompleted, []BatchAction{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(string(tc.status), func(t *testing.T) {
+			batch := NewBatch("batch-1", "product-1")
+			batch.Status = tc.status
+
+			if actions := batch.AllowedActions(); !reflect.DeepEqual(actions, tc.expected) {
+				t.Errorf("Expected actions %v, got %v", tc.expected, actions)
+			}
+		})
+	}
+}
+
+func TestBatch_MarkAsDamagedRejectsFinishedBatches(t *testing.T) {
+	for _, status := range []BatchStatus{BatchStatusCompleted, BatchStatusCancelled, BatchStatusDamaged} {
+		batch := NewBatch("batch-1", "product-1")
+		batch.Status = status
+
+		if err := batch.MarkAsDamaged(); !errors.Is(err, ErrInvalidTransition) {
+			t.Errorf("Expected ErrInvalidTransition marking a %s batch as damaged, got %v", status, err)
+		}
+		if batch.Status != status {
+			t.Errorf("Expected status to stay %s, got %s", status, batch.Status)
+		}
+	}
+
+	batch := NewBatch("batch-1", "product-1")
+	if err := batch.MarkAsDamaged(); err != nil || batch.Status != BatchStatusDamaged {
+		t.Errorf("Expected pending batch to be marked as damaged, got %s (err: %v)", batch.Status, err)
+	}
+	if err := batch.Cancel(); err != nil {
+		t.Errorf("Expected damaged batch to be cancellable, got %v", err)
+	}
+	if err := batch.Cancel(); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("Expected ErrInvalidTransition cancelling twice, got %v", err)
+	}
+}
+
+func TestBatch_ItemStatusTransitions(t *testing.T) {
+	batch := NewBatch("batch-1", "product-1")
+	if err := batch.AddItem("order-1", "product-1", 1, ItemStatusAllocated); err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+
+	steps := []struct {
+		status  ItemStatus
+		allowed bool
+	}{
+		{ItemStatusShipped, true},
+		{ItemStatusShipped, true}, // redelivered event
+		{ItemStatusAllocated, false},
+		{ItemStatusDelivered, true},
+		{ItemStatusReturned, true},
+		{ItemStatusDamageProcessed, false},
+		{ItemStatusDamageMinor, true},
+		{ItemStatusDamageProcessed, true},
+		{ItemStatusDamageMajor, false},
+	}
+
+	for _, step := range steps {
+		current := batch.Items[0].Status
+		err := batch.UpdateItemStatus("order-1", step.status)
+		if step.allowed && err != nil {
+			t.Errorf("Expected %s -> %s to be allowed, got %v", current, step.status, err)
+		}
+		if !step.allowed && !errors.Is(err, ErrInvalidTransition) {
+			t.Errorf("Expected %s -> %s to be rejected, got %v", current, step.status, err)
+		}
+	}
+
+	if err := batch.UpdateItemStatus("order-1", "lost"); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected ErrValidation for unknown item status, got %v", err)
+	}
+	if err := batch.AddItem("order-2", "product-1", 1, "lost"); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected ErrValidation adding an item with unknown status, got %v", err)
+	}
+
+	// Re-adding an existing order follows the same transitions
+	if err := batch.AddItem("order-1", "product-1", 1, ItemStatusAllocated); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("Expected ErrInvalidTransition re-allocating a processed damage, got %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package domain
+
+import (
+	"time"
+)
+
+// BatchChange is an entry in the change history of a batch: the difference between the
+// stored state of the batch and its state after a command, recorded with the types of
+// the batch events the command published.
+// The first change of a batch carries the created batch, the following ones the delta
+// from the previous version, and the last one may delete the batch.
+type BatchChange struct {
+	BatchID    string           `json:"batch_id"`
+	Version    int64            `json:"version"`
+	EventTypes []BatchEventType `json:"event_types,omitempty"`
+	Created    *Batch           `json:"created,omitempty"`
+	Delta      *BatchDelta      `json:"delta,omitempty"`
+	Deleted    bool             `json:"deleted,omitempty"`
+	RecordedAt time.Time        `json:"recorded_at"`
+}
+
+// BatchSnapshot is the state of a batch at a version of its history, taken so that a past
+// state of the batch can be rebuilt without replaying all its changes
+type BatchSnapshot struct {
+	Batch   *Batch    `json:"batch"`
+	TakenAt time.Time `json:"taken_at"`
+}
+
+// NewBatchChange records the change from the stored state of a batch, nil for a new
+// batch, to its state after a command, nil when the command deleted it
+func NewBatchChange(before, after *Batch, events []*BatchEvent, recordedAt time.Time) BatchChange {
+	change := BatchChange{RecordedAt: recordedAt}
+	for _, event := range events {
+		change.EventTypes = append(change.EventTypes, event.EventType)
+	}
+
+	switch {
+	case after == nil:
+		change.BatchID = before.ID
+		change.Version = before.Version + 1
+		change.Deleted = true
+	case before == nil:
+		change.BatchID = after.ID
+		change.Version = after.Version
+		change.Created = after.Clone()
+	default:
+		change.BatchID = after.ID
+		change.Version = after.Version
+		change.Delta = DiffBatch(before, after)
+	}
+	return change
+}
+
+// Apply returns the state of the batch after the change, nil if the change deleted it.
+// The change must follow the given state, which is nil before the batch is created;
+// otherwise a conflict error is returned.
+func (c BatchChange) Apply(state *Batch) (*Batch, error) {
+	switch {
+	case c.Created != nil:
+		if state != nil {
+			return nil, NewConflictError("change %d creates batch %s, which already exists", c.Version, c.BatchID)
+		}
+		return c.Created.Clone(), nil
+	case state == nil:
+		return nil, NewConflictError("change %d of batch %s has no state to apply to", c.Version, c.BatchID)
+	case state.Version+1 != c.Version:
+		return nil, NewConflictError("change %d of batch %s does not follow version %d", c.Version, c.BatchID, state.Version)
+	case c.Deleted:
+		return nil, nil
+	case c.Delta == nil:
+		return nil, NewConflictError("change %d of batch %s carries no delta", c.Version, c.BatchID)
+	}
+	return c.Delta.Apply(state)
+}
+
+// ReplayBatch rebuilds a batch by applying its changes in order to a snapshot, or from
+// its creation when the snapshot is nil. Changes up to the version of the snapshot are
+// skipped. It returns nil when the changes delete the batch.
+func ReplayBatch(snapshot *BatchSnapshot, changes []BatchChange) (*Batch, error) {
+	var state *Batch
+	if snapshot != nil {
+		state = snapshot.Batch.Clone()
+	}
+
+	for _, change := range changes {
+		if state != nil && change.Version <= state.Version {
+			continue
+		}
+		next, err := change.Apply(state)
+		if err != nil {
+			return nil, err
+		}
+		state = next
+	}
+	return state, nil
+}
@@ -0,0 +1,97 @@
+package domain
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReplayBatch_RebuildsBatchFromChanges(t *testing.T) {
+	at := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	batch := NewBatch("batch-1", "product-1")
+
+	var (
+		changes   []BatchChange
+		snapshots []*BatchSnapshot
+		stored    *Batch
+	)
+	record := func(change func(batch *Batch) error, eventType BatchEventType) {
+		t.Helper()
+		if err := change(batch); err != nil {
+			t.Fatalf("Failed to change batch: %v", err)
+		}
+		batch.Version++
+		event := &BatchEvent{EventType: eventType, BatchID: batch.ID}
+		changes = append(changes, NewBatchChange(stored, batch, []*BatchEvent{event}, at))
+		stored = batch.Clone()
+		snapshots = append(snapshots, &BatchSnapshot{Batch: stored.Clone(), TakenAt: at})
+	}
+
+	record(func(batch *Batch) error { return nil }, BatchEventCreated)
+	record(func(batch *Batch) error {
+		return batch.AddItem("order-1", "product-1", 2, ItemStatusAllocated)
+	}, BatchEventItemAdded)
+	record(func(batch *Batch) error {
+		return batch.AddItem("order-2", "product-1", 1, ItemStatusAllocated)
+	}, BatchEventItemAdded)
+	record(func(batch *Batch) error { return batch.RemoveItem("order-1") }, BatchEventItemRemoved)
+	record(func(batch *Batch) error { return batch.StartProcessing() }, BatchEventProcessing)
+
+	if changes[0].Created == nil || changes[1].Delta == nil || changes[1].EventTypes[0] != BatchEventItemAdded {
+		t.Fatalf("Expected a creation followed by deltas, got %+v", changes[:2])
+	}
+
+	// From the creation, and from every snapshot with the changes after it
+	rebuilt, err := ReplayBatch(nil, changes)
+	if err != nil {
+		t.Fatalf("Failed to replay batch: %v", err)
+	}
+	if !reflect.DeepEqual(rebuilt, batch) {
+		t.Errorf("Expected %+v, got %+v", batch, rebuilt)
+	}
+	for _, snapshot := range snapshots {
+		rebuilt, err := ReplayBatch(snapshot, changes)
+		if err != nil {
+			t.Fatalf("Failed to replay batch from version %d: %v", snapshot.Batch.Version, err)
+		}
+		if !reflect.DeepEqual(rebuilt, batch) {
+			t.Errorf("Expected %+v from version %d, got %+v", batch, snapshot.Batch.Version, rebuilt)
+		}
+	}
+
+	// An earlier state is the replay of the changes up to it
+	rebuilt, err = ReplayBatch(nil, changes[:3])
+	if err != nil || len(rebuilt.Items) != 2 || rebuilt.Version != 3 {
+		t.Errorf("Expected version 3 with two items, got %+v (%v)", rebuilt, err)
+	}
+
+	deleted := append(changes, NewBatchChange(batch, nil, nil, at))
+	if rebuilt, err := ReplayBatch(snapshots[2], deleted); err != nil || rebuilt != nil {
+		t.Errorf("Expected the deleted batch to replay to nil, got %+v (%v)", rebuilt, err)
+	}
+}
+
+func TestReplayBatch_RejectsGapsInStream(t *testing.T) {
+	before := NewBatch("batch-1", "product-1")
+	before.Version = 1
+	after := before.Clone()
+	if err := after.AddItem("order-1", "product-1", 2, ItemStatusAllocated); err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+	after.Version = 2
+	third := after.Clone()
+	if err := third.StartProcessing(); err != nil {
+		t.Fatalf("Failed to start processing: %v", err)
+	}
+	third.Version = 3
+
+	created := NewBatchChange(nil, before, nil, time.Now())
+	missing := NewBatchChange(after, third, nil, time.Now())
+	if _, err := ReplayBatch(nil, []BatchChange{created, missing}); !errors.Is(err, ErrConcurrencyConflict) {
+		t.Errorf("Expected a conflict for a missing change, got %v", err)
+	}
+	if _, err := ReplayBatch(nil, []BatchChange{missing}); !errors.Is(err, ErrConcurrencyConflict) {
+		t.Errorf("Expected a conflict for a stream without creation, got %v", err)
+	}
+}
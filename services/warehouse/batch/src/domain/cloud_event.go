@@ -0,0 +1,169 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// CloudEventsSpecVersion is the supported version of the CloudEvents specification
+	CloudEventsSpecVersion = "1.0"
+
+	// CloudEventsContentType is the content type of a structured-mode CloudEvent
+	CloudEventsContentType = "application/cloudevents+json"
+
+	// CloudEventsHeaderPrefix prefixes the attributes of a binary-mode CloudEvent in the
+	// Kafka message headers
+	CloudEventsHeaderPrefix = "ce_"
+)
+
+// CloudEvent is the JSON envelope of a structured-mode CloudEvent. In binary mode the
+// same attributes travel as ce_ headers and the message value holds the data. JSON data
+// is embedded as is, any other data is carried base64-encoded in data_base64.
+type CloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Type            string          `json:"type"`
+	Source          string          `json:"source"`
+	Subject         string          `json:"subject,omitempty"`
+	Time            *time.Time      `json:"time,omitempty"`
+	DataContentType string          `json:"datacontenttype,omitempty"`
+	Data            json.RawMessage `json:"data,omitempty"`
+	DataBase64      []byte          `json:"data_base64,omitempty"`
+}
+
+// Validate checks the required attributes of the CloudEvent
+func (e *CloudEvent) Validate() error {
+	if e.SpecVersion != CloudEventsSpecVersion {
+		return fmt.Errorf("unsupported CloudEvents spec version %q", e.SpecVersion)
+	}
+	if e.ID == "" || e.Type == "" || e.Source == "" {
+		return fmt.Errorf("CloudEvent requires id, type and source attributes")
+	}
+	if len(e.Data) == 0 && len(e.DataBase64) == 0 {
+		return fmt.Errorf("CloudEvent %s has no data", e.ID)
+	}
+	return nil
+}
+
+// payload returns the data of the CloudEvent, decoded from data_base64 if needed
+func (e *CloudEvent) payload() []byte {
+	if len(e.DataBase64) > 0 {
+		return e.DataBase64
+	}
+	return e.Data
+}
+
+// DecodeOrderEvent decodes an order event message. Binary-mode CloudEvents are recognized
+// by their ce_specversion header and structured-mode ones by their content type or
+// specversion attribute; any other message is a plain order event. The data is decoded
+// by the codec of its content type and the attributes of a CloudEvent fill the event ID,
+// type, timestamp and order ID of its data. The trace context of the event is read from
+// its traceparent and tracestate headers.
+func (codecs EventCodecs) DecodeOrderEvent(payload []byte, headers []MessageHeader) (OrderEvent, error) {
+	var orderEvent OrderEvent
+
+	if err := checkSchemaVersion(headers); err != nil {
+		return orderEvent, err
+	}
+	cloudEvent, err := decodeCloudEvent(payload, headers)
+	if err != nil {
+		return orderEvent, err
+	}
+	if cloudEvent == nil {
+		codec, err := codecs.ForContentType(headerValue(headers, "content-type"))
+		if err != nil {
+			return orderEvent, err
+		}
+		orderEvent, err = codec.DecodeOrderEvent(payload)
+		orderEvent.TraceContext = traceContextFromHeaders(headers)
+		return orderEvent, err
+	}
+
+	codec, err := codecs.ForContentType(cloudEvent.DataContentType)
+	if err != nil {
+		return orderEvent, fmt.Errorf("failed to decode data of CloudEvent %s: %w", cloudEvent.ID, err)
+	}
+	if orderEvent, err = codec.DecodeOrderEvent(cloudEvent.payload()); err != nil {
+		return orderEvent, fmt.Errorf("failed to decode data of CloudEvent %s: %w", cloudEvent.ID, err)
+	}
+	orderEvent.EventID = cloudEvent.ID
+	if orderEvent.EventType == "" {
+		orderEvent.EventType = cloudEvent.Type
+	}
+	if orderEvent.Timestamp.IsZero() && cloudEvent.Time != nil {
+		orderEvent.Timestamp = *cloudEvent.Time
+	}
+	if orderEvent.OrderID == "" {
+		orderEvent.OrderID = cloudEvent.Subject
+	}
+	if orderEvent.OrderID == "" {
+		orderEvent.OrderID = orderEvent.Order.ID
+	}
+	orderEvent.TraceContext = traceContextFromHeaders(headers)
+	return orderEvent, nil
+}
+
+// decodeCloudEvent returns the CloudEvent carried by a message, or nil if the message is
+// not a CloudEvent
+func decodeCloudEvent(payload []byte, headers []MessageHeader) (*CloudEvent, error) {
+	attributes := make(map[string]string)
+	for _, header := range headers {
+		if strings.HasPrefix(header.Key, CloudEventsHeaderPrefix) {
+			attributes[strings.TrimPrefix(header.Key, CloudEventsHeaderPrefix)] = header.Value
+		}
+	}
+	contentType := headerValue(headers, "content-type")
+
+	var cloudEvent CloudEvent
+	switch {
+	case attributes["specversion"] != "":
+		cloudEvent = CloudEvent{
+			SpecVersion:     attributes["specversion"],
+			ID:              attributes["id"],
+			Type:            attributes["type"],
+			Source:          attributes["source"],
+			Subject:         attributes["subject"],
+			DataContentType: contentType,
+			Data:            payload,
+		}
+		if value := attributes["time"]; value != "" {
+			at, err := time.Parse(time.RFC3339Nano, value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid ce_time header %q: %w", value, err)
+			}
+			cloudEvent.Time = &at
+		}
+	case strings.HasPrefix(contentType, CloudEventsContentType) || isStructuredCloudEvent(payload):
+		if err := json.Unmarshal(payload, &cloudEvent); err != nil {
+			return nil, fmt.Errorf("failed to decode CloudEvent: %w", err)
+		}
+	default:
+		return nil, nil
+	}
+
+	if err := cloudEvent.Validate(); err != nil {
+		return nil, err
+	}
+	return &cloudEvent, nil
+}
+
+// headerValue returns the value of a header, matching its key case-insensitively
+func headerValue(headers []MessageHeader, key string) string {
+	for _, header := range headers {
+		if strings.EqualFold(header.Key, key) {
+			return header.Value
+		}
+	}
+	return ""
+}
+
+// isStructuredCloudEvent reports whether a JSON payload carries a specversion attribute
+func isStructuredCloudEvent(payload []byte) bool {
+	var envelope struct {
+		SpecVersion string `json:"specversion"`
+	}
+	return json.Unmarshal(payload, &envelope) == nil && envelope.SpecVersion != ""
+}
@@ -0,0 +1,101 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEventCodecs_DecodeOrderEvent(t *testing.T) {
+	var codecs EventCodecs // JSON only
+	at := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	plain := []byte(`{"event_type":"order.created","order_id":"order-1","order":{"id":"order-1","quantity":2}}`)
+	binaryHeaders := []MessageHeader{
+		{Key: "ce_specversion", Value: "1.0"},
+		{Key: "ce_id", Value: "evt-1"},
+		{Key: "ce_type", Value: "order.created"},
+		{Key: "ce_source", Value: "/medisupply/orders"},
+		{Key: "ce_subject", Value: "order-1"},
+		{Key: "ce_time", Value: at.Format(time.RFC3339Nano)},
+	}
+
+	event, err := codecs.DecodeOrderEvent(plain, nil)
+	if err != nil {
+		t.Fatalf("Failed to decode plain order event: %v", err)
+	}
+	if event.EventID != "" || event.EventType != "order.created" || event.Order.Quantity != 2 {
+		t.Errorf("Expected the plain order event as sent, got %+v", event)
+	}
+
+	// The data of a binary-mode event only needs the order
+	event, err = codecs.DecodeOrderEvent([]byte(`{"order":{"id":"order-1","quantity":2}}`), binaryHeaders)
+	if err != nil {
+		t.Fatalf("Failed to decode binary-mode CloudEvent: %v", err)
+	}
+	if event.EventID != "evt-1" || event.EventType != "order.created" || event.OrderID != "order-1" || !event.Timestamp.Equal(at) {
+		t.Errorf("Expected the CloudEvent attributes to fill the order event, got %+v", event)
+	}
+
+	structured := []byte(`{"specversion":"1.0","id":"evt-2","type":"order.created","source":"/medisupply/orders","time":"2025-03-01T09:00:00Z","data":` + string(plain) + `}`)
+	for name, headers := range map[string][]MessageHeader{
+		"sniffed":      nil,
+		"content type": {{Key: "content-type", Value: CloudEventsContentType + "; charset=utf-8"}},
+	} {
+		event, err = codecs.DecodeOrderEvent(structured, headers)
+		if err != nil {
+			t.Fatalf("Failed to decode structured-mode CloudEvent (%s): %v", name, err)
+		}
+		if event.EventID != "evt-2" || event.OrderID != "order-1" || event.Order.Quantity != 2 {
+			t.Errorf("Expected the structured CloudEvent to be decoded (%s), got %+v", name, event)
+		}
+	}
+
+	invalid := map[string]struct {
+		payload []byte
+		headers []MessageHeader
+	}{
+		"unsupported spec version": {[]byte(`{"specversion":"0.3","id":"evt-3","type":"order.created","source":"/orders","data":{}}`), nil},
+		"missing id":               {[]byte(`{"specversion":"1.0","type":"order.created","source":"/orders","data":{}}`), nil},
+		"missing data":             {[]byte(`{"specversion":"1.0","id":"evt-3","type":"order.created","source":"/orders"}`), nil},
+		"invalid time":             {plain, append([]MessageHeader{{Key: "ce_time", Value: "yesterday"}}, binaryHeaders[:4]...)},
+		"newer schema version":     {plain, []MessageHeader{{Key: SchemaVersionHeader, Value: "2"}}},
+		"unsupported content type": {plain, []MessageHeader{{Key: "content-type", Value: "application/avro"}}},
+	}
+	for name, tc := range invalid {
+		if _, err := codecs.DecodeOrderEvent(tc.payload, tc.headers); err == nil {
+			t.Errorf("Expected an error for %s", name)
+		}
+	}
+}
+
+func TestEventCodecs_DecodeOrderEventTraceContext(t *testing.T) {
+	var codecs EventCodecs
+	traceContext := TraceContext{
+		TraceParent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		TraceState:  "vendor=value",
+	}
+	plain := []byte(`{"event_type":"order.created","order_id":"order-1"}`)
+	cloudEventHeaders := []MessageHeader{
+		{Key: "ce_specversion", Value: "1.0"},
+		{Key: "ce_id", Value: "evt-1"},
+		{Key: "ce_type", Value: "order.created"},
+		{Key: "ce_source", Value: "/medisupply/orders"},
+	}
+
+	for name, headers := range map[string][]MessageHeader{
+		"plain":       traceContext.Headers(),
+		"binary mode": append(cloudEventHeaders, traceContext.Headers()...),
+	} {
+		event, err := codecs.DecodeOrderEvent(plain, headers)
+		if err != nil {
+			t.Fatalf("Failed to decode %s order event: %v", name, err)
+		}
+		if event.TraceContext != traceContext {
+			t.Errorf("Expected the trace context of the %s message, got %+v", name, event.TraceContext)
+		}
+	}
+
+	event, _ := codecs.DecodeOrderEvent(plain, nil)
+	if !event.TraceContext.IsZero() {
+		t.Errorf("Expected no trace context without trace headers, got %+v", event.TraceContext)
+	}
+}
@@ -0,0 +1,122 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// DeadLetterStatus represents whether a dead letter still needs attention
+type DeadLetterStatus string
+
+const (
+	// DeadLetterStatusParked is a dead letter waiting to be fixed or replayed
+	DeadLetterStatusParked DeadLetterStatus = "parked"
+
+	// DeadLetterStatusReplayed is a dead letter that was replayed successfully
+	DeadLetterStatusReplayed DeadLetterStatus = "replayed"
+)
+
+// MessageHeader is a header of a consumed message
+type MessageHeader struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// DeadLetterReplay records the outcome of replaying a dead letter
+type DeadLetterReplay struct {
+	ReplayedAt time.Time `json:"replayed_at"`
+	Succeeded  bool      `json:"succeeded"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// DeadLetter is a consumed order event message that could not be handled and
+// was parked instead of being retried forever
+type DeadLetter struct {
+	ID         string             `json:"id"`
+	Topic      string             `json:"topic"`
+	Partition  int                `json:"partition"`
+	Offset     int64              `json:"offset"`
+	Key        string             `json:"key"`
+	Payload    []byte             `json:"payload"`
+	Headers    []MessageHeader    `json:"headers"`
+	ErrorClass string             `json:"error_class"`
+	Reason     string             `json:"reason"`
+	Attempts   int                `json:"attempts"`
+	FailedAt   time.Time          `json:"failed_at"`
+	Status     DeadLetterStatus   `json:"status"`
+	EditedAt   *time.Time         `json:"edited_at,omitempty"`
+	Replays    []DeadLetterReplay `json:"replays"`
+}
+
+// DeadLetterID identifies a dead letter by the position of the original message,
+// so parking a redelivered message twice yields the same dead letter
+func DeadLetterID(topic string, partition int, offset int64) string {
+	return fmt.Sprintf("%s-%d-%d", topic, partition, offset)
+}
+
+// EventID returns the event ID of the original message: its event_id header, or its
+// topic/partition/offset like the consumer uses
+func (d *DeadLetter) EventID() string {
+	for _, header := range d.Headers {
+		if header.Key == "event_id" && header.Value != "" {
+			return header.Value
+		}
+	}
+	return fmt.Sprintf("%s/%d/%d", d.Topic, d.Partition, d.Offset)
+}
+
+// EditPayload replaces the payload of a parked dead letter
+func (d *DeadLetter) EditPayload(payload []byte, editedAt time.Time) error {
+	if d.Status != DeadLetterStatusParked {
+		return NewTransitionError("cannot edit dead letter %s in %s status", d.ID, d.Status)
+	}
+	if len(payload) == 0 {
+		return NewValidationError("payload of dead letter %s cannot be empty", d.ID)
+	}
+
+	d.Payload = payload
+	d.EditedAt = &editedAt
+	return nil
+}
+
+// CanReplay reports whether the dead letter may be replayed
+func (d *DeadLetter) CanReplay() error {
+	if d.Status != DeadLetterStatusParked {
+		return NewTransitionError("cannot replay dead letter %s in %s status", d.ID, d.Status)
+	}
+	return nil
+}
+
+// RecordReplay records the outcome of a replay; a successful replay settles the dead letter
+func (d *DeadLetter) RecordReplay(replayedAt time.Time, replayErr error) DeadLetterReplay {
+	replay := DeadLetterReplay{ReplayedAt: replayedAt, Succeeded: replayErr == nil}
+	if replayErr != nil {
+		replay.Error = replayErr.Error()
+	} else {
+		d.Status = DeadLetterStatusReplayed
+	}
+
+	d.Replays = append(d.Replays, replay)
+	return replay
+}
+
+// DeadLetterPublisher defines the contract for parking messages that failed handling
+type DeadLetterPublisher interface {
+	PublishDeadLetter(deadLetter *DeadLetter) error
+}
+
+// DeadLetterStore defines the contract for keeping parked messages so they can be
+// inspected, fixed and replayed
+type DeadLetterStore interface {
+	// Save creates or replaces a dead letter
+	Save(deadLetter *DeadLetter) error
+
+	// FindByID retrieves a dead letter, returning an ErrNotFound error if it does not exist
+	FindByID(id string) (*DeadLetter, error)
+
+	// FindAll retrieves all dead letters, oldest failure first
+	FindAll() ([]*DeadLetter, error)
+
+	// FindByStatus retrieves the dead letters in the given status, oldest failure first
+	FindByStatus(status DeadLetterStatus) ([]*DeadLetter, error)
+}
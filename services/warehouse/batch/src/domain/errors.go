@@ -0,0 +1,74 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNotFound indicates that a batch, item or other entity does not exist
+	ErrNotFound = errors.New("not found")
+
+	// ErrInvalidTransition indicates that the current status does not allow the requested operation
+	ErrInvalidTransition = errors.New("invalid state transition")
+
+	// ErrValidation indicates that the input of an operation is invalid
+	ErrValidation = errors.New("validation failed")
+
+	// ErrConcurrencyConflict indicates that a batch was modified by someone else
+	// after it was loaded, so the change was based on a stale version
+	ErrConcurrencyConflict = errors.New("concurrency conflict")
+
+	// ErrInsufficientStock indicates that a product does not have enough available stock
+	ErrInsufficientStock = errors.New("insufficient stock")
+
+	// ErrAlreadyExists indicates that the entity being created is already registered
+	ErrAlreadyExists = errors.New("already exists")
+)
+
+// DomainError is an error with a human readable message that matches one of
+// the sentinel errors above through errors.Is
+type DomainError struct {
+	Kind    error
+	Message string
+}
+
+// Error returns the human readable message
+func (e *DomainError) Error() string {
+	return e.Message
+}
+
+// Is reports whether the error belongs to the given sentinel kind
+func (e *DomainError) Is(target error) bool {
+	return e.Kind == target
+}
+
+// NewNotFoundError creates an error that matches ErrNotFound
+func NewNotFoundError(format string, args ...interface{}) error {
+	return &DomainError{Kind: ErrNotFound, Message: fmt.Sprintf(format, args...)}
+}
+
+// NewTransitionError creates an error that matches ErrInvalidTransition
+func NewTransitionError(format string, args ...interface{}) error {
+	return &DomainError{Kind: ErrInvalidTransition, Message: fmt.Sprintf(format, args...)}
+}
+
+// NewValidationError creates an error that matches ErrValidation
+func NewValidationError(format string, args ...interface{}) error {
+	return &DomainError{Kind: ErrValidation, Message: fmt.Sprintf(format, args...)}
+}
+
+// NewConflictError creates an error that matches ErrConcurrencyConflict
+func NewConflictError(format string, args ...interface{}) error {
+	return &DomainError{Kind: ErrConcurrencyConflict, Message: fmt.Sprintf(format, args...)}
+}
+
+// NewInsufficientStockError creates an error that matches ErrInsufficientStock
+func NewInsufficientStockError(format string, args ...interface{}) error {
+	return &DomainError{Kind: ErrInsufficientStock, Message: fmt.Sprintf(format, args...)}
+}
+
+// NewAlreadyExistsError creates an error that matches ErrAlreadyExists
+func NewAlreadyExistsError(format string, args ...interface{}) error {
+	return &DomainError{Kind: ErrAlreadyExists, Message: fmt.Sprintf(format, args...)}
+}
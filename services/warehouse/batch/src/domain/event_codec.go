@@ -0,0 +1,94 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+)
+
+const (
+	// EventSchemaVersion is the version of the event schemas (services/schemas) the
+	// service reads and writes. Compatible changes keep the version; a breaking change
+	// needs a new schema package and a new version.
+	EventSchemaVersion = "1"
+
+	// SchemaVersionHeader carries the schema version of an event in the message headers
+	SchemaVersionHeader = "schema_version"
+
+	// JSONContentType is the content type of events encoded as JSON
+	JSONContentType = "application/json"
+
+	// ProtobufContentType is the content type of events encoded as Protobuf
+	ProtobufContentType = "application/x-protobuf"
+)
+
+// EventCodec encodes the events the service publishes and decodes the order events it
+// consumes in one wire format of the event schemas
+type EventCodec interface {
+	// ContentType identifies the wire format in the content-type of a message
+	ContentType() string
+	EncodeBatchEvent(event *BatchEvent) ([]byte, error)
+	EncodeInventoryEvent(event *InventoryEvent) ([]byte, error)
+	DecodeOrderEvent(data []byte) (OrderEvent, error)
+}
+
+// JSONEventCodec encodes events as JSON objects whose fields are named as in the
+// event schemas
+type JSONEventCodec struct{}
+
+// ContentType returns the JSON content type
+func (JSONEventCodec) ContentType() string {
+	return JSONContentType
+}
+
+// EncodeBatchEvent encodes a batch event as JSON
+func (JSONEventCodec) EncodeBatchEvent(event *BatchEvent) ([]byte, error) {
+	return json.Marshal(event)
+}
+
+// EncodeInventoryEvent encodes an inventory event as JSON
+func (JSONEventCodec) EncodeInventoryEvent(event *InventoryEvent) ([]byte, error) {
+	return json.Marshal(event)
+}
+
+// DecodeOrderEvent decodes a JSON order event
+func (JSONEventCodec) DecodeOrderEvent(data []byte) (OrderEvent, error) {
+	var orderEvent OrderEvent
+	err := json.Unmarshal(data, &orderEvent)
+	return orderEvent, err
+}
+
+// EventCodecs are the codecs a service accepts, selected by the content type of a message
+type EventCodecs []EventCodec
+
+// ForContentType returns the codec of a content type, ignoring its parameters. JSON is
+// always accepted and is assumed for messages without content type.
+func (codecs EventCodecs) ForContentType(contentType string) (EventCodec, error) {
+	mediaType := JSONContentType
+	if contentType != "" {
+		parsed, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return nil, fmt.Errorf("invalid content type %q: %w", contentType, err)
+		}
+		mediaType = parsed
+	}
+
+	for _, codec := range codecs {
+		if codec.ContentType() == mediaType {
+			return codec, nil
+		}
+	}
+	if mediaType == JSONContentType {
+		return JSONEventCodec{}, nil
+	}
+	return nil, fmt.Errorf("unsupported content type %q", contentType)
+}
+
+// checkSchemaVersion rejects messages written with another version of the event schemas.
+// Messages without a version header predate it and are read as the current version.
+func checkSchemaVersion(headers []MessageHeader) error {
+	if version := headerValue(headers, SchemaVersionHeader); version != "" && version != EventSchemaVersion {
+		return fmt.Errorf("unsupported event schema version %q (expected %q)", version, EventSchemaVersion)
+	}
+	return nil
+}
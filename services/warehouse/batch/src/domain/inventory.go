@@ -0,0 +1,244 @@
+package domain
+
+import (
+	"time"
+)
+
+// StockMovementType represents the kind of change recorded in the stock ledger
+type StockMovementType string
+
+const (
+	// StockMovementReceipt adds received units to the on-hand quantity
+	StockMovementReceipt StockMovementType = "receipt"
+
+	// StockMovementAdjustment corrects the on-hand quantity after a count
+	StockMovementAdjustment StockMovementType = "adjustment"
+
+	// StockMovementReservation sets units aside for an order
+	StockMovementReservation StockMovementType = "reservation"
+
+	// StockMovementRelease returns the units reserved for an order to the available quantity
+	StockMovementRelease StockMovementType = "release"
+
+	// StockMovementShipment removes the units reserved for a shipped order from the warehouse
+	StockMovementShipment StockMovementType = "shipment"
+)
+
+// StockReservationStatus represents the lifecycle of a stock reservation
+type StockReservationStatus string
+
+const (
+	StockReservationActive   StockReservationStatus = "active"
+	StockReservationReleased StockReservationStatus = "released"
+	StockReservationShipped  StockReservationStatus = "shipped"
+
+	// StockReservationRolledBack is a reservation given back because its order could not
+	// be batched; the order reserves its stock again when its event is retried
+	StockReservationRolledBack StockReservationStatus = "rolled_back"
+
+	// StockReservationWrittenOff is a reservation whose units were written off or
+	// destroyed in quarantine
+	StockReservationWrittenOff StockReservationStatus = "written_off"
+)
+
+// StockLevel holds the stock of a product. Available is the on-hand quantity that is
+// not reserved for orders. Version is used like the batch version to reject changes
+// made on a stale copy.
+type StockLevel struct {
+	ProductID string    `json:"product_id"`
+	OnHand    int       `json:"on_hand"`
+	Reserved  int       `json:"reserved"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Version   int64     `json:"version"`
+}
+
+// StockReservation is the stock set aside for an order
+type StockReservation struct {
+	OrderID   string                 `json:"order_id"`
+	ProductID string                 `json:"product_id"`
+	Quantity  int                    `json:"quantity"`
+	Status    StockReservationStatus `json:"status"`
+	CreatedAt time.Time              `json:"created_at"`
+	UpdatedAt time.Time              `json:"updated_at"`
+}
+
+// StockMovement is an entry of the stock ledger. Quantity is the change to the on-hand
+// quantity for receipts, adjustments and shipments, and the reserved quantity for
+// reservations and releases; the resulting quantities are recorded with it.
+type StockMovement struct {
+	ID            int64             `json:"id"`
+	ProductID     string            `json:"product_id"`
+	OrderID       string            `json:"order_id,omitempty"`
+	Type          StockMovementType `json:"type"`
+	Quantity      int               `json:"quantity"`
+	OnHandAfter   int               `json:"on_hand_after"`
+	ReservedAfter int               `json:"reserved_after"`
+	Reason        string            `json:"reason,omitempty"`
+	OccurredAt    time.Time         `json:"occurred_at"`
+}
+
+// NewStockLevel creates an empty stock level for a product
+func NewStockLevel(productID string) *StockLevel {
+	return &StockLevel{
+		ProductID: productID,
+		UpdatedAt: time.Now(),
+	}
+}
+
+// Available returns the on-hand quantity that is not reserved
+func (s *StockLevel) Available() int {
+	return s.OnHand - s.Reserved
+}
+
+// Receive adds received units to the on-hand quantity
+func (s *StockLevel) Receive(quantity int, reason string, at time.Time) (*StockMovement, error) {
+	if quantity <= 0 {
+		return nil, NewValidationError("received quantity of product %s must be positive, got %d", s.ProductID, quantity)
+	}
+
+	s.OnHand += quantity
+	return s.record(StockMovementReceipt, "", quantity, reason, at), nil
+}
+
+// Adjust corrects the on-hand quantity by the given delta. The on-hand quantity cannot
+// drop below the quantity reserved for orders.
+func (s *StockLevel) Adjust(delta int, reason string, at time.Time) (*StockMovement, error) {
+	if delta == 0 {
+		return nil, NewValidationError("adjustment of product %s cannot be zero", s.ProductID)
+	}
+	if reason == "" {
+		return nil, NewValidationError("adjustment of product %s requires a reason", s.ProductID)
+	}
+	if s.OnHand+delta < s.Reserved {
+		return nil, NewValidationError("cannot adjust product %s by %d: %d on hand, %d reserved",
+			s.ProductID, delta, s.OnHand, s.Reserved)
+	}
+
+	s.OnHand += delta
+	return s.record(StockMovementAdjustment, "", delta, reason, at), nil
+}
+
+// Reserve sets units aside for an order, failing with an ErrInsufficientStock error
+// when fewer units are available
+func (s *StockLevel) Reserve(orderID string, quantity int, at time.Time) (*StockReservation, *StockMovement, error) {
+	if quantity <= 0 {
+		return nil, nil, NewValidationError("reserved quantity of order %s must be positive, got %d", orderID, quantity)
+	}
+	if quantity > s.Available() {
+		return nil, nil, NewInsufficientStockError("cannot reserve %d units of product %s for order %s: %d available",
+			quantity, s.ProductID, orderID, s.Available())
+	}
+
+	s.Reserved += quantity
+	reservation := &StockReservation{
+		OrderID:   orderID,
+		ProductID: s.ProductID,
+		Quantity:  quantity,
+		Status:    StockReservationActive,
+		CreatedAt: at,
+		UpdatedAt: at,
+	}
+	return reservation, s.record(StockMovementReservation, orderID, quantity, "", at), nil
+}
+
+// Release returns the units of an active reservation to the available quantity
+func (s *StockLevel) Release(reservation *StockReservation, at time.Time) (*StockMovement, error) {
+	if err := s.settle(reservation, StockReservationReleased, at); err != nil {
+		return nil, err
+	}
+
+	s.Reserved -= reservation.Quantity
+	return s.record(StockMovementRelease, reservation.OrderID, -reservation.Quantity, "", at), nil
+}
+
+// Ship removes the units of an active reservation from the on-hand quantity
+func (s *StockLevel) Ship(reservation *StockReservation, at time.Time) (*StockMovement, error) {
+	if err := s.settle(reservation, StockReservationShipped, at); err != nil {
+		return nil, err
+	}
+
+	s.Reserved -= reservation.Quantity
+	s.OnHand -= reservation.Quantity
+	return s.record(StockMovementShipment, reservation.OrderID, -reservation.Quantity, "", at), nil
+}
+
+// RollBack returns the units of an active reservation whose order could not be batched
+// to the available quantity
+func (s *StockLevel) RollBack(reservation *StockReservation, reason string, at time.Time) (*StockMovement, error) {
+	if err := s.settle(reservation, StockReservationRolledBack, at); err != nil {
+		return nil, err
+	}
+
+	s.Reserved -= reservation.Quantity
+	return s.record(StockMovementRelease, reservation.OrderID, -reservation.Quantity, reason, at), nil
+}
+
+// WriteOff removes the units of an active reservation that were written off or destroyed
+// from the on-hand quantity, recording the change as an adjustment
+func (s *StockLevel) WriteOff(reservation *StockReservation, reason string, at time.Time) (*StockMovement, error) {
+	if reason == "" {
+		return nil, NewValidationError("write-off of order %s requires a reason", reservation.OrderID)
+	}
+	if err := s.settle(reservation, StockReservationWrittenOff, at); err != nil {
+		return nil, err
+	}
+
+	s.Reserved -= reservation.Quantity
+	s.OnHand -= reservation.Quantity
+	return s.record(StockMovementAdjustment, reservation.OrderID, -reservation.Quantity, reason, at), nil
+}
+
+// settle moves an active reservation of this product to its final status
+func (s *StockLevel) settle(reservation *StockReservation, status StockReservationStatus, at time.Time) error {
+	if reservation.ProductID != s.ProductID {
+		return NewValidationError("reservation of order %s is for product %s, not %s",
+			reservation.OrderID, reservation.ProductID, s.ProductID)
+	}
+	if reservation.Status != StockReservationActive {
+		return NewTransitionError("reservation of order %s is already %s", reservation.OrderID, reservation.Status)
+	}
+
+	reservation.Status = status
+	reservation.UpdatedAt = at
+	return nil
+}
+
+// record stamps the stock level and returns the ledger entry of a change
+func (s *StockLevel) record(movementType StockMovementType, orderID string, quantity int, reason string, at time.Time) *StockMovement {
+	s.UpdatedAt = at
+	return &StockMovement{
+		ProductID:     s.ProductID,
+		OrderID:       orderID,
+		Type:          movementType,
+		Quantity:      quantity,
+		OnHandAfter:   s.OnHand,
+		ReservedAfter: s.Reserved,
+		Reason:        reason,
+		OccurredAt:    at,
+	}
+}
+
+// InventoryRepository defines the contract for stock persistence
+type InventoryRepository interface {
+	// FindStock retrieves the stock level of a product, returning an ErrNotFound error
+	// if the product has never been stocked
+	FindStock(productID string) (*StockLevel, error)
+
+	// GetAllStock retrieves the stock levels of all products
+	GetAllStock() ([]*StockLevel, error)
+
+	// FindReservation retrieves the stock reservation of an order
+	FindReservation(orderID string) (*StockReservation, error)
+
+	// SaveStock stores the stock level together with the reservation it changed (if any),
+	// its ledger entry and the inventory events it caused atomically. The events are
+	// enqueued in the inventory outbox. The stock version must match the stored one (zero
+	// for a new stock level), otherwise an ErrConcurrencyConflict error is returned.
+	SaveStock(stock *StockLevel, reservation *StockReservation, movement *StockMovement, events ...*InventoryEvent) error
+
+	// FindMovements retrieves the stock ledger of a product, oldest movement first
+	FindMovements(productID string) ([]*StockMovement, error)
+
+	// The repository also owns the outbox of inventory events
+	InventoryOutboxStore
+}
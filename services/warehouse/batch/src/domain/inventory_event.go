@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"time"
+)
+
+// InventoryEventType represents the type of inventory event
+type InventoryEventType string
+
+const (
+	InventoryEventAllocationFailed InventoryEventType = "inventory.allocation_failed"
+)
+
+// InventoryEvent represents a domain event about the stock of a product
+type InventoryEvent struct {
+	EventType         InventoryEventType `json:"event_type"`
+	ProductID         string             `json:"product_id"`
+	OrderID           string             `json:"order_id"`
+	RequestedQuantity int                `json:"requested_quantity"`
+	AvailableQuantity int                `json:"available_quantity"`
+	Reason            string             `json:"reason"`
+	Timestamp         time.Time          `json:"timestamp"`
+}
+
+// NewAllocationFailedEvent creates an event reporting that an order could not be
+// allocated because the product does not have enough available stock
+func NewAllocationFailedEvent(orderID, productID string, requested, available int, reason string) *InventoryEvent {
+	return &InventoryEvent{
+		EventType:         InventoryEventAllocationFailed,
+		ProductID:         productID,
+		OrderID:           orderID,
+		RequestedQuantity: requested,
+		AvailableQuantity: available,
+		Reason:            reason,
+		Timestamp:         time.Now().UTC(),
+	}
+}
+
+// InventoryEventPublisher defines the interface for publishing inventory events
+type InventoryEventPublisher interface {
+	PublishInventoryEvent(event *InventoryEvent) error
+}
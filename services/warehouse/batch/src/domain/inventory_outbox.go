@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// InventoryOutboxMessage represents an inventory event stored in the inventory outbox
+// until it has been delivered to the message broker
+type InventoryOutboxMessage struct {
+	ID            int64              `json:"id"`
+	ProductID     string             `json:"product_id"`
+	EventType     InventoryEventType `json:"event_type"`
+	Payload       []byte             `json:"payload"`
+	Attempts      int                `json:"attempts"`
+	LastError     string             `json:"last_error,omitempty"`
+	CreatedAt     time.Time          `json:"created_at"`
+	NextAttemptAt time.Time          `json:"next_attempt_at"`
+	DeadAt        *time.Time         `json:"dead_at,omitempty"`
+}
+
+// NewInventoryOutboxMessage serializes an inventory event into an outbox message
+func NewInventoryOutboxMessage(event *InventoryEvent) (*InventoryOutboxMessage, error) {
+	if event == nil {
+		return nil, fmt.Errorf("event cannot be nil")
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal inventory event: %w", err)
+	}
+
+	now := time.Now().UTC()
+	return &InventoryOutboxMessage{
+		ProductID:     event.ProductID,
+		EventType:     event.EventType,
+		Payload:       payload,
+		CreatedAt:     now,
+		NextAttemptAt: now,
+	}, nil
+}
+
+// Event decodes the inventory event stored in the message
+func (m *InventoryOutboxMessage) Event() (*InventoryEvent, error) {
+	var event InventoryEvent
+	if err := json.Unmarshal(m.Payload, &event); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal inventory outbox message %d: %w", m.ID, err)
+	}
+	return &event, nil
+}
+
+// InventoryOutboxStore defines the contract for draining the inventory outbox
+type InventoryOutboxStore interface {
+	// PendingInventoryOutboxMessages returns up to limit undelivered messages with an ID
+	// greater than afterID, in insertion order. Messages marked dead are not returned.
+	PendingInventoryOutboxMessages(afterID int64, limit int) ([]*InventoryOutboxMessage, error)
+
+	// MarkInventoryOutboxMessagePublished records that a message was delivered
+	MarkInventoryOutboxMessagePublished(id int64) error
+
+	// MarkInventoryOutboxMessageFailed records a failed delivery and schedules the next attempt
+	MarkInventoryOutboxMessageFailed(id int64, reason string, nextAttemptAt time.Time) error
+
+	// MarkInventoryOutboxMessageDead records that a message cannot be delivered and stops retrying it
+	MarkInventoryOutboxMessageDead(id int64, reason string) error
+}
@@ -0,0 +1,92 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStockLevel_ReserveReleaseShip(t *testing.T) {
+	at := time.Date(2024, 12, 1, 12, 0, 0, 0, time.UTC)
+	stock := NewStockLevel("product-1")
+
+	if _, err := stock.Receive(10, "", at); err != nil {
+		t.Fatalf("Failed to receive stock: %v", err)
+	}
+
+	first, movement, err := stock.Reserve("order-1", 6, at)
+	if err != nil {
+		t.Fatalf("Failed to reserve stock: %v", err)
+	}
+	if movement.Type != StockMovementReservation || movement.OnHandAfter != 10 || movement.ReservedAfter != 6 {
+		t.Errorf("Unexpected reservation movement: %+v", movement)
+	}
+
+	if _, _, err := stock.Reserve("order-2", 5, at); !errors.Is(err, ErrInsufficientStock) {
+		t.Fatalf("Expected ErrInsufficientStock with 4 available, got %v", err)
+	}
+	if stock.Reserved != 6 {
+		t.Errorf("Expected a failed reservation to leave 6 reserved, got %d", stock.Reserved)
+	}
+
+	second, _, err := stock.Reserve("order-2", 4, at)
+	if err != nil {
+		t.Fatalf("Failed to reserve the remaining stock: %v", err)
+	}
+	if stock.Available() != 0 {
+		t.Errorf("Expected no available stock, got %d", stock.Available())
+	}
+
+	if _, err := stock.Release(first, at); err != nil {
+		t.Fatalf("Failed to release reservation: %v", err)
+	}
+	if _, err := stock.Ship(second, at); err != nil {
+		t.Fatalf("Failed to ship reservation: %v", err)
+	}
+	if stock.OnHand != 6 || stock.Reserved != 0 || stock.Available() != 6 {
+		t.Errorf("Expected 6 on hand and nothing reserved, got %+v", stock)
+	}
+
+	if _, err := stock.Ship(first, at); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("Expected ErrInvalidTransition shipping a released reservation, got %v", err)
+	}
+
+	third, _, _ := stock.Reserve("order-3", 2, at)
+	movement, err = stock.RollBack(third, "order could not be batched", at)
+	if err != nil {
+		t.Fatalf("Failed to roll back reservation: %v", err)
+	}
+	if third.Status != StockReservationRolledBack || movement.Type != StockMovementRelease || stock.Reserved != 0 {
+		t.Errorf("Expected the rolled back units to be available again, got %+v and %+v", third, stock)
+	}
+}
+
+func TestStockLevel_Adjust(t *testing.T) {
+	at := time.Date(2024, 12, 1, 12, 0, 0, 0, time.UTC)
+	stock := &StockLevel{ProductID: "product-1", OnHand: 10, Reserved: 8}
+
+	tests := []struct {
+		name   string
+		delta  int
+		reason string
+	}{
+		{"zero delta", 0, "count"},
+		{"missing reason", -1, ""},
+		{"below reserved", -3, "count"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := stock.Adjust(tt.delta, tt.reason, at); !errors.Is(err, ErrValidation) {
+				t.Errorf("Expected ErrValidation, got %v", err)
+			}
+		})
+	}
+
+	movement, err := stock.Adjust(-2, "cycle count", at)
+	if err != nil {
+		t.Fatalf("Failed to adjust stock: %v", err)
+	}
+	if stock.OnHand != 8 || movement.Quantity != -2 || movement.Reason != "cycle count" {
+		t.Errorf("Unexpected adjustment: stock %+v, movement %+v", stock, movement)
+	}
+}
@@ -65,6 +65,34 @@ func (m *MockBatchEventPublisher) SetShouldFail(shouldFail bool, err error) {
 	m.FailureError = err
 }
 
+// MockInventoryEventPublisher is a mock implementation of InventoryEventPublisher for testing
+type MockInventoryEventPublisher struct {
+	PublishedEvents []*InventoryEvent
+	ShouldFail      bool
+	FailureError    error
+}
+
+// NewMockInventoryEventPublisher creates a new mock inventory event publisher
+func NewMockInventoryEventPublisher() *MockInventoryEventPublisher {
+	return &MockInventoryEventPublisher{
+		PublishedEvents: make([]*InventoryEvent, 0),
+	}
+}
+
+// PublishInventoryEvent implements the InventoryEventPublisher interface
+func (m *MockInventoryEventPublisher) PublishInventoryEvent(event *InventoryEvent) error {
+	if m.ShouldFail {
+		if m.FailureError != nil {
+			return m.FailureError
+		}
+		return &MockPublishError{Message: "mock publish failure"}
+	}
+
+	m.PublishedEvents = append(m.PublishedEvents, event)
+	log.Printf("Mock: Published inventory event %s for order %s", event.EventType, event.OrderID)
+	return nil
+}
+
 // MockPublishError represents a mock publish error
 type MockPublishError struct {
 	Message string
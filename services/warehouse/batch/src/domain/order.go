@@ -14,12 +14,18 @@ type Order struct {
 	UpdatedAt    time.Time `json:"updated_at"`
 }
 
-// OrderEvent represents an order event from the order-events topic
+// OrderEvent represents an order event from the order-events topic.
+// EventID identifies the event for duplicate detection; the consumer fills it from
+// the message headers or its Kafka position when the payload does not carry one.
 type OrderEvent struct {
+	EventID   string    `json:"event_id,omitempty"`
 	EventType string    `json:"event_type"`
 	OrderID   string    `json:"order_id"`
 	Order     Order     `json:"order"`
 	Timestamp time.Time `json:"timestamp"`
+
+	// TraceContext is the trace of the message the event was consumed from
+	TraceContext TraceContext `json:"-"`
 }
 
 // OrderEventHandler defines the contract for handling order events
@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// OutboxMessage represents a batch event stored in the transactional outbox
+// until it has been delivered to the message broker
+type OutboxMessage struct {
+	ID            int64          `json:"id"`
+	BatchID       string         `json:"batch_id"`
+	EventType     BatchEventType `json:"event_type"`
+	Payload       []byte         `json:"payload"`
+	Attempts      int            `json:"attempts"`
+	LastError     string         `json:"last_error,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+	NextAttemptAt time.Time      `json:"next_attempt_at"`
+	DeadAt        *time.Time     `json:"dead_at,omitempty"`
+}
+
+// NewOutboxMessage serializes a batch event into an outbox message.
+// The batch snapshot is captured at this point, so later changes to the
+// batch do not leak into the stored event.
+func NewOutboxMessage(event *BatchEvent) (*OutboxMessage, error) {
+	if event == nil {
+		return nil, fmt.Errorf("event cannot be nil")
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal batch event: %w", err)
+	}
+
+	now := time.Now().UTC()
+	return &OutboxMessage{
+		BatchID:       event.BatchID,
+		EventType:     event.EventType,
+		Payload:       payload,
+		CreatedAt:     now,
+		NextAttemptAt: now,
+	}, nil
+}
+
+// Event decodes the batch event stored in the message. The message ID is its sequence:
+// outbox IDs grow in insertion order, so the events of a batch get increasing sequences.
+func (m *OutboxMessage) Event() (*BatchEvent, error) {
+	var event BatchEvent
+	if err := json.Unmarshal(m.Payload, &event); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal outbox message %d: %w", m.ID, err)
+	}
+	event.Sequence = m.ID
+	return &event, nil
+}
+
+// OutboxStore defines the contract for draining the transactional outbox
+type OutboxStore interface {
+	// PendingOutboxMessages returns up to limit undelivered messages with an ID greater
+	// than afterID, in insertion order. Messages marked dead are not returned.
+	PendingOutboxMessages(afterID int64, limit int) ([]*OutboxMessage, error)
+
+	// MarkOutboxMessagePublished records that a message was delivered
+	MarkOutboxMessagePublished(id int64) error
+
+	// MarkOutboxMessageFailed records a failed delivery and schedules the next attempt
+	MarkOutboxMessageFailed(id int64, reason string, nextAttemptAt time.Time) error
+
+	// MarkOutboxMessageDead records that a message cannot be delivered and stops retrying it
+	MarkOutboxMessageDead(id int64, reason string) error
+}
@@ -0,0 +1,27 @@
+package domain
+
+import "time"
+
+// ProcessedEventStore remembers which order events have already been applied so
+// that redelivered messages are not handled twice. Events are identified by their
+// event ID, or by their topic/partition/offset when the producer does not set one.
+type ProcessedEventStore interface {
+	// IsProcessed reports whether the event has already been handled
+	IsProcessed(eventID string) (bool, error)
+
+	// ClaimEvent atomically records that a worker started handling the event. It returns
+	// false when the event was already handled or is claimed by another worker; a claim
+	// made before staleBefore is taken over, as its worker is assumed to have died.
+	ClaimEvent(eventID string, claimedAt, staleBefore time.Time) (bool, error)
+
+	// ReleaseEvent forgets the claim of an event that could not be handled, so that a
+	// redelivery handles it again
+	ReleaseEvent(eventID string) error
+
+	// MarkProcessed records that the event has been handled, completing its claim
+	MarkProcessed(eventID string, processedAt time.Time) error
+
+	// PurgeProcessedBefore forgets the events handled before the cutoff and
+	// returns how many were removed
+	PurgeProcessedBefore(cutoff time.Time) (int, error)
+}
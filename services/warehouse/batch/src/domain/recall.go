@@ -0,0 +1,290 @@
+package domain
+
+import (
+	"time"
+)
+
+// RecallStatus represents the progress of a product recall
+type RecallStatus string
+
+const (
+	// RecallStatusOpen is a recall whose affected batches are still being put on hold
+	RecallStatusOpen RecallStatus = "open"
+
+	// RecallStatusContained is a recall whose stock is all on hold, waiting for the
+	// affected orders to be resolved with their customers
+	RecallStatusContained RecallStatus = "contained"
+
+	// RecallStatusClosed is a recall whose affected orders are all resolved
+	RecallStatusClosed RecallStatus = "closed"
+)
+
+// RecallBatchStatus represents what the recall did with an affected batch
+type RecallBatchStatus string
+
+const (
+	// RecallBatchPending is a batch that has not been put on hold yet
+	RecallBatchPending RecallBatchStatus = "pending"
+
+	// RecallBatchHeld is a batch whose stock was put on hold
+	RecallBatchHeld RecallBatchStatus = "held"
+
+	// RecallBatchNotInStock is a completed, cancelled or destroyed batch with no stock
+	// left to hold; its orders still have to be resolved
+	RecallBatchNotInStock RecallBatchStatus = "not_in_stock"
+)
+
+// RecallBatch is a batch affected by a recall
+type RecallBatch struct {
+	BatchID   string            `json:"batch_id"`
+	LotNumber string            `json:"lot_number,omitempty"`
+	Status    RecallBatchStatus `json:"status"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// RecallOrder is an order affected by a recall. ItemStatus is the status of the order
+// in its batch when the recall was initiated, telling whether it already left the warehouse.
+type RecallOrder struct {
+	OrderID    string     `json:"order_id"`
+	CustomerID string     `json:"customer_id,omitempty"`
+	BatchID    string     `json:"batch_id"`
+	LotNumber  string     `json:"lot_number,omitempty"`
+	Quantity   int        `json:"quantity"`
+	ItemStatus ItemStatus `json:"item_status"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+}
+
+// RecallProgress summarizes how far a recall has come
+type RecallProgress struct {
+	BatchesTotal   int `json:"batches_total"`
+	BatchesPending int `json:"batches_pending"`
+	OrdersTotal    int `json:"orders_total"`
+	OrdersResolved int `json:"orders_resolved"`
+}
+
+// RecallCase is the recall of a product, or of a single lot of it, announced by the
+// manufacturer. It lists the affected batches, which are put on hold, and the affected
+// orders, which are resolved one by one with their customers.
+// Version is used like the batch version to reject changes made on a stale copy.
+type RecallCase struct {
+	ID          string        `json:"id"`
+	ProductID   string        `json:"product_id"`
+	LotNumber   string        `json:"lot_number,omitempty"`
+	Reason      string        `json:"reason"`
+	InitiatedBy string        `json:"initiated_by"`
+	Status      RecallStatus  `json:"status"`
+	Batches     []RecallBatch `json:"batches"`
+	Orders      []RecallOrder `json:"orders"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+	ClosedAt    *time.Time    `json:"closed_at,omitempty"`
+	Version     int64         `json:"version"`
+}
+
+// NewRecallCase opens the recall of a product. An empty lot number recalls every lot
+// of the product.
+func NewRecallCase(id, productID, lotNumber, reason, initiatedBy string, at time.Time) (*RecallCase, error) {
+	if productID == "" {
+		return nil, NewValidationError("recall requires a product")
+	}
+	if reason == "" {
+		return nil, NewValidationError("recall of product %s requires a reason", productID)
+	}
+	if initiatedBy == "" {
+		return nil, NewValidationError("recall of product %s requires who initiated it", productID)
+	}
+
+	return &RecallCase{
+		ID:          id,
+		ProductID:   productID,
+		LotNumber:   lotNumber,
+		Reason:      reason,
+		InitiatedBy: initiatedBy,
+		Status:      RecallStatusOpen,
+		Batches:     make([]RecallBatch, 0),
+		Orders:      make([]RecallOrder, 0),
+		CreatedAt:   at,
+		UpdatedAt:   at,
+	}, nil
+}
+
+// AffectedItems returns the items of the batch covered by the recall
+func (r *RecallCase) AffectedItems(batch *Batch) []BatchItem {
+	if batch.ProductID != r.ProductID {
+		return nil
+	}
+
+	var affected []BatchItem
+	for _, item := range batch.Items {
+		if r.LotNumber == "" || item.LotNumber == r.LotNumber || batch.LotNumber == r.LotNumber {
+			affected = append(affected, item)
+		}
+	}
+	return affected
+}
+
+// Covers reports whether the batch holds stock of the recalled product or lot. A lot
+// batch without orders is covered as well, its stock has to be held all the same.
+func (r *RecallCase) Covers(batch *Batch) bool {
+	if batch.ProductID != r.ProductID {
+		return false
+	}
+	if r.LotNumber == "" || batch.LotNumber == r.LotNumber {
+		return true
+	}
+	return len(r.AffectedItems(batch)) > 0
+}
+
+// AddBatch records a covered batch and its affected orders. Adding a batch twice is a no-op.
+func (r *RecallCase) AddBatch(batch *Batch, at time.Time) error {
+	if !r.Covers(batch) {
+		return NewValidationError("batch %s is not covered by recall %s", batch.ID, r.ID)
+	}
+	for _, recalled := range r.Batches {
+		if recalled.BatchID == batch.ID {
+			return nil
+		}
+	}
+
+	r.Batches = append(r.Batches, RecallBatch{
+		BatchID:   batch.ID,
+		LotNumber: batch.LotNumber,
+		Status:    RecallBatchPending,
+		UpdatedAt: at,
+	})
+	for _, item := range r.AffectedItems(batch) {
+		r.Orders = append(r.Orders, RecallOrder{
+			OrderID:    item.OrderID,
+			CustomerID: item.CustomerID,
+			BatchID:    batch.ID,
+			LotNumber:  item.LotNumber,
+			Quantity:   item.Quantity,
+			ItemStatus: item.Status,
+		})
+	}
+	r.UpdatedAt = at
+	return nil
+}
+
+// RecordBatchOutcome records whether the stock of a pending batch was put on hold.
+// The recall is contained once no batch is pending, and closed right away when it
+// affects no orders.
+func (r *RecallCase) RecordBatchOutcome(batchID string, status RecallBatchStatus, at time.Time) error {
+	if status != RecallBatchHeld && status != RecallBatchNotInStock {
+		return NewValidationError("unknown outcome %s for batch %s of recall %s", status, batchID, r.ID)
+	}
+
+	for i, recalled := range r.Batches {
+		if recalled.BatchID != batchID {
+			continue
+		}
+		r.Batches[i].Status = status
+		r.Batches[i].UpdatedAt = at
+		r.UpdatedAt = at
+		r.advance(at)
+		return nil
+	}
+	return NewNotFoundError("batch %s is not part of recall %s", batchID, r.ID)
+}
+
+// ResolveOrder records that an affected order was settled with its customer.
+// Resolving an order twice is a no-op.
+func (r *RecallCase) ResolveOrder(orderID string, at time.Time) error {
+	if r.Status == RecallStatusOpen {
+		return NewTransitionError("cannot resolve order %s: recall %s still has batches to hold", orderID, r.ID)
+	}
+
+	for i, order := range r.Orders {
+		if order.OrderID != orderID {
+			continue
+		}
+		if order.ResolvedAt == nil {
+			r.Orders[i].ResolvedAt = &at
+			r.UpdatedAt = at
+			r.advance(at)
+		}
+		return nil
+	}
+	return NewNotFoundError("order %s is not affected by recall %s", orderID, r.ID)
+}
+
+// PendingBatchIDs returns the batches that still have to be put on hold
+func (r *RecallCase) PendingBatchIDs() []string {
+	var pending []string
+	for _, recalled := range r.Batches {
+		if recalled.Status == RecallBatchPending {
+			pending = append(pending, recalled.BatchID)
+		}
+	}
+	return pending
+}
+
+// CustomerIDs returns the distinct customers of the affected orders, in order of appearance
+func (r *RecallCase) CustomerIDs() []string {
+	seen := make(map[string]bool)
+	customers := make([]string, 0)
+	for _, order := range r.Orders {
+		if order.CustomerID == "" || seen[order.CustomerID] {
+			continue
+		}
+		seen[order.CustomerID] = true
+		customers = append(customers, order.CustomerID)
+	}
+	return customers
+}
+
+// OrdersOfBatch returns the affected orders of a batch
+func (r *RecallCase) OrdersOfBatch(batchID string) []RecallOrder {
+	var orders []RecallOrder
+	for _, order := range r.Orders {
+		if order.BatchID == batchID {
+			orders = append(orders, order)
+		}
+	}
+	return orders
+}
+
+// Progress summarizes the held batches and resolved orders of the recall
+func (r *RecallCase) Progress() RecallProgress {
+	progress := RecallProgress{
+		BatchesTotal: len(r.Batches),
+		OrdersTotal:  len(r.Orders),
+	}
+	for _, recalled := range r.Batches {
+		if recalled.Status == RecallBatchPending {
+			progress.BatchesPending++
+		}
+	}
+	for _, order := range r.Orders {
+		if order.ResolvedAt != nil {
+			progress.OrdersResolved++
+		}
+	}
+	return progress
+}
+
+// advance moves the recall forward once its batches are held and its orders resolved
+func (r *RecallCase) advance(at time.Time) {
+	progress := r.Progress()
+	if r.Status == RecallStatusOpen && progress.BatchesPending == 0 {
+		r.Status = RecallStatusContained
+	}
+	if r.Status == RecallStatusContained && progress.OrdersResolved == progress.OrdersTotal {
+		r.Status = RecallStatusClosed
+		r.ClosedAt = &at
+	}
+}
+
+// RecallRepository defines the contract for recall case persistence
+type RecallRepository interface {
+	// Save stores or updates a recall case. The version must match the stored one (zero
+	// for a new recall), otherwise an ErrConcurrencyConflict error is returned. On success
+	// the version of the given recall is incremented.
+	Save(recall *RecallCase) error
+
+	// FindByID retrieves a recall case by its ID
+	FindByID(id string) (*RecallCase, error)
+
+	// GetAll retrieves all recall cases, oldest first
+	GetAll() ([]*RecallCase, error)
+}
@@ -0,0 +1,150 @@
+package domain
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newRecallTestBatch(t *testing.T, id, lotNumber string, orders map[string]string) *Batch {
+	t.Helper()
+
+	batch := NewBatch(id, "product-1")
+	batch.LotNumber = lotNumber
+	for orderID, customerID := range orders {
+		if err := batch.AddItem(orderID, "product-1", 2, ItemStatusAllocated); err != nil {
+			t.Fatalf("Failed to add item: %v", err)
+		}
+		if err := batch.SetItemCustomer(orderID, customerID); err != nil {
+			t.Fatalf("Failed to set customer: %v", err)
+		}
+	}
+	return batch
+}
+
+func TestNewRecallCase_Validation(t *testing.T) {
+	at := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	if _, err := NewRecallCase("recall-1", "", "LOT-A", "contamination", "qa-lead", at); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected ErrValidation without product, got %v", err)
+	}
+	if _, err := NewRecallCase("recall-1", "product-1", "LOT-A", "", "qa-lead", at); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected ErrValidation without reason, got %v", err)
+	}
+	if _, err := NewRecallCase("recall-1", "product-1", "LOT-A", "contamination", "", at); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected ErrValidation without initiator, got %v", err)
+	}
+}
+
+func TestRecallCase_CoversLotOrWholeProduct(t *testing.T) {
+	at := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	lotA := newRecallTestBatch(t, "batch-a", "LOT-A", map[string]string{"order-1": "customer-1"})
+	lotB := newRecallTestBatch(t, "batch-b", "LOT-B", map[string]string{"order-2": "customer-2"})
+	other := NewBatch("batch-c", "product-2")
+
+	lotRecall, _ := NewRecallCase("recall-1", "product-1", "LOT-A", "contamination", "qa-lead", at)
+	if !lotRecall.Covers(lotA) || lotRecall.Covers(lotB) || lotRecall.Covers(other) {
+		t.Error("Expected a lot recall to cover only the batches of that lot")
+	}
+
+	productRecall, _ := NewRecallCase("recall-2", "product-1", "", "label error", "qa-lead", at)
+	if !productRecall.Covers(lotA) || !productRecall.Covers(lotB) || productRecall.Covers(other) {
+		t.Error("Expected a product recall to cover every batch of the product")
+	}
+
+	if err := lotRecall.AddBatch(lotB, at); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected ErrValidation adding an uncovered batch, got %v", err)
+	}
+}
+
+func TestRecallCase_Progress(t *testing.T) {
+	at := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	held := newRecallTestBatch(t, "batch-a", "LOT-A", map[string]string{"order-1": "customer-1", "order-2": "customer-1"})
+	shipped := newRecallTestBatch(t, "batch-b", "LOT-A", map[string]string{"order-3": "customer-2"})
+
+	recall, err := NewRecallCase("recall-1", "product-1", "LOT-A", "contamination", "qa-lead", at)
+	if err != nil {
+		t.Fatalf("Failed to create recall: %v", err)
+	}
+	for _, batch := range []*Batch{held, shipped, held} {
+		if err := recall.AddBatch(batch, at); err != nil {
+			t.Fatalf("Failed to add batch: %v", err)
+		}
+	}
+
+	if len(recall.Batches) != 2 || len(recall.Orders) != 3 {
+		t.Fatalf("Expected 2 batches and 3 orders, got %d and %d", len(recall.Batches), len(recall.Orders))
+	}
+	if customers := recall.CustomerIDs(); len(customers) != 2 {
+		t.Errorf("Expected 2 distinct customers, got %v", customers)
+	}
+	if !reflect.DeepEqual(recall.PendingBatchIDs(), []string{"batch-a", "batch-b"}) {
+		t.Errorf("Expected both batches pending, got %v", recall.PendingBatchIDs())
+	}
+
+	if err := recall.ResolveOrder("order-1", at); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("Expected ErrInvalidTransition resolving an order before the stock is held, got %v", err)
+	}
+
+	recall.RecordBatchOutcome("batch-a", RecallBatchHeld, at)
+	if recall.Status != RecallStatusOpen {
+		t.Errorf("Expected recall to stay open with a pending batch, got %s", recall.Status)
+	}
+	recall.RecordBatchOutcome("batch-b", RecallBatchNotInStock, at)
+	if recall.Status != RecallStatusContained {
+		t.Errorf("Expected recall to be contained, got %s", recall.Status)
+	}
+
+	if err := recall.ResolveOrder("order-9", at); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for an unaffected order, got %v", err)
+	}
+	for _, orderID := range []string{"order-1", "order-2", "order-2"} {
+		if err := recall.ResolveOrder(orderID, at); err != nil {
+			t.Fatalf("Failed to resolve order %s: %v", orderID, err)
+		}
+	}
+
+	progress := recall.Progress()
+	expected := RecallProgress{BatchesTotal: 2, BatchesPending: 0, OrdersTotal: 3, OrdersResolved: 2}
+	if progress != expected {
+		t.Errorf("Expected progress %+v, got %+v", expected, progress)
+	}
+
+	recall.ResolveOrder("order-3", at)
+	if recall.Status != RecallStatusClosed || recall.ClosedAt == nil {
+		t.Errorf("Expected recall to be closed once every order is resolved, got %s", recall.Status)
+	}
+}
+
+func TestBatch_PlaceOnHold(t *testing.T) {
+	at := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	batch := newRecallTestBatch(t, "batch-a", "LOT-A", map[string]string{"order-1": "customer-1"})
+
+	if err := batch.PlaceOnHold("", at); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected ErrValidation without a reason, got %v", err)
+	}
+	if err := batch.PlaceOnHold("recall of lot LOT-A", at); err != nil {
+		t.Fatalf("Failed to put batch on hold: %v", err)
+	}
+	if batch.Status != BatchStatusOnHold || batch.HoldReason != "recall of lot LOT-A" {
+		t.Errorf("Expected batch on hold with its reason, got %s %q", batch.Status, batch.HoldReason)
+	}
+
+	// Stock on hold is not allocated, processed or shipped
+	if err := batch.AddItem("order-2", "product-1", 1, ItemStatusAllocated); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("Expected ErrInvalidTransition allocating from a batch on hold, got %v", err)
+	}
+	if err := batch.StartProcessing(); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("Expected ErrInvalidTransition processing a batch on hold, got %v", err)
+	}
+	if err := batch.UpdateItemStatus("order-1", ItemStatusShipped); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("Expected ErrInvalidTransition shipping an order on hold, got %v", err)
+	}
+
+	completed := NewBatch("batch-b", "product-1")
+	completed.Status = BatchStatusCompleted
+	if err := completed.PlaceOnHold("recall", at); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("Expected ErrInvalidTransition holding a completed batch, got %v", err)
+	}
+}
@@ -0,0 +1,206 @@
+package domain
+
+import (
+	"time"
+)
+
+// ReturnCondition represents the state in which returned goods arrived at the warehouse
+type ReturnCondition string
+
+const (
+	// ReturnConditionUninspected is a return whose goods have not been inspected yet
+	ReturnConditionUninspected ReturnCondition = "uninspected"
+
+	// ReturnConditionSealed is a return whose packaging is intact
+	ReturnConditionSealed ReturnCondition = "sealed"
+
+	// ReturnConditionOpened is a return whose packaging was opened but whose goods are intact
+	ReturnConditionOpened ReturnCondition = "opened"
+
+	// ReturnConditionDamaged is a return whose goods are damaged
+	ReturnConditionDamaged ReturnCondition = "damaged"
+)
+
+// ReturnDisposition represents what the warehouse does with returned goods
+type ReturnDisposition string
+
+const (
+	// ReturnDispositionRestock puts the returned goods back into stock
+	ReturnDispositionRestock ReturnDisposition = "restock"
+
+	// ReturnDispositionQuarantine keeps the returned goods apart until a final decision
+	// to restock or destroy them
+	ReturnDispositionQuarantine ReturnDisposition = "quarantine"
+
+	// ReturnDispositionDestroy destroys the returned goods
+	ReturnDispositionDestroy ReturnDisposition = "destroy"
+)
+
+// ReturnStatus represents the progress of a return
+type ReturnStatus string
+
+const (
+	// ReturnStatusOpen is a return waiting for a disposition
+	ReturnStatusOpen ReturnStatus = "open"
+
+	// ReturnStatusQuarantined is a return whose goods are kept in quarantine
+	ReturnStatusQuarantined ReturnStatus = "quarantined"
+
+	// ReturnStatusResolved is a return whose goods were restocked or destroyed
+	ReturnStatusResolved ReturnStatus = "resolved"
+)
+
+// ValidateReturnCondition returns an ErrValidation error if the condition is unknown
+func ValidateReturnCondition(condition ReturnCondition) error {
+	switch condition {
+	case ReturnConditionUninspected, ReturnConditionSealed, ReturnConditionOpened, ReturnConditionDamaged:
+		return nil
+	}
+	return NewValidationError("unknown return condition: %s", condition)
+}
+
+// ValidateReturnDisposition returns an ErrValidation error if the disposition is unknown
+func ValidateReturnDisposition(disposition ReturnDisposition) error {
+	switch disposition {
+	case ReturnDispositionRestock, ReturnDispositionQuarantine, ReturnDispositionDestroy:
+		return nil
+	}
+	return NewValidationError("unknown return disposition: %s", disposition)
+}
+
+// ReturnAuthorization is a return merchandise authorization (RMA): the goods of an
+// order sent back by the customer. It is linked to the batch item the order was
+// fulfilled from and records why the goods came back, in which condition they arrived
+// and what the warehouse decided to do with them.
+// Version is used like the batch version to reject changes made on a stale copy.
+type ReturnAuthorization struct {
+	ID          string            `json:"id"`
+	OrderID     string            `json:"order_id"`
+	CustomerID  string            `json:"customer_id,omitempty"`
+	ProductID   string            `json:"product_id"`
+	BatchID     string            `json:"batch_id"`
+	LotNumber   string            `json:"lot_number,omitempty"`
+	Quantity    int               `json:"quantity"`
+	Reason      string            `json:"reason"`
+	Condition   ReturnCondition   `json:"condition"`
+	Status      ReturnStatus      `json:"status"`
+	Disposition ReturnDisposition `json:"disposition,omitempty"`
+	DecidedBy   string            `json:"decided_by,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+	ResolvedAt  *time.Time        `json:"resolved_at,omitempty"`
+	Version     int64             `json:"version"`
+}
+
+// NewReturnAuthorization opens the return of an order from the batch it was fulfilled
+// from. A zero quantity returns the whole item and an empty condition records the goods
+// as uninspected.
+func NewReturnAuthorization(id string, batch *Batch, orderID string, quantity int, reason string, condition ReturnCondition, at time.Time) (*ReturnAuthorization, error) {
+	item, err := batch.GetItemByOrderID(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if !item.Status.CanTransitionTo(ItemStatusReturned) {
+		return nil, NewTransitionError("cannot return order %s in status %s", orderID, item.Status)
+	}
+	if quantity == 0 {
+		quantity = item.Quantity
+	}
+	if quantity < 0 || quantity > item.Quantity {
+		return nil, NewValidationError("return of order %s must be between 1 and %d units, got %d", orderID, item.Quantity, quantity)
+	}
+	if reason == "" {
+		return nil, NewValidationError("return of order %s requires a reason", orderID)
+	}
+	if condition == "" {
+		condition = ReturnConditionUninspected
+	}
+	if err := ValidateReturnCondition(condition); err != nil {
+		return nil, err
+	}
+
+	lotNumber := item.LotNumber
+	if lotNumber == "" {
+		lotNumber = batch.LotNumber
+	}
+
+	return &ReturnAuthorization{
+		ID:         id,
+		OrderID:    orderID,
+		CustomerID: item.CustomerID,
+		ProductID:  item.ProductID,
+		BatchID:    batch.ID,
+		LotNumber:  lotNumber,
+		Quantity:   quantity,
+		Reason:     reason,
+		Condition:  condition,
+		Status:     ReturnStatusOpen,
+		CreatedAt:  at,
+		UpdatedAt:  at,
+	}, nil
+}
+
+// Resolve records the disposition of the returned goods. Goods in quarantine can only be
+// restocked or destroyed afterwards, and only sealed or opened goods can be restocked.
+// An empty condition keeps the condition recorded so far.
+func (r *ReturnAuthorization) Resolve(disposition ReturnDisposition, condition ReturnCondition, decidedBy string, at time.Time) error {
+	if err := ValidateReturnDisposition(disposition); err != nil {
+		return err
+	}
+	if decidedBy == "" {
+		return NewValidationError("disposition of return %s requires who decided it", r.ID)
+	}
+	if condition == "" {
+		condition = r.Condition
+	}
+	if err := ValidateReturnCondition(condition); err != nil {
+		return err
+	}
+
+	switch r.Status {
+	case ReturnStatusOpen:
+	case ReturnStatusQuarantined:
+		if disposition == ReturnDispositionQuarantine {
+			return NewTransitionError("return %s is already in quarantine", r.ID)
+		}
+	default:
+		return NewTransitionError("return %s is already %s", r.ID, r.Status)
+	}
+
+	if disposition == ReturnDispositionRestock && condition != ReturnConditionSealed && condition != ReturnConditionOpened {
+		return NewValidationError("return %s cannot be restocked in condition %s", r.ID, condition)
+	}
+
+	r.Disposition = disposition
+	r.Condition = condition
+	r.DecidedBy = decidedBy
+	r.UpdatedAt = at
+	if disposition == ReturnDispositionQuarantine {
+		r.Status = ReturnStatusQuarantined
+		return nil
+	}
+	r.Status = ReturnStatusResolved
+	r.ResolvedAt = &at
+	return nil
+}
+
+// ReturnRepository defines the contract for return authorization persistence
+type ReturnRepository interface {
+	// Save stores or updates a return. The version must match the stored one (zero for a
+	// new return), otherwise an ErrConcurrencyConflict error is returned. An order has at
+	// most one return; saving a second one fails with an ErrAlreadyExists error. On
+	// success the version of the given return is incremented.
+	Save(rma *ReturnAuthorization) error
+
+	// FindByID retrieves a return by its ID
+	FindByID(id string) (*ReturnAuthorization, error)
+
+	// FindByOrderID retrieves the return of an order
+	FindByOrderID(orderID string) (*ReturnAuthorization, error)
+
+	// FindByBatchID retrieves the returns of the items of a batch, oldest first
+	FindByBatchID(batchID string) ([]*ReturnAuthorization, error)
+
+	// GetAll retrieves all returns, oldest first
+	GetAll() ([]*ReturnAuthorization, error)
+}
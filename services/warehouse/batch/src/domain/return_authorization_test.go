@@ -0,0 +1,93 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func newReturnTestBatch(t *testing.T, status ItemStatus) *Batch {
+	t.Helper()
+
+	batch := NewBatch("batch-a", "product-1")
+	batch.LotNumber = "LOT-A"
+	if err := batch.AddItem("order-1", "product-1", 4, status); err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+	if err := batch.SetItemCustomer("order-1", "customer-1"); err != nil {
+		t.Fatalf("Failed to set customer: %v", err)
+	}
+	return batch
+}
+
+func TestNewReturnAuthorization(t *testing.T) {
+	at := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	batch := newReturnTestBatch(t, ItemStatusDelivered)
+
+	if _, err := NewReturnAuthorization("rma-1", batch, "order-9", 0, "wrong item", "", at); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for an order outside the batch, got %v", err)
+	}
+	if _, err := NewReturnAuthorization("rma-1", batch, "order-1", 5, "wrong item", "", at); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected ErrValidation returning more than was shipped, got %v", err)
+	}
+	if _, err := NewReturnAuthorization("rma-1", batch, "order-1", 0, "", "", at); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected ErrValidation without a reason, got %v", err)
+	}
+	if _, err := NewReturnAuthorization("rma-1", batch, "order-1", 0, "wrong item", "crushed", at); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected ErrValidation for an unknown condition, got %v", err)
+	}
+
+	allocated := newReturnTestBatch(t, ItemStatusAllocated)
+	if _, err := NewReturnAuthorization("rma-1", allocated, "order-1", 0, "wrong item", "", at); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("Expected ErrInvalidTransition returning an order that was not shipped, got %v", err)
+	}
+
+	rma, err := NewReturnAuthorization("rma-1", batch, "order-1", 0, "wrong item", "", at)
+	if err != nil {
+		t.Fatalf("Failed to open return: %v", err)
+	}
+	if rma.Quantity != 4 || rma.Condition != ReturnConditionUninspected || rma.Status != ReturnStatusOpen {
+		t.Errorf("Expected an open return of the whole uninspected item, got %+v", rma)
+	}
+	if rma.BatchID != "batch-a" || rma.LotNumber != "LOT-A" || rma.CustomerID != "customer-1" {
+		t.Errorf("Expected the return to be linked to the batch item, got %+v", rma)
+	}
+}
+
+func TestReturnAuthorization_Resolve(t *testing.T) {
+	at := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	batch := newReturnTestBatch(t, ItemStatusShipped)
+
+	rma, _ := NewReturnAuthorization("rma-1", batch, "order-1", 2, "wrong item", ReturnConditionDamaged, at)
+	if err := rma.Resolve("donate", "", "qa-lead", at); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected ErrValidation for an unknown disposition, got %v", err)
+	}
+	if err := rma.Resolve(ReturnDispositionRestock, "", "", at); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected ErrValidation without who decided, got %v", err)
+	}
+	if err := rma.Resolve(ReturnDispositionRestock, "", "qa-lead", at); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected ErrValidation restocking damaged goods, got %v", err)
+	}
+
+	if err := rma.Resolve(ReturnDispositionQuarantine, "", "qa-lead", at); err != nil {
+		t.Fatalf("Failed to quarantine return: %v", err)
+	}
+	if rma.Status != ReturnStatusQuarantined || rma.ResolvedAt != nil {
+		t.Errorf("Expected return in quarantine, got %s", rma.Status)
+	}
+	if err := rma.Resolve(ReturnDispositionQuarantine, "", "qa-lead", at); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("Expected ErrInvalidTransition quarantining twice, got %v", err)
+	}
+
+	// Inspection in quarantine finds the goods intact
+	resolvedAt := at.Add(time.Hour)
+	if err := rma.Resolve(ReturnDispositionRestock, ReturnConditionOpened, "qa-lead", resolvedAt); err != nil {
+		t.Fatalf("Failed to restock return: %v", err)
+	}
+	if rma.Status != ReturnStatusResolved || rma.Condition != ReturnConditionOpened || rma.ResolvedAt == nil || !rma.ResolvedAt.Equal(resolvedAt) {
+		t.Errorf("Expected resolved return of opened goods, got %+v", rma)
+	}
+	if err := rma.Resolve(ReturnDispositionDestroy, "", "qa-lead", at); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("Expected ErrInvalidTransition resolving twice, got %v", err)
+	}
+}
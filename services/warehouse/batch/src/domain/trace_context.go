@@ -0,0 +1,57 @@
+package domain
+
+// W3C Trace Context headers that carry the trace of a message across services
+const (
+	TraceParentHeader = "traceparent"
+	TraceStateHeader  = "tracestate"
+)
+
+// TraceContext is the W3C trace context of the operation that caused a message or a
+// command, so its trace continues wherever the message is handled or the events of the
+// command are published. A zero trace context starts a new trace.
+type TraceContext struct {
+	TraceParent string `json:"traceparent,omitempty"`
+	TraceState  string `json:"tracestate,omitempty"`
+}
+
+// IsZero reports whether the trace context carries no trace
+func (tc TraceContext) IsZero() bool {
+	return tc.TraceParent == ""
+}
+
+// Headers returns the trace context as message headers, or none for a zero trace context
+func (tc TraceContext) Headers() []MessageHeader {
+	if tc.IsZero() {
+		return nil
+	}
+	headers := []MessageHeader{{Key: TraceParentHeader, Value: tc.TraceParent}}
+	if tc.TraceState != "" {
+		headers = append(headers, MessageHeader{Key: TraceStateHeader, Value: tc.TraceState})
+	}
+	return headers
+}
+
+// Tracer records the spans of the application services; it is implemented by the
+// tracing infrastructure
+type Tracer interface {
+	// StartSpan starts a span as a child of the span of the parent trace context and
+	// returns the trace context of the new span and the function that ends it with the
+	// outcome of the operation
+	StartSpan(parent TraceContext, name string, attributes map[string]string) (TraceContext, func(err error))
+}
+
+// NoopTracer records no spans; the trace context of the parent is passed on unchanged
+type NoopTracer struct{}
+
+// StartSpan returns the parent trace context
+func (NoopTracer) StartSpan(parent TraceContext, name string, attributes map[string]string) (TraceContext, func(err error)) {
+	return parent, func(error) {}
+}
+
+// traceContextFromHeaders reads the trace context of a message from its headers
+func traceContextFromHeaders(headers []MessageHeader) TraceContext {
+	return TraceContext{
+		TraceParent: headerValue(headers, TraceParentHeader),
+		TraceState:  headerValue(headers, TraceStateHeader),
+	}
+}
@@ -0,0 +1,72 @@
+package drivenadapters
+
+import (
+	"time"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+)
+
+// DefaultBatchHistorySnapshotInterval is how many changes of a batch are recorded in its
+// history, at most, for each snapshot of the batch
+const DefaultBatchHistorySnapshotInterval = 20
+
+// BatchRepositoryOption configures optional batch repository behaviour
+type BatchRepositoryOption func(*batchRepositoryOptions)
+
+// batchRepositoryOptions holds the optional behaviour shared by the batch repositories
+type batchRepositoryOptions struct {
+	// changeHistory records the change of every command in the history of its batch
+	changeHistory    bool
+	snapshotInterval int64
+}
+
+// WithChangeHistory keeps the history of changes of every batch so FindByIDAt can rebuild
+// a batch as it was at a past time. Each save records the difference between the stored
+// and the new state of the batch within the transaction that stores it, and a snapshot
+// is taken every snapshotInterval changes so a rebuild replays at most that many. The
+// stored batches remain the source of truth of their current state; the history is
+// derived from them. Batches stored before the history was enabled start their history
+// with a snapshot of their state when they next change.
+func WithChangeHistory(snapshotInterval int) BatchRepositoryOption {
+	return func(o *batchRepositoryOptions) {
+		if snapshotInterval < 1 {
+			snapshotInterval = DefaultBatchHistorySnapshotInterval
+		}
+		o.changeHistory = true
+		o.snapshotInterval = int64(snapshotInterval)
+	}
+}
+
+// newBatchRepositoryOptions applies the options to the defaults
+func newBatchRepositoryOptions(opts []BatchRepositoryOption) batchRepositoryOptions {
+	options := batchRepositoryOptions{snapshotInterval: DefaultBatchHistorySnapshotInterval}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// snapshotDue reports whether a snapshot of the batch is taken after the change
+func (o batchRepositoryOptions) snapshotDue(change domain.BatchChange) bool {
+	return !change.Deleted && change.Version%o.snapshotInterval == 0
+}
+
+// recordedBy reports whether something recorded at the given time is part of the state
+// as of at; a zero at stands for the latest state
+func recordedBy(at, recordedAt time.Time) bool {
+	return at.IsZero() || !recordedAt.After(at)
+}
+
+// newBatchNotFoundAtError reports that a batch does not exist, or did not exist at the
+// given time
+func newBatchNotFoundAtError(id string, at time.Time) error {
+	if at.IsZero() {
+		return domain.NewNotFoundError("batch with ID %s not found", id)
+	}
+	return domain.NewNotFoundError("batch with ID %s did not exist at %s", id, at.UTC().Format(time.RFC3339))
+}
+
+// newChangeHistoryDisabledError reports an as-of query on a repository that keeps no history
+func newChangeHistoryDisabledError() error {
+	return domain.NewValidationError("as-of queries need the batch change history (BATCH_CHANGE_HISTORY=true)")
+}
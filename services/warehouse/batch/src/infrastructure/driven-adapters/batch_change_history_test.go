@@ -0,0 +1,145 @@
+package drivenadapters
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+)
+
+// historyBatchRepository is a batch repository that answers as-of queries
+type historyBatchRepository interface {
+	domain.BatchRepository
+	domain.BatchChangeHistory
+}
+
+func TestBatchRepositories_ChangeHistory(t *testing.T) {
+	sqlRepo, err := NewBatchSQLRepository(newTestSQLDB(t), SQLDriverSQLite, WithChangeHistory(3))
+	if err != nil {
+		t.Fatalf("Failed to create SQL repository: %v", err)
+	}
+
+	repos := map[string]historyBatchRepository{
+		"memory": NewBatchMemoryRepository(WithChangeHistory(3)),
+		"sql":    sqlRepo,
+	}
+
+	for name, repo := range repos {
+		t.Run(name, func(t *testing.T) {
+			beforeCreation := time.Now().UTC()
+			time.Sleep(time.Millisecond)
+
+			batch := domain.NewBatch("batch-1", "product-1")
+			save := func(eventType domain.BatchEventType, change func() error) time.Time {
+				t.Helper()
+				if err := change(); err != nil {
+					t.Fatalf("Failed to change batch: %v", err)
+				}
+				event := &domain.BatchEvent{EventType: eventType, BatchID: batch.ID, Batch: batch}
+				if err := repo.SaveWithEvents(batch, domain.BatchCommand{}, event); err != nil {
+					t.Fatalf("Failed to save batch: %v", err)
+				}
+				// Separate the changes in time so that each one can be queried
+				time.Sleep(time.Millisecond)
+				return time.Now().UTC()
+			}
+			noChange := func() error { return nil }
+
+			save(domain.BatchEventCreated, noChange)
+			afterFirstItem := save(domain.BatchEventItemAdded, func() error {
+				return batch.AddItem("order-1", "product-1", 2, domain.ItemStatusAllocated)
+			})
+			save(domain.BatchEventItemAdded, func() error {
+				return batch.AddItem("order-2", "product-1", 1, domain.ItemStatusAllocated)
+			})
+			save(domain.BatchEventItemUpdated, func() error {
+				return batch.UpdateItemStatus("order-1", domain.ItemStatusShipped)
+			})
+			afterProcessing := save(domain.BatchEventProcessing, batch.StartProcessing)
+
+			found, err := repo.FindByID("batch-1")
+			if err != nil {
+				t.Fatalf("Failed to find batch: %v", err)
+			}
+
+			// The latest state is rebuilt from the snapshot at version 3 and two changes
+			latest, err := repo.FindByIDAt("batch-1", afterProcessing)
+			if err != nil {
+				t.Fatalf("Failed to find batch as of %s: %v", afterProcessing, err)
+			}
+			if latest.Version != 5 || latest.Status != domain.BatchStatusProcessing || len(latest.Items) != 2 ||
+				latest.Items[0].Status != domain.ItemStatusShipped || latest.Version != found.Version {
+				t.Errorf("Expected version 5 processing with order-1 shipped, got %+v", latest)
+			}
+
+			asOf, err := repo.FindByIDAt("batch-1", afterFirstItem)
+			if err != nil {
+				t.Fatalf("Failed to find batch as of %s: %v", afterFirstItem, err)
+			}
+			if asOf.Version != 2 || asOf.Status != domain.BatchStatusPending || len(asOf.Items) != 1 ||
+				asOf.Items[0].Status != domain.ItemStatusAllocated {
+				t.Errorf("Expected version 2 with order-1 allocated, got %+v", asOf)
+			}
+			if _, err := repo.FindByIDAt("batch-1", beforeCreation); !errors.Is(err, domain.ErrNotFound) {
+				t.Errorf("Expected ErrNotFound before the batch was created, got %v", err)
+			}
+
+			// A deleted batch is gone, but its past states remain
+			if err := repo.DeleteWithEvents(found, domain.BatchCommand{}); err != nil {
+				t.Fatalf("Failed to delete batch: %v", err)
+			}
+			if _, err := repo.FindByID("batch-1"); !errors.Is(err, domain.ErrNotFound) {
+				t.Errorf("Expected ErrNotFound for the deleted batch, got %v", err)
+			}
+			if asOf, err := repo.FindByIDAt("batch-1", afterProcessing); err != nil || asOf.Version != 5 {
+				t.Errorf("Expected version 5 before the deletion, got %+v (%v)", asOf, err)
+			}
+		})
+	}
+}
+
+func TestBatchSQLRepository_ChangeHistoryStartsFromStoredBatches(t *testing.T) {
+	db := newTestSQLDB(t)
+	plain, err := NewBatchSQLRepository(db, SQLDriverSQLite)
+	if err != nil {
+		t.Fatalf("Failed to create SQL repository: %v", err)
+	}
+
+	batch := domain.NewBatch("batch-1", "product-1")
+	if err := batch.AddItem("order-1", "product-1", 2, domain.ItemStatusAllocated); err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+	if err := plain.Save(batch); err != nil {
+		t.Fatalf("Failed to save batch: %v", err)
+	}
+	if _, err := plain.FindByIDAt("batch-1", time.Now()); !errors.Is(err, domain.ErrValidation) {
+		t.Errorf("Expected ErrValidation without the change history, got %v", err)
+	}
+
+	repo, err := NewBatchSQLRepository(db, SQLDriverSQLite, WithChangeHistory(10))
+	if err != nil {
+		t.Fatalf("Failed to create SQL repository with change history: %v", err)
+	}
+	if found, err := repo.FindByID("batch-1"); err != nil || len(found.Items) != 1 {
+		t.Fatalf("Expected the stored batch without a history, got %+v (%v)", found, err)
+	}
+
+	// The first change starts the history with a snapshot of the stored batch
+	time.Sleep(time.Millisecond)
+	beforeChange := time.Now().UTC()
+	time.Sleep(time.Millisecond)
+	if err := batch.AddItem("order-2", "product-1", 1, domain.ItemStatusAllocated); err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+	if err := repo.Save(batch); err != nil {
+		t.Fatalf("Failed to save batch: %v", err)
+	}
+
+	if found, err := repo.FindByID("batch-1"); err != nil || found.Version != 2 || len(found.Items) != 2 {
+		t.Errorf("Expected version 2 with two items, got %+v (%v)", found, err)
+	}
+	if asOf, err := repo.FindByIDAt("batch-1", beforeChange); err != nil || asOf.Version != 1 || len(asOf.Items) != 1 {
+		t.Errorf("Expected version 1 with one item before the change, got %+v (%v)", asOf, err)
+	}
+}
@@ -0,0 +1,352 @@
+package drivenadapters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+	"github.com/segmentio/kafka-go"
+)
+
+// BatchPublishMode selects how batch events are written to Kafka
+type BatchPublishMode string
+
+const (
+	// BatchPublishSync writes every batch event on its own and waits for the broker
+	BatchPublishSync BatchPublishMode = "sync"
+
+	// BatchPublishAsync buffers batch events in memory and writes them in batches in the
+	// background, reporting the outcome of every event through a callback
+	BatchPublishAsync BatchPublishMode = "async"
+)
+
+// ParseBatchPublishMode converts a configured publish mode into a BatchPublishMode
+func ParseBatchPublishMode(value string) (BatchPublishMode, error) {
+	switch mode := BatchPublishMode(value); mode {
+	case BatchPublishSync, BatchPublishAsync:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown batch publish mode %q (expected %q or %q)", value, BatchPublishSync, BatchPublishAsync)
+}
+
+// ErrPublisherClosed is reported for batch events published after the publisher was closed
+var ErrPublisherClosed = errors.New("batch event publisher is closed")
+
+// asyncWriterBatchTimeout is how long the Kafka writer waits for a partition batch to
+// fill up; the adapter already buffered the events, so the writer sends them right away
+const asyncWriterBatchTimeout = time.Millisecond
+
+// AsyncPublishConfig holds how the asynchronous publisher buffers batch events
+type AsyncPublishConfig struct {
+	// BatchSize is how many buffered events are written to Kafka at once
+	BatchSize int
+	// BatchTimeout is how long the first buffered event waits for more before writing
+	BatchTimeout time.Duration
+	// QueueSize is how many events may be buffered before publishing blocks
+	QueueSize int
+}
+
+// DefaultAsyncPublishConfig returns the default buffering of the asynchronous publisher
+func DefaultAsyncPublishConfig() AsyncPublishConfig {
+	return AsyncPublishConfig{
+		BatchSize:    100,
+		BatchTimeout: 50 * time.Millisecond,
+		QueueSize:    1000,
+	}
+}
+
+// withDefaults replaces the unset settings with the default ones
+func (config AsyncPublishConfig) withDefaults() AsyncPublishConfig {
+	defaults := DefaultAsyncPublishConfig()
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaults.BatchSize
+	}
+	if config.BatchTimeout <= 0 {
+		config.BatchTimeout = defaults.BatchTimeout
+	}
+	if config.QueueSize < config.BatchSize {
+		config.QueueSize = config.BatchSize
+	}
+	return config
+}
+
+// kafkaMessageWriter is the part of the Kafka writer the asynchronous publisher uses; it
+// is implemented by kafka.Writer
+type kafkaMessageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// queuedBatchEvent is a batch event waiting to be written and who to report its outcome to
+type queuedBatchEvent struct {
+	event    *domain.BatchEvent
+	message  kafka.Message
+	callback domain.BatchEventDeliveryCallback
+}
+
+// AsyncBatchEventPublisherAdapter implements the AsyncBatchEventPublisher interface using
+// Kafka. Events are encoded as by BatchEventPublisherAdapter, buffered in a bounded queue
+// and written in the background once BatchSize events are buffered or the first one
+// waited BatchTimeout. Publishing blocks while the queue is full.
+//
+// Events of a batch are written in the order they were queued. Once one fails, the
+// events of its batch queued after it fail without being written until the queue has
+// drained, so they cannot overtake it when it is published again.
+type AsyncBatchEventPublisherAdapter struct {
+	publisher *BatchEventPublisherAdapter
+	writer    kafkaMessageWriter
+	config    AsyncPublishConfig
+
+	// mutex keeps the queue in the order the events were encoded and guards closing it
+	mutex   sync.Mutex
+	closed  bool
+	queue   chan *queuedBatchEvent
+	flushes chan chan struct{}
+	stopped chan struct{}
+
+	// failed holds the batches with a failed event since the queue last drained, with the
+	// first failure; it is only used by the background writer
+	failed map[string]error
+}
+
+// NewAsyncBatchEventPublisherAdapter creates a new AsyncBatchEventPublisherAdapter and
+// starts its background writer; Close stops it
+func NewAsyncBatchEventPublisherAdapter(conn *KafkaConnection, topic string, config AsyncPublishConfig, opts ...BatchEventPublisherOption) *AsyncBatchEventPublisherAdapter {
+	config = config.withDefaults()
+	publisher := NewBatchEventPublisherAdapter(conn, topic, opts...)
+	publisher.writer.BatchSize = config.BatchSize
+	publisher.writer.BatchTimeout = asyncWriterBatchTimeout
+	return newAsyncBatchEventPublisher(publisher, publisher.writer, config)
+}
+
+// newAsyncBatchEventPublisher creates an asynchronous publisher that encodes events with
+// the publisher and writes them with the writer
+func newAsyncBatchEventPublisher(publisher *BatchEventPublisherAdapter, writer kafkaMessageWriter, config AsyncPublishConfig) *AsyncBatchEventPublisherAdapter {
+	config = config.withDefaults()
+	p := &AsyncBatchEventPublisherAdapter{
+		publisher: publisher,
+		writer:    writer,
+		config:    config,
+		queue:     make(chan *queuedBatchEvent, config.QueueSize),
+		flushes:   make(chan chan struct{}),
+		stopped:   make(chan struct{}),
+		failed:    make(map[string]error),
+	}
+	go p.run()
+	return p
+}
+
+// PublishBatchEvent queues a batch event, flushes the queue and returns the outcome of the event
+func (p *AsyncBatchEventPublisherAdapter) PublishBatchEvent(event *domain.BatchEvent) error {
+	result := make(chan error, 1)
+	callback := func(_ *domain.BatchEvent, err error) { result <- err }
+	if err := p.PublishBatchEventAsync(context.Background(), event, callback); err != nil {
+		return err
+	}
+	if err := p.Flush(context.Background()); err != nil {
+		return err
+	}
+	return <-result
+}
+
+// PublishBatchEventAsync queues a batch event and calls the callback with its delivery
+// outcome from the background writer. It blocks while the queue is full, until the
+// context is done.
+func (p *AsyncBatchEventPublisherAdapter) PublishBatchEventAsync(ctx context.Context, event *domain.BatchEvent, callback domain.BatchEventDeliveryCallback) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.closed {
+		return ErrPublisherClosed
+	}
+
+	published, message, err := p.publisher.encode(event)
+	if err != nil {
+		return err
+	}
+
+	// The next event of the batch is a delta from this one; a failed write forgets it
+	p.publisher.recordPublished(event, published)
+
+	select {
+	case p.queue <- &queuedBatchEvent{event: event, message: message, callback: callback}:
+		return nil
+	case <-ctx.Done():
+		if p.publisher.deltas != nil {
+			p.publisher.deltas.forget(event.BatchID)
+		}
+		return fmt.Errorf("batch event publisher queue is full: %w", ctx.Err())
+	}
+}
+
+// Flush writes the buffered events and returns once the outcomes of all events queued
+// before it were reported, or when the context is done
+func (p *AsyncBatchEventPublisherAdapter) Flush(ctx context.Context) error {
+	flushed := make(chan struct{})
+	select {
+	case p.flushes <- flushed:
+	case <-p.stopped:
+		// Closing wrote every queued event
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close writes the buffered events, stops the background writer and closes the Kafka
+// writer. Events published afterwards fail with ErrPublisherClosed.
+func (p *AsyncBatchEventPublisherAdapter) Close() error {
+	p.mutex.Lock()
+	if p.closed {
+		p.mutex.Unlock()
+		return nil
+	}
+	p.closed = true
+	close(p.queue)
+	p.mutex.Unlock()
+
+	<-p.stopped
+	return p.writer.Close()
+}
+
+// run buffers the queued events and writes them when the buffer is full, when the first
+// buffered event waited BatchTimeout, on Flush and on Close
+func (p *AsyncBatchEventPublisherAdapter) run() {
+	defer close(p.stopped)
+
+	var pending []*queuedBatchEvent
+	var timeout <-chan time.Time
+	for {
+		var flushed chan struct{}
+		select {
+		case queued, ok := <-p.queue:
+			if !ok {
+				p.write(pending)
+				return
+			}
+			pending = append(pending, queued)
+			if len(pending) < p.config.BatchSize {
+				if timeout == nil {
+					timeout = time.After(p.config.BatchTimeout)
+				}
+				continue
+			}
+		case <-timeout:
+		case flushed = <-p.flushes:
+			pending = p.drain(pending)
+		}
+
+		p.write(pending)
+		pending, timeout = nil, nil
+		if flushed != nil {
+			close(flushed)
+		}
+	}
+}
+
+// drain appends the events waiting in the queue to pending without blocking
+func (p *AsyncBatchEventPublisherAdapter) drain(pending []*queuedBatchEvent) []*queuedBatchEvent {
+	for {
+		select {
+		case queued, ok := <-p.queue:
+			if !ok {
+				return pending
+			}
+			pending = append(pending, queued)
+		default:
+			return pending
+		}
+	}
+}
+
+// write writes the events to Kafka BatchSize at a time and reports their outcomes. The
+// failed batches are forgotten once nothing is left in the queue behind them.
+func (p *AsyncBatchEventPublisherAdapter) write(events []*queuedBatchEvent) {
+	for len(events) > 0 {
+		size := p.config.BatchSize
+		if size > len(events) {
+			size = len(events)
+		}
+		p.writeBatch(events[:size])
+		events = events[size:]
+	}
+
+	if len(p.queue) == 0 && len(p.failed) > 0 {
+		p.failed = make(map[string]error)
+	}
+}
+
+// writeBatch writes the events of batches without a failed event in a single call and
+// reports the outcome of every event
+func (p *AsyncBatchEventPublisherAdapter) writeBatch(events []*queuedBatchEvent) {
+	var writable []*queuedBatchEvent
+	var messages []kafka.Message
+	for _, queued := range events {
+		if cause, ok := p.failed[queued.event.BatchID]; ok {
+			p.report(queued, fmt.Errorf("held back behind a failed event of batch %s: %w", queued.event.BatchID, cause))
+			continue
+		}
+		writable = append(writable, queued)
+		messages = append(messages, queued.message)
+	}
+	if len(messages) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := p.writer.WriteMessages(ctx, messages...)
+
+	// A write fails as a whole, or message by message when some partitions failed
+	var writeErrors kafka.WriteErrors
+	if !errors.As(err, &writeErrors) || len(writeErrors) != len(writable) {
+		writeErrors = nil
+	}
+
+	delivered := 0
+	for i, queued := range writable {
+		cause := err
+		if writeErrors != nil {
+			cause = writeErrors[i]
+		}
+		if cause == nil {
+			delivered++
+			p.report(queued, nil)
+			continue
+		}
+
+		cause = fmt.Errorf("failed to write batch event to Kafka topic %s: %w", p.publisher.topic, cause)
+		if _, ok := p.failed[queued.event.BatchID]; !ok {
+			p.failed[queued.event.BatchID] = cause
+		}
+		p.report(queued, cause)
+	}
+
+	if delivered < len(writable) {
+		log.Printf("Published %d of %d batch events to %s: %v", delivered, len(writable), p.publisher.topic, err)
+	} else {
+		log.Printf("Successfully published %d batch events to %s", delivered, p.publisher.topic)
+	}
+}
+
+// report calls the callback of an event with its outcome. A failed event is forgotten by
+// the delta tracker, so the next event of its batch is published as a snapshot.
+func (p *AsyncBatchEventPublisherAdapter) report(queued *queuedBatchEvent, err error) {
+	if err != nil && p.publisher.deltas != nil {
+		p.publisher.deltas.forget(queued.event.BatchID)
+	}
+	if queued.callback != nil {
+		queued.callback(queued.event, err)
+	}
+}
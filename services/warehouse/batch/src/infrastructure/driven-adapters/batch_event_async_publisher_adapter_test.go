@@ -0,0 +1,251 @@
+package drivenadapters
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+	"github.com/segmentio/kafka-go"
+)
+
+// fakeKafkaWriter records the messages of every write and fails the messages keyed by
+// the batches in failBatches. Writes wait for release when it is set.
+type fakeKafkaWriter struct {
+	mutex       sync.Mutex
+	writes      [][]kafka.Message
+	failBatches map[string]bool
+	release     chan struct{}
+	closed      bool
+}
+
+func (w *fakeKafkaWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	if w.release != nil {
+		<-w.release
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.writes = append(w.writes, msgs)
+
+	errs := make(kafka.WriteErrors, len(msgs))
+	for i, msg := range msgs {
+		if w.failBatches[string(msg.Key)] {
+			errs[i] = kafka.NotLeaderForPartition
+		}
+	}
+	if errs.Count() == 0 {
+		return nil
+	}
+	return errs
+}
+
+func (w *fakeKafkaWriter) Close() error {
+	w.closed = true
+	return nil
+}
+
+// writeSizes returns how many messages every write carried
+func (w *fakeKafkaWriter) writeSizes() []int {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	sizes := make([]int, len(w.writes))
+	for i, write := range w.writes {
+		sizes[i] = len(write)
+	}
+	return sizes
+}
+
+// deliveryRecorder collects the outcomes reported to its callback
+type deliveryRecorder struct {
+	mutex    sync.Mutex
+	outcomes []error
+	events   []*domain.BatchEvent
+	reported chan struct{}
+}
+
+func newDeliveryRecorder() *deliveryRecorder {
+	return &deliveryRecorder{reported: make(chan struct{}, 100)}
+}
+
+func (r *deliveryRecorder) callback(event *domain.BatchEvent, err error) {
+	r.mutex.Lock()
+	r.events = append(r.events, event)
+	r.outcomes = append(r.outcomes, err)
+	r.mutex.Unlock()
+	r.reported <- struct{}{}
+}
+
+// await waits until count more outcomes were reported
+func (r *deliveryRecorder) await(t *testing.T, count int) {
+	t.Helper()
+	for i := 0; i < count; i++ {
+		select {
+		case <-r.reported:
+		case <-time.After(time.Second):
+			t.Fatalf("Expected %d more delivery outcomes, got %d", count-i, i)
+		}
+	}
+}
+
+func newTestAsyncPublisher(t *testing.T, writer *fakeKafkaWriter, config AsyncPublishConfig, opts ...BatchEventPublisherOption) *AsyncBatchEventPublisherAdapter {
+	t.Helper()
+	conn, err := NewKafkaConnection([]string{"localhost:9092"}, KafkaSecurity{})
+	if err != nil {
+		t.Fatalf("Failed to create Kafka connection: %v", err)
+	}
+	opts = append([]BatchEventPublisherOption{WithBatchEventCodec(domain.JSONEventCodec{})}, opts...)
+	publisher := newAsyncBatchEventPublisher(NewBatchEventPublisherAdapter(conn, "batch-events", opts...), writer, config)
+	t.Cleanup(func() { publisher.Close() })
+	return publisher
+}
+
+func newTestBatchEvent(batchID string, sequence int64) *domain.BatchEvent {
+	event := domain.NewBatchCreatedEvent(domain.NewBatch(batchID, "product-1"))
+	event.Sequence = sequence
+	return event
+}
+
+func TestParseBatchPublishMode(t *testing.T) {
+	for _, value := range []string{"sync", "async"} {
+		if mode, err := ParseBatchPublishMode(value); err != nil || string(mode) != value {
+			t.Errorf("Expected %q to parse, got %q (%v)", value, mode, err)
+		}
+	}
+	if _, err := ParseBatchPublishMode("fire-and-forget"); err == nil {
+		t.Error("Expected an unknown publish mode to be rejected")
+	}
+}
+
+func TestAsyncBatchEventPublisherAdapter_WritesBySizeAndTime(t *testing.T) {
+	writer := &fakeKafkaWriter{}
+	publisher := newTestAsyncPublisher(t, writer, AsyncPublishConfig{BatchSize: 3, BatchTimeout: 20 * time.Millisecond})
+	recorder := newDeliveryRecorder()
+
+	// A full batch is written at once
+	for i := 1; i <= 3; i++ {
+		if err := publisher.PublishBatchEventAsync(context.Background(), newTestBatchEvent("batch-1", int64(i)), recorder.callback); err != nil {
+			t.Fatalf("Failed to publish: %v", err)
+		}
+	}
+	recorder.await(t, 3)
+
+	// A partial batch is written once its first event waited the batch timeout
+	if err := publisher.PublishBatchEventAsync(context.Background(), newTestBatchEvent("batch-2", 1), recorder.callback); err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+	recorder.await(t, 1)
+
+	if sizes := writer.writeSizes(); len(sizes) != 2 || sizes[0] != 3 || sizes[1] != 1 {
+		t.Errorf("Expected a write of 3 events and a write of 1, got %v", sizes)
+	}
+	for i, err := range recorder.outcomes {
+		if err != nil {
+			t.Errorf("Expected event %d to be delivered, got %v", i, err)
+		}
+	}
+
+	// The synchronous interface waits for the delivery
+	if err := publisher.PublishBatchEvent(newTestBatchEvent("batch-3", 1)); err != nil {
+		t.Errorf("Expected the event to be delivered, got %v", err)
+	}
+}
+
+func TestAsyncBatchEventPublisherAdapter_Backpressure(t *testing.T) {
+	writer := &fakeKafkaWriter{release: make(chan struct{})}
+	publisher := newTestAsyncPublisher(t, writer, AsyncPublishConfig{BatchSize: 1, BatchTimeout: time.Hour, QueueSize: 1})
+	recorder := newDeliveryRecorder()
+
+	// The first event is being written and the second one fills the queue
+	for i := 1; i <= 2; i++ {
+		if err := publisher.PublishBatchEventAsync(context.Background(), newTestBatchEvent("batch-1", int64(i)), recorder.callback); err != nil {
+			t.Fatalf("Failed to publish: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := publisher.PublishBatchEventAsync(ctx, newTestBatchEvent("batch-1", 3), recorder.callback)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected publishing to block while the queue is full, got %v", err)
+	}
+
+	close(writer.release)
+	recorder.await(t, 2)
+	if sizes := writer.writeSizes(); len(sizes) != 2 {
+		t.Errorf("Expected the queued events to be written once the writer caught up, got %v", sizes)
+	}
+}
+
+func TestAsyncBatchEventPublisherAdapter_HoldsBackFailedBatches(t *testing.T) {
+	writer := &fakeKafkaWriter{failBatches: map[string]bool{"batch-1": true}}
+	publisher := newTestAsyncPublisher(t, writer, AsyncPublishConfig{BatchSize: 2, BatchTimeout: time.Hour},
+		WithBatchEventPayload(BatchEventPayloadDelta, 10))
+	recorder := newDeliveryRecorder()
+
+	events := []*domain.BatchEvent{
+		newTestBatchEvent("batch-1", 1),
+		newTestBatchEvent("batch-2", 1),
+		newTestBatchEvent("batch-1", 2),
+	}
+	for _, event := range events {
+		if err := publisher.PublishBatchEventAsync(context.Background(), event, recorder.callback); err != nil {
+			t.Fatalf("Failed to publish: %v", err)
+		}
+	}
+	if err := publisher.Flush(context.Background()); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+
+	// Flush returns once every outcome was reported
+	if len(recorder.outcomes) != 3 {
+		t.Fatalf("Expected 3 outcomes after flushing, got %d", len(recorder.outcomes))
+	}
+	if !errors.Is(recorder.outcomes[0], kafka.NotLeaderForPartition) || recorder.outcomes[1] != nil {
+		t.Errorf("Expected the batch-1 event to fail and the batch-2 event to be delivered, got %v", recorder.outcomes)
+	}
+	if !errors.Is(recorder.outcomes[2], kafka.NotLeaderForPartition) {
+		t.Errorf("Expected the later batch-1 event to be held back, got %v", recorder.outcomes[2])
+	}
+	if sizes := writer.writeSizes(); len(sizes) != 1 || sizes[0] != 2 {
+		t.Errorf("Expected the held back event not to be written, got writes of %v", sizes)
+	}
+	if _, known := publisher.publisher.deltas.batches["batch-1"]; known {
+		t.Error("Expected the failed batch to be forgotten, so its next event is a snapshot")
+	}
+
+	// Once the queue drained, the batch is published again
+	writer.failBatches = nil
+	if err := publisher.PublishBatchEvent(newTestBatchEvent("batch-1", 1)); err != nil {
+		t.Errorf("Expected the retried event to be delivered, got %v", err)
+	}
+}
+
+func TestAsyncBatchEventPublisherAdapter_CloseWritesBufferedEvents(t *testing.T) {
+	writer := &fakeKafkaWriter{}
+	publisher := newTestAsyncPublisher(t, writer, AsyncPublishConfig{BatchSize: 10, BatchTimeout: time.Hour})
+	recorder := newDeliveryRecorder()
+
+	for i := 1; i <= 2; i++ {
+		if err := publisher.PublishBatchEventAsync(context.Background(), newTestBatchEvent("batch-1", int64(i)), recorder.callback); err != nil {
+			t.Fatalf("Failed to publish: %v", err)
+		}
+	}
+	if err := publisher.Close(); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+
+	if len(recorder.outcomes) != 2 || recorder.outcomes[0] != nil || recorder.outcomes[1] != nil || !writer.closed {
+		t.Errorf("Expected the buffered events to be delivered before the writer closed, got %v", recorder.outcomes)
+	}
+	if err := publisher.PublishBatchEventAsync(context.Background(), newTestBatchEvent("batch-1", 3), recorder.callback); !errors.Is(err, ErrPublisherClosed) {
+		t.Errorf("Expected ErrPublisherClosed after closing, got %v", err)
+	}
+	if err := publisher.Flush(context.Background()); err != nil {
+		t.Errorf("Expected flushing a closed publisher to succeed, got %v", err)
+	}
+}
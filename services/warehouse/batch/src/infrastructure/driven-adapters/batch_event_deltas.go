@@ -0,0 +1,123 @@
+package drivenadapters
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+)
+
+// BatchEventPayload selects how much of its batch a published batch event carries
+type BatchEventPayload string
+
+const (
+	// BatchEventPayloadFull carries the whole batch with every event
+	BatchEventPayloadFull BatchEventPayload = "full"
+
+	// BatchEventPayloadDelta carries the change since the previous event of the batch,
+	// with a full snapshot every few events
+	BatchEventPayloadDelta BatchEventPayload = "delta"
+)
+
+// DefaultBatchSnapshotInterval is how many events of a batch are published, at most,
+// for each full snapshot in delta mode
+const DefaultBatchSnapshotInterval = 10
+
+// ParseBatchEventPayload converts a configured payload into a BatchEventPayload
+func ParseBatchEventPayload(value string) (BatchEventPayload, error) {
+	switch payload := BatchEventPayload(value); payload {
+	case BatchEventPayloadFull, BatchEventPayloadDelta:
+		return payload, nil
+	}
+	return "", fmt.Errorf("unknown batch event payload %q (expected %q or %q)", value, BatchEventPayloadFull, BatchEventPayloadDelta)
+}
+
+// batchDeltaTracker turns batch events into delta events. It remembers the last
+// published state of every open batch, and replaces the batch of the next event by
+// the delta from that state. A snapshot is published instead when the state of the
+// batch is unknown, e.g. after a restart, and every snapshotInterval events.
+// Events that were never published do not move the state, so a delta always applies
+// to the event at its BaseSequence as received by consumers.
+type batchDeltaTracker struct {
+	snapshotInterval int
+
+	mutex   sync.Mutex
+	batches map[string]publishedBatch
+}
+
+// publishedBatch is the last published state of a batch
+type publishedBatch struct {
+	batch    *domain.Batch
+	sequence int64
+	// deltas is how many deltas were published since the last snapshot
+	deltas int
+}
+
+// newBatchDeltaTracker creates a tracker that publishes a snapshot every snapshotInterval events
+func newBatchDeltaTracker(snapshotInterval int) *batchDeltaTracker {
+	if snapshotInterval < 1 {
+		snapshotInterval = DefaultBatchSnapshotInterval
+	}
+	return &batchDeltaTracker{
+		snapshotInterval: snapshotInterval,
+		batches:          make(map[string]publishedBatch),
+	}
+}
+
+// compact returns the event to publish for a batch event: a copy of it carrying the
+// delta from the last published state of its batch, or the event itself as a snapshot
+func (t *batchDeltaTracker) compact(event *domain.BatchEvent) *domain.BatchEvent {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	last, known := t.batches[event.BatchID]
+	if event.Batch == nil || event.Sequence == 0 || !known || event.Sequence <= last.sequence ||
+		last.deltas+1 >= t.snapshotInterval {
+		return event
+	}
+
+	compacted := *event
+	compacted.Batch = nil
+	compacted.BaseSequence = last.sequence
+	compacted.Delta = domain.DiffBatch(last.batch, event.Batch)
+	return &compacted
+}
+
+// record remembers the state of the batch of a published event, or forgets the batch
+// once it is deleted or closed
+func (t *batchDeltaTracker) record(event, compacted *domain.BatchEvent) {
+	if event.Batch == nil || event.Sequence == 0 {
+		return
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	switch {
+	case event.EventType == domain.BatchEventItemRemoved && event.Batch.IsEmpty(),
+		event.Batch.Status == domain.BatchStatusCompleted,
+		event.Batch.Status == domain.BatchStatusCancelled,
+		event.Batch.Status == domain.BatchStatusDestroyed:
+		// Later events of the batch, such as recalls, are rare enough to be snapshots
+		delete(t.batches, event.BatchID)
+		return
+	}
+
+	deltas := 0
+	if compacted.Delta != nil {
+		deltas = t.batches[event.BatchID].deltas + 1
+	}
+	t.batches[event.BatchID] = publishedBatch{
+		batch:    event.Batch.Clone(),
+		sequence: event.Sequence,
+		deltas:   deltas,
+	}
+}
+
+// forget drops the state of a batch, so that its next event is published as a snapshot
+func (t *batchDeltaTracker) forget(batchID string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	delete(t.batches, batchID)
+}
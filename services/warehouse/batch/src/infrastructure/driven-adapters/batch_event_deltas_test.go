@@ -0,0 +1,99 @@
+package drivenadapters
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+)
+
+func TestBatchDeltaTracker_PublishesDeltasBetweenSnapshots(t *testing.T) {
+	tracker := newBatchDeltaTracker(3)
+	batch := domain.NewBatch("batch-1", "product-1")
+
+	// A consumer rebuilding the batch from the published events
+	var (
+		rebuilt      *domain.Batch
+		lastSequence int64
+	)
+	publish := func(sequence int64, eventType domain.BatchEventType, change func() error, delivered bool) *domain.BatchEvent {
+		t.Helper()
+		if err := change(); err != nil {
+			t.Fatalf("Failed to change batch: %v", err)
+		}
+		batch.Version++
+		event := &domain.BatchEvent{EventType: eventType, BatchID: batch.ID, Batch: batch.Clone(), Sequence: sequence}
+
+		published := tracker.compact(event)
+		if !delivered {
+			return published
+		}
+		tracker.record(event, published)
+
+		if published.Delta == nil {
+			rebuilt, lastSequence = published.Batch, published.Sequence
+			return published
+		}
+		if published.Batch != nil || published.BaseSequence != lastSequence {
+			t.Fatalf("Expected a delta from sequence %d, got %+v", lastSequence, published)
+		}
+		next, err := published.Delta.Apply(rebuilt)
+		if err != nil {
+			t.Fatalf("Failed to apply delta %d: %v", sequence, err)
+		}
+		rebuilt, lastSequence = next, published.Sequence
+		return published
+	}
+	noChange := func() error { return nil }
+
+	if event := publish(1, domain.BatchEventCreated, noChange, true); event.Delta != nil {
+		t.Error("Expected the first event of a batch to be a snapshot")
+	}
+	event := publish(2, domain.BatchEventItemAdded, func() error {
+		return batch.AddItem("order-1", "product-1", 2, domain.ItemStatusAllocated)
+	}, true)
+	if event.Delta == nil || len(event.Delta.ItemsAdded) != 1 {
+		t.Errorf("Expected a delta adding order-1, got %+v", event)
+	}
+	publish(3, domain.BatchEventItemAdded, func() error {
+		return batch.AddItem("order-2", "product-1", 1, domain.ItemStatusAllocated)
+	}, true)
+	if event := publish(4, domain.BatchEventItemUpdated, func() error {
+		return batch.UpdateItemStatus("order-1", domain.ItemStatusShipped)
+	}, true); event.Delta != nil {
+		t.Error("Expected a snapshot after two deltas")
+	}
+
+	// An event that was not delivered leaves the next delta based on the last delivered one
+	publish(5, domain.BatchEventItemUpdated, func() error {
+		return batch.UpdateItemStatus("order-2", domain.ItemStatusShipped)
+	}, false)
+	event = publish(6, domain.BatchEventProcessing, batch.StartProcessing, true)
+	if event.Delta == nil || len(event.Delta.ItemsUpdated) != 1 || event.Delta.Status == nil {
+		t.Errorf("Expected a delta with the undelivered item update and the status, got %+v", event.Delta)
+	}
+	if !reflect.DeepEqual(rebuilt, batch) {
+		t.Errorf("Expected the rebuilt batch %+v, got %+v", batch, rebuilt)
+	}
+
+	// A redelivered event and the events of a closed batch are snapshots
+	redelivered := &domain.BatchEvent{EventType: domain.BatchEventProcessing, BatchID: batch.ID, Batch: batch.Clone(), Sequence: 6}
+	if tracker.compact(redelivered).Delta != nil {
+		t.Error("Expected a redelivered event to be a snapshot")
+	}
+	publish(7, domain.BatchEventCompleted, batch.Complete, true)
+	if event := publish(8, domain.BatchEventRecalled, noChange, true); event.Delta != nil {
+		t.Error("Expected an event of a completed batch to be a snapshot")
+	}
+}
+
+func TestParseBatchEventPayload(t *testing.T) {
+	for _, value := range []string{"full", "delta"} {
+		if payload, err := ParseBatchEventPayload(value); err != nil || string(payload) != value {
+			t.Errorf("Expected payload %s, got %s (%v)", value, payload, err)
+		}
+	}
+	if _, err := ParseBatchEventPayload("compact"); err == nil {
+		t.Error("Expected an error for an unknown payload")
+	}
+}
@@ -2,27 +2,61 @@ package drivenadapters
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
-	"strings"
 	"time"
 
 	"github.com/segmentio/kafka-go"
 	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
 )
 
-// BatchEventPublisherAdapter implements the BatchEventPublisher interface using Kafka
+// BatchEventPublisherAdapter implements the BatchEventPublisher interface using Kafka.
+// Events are published as CloudEvents 1.0, in binary mode unless configured otherwise,
+// with Protobuf data unless another codec is configured. They carry the whole batch
+// unless delta payloads are configured.
 type BatchEventPublisherAdapter struct {
-	writer        *kafka.Writer
-	topic         string
-	brokerAddress string
+	writer *kafka.Writer
+	topic  string
+	codec  domain.EventCodec
+	mode   CloudEventsMode
+	source string
+	deltas *batchDeltaTracker
+}
+
+// BatchEventPublisherOption configures optional BatchEventPublisherAdapter behaviour
+type BatchEventPublisherOption func(*BatchEventPublisherAdapter)
+
+// WithCloudEvents sets the CloudEvents mode and the source attribute of published events
+func WithCloudEvents(mode CloudEventsMode, source string) BatchEventPublisherOption {
+	return func(p *BatchEventPublisherAdapter) {
+		p.mode = mode
+		p.source = source
+	}
+}
+
+// WithBatchEventCodec sets the codec that encodes the data of published events
+func WithBatchEventCodec(codec domain.EventCodec) BatchEventPublisherOption {
+	return func(p *BatchEventPublisherAdapter) {
+		p.codec = codec
+	}
+}
+
+// WithBatchEventPayload sets how much of its batch a published event carries. In delta
+// mode a full snapshot of a batch is published every snapshotInterval events.
+func WithBatchEventPayload(payload BatchEventPayload, snapshotInterval int) BatchEventPublisherOption {
+	return func(p *BatchEventPublisherAdapter) {
+		p.deltas = nil
+		if payload == BatchEventPayloadDelta {
+			p.deltas = newBatchDeltaTracker(snapshotInterval)
+		}
+	}
 }
 
 // NewBatchEventPublisherAdapter creates a new BatchEventPublisherAdapter
-func NewBatchEventPublisherAdapter(brokerAddress, topic string) *BatchEventPublisherAdapter {
+func NewBatchEventPublisherAdapter(conn *KafkaConnection, topic string, opts ...BatchEventPublisherOption) *BatchEventPublisherAdapter {
 	writer := &kafka.Writer{
-		Addr:         kafka.TCP(brokerAddress),
+		Addr:         conn.addr(),
+		Transport:    conn.transport,
 		Topic:        topic,
 		Balancer:     &kafka.LeastBytes{},
 		RequiredAcks: kafka.RequireOne,
@@ -31,126 +65,55 @@ func NewBatchEventPublisherAdapter(brokerAddress, topic string) *BatchEventPubli
 		ReadTimeout:  10 * time.Second,
 	}
 
-	return &BatchEventPublisherAdapter{
-		writer:        writer,
-		topic:         topic,
-		brokerAddress: brokerAddress,
+	publisher := &BatchEventPublisherAdapter{
+		writer: writer,
+		topic:  topic,
+		codec:  ProtobufEventCodec{},
+		mode:   CloudEventsBinary,
+		source: DefaultCloudEventsSource,
+	}
+	for _, opt := range opts {
+		opt(publisher)
 	}
+	return publisher
 }
 
-// PublishBatchEvent publishes a batch event to Kafka
+// PublishBatchEvent publishes a batch event to Kafka as a CloudEvent
 func (p *BatchEventPublisherAdapter) PublishBatchEvent(event *domain.BatchEvent) error {
-	// Serialize the event to JSON
-	eventData, err := json.Marshal(event)
+	published, message, err := p.encode(event)
 	if err != nil {
-		return fmt.Errorf("failed to marshal batch event: %w", err)
+		return err
 	}
 
-	// Create Kafka message
-	message := kafka.Message{
-		Key:   []byte(event.BatchID), // Use batch ID as partition key
-		Value: eventData,
-		Headers: []kafka.Header{
-			{
-				Key:   "event_type",
-				Value: []byte(event.EventType),
-			},
-			{
-				Key:   "batch_id",
-				Value: []byte(event.BatchID),
-			},
-			{
-				Key:   "product_id",
-				Value: []byte(event.ProductID),
-			},
-			{
-				Key:   "timestamp",
-				Value: []byte(event.Timestamp.Format(time.RFC3339)),
-			},
-		},
-	}
-
-	// Add order_id header if present
-	if event.OrderID != nil {
-		message.Headers = append(message.Headers, kafka.Header{
-			Key:   "order_id",
-			Value: []byte(*event.OrderID),
-		})
-	}
-
-	// Write message to Kafka with retry logic for topic/partition errors
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	err = p.writer.WriteMessages(ctx, message)
-	if err != nil {
-		// Check if it's an "Unknown Topic Or Partition" error (Kafka error code 3)
-		if isUnknownTopicOrPartitionError(err) {
-			log.Printf("Unknown topic or partition error detected for topic '%s', attempting to recreate writer: %v", p.topic, err)
-			
-			// Close the current writer
-			if closeErr := p.writer.Close(); closeErr != nil {
-				log.Printf("Warning: failed to close old writer: %v", closeErr)
-			}
-			
-			// Recreate the writer
-			p.recreateWriter()
-			
-			// Wait a moment for topic to be available
-			log.Printf("Waiting 2 seconds for topic '%s' to become available...", p.topic)
-			time.Sleep(2 * time.Second)
-			
-			// Retry the write operation with new writer
-			log.Printf("Retrying batch event publish for batch %s with recreated writer", event.BatchID)
-			retryCtx, retryCancel := context.WithTimeout(context.Background(), 10*time.Second)
-			defer retryCancel()
-			
-			if retryErr := p.writer.WriteMessages(retryCtx, message); retryErr != nil {
-				log.Printf("Retry failed for batch event %s (batch %s): %v", event.EventType, event.BatchID, retryErr)
-				return fmt.Errorf("failed to write batch event to Kafka after retry: %w", retryErr)
-			}
-			
-			log.Printf("Successfully published batch event after writer recreation: %s for batch %s", event.EventType, event.BatchID)
-			return nil
-		}
-		
-		return fmt.Errorf("failed to write batch event to Kafka: %w", err)
+	if err := p.writer.WriteMessages(ctx, message); err != nil {
+		return fmt.Errorf("failed to write batch event to Kafka topic %s: %w", p.topic, err)
 	}
 
 	log.Printf("Successfully published batch event: %s for batch %s", event.EventType, event.BatchID)
+	p.recordPublished(event, published)
 	return nil
 }
 
-// recreateWriter creates a new Kafka writer instance
-func (p *BatchEventPublisherAdapter) recreateWriter() {
-	log.Printf("Recreating Kafka writer for topic %s", p.topic)
-	
-	p.writer = &kafka.Writer{
-		Addr:         kafka.TCP(p.brokerAddress),
-		Topic:        p.topic,
-		Balancer:     &kafka.LeastBytes{},
-		RequiredAcks: kafka.RequireOne,
-		Async:        false, // Synchronous writes for reliability
-		WriteTimeout: 10 * time.Second,
-		ReadTimeout:  10 * time.Second,
+// encode returns the event to publish for a batch event, a delta in delta mode, and its
+// Kafka message
+func (p *BatchEventPublisherAdapter) encode(event *domain.BatchEvent) (*domain.BatchEvent, kafka.Message, error) {
+	published := event
+	if p.deltas != nil {
+		published = p.deltas.compact(event)
 	}
-	
-	log.Printf("Kafka writer recreated successfully for topic %s", p.topic)
+
+	message, err := newBatchEventMessage(published, p.codec, p.mode, p.source)
+	return published, message, err
 }
 
-// isUnknownTopicOrPartitionError checks if the error is related to unknown topic or partition
-func isUnknownTopicOrPartitionError(err error) bool {
-	if err == nil {
-		return false
+// recordPublished remembers the published state of the batch in delta mode
+func (p *BatchEventPublisherAdapter) recordPublished(event, published *domain.BatchEvent) {
+	if p.deltas != nil {
+		p.deltas.record(event, published)
 	}
-	
-	// Check for Kafka error code 3 (UnknownTopicOrPartition)
-	// This can appear in different error message formats
-	errStr := strings.ToLower(err.Error())
-	return strings.Contains(errStr, "[3] unknown topic or partition") ||
-		   strings.Contains(errStr, "unknowntopicorpartition") ||
-		   strings.Contains(errStr, "unknown topic or partition") ||
-		   strings.Contains(errStr, "topic or partition that does not exist")
 }
 
 // Close closes the Kafka writer
@@ -1,73 +1,24 @@
 package drivenadapters
 
 import (
-	"errors"
+	"encoding/json"
+	"strings"
 	"testing"
-)
-
-func TestIsUnknownTopicOrPartitionError(t *testing.T) {
-	testCases := []struct {
-		name     string
-		err      error
-		expected bool
-	}{
-		{
-			name:     "nil error",
-			err:      nil,
-			expected: false,
-		},
-		{
-			name:     "exact match with error code",
-			err:      errors.New("[3] Unknown Topic Or Partition: the request is for a topic or partition that does not exist on this broker"),
-			expected: true,
-		},
-		{
-			name:     "lowercase version",
-			err:      errors.New("[3] unknown topic or partition: the request is for a topic or partition that does not exist on this broker"),
-			expected: true,
-		},
-		{
-			name:     "UnknownTopicOrPartition format",
-			err:      errors.New("kafka: UnknownTopicOrPartition"),
-			expected: true,
-		},
-		{
-			name:     "generic unknown topic message",
-			err:      errors.New("unknown topic or partition"),
-			expected: true,
-		},
-		{
-			name:     "topic does not exist message",
-			err:      errors.New("topic or partition that does not exist"),
-			expected: true,
-		},
-		{
-			name:     "different error",
-			err:      errors.New("connection refused"),
-			expected: false,
-		},
-		{
-			name:     "different kafka error",
-			err:      errors.New("[1] OffsetOutOfRange"),
-			expected: false,
-		},
-	}
+	"time"
 
-	for _, tc := range testCases {
-		t.Run(tc.name, func(t *testing.T) {
-			result := isUnknownTopicOrPartitionError(tc.err)
-			if result != tc.expected {
-				t.Errorf("Expected %v, got %v for error: %v", tc.expected, result, tc.err)
-			}
-		})
-	}
-}
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/infrastructure/eventspb"
+	"google.golang.org/protobuf/proto"
+)
 
 func TestBatchEventPublisherAdapterCreation(t *testing.T) {
-	brokerAddress := "localhost:9092"
+	conn, err := NewKafkaConnection([]string{"localhost:9092"}, KafkaSecurity{})
+	if err != nil {
+		t.Fatalf("Failed to create Kafka connection: %v", err)
+	}
 	topic := "test-topic"
 	
-	adapter := NewBatchEventPublisherAdapter(brokerAddress, topic)
+	adapter := NewBatchEventPublisherAdapter(conn, topic)
 	
 	if adapter == nil {
 		t.Fatal("Expected adapter to be created, got nil")
@@ -77,14 +28,110 @@ func TestBatchEventPublisherAdapterCreation(t *testing.T) {
 		t.Errorf("Expected topic %s, got %s", topic, adapter.topic)
 	}
 	
-	if adapter.brokerAddress != brokerAddress {
-		t.Errorf("Expected broker address %s, got %s", brokerAddress, adapter.brokerAddress)
-	}
-	
 	if adapter.writer == nil {
 		t.Error("Expected writer to be initialized")
 	}
 	
 	// Clean up
 	adapter.Close()
-}
\ No newline at end of file
+}
+
+func TestNewBatchEventMessage(t *testing.T) {
+	batch := domain.NewBatch("batch-1", "product-1")
+	batch.AddItem("order-1", "product-1", 2, domain.ItemStatusAllocated)
+	item, _ := batch.GetItemByOrderID("order-1")
+	event := domain.NewBatchItemAddedEvent(batch, "order-1", item)
+	event.Timestamp = time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	binary, err := newBatchEventMessage(event, domain.JSONEventCodec{}, CloudEventsBinary, DefaultCloudEventsSource)
+	if err != nil {
+		t.Fatalf("Failed to encode binary-mode message: %v", err)
+	}
+	headers := make(map[string]string)
+	for _, header := range binary.Headers {
+		headers[header.Key] = string(header.Value)
+	}
+	expected := map[string]string{
+		"ce_specversion": "1.0",
+		"ce_id":          "batch-1/batch.item_added/1740819600000000000/order-1",
+		"ce_type":        "batch.item_added",
+		"ce_source":      DefaultCloudEventsSource,
+		"ce_subject":     "batch-1",
+		"ce_time":        "2025-03-01T09:00:00Z",
+		"content-type":   "application/json",
+		"schema_version": "1",
+	}
+	for key, value := range expected {
+		if headers[key] != value {
+			t.Errorf("Expected header %s=%q, got %q", key, value, headers[key])
+		}
+	}
+	var data domain.BatchEvent
+	if err := json.Unmarshal(binary.Value, &data); err != nil || data.BatchID != "batch-1" || string(binary.Key) != "batch-1" {
+		t.Errorf("Expected the batch event as the value keyed by batch ID, got %s (%v)", binary.Value, err)
+	}
+
+	structured, err := newBatchEventMessage(event, ProtobufEventCodec{}, CloudEventsStructured, "/test")
+	if err != nil {
+		t.Fatalf("Failed to encode structured-mode message: %v", err)
+	}
+	if len(structured.Headers) != 2 || string(structured.Headers[0].Value) != domain.CloudEventsContentType {
+		t.Errorf("Expected the CloudEvents content type and schema version headers, got %v", structured.Headers)
+	}
+	var envelope domain.CloudEvent
+	if err := json.Unmarshal(structured.Value, &envelope); err != nil {
+		t.Fatalf("Failed to decode envelope: %v", err)
+	}
+	if err := envelope.Validate(); err != nil {
+		t.Errorf("Expected a valid CloudEvent, got %v", err)
+	}
+	if envelope.ID != expected["ce_id"] || envelope.Source != "/test" || envelope.Time == nil || !envelope.Time.Equal(event.Timestamp) {
+		t.Errorf("Expected the envelope to carry the event attributes, got %+v", envelope)
+	}
+	var message eventspb.BatchEvent
+	if envelope.DataContentType != domain.ProtobufContentType || len(envelope.Data) != 0 {
+		t.Errorf("Expected Protobuf data, got %s: %s", envelope.DataContentType, envelope.Data)
+	}
+	if err := proto.Unmarshal(envelope.DataBase64, &message); err != nil || message.GetOrderId() != "order-1" {
+		t.Errorf("Expected the batch event as base64 data, got %v (%v)", &message, err)
+	}
+}
+
+func TestNewBatchEventMessage_TraceContext(t *testing.T) {
+	event := domain.NewBatchCreatedEvent(domain.NewBatch("batch-1", "product-1"))
+	event.TraceContext = domain.TraceContext{
+		TraceParent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		TraceState:  "vendor=value",
+	}
+
+	for _, mode := range []CloudEventsMode{CloudEventsBinary, CloudEventsStructured} {
+		message, err := newBatchEventMessage(event, domain.JSONEventCodec{}, mode, DefaultCloudEventsSource)
+		if err != nil {
+			t.Fatalf("%s: failed to encode message: %v", mode, err)
+		}
+		headers := make(map[string]string)
+		for _, header := range message.Headers {
+			headers[header.Key] = string(header.Value)
+		}
+		if headers["traceparent"] != event.TraceContext.TraceParent || headers["tracestate"] != event.TraceContext.TraceState {
+			t.Errorf("%s: expected the trace context in the headers, got %v", mode, headers)
+		}
+		if strings.Contains(string(message.Value), "trace_context") {
+			t.Errorf("%s: expected the trace context to be left out of the payload, got %s", mode, message.Value)
+		}
+	}
+	if event.TraceContext.IsZero() {
+		t.Error("Expected the event to keep its trace context")
+	}
+}
+
+func TestParseCloudEventsMode(t *testing.T) {
+	for _, value := range []string{"binary", "structured"} {
+		if mode, err := ParseCloudEventsMode(value); err != nil || string(mode) != value {
+			t.Errorf("Expected mode %s, got %s (%v)", value, mode, err)
+		}
+	}
+	if _, err := ParseCloudEventsMode("batch"); err == nil {
+		t.Error("Expected an error for an unknown mode")
+	}
+}
@@ -2,27 +2,47 @@ package drivenadapters
 
 import (
 	"fmt"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
 )
 
 // BatchMemoryRepository implements BatchRepository using in-memory storage
 type BatchMemoryRepository struct {
-	batches map[string]*domain.Batch
-	mutex   sync.RWMutex
+	batches      map[string]*domain.Batch
+	outbox       map[int64]*domain.OutboxMessage
+	nextOutboxID int64
+	audit        map[string][]*domain.BatchAuditEntry
+	nextAuditID  int64
+	options      batchRepositoryOptions
+	changes      map[string][]domain.BatchChange
+	snapshots    map[string][]domain.BatchSnapshot
+	mutex        sync.RWMutex
 }
 
 // NewBatchMemoryRepository creates a new in-memory batch repository
-func NewBatchMemoryRepository() *BatchMemoryRepository {
+func NewBatchMemoryRepository(opts ...BatchRepositoryOption) *BatchMemoryRepository {
 	return &BatchMemoryRepository{
-		batches: make(map[string]*domain.Batch),
-		mutex:   sync.RWMutex{},
+		batches:   make(map[string]*domain.Batch),
+		outbox:    make(map[int64]*domain.OutboxMessage),
+		audit:     make(map[string][]*domain.BatchAuditEntry),
+		options:   newBatchRepositoryOptions(opts),
+		changes:   make(map[string][]domain.BatchChange),
+		snapshots: make(map[string][]domain.BatchSnapshot),
+		mutex:     sync.RWMutex{},
 	}
 }
 
 // Save stores or updates a batch
 func (r *BatchMemoryRepository) Save(batch *domain.Batch) error {
+	return r.SaveWithEvents(batch, domain.BatchCommand{})
+}
+
+// SaveWithEvents stores or updates a batch, records the command in its audit trail and
+// enqueues its events in the outbox atomically
+func (r *BatchMemoryRepository) SaveWithEvents(batch *domain.Batch, command domain.BatchCommand, events ...*domain.BatchEvent) error {
 	if batch == nil {
 		return fmt.Errorf("batch cannot be nil")
 	}
@@ -30,12 +50,30 @@ func (r *BatchMemoryRepository) Save(batch *domain.Batch) error {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
+	if err := r.checkVersion(batch); err != nil {
+		return err
+	}
+
+	// The events share the batch snapshot, so bump the version before serializing them
+	batch.Version++
+	messages, err := newOutboxMessages(command, events)
+	if err != nil {
+		batch.Version--
+		return err
+	}
+
+	r.enqueueOutboxMessages(messages)
+
 	// Create a deep copy to avoid external modifications
 	batchCopy := *batch
 	itemsCopy := make([]domain.BatchItem, len(batch.Items))
 	copy(itemsCopy, batch.Items)
 	batchCopy.Items = itemsCopy
 
+	r.recordAudit(command, r.batches[batch.ID], &batchCopy, events)
+	if r.options.changeHistory {
+		r.recordChange(batch.ID, r.batches[batch.ID], &batchCopy, events)
+	}
 	r.batches[batch.ID] = &batchCopy
 	return nil
 }
@@ -47,7 +85,7 @@ func (r *BatchMemoryRepository) FindByID(id string) (*domain.Batch, error) {
 
 	batch, exists := r.batches[id]
 	if !exists {
-		return nil, fmt.Errorf("batch with ID %s not found", id)
+		return nil, domain.NewNotFoundError("batch with ID %s not found", id)
 	}
 
 	// Return a copy to avoid external modifications
@@ -115,14 +153,16 @@ func (r *BatchMemoryRepository) FindByOrderID(orderID string) (*domain.Batch, er
 		}
 	}
 
-	return nil, fmt.Errorf("no batch found containing order %s", orderID)
+	return nil, domain.NewNotFoundError("no batch found containing order %s", orderID)
 }
 
-// FindPendingBatchForProduct finds a pending batch for a product (for adding new orders)
-func (r *BatchMemoryRepository) FindPendingBatchForProduct(productID string) (*domain.Batch, error) {
+// FindPendingBatchesForProduct retrieves the pending batches of a product in
+// first-expired-first-out order
+func (r *BatchMemoryRepository) FindPendingBatchesForProduct(productID string) ([]*domain.Batch, error) {
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 
+	var result []*domain.Batch
 	for _, batch := range r.batches {
 		if batch.ProductID == productID && batch.Status == domain.BatchStatusPending {
 			// Create a copy
@@ -130,11 +170,37 @@ func (r *BatchMemoryRepository) FindPendingBatchForProduct(productID string) (*d
 			itemsCopy := make([]domain.BatchItem, len(batch.Items))
 			copy(itemsCopy, batch.Items)
 			batchCopy.Items = itemsCopy
-			return &batchCopy, nil
+			result = append(result, &batchCopy)
+		}
+	}
+
+	domain.SortFirstExpiredFirstOut(result)
+	return result, nil
+}
+
+// FindExpiringBefore retrieves the open batches whose lot expires before the cutoff
+func (r *BatchMemoryRepository) FindExpiringBefore(cutoff time.Time) ([]*domain.Batch, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var result []*domain.Batch
+	for _, batch := range r.batches {
+		if batch.Status == domain.BatchStatusCompleted || batch.Status == domain.BatchStatusCancelled ||
+			batch.Status == domain.BatchStatusDestroyed {
+			continue
+		}
+		if batch.ExpiresAt != nil && batch.ExpiresAt.Before(cutoff) {
+			// Create a copy
+			batchCopy := *batch
+			itemsCopy := make([]domain.BatchItem, len(batch.Items))
+			copy(itemsCopy, batch.Items)
+			batchCopy.Items = itemsCopy
+			result = append(result, &batchCopy)
 		}
 	}
 
-	return nil, fmt.Errorf("no pending batch found for product %s", productID)
+	domain.SortFirstExpiredFirstOut(result)
+	return result, nil
 }
 
 // Delete removes a batch from the repository
@@ -142,14 +208,154 @@ func (r *BatchMemoryRepository) Delete(id string) error {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	if _, exists := r.batches[id]; !exists {
-		return fmt.Errorf("batch with ID %s not found", id)
+	stored, exists := r.batches[id]
+	if !exists {
+		return domain.NewNotFoundError("batch with ID %s not found", id)
 	}
 
+	r.recordAudit(domain.BatchCommand{}, stored, nil, nil)
+	if r.options.changeHistory {
+		r.recordChange(id, stored, nil, nil)
+	}
 	delete(r.batches, id)
 	return nil
 }
 
+// DeleteWithEvents removes a batch, records the command in its audit trail and enqueues
+// its events in the outbox atomically
+func (r *BatchMemoryRepository) DeleteWithEvents(batch *domain.Batch, command domain.BatchCommand, events ...*domain.BatchEvent) error {
+	if batch == nil {
+		return fmt.Errorf("batch cannot be nil")
+	}
+
+	messages, err := newOutboxMessages(command, events)
+	if err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, exists := r.batches[batch.ID]; !exists {
+		return domain.NewNotFoundError("batch with ID %s not found", batch.ID)
+	}
+
+	if err := r.checkVersion(batch); err != nil {
+		return err
+	}
+
+	r.recordAudit(command, r.batches[batch.ID], nil, events)
+	if r.options.changeHistory {
+		r.recordChange(batch.ID, r.batches[batch.ID], nil, events)
+	}
+	delete(r.batches, batch.ID)
+	r.enqueueOutboxMessages(messages)
+	return nil
+}
+
+// FindByIDAt rebuilds a batch from the changes recorded up to the given time
+func (r *BatchMemoryRepository) FindByIDAt(id string, at time.Time) (*domain.Batch, error) {
+	if !r.options.changeHistory {
+		return nil, newChangeHistoryDisabledError()
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.loadBatch(id, at)
+}
+
+// FindBatchHistory returns up to limit audit entries of a batch in the order they were
+// recorded, skipping the first offset ones, and the total number of entries
+func (r *BatchMemoryRepository) FindBatchHistory(batchID string, offset, limit int) ([]*domain.BatchAuditEntry, int, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	entries := r.audit[batchID]
+	result := make([]*domain.BatchAuditEntry, 0)
+	for i := offset; i < len(entries) && len(result) < limit; i++ {
+		entryCopy := *entries[i]
+		result = append(result, &entryCopy)
+	}
+	return result, len(entries), nil
+}
+
+// recordAudit appends the command that changed a batch from its stored state to its new
+// state, nil when it is deleted, to the audit trail of the batch; the caller must hold the lock
+func (r *BatchMemoryRepository) recordAudit(command domain.BatchCommand, before, after *domain.Batch, events []*domain.BatchEvent) {
+	entry := domain.NewBatchAuditEntry(command, before, after, events, time.Now().UTC())
+	r.nextAuditID++
+	entry.ID = r.nextAuditID
+	r.audit[entry.BatchID] = append(r.audit[entry.BatchID], entry)
+}
+
+// recordChange appends the change from the stored state of a batch to its new state,
+// nil when it is deleted, to the history of the batch and takes a snapshot when one is
+// due; the caller must hold the lock
+func (r *BatchMemoryRepository) recordChange(id string, before, after *domain.Batch, events []*domain.BatchEvent) {
+	if before != nil && len(r.changes[id]) == 0 && len(r.snapshots[id]) == 0 {
+		// The batch was stored before the change history was enabled
+		r.snapshots[id] = append(r.snapshots[id], domain.BatchSnapshot{Batch: before.Clone(), TakenAt: before.UpdatedAt})
+	}
+
+	change := domain.NewBatchChange(before, after, events, time.Now().UTC())
+	r.changes[id] = append(r.changes[id], change)
+	if r.options.snapshotDue(change) {
+		r.snapshots[id] = append(r.snapshots[id], domain.BatchSnapshot{Batch: after.Clone(), TakenAt: change.RecordedAt})
+	}
+}
+
+// loadBatch rebuilds a batch from the last snapshot and the changes of its history
+// recorded up to the given time, zero for its latest state. A batch without a history
+// has not changed since the change history was enabled and is read as stored.
+// The caller must hold the lock.
+func (r *BatchMemoryRepository) loadBatch(id string, at time.Time) (*domain.Batch, error) {
+	snapshots, changes := r.snapshots[id], r.changes[id]
+	if len(snapshots) == 0 && len(changes) == 0 {
+		stored, exists := r.batches[id]
+		if !exists || !recordedBy(at, stored.UpdatedAt) {
+			return nil, newBatchNotFoundAtError(id, at)
+		}
+		return stored.Clone(), nil
+	}
+
+	var snapshot *domain.BatchSnapshot
+	for i := len(snapshots) - 1; i >= 0; i-- {
+		if recordedBy(at, snapshots[i].TakenAt) {
+			snapshot = &snapshots[i]
+			break
+		}
+	}
+	var recorded []domain.BatchChange
+	for _, change := range changes {
+		if recordedBy(at, change.RecordedAt) {
+			recorded = append(recorded, change)
+		}
+	}
+
+	batch, err := domain.ReplayBatch(snapshot, recorded)
+	if err != nil {
+		// A history that does not replay is corrupt, not a conflict the caller could retry
+		return nil, fmt.Errorf("failed to replay batch %s: %v", id, err)
+	}
+	if batch == nil {
+		return nil, newBatchNotFoundAtError(id, at)
+	}
+	return batch, nil
+}
+
+// checkVersion rejects changes made on a stale copy of the batch
+func (r *BatchMemoryRepository) checkVersion(batch *domain.Batch) error {
+	var storedVersion int64
+	if stored, exists := r.batches[batch.ID]; exists {
+		storedVersion = stored.Version
+	}
+
+	if batch.Version != storedVersion {
+		return newBatchConflictError(batch.ID, batch.Version)
+	}
+	return nil
+}
+
 // GetAll retrieves all batches
 func (r *BatchMemoryRepository) GetAll() ([]*domain.Batch, error) {
 	r.mutex.RLock()
@@ -173,4 +379,107 @@ func (r *BatchMemoryRepository) GetBatchCount() int {
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 	return len(r.batches)
+}
+
+// PendingOutboxMessages returns up to limit undelivered messages after the given ID in insertion order
+func (r *BatchMemoryRepository) PendingOutboxMessages(afterID int64, limit int) ([]*domain.OutboxMessage, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	result := make([]*domain.OutboxMessage, 0, len(r.outbox))
+	for _, message := range r.outbox {
+		if message.ID <= afterID || message.DeadAt != nil {
+			continue
+		}
+		messageCopy := *message
+		result = append(result, &messageCopy)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+// MarkOutboxMessagePublished records that a message was delivered
+func (r *BatchMemoryRepository) MarkOutboxMessagePublished(id int64) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, exists := r.outbox[id]; !exists {
+		return domain.NewNotFoundError("outbox message %d not found", id)
+	}
+
+	delete(r.outbox, id)
+	return nil
+}
+
+// MarkOutboxMessageFailed records a failed delivery and schedules the next attempt
+func (r *BatchMemoryRepository) MarkOutboxMessageFailed(id int64, reason string, nextAttemptAt time.Time) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	message, exists := r.outbox[id]
+	if !exists {
+		return domain.NewNotFoundError("outbox message %d not found", id)
+	}
+
+	message.Attempts++
+	message.LastError = reason
+	message.NextAttemptAt = nextAttemptAt
+	return nil
+}
+
+// MarkOutboxMessageDead records that a message cannot be delivered and stops retrying it
+func (r *BatchMemoryRepository) MarkOutboxMessageDead(id int64, reason string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	message, exists := r.outbox[id]
+	if !exists {
+		return domain.NewNotFoundError("outbox message %d not found", id)
+	}
+
+	deadAt := time.Now().UTC()
+	message.Attempts++
+	message.LastError = reason
+	message.DeadAt = &deadAt
+	return nil
+}
+
+// enqueueOutboxMessages assigns IDs to the messages and stores them; the caller must hold the lock
+func (r *BatchMemoryRepository) enqueueOutboxMessages(messages []*domain.OutboxMessage) {
+	for _, message := range messages {
+		r.nextOutboxID++
+		message.ID = r.nextOutboxID
+		r.outbox[message.ID] = message
+	}
+}
+
+// newOutboxMessages serializes the events into outbox messages. Events without a trace
+// context are stored with the trace of the command that raised them.
+func newOutboxMessages(command domain.BatchCommand, events []*domain.BatchEvent) ([]*domain.OutboxMessage, error) {
+	messages := make([]*domain.OutboxMessage, 0, len(events))
+	for _, event := range events {
+		if event.TraceContext.IsZero() && !command.TraceContext.IsZero() {
+			traced := *event
+			traced.TraceContext = command.TraceContext
+			event = &traced
+		}
+		message, err := domain.NewOutboxMessage(event)
+		if err != nil {
+			return nil, fmt.Errorf("failed to enqueue batch event: %w", err)
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+// newBatchConflictError reports that the batch was changed after the given version was loaded
+func newBatchConflictError(id string, version int64) error {
+	return domain.NewConflictError("batch %s was modified concurrently (version %d is stale)", id, version)
 }
\ No newline at end of file
@@ -0,0 +1,956 @@
+package drivenadapters
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+)
+
+const batchColumns = `id, product_id, status, total_items, created_at, updated_at, processed_at, version,
+	lot_number, manufactured_at, expires_at,
+	inspector, inspection_findings, inspected_at, disposition_outcome, disposition_decided_by,
+	disposition_reason, disposition_decided_at, hold_reason`
+
+const batchItemColumns = `batch_id, order_id, product_id, quantity, status, added_at, processed_at, lot_number, customer_id`
+
+const outboxColumns = `id, batch_id, event_type, payload, attempts, last_error, created_at, next_attempt_at`
+
+const batchAuditColumns = `id, batch_id, version, command, actor, source_event, event_types,
+	status_before, status_after, items, recorded_at`
+
+// BatchSQLRepository implements BatchRepository on top of a relational database
+type BatchSQLRepository struct {
+	db      *sql.DB
+	driver  string
+	options batchRepositoryOptions
+}
+
+// NewBatchSQLRepository creates a new SQL batch repository and applies pending schema migrations
+func NewBatchSQLRepository(db *sql.DB, driver string, opts ...BatchRepositoryOption) (*BatchSQLRepository, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database cannot be nil")
+	}
+
+	if driver != SQLDriverPostgres && driver != SQLDriverSQLite {
+		return nil, fmt.Errorf("unsupported SQL driver: %s", driver)
+	}
+
+	if err := migrateSQLSchema(db, driver); err != nil {
+		return nil, fmt.Errorf("failed to migrate database schema: %w", err)
+	}
+
+	return &BatchSQLRepository{
+		db:      db,
+		driver:  driver,
+		options: newBatchRepositoryOptions(opts),
+	}, nil
+}
+
+// Save stores or updates a batch together with its items
+func (r *BatchSQLRepository) Save(batch *domain.Batch) error {
+	return r.SaveWithEvents(batch, domain.BatchCommand{})
+}
+
+// SaveWithEvents stores or updates a batch, records the command in its audit trail and
+// enqueues its events in the outbox within the same transaction
+func (r *BatchSQLRepository) SaveWithEvents(batch *domain.Batch, command domain.BatchCommand, events ...*domain.BatchEvent) error {
+	if batch == nil {
+		return fmt.Errorf("batch cannot be nil")
+	}
+
+	// The events share the batch snapshot, so bump the version before serializing them
+	// and restore it if the batch is not stored
+	expectedVersion := batch.Version
+	batch.Version++
+	committed := false
+	defer func() {
+		if !committed {
+			batch.Version = expectedVersion
+		}
+	}()
+
+	messages, err := newOutboxMessages(command, events)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	before, err := r.storedBatch(tx, batch.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := r.saveBatch(tx, batch, expectedVersion); err != nil {
+		return err
+	}
+
+	if err := r.recordAudit(tx, command, before, batch, events); err != nil {
+		return err
+	}
+
+	if r.options.changeHistory {
+		if err := r.recordChange(tx, batch.ID, before, batch, events); err != nil {
+			return err
+		}
+	}
+
+	if err := r.insertOutboxMessages(tx, messages); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit batch %s: %w", batch.ID, err)
+	}
+	committed = true
+	return nil
+}
+
+// saveBatch inserts or updates the batch row and replaces its items inside the given
+// transaction. The row is only updated if it still has the expected version.
+func (r *BatchSQLRepository) saveBatch(tx *sql.Tx, batch *domain.Batch, expectedVersion int64) error {
+	var (
+		result     sql.Result
+		err        error
+		quarantine = newQuarantineRow(batch)
+	)
+	if expectedVersion == 0 {
+		result, err = tx.Exec(r.rebind(`INSERT INTO batches (`+batchColumns+`)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (id) DO NOTHING`),
+			batch.ID,
+			batch.ProductID,
+			string(batch.Status),
+			batch.TotalItems,
+			batch.CreatedAt.UTC(),
+			batch.UpdatedAt.UTC(),
+			nullTime(batch.ProcessedAt),
+			batch.Version,
+			batch.LotNumber,
+			nullTime(batch.ManufacturedAt),
+			nullTime(batch.ExpiresAt),
+			quarantine.inspector,
+			quarantine.findings,
+			quarantine.inspectedAt,
+			quarantine.outcome,
+			quarantine.decidedBy,
+			quarantine.reason,
+			quarantine.decidedAt,
+			batch.HoldReason,
+		)
+	} else {
+		result, err = tx.Exec(r.rebind(`UPDATE batches SET
+				product_id = ?,
+				status = ?,
+				total_items = ?,
+				updated_at = ?,
+				processed_at = ?,
+				version = ?,
+				lot_number = ?,
+				manufactured_at = ?,
+				expires_at = ?,
+				inspector = ?,
+				inspection_findings = ?,
+				inspected_at = ?,
+				disposition_outcome = ?,
+				disposition_decided_by = ?,
+				disposition_reason = ?,
+				disposition_decided_at = ?,
+				hold_reason = ?
+			WHERE id = ? AND version = ?`),
+			batch.ProductID,
+			string(batch.Status),
+			batch.TotalItems,
+			batch.UpdatedAt.UTC(),
+			nullTime(batch.ProcessedAt),
+			batch.Version,
+			batch.LotNumber,
+			nullTime(batch.ManufacturedAt),
+			nullTime(batch.ExpiresAt),
+			quarantine.inspector,
+			quarantine.findings,
+			quarantine.inspectedAt,
+			quarantine.outcome,
+			quarantine.decidedBy,
+			quarantine.reason,
+			quarantine.decidedAt,
+			batch.HoldReason,
+			batch.ID,
+			expectedVersion,
+		)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save batch %s: %w", batch.ID, err)
+	}
+
+	if err := requireAffected(result, newBatchConflictError(batch.ID, expectedVersion)); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(r.rebind(`DELETE FROM batch_items WHERE batch_id = ?`), batch.ID); err != nil {
+		return fmt.Errorf("failed to clear items of batch %s: %w", batch.ID, err)
+	}
+
+	insertItem := r.rebind(`INSERT INTO batch_items (position, ` + batchItemColumns + `)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	for position, item := range batch.Items {
+		if _, err := tx.Exec(insertItem,
+			position,
+			batch.ID,
+			item.OrderID,
+			item.ProductID,
+			item.Quantity,
+			item.Status,
+			item.AddedAt.UTC(),
+			nullTime(item.ProcessedAt),
+			item.LotNumber,
+			item.CustomerID,
+		); err != nil {
+			return fmt.Errorf("failed to save item %s of batch %s: %w", item.OrderID, batch.ID, err)
+		}
+	}
+	return nil
+}
+
+// FindByID retrieves a batch by its ID
+func (r *BatchSQLRepository) FindByID(id string) (*domain.Batch, error) {
+	batches, err := r.queryBatches(r.db, `WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(batches) == 0 {
+		return nil, domain.NewNotFoundError("batch with ID %s not found", id)
+	}
+	return batches[0], nil
+}
+
+// FindByProductID retrieves all batches for a specific product
+func (r *BatchSQLRepository) FindByProductID(productID string) ([]*domain.Batch, error) {
+	return r.queryBatches(r.db, `WHERE product_id = ? ORDER BY created_at, id`, productID)
+}
+
+// FindByStatus retrieves all batches with a specific status
+func (r *BatchSQLRepository) FindByStatus(status domain.BatchStatus) ([]*domain.Batch, error) {
+	return r.queryBatches(r.db, `WHERE status = ? ORDER BY created_at, id`, string(status))
+}
+
+// FindByOrderID finds the batch containing a specific order
+func (r *BatchSQLRepository) FindByOrderID(orderID string) (*domain.Batch, error) {
+	batches, err := r.queryBatches(r.db, `WHERE id IN (SELECT batch_id FROM batch_items WHERE order_id = ?) ORDER BY created_at, id`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if len(batches) == 0 {
+		return nil, domain.NewNotFoundError("no batch found containing order %s", orderID)
+	}
+	return batches[0], nil
+}
+
+// FindPendingBatchesForProduct retrieves the pending batches of a product in
+// first-expired-first-out order
+func (r *BatchSQLRepository) FindPendingBatchesForProduct(productID string) ([]*domain.Batch, error) {
+	batches, err := r.queryBatches(r.db, `WHERE product_id = ? AND status = ? ORDER BY created_at, id`,
+		productID, string(domain.BatchStatusPending))
+	if err != nil {
+		return nil, err
+	}
+	domain.SortFirstExpiredFirstOut(batches)
+	return batches, nil
+}
+
+// FindExpiringBefore retrieves the open batches whose lot expires before the cutoff
+func (r *BatchSQLRepository) FindExpiringBefore(cutoff time.Time) ([]*domain.Batch, error) {
+	return r.queryBatches(r.db, `WHERE expires_at IS NOT NULL AND expires_at < ? AND status NOT IN (?, ?, ?)
+		ORDER BY expires_at, created_at, id`,
+		cutoff.UTC(), string(domain.BatchStatusCompleted), string(domain.BatchStatusCancelled), string(domain.BatchStatusDestroyed))
+}
+
+// Delete removes a batch and its items from the repository
+func (r *BatchSQLRepository) Delete(id string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	before, err := r.storedBatch(tx, id)
+	if err != nil {
+		return err
+	}
+	if before == nil {
+		return domain.NewNotFoundError("batch with ID %s not found", id)
+	}
+
+	if err := r.recordAudit(tx, domain.BatchCommand{}, before, nil, nil); err != nil {
+		return err
+	}
+
+	if r.options.changeHistory {
+		if err := r.recordChange(tx, id, before, nil, nil); err != nil {
+			return err
+		}
+	}
+
+	if err := r.deleteBatch(tx, id); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit deletion of batch %s: %w", id, err)
+	}
+	return nil
+}
+
+// DeleteWithEvents removes a batch, records the command in its audit trail and enqueues
+// its events in the outbox within the same transaction
+func (r *BatchSQLRepository) DeleteWithEvents(batch *domain.Batch, command domain.BatchCommand, events ...*domain.BatchEvent) error {
+	if batch == nil {
+		return fmt.Errorf("batch cannot be nil")
+	}
+
+	messages, err := newOutboxMessages(command, events)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	before, err := r.storedBatch(tx, batch.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := r.checkVersion(tx, batch); err != nil {
+		return err
+	}
+
+	if err := r.recordAudit(tx, command, before, nil, events); err != nil {
+		return err
+	}
+
+	if r.options.changeHistory {
+		if err := r.recordChange(tx, batch.ID, before, nil, events); err != nil {
+			return err
+		}
+	}
+
+	if err := r.deleteBatch(tx, batch.ID); err != nil {
+		return err
+	}
+
+	if err := r.insertOutboxMessages(tx, messages); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit deletion of batch %s: %w", batch.ID, err)
+	}
+	return nil
+}
+
+// checkVersion locks the batch row by touching it and rejects stale copies of the batch
+func (r *BatchSQLRepository) checkVersion(tx *sql.Tx, batch *domain.Batch) error {
+	result, err := tx.Exec(r.rebind(`UPDATE batches SET version = version + 1 WHERE id = ? AND version = ?`),
+		batch.ID, batch.Version)
+	if err != nil {
+		return fmt.Errorf("failed to check version of batch %s: %w", batch.ID, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to read affected rows: %w", err)
+	}
+	if affected > 0 {
+		return nil
+	}
+
+	var count int
+	if err := tx.QueryRow(r.rebind(`SELECT COUNT(*) FROM batches WHERE id = ?`), batch.ID).Scan(&count); err != nil {
+		return fmt.Errorf("failed to check batch %s: %w", batch.ID, err)
+	}
+	if count == 0 {
+		return domain.NewNotFoundError("batch with ID %s not found", batch.ID)
+	}
+	return newBatchConflictError(batch.ID, batch.Version)
+}
+
+// deleteBatch removes the batch row and its items inside the given transaction
+func (r *BatchSQLRepository) deleteBatch(tx *sql.Tx, id string) error {
+	if _, err := tx.Exec(r.rebind(`DELETE FROM batch_items WHERE batch_id = ?`), id); err != nil {
+		return fmt.Errorf("failed to delete items of batch %s: %w", id, err)
+	}
+
+	result, err := tx.Exec(r.rebind(`DELETE FROM batches WHERE id = ?`), id)
+	if err != nil {
+		return fmt.Errorf("failed to delete batch %s: %w", id, err)
+	}
+
+	return requireAffected(result, domain.NewNotFoundError("batch with ID %s not found", id))
+}
+
+// FindBatchHistory returns up to limit audit entries of a batch in the order they were
+// recorded, skipping the first offset ones, and the total number of entries
+func (r *BatchSQLRepository) FindBatchHistory(batchID string, offset, limit int) ([]*domain.BatchAuditEntry, int, error) {
+	var total int
+	if err := r.db.QueryRow(r.rebind(`SELECT COUNT(*) FROM batch_audit_log WHERE batch_id = ?`), batchID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count history of batch %s: %w", batchID, err)
+	}
+
+	rows, err := r.db.Query(r.rebind(`SELECT `+batchAuditColumns+` FROM batch_audit_log
+		WHERE batch_id = ? ORDER BY id LIMIT ? OFFSET ?`), batchID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query history of batch %s: %w", batchID, err)
+	}
+	defer rows.Close()
+
+	entries := make([]*domain.BatchAuditEntry, 0)
+	for rows.Next() {
+		var (
+			entry        domain.BatchAuditEntry
+			command      string
+			eventTypes   string
+			statusBefore string
+			statusAfter  string
+			items        string
+		)
+		if err := rows.Scan(
+			&entry.ID,
+			&entry.BatchID,
+			&entry.Version,
+			&command,
+			&entry.Command.Actor,
+			&entry.Command.SourceEvent,
+			&eventTypes,
+			&statusBefore,
+			&statusAfter,
+			&items,
+			&entry.RecordedAt,
+		); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan audit entry of batch %s: %w", batchID, err)
+		}
+		entry.Command.Name = domain.BatchCommandName(command)
+		entry.StatusBefore = domain.BatchStatus(statusBefore)
+		entry.StatusAfter = domain.BatchStatus(statusAfter)
+		if err := json.Unmarshal([]byte(eventTypes), &entry.EventTypes); err != nil {
+			return nil, 0, fmt.Errorf("failed to decode event types of audit entry %d: %w", entry.ID, err)
+		}
+		if err := json.Unmarshal([]byte(items), &entry.Items); err != nil {
+			return nil, 0, fmt.Errorf("failed to decode items of audit entry %d: %w", entry.ID, err)
+		}
+		entries = append(entries, &entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to read history of batch %s: %w", batchID, err)
+	}
+	return entries, total, nil
+}
+
+// recordAudit appends the command that changed a batch from its stored state to its new
+// state, nil when it is deleted, to the audit trail inside the given transaction
+func (r *BatchSQLRepository) recordAudit(tx *sql.Tx, command domain.BatchCommand, before, after *domain.Batch, events []*domain.BatchEvent) error {
+	entry := domain.NewBatchAuditEntry(command, before, after, events, time.Now().UTC())
+
+	eventTypes, err := json.Marshal(entry.EventTypes)
+	if err != nil {
+		return fmt.Errorf("failed to serialize event types of batch %s: %w", entry.BatchID, err)
+	}
+	items, err := json.Marshal(entry.Items)
+	if err != nil {
+		return fmt.Errorf("failed to serialize item changes of batch %s: %w", entry.BatchID, err)
+	}
+
+	if _, err := tx.Exec(r.rebind(`INSERT INTO batch_audit_log
+		(batch_id, version, command, actor, source_event, event_types, status_before, status_after, items, recorded_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+		entry.BatchID,
+		entry.Version,
+		string(entry.Command.Name),
+		entry.Command.Actor,
+		entry.Command.SourceEvent,
+		string(eventTypes),
+		string(entry.StatusBefore),
+		string(entry.StatusAfter),
+		string(items),
+		entry.RecordedAt,
+	); err != nil {
+		return fmt.Errorf("failed to record history of batch %s: %w", entry.BatchID, err)
+	}
+	return nil
+}
+
+// FindByIDAt rebuilds a batch from the changes recorded up to the given time
+func (r *BatchSQLRepository) FindByIDAt(id string, at time.Time) (*domain.Batch, error) {
+	if !r.options.changeHistory {
+		return nil, newChangeHistoryDisabledError()
+	}
+	return r.loadBatch(r.db, id, at)
+}
+
+// storedBatch loads the stored state of a batch, nil if it does not exist
+func (r *BatchSQLRepository) storedBatch(q sqlQuerier, id string) (*domain.Batch, error) {
+	batches, err := r.queryBatches(q, `WHERE id = ?`, id)
+	if err != nil || len(batches) == 0 {
+		return nil, err
+	}
+	return batches[0], nil
+}
+
+// recordChange appends the change from the stored state of a batch to its new state,
+// nil when it is deleted, to the history of the batch and takes a snapshot when one is
+// due, inside the given transaction
+func (r *BatchSQLRepository) recordChange(tx *sql.Tx, id string, before, after *domain.Batch, events []*domain.BatchEvent) error {
+	if before != nil {
+		length, err := r.streamLength(tx, id)
+		if err != nil {
+			return err
+		}
+		if length == 0 {
+			// The batch was stored before the change history was enabled
+			if err := r.insertSnapshot(tx, domain.BatchSnapshot{Batch: before, TakenAt: before.UpdatedAt}); err != nil {
+				return err
+			}
+		}
+	}
+
+	change := domain.NewBatchChange(before, after, events, time.Now().UTC())
+	payload, err := json.Marshal(change)
+	if err != nil {
+		return fmt.Errorf("failed to serialize change %d of batch %s: %w", change.Version, id, err)
+	}
+	if _, err := tx.Exec(r.rebind(`INSERT INTO batch_changes (batch_id, version, payload, recorded_at) VALUES (?, ?, ?, ?)`),
+		id, change.Version, string(payload), change.RecordedAt); err != nil {
+		return fmt.Errorf("failed to record change %d of batch %s: %w", change.Version, id, err)
+	}
+
+	if r.options.snapshotDue(change) {
+		return r.insertSnapshot(tx, domain.BatchSnapshot{Batch: after, TakenAt: change.RecordedAt})
+	}
+	return nil
+}
+
+// insertSnapshot stores a snapshot of a batch inside the given transaction
+func (r *BatchSQLRepository) insertSnapshot(tx *sql.Tx, snapshot domain.BatchSnapshot) error {
+	payload, err := json.Marshal(snapshot.Batch)
+	if err != nil {
+		return fmt.Errorf("failed to serialize snapshot of batch %s: %w", snapshot.Batch.ID, err)
+	}
+	if _, err := tx.Exec(r.rebind(`INSERT INTO batch_snapshots (batch_id, version, payload, taken_at) VALUES (?, ?, ?, ?)`),
+		snapshot.Batch.ID, snapshot.Batch.Version, string(payload), snapshot.TakenAt.UTC()); err != nil {
+		return fmt.Errorf("failed to store snapshot %d of batch %s: %w", snapshot.Batch.Version, snapshot.Batch.ID, err)
+	}
+	return nil
+}
+
+// streamLength counts the changes and snapshots in the history of a batch
+func (r *BatchSQLRepository) streamLength(q sqlQuerier, id string) (int, error) {
+	var length int
+	if err := q.QueryRow(r.rebind(`SELECT
+			(SELECT COUNT(*) FROM batch_changes WHERE batch_id = ?) +
+			(SELECT COUNT(*) FROM batch_snapshots WHERE batch_id = ?)`), id, id).Scan(&length); err != nil {
+		return 0, fmt.Errorf("failed to read history of batch %s: %w", id, err)
+	}
+	return length, nil
+}
+
+// loadBatch rebuilds a batch from the last snapshot and the changes of its history
+// recorded up to the given time, zero for its latest state. A batch without a history
+// has not changed since the change history was enabled and is read as stored.
+func (r *BatchSQLRepository) loadBatch(q sqlQuerier, id string, at time.Time) (*domain.Batch, error) {
+	timeClause, args := "", []interface{}{id}
+	if !at.IsZero() {
+		timeClause, args = ` AND taken_at <= ?`, append(args, at.UTC())
+	}
+
+	var (
+		snapshot *domain.BatchSnapshot
+		payload  string
+		takenAt  time.Time
+	)
+	err := q.QueryRow(r.rebind(`SELECT payload, taken_at FROM batch_snapshots
+		WHERE batch_id = ?`+timeClause+` ORDER BY version DESC LIMIT 1`), args...).Scan(&payload, &takenAt)
+	switch {
+	case err == sql.ErrNoRows:
+	case err != nil:
+		return nil, fmt.Errorf("failed to load snapshot of batch %s: %w", id, err)
+	default:
+		snapshot = &domain.BatchSnapshot{TakenAt: takenAt}
+		if err := json.Unmarshal([]byte(payload), &snapshot.Batch); err != nil {
+			return nil, fmt.Errorf("failed to decode snapshot of batch %s: %w", id, err)
+		}
+	}
+
+	var afterVersion int64
+	if snapshot != nil {
+		afterVersion = snapshot.Batch.Version
+	}
+	timeClause, args = "", []interface{}{id, afterVersion}
+	if !at.IsZero() {
+		timeClause, args = ` AND recorded_at <= ?`, append(args, at.UTC())
+	}
+	rows, err := q.Query(r.rebind(`SELECT payload FROM batch_changes
+		WHERE batch_id = ? AND version > ?`+timeClause+` ORDER BY version`), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load changes of batch %s: %w", id, err)
+	}
+	defer rows.Close()
+
+	var changes []domain.BatchChange
+	for rows.Next() {
+		var change domain.BatchChange
+		if err := rows.Scan(&payload); err != nil {
+			return nil, fmt.Errorf("failed to scan change of batch %s: %w", id, err)
+		}
+		if err := json.Unmarshal([]byte(payload), &change); err != nil {
+			return nil, fmt.Errorf("failed to decode change of batch %s: %w", id, err)
+		}
+		changes = append(changes, change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read changes of batch %s: %w", id, err)
+	}
+
+	if snapshot == nil && len(changes) == 0 {
+		length, err := r.streamLength(q, id)
+		if err != nil {
+			return nil, err
+		}
+		if length > 0 {
+			return nil, newBatchNotFoundAtError(id, at)
+		}
+		stored, err := r.storedBatch(q, id)
+		if err != nil {
+			return nil, err
+		}
+		if stored == nil || !recordedBy(at, stored.UpdatedAt) {
+			return nil, newBatchNotFoundAtError(id, at)
+		}
+		return stored, nil
+	}
+
+	batch, err := domain.ReplayBatch(snapshot, changes)
+	if err != nil {
+		// A history that does not replay is corrupt, not a conflict the caller could retry
+		return nil, fmt.Errorf("failed to replay batch %s: %v", id, err)
+	}
+	if batch == nil {
+		return nil, newBatchNotFoundAtError(id, at)
+	}
+	return batch, nil
+}
+
+// GetAll retrieves all batches
+func (r *BatchSQLRepository) GetAll() ([]*domain.Batch, error) {
+	return r.queryBatches(r.db, `ORDER BY created_at, id`)
+}
+
+// queryBatches loads the batches matching the given clause and attaches their items
+func (r *BatchSQLRepository) queryBatches(q sqlQuerier, clause string, args ...interface{}) ([]*domain.Batch, error) {
+	rows, err := q.Query(r.rebind(`SELECT `+batchColumns+` FROM batches `+clause), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query batches: %w", err)
+	}
+	defer rows.Close()
+
+	var batches []*domain.Batch
+	index := make(map[string]*domain.Batch)
+	for rows.Next() {
+		var (
+			batch          domain.Batch
+			status         string
+			processedAt    sql.NullTime
+			manufacturedAt sql.NullTime
+			expiresAt      sql.NullTime
+			quarantine     quarantineRow
+		)
+		if err := rows.Scan(
+			&batch.ID,
+			&batch.ProductID,
+			&status,
+			&batch.TotalItems,
+			&batch.CreatedAt,
+			&batch.UpdatedAt,
+			&processedAt,
+			&batch.Version,
+			&batch.LotNumber,
+			&manufacturedAt,
+			&expiresAt,
+			&quarantine.inspector,
+			&quarantine.findings,
+			&quarantine.inspectedAt,
+			&quarantine.outcome,
+			&quarantine.decidedBy,
+			&quarantine.reason,
+			&quarantine.decidedAt,
+			&batch.HoldReason,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan batch: %w", err)
+		}
+		batch.Status = domain.BatchStatus(status)
+		batch.ProcessedAt = timePtr(processedAt)
+		batch.ManufacturedAt = timePtr(manufacturedAt)
+		batch.ExpiresAt = timePtr(expiresAt)
+		quarantine.applyTo(&batch)
+		batch.Items = make([]domain.BatchItem, 0)
+
+		batches = append(batches, &batch)
+		index[batch.ID] = &batch
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read batches: %w", err)
+	}
+
+	if len(batches) == 0 {
+		return batches, nil
+	}
+
+	if err := r.loadItems(q, index); err != nil {
+		return nil, err
+	}
+	return batches, nil
+}
+
+// loadItems fetches the items of the indexed batches in a single query
+func (r *BatchSQLRepository) loadItems(q sqlQuerier, index map[string]*domain.Batch) error {
+	ids := make([]interface{}, 0, len(index))
+	for id := range index {
+		ids = append(ids, id)
+	}
+
+	query := `SELECT ` + batchItemColumns + ` FROM batch_items
+		WHERE batch_id IN (` + placeholders(len(ids)) + `)
+		ORDER BY batch_id, position`
+	rows, err := q.Query(r.rebind(query), ids...)
+	if err != nil {
+		return fmt.Errorf("failed to query batch items: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			batchID     string
+			item        domain.BatchItem
+			processedAt sql.NullTime
+		)
+		if err := rows.Scan(
+			&batchID,
+			&item.OrderID,
+			&item.ProductID,
+			&item.Quantity,
+			&item.Status,
+			&item.AddedAt,
+			&processedAt,
+			&item.LotNumber,
+			&item.CustomerID,
+		); err != nil {
+			return fmt.Errorf("failed to scan batch item: %w", err)
+		}
+		item.ProcessedAt = timePtr(processedAt)
+
+		if batch, ok := index[batchID]; ok {
+			batch.Items = append(batch.Items, item)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read batch items: %w", err)
+	}
+	return nil
+}
+
+// PendingOutboxMessages returns up to limit undelivered messages after the given ID in insertion order
+func (r *BatchSQLRepository) PendingOutboxMessages(afterID int64, limit int) ([]*domain.OutboxMessage, error) {
+	query := `SELECT ` + outboxColumns + ` FROM outbox_messages
+		WHERE published_at IS NULL AND dead_at IS NULL AND id > ?
+		ORDER BY id`
+	args := []interface{}{afterID}
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+
+	rows, err := r.db.Query(r.rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query outbox messages: %w", err)
+	}
+	defer rows.Close()
+
+	messages := make([]*domain.OutboxMessage, 0)
+	for rows.Next() {
+		var (
+			message   domain.OutboxMessage
+			eventType string
+			payload   string
+		)
+		if err := rows.Scan(
+			&message.ID,
+			&message.BatchID,
+			&eventType,
+			&payload,
+			&message.Attempts,
+			&message.LastError,
+			&message.CreatedAt,
+			&message.NextAttemptAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan outbox message: %w", err)
+		}
+		message.EventType = domain.BatchEventType(eventType)
+		message.Payload = []byte(payload)
+		messages = append(messages, &message)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read outbox messages: %w", err)
+	}
+	return messages, nil
+}
+
+// MarkOutboxMessagePublished records that a message was delivered
+func (r *BatchSQLRepository) MarkOutboxMessagePublished(id int64) error {
+	result, err := r.db.Exec(r.rebind(`UPDATE outbox_messages SET published_at = ? WHERE id = ?`),
+		time.Now().UTC(), id)
+	if err != nil {
+		return fmt.Errorf("failed to mark outbox message %d as published: %w", id, err)
+	}
+	return requireAffected(result, domain.NewNotFoundError("outbox message %d not found", id))
+}
+
+// MarkOutboxMessageFailed records a failed delivery and schedules the next attempt
+func (r *BatchSQLRepository) MarkOutboxMessageFailed(id int64, reason string, nextAttemptAt time.Time) error {
+	result, err := r.db.Exec(r.rebind(`UPDATE outbox_messages
+		SET attempts = attempts + 1, last_error = ?, next_attempt_at = ?
+		WHERE id = ?`), reason, nextAttemptAt.UTC(), id)
+	if err != nil {
+		return fmt.Errorf("failed to mark outbox message %d as failed: %w", id, err)
+	}
+	return requireAffected(result, domain.NewNotFoundError("outbox message %d not found", id))
+}
+
+// MarkOutboxMessageDead records that a message cannot be delivered and stops retrying it
+func (r *BatchSQLRepository) MarkOutboxMessageDead(id int64, reason string) error {
+	result, err := r.db.Exec(r.rebind(`UPDATE outbox_messages
+		SET attempts = attempts + 1, last_error = ?, dead_at = ?
+		WHERE id = ?`), reason, time.Now().UTC(), id)
+	if err != nil {
+		return fmt.Errorf("failed to mark outbox message %d as dead: %w", id, err)
+	}
+	return requireAffected(result, domain.NewNotFoundError("outbox message %d not found", id))
+}
+
+// insertOutboxMessages stores the messages inside the given transaction
+func (r *BatchSQLRepository) insertOutboxMessages(tx *sql.Tx, messages []*domain.OutboxMessage) error {
+	insert := r.rebind(`INSERT INTO outbox_messages
+		(batch_id, event_type, payload, attempts, last_error, created_at, next_attempt_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`)
+	for _, message := range messages {
+		if _, err := tx.Exec(insert,
+			message.BatchID,
+			string(message.EventType),
+			string(message.Payload),
+			message.Attempts,
+			message.LastError,
+			message.CreatedAt.UTC(),
+			message.NextAttemptAt.UTC(),
+		); err != nil {
+			return fmt.Errorf("failed to enqueue %s event for batch %s: %w", message.EventType, message.BatchID, err)
+		}
+	}
+	return nil
+}
+
+// rebind adapts a query written with '?' placeholders to the repository driver
+func (r *BatchSQLRepository) rebind(query string) string {
+	return rebindQuery(r.driver, strings.TrimSpace(query))
+}
+
+// sqlQuerier is implemented by both *sql.DB and *sql.Tx
+type sqlQuerier interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// requireAffected returns notFoundErr when the statement did not affect any row
+func requireAffected(result sql.Result, notFoundErr error) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to read affected rows: %w", err)
+	}
+	if affected == 0 {
+		return notFoundErr
+	}
+	return nil
+}
+
+// quarantineRow holds the quarantine columns of a batch row. The inspection and the
+// disposition are only present when their timestamp is set.
+type quarantineRow struct {
+	inspector   string
+	findings    string
+	inspectedAt sql.NullTime
+	outcome     string
+	decidedBy   string
+	reason      string
+	decidedAt   sql.NullTime
+}
+
+// newQuarantineRow flattens the inspection and disposition of a batch into columns
+func newQuarantineRow(batch *domain.Batch) quarantineRow {
+	var row quarantineRow
+	if inspection := batch.Inspection; inspection != nil {
+		row.inspector = inspection.Inspector
+		row.findings = inspection.Findings
+		row.inspectedAt = nullTime(&inspection.InspectedAt)
+	}
+	if disposition := batch.Disposition; disposition != nil {
+		row.outcome = string(disposition.Outcome)
+		row.decidedBy = disposition.DecidedBy
+		row.reason = disposition.Reason
+		row.decidedAt = nullTime(&disposition.DecidedAt)
+	}
+	return row
+}
+
+// applyTo restores the inspection and disposition of a batch from its columns
+func (row quarantineRow) applyTo(batch *domain.Batch) {
+	if row.inspectedAt.Valid {
+		batch.Inspection = &domain.BatchInspection{
+			Inspector:   row.inspector,
+			Findings:    row.findings,
+			InspectedAt: row.inspectedAt.Time,
+		}
+	}
+	if row.decidedAt.Valid {
+		batch.Disposition = &domain.QuarantineDisposition{
+			Outcome:   domain.QuarantineOutcome(row.outcome),
+			DecidedBy: row.decidedBy,
+			Reason:    row.reason,
+			DecidedAt: row.decidedAt.Time,
+		}
+	}
+}
+
+// nullTime converts an optional time into a value suitable for a nullable column
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
+
+// timePtr converts a nullable column value into an optional time
+func timePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	value := t.Time
+	return &value
+}
@@ -0,0 +1,642 @@
+package drivenadapters
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+)
+
+// newTestSQLDB opens an embedded SQLite database stored in a temporary directory
+func newTestSQLDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dsn := filepath.Join(t.TempDir(), "warehouse.db") + "?_foreign_keys=on&_busy_timeout=5000"
+	db, err := sql.Open(SQLDriverSQLite, dsn)
+	if err != nil {
+		t.Fatalf("Failed to open SQLite database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func newTestSQLRepository(t *testing.T) *BatchSQLRepository {
+	t.Helper()
+
+	repo, err := NewBatchSQLRepository(newTestSQLDB(t), SQLDriverSQLite)
+	if err != nil {
+		t.Fatalf("Failed to create SQL repository: %v", err)
+	}
+	return repo
+}
+
+func TestBatchSQLRepository_SaveAndFindByID(t *testing.T) {
+	repo := newTestSQLRepository(t)
+
+	batch := domain.NewBatch("batch-1", "product-1")
+	if err := batch.AddItem("order-1", "product-1", 5, "allocated"); err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+	if err := batch.AddItem("order-2", "product-1", 3, "allocated"); err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+	if err := batch.UpdateItemStatus("order-2", "shipped"); err != nil {
+		t.Fatalf("Failed to update item: %v", err)
+	}
+
+	if err := repo.Save(batch); err != nil {
+		t.Fatalf("Failed to save batch: %v", err)
+	}
+
+	found, err := repo.FindByID("batch-1")
+	if err != nil {
+		t.Fatalf("Failed to find batch: %v", err)
+	}
+
+	if found.ProductID != "product-1" {
+		t.Errorf("Expected product ID product-1, got %s", found.ProductID)
+	}
+	if found.Status != domain.BatchStatusPending {
+		t.Errorf("Expected status %s, got %s", domain.BatchStatusPending, found.Status)
+	}
+	if found.TotalItems != 2 || len(found.Items) != 2 {
+		t.Fatalf("Expected 2 items, got total=%d len=%d", found.TotalItems, len(found.Items))
+	}
+	if found.Items[0].OrderID != "order-1" || found.Items[1].OrderID != "order-2" {
+		t.Errorf("Expected items to keep their order, got %s, %s", found.Items[0].OrderID, found.Items[1].OrderID)
+	}
+	if found.Items[1].ProcessedAt == nil {
+		t.Error("Expected processed_at to be stored for shipped item")
+	}
+	if !found.CreatedAt.Equal(batch.CreatedAt) {
+		t.Errorf("Expected created_at %v, got %v", batch.CreatedAt, found.CreatedAt)
+	}
+	if found.GetTotalQuantity() != 8 {
+		t.Errorf("Expected total quantity 8, got %d", found.GetTotalQuantity())
+	}
+}
+
+func TestBatchSQLRepository_SaveUpdatesExistingBatch(t *testing.T) {
+	repo := newTestSQLRepository(t)
+
+	batch := domain.NewBatch("batch-1", "product-1")
+	batch.AddItem("order-1", "product-1", 5, "allocated")
+	batch.AddItem("order-2", "product-1", 2, "allocated")
+	if err := repo.Save(batch); err != nil {
+		t.Fatalf("Failed to save batch: %v", err)
+	}
+
+	if err := batch.RemoveItem("order-1"); err != nil {
+		t.Fatalf("Failed to remove item: %v", err)
+	}
+	if err := batch.StartProcessing(); err != nil {
+		t.Fatalf("Failed to start processing: %v", err)
+	}
+	if err := batch.Complete(); err != nil {
+		t.Fatalf("Failed to complete batch: %v", err)
+	}
+	if err := repo.Save(batch); err != nil {
+		t.Fatalf("Failed to save updated batch: %v", err)
+	}
+
+	found, err := repo.FindByID("batch-1")
+	if err != nil {
+		t.Fatalf("Failed to find batch: %v", err)
+	}
+	if found.Status != domain.BatchStatusCompleted {
+		t.Errorf("Expected status %s, got %s", domain.BatchStatusCompleted, found.Status)
+	}
+	if found.ProcessedAt == nil {
+		t.Error("Expected processed_at to be set")
+	}
+	if len(found.Items) != 1 || found.Items[0].OrderID != "order-2" {
+		t.Errorf("Expected only order-2 to remain, got %+v", found.Items)
+	}
+}
+
+func TestBatchSQLRepository_Queries(t *testing.T) {
+	repo := newTestSQLRepository(t)
+
+	pending := domain.NewBatch("batch-pending", "product-1")
+	pending.AddItem("order-1", "product-1", 1, "allocated")
+
+	processing := domain.NewBatch("batch-processing", "product-1")
+	processing.CreatedAt = pending.CreatedAt.Add(-time.Hour)
+	processing.AddItem("order-2", "product-1", 1, "allocated")
+	processing.StartProcessing()
+
+	other := domain.NewBatch("batch-other", "product-2")
+	other.AddItem("order-3", "product-2", 1, "allocated")
+
+	for _, batch := range []*domain.Batch{pending, processing, other} {
+		if err := repo.Save(batch); err != nil {
+			t.Fatalf("Failed to save batch %s: %v", batch.ID, err)
+		}
+	}
+
+	byProduct, err := repo.FindByProductID("product-1")
+	if err != nil {
+		t.Fatalf("FindByProductID failed: %v", err)
+	}
+	if len(byProduct) != 2 {
+		t.Errorf("Expected 2 batches for product-1, got %d", len(byProduct))
+	}
+
+	byStatus, err := repo.FindByStatus(domain.BatchStatusProcessing)
+	if err != nil {
+		t.Fatalf("FindByStatus failed: %v", err)
+	}
+	if len(byStatus) != 1 || byStatus[0].ID != "batch-processing" {
+		t.Errorf("Expected batch-processing, got %+v", byStatus)
+	}
+
+	byOrder, err := repo.FindByOrderID("order-3")
+	if err != nil {
+		t.Fatalf("FindByOrderID failed: %v", err)
+	}
+	if byOrder.ID != "batch-other" || len(byOrder.Items) != 1 {
+		t.Errorf("Expected batch-other with its item, got %+v", byOrder)
+	}
+
+	if _, err := repo.FindByOrderID("missing-order"); err == nil {
+		t.Error("Expected error for unknown order")
+	}
+
+	pendingFound, err := repo.FindPendingBatchesForProduct("product-1")
+	if err != nil {
+		t.Fatalf("FindPendingBatchesForProduct failed: %v", err)
+	}
+	if len(pendingFound) != 1 || pendingFound[0].ID != "batch-pending" {
+		t.Errorf("Expected batch-pending, got %+v", pendingFound)
+	}
+
+	if none, err := repo.FindPendingBatchesForProduct("product-3"); err != nil || len(none) != 0 {
+		t.Errorf("Expected no pending batches, got %+v (err: %v)", none, err)
+	}
+
+	all, err := repo.GetAll()
+	if err != nil {
+		t.Fatalf("GetAll failed: %v", err)
+	}
+	if len(all) != 3 {
+		t.Errorf("Expected 3 batches, got %d", len(all))
+	}
+}
+
+func TestBatchSQLRepository_LotsAndExpiry(t *testing.T) {
+	repo := newTestSQLRepository(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	late, err := domain.NewLotBatch("batch-late", "product-1", "LOT-LATE", now.AddDate(-1, 0, 0), now.AddDate(0, 6, 0), now)
+	if err != nil {
+		t.Fatalf("Failed to create lot batch: %v", err)
+	}
+	soon, err := domain.NewLotBatch("batch-soon", "product-1", "LOT-SOON", now.AddDate(-1, 0, 0), now.AddDate(0, 0, 10), now)
+	if err != nil {
+		t.Fatalf("Failed to create lot batch: %v", err)
+	}
+	if err := soon.AddItem("order-1", "product-1", 2, domain.ItemStatusAllocated); err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+	untracked := domain.NewBatch("batch-untracked", "product-1")
+	untracked.CreatedAt = now.Add(-time.Hour)
+
+	for _, batch := range []*domain.Batch{late, soon, untracked} {
+		if err := repo.Save(batch); err != nil {
+			t.Fatalf("Failed to save batch %s: %v", batch.ID, err)
+		}
+	}
+
+	found, err := repo.FindByID("batch-soon")
+	if err != nil {
+		t.Fatalf("Failed to find batch: %v", err)
+	}
+	if found.LotNumber != "LOT-SOON" || found.ExpiresAt == nil || !found.ExpiresAt.Equal(*soon.ExpiresAt) {
+		t.Errorf("Expected lot LOT-SOON expiring %v, got %s expiring %v", soon.ExpiresAt, found.LotNumber, found.ExpiresAt)
+	}
+	if found.ManufacturedAt == nil || !found.ManufacturedAt.Equal(*soon.ManufacturedAt) {
+		t.Errorf("Expected manufactured_at %v, got %v", soon.ManufacturedAt, found.ManufacturedAt)
+	}
+	if found.Items[0].LotNumber != "LOT-SOON" {
+		t.Errorf("Expected item to keep its lot, got %q", found.Items[0].LotNumber)
+	}
+
+	pending, err := repo.FindPendingBatchesForProduct("product-1")
+	if err != nil {
+		t.Fatalf("FindPendingBatchesForProduct failed: %v", err)
+	}
+	var ids []string
+	for _, batch := range pending {
+		ids = append(ids, batch.ID)
+	}
+	if len(ids) != 3 || ids[0] != "batch-soon" || ids[1] != "batch-late" || ids[2] != "batch-untracked" {
+		t.Errorf("Expected first-expired-first-out order, got %v", ids)
+	}
+
+	expiring, err := repo.FindExpiringBefore(now.AddDate(0, 1, 0))
+	if err != nil {
+		t.Fatalf("FindExpiringBefore failed: %v", err)
+	}
+	if len(expiring) != 1 || expiring[0].ID != "batch-soon" {
+		t.Errorf("Expected only batch-soon to expire within a month, got %+v", expiring)
+	}
+
+	if err := soon.Cancel(); err != nil {
+		t.Fatalf("Failed to cancel batch: %v", err)
+	}
+	if err := repo.Save(soon); err != nil {
+		t.Fatalf("Failed to save cancelled batch: %v", err)
+	}
+	expiring, err = repo.FindExpiringBefore(now.AddDate(0, 1, 0))
+	if err != nil {
+		t.Fatalf("FindExpiringBefore failed: %v", err)
+	}
+	if len(expiring) != 0 {
+		t.Errorf("Expected cancelled batches not to be reported, got %+v", expiring)
+	}
+}
+
+func TestBatchSQLRepository_Quarantine(t *testing.T) {
+	repo := newTestSQLRepository(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	batch := domain.NewBatch("batch-1", "product-1")
+	for _, orderID := range []string{"order-1", "order-2"} {
+		if err := batch.AddItem(orderID, "product-1", 1, domain.ItemStatusAllocated); err != nil {
+			t.Fatalf("Failed to add item: %v", err)
+		}
+	}
+	if err := repo.Save(batch); err != nil {
+		t.Fatalf("Failed to save batch: %v", err)
+	}
+
+	found, _ := repo.FindByID("batch-1")
+	if found.Inspection != nil || found.Disposition != nil {
+		t.Errorf("Expected no quarantine on a new batch, got %+v %+v", found.Inspection, found.Disposition)
+	}
+
+	if err := batch.MarkAsDamaged(); err != nil {
+		t.Fatalf("Failed to mark batch as damaged: %v", err)
+	}
+	if err := batch.RecordInspection("qa-1", "cold-chain excursion of 3h at 12C", now); err != nil {
+		t.Fatalf("Failed to record inspection: %v", err)
+	}
+	if _, err := batch.WriteOff([]string{"order-2"}, "qa-lead", "temperature logger out of range", now.Add(time.Hour)); err != nil {
+		t.Fatalf("Failed to write off: %v", err)
+	}
+	if err := repo.Save(batch); err != nil {
+		t.Fatalf("Failed to save batch: %v", err)
+	}
+
+	found, err := repo.FindByID("batch-1")
+	if err != nil {
+		t.Fatalf("Failed to find batch: %v", err)
+	}
+	if found.Inspection == nil || found.Inspection.Inspector != "qa-1" || !found.Inspection.InspectedAt.Equal(now) {
+		t.Errorf("Expected the inspection of qa-1 to be stored, got %+v", found.Inspection)
+	}
+	if found.Disposition == nil || found.Disposition.Outcome != domain.QuarantineOutcomePartialWriteOff ||
+		found.Disposition.Reason != "temperature logger out of range" || !found.Disposition.DecidedAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("Expected the partial write-off to be stored, got %+v", found.Disposition)
+	}
+	if found.Items[0].Status != domain.ItemStatusQuarantineReleased || found.Items[1].Status != domain.ItemStatusWrittenOff {
+		t.Errorf("Expected order-1 released and order-2 written off, got %+v", found.Items)
+	}
+}
+
+func TestBatchSQLRepository_HoldAndCustomers(t *testing.T) {
+	repo := newTestSQLRepository(t)
+
+	batch := domain.NewBatch("batch-1", "product-1")
+	if err := batch.AddItem("order-1", "product-1", 1, domain.ItemStatusAllocated); err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+	if err := batch.SetItemCustomer("order-1", "customer-1"); err != nil {
+		t.Fatalf("Failed to set customer: %v", err)
+	}
+	if err := batch.PlaceOnHold("recall RECALL-1: contamination", time.Now()); err != nil {
+		t.Fatalf("Failed to put batch on hold: %v", err)
+	}
+	if err := repo.Save(batch); err != nil {
+		t.Fatalf("Failed to save batch: %v", err)
+	}
+
+	found, err := repo.FindByID("batch-1")
+	if err != nil {
+		t.Fatalf("Failed to find batch: %v", err)
+	}
+	if found.Status != domain.BatchStatusOnHold || found.HoldReason != "recall RECALL-1: contamination" {
+		t.Errorf("Expected batch on hold with its reason, got %s %q", found.Status, found.HoldReason)
+	}
+	if found.Items[0].CustomerID != "customer-1" {
+		t.Errorf("Expected item to keep its customer, got %q", found.Items[0].CustomerID)
+	}
+
+	// Held stock is not offered for allocation
+	pending, err := repo.FindPendingBatchesForProduct("product-1")
+	if err != nil {
+		t.Fatalf("Failed to find pending batches: %v", err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("Expected no pending batch, got %d", len(pending))
+	}
+}
+
+func TestBatchSQLRepository_Delete(t *testing.T) {
+	repo := newTestSQLRepository(t)
+
+	batch := domain.NewBatch("batch-1", "product-1")
+	batch.AddItem("order-1", "product-1", 1, "allocated")
+	if err := repo.Save(batch); err != nil {
+		t.Fatalf("Failed to save batch: %v", err)
+	}
+
+	if err := repo.Delete("batch-1"); err != nil {
+		t.Fatalf("Failed to delete batch: %v", err)
+	}
+
+	if _, err := repo.FindByID("batch-1"); err == nil {
+		t.Error("Expected batch to be deleted")
+	}
+	if _, err := repo.FindByOrderID("order-1"); err == nil {
+		t.Error("Expected batch items to be deleted")
+	}
+	if err := repo.Delete("batch-1"); err == nil {
+		t.Error("Expected error when deleting a missing batch")
+	}
+}
+
+func TestBatchSQLRepository_MigrationsAreIdempotent(t *testing.T) {
+	db := newTestSQLDB(t)
+
+	if _, err := NewBatchSQLRepository(db, SQLDriverSQLite); err != nil {
+		t.Fatalf("First migration run failed: %v", err)
+	}
+	if _, err := NewBatchSQLRepository(db, SQLDriverSQLite); err != nil {
+		t.Fatalf("Second migration run failed: %v", err)
+	}
+
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&count); err != nil {
+		t.Fatalf("Failed to count migrations: %v", err)
+	}
+	if count != len(sqlMigrations) {
+		t.Errorf("Expected %d applied migrations, got %d", len(sqlMigrations), count)
+	}
+}
+
+func TestRebindQuery(t *testing.T) {
+	query := `SELECT * FROM batches WHERE product_id = ? AND status = ?`
+
+	if got := rebindQuery(SQLDriverSQLite, query); got != query {
+		t.Errorf("Expected SQLite query to be unchanged, got %s", got)
+	}
+
+	expected := `SELECT * FROM batches WHERE product_id = $1 AND status = $2`
+	if got := rebindQuery(SQLDriverPostgres, query); got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+}
+
+func TestBatchSQLRepository_OutboxIsWrittenWithBatch(t *testing.T) {
+	repo := newTestSQLRepository(t)
+
+	batch := domain.NewBatch("batch-1", "product-1")
+	batch.AddItem("order-1", "product-1", 2, "allocated")
+	item, _ := batch.GetItemByOrderID("order-1")
+
+	if err := repo.SaveWithEvents(batch, domain.BatchCommand{},
+		domain.NewBatchCreatedEvent(batch),
+		domain.NewBatchItemAddedEvent(batch, "order-1", item),
+	); err != nil {
+		t.Fatalf("Failed to save batch with events: %v", err)
+	}
+
+	pending, err := repo.PendingOutboxMessages(0, 10)
+	if err != nil {
+		t.Fatalf("Failed to read outbox: %v", err)
+	}
+	if len(pending) != 2 {
+		t.Fatalf("Expected 2 outbox messages, got %d", len(pending))
+	}
+	if pending[0].EventType != domain.BatchEventCreated || pending[1].EventType != domain.BatchEventItemAdded {
+		t.Errorf("Expected messages in insertion order, got %s, %s", pending[0].EventType, pending[1].EventType)
+	}
+
+	event, err := pending[1].Event()
+	if err != nil {
+		t.Fatalf("Failed to decode outbox event: %v", err)
+	}
+	if event.OrderID == nil || *event.OrderID != "order-1" {
+		t.Errorf("Expected decoded event for order-1, got %+v", event)
+	}
+	if event.Sequence != pending[1].ID || event.Sequence <= pending[0].ID {
+		t.Errorf("Expected the message ID %d as sequence, got %d", pending[1].ID, event.Sequence)
+	}
+
+	retryAt := time.Now().Add(time.Minute)
+	if err := repo.MarkOutboxMessageFailed(pending[0].ID, "broker unavailable", retryAt); err != nil {
+		t.Fatalf("Failed to mark message as failed: %v", err)
+	}
+	if err := repo.MarkOutboxMessagePublished(pending[1].ID); err != nil {
+		t.Fatalf("Failed to mark message as published: %v", err)
+	}
+
+	pending, err = repo.PendingOutboxMessages(0, 10)
+	if err != nil {
+		t.Fatalf("Failed to read outbox: %v", err)
+	}
+	if len(pending) != 1 {
+		t.Fatalf("Expected 1 pending message, got %d", len(pending))
+	}
+	if pending[0].Attempts != 1 || pending[0].LastError != "broker unavailable" {
+		t.Errorf("Expected failure to be recorded, got attempts=%d error=%q", pending[0].Attempts, pending[0].LastError)
+	}
+	if !pending[0].NextAttemptAt.Equal(retryAt.UTC()) {
+		t.Errorf("Expected next attempt at %v, got %v", retryAt.UTC(), pending[0].NextAttemptAt)
+	}
+
+	if after, _ := repo.PendingOutboxMessages(pending[0].ID, 10); len(after) != 0 {
+		t.Errorf("Expected no pending message after ID %d, got %d", pending[0].ID, len(after))
+	}
+	if err := repo.MarkOutboxMessageDead(pending[0].ID, "undecodable payload"); err != nil {
+		t.Fatalf("Failed to mark message as dead: %v", err)
+	}
+	if pending, _ = repo.PendingOutboxMessages(0, 10); len(pending) != 0 {
+		t.Errorf("Expected dead message not to be pending, got %d", len(pending))
+	}
+}
+
+func TestBatchSQLRepository_FailedSaveDoesNotEnqueueEvents(t *testing.T) {
+	repo := newTestSQLRepository(t)
+
+	batch := domain.NewBatch("batch-1", "product-1")
+	if err := repo.SaveWithEvents(batch, domain.BatchCommand{}, domain.NewBatchCreatedEvent(batch)); err != nil {
+		t.Fatalf("Failed to save batch: %v", err)
+	}
+
+	missing := domain.NewBatch("missing-batch", "product-1")
+	if err := repo.DeleteWithEvents(missing, domain.BatchCommand{}, domain.NewBatchCancelledEvent(missing)); err == nil {
+		t.Fatal("Expected deleting a missing batch to fail")
+	}
+
+	pending, err := repo.PendingOutboxMessages(0, 10)
+	if err != nil {
+		t.Fatalf("Failed to read outbox: %v", err)
+	}
+	if len(pending) != 1 {
+		t.Errorf("Expected the rolled back event not to be stored, got %d messages", len(pending))
+	}
+}
+
+func TestBatchRepositories_RejectStaleVersions(t *testing.T) {
+	repositories := map[string]domain.BatchRepository{
+		"memory": NewBatchMemoryRepository(),
+		"sql":    newTestSQLRepository(t),
+	}
+
+	for name, repo := range repositories {
+		t.Run(name, func(t *testing.T) {
+			batch := domain.NewBatch("batch-1", "product-1")
+			batch.AddItem("order-1", "product-1", 1, "allocated")
+			if err := repo.Save(batch); err != nil {
+				t.Fatalf("Failed to save batch: %v", err)
+			}
+			if batch.Version != 1 {
+				t.Fatalf("Expected version 1 after first save, got %d", batch.Version)
+			}
+
+			// Two copies loaded at the same version
+			first, _ := repo.FindByID("batch-1")
+			second, _ := repo.FindByID("batch-1")
+
+			first.AddItem("order-2", "product-1", 1, "allocated")
+			if err := repo.SaveWithEvents(first, domain.BatchCommand{}, domain.NewBatchItemAddedEvent(first, "order-2", &first.Items[1])); err != nil {
+				t.Fatalf("Failed to save first copy: %v", err)
+			}
+
+			second.AddItem("order-3", "product-1", 1, "allocated")
+			err := repo.SaveWithEvents(second, domain.BatchCommand{}, domain.NewBatchItemAddedEvent(second, "order-3", &second.Items[1]))
+			if !errors.Is(err, domain.ErrConcurrencyConflict) {
+				t.Fatalf("Expected concurrency conflict for stale copy, got %v", err)
+			}
+			if second.Version != 1 {
+				t.Errorf("Expected stale copy to keep version 1, got %d", second.Version)
+			}
+
+			stored, _ := repo.FindByID("batch-1")
+			if stored.Version != 2 || len(stored.Items) != 2 || stored.HasOrder("order-3") {
+				t.Errorf("Expected version 2 with the first copy's items, got version %d with %+v", stored.Version, stored.Items)
+			}
+
+			if err := repo.DeleteWithEvents(second, domain.BatchCommand{}); !errors.Is(err, domain.ErrConcurrencyConflict) {
+				t.Errorf("Expected concurrency conflict when deleting a stale copy, got %v", err)
+			}
+
+			if err := repo.Save(domain.NewBatch("batch-1", "product-1")); !errors.Is(err, domain.ErrConcurrencyConflict) {
+				t.Errorf("Expected concurrency conflict when creating an existing batch, got %v", err)
+			}
+
+			// Only the successful save enqueued its event
+			pending, _ := repo.PendingOutboxMessages(0, 0)
+			if len(pending) != 1 {
+				t.Fatalf("Expected 1 outbox message, got %d", len(pending))
+			}
+			event, err := pending[0].Event()
+			if err != nil {
+				t.Fatalf("Failed to decode outbox message: %v", err)
+			}
+			if event.Batch.Version != 2 {
+				t.Errorf("Expected event snapshot to carry the saved version 2, got %d", event.Batch.Version)
+			}
+		})
+	}
+}
+
+func TestBatchRepositories_RecordHistory(t *testing.T) {
+	repositories := map[string]domain.BatchRepository{
+		"memory": NewBatchMemoryRepository(),
+		"sql":    newTestSQLRepository(t),
+	}
+
+	for name, repo := range repositories {
+		t.Run(name, func(t *testing.T) {
+			issuer := domain.BatchIssuer{Actor: "order-events", SourceEvent: "event-1"}
+			batch := domain.NewBatch("batch-1", "product-1")
+			batch.AddItem("order-1", "product-1", 2, "allocated")
+			if err := repo.SaveWithEvents(batch, domain.BatchCommand{Name: domain.BatchCommandAddOrder, BatchIssuer: issuer},
+				domain.NewBatchCreatedEvent(batch)); err != nil {
+				t.Fatalf("Failed to save batch: %v", err)
+			}
+
+			batch.UpdateItemStatus("order-1", "shipped")
+			batch.AddItem("order-2", "product-1", 1, "allocated")
+			if err := repo.SaveWithEvents(batch, domain.BatchCommand{Name: domain.BatchCommandUpdateOrder}); err != nil {
+				t.Fatalf("Failed to save batch: %v", err)
+			}
+			batch.StartProcessing()
+			if err := repo.SaveWithEvents(batch, domain.BatchCommand{Name: domain.BatchCommandProcess, BatchIssuer: domain.BatchIssuer{Actor: "alice"}},
+				domain.NewBatchProcessingStartedEvent(batch)); err != nil {
+				t.Fatalf("Failed to save batch: %v", err)
+			}
+			// A failed command leaves no trace
+			stale := domain.NewBatch("batch-1", "product-1")
+			if err := repo.SaveWithEvents(stale, domain.BatchCommand{Name: domain.BatchCommandCreateLot}); err == nil {
+				t.Fatal("Expected the stale save to fail")
+			}
+
+			history, total, err := repo.FindBatchHistory("batch-1", 0, 10)
+			if err != nil {
+				t.Fatalf("Failed to find history: %v", err)
+			}
+			if total != 3 || len(history) != 3 {
+				t.Fatalf("Expected 3 entries, got %d of %d", len(history), total)
+			}
+
+			created := history[0]
+			if created.Command.Name != domain.BatchCommandAddOrder || created.Command.BatchIssuer != issuer ||
+				created.StatusBefore != "" || created.StatusAfter != domain.BatchStatusPending ||
+				len(created.Items.Added) != 1 || created.Version != 1 ||
+				len(created.EventTypes) != 1 || created.EventTypes[0] != domain.BatchEventCreated {
+				t.Errorf("Expected the creation by event-1 with order-1, got %+v", created)
+			}
+			updated := history[1]
+			if len(updated.Items.Added) != 1 || updated.Items.Added[0].OrderID != "order-2" ||
+				len(updated.Items.Updated) != 1 || updated.Items.Updated[0].New.Status != "shipped" {
+				t.Errorf("Expected order-2 added and order-1 shipped, got %+v", updated.Items)
+			}
+			processed := history[2]
+			if processed.Command.Actor != "alice" || processed.StatusBefore != domain.BatchStatusPending ||
+				processed.StatusAfter != domain.BatchStatusProcessing || processed.Version != 3 {
+				t.Errorf("Expected alice to start processing at version 3, got %+v", processed)
+			}
+
+			page, total, err := repo.FindBatchHistory("batch-1", 1, 1)
+			if err != nil || total != 3 || len(page) != 1 || page[0].ID != updated.ID {
+				t.Errorf("Expected the second entry of 3, got %+v of %d (%v)", page, total, err)
+			}
+
+			// The history outlives the batch
+			if err := repo.DeleteWithEvents(batch, domain.BatchCommand{Name: domain.BatchCommandRemoveOrder}); err != nil {
+				t.Fatalf("Failed to delete batch: %v", err)
+			}
+			history, total, err = repo.FindBatchHistory("batch-1", 3, 10)
+			if err != nil || total != 4 || len(history) != 1 {
+				t.Fatalf("Expected the deletion as the fourth entry, got %+v of %d (%v)", history, total, err)
+			}
+			if deleted := history[0]; deleted.StatusBefore != domain.BatchStatusProcessing || deleted.StatusAfter != "" ||
+				len(deleted.Items.Removed) != 2 || deleted.Version != 4 {
+				t.Errorf("Expected the deletion of both items, got %+v", deleted)
+			}
+
+			if history, total, err := repo.FindBatchHistory("batch-2", 0, 10); err != nil || total != 0 || len(history) != 0 {
+				t.Errorf("Expected no history for an unknown batch, got %+v of %d (%v)", history, total, err)
+			}
+		})
+	}
+}
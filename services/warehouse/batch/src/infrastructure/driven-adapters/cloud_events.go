@@ -0,0 +1,118 @@
+package drivenadapters
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+	"github.com/segmentio/kafka-go"
+)
+
+// CloudEventsMode selects how an event is encoded as a CloudEvent in a Kafka message
+type CloudEventsMode string
+
+const (
+	// CloudEventsBinary carries the attributes in ce_ headers and the event as the message value
+	CloudEventsBinary CloudEventsMode = "binary"
+
+	// CloudEventsStructured carries the attributes and the event in a JSON envelope
+	CloudEventsStructured CloudEventsMode = "structured"
+)
+
+// DefaultCloudEventsSource is the source attribute of the events published by the service
+const DefaultCloudEventsSource = "/medisupply/warehouse/batch"
+
+// ParseCloudEventsMode converts a configured mode into a CloudEventsMode
+func ParseCloudEventsMode(value string) (CloudEventsMode, error) {
+	switch mode := CloudEventsMode(value); mode {
+	case CloudEventsBinary, CloudEventsStructured:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown CloudEvents mode %q (expected %q or %q)", value, CloudEventsBinary, CloudEventsStructured)
+}
+
+// newBatchEventMessage encodes a batch event with the codec as a CloudEvent keyed by
+// batch ID. The subject is the batch ID and the type is the batch event type. A
+// structured-mode envelope embeds JSON data and carries other data base64-encoded. The
+// trace context of the event is carried in headers in either mode.
+func newBatchEventMessage(event *domain.BatchEvent, codec domain.EventCodec, mode CloudEventsMode, source string) (kafka.Message, error) {
+	traceContext := event.TraceContext
+	if !traceContext.IsZero() {
+		untraced := *event
+		untraced.TraceContext = domain.TraceContext{}
+		event = &untraced
+	}
+
+	data, err := codec.EncodeBatchEvent(event)
+	if err != nil {
+		return kafka.Message{}, fmt.Errorf("failed to encode batch event: %w", err)
+	}
+
+	timestamp := event.Timestamp.UTC()
+	cloudEvent := domain.CloudEvent{
+		SpecVersion:     domain.CloudEventsSpecVersion,
+		ID:              batchEventID(event),
+		Type:            string(event.EventType),
+		Source:          source,
+		Subject:         event.BatchID,
+		Time:            &timestamp,
+		DataContentType: codec.ContentType(),
+	}
+
+	message := kafka.Message{
+		Key: []byte(event.BatchID), // Use batch ID as partition key
+	}
+
+	if mode == CloudEventsStructured {
+		if cloudEvent.DataContentType == domain.JSONContentType {
+			cloudEvent.Data = data
+		} else {
+			cloudEvent.DataBase64 = data
+		}
+		envelope, err := json.Marshal(cloudEvent)
+		if err != nil {
+			return kafka.Message{}, fmt.Errorf("failed to marshal CloudEvent %s: %w", cloudEvent.ID, err)
+		}
+		message.Value = envelope
+		message.Headers = []kafka.Header{
+			{Key: "content-type", Value: []byte(domain.CloudEventsContentType)},
+			{Key: domain.SchemaVersionHeader, Value: []byte(domain.EventSchemaVersion)},
+		}
+		message.Headers = append(message.Headers, kafkaHeaders(traceContext.Headers())...)
+		return message, nil
+	}
+
+	message.Value = data
+	message.Headers = []kafka.Header{
+		{Key: "content-type", Value: []byte(cloudEvent.DataContentType)},
+		{Key: domain.SchemaVersionHeader, Value: []byte(domain.EventSchemaVersion)},
+		{Key: domain.CloudEventsHeaderPrefix + "specversion", Value: []byte(cloudEvent.SpecVersion)},
+		{Key: domain.CloudEventsHeaderPrefix + "id", Value: []byte(cloudEvent.ID)},
+		{Key: domain.CloudEventsHeaderPrefix + "type", Value: []byte(cloudEvent.Type)},
+		{Key: domain.CloudEventsHeaderPrefix + "source", Value: []byte(cloudEvent.Source)},
+		{Key: domain.CloudEventsHeaderPrefix + "subject", Value: []byte(cloudEvent.Subject)},
+		{Key: domain.CloudEventsHeaderPrefix + "time", Value: []byte(timestamp.Format(time.RFC3339Nano))},
+	}
+	message.Headers = append(message.Headers, kafkaHeaders(traceContext.Headers())...)
+	return message, nil
+}
+
+// kafkaHeaders converts message headers into Kafka headers
+func kafkaHeaders(headers []domain.MessageHeader) []kafka.Header {
+	converted := make([]kafka.Header, len(headers))
+	for i, header := range headers {
+		converted[i] = kafka.Header{Key: header.Key, Value: []byte(header.Value)}
+	}
+	return converted
+}
+
+// batchEventID derives the CloudEvent ID of a batch event from its content, so an event
+// delivered again by the outbox relay keeps its ID and consumers can detect the duplicate
+func batchEventID(event *domain.BatchEvent) string {
+	id := fmt.Sprintf("%s/%s/%d", event.BatchID, event.EventType, event.Timestamp.UnixNano())
+	if event.OrderID != nil {
+		id += "/" + *event.OrderID
+	}
+	return id
+}
@@ -0,0 +1,91 @@
+package drivenadapters
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+)
+
+// DeadLetterMemoryStore implements DeadLetterStore using in-memory storage
+type DeadLetterMemoryStore struct {
+	deadLetters map[string]*domain.DeadLetter
+	mutex       sync.RWMutex
+}
+
+// NewDeadLetterMemoryStore creates a new in-memory dead letter store
+func NewDeadLetterMemoryStore() *DeadLetterMemoryStore {
+	return &DeadLetterMemoryStore{
+		deadLetters: make(map[string]*domain.DeadLetter),
+	}
+}
+
+// Save creates or replaces a dead letter
+func (s *DeadLetterMemoryStore) Save(deadLetter *domain.DeadLetter) error {
+	if deadLetter == nil {
+		return fmt.Errorf("dead letter cannot be nil")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.deadLetters[deadLetter.ID] = copyDeadLetter(deadLetter)
+	return nil
+}
+
+// FindByID retrieves a dead letter by its ID
+func (s *DeadLetterMemoryStore) FindByID(id string) (*domain.DeadLetter, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	deadLetter, exists := s.deadLetters[id]
+	if !exists {
+		return nil, domain.NewNotFoundError("dead letter with ID %s not found", id)
+	}
+	return copyDeadLetter(deadLetter), nil
+}
+
+// FindAll retrieves all dead letters, oldest failure first
+func (s *DeadLetterMemoryStore) FindAll() ([]*domain.DeadLetter, error) {
+	return s.find(func(*domain.DeadLetter) bool { return true }), nil
+}
+
+// FindByStatus retrieves the dead letters in the given status, oldest failure first
+func (s *DeadLetterMemoryStore) FindByStatus(status domain.DeadLetterStatus) ([]*domain.DeadLetter, error) {
+	return s.find(func(deadLetter *domain.DeadLetter) bool { return deadLetter.Status == status }), nil
+}
+
+// find returns copies of the matching dead letters, oldest failure first
+func (s *DeadLetterMemoryStore) find(matches func(*domain.DeadLetter) bool) []*domain.DeadLetter {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var result []*domain.DeadLetter
+	for _, deadLetter := range s.deadLetters {
+		if matches(deadLetter) {
+			result = append(result, copyDeadLetter(deadLetter))
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].FailedAt.Equal(result[j].FailedAt) {
+			return result[i].ID < result[j].ID
+		}
+		return result[i].FailedAt.Before(result[j].FailedAt)
+	})
+	return result
+}
+
+// copyDeadLetter creates a deep copy to avoid external modifications
+func copyDeadLetter(deadLetter *domain.DeadLetter) *domain.DeadLetter {
+	deadLetterCopy := *deadLetter
+	deadLetterCopy.Payload = append([]byte(nil), deadLetter.Payload...)
+	deadLetterCopy.Headers = append([]domain.MessageHeader(nil), deadLetter.Headers...)
+	deadLetterCopy.Replays = append([]domain.DeadLetterReplay(nil), deadLetter.Replays...)
+	if deadLetter.EditedAt != nil {
+		editedAt := *deadLetter.EditedAt
+		deadLetterCopy.EditedAt = &editedAt
+	}
+	return &deadLetterCopy
+}
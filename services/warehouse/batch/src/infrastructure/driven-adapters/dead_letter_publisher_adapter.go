@@ -0,0 +1,95 @@
+package drivenadapters
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+	"github.com/segmentio/kafka-go"
+)
+
+// Headers added to a dead-lettered message next to its original headers
+const (
+	DeadLetterHeaderReason            = "dlq_reason"
+	DeadLetterHeaderErrorClass        = "dlq_error_class"
+	DeadLetterHeaderAttempts          = "dlq_attempts"
+	DeadLetterHeaderFailedAt          = "dlq_failed_at"
+	DeadLetterHeaderOriginalTopic     = "dlq_original_topic"
+	DeadLetterHeaderOriginalPartition = "dlq_original_partition"
+	DeadLetterHeaderOriginalOffset    = "dlq_original_offset"
+)
+
+// DeadLetterPublisherAdapter implements the DeadLetterPublisher interface by writing
+// the original message to a Kafka dead-letter topic
+type DeadLetterPublisherAdapter struct {
+	writer *kafka.Writer
+	topic  string
+}
+
+// NewDeadLetterPublisherAdapter creates a new DeadLetterPublisherAdapter
+func NewDeadLetterPublisherAdapter(conn *KafkaConnection, topic string) *DeadLetterPublisherAdapter {
+	writer := &kafka.Writer{
+		Addr:         conn.addr(),
+		Transport:    conn.transport,
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+		Async:        false,
+		WriteTimeout: 10 * time.Second,
+		ReadTimeout:  10 * time.Second,
+	}
+
+	return &DeadLetterPublisherAdapter{
+		writer: writer,
+		topic:  topic,
+	}
+}
+
+// PublishDeadLetter writes the original key, payload and headers to the dead-letter
+// topic, together with headers describing the failure
+func (p *DeadLetterPublisherAdapter) PublishDeadLetter(deadLetter *domain.DeadLetter) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := p.writer.WriteMessages(ctx, newDeadLetterMessage(deadLetter)); err != nil {
+		return fmt.Errorf("failed to write dead letter to Kafka topic %s: %w", p.topic, err)
+	}
+
+	log.Printf("Dead-lettered message %s/%d/%d to %s: %s",
+		deadLetter.Topic, deadLetter.Partition, deadLetter.Offset, p.topic, deadLetter.Reason)
+	return nil
+}
+
+// newDeadLetterMessage builds the Kafka message for a dead letter
+func newDeadLetterMessage(deadLetter *domain.DeadLetter) kafka.Message {
+	headers := make([]kafka.Header, 0, len(deadLetter.Headers)+7)
+	for _, header := range deadLetter.Headers {
+		headers = append(headers, kafka.Header{Key: header.Key, Value: []byte(header.Value)})
+	}
+	headers = append(headers,
+		kafka.Header{Key: DeadLetterHeaderReason, Value: []byte(deadLetter.Reason)},
+		kafka.Header{Key: DeadLetterHeaderErrorClass, Value: []byte(deadLetter.ErrorClass)},
+		kafka.Header{Key: DeadLetterHeaderAttempts, Value: []byte(strconv.Itoa(deadLetter.Attempts))},
+		kafka.Header{Key: DeadLetterHeaderFailedAt, Value: []byte(deadLetter.FailedAt.UTC().Format(time.RFC3339))},
+		kafka.Header{Key: DeadLetterHeaderOriginalTopic, Value: []byte(deadLetter.Topic)},
+		kafka.Header{Key: DeadLetterHeaderOriginalPartition, Value: []byte(strconv.Itoa(deadLetter.Partition))},
+		kafka.Header{Key: DeadLetterHeaderOriginalOffset, Value: []byte(strconv.FormatInt(deadLetter.Offset, 10))},
+	)
+
+	return kafka.Message{
+		Key:     []byte(deadLetter.Key),
+		Value:   deadLetter.Payload,
+		Headers: headers,
+	}
+}
+
+// Close closes the Kafka writer
+func (p *DeadLetterPublisherAdapter) Close() error {
+	if p.writer != nil {
+		return p.writer.Close()
+	}
+	return nil
+}
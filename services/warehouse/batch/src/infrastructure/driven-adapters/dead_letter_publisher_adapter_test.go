@@ -0,0 +1,51 @@
+package drivenadapters
+
+import (
+	"testing"
+	"time"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+)
+
+func TestNewDeadLetterMessage(t *testing.T) {
+	deadLetter := &domain.DeadLetter{
+		Topic:      "order-events",
+		Partition:  2,
+		Offset:     42,
+		Key:        "order-1",
+		Payload:    []byte(`{not json`),
+		Headers:    []domain.MessageHeader{{Key: "event_id", Value: "evt-1"}},
+		ErrorClass: "decode",
+		Reason:     "failed to decode order event: invalid character 'n'",
+		Attempts:   1,
+		FailedAt:   time.Date(2024, 12, 1, 12, 0, 0, 0, time.UTC),
+	}
+
+	msg := newDeadLetterMessage(deadLetter)
+
+	if string(msg.Key) != "order-1" || string(msg.Value) != `{not json` {
+		t.Errorf("Expected the original key and payload, got %s / %s", msg.Key, msg.Value)
+	}
+	if msg.Headers[0].Key != "event_id" || string(msg.Headers[0].Value) != "evt-1" {
+		t.Errorf("Expected the original headers first, got %+v", msg.Headers[0])
+	}
+
+	headers := make(map[string]string)
+	for _, header := range msg.Headers {
+		headers[header.Key] = string(header.Value)
+	}
+	expected := map[string]string{
+		DeadLetterHeaderReason:            deadLetter.Reason,
+		DeadLetterHeaderErrorClass:        "decode",
+		DeadLetterHeaderAttempts:          "1",
+		DeadLetterHeaderFailedAt:          "2024-12-01T12:00:00Z",
+		DeadLetterHeaderOriginalTopic:     "order-events",
+		DeadLetterHeaderOriginalPartition: "2",
+		DeadLetterHeaderOriginalOffset:    "42",
+	}
+	for key, value := range expected {
+		if headers[key] != value {
+			t.Errorf("Expected header %s=%q, got %q", key, value, headers[key])
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package drivenadapters
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+)
+
+const deadLetterColumns = `id, topic, partition_no, offset_no, message_key, payload, headers, error_class, reason, attempts, status, failed_at, edited_at, replays`
+
+// DeadLetterSQLStore implements DeadLetterStore on top of a relational database.
+// Headers and replay outcomes are stored as JSON documents.
+type DeadLetterSQLStore struct {
+	db     *sql.DB
+	driver string
+}
+
+// NewDeadLetterSQLStore creates a new SQL dead letter store and applies pending schema migrations
+func NewDeadLetterSQLStore(db *sql.DB, driver string) (*DeadLetterSQLStore, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database cannot be nil")
+	}
+
+	if driver != SQLDriverPostgres && driver != SQLDriverSQLite {
+		return nil, fmt.Errorf("unsupported SQL driver: %s", driver)
+	}
+
+	if err := migrateSQLSchema(db, driver); err != nil {
+		return nil, fmt.Errorf("failed to migrate database schema: %w", err)
+	}
+
+	return &DeadLetterSQLStore{
+		db:     db,
+		driver: driver,
+	}, nil
+}
+
+// Save creates or replaces a dead letter
+func (s *DeadLetterSQLStore) Save(deadLetter *domain.DeadLetter) error {
+	if deadLetter == nil {
+		return fmt.Errorf("dead letter cannot be nil")
+	}
+
+	headers, err := json.Marshal(deadLetter.Headers)
+	if err != nil {
+		return fmt.Errorf("failed to serialize headers of dead letter %s: %w", deadLetter.ID, err)
+	}
+	replays, err := json.Marshal(deadLetter.Replays)
+	if err != nil {
+		return fmt.Errorf("failed to serialize replays of dead letter %s: %w", deadLetter.ID, err)
+	}
+
+	query := rebindQuery(s.driver, `INSERT INTO dead_letters (`+deadLetterColumns+`)
+		VALUES (`+placeholders(14)+`)
+		ON CONFLICT (id) DO UPDATE SET
+			payload = excluded.payload,
+			headers = excluded.headers,
+			error_class = excluded.error_class,
+			reason = excluded.reason,
+			attempts = excluded.attempts,
+			status = excluded.status,
+			edited_at = excluded.edited_at,
+			replays = excluded.replays`)
+	if _, err := s.db.Exec(query,
+		deadLetter.ID,
+		deadLetter.Topic,
+		deadLetter.Partition,
+		deadLetter.Offset,
+		deadLetter.Key,
+		deadLetter.Payload,
+		string(headers),
+		deadLetter.ErrorClass,
+		deadLetter.Reason,
+		deadLetter.Attempts,
+		string(deadLetter.Status),
+		deadLetter.FailedAt.UTC(),
+		nullTime(deadLetter.EditedAt),
+		string(replays),
+	); err != nil {
+		return fmt.Errorf("failed to save dead letter %s: %w", deadLetter.ID, err)
+	}
+	return nil
+}
+
+// FindByID retrieves a dead letter by its ID
+func (s *DeadLetterSQLStore) FindByID(id string) (*domain.DeadLetter, error) {
+	query := rebindQuery(s.driver, `SELECT `+deadLetterColumns+` FROM dead_letters WHERE id = ?`)
+	deadLetter, err := scanDeadLetter(s.db.QueryRow(query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.NewNotFoundError("dead letter with ID %s not found", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return deadLetter, nil
+}
+
+// FindAll retrieves all dead letters, oldest failure first
+func (s *DeadLetterSQLStore) FindAll() ([]*domain.DeadLetter, error) {
+	return s.queryDeadLetters(`ORDER BY failed_at, id`)
+}
+
+// FindByStatus retrieves the dead letters in the given status, oldest failure first
+func (s *DeadLetterSQLStore) FindByStatus(status domain.DeadLetterStatus) ([]*domain.DeadLetter, error) {
+	return s.queryDeadLetters(`WHERE status = ? ORDER BY failed_at, id`, string(status))
+}
+
+// queryDeadLetters runs a dead letter query with the given clause
+func (s *DeadLetterSQLStore) queryDeadLetters(clause string, args ...interface{}) ([]*domain.DeadLetter, error) {
+	rows, err := s.db.Query(rebindQuery(s.driver, `SELECT `+deadLetterColumns+` FROM dead_letters `+clause), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dead letters: %w", err)
+	}
+	defer rows.Close()
+
+	var deadLetters []*domain.DeadLetter
+	for rows.Next() {
+		deadLetter, err := scanDeadLetter(rows)
+		if err != nil {
+			return nil, err
+		}
+		deadLetters = append(deadLetters, deadLetter)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read dead letters: %w", err)
+	}
+	return deadLetters, nil
+}
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanDeadLetter reads a dead letter row selected with deadLetterColumns
+func scanDeadLetter(row rowScanner) (*domain.DeadLetter, error) {
+	var (
+		deadLetter domain.DeadLetter
+		headers    string
+		status     string
+		editedAt   sql.NullTime
+		replays    string
+	)
+	if err := row.Scan(
+		&deadLetter.ID,
+		&deadLetter.Topic,
+		&deadLetter.Partition,
+		&deadLetter.Offset,
+		&deadLetter.Key,
+		&deadLetter.Payload,
+		&headers,
+		&deadLetter.ErrorClass,
+		&deadLetter.Reason,
+		&deadLetter.Attempts,
+		&status,
+		&deadLetter.FailedAt,
+		&editedAt,
+		&replays,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan dead letter: %w", err)
+	}
+
+	if err := json.Unmarshal([]byte(headers), &deadLetter.Headers); err != nil {
+		return nil, fmt.Errorf("failed to deserialize headers of dead letter %s: %w", deadLetter.ID, err)
+	}
+	if err := json.Unmarshal([]byte(replays), &deadLetter.Replays); err != nil {
+		return nil, fmt.Errorf("failed to deserialize replays of dead letter %s: %w", deadLetter.ID, err)
+	}
+	deadLetter.Status = domain.DeadLetterStatus(status)
+	deadLetter.EditedAt = timePtr(editedAt)
+	return &deadLetter, nil
+}
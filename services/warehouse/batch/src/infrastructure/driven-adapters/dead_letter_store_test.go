@@ -0,0 +1,110 @@
+package drivenadapters
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+)
+
+func TestDeadLetterStores(t *testing.T) {
+	sqlStore, err := NewDeadLetterSQLStore(newTestSQLDB(t), SQLDriverSQLite)
+	if err != nil {
+		t.Fatalf("Failed to create SQL dead letter store: %v", err)
+	}
+
+	stores := map[string]domain.DeadLetterStore{
+		"memory": NewDeadLetterMemoryStore(),
+		"sql":    sqlStore,
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			failedAt := time.Date(2024, 12, 1, 12, 0, 0, 0, time.UTC)
+			newer := &domain.DeadLetter{
+				ID:         "order-events-0-8",
+				Topic:      "order-events",
+				Offset:     8,
+				Payload:    []byte(`{"event_type":"order.shipped"}`),
+				Headers:    []domain.MessageHeader{},
+				ErrorClass: "domain",
+				Reason:     "order not found",
+				Attempts:   3,
+				FailedAt:   failedAt.Add(time.Minute),
+				Status:     domain.DeadLetterStatusParked,
+			}
+			older := &domain.DeadLetter{
+				ID:         "order-events-1-7",
+				Topic:      "order-events",
+				Partition:  1,
+				Offset:     7,
+				Key:        "order-1",
+				Payload:    []byte{'{', 0xff},
+				Headers:    []domain.MessageHeader{{Key: "event_id", Value: "evt-7"}},
+				ErrorClass: "decode",
+				Reason:     "invalid character",
+				Attempts:   1,
+				FailedAt:   failedAt,
+				Status:     domain.DeadLetterStatusParked,
+			}
+
+			if _, err := store.FindByID(older.ID); !errors.Is(err, domain.ErrNotFound) {
+				t.Fatalf("Expected ErrNotFound for unknown dead letter, got %v", err)
+			}
+
+			for _, deadLetter := range []*domain.DeadLetter{newer, older} {
+				if err := store.Save(deadLetter); err != nil {
+					t.Fatalf("Failed to save dead letter: %v", err)
+				}
+			}
+
+			found, err := store.FindByID(older.ID)
+			if err != nil {
+				t.Fatalf("Failed to find dead letter: %v", err)
+			}
+			if string(found.Payload) != string(older.Payload) || found.Key != "order-1" || found.Partition != 1 {
+				t.Errorf("Expected the stored message, got %+v", found)
+			}
+			if len(found.Headers) != 1 || found.Headers[0].Value != "evt-7" {
+				t.Errorf("Expected the original headers, got %+v", found.Headers)
+			}
+
+			// Update the payload and record a successful replay
+			if err := found.EditPayload([]byte(`{"event_type":"order.created"}`), failedAt.Add(time.Hour)); err != nil {
+				t.Fatalf("Failed to edit payload: %v", err)
+			}
+			found.RecordReplay(failedAt.Add(2*time.Hour), nil)
+			if err := store.Save(found); err != nil {
+				t.Fatalf("Failed to update dead letter: %v", err)
+			}
+
+			replayed, err := store.FindByID(older.ID)
+			if err != nil {
+				t.Fatalf("Failed to find dead letter: %v", err)
+			}
+			if replayed.Status != domain.DeadLetterStatusReplayed || len(replayed.Replays) != 1 || !replayed.Replays[0].Succeeded {
+				t.Errorf("Expected a replayed dead letter with one replay, got %+v", replayed)
+			}
+			if replayed.EditedAt == nil || !replayed.EditedAt.Equal(failedAt.Add(time.Hour)) {
+				t.Errorf("Expected edit time to be stored, got %v", replayed.EditedAt)
+			}
+
+			all, err := store.FindAll()
+			if err != nil {
+				t.Fatalf("Failed to find dead letters: %v", err)
+			}
+			if len(all) != 2 || all[0].ID != older.ID || all[1].ID != newer.ID {
+				t.Errorf("Expected dead letters oldest failure first, got %d", len(all))
+			}
+
+			parked, err := store.FindByStatus(domain.DeadLetterStatusParked)
+			if err != nil {
+				t.Fatalf("Failed to find parked dead letters: %v", err)
+			}
+			if len(parked) != 1 || parked[0].ID != newer.ID {
+				t.Errorf("Expected only the newer dead letter to be parked, got %d", len(parked))
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package drivenadapters
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+	"github.com/segmentio/kafka-go"
+)
+
+// InventoryEventPublisherAdapter implements the InventoryEventPublisher interface using Kafka.
+// Events are encoded as Protobuf unless another codec is configured.
+type InventoryEventPublisherAdapter struct {
+	writer *kafka.Writer
+	topic  string
+	codec  domain.EventCodec
+}
+
+// InventoryEventPublisherOption configures optional InventoryEventPublisherAdapter behaviour
+type InventoryEventPublisherOption func(*InventoryEventPublisherAdapter)
+
+// WithInventoryEventCodec sets the codec that encodes published events
+func WithInventoryEventCodec(codec domain.EventCodec) InventoryEventPublisherOption {
+	return func(p *InventoryEventPublisherAdapter) {
+		p.codec = codec
+	}
+}
+
+// NewInventoryEventPublisherAdapter creates a new InventoryEventPublisherAdapter
+func NewInventoryEventPublisherAdapter(conn *KafkaConnection, topic string, opts ...InventoryEventPublisherOption) *InventoryEventPublisherAdapter {
+	writer := &kafka.Writer{
+		Addr:         conn.addr(),
+		Transport:    conn.transport,
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+		Async:        false,
+		WriteTimeout: 10 * time.Second,
+		ReadTimeout:  10 * time.Second,
+	}
+
+	publisher := &InventoryEventPublisherAdapter{
+		writer: writer,
+		topic:  topic,
+		codec:  ProtobufEventCodec{},
+	}
+	for _, opt := range opts {
+		opt(publisher)
+	}
+	return publisher
+}
+
+// PublishInventoryEvent publishes an inventory event to Kafka, keyed by product ID so
+// the events of a product stay in order
+func (p *InventoryEventPublisherAdapter) PublishInventoryEvent(event *domain.InventoryEvent) error {
+	message, err := newInventoryEventMessage(event, p.codec)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := p.writer.WriteMessages(ctx, message); err != nil {
+		return fmt.Errorf("failed to write inventory event to Kafka topic %s: %w", p.topic, err)
+	}
+
+	log.Printf("Successfully published inventory event: %s for order %s", event.EventType, event.OrderID)
+	return nil
+}
+
+// newInventoryEventMessage builds the Kafka message for an inventory event encoded with the codec
+func newInventoryEventMessage(event *domain.InventoryEvent, codec domain.EventCodec) (kafka.Message, error) {
+	eventData, err := codec.EncodeInventoryEvent(event)
+	if err != nil {
+		return kafka.Message{}, fmt.Errorf("failed to encode inventory event: %w", err)
+	}
+
+	return kafka.Message{
+		Key:   []byte(event.ProductID),
+		Value: eventData,
+		Headers: []kafka.Header{
+			{Key: "content-type", Value: []byte(codec.ContentType())},
+			{Key: domain.SchemaVersionHeader, Value: []byte(domain.EventSchemaVersion)},
+			{Key: "event_type", Value: []byte(event.EventType)},
+			{Key: "product_id", Value: []byte(event.ProductID)},
+			{Key: "order_id", Value: []byte(event.OrderID)},
+			{Key: "timestamp", Value: []byte(event.Timestamp.Format(time.RFC3339))},
+		},
+	}, nil
+}
+
+// Close closes the Kafka writer
+func (p *InventoryEventPublisherAdapter) Close() error {
+	if p.writer != nil {
+		return p.writer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+package drivenadapters
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+)
+
+// InventoryMemoryRepository implements InventoryRepository using in-memory storage
+type InventoryMemoryRepository struct {
+	stock          map[string]*domain.StockLevel
+	reservations   map[string]*domain.StockReservation
+	movements      map[string][]*domain.StockMovement
+	nextMovementID int64
+	outbox         map[int64]*domain.InventoryOutboxMessage
+	nextOutboxID   int64
+	mutex          sync.RWMutex
+}
+
+// NewInventoryMemoryRepository creates a new in-memory inventory repository
+func NewInventoryMemoryRepository() *InventoryMemoryRepository {
+	return &InventoryMemoryRepository{
+		stock:        make(map[string]*domain.StockLevel),
+		reservations: make(map[string]*domain.StockReservation),
+		movements:    make(map[string][]*domain.StockMovement),
+		outbox:       make(map[int64]*domain.InventoryOutboxMessage),
+	}
+}
+
+// FindStock retrieves the stock level of a product
+func (r *InventoryMemoryRepository) FindStock(productID string) (*domain.StockLevel, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	stock, exists := r.stock[productID]
+	if !exists {
+		return nil, domain.NewNotFoundError("no stock found for product %s", productID)
+	}
+
+	stockCopy := *stock
+	return &stockCopy, nil
+}
+
+// GetAllStock retrieves the stock levels of all products, ordered by product ID
+func (r *InventoryMemoryRepository) GetAllStock() ([]*domain.StockLevel, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	result := make([]*domain.StockLevel, 0, len(r.stock))
+	for _, stock := range r.stock {
+		stockCopy := *stock
+		result = append(result, &stockCopy)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ProductID < result[j].ProductID
+	})
+	return result, nil
+}
+
+// FindReservation retrieves the stock reservation of an order
+func (r *InventoryMemoryRepository) FindReservation(orderID string) (*domain.StockReservation, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	reservation, exists := r.reservations[orderID]
+	if !exists {
+		return nil, domain.NewNotFoundError("no stock reservation found for order %s", orderID)
+	}
+
+	reservationCopy := *reservation
+	return &reservationCopy, nil
+}
+
+// SaveStock stores the stock level, the changed reservation, the ledger entry and the
+// outbox messages of the events atomically
+func (r *InventoryMemoryRepository) SaveStock(stock *domain.StockLevel, reservation *domain.StockReservation, movement *domain.StockMovement, events ...*domain.InventoryEvent) error {
+	if stock == nil {
+		return fmt.Errorf("stock cannot be nil")
+	}
+
+	messages, err := newInventoryOutboxMessages(events)
+	if err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var storedVersion int64
+	if stored, exists := r.stock[stock.ProductID]; exists {
+		storedVersion = stored.Version
+	}
+	if storedVersion != stock.Version {
+		return newStockConflictError(stock.ProductID, stock.Version)
+	}
+
+	stock.Version++
+	stockCopy := *stock
+	r.stock[stock.ProductID] = &stockCopy
+
+	if reservation != nil {
+		reservationCopy := *reservation
+		r.reservations[reservation.OrderID] = &reservationCopy
+	}
+
+	if movement != nil {
+		r.nextMovementID++
+		movement.ID = r.nextMovementID
+		movementCopy := *movement
+		r.movements[movement.ProductID] = append(r.movements[movement.ProductID], &movementCopy)
+	}
+
+	for _, message := range messages {
+		r.nextOutboxID++
+		message.ID = r.nextOutboxID
+		r.outbox[message.ID] = message
+	}
+	return nil
+}
+
+// FindMovements retrieves the stock ledger of a product, oldest movement first
+func (r *InventoryMemoryRepository) FindMovements(productID string) ([]*domain.StockMovement, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	result := make([]*domain.StockMovement, 0, len(r.movements[productID]))
+	for _, movement := range r.movements[productID] {
+		movementCopy := *movement
+		result = append(result, &movementCopy)
+	}
+	return result, nil
+}
+
+// PendingInventoryOutboxMessages returns up to limit undelivered messages after the given ID in insertion order
+func (r *InventoryMemoryRepository) PendingInventoryOutboxMessages(afterID int64, limit int) ([]*domain.InventoryOutboxMessage, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	result := make([]*domain.InventoryOutboxMessage, 0, len(r.outbox))
+	for _, message := range r.outbox {
+		if message.ID <= afterID || message.DeadAt != nil {
+			continue
+		}
+		messageCopy := *message
+		result = append(result, &messageCopy)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+// MarkInventoryOutboxMessagePublished records that a message was delivered
+func (r *InventoryMemoryRepository) MarkInventoryOutboxMessagePublished(id int64) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, exists := r.outbox[id]; !exists {
+		return domain.NewNotFoundError("inventory outbox message %d not found", id)
+	}
+
+	delete(r.outbox, id)
+	return nil
+}
+
+// MarkInventoryOutboxMessageFailed records a failed delivery and schedules the next attempt
+func (r *InventoryMemoryRepository) MarkInventoryOutboxMessageFailed(id int64, reason string, nextAttemptAt time.Time) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	message, exists := r.outbox[id]
+	if !exists {
+		return domain.NewNotFoundError("inventory outbox message %d not found", id)
+	}
+
+	message.Attempts++
+	message.LastError = reason
+	message.NextAttemptAt = nextAttemptAt
+	return nil
+}
+
+// MarkInventoryOutboxMessageDead records that a message cannot be delivered and stops retrying it
+func (r *InventoryMemoryRepository) MarkInventoryOutboxMessageDead(id int64, reason string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	message, exists := r.outbox[id]
+	if !exists {
+		return domain.NewNotFoundError("inventory outbox message %d not found", id)
+	}
+
+	deadAt := time.Now().UTC()
+	message.Attempts++
+	message.LastError = reason
+	message.DeadAt = &deadAt
+	return nil
+}
+
+// newInventoryOutboxMessages serializes the events into inventory outbox messages
+func newInventoryOutboxMessages(events []*domain.InventoryEvent) ([]*domain.InventoryOutboxMessage, error) {
+	messages := make([]*domain.InventoryOutboxMessage, 0, len(events))
+	for _, event := range events {
+		message, err := domain.NewInventoryOutboxMessage(event)
+		if err != nil {
+			return nil, fmt.Errorf("failed to enqueue inventory event: %w", err)
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+// newStockConflictError reports that the stock was changed after the given version was loaded
+func newStockConflictError(productID string, version int64) error {
+	return domain.NewConflictError("stock of product %s was modified concurrently (version %d is stale)", productID, version)
+}
@@ -0,0 +1,191 @@
+package drivenadapters
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+)
+
+func TestInventoryRepositories(t *testing.T) {
+	sqlRepo, err := NewInventorySQLRepository(newTestSQLDB(t), SQLDriverSQLite)
+	if err != nil {
+		t.Fatalf("Failed to create SQL inventory repository: %v", err)
+	}
+
+	repos := map[string]domain.InventoryRepository{
+		"memory": NewInventoryMemoryRepository(),
+		"sql":    sqlRepo,
+	}
+
+	for name, repo := range repos {
+		t.Run(name, func(t *testing.T) {
+			at := time.Date(2024, 12, 1, 12, 0, 0, 0, time.UTC)
+
+			if _, err := repo.FindStock("product-1"); !errors.Is(err, domain.ErrNotFound) {
+				t.Fatalf("Expected ErrNotFound for unstocked product, got %v", err)
+			}
+			if _, err := repo.FindReservation("order-1"); !errors.Is(err, domain.ErrNotFound) {
+				t.Fatalf("Expected ErrNotFound for unknown reservation, got %v", err)
+			}
+
+			stock := domain.NewStockLevel("product-1")
+			movement, err := stock.Receive(10, "purchase order PO-1", at)
+			if err != nil {
+				t.Fatalf("Failed to receive stock: %v", err)
+			}
+			if err := repo.SaveStock(stock, nil, movement); err != nil {
+				t.Fatalf("Failed to save new stock: %v", err)
+			}
+			if stock.Version != 1 {
+				t.Errorf("Expected version 1 after first save, got %d", stock.Version)
+			}
+
+			// A second copy created from scratch is stale
+			if err := repo.SaveStock(domain.NewStockLevel("product-1"), nil, movement); !errors.Is(err, domain.ErrConcurrencyConflict) {
+				t.Errorf("Expected ErrConcurrencyConflict for a duplicate new stock level, got %v", err)
+			}
+
+			stale, err := repo.FindStock("product-1")
+			if err != nil {
+				t.Fatalf("Failed to find stock: %v", err)
+			}
+
+			reservation, movement, err := stock.Reserve("order-1", 4, at.Add(time.Minute))
+			if err != nil {
+				t.Fatalf("Failed to reserve stock: %v", err)
+			}
+			if err := repo.SaveStock(stock, reservation, movement); err != nil {
+				t.Fatalf("Failed to save reservation: %v", err)
+			}
+
+			staleMovement, err := stale.Receive(1, "", at)
+			if err != nil {
+				t.Fatalf("Failed to receive stock: %v", err)
+			}
+			if err := repo.SaveStock(stale, nil, staleMovement); !errors.Is(err, domain.ErrConcurrencyConflict) {
+				t.Errorf("Expected ErrConcurrencyConflict for a stale stock level, got %v", err)
+			}
+
+			stored, err := repo.FindStock("product-1")
+			if err != nil {
+				t.Fatalf("Failed to find stock: %v", err)
+			}
+			if stored.OnHand != 10 || stored.Reserved != 4 || stored.Version != 2 {
+				t.Errorf("Expected 10 on hand, 4 reserved at version 2, got %+v", stored)
+			}
+
+			storedReservation, err := repo.FindReservation("order-1")
+			if err != nil {
+				t.Fatalf("Failed to find reservation: %v", err)
+			}
+			if storedReservation.Quantity != 4 || storedReservation.Status != domain.StockReservationActive {
+				t.Errorf("Expected active reservation of 4 units, got %+v", storedReservation)
+			}
+
+			movement, err = stored.Release(storedReservation, at.Add(2*time.Minute))
+			if err != nil {
+				t.Fatalf("Failed to release reservation: %v", err)
+			}
+			if err := repo.SaveStock(stored, storedReservation, movement); err != nil {
+				t.Fatalf("Failed to save release: %v", err)
+			}
+
+			released, _ := repo.FindReservation("order-1")
+			if released.Status != domain.StockReservationReleased {
+				t.Errorf("Expected released reservation, got %s", released.Status)
+			}
+
+			movements, err := repo.FindMovements("product-1")
+			if err != nil {
+				t.Fatalf("Failed to find movements: %v", err)
+			}
+			types := []domain.StockMovementType{domain.StockMovementReceipt, domain.StockMovementReservation, domain.StockMovementRelease}
+			if len(movements) != len(types) {
+				t.Fatalf("Expected %d movements, got %d", len(types), len(movements))
+			}
+			for i, movementType := range types {
+				if movements[i].Type != movementType {
+					t.Errorf("Expected movement %d to be %s, got %s", i, movementType, movements[i].Type)
+				}
+			}
+			if movements[1].OrderID != "order-1" || movements[1].ReservedAfter != 4 || movements[0].Reason != "purchase order PO-1" {
+				t.Errorf("Unexpected movements: %+v %+v", movements[0], movements[1])
+			}
+
+			all, err := repo.GetAllStock()
+			if err != nil || len(all) != 1 {
+				t.Errorf("Expected 1 stock level, got %d (%v)", len(all), err)
+			}
+		})
+	}
+}
+
+func TestInventoryRepositories_Outbox(t *testing.T) {
+	sqlRepo, err := NewInventorySQLRepository(newTestSQLDB(t), SQLDriverSQLite)
+	if err != nil {
+		t.Fatalf("Failed to create SQL inventory repository: %v", err)
+	}
+
+	repos := map[string]domain.InventoryRepository{
+		"memory": NewInventoryMemoryRepository(),
+		"sql":    sqlRepo,
+	}
+
+	for name, repo := range repos {
+		t.Run(name, func(t *testing.T) {
+			stock := domain.NewStockLevel("product-1")
+			events := []*domain.InventoryEvent{
+				domain.NewAllocationFailedEvent("order-1", "product-1", 3, 0, "no stock"),
+				domain.NewAllocationFailedEvent("order-2", "product-1", 1, 0, "no stock"),
+			}
+			if err := repo.SaveStock(stock, nil, nil, events...); err != nil {
+				t.Fatalf("Failed to save stock with events: %v", err)
+			}
+
+			// The events are not enqueued when the stock is stale
+			stale := domain.NewStockLevel("product-1")
+			if err := repo.SaveStock(stale, nil, nil, events[0]); !errors.Is(err, domain.ErrConcurrencyConflict) {
+				t.Fatalf("Expected ErrConcurrencyConflict for stale stock, got %v", err)
+			}
+
+			pending, err := repo.PendingInventoryOutboxMessages(0, 0)
+			if err != nil {
+				t.Fatalf("Failed to read outbox: %v", err)
+			}
+			if len(pending) != 2 {
+				t.Fatalf("Expected 2 outbox messages, got %d", len(pending))
+			}
+			event, err := pending[0].Event()
+			if err != nil || event.OrderID != "order-1" || pending[0].EventType != domain.InventoryEventAllocationFailed {
+				t.Errorf("Expected the allocation failure of order-1 first, got %+v (%v)", event, err)
+			}
+
+			if err := repo.MarkInventoryOutboxMessageFailed(pending[0].ID, "broker unavailable", time.Now().Add(time.Minute)); err != nil {
+				t.Fatalf("Failed to mark message failed: %v", err)
+			}
+			if err := repo.MarkInventoryOutboxMessageDead(pending[1].ID, "broker unavailable"); err != nil {
+				t.Fatalf("Failed to mark message dead: %v", err)
+			}
+
+			pending, _ = repo.PendingInventoryOutboxMessages(0, 0)
+			if len(pending) != 1 || pending[0].Attempts != 1 || pending[0].LastError != "broker unavailable" {
+				t.Fatalf("Expected only the retried message to be pending, got %+v", pending)
+			}
+			if after, _ := repo.PendingInventoryOutboxMessages(pending[0].ID, 0); len(after) != 0 {
+				t.Errorf("Expected no messages after %d, got %d", pending[0].ID, len(after))
+			}
+
+			if err := repo.MarkInventoryOutboxMessagePublished(pending[0].ID); err != nil {
+				t.Fatalf("Failed to mark message published: %v", err)
+			}
+			if pending, _ = repo.PendingInventoryOutboxMessages(0, 0); len(pending) != 0 {
+				t.Errorf("Expected outbox to be drained, got %d messages", len(pending))
+			}
+			if err := repo.MarkInventoryOutboxMessagePublished(999); !errors.Is(err, domain.ErrNotFound) {
+				t.Errorf("Expected ErrNotFound for an unknown message, got %v", err)
+			}
+		})
+	}
+}
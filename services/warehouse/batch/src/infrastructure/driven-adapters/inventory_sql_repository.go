@@ -0,0 +1,349 @@
+package drivenadapters
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+)
+
+const stockLevelColumns = `product_id, on_hand, reserved, updated_at, version`
+
+const stockReservationColumns = `order_id, product_id, quantity, status, created_at, updated_at`
+
+const stockMovementColumns = `id, product_id, order_id, movement_type, quantity, on_hand_after, reserved_after, reason, occurred_at`
+
+const inventoryOutboxColumns = `id, product_id, event_type, payload, attempts, last_error, created_at, next_attempt_at`
+
+// InventorySQLRepository implements InventoryRepository on top of a relational database.
+// It shares the schema migrations of the SQL batch repository.
+type InventorySQLRepository struct {
+	db     *sql.DB
+	driver string
+}
+
+// NewInventorySQLRepository creates a new SQL inventory repository and applies pending schema migrations
+func NewInventorySQLRepository(db *sql.DB, driver string) (*InventorySQLRepository, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database cannot be nil")
+	}
+
+	if driver != SQLDriverPostgres && driver != SQLDriverSQLite {
+		return nil, fmt.Errorf("unsupported SQL driver: %s", driver)
+	}
+
+	if err := migrateSQLSchema(db, driver); err != nil {
+		return nil, fmt.Errorf("failed to migrate database schema: %w", err)
+	}
+
+	return &InventorySQLRepository{
+		db:     db,
+		driver: driver,
+	}, nil
+}
+
+// FindStock retrieves the stock level of a product
+func (r *InventorySQLRepository) FindStock(productID string) (*domain.StockLevel, error) {
+	stock, err := r.queryStock(`WHERE product_id = ?`, productID)
+	if err != nil {
+		return nil, err
+	}
+	if len(stock) == 0 {
+		return nil, domain.NewNotFoundError("no stock found for product %s", productID)
+	}
+	return stock[0], nil
+}
+
+// GetAllStock retrieves the stock levels of all products, ordered by product ID
+func (r *InventorySQLRepository) GetAllStock() ([]*domain.StockLevel, error) {
+	return r.queryStock(`ORDER BY product_id`)
+}
+
+// FindReservation retrieves the stock reservation of an order
+func (r *InventorySQLRepository) FindReservation(orderID string) (*domain.StockReservation, error) {
+	var (
+		reservation domain.StockReservation
+		status      string
+	)
+	query := rebindQuery(r.driver, `SELECT `+stockReservationColumns+` FROM stock_reservations WHERE order_id = ?`)
+	err := r.db.QueryRow(query, orderID).Scan(
+		&reservation.OrderID,
+		&reservation.ProductID,
+		&reservation.Quantity,
+		&status,
+		&reservation.CreatedAt,
+		&reservation.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, domain.NewNotFoundError("no stock reservation found for order %s", orderID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stock reservation of order %s: %w", orderID, err)
+	}
+	reservation.Status = domain.StockReservationStatus(status)
+	return &reservation, nil
+}
+
+// SaveStock stores the stock level, the changed reservation, the ledger entry and the
+// outbox messages of the events within one transaction. The stock row is only updated
+// if it still has the expected version.
+func (r *InventorySQLRepository) SaveStock(stock *domain.StockLevel, reservation *domain.StockReservation, movement *domain.StockMovement, events ...*domain.InventoryEvent) error {
+	if stock == nil {
+		return fmt.Errorf("stock cannot be nil")
+	}
+
+	messages, err := newInventoryOutboxMessages(events)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	expectedVersion := stock.Version
+	var result sql.Result
+	if expectedVersion == 0 {
+		result, err = tx.Exec(rebindQuery(r.driver, `INSERT INTO stock_levels (`+stockLevelColumns+`)
+			VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT (product_id) DO NOTHING`),
+			stock.ProductID,
+			stock.OnHand,
+			stock.Reserved,
+			stock.UpdatedAt.UTC(),
+			expectedVersion+1,
+		)
+	} else {
+		result, err = tx.Exec(rebindQuery(r.driver, `UPDATE stock_levels SET
+				on_hand = ?,
+				reserved = ?,
+				updated_at = ?,
+				version = ?
+			WHERE product_id = ? AND version = ?`),
+			stock.OnHand,
+			stock.Reserved,
+			stock.UpdatedAt.UTC(),
+			expectedVersion+1,
+			stock.ProductID,
+			expectedVersion,
+		)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save stock of product %s: %w", stock.ProductID, err)
+	}
+	if err := requireAffected(result, newStockConflictError(stock.ProductID, expectedVersion)); err != nil {
+		return err
+	}
+
+	if reservation != nil {
+		if _, err := tx.Exec(rebindQuery(r.driver, `INSERT INTO stock_reservations (`+stockReservationColumns+`)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT (order_id) DO UPDATE SET
+				quantity = excluded.quantity,
+				status = excluded.status,
+				updated_at = excluded.updated_at`),
+			reservation.OrderID,
+			reservation.ProductID,
+			reservation.Quantity,
+			string(reservation.Status),
+			reservation.CreatedAt.UTC(),
+			reservation.UpdatedAt.UTC(),
+		); err != nil {
+			return fmt.Errorf("failed to save stock reservation of order %s: %w", reservation.OrderID, err)
+		}
+	}
+
+	var movementID int64
+	if movement != nil {
+		if err := tx.QueryRow(rebindQuery(r.driver, `INSERT INTO stock_movements
+			(product_id, order_id, movement_type, quantity, on_hand_after, reserved_after, reason, occurred_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			RETURNING id`),
+			movement.ProductID,
+			movement.OrderID,
+			string(movement.Type),
+			movement.Quantity,
+			movement.OnHandAfter,
+			movement.ReservedAfter,
+			movement.Reason,
+			movement.OccurredAt.UTC(),
+		).Scan(&movementID); err != nil {
+			return fmt.Errorf("failed to record %s movement of product %s: %w", movement.Type, movement.ProductID, err)
+		}
+	}
+
+	insert := rebindQuery(r.driver, `INSERT INTO inventory_outbox_messages
+		(product_id, event_type, payload, attempts, last_error, created_at, next_attempt_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`)
+	for _, message := range messages {
+		if _, err := tx.Exec(insert,
+			message.ProductID,
+			string(message.EventType),
+			string(message.Payload),
+			message.Attempts,
+			message.LastError,
+			message.CreatedAt.UTC(),
+			message.NextAttemptAt.UTC(),
+		); err != nil {
+			return fmt.Errorf("failed to enqueue %s event for product %s: %w", message.EventType, message.ProductID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit stock of product %s: %w", stock.ProductID, err)
+	}
+
+	stock.Version = expectedVersion + 1
+	if movement != nil {
+		movement.ID = movementID
+	}
+	return nil
+}
+
+// FindMovements retrieves the stock ledger of a product, oldest movement first
+func (r *InventorySQLRepository) FindMovements(productID string) ([]*domain.StockMovement, error) {
+	query := rebindQuery(r.driver, `SELECT `+stockMovementColumns+` FROM stock_movements
+		WHERE product_id = ?
+		ORDER BY id`)
+	rows, err := r.db.Query(query, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stock movements of product %s: %w", productID, err)
+	}
+	defer rows.Close()
+
+	movements := make([]*domain.StockMovement, 0)
+	for rows.Next() {
+		var (
+			movement     domain.StockMovement
+			movementType string
+		)
+		if err := rows.Scan(
+			&movement.ID,
+			&movement.ProductID,
+			&movement.OrderID,
+			&movementType,
+			&movement.Quantity,
+			&movement.OnHandAfter,
+			&movement.ReservedAfter,
+			&movement.Reason,
+			&movement.OccurredAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan stock movement: %w", err)
+		}
+		movement.Type = domain.StockMovementType(movementType)
+		movements = append(movements, &movement)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read stock movements: %w", err)
+	}
+	return movements, nil
+}
+
+// PendingInventoryOutboxMessages returns up to limit undelivered messages after the given ID in insertion order
+func (r *InventorySQLRepository) PendingInventoryOutboxMessages(afterID int64, limit int) ([]*domain.InventoryOutboxMessage, error) {
+	query := `SELECT ` + inventoryOutboxColumns + ` FROM inventory_outbox_messages
+		WHERE published_at IS NULL AND dead_at IS NULL AND id > ?
+		ORDER BY id`
+	args := []interface{}{afterID}
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+
+	rows, err := r.db.Query(rebindQuery(r.driver, query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query inventory outbox messages: %w", err)
+	}
+	defer rows.Close()
+
+	messages := make([]*domain.InventoryOutboxMessage, 0)
+	for rows.Next() {
+		var (
+			message   domain.InventoryOutboxMessage
+			eventType string
+			payload   string
+		)
+		if err := rows.Scan(
+			&message.ID,
+			&message.ProductID,
+			&eventType,
+			&payload,
+			&message.Attempts,
+			&message.LastError,
+			&message.CreatedAt,
+			&message.NextAttemptAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan inventory outbox message: %w", err)
+		}
+		message.EventType = domain.InventoryEventType(eventType)
+		message.Payload = []byte(payload)
+		messages = append(messages, &message)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read inventory outbox messages: %w", err)
+	}
+	return messages, nil
+}
+
+// MarkInventoryOutboxMessagePublished records that a message was delivered
+func (r *InventorySQLRepository) MarkInventoryOutboxMessagePublished(id int64) error {
+	result, err := r.db.Exec(rebindQuery(r.driver, `UPDATE inventory_outbox_messages SET published_at = ? WHERE id = ?`),
+		time.Now().UTC(), id)
+	if err != nil {
+		return fmt.Errorf("failed to mark inventory outbox message %d as published: %w", id, err)
+	}
+	return requireAffected(result, domain.NewNotFoundError("inventory outbox message %d not found", id))
+}
+
+// MarkInventoryOutboxMessageFailed records a failed delivery and schedules the next attempt
+func (r *InventorySQLRepository) MarkInventoryOutboxMessageFailed(id int64, reason string, nextAttemptAt time.Time) error {
+	result, err := r.db.Exec(rebindQuery(r.driver, `UPDATE inventory_outbox_messages
+		SET attempts = attempts + 1, last_error = ?, next_attempt_at = ?
+		WHERE id = ?`), reason, nextAttemptAt.UTC(), id)
+	if err != nil {
+		return fmt.Errorf("failed to mark inventory outbox message %d as failed: %w", id, err)
+	}
+	return requireAffected(result, domain.NewNotFoundError("inventory outbox message %d not found", id))
+}
+
+// MarkInventoryOutboxMessageDead records that a message cannot be delivered and stops retrying it
+func (r *InventorySQLRepository) MarkInventoryOutboxMessageDead(id int64, reason string) error {
+	result, err := r.db.Exec(rebindQuery(r.driver, `UPDATE inventory_outbox_messages
+		SET attempts = attempts + 1, last_error = ?, dead_at = ?
+		WHERE id = ?`), reason, time.Now().UTC(), id)
+	if err != nil {
+		return fmt.Errorf("failed to mark inventory outbox message %d as dead: %w", id, err)
+	}
+	return requireAffected(result, domain.NewNotFoundError("inventory outbox message %d not found", id))
+}
+
+// queryStock loads the stock levels matching the given clause
+func (r *InventorySQLRepository) queryStock(clause string, args ...interface{}) ([]*domain.StockLevel, error) {
+	rows, err := r.db.Query(rebindQuery(r.driver, `SELECT `+stockLevelColumns+` FROM stock_levels `+clause), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stock levels: %w", err)
+	}
+	defer rows.Close()
+
+	stock := make([]*domain.StockLevel, 0)
+	for rows.Next() {
+		var level domain.StockLevel
+		if err := rows.Scan(
+			&level.ProductID,
+			&level.OnHand,
+			&level.Reserved,
+			&level.UpdatedAt,
+			&level.Version,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan stock level: %w", err)
+		}
+		stock = append(stock, &level)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read stock levels: %w", err)
+	}
+	return stock, nil
+}
@@ -0,0 +1,166 @@
+package drivenadapters
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+)
+
+// KafkaSASLMechanism is how the service authenticates to the Kafka brokers
+type KafkaSASLMechanism string
+
+const (
+	KafkaSASLNone        KafkaSASLMechanism = "none"
+	KafkaSASLPlain       KafkaSASLMechanism = "plain"
+	KafkaSASLScramSHA256 KafkaSASLMechanism = "scram-sha-256"
+	KafkaSASLScramSHA512 KafkaSASLMechanism = "scram-sha-512"
+)
+
+// ParseKafkaSASLMechanism parses a SASL mechanism name; an empty name means none
+func ParseKafkaSASLMechanism(value string) (KafkaSASLMechanism, error) {
+	switch mechanism := KafkaSASLMechanism(value); mechanism {
+	case "":
+		return KafkaSASLNone, nil
+	case KafkaSASLNone, KafkaSASLPlain, KafkaSASLScramSHA256, KafkaSASLScramSHA512:
+		return mechanism, nil
+	}
+	return "", fmt.Errorf("unknown SASL mechanism %q (expected %q, %q, %q or %q)",
+		value, KafkaSASLNone, KafkaSASLPlain, KafkaSASLScramSHA256, KafkaSASLScramSHA512)
+}
+
+// KafkaSecurity holds how the service authenticates to the Kafka brokers and encrypts
+// the connections. TLS is enabled by TLS or by any of the certificate files.
+type KafkaSecurity struct {
+	SASLMechanism KafkaSASLMechanism
+	Username      string
+	Password      string
+
+	TLS bool
+	// CAFile verifies the brokers with the given PEM certificates instead of the system ones
+	CAFile string
+	// CertFile and KeyFile authenticate the service with a client certificate (mutual TLS)
+	CertFile string
+	KeyFile  string
+	// InsecureSkipVerify accepts any broker certificate; only meant for development
+	InsecureSkipVerify bool
+}
+
+// KafkaConnection is how the service reaches the Kafka cluster: the brokers, TLS and
+// SASL. The same connection is shared by the readers, the writers and the admin client.
+type KafkaConnection struct {
+	brokers   []string
+	tls       *tls.Config
+	sasl      sasl.Mechanism
+	transport *kafka.Transport
+}
+
+// NewKafkaConnection creates a connection to the brokers with the given security. It
+// fails when the certificates cannot be loaded or the SASL credentials are incomplete.
+func NewKafkaConnection(brokers []string, security KafkaSecurity) (*KafkaConnection, error) {
+	if len(brokers) == 0 {
+		return nil, fmt.Errorf("no Kafka brokers configured")
+	}
+
+	tlsConfig, err := newKafkaTLSConfig(security)
+	if err != nil {
+		return nil, err
+	}
+	mechanism, err := newKafkaSASLMechanism(security)
+	if err != nil {
+		return nil, err
+	}
+
+	return &KafkaConnection{
+		brokers: brokers,
+		tls:     tlsConfig,
+		sasl:    mechanism,
+		transport: &kafka.Transport{
+			DialTimeout: 10 * time.Second,
+			TLS:         tlsConfig,
+			SASL:        mechanism,
+		},
+	}, nil
+}
+
+// Brokers returns the addresses of the brokers
+func (c *KafkaConnection) Brokers() []string {
+	return c.brokers
+}
+
+// Dialer returns a dialer for Kafka readers that connects with the TLS and SASL of the connection
+func (c *KafkaConnection) Dialer() *kafka.Dialer {
+	return &kafka.Dialer{
+		Timeout:       10 * time.Second,
+		DualStack:     true,
+		TLS:           c.tls,
+		SASLMechanism: c.sasl,
+	}
+}
+
+// addr returns the address of the cluster for writers and the admin client
+func (c *KafkaConnection) addr() net.Addr {
+	return kafka.TCP(c.brokers...)
+}
+
+// newKafkaTLSConfig builds the TLS configuration of the security, nil without TLS
+func newKafkaTLSConfig(security KafkaSecurity) (*tls.Config, error) {
+	if !security.TLS && security.CAFile == "" && security.CertFile == "" && security.KeyFile == "" {
+		return nil, nil
+	}
+
+	config := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: security.InsecureSkipVerify,
+	}
+	if security.CAFile != "" {
+		pem, err := os.ReadFile(security.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Kafka CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("Kafka CA file %s holds no PEM certificates", security.CAFile)
+		}
+		config.RootCAs = pool
+	}
+
+	if (security.CertFile == "") != (security.KeyFile == "") {
+		return nil, fmt.Errorf("a Kafka client certificate needs both a certificate and a key file")
+	}
+	if security.CertFile != "" {
+		certificate, err := tls.LoadX509KeyPair(security.CertFile, security.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load Kafka client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+	return config, nil
+}
+
+// newKafkaSASLMechanism builds the SASL mechanism of the security, nil without SASL
+func newKafkaSASLMechanism(security KafkaSecurity) (sasl.Mechanism, error) {
+	if security.SASLMechanism == "" || security.SASLMechanism == KafkaSASLNone {
+		return nil, nil
+	}
+	if security.Username == "" || security.Password == "" {
+		return nil, fmt.Errorf("SASL mechanism %s needs a username and a password", security.SASLMechanism)
+	}
+
+	switch security.SASLMechanism {
+	case KafkaSASLPlain:
+		return plain.Mechanism{Username: security.Username, Password: security.Password}, nil
+	case KafkaSASLScramSHA256:
+		return scram.Mechanism(scram.SHA256, security.Username, security.Password)
+	case KafkaSASLScramSHA512:
+		return scram.Mechanism(scram.SHA512, security.Username, security.Password)
+	}
+	return nil, fmt.Errorf("unknown SASL mechanism %q", security.SASLMechanism)
+}
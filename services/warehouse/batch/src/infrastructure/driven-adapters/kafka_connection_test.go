@@ -0,0 +1,147 @@
+package drivenadapters
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestCertificate writes a self-signed certificate and its key as PEM files to dir
+// and returns their paths
+func writeTestCertificate(t *testing.T, dir, name string) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+
+	certFile = filepath.Join(dir, name+".crt")
+	keyFile = filepath.Join(dir, name+".key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("Failed to write certificate: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+	return certFile, keyFile
+}
+
+func TestParseKafkaSASLMechanism(t *testing.T) {
+	tests := map[string]KafkaSASLMechanism{
+		"":              KafkaSASLNone,
+		"none":          KafkaSASLNone,
+		"plain":         KafkaSASLPlain,
+		"scram-sha-256": KafkaSASLScramSHA256,
+		"scram-sha-512": KafkaSASLScramSHA512,
+	}
+	for value, expected := range tests {
+		mechanism, err := ParseKafkaSASLMechanism(value)
+		if err != nil || mechanism != expected {
+			t.Errorf("Expected %q to parse as %q, got %q (%v)", value, expected, mechanism, err)
+		}
+	}
+	if _, err := ParseKafkaSASLMechanism("gssapi"); err == nil {
+		t.Error("Expected an unsupported mechanism to be rejected")
+	}
+}
+
+func TestNewKafkaConnection(t *testing.T) {
+	brokers := []string{"kafka-1:9093", "kafka-2:9093"}
+
+	conn, err := NewKafkaConnection(brokers, KafkaSecurity{})
+	if err != nil {
+		t.Fatalf("Failed to create plaintext connection: %v", err)
+	}
+	if conn.transport.TLS != nil || conn.transport.SASL != nil || conn.Dialer().TLS != nil || conn.Dialer().SASLMechanism != nil {
+		t.Error("Expected a plaintext connection without TLS or SASL")
+	}
+	if got := conn.addr().String(); got != "kafka-1:9093,kafka-2:9093" {
+		t.Errorf("Expected both brokers in the cluster address, got %s", got)
+	}
+
+	dir := t.TempDir()
+	caFile, _ := writeTestCertificate(t, dir, "ca")
+	certFile, keyFile := writeTestCertificate(t, dir, "client")
+	conn, err = NewKafkaConnection(brokers, KafkaSecurity{
+		SASLMechanism: KafkaSASLScramSHA512,
+		Username:      "batch-service",
+		Password:      "secret",
+		CAFile:        caFile,
+		CertFile:      certFile,
+		KeyFile:       keyFile,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create secured connection: %v", err)
+	}
+	dialer := conn.Dialer()
+	if dialer.TLS == nil || dialer.TLS.RootCAs == nil || len(dialer.TLS.Certificates) != 1 {
+		t.Errorf("Expected the reader dialer to verify with the CA and present the client certificate, got %+v", dialer.TLS)
+	}
+	if dialer.SASLMechanism == nil || dialer.SASLMechanism.Name() != "SCRAM-SHA-512" {
+		t.Errorf("Expected the reader dialer to authenticate with SCRAM-SHA-512, got %v", dialer.SASLMechanism)
+	}
+	if conn.transport.TLS != dialer.TLS || conn.transport.SASL != dialer.SASLMechanism {
+		t.Error("Expected the writers to share the TLS and SASL settings of the readers")
+	}
+
+	conn, err = NewKafkaConnection(brokers, KafkaSecurity{SASLMechanism: KafkaSASLPlain, Username: "batch-service", Password: "secret", TLS: true})
+	if err != nil {
+		t.Fatalf("Failed to create SASL/PLAIN connection: %v", err)
+	}
+	if conn.transport.SASL.Name() != "PLAIN" || conn.transport.TLS == nil || conn.transport.TLS.RootCAs != nil {
+		t.Errorf("Expected PLAIN over TLS verified with the system CAs, got %v and %+v", conn.transport.SASL, conn.transport.TLS)
+	}
+}
+
+func TestNewKafkaConnection_InvalidSettings(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCertificate(t, dir, "client")
+	notPEM := filepath.Join(dir, "not-pem.crt")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	tests := map[string]struct {
+		brokers  []string
+		security KafkaSecurity
+	}{
+		"no brokers":             {nil, KafkaSecurity{}},
+		"missing password":       {[]string{"kafka:9092"}, KafkaSecurity{SASLMechanism: KafkaSASLScramSHA512, Username: "batch-service"}},
+		"missing CA file":        {[]string{"kafka:9092"}, KafkaSecurity{CAFile: filepath.Join(dir, "missing.crt")}},
+		"CA file without PEM":    {[]string{"kafka:9092"}, KafkaSecurity{CAFile: notPEM}},
+		"certificate, no key":    {[]string{"kafka:9092"}, KafkaSecurity{CertFile: certFile}},
+		"key, no certificate":    {[]string{"kafka:9092"}, KafkaSecurity{KeyFile: keyFile}},
+		"mismatched key pair":    {[]string{"kafka:9092"}, KafkaSecurity{CertFile: certFile, KeyFile: notPEM}},
+		"unknown SASL mechanism": {[]string{"kafka:9092"}, KafkaSecurity{SASLMechanism: "gssapi", Username: "u", Password: "p"}},
+	}
+	for name, tt := range tests {
+		if _, err := NewKafkaConnection(tt.brokers, tt.security); err == nil {
+			t.Errorf("%s: expected the connection to be rejected", name)
+		}
+	}
+}
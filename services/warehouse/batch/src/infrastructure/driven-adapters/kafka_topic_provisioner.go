@@ -0,0 +1,210 @@
+package drivenadapters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// topicReadyPollInterval is how often the provisioner checks whether created topics
+// have a leader for every partition
+const topicReadyPollInterval = 200 * time.Millisecond
+
+// KafkaTopicSpec declares a topic the service publishes to and how it is configured
+type KafkaTopicSpec struct {
+	Name              string
+	Partitions        int
+	ReplicationFactor int
+	// Retention is how long messages are kept; zero keeps the broker default and a
+	// negative retention keeps messages forever
+	Retention time.Duration
+	// Compacted keeps the latest message of every key instead of deleting old messages
+	Compacted bool
+}
+
+// configs returns the topic-level configuration of the spec
+func (s KafkaTopicSpec) configs() map[string]string {
+	configs := map[string]string{"cleanup.policy": "delete"}
+	if s.Compacted {
+		configs["cleanup.policy"] = "compact"
+	}
+	switch {
+	case s.Retention < 0:
+		configs["retention.ms"] = "-1"
+	case s.Retention > 0:
+		configs["retention.ms"] = strconv.FormatInt(s.Retention.Milliseconds(), 10)
+	}
+	return configs
+}
+
+// kafkaTopicAdmin is the part of the Kafka admin API the provisioner uses; it is
+// implemented by kafka.Client
+type kafkaTopicAdmin interface {
+	CreateTopics(ctx context.Context, req *kafka.CreateTopicsRequest) (*kafka.CreateTopicsResponse, error)
+	IncrementalAlterConfigs(ctx context.Context, req *kafka.IncrementalAlterConfigsRequest) (*kafka.IncrementalAlterConfigsResponse, error)
+	Metadata(ctx context.Context, req *kafka.MetadataRequest) (*kafka.MetadataResponse, error)
+}
+
+// KafkaTopicProvisioner declares the topics of the service through the Kafka admin API
+type KafkaTopicProvisioner struct {
+	admin kafkaTopicAdmin
+}
+
+// NewKafkaTopicProvisioner creates a new KafkaTopicProvisioner for the cluster of the connection
+func NewKafkaTopicProvisioner(conn *KafkaConnection) *KafkaTopicProvisioner {
+	return &KafkaTopicProvisioner{
+		admin: &kafka.Client{Addr: conn.addr(), Timeout: 10 * time.Second, Transport: conn.transport},
+	}
+}
+
+// ProvisionTopics creates the declared topics that do not exist, applies the retention
+// and cleanup policy of the ones that do, and waits until every partition of every
+// topic has a leader. Existing topics keep their partitions and replication factor; a
+// mismatch is only logged. It fails when a topic cannot be created or configured, e.g.
+// because its replication factor exceeds the number of brokers, or is not ready before
+// the context is done.
+func (p *KafkaTopicProvisioner) ProvisionTopics(ctx context.Context, specs []KafkaTopicSpec) error {
+	if len(specs) == 0 {
+		return nil
+	}
+
+	topics := make([]kafka.TopicConfig, len(specs))
+	for i, spec := range specs {
+		topics[i] = kafka.TopicConfig{
+			Topic:             spec.Name,
+			NumPartitions:     spec.Partitions,
+			ReplicationFactor: spec.ReplicationFactor,
+		}
+		for name, value := range spec.configs() {
+			topics[i].ConfigEntries = append(topics[i].ConfigEntries, kafka.ConfigEntry{ConfigName: name, ConfigValue: value})
+		}
+	}
+
+	response, err := p.admin.CreateTopics(ctx, &kafka.CreateTopicsRequest{Topics: topics})
+	if err != nil {
+		return fmt.Errorf("failed to create Kafka topics: %w", err)
+	}
+
+	var existing []KafkaTopicSpec
+	for _, spec := range specs {
+		switch err := response.Errors[spec.Name]; {
+		case err == nil:
+			log.Printf("Created Kafka topic %s with %d partitions and replication factor %d",
+				spec.Name, spec.Partitions, spec.ReplicationFactor)
+		case errors.Is(err, kafka.TopicAlreadyExists):
+			existing = append(existing, spec)
+		default:
+			return fmt.Errorf("failed to create Kafka topic %s with %d partitions and replication factor %d: %w",
+				spec.Name, spec.Partitions, spec.ReplicationFactor, err)
+		}
+	}
+
+	if err := p.configureTopics(ctx, existing); err != nil {
+		return err
+	}
+	return p.waitForTopics(ctx, specs)
+}
+
+// configureTopics applies the retention and cleanup policy of topics that already exist
+func (p *KafkaTopicProvisioner) configureTopics(ctx context.Context, specs []KafkaTopicSpec) error {
+	if len(specs) == 0 {
+		return nil
+	}
+
+	request := &kafka.IncrementalAlterConfigsRequest{}
+	for _, spec := range specs {
+		resource := kafka.IncrementalAlterConfigsRequestResource{
+			ResourceType: kafka.ResourceTypeTopic,
+			ResourceName: spec.Name,
+		}
+		for name, value := range spec.configs() {
+			resource.Configs = append(resource.Configs, kafka.IncrementalAlterConfigsRequestConfig{
+				Name:            name,
+				Value:           value,
+				ConfigOperation: kafka.ConfigOperationSet,
+			})
+		}
+		request.Resources = append(request.Resources, resource)
+	}
+
+	response, err := p.admin.IncrementalAlterConfigs(ctx, request)
+	if err != nil {
+		return fmt.Errorf("failed to configure Kafka topics: %w", err)
+	}
+	for _, resource := range response.Resources {
+		if resource.Error != nil {
+			return fmt.Errorf("failed to configure Kafka topic %s: %w", resource.ResourceName, resource.Error)
+		}
+	}
+	return nil
+}
+
+// waitForTopics polls the cluster metadata until every partition of the topics has a leader
+func (p *KafkaTopicProvisioner) waitForTopics(ctx context.Context, specs []KafkaTopicSpec) error {
+	names := make([]string, len(specs))
+	for i, spec := range specs {
+		names[i] = spec.Name
+	}
+
+	for {
+		err := p.checkTopicsReady(ctx, specs, names)
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("Kafka topics not ready: %w", err)
+		case <-time.After(topicReadyPollInterval):
+		}
+	}
+}
+
+// checkTopicsReady returns an error describing the first topic that is missing or has
+// a partition without a leader, or nil when all topics are ready
+func (p *KafkaTopicProvisioner) checkTopicsReady(ctx context.Context, specs []KafkaTopicSpec, names []string) error {
+	metadata, err := p.admin.Metadata(ctx, &kafka.MetadataRequest{Topics: names})
+	if err != nil {
+		return fmt.Errorf("failed to read Kafka metadata: %w", err)
+	}
+
+	topics := make(map[string]kafka.Topic, len(metadata.Topics))
+	for _, topic := range metadata.Topics {
+		topics[topic.Name] = topic
+	}
+
+	for _, spec := range specs {
+		topic, ok := topics[spec.Name]
+		switch {
+		case !ok:
+			return fmt.Errorf("topic %s is missing from the cluster metadata", spec.Name)
+		case topic.Error != nil:
+			return fmt.Errorf("topic %s: %w", spec.Name, topic.Error)
+		case len(topic.Partitions) == 0:
+			return fmt.Errorf("topic %s has no partitions yet", spec.Name)
+		}
+		for _, partition := range topic.Partitions {
+			if partition.Error != nil {
+				return fmt.Errorf("partition %d of topic %s: %w", partition.ID, spec.Name, partition.Error)
+			}
+		}
+	}
+
+	for _, spec := range specs {
+		topic := topics[spec.Name]
+		if len(topic.Partitions) != spec.Partitions {
+			log.Printf("Warning: Kafka topic %s has %d partitions, %d are configured; existing topics are not repartitioned",
+				spec.Name, len(topic.Partitions), spec.Partitions)
+		}
+		if replicas := len(topic.Partitions[0].Replicas); replicas != spec.ReplicationFactor {
+			log.Printf("Warning: Kafka topic %s has replication factor %d, %d is configured; existing topics are not reassigned",
+				spec.Name, replicas, spec.ReplicationFactor)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package drivenadapters
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// fakeKafkaTopicAdmin is an in-memory cluster with the given topics and their partition counts
+type fakeKafkaTopicAdmin struct {
+	topics       map[string]int
+	createErrors map[string]error
+	configured   map[string]map[string]string
+	// leaderlessPolls is how many metadata requests report partitions without a leader
+	leaderlessPolls int
+}
+
+func newFakeKafkaTopicAdmin(topics map[string]int) *fakeKafkaTopicAdmin {
+	return &fakeKafkaTopicAdmin{
+		topics:       topics,
+		createErrors: make(map[string]error),
+		configured:   make(map[string]map[string]string),
+	}
+}
+
+func (a *fakeKafkaTopicAdmin) CreateTopics(ctx context.Context, req *kafka.CreateTopicsRequest) (*kafka.CreateTopicsResponse, error) {
+	response := &kafka.CreateTopicsResponse{Errors: make(map[string]error)}
+	for _, topic := range req.Topics {
+		if err := a.createErrors[topic.Topic]; err != nil {
+			response.Errors[topic.Topic] = err
+			continue
+		}
+		if _, ok := a.topics[topic.Topic]; ok {
+			response.Errors[topic.Topic] = kafka.TopicAlreadyExists
+			continue
+		}
+		a.topics[topic.Topic] = topic.NumPartitions
+		a.configured[topic.Topic] = make(map[string]string)
+		for _, entry := range topic.ConfigEntries {
+			a.configured[topic.Topic][entry.ConfigName] = entry.ConfigValue
+		}
+	}
+	return response, nil
+}
+
+func (a *fakeKafkaTopicAdmin) IncrementalAlterConfigs(ctx context.Context, req *kafka.IncrementalAlterConfigsRequest) (*kafka.IncrementalAlterConfigsResponse, error) {
+	response := &kafka.IncrementalAlterConfigsResponse{}
+	for _, resource := range req.Resources {
+		configs := make(map[string]string)
+		for _, config := range resource.Configs {
+			configs[config.Name] = config.Value
+		}
+		a.configured[resource.ResourceName] = configs
+		response.Resources = append(response.Resources, kafka.IncrementalAlterConfigsResponseResource{
+			ResourceType: resource.ResourceType,
+			ResourceName: resource.ResourceName,
+		})
+	}
+	return response, nil
+}
+
+func (a *fakeKafkaTopicAdmin) Metadata(ctx context.Context, req *kafka.MetadataRequest) (*kafka.MetadataResponse, error) {
+	response := &kafka.MetadataResponse{}
+	for _, name := range req.Topics {
+		partitions, ok := a.topics[name]
+		if !ok {
+			response.Topics = append(response.Topics, kafka.Topic{Name: name, Error: kafka.UnknownTopicOrPartition})
+			continue
+		}
+
+		topic := kafka.Topic{Name: name}
+		for id := 0; id < partitions; id++ {
+			partition := kafka.Partition{Topic: name, ID: id, Replicas: []kafka.Broker{{ID: 1}}}
+			if a.leaderlessPolls > 0 {
+				partition.Error = kafka.LeaderNotAvailable
+			}
+			topic.Partitions = append(topic.Partitions, partition)
+		}
+		response.Topics = append(response.Topics, topic)
+	}
+	if a.leaderlessPolls > 0 {
+		a.leaderlessPolls--
+	}
+	return response, nil
+}
+
+func TestKafkaTopicProvisioner_ProvisionTopics(t *testing.T) {
+	admin := newFakeKafkaTopicAdmin(map[string]int{"dead-letters": 1})
+	admin.leaderlessPolls = 2
+	provisioner := &KafkaTopicProvisioner{admin: admin}
+
+	specs := []KafkaTopicSpec{
+		{Name: "batch-events", Partitions: 3, ReplicationFactor: 1, Retention: 7 * 24 * time.Hour},
+		{Name: "batch-state", Partitions: 3, ReplicationFactor: 1, Retention: -1, Compacted: true},
+		{Name: "dead-letters", Partitions: 1, ReplicationFactor: 1, Retention: 30 * 24 * time.Hour},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := provisioner.ProvisionTopics(ctx, specs); err != nil {
+		t.Fatalf("Failed to provision topics: %v", err)
+	}
+
+	if admin.topics["batch-events"] != 3 || admin.topics["batch-state"] != 3 {
+		t.Errorf("Expected the missing topics to be created with 3 partitions, got %v", admin.topics)
+	}
+	expected := map[string]map[string]string{
+		"batch-events": {"cleanup.policy": "delete", "retention.ms": "604800000"},
+		"batch-state":  {"cleanup.policy": "compact", "retention.ms": "-1"},
+		// The existing topic is reconfigured
+		"dead-letters": {"cleanup.policy": "delete", "retention.ms": "2592000000"},
+	}
+	for name, configs := range expected {
+		for key, value := range configs {
+			if got := admin.configured[name][key]; got != value {
+				t.Errorf("Expected %s=%s for topic %s, got %q", key, value, name, got)
+			}
+		}
+	}
+	if admin.leaderlessPolls != 0 {
+		t.Errorf("Expected provisioning to wait for the partition leaders, %d polls left", admin.leaderlessPolls)
+	}
+}
+
+func TestKafkaTopicProvisioner_FailsFast(t *testing.T) {
+	admin := newFakeKafkaTopicAdmin(map[string]int{})
+	admin.createErrors["batch-events"] = kafka.InvalidReplicationFactor
+	provisioner := &KafkaTopicProvisioner{admin: admin}
+
+	specs := []KafkaTopicSpec{{Name: "batch-events", Partitions: 3, ReplicationFactor: 3}}
+	err := provisioner.ProvisionTopics(context.Background(), specs)
+	if !errors.Is(err, kafka.InvalidReplicationFactor) {
+		t.Fatalf("Expected the invalid replication factor to be reported, got %v", err)
+	}
+
+	// A topic that never gets a leader fails when the context is done
+	admin = newFakeKafkaTopicAdmin(map[string]int{})
+	admin.leaderlessPolls = 1000
+	provisioner = &KafkaTopicProvisioner{admin: admin}
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	err = provisioner.ProvisionTopics(ctx, []KafkaTopicSpec{{Name: "batch-events", Partitions: 1, ReplicationFactor: 1}})
+	if !errors.Is(err, kafka.LeaderNotAvailable) {
+		t.Errorf("Expected the leaderless partition to be reported, got %v", err)
+	}
+}
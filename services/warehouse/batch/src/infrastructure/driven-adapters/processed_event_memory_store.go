@@ -0,0 +1,84 @@
+package drivenadapters
+
+import (
+	"sync"
+	"time"
+)
+
+// processedEventRecord is a handled event, or an event claimed by a worker
+type processedEventRecord struct {
+	at      time.Time
+	claimed bool
+}
+
+// ProcessedEventMemoryStore implements ProcessedEventStore using in-memory storage
+type ProcessedEventMemoryStore struct {
+	processed map[string]processedEventRecord
+	mutex     sync.RWMutex
+}
+
+// NewProcessedEventMemoryStore creates a new in-memory processed event store
+func NewProcessedEventMemoryStore() *ProcessedEventMemoryStore {
+	return &ProcessedEventMemoryStore{
+		processed: make(map[string]processedEventRecord),
+	}
+}
+
+// IsProcessed reports whether the event has already been handled
+func (s *ProcessedEventMemoryStore) IsProcessed(eventID string) (bool, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	record, exists := s.processed[eventID]
+	return exists && !record.claimed, nil
+}
+
+// ClaimEvent records that a worker started handling the event, unless it is already
+// handled or claimed since staleBefore
+func (s *ProcessedEventMemoryStore) ClaimEvent(eventID string, claimedAt, staleBefore time.Time) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if record, exists := s.processed[eventID]; exists && (!record.claimed || !record.at.Before(staleBefore)) {
+		return false, nil
+	}
+	s.processed[eventID] = processedEventRecord{at: claimedAt, claimed: true}
+	return true, nil
+}
+
+// ReleaseEvent forgets the claim of an event that could not be handled
+func (s *ProcessedEventMemoryStore) ReleaseEvent(eventID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if record, exists := s.processed[eventID]; exists && record.claimed {
+		delete(s.processed, eventID)
+	}
+	return nil
+}
+
+// MarkProcessed records that the event has been handled
+func (s *ProcessedEventMemoryStore) MarkProcessed(eventID string, processedAt time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if record, exists := s.processed[eventID]; !exists || record.claimed {
+		s.processed[eventID] = processedEventRecord{at: processedAt}
+	}
+	return nil
+}
+
+// PurgeProcessedBefore forgets the events handled before the cutoff
+func (s *ProcessedEventMemoryStore) PurgeProcessedBefore(cutoff time.Time) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	purged := 0
+	for eventID, record := range s.processed {
+		if record.at.Before(cutoff) {
+			delete(s.processed, eventID)
+			purged++
+		}
+	}
+	return purged, nil
+}
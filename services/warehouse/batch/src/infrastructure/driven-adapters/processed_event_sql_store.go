@@ -0,0 +1,107 @@
+package drivenadapters
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Status values of the processed_events rows; processed_at is the claim time of a claimed event
+const (
+	processedEventStatusClaimed   = "claimed"
+	processedEventStatusProcessed = "processed"
+)
+
+// ProcessedEventSQLStore implements ProcessedEventStore on top of a relational database.
+// It shares the schema migrations of the SQL batch repository.
+type ProcessedEventSQLStore struct {
+	db     *sql.DB
+	driver string
+}
+
+// NewProcessedEventSQLStore creates a new SQL processed event store and applies pending schema migrations
+func NewProcessedEventSQLStore(db *sql.DB, driver string) (*ProcessedEventSQLStore, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database cannot be nil")
+	}
+
+	if driver != SQLDriverPostgres && driver != SQLDriverSQLite {
+		return nil, fmt.Errorf("unsupported SQL driver: %s", driver)
+	}
+
+	if err := migrateSQLSchema(db, driver); err != nil {
+		return nil, fmt.Errorf("failed to migrate database schema: %w", err)
+	}
+
+	return &ProcessedEventSQLStore{
+		db:     db,
+		driver: driver,
+	}, nil
+}
+
+// IsProcessed reports whether the event has already been handled
+func (s *ProcessedEventSQLStore) IsProcessed(eventID string) (bool, error) {
+	var count int
+	query := rebindQuery(s.driver, `SELECT COUNT(*) FROM processed_events WHERE event_id = ? AND status = ?`)
+	if err := s.db.QueryRow(query, eventID, processedEventStatusProcessed).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to check processed event %s: %w", eventID, err)
+	}
+	return count > 0, nil
+}
+
+// ClaimEvent records that a worker started handling the event, unless it is already
+// handled or claimed since staleBefore. The insert, or the takeover of a stale claim,
+// is a single statement, so concurrent workers cannot both claim the event.
+func (s *ProcessedEventSQLStore) ClaimEvent(eventID string, claimedAt, staleBefore time.Time) (bool, error) {
+	query := rebindQuery(s.driver, `INSERT INTO processed_events (event_id, processed_at, status)
+		VALUES (?, ?, ?)
+		ON CONFLICT (event_id) DO UPDATE SET processed_at = excluded.processed_at
+		WHERE processed_events.status = ? AND processed_events.processed_at < ?`)
+	result, err := s.db.Exec(query, eventID, claimedAt.UTC(), processedEventStatusClaimed,
+		processedEventStatusClaimed, staleBefore.UTC())
+	if err != nil {
+		return false, fmt.Errorf("failed to claim event %s: %w", eventID, err)
+	}
+
+	claimed, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to read affected rows: %w", err)
+	}
+	return claimed > 0, nil
+}
+
+// ReleaseEvent forgets the claim of an event that could not be handled
+func (s *ProcessedEventSQLStore) ReleaseEvent(eventID string) error {
+	query := rebindQuery(s.driver, `DELETE FROM processed_events WHERE event_id = ? AND status = ?`)
+	if _, err := s.db.Exec(query, eventID, processedEventStatusClaimed); err != nil {
+		return fmt.Errorf("failed to release event %s: %w", eventID, err)
+	}
+	return nil
+}
+
+// MarkProcessed records that the event has been handled
+func (s *ProcessedEventSQLStore) MarkProcessed(eventID string, processedAt time.Time) error {
+	query := rebindQuery(s.driver, `INSERT INTO processed_events (event_id, processed_at, status)
+		VALUES (?, ?, ?)
+		ON CONFLICT (event_id) DO UPDATE SET processed_at = excluded.processed_at, status = excluded.status
+		WHERE processed_events.status = ?`)
+	if _, err := s.db.Exec(query, eventID, processedAt.UTC(), processedEventStatusProcessed, processedEventStatusClaimed); err != nil {
+		return fmt.Errorf("failed to mark event %s as processed: %w", eventID, err)
+	}
+	return nil
+}
+
+// PurgeProcessedBefore forgets the events handled before the cutoff
+func (s *ProcessedEventSQLStore) PurgeProcessedBefore(cutoff time.Time) (int, error) {
+	query := rebindQuery(s.driver, `DELETE FROM processed_events WHERE processed_at < ?`)
+	result, err := s.db.Exec(query, cutoff.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge processed events: %w", err)
+	}
+
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read affected rows: %w", err)
+	}
+	return int(purged), nil
+}
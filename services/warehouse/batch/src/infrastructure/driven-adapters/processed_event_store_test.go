@@ -0,0 +1,93 @@
+package drivenadapters
+
+import (
+	"testing"
+	"time"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+)
+
+func TestProcessedEventStores(t *testing.T) {
+	sqlStore, err := NewProcessedEventSQLStore(newTestSQLDB(t), SQLDriverSQLite)
+	if err != nil {
+		t.Fatalf("Failed to create SQL processed event store: %v", err)
+	}
+
+	stores := map[string]domain.ProcessedEventStore{
+		"memory": NewProcessedEventMemoryStore(),
+		"sql":    sqlStore,
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			now := time.Now()
+
+			if processed, err := store.IsProcessed("order-events/0/1"); err != nil || processed {
+				t.Fatalf("Expected unknown event not to be processed, got %v (err: %v)", processed, err)
+			}
+
+			if err := store.MarkProcessed("order-events/0/1", now.Add(-2*time.Hour)); err != nil {
+				t.Fatalf("Failed to mark event: %v", err)
+			}
+			if err := store.MarkProcessed("evt-2", now); err != nil {
+				t.Fatalf("Failed to mark event: %v", err)
+			}
+			// Marking twice is not an error
+			if err := store.MarkProcessed("evt-2", now); err != nil {
+				t.Fatalf("Failed to mark event again: %v", err)
+			}
+
+			if processed, _ := store.IsProcessed("order-events/0/1"); !processed {
+				t.Error("Expected event to be processed")
+			}
+
+			purged, err := store.PurgeProcessedBefore(now.Add(-time.Hour))
+			if err != nil {
+				t.Fatalf("Failed to purge: %v", err)
+			}
+			if purged != 1 {
+				t.Errorf("Expected 1 purged event, got %d", purged)
+			}
+
+			if processed, _ := store.IsProcessed("order-events/0/1"); processed {
+				t.Error("Expected expired event to be forgotten")
+			}
+			if processed, _ := store.IsProcessed("evt-2"); !processed {
+				t.Error("Expected recent event to be kept")
+			}
+
+			// Only one worker claims an event, until it is released or its claim is stale
+			if claimed, err := store.ClaimEvent("evt-3", now, now.Add(-time.Minute)); err != nil || !claimed {
+				t.Fatalf("Expected new event to be claimed, got %v (err: %v)", claimed, err)
+			}
+			if claimed, _ := store.ClaimEvent("evt-3", now, now.Add(-time.Minute)); claimed {
+				t.Error("Expected a claimed event not to be claimed again")
+			}
+			if processed, _ := store.IsProcessed("evt-3"); processed {
+				t.Error("Expected a claimed event not to be processed yet")
+			}
+			if err := store.ReleaseEvent("evt-3"); err != nil {
+				t.Fatalf("Failed to release event: %v", err)
+			}
+			if claimed, _ := store.ClaimEvent("evt-3", now, now.Add(-time.Minute)); !claimed {
+				t.Error("Expected a released event to be claimed again")
+			}
+			if claimed, _ := store.ClaimEvent("evt-3", now.Add(2*time.Minute), now.Add(time.Minute)); !claimed {
+				t.Error("Expected a stale claim to be taken over")
+			}
+			if err := store.MarkProcessed("evt-3", now); err != nil {
+				t.Fatalf("Failed to mark event: %v", err)
+			}
+			if processed, _ := store.IsProcessed("evt-3"); !processed {
+				t.Error("Expected event to be processed")
+			}
+			if claimed, _ := store.ClaimEvent("evt-2", now.Add(time.Hour), now.Add(time.Hour)); claimed {
+				t.Error("Expected a processed event not to be claimed")
+			}
+			store.ReleaseEvent("evt-2")
+			if processed, _ := store.IsProcessed("evt-2"); !processed {
+				t.Error("Expected releasing a processed event to keep it")
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package drivenadapters
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/infrastructure/eventspb"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ProtobufEventCodec implements domain.EventCodec with the Protobuf event schemas
+type ProtobufEventCodec struct{}
+
+// ParseEventEncoding returns the codec of a configured event encoding, "protobuf" or "json"
+func ParseEventEncoding(value string) (domain.EventCodec, error) {
+	switch value {
+	case "protobuf":
+		return ProtobufEventCodec{}, nil
+	case "json":
+		return domain.JSONEventCodec{}, nil
+	}
+	return nil, fmt.Errorf("unknown event encoding %q (expected %q or %q)", value, "protobuf", "json")
+}
+
+// ContentType returns the Protobuf content type
+func (ProtobufEventCodec) ContentType() string {
+	return domain.ProtobufContentType
+}
+
+// EncodeBatchEvent encodes a batch event as a medisupply.events.v1.BatchEvent
+func (ProtobufEventCodec) EncodeBatchEvent(event *domain.BatchEvent) ([]byte, error) {
+	message := &eventspb.BatchEvent{
+		EventType:    string(event.EventType),
+		BatchId:      event.BatchID,
+		ProductId:    event.ProductID,
+		Batch:        toBatchMessage(event.Batch),
+		OrderId:      event.OrderID,
+		ItemDetails:  toBatchItemMessage(event.ItemDetails),
+		Timestamp:    toTimestamp(event.Timestamp),
+		Sequence:     event.Sequence,
+		BaseSequence: event.BaseSequence,
+		Delta:        toBatchDeltaMessage(event.Delta),
+	}
+	if recall := event.Recall; recall != nil {
+		message.Recall = &eventspb.RecallNotice{
+			RecallId:  recall.RecallID,
+			LotNumber: recall.LotNumber,
+			Reason:    recall.Reason,
+		}
+		for _, order := range recall.AffectedOrders {
+			message.Recall.AffectedOrders = append(message.Recall.AffectedOrders, &eventspb.RecallOrder{
+				OrderId:    order.OrderID,
+				CustomerId: order.CustomerID,
+				BatchId:    order.BatchID,
+				LotNumber:  order.LotNumber,
+				Quantity:   int64(order.Quantity),
+				ItemStatus: string(order.ItemStatus),
+				ResolvedAt: toOptionalTimestamp(order.ResolvedAt),
+			})
+		}
+	}
+	return proto.Marshal(message)
+}
+
+// EncodeInventoryEvent encodes an inventory event as a medisupply.events.v1.InventoryEvent
+func (ProtobufEventCodec) EncodeInventoryEvent(event *domain.InventoryEvent) ([]byte, error) {
+	return proto.Marshal(&eventspb.InventoryEvent{
+		EventType:         string(event.EventType),
+		ProductId:         event.ProductID,
+		OrderId:           event.OrderID,
+		RequestedQuantity: int64(event.RequestedQuantity),
+		AvailableQuantity: int64(event.AvailableQuantity),
+		Reason:            event.Reason,
+		Timestamp:         toTimestamp(event.Timestamp),
+	})
+}
+
+// DecodeOrderEvent decodes a medisupply.events.v1.OrderEvent
+func (ProtobufEventCodec) DecodeOrderEvent(data []byte) (domain.OrderEvent, error) {
+	var message eventspb.OrderEvent
+	if err := proto.Unmarshal(data, &message); err != nil {
+		return domain.OrderEvent{}, fmt.Errorf("failed to decode Protobuf order event: %w", err)
+	}
+
+	order := message.GetOrder()
+	return domain.OrderEvent{
+		EventID:   message.GetEventId(),
+		EventType: message.GetEventType(),
+		OrderID:   message.GetOrderId(),
+		Order: domain.Order{
+			ID:          order.GetId(),
+			CustomerID:  order.GetCustomerId(),
+			ProductID:   order.GetProductId(),
+			Quantity:    int(order.GetQuantity()),
+			Status:      order.GetStatus(),
+			TotalAmount: order.GetTotalAmount(),
+			CreatedAt:   fromTimestamp(order.GetCreatedAt()),
+			UpdatedAt:   fromTimestamp(order.GetUpdatedAt()),
+		},
+		Timestamp: fromTimestamp(message.GetTimestamp()),
+	}, nil
+}
+
+// toBatchMessage converts a batch to its Protobuf message
+func toBatchMessage(batch *domain.Batch) *eventspb.Batch {
+	if batch == nil {
+		return nil
+	}
+
+	message := &eventspb.Batch{
+		Id:             batch.ID,
+		ProductId:      batch.ProductID,
+		LotNumber:      batch.LotNumber,
+		ManufacturedAt: toOptionalTimestamp(batch.ManufacturedAt),
+		ExpiresAt:      toOptionalTimestamp(batch.ExpiresAt),
+		Status:         string(batch.Status),
+		TotalItems:     int64(batch.TotalItems),
+		CreatedAt:      toTimestamp(batch.CreatedAt),
+		UpdatedAt:      toTimestamp(batch.UpdatedAt),
+		ProcessedAt:    toOptionalTimestamp(batch.ProcessedAt),
+		Inspection:     toInspectionMessage(batch.Inspection),
+		Disposition:    toDispositionMessage(batch.Disposition),
+		HoldReason:     batch.HoldReason,
+		Version:        batch.Version,
+	}
+	for i := range batch.Items {
+		message.Items = append(message.Items, toBatchItemMessage(&batch.Items[i]))
+	}
+	return message
+}
+
+// toInspectionMessage converts the inspection of a batch to its Protobuf message
+func toInspectionMessage(inspection *domain.BatchInspection) *eventspb.BatchInspection {
+	if inspection == nil {
+		return nil
+	}
+
+	return &eventspb.BatchInspection{
+		Inspector:   inspection.Inspector,
+		Findings:    inspection.Findings,
+		InspectedAt: toTimestamp(inspection.InspectedAt),
+	}
+}
+
+// toDispositionMessage converts the quarantine disposition of a batch to its Protobuf message
+func toDispositionMessage(disposition *domain.QuarantineDisposition) *eventspb.QuarantineDisposition {
+	if disposition == nil {
+		return nil
+	}
+
+	return &eventspb.QuarantineDisposition{
+		Outcome:   string(disposition.Outcome),
+		DecidedBy: disposition.DecidedBy,
+		Reason:    disposition.Reason,
+		DecidedAt: toTimestamp(disposition.DecidedAt),
+	}
+}
+
+// toBatchDeltaMessage converts a batch delta to its Protobuf message
+func toBatchDeltaMessage(delta *domain.BatchDelta) *eventspb.BatchDelta {
+	if delta == nil {
+		return nil
+	}
+
+	message := &eventspb.BatchDelta{
+		UpdatedAt: toTimestamp(delta.UpdatedAt),
+		Version:   delta.Version,
+	}
+	if status := delta.Status; status != nil {
+		message.Status = &eventspb.BatchStatusChange{Old: string(status.Old), New: string(status.New)}
+	}
+	for i := range delta.ItemsAdded {
+		message.ItemsAdded = append(message.ItemsAdded, toBatchItemMessage(&delta.ItemsAdded[i]))
+	}
+	for i := range delta.ItemsUpdated {
+		change := &delta.ItemsUpdated[i]
+		message.ItemsUpdated = append(message.ItemsUpdated, &eventspb.BatchItemChange{
+			Old: toBatchItemMessage(&change.Old),
+			New: toBatchItemMessage(&change.New),
+		})
+	}
+	for i := range delta.ItemsRemoved {
+		message.ItemsRemoved = append(message.ItemsRemoved, toBatchItemMessage(&delta.ItemsRemoved[i]))
+	}
+	if attributes := delta.Attributes; attributes != nil {
+		message.Attributes = &eventspb.BatchAttributesChange{
+			Old: toBatchAttributesMessage(attributes.Old),
+			New: toBatchAttributesMessage(attributes.New),
+		}
+	}
+	return message
+}
+
+// toBatchAttributesMessage converts the attributes of a batch to their Protobuf message
+func toBatchAttributesMessage(attributes domain.BatchAttributes) *eventspb.BatchAttributes {
+	return &eventspb.BatchAttributes{
+		LotNumber:      attributes.LotNumber,
+		ManufacturedAt: toOptionalTimestamp(attributes.ManufacturedAt),
+		ExpiresAt:      toOptionalTimestamp(attributes.ExpiresAt),
+		ProcessedAt:    toOptionalTimestamp(attributes.ProcessedAt),
+		Inspection:     toInspectionMessage(attributes.Inspection),
+		Disposition:    toDispositionMessage(attributes.Disposition),
+		HoldReason:     attributes.HoldReason,
+	}
+}
+
+// toBatchItemMessage converts a batch item to its Protobuf message
+func toBatchItemMessage(item *domain.BatchItem) *eventspb.BatchItem {
+	if item == nil {
+		return nil
+	}
+
+	return &eventspb.BatchItem{
+		OrderId:     item.OrderID,
+		CustomerId:  item.CustomerID,
+		ProductId:   item.ProductID,
+		Quantity:    int64(item.Quantity),
+		Status:      string(item.Status),
+		LotNumber:   item.LotNumber,
+		AddedAt:     toTimestamp(item.AddedAt),
+		ProcessedAt: toOptionalTimestamp(item.ProcessedAt),
+	}
+}
+
+// toTimestamp converts a time to a Protobuf timestamp, leaving the zero time unset
+func toTimestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+// toOptionalTimestamp converts an optional time to a Protobuf timestamp
+func toOptionalTimestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return toTimestamp(*t)
+}
+
+// fromTimestamp converts a Protobuf timestamp to a UTC time, the zero time if unset
+func fromTimestamp(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
@@ -0,0 +1,173 @@
+package drivenadapters
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/infrastructure/eventspb"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestProtobufEventCodec_DecodeOrderEvent(t *testing.T) {
+	// Written with the released v1 schema; it must stay readable, so it is never regenerated
+	data, err := os.ReadFile("testdata/order_event.v1.binpb")
+	if err != nil {
+		t.Fatalf("Failed to read v1 order event: %v", err)
+	}
+
+	codecs := domain.EventCodecs{ProtobufEventCodec{}}
+	event, err := codecs.DecodeOrderEvent(data, []domain.MessageHeader{
+		{Key: "content-type", Value: domain.ProtobufContentType},
+		{Key: domain.SchemaVersionHeader, Value: domain.EventSchemaVersion},
+	})
+	if err != nil {
+		t.Fatalf("Failed to decode v1 order event: %v", err)
+	}
+
+	at := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	expected := domain.OrderEvent{
+		EventID:   "evt-1",
+		EventType: "order.created",
+		OrderID:   "order-1",
+		Order: domain.Order{
+			ID:          "order-1",
+			CustomerID:  "customer-1",
+			ProductID:   "product-1",
+			Quantity:    3,
+			Status:      "pending",
+			TotalAmount: 42.5,
+			CreatedAt:   at,
+			UpdatedAt:   at.Add(time.Minute),
+		},
+		Timestamp: at.Add(time.Minute),
+	}
+	if event != expected {
+		t.Errorf("Expected %+v, got %+v", expected, event)
+	}
+
+	if _, err := (ProtobufEventCodec{}).DecodeOrderEvent([]byte(`{"event_type":"order.created"}`)); err == nil {
+		t.Error("Expected an error for a JSON payload")
+	}
+}
+
+func TestProtobufEventCodec_EncodeBatchEvent(t *testing.T) {
+	at := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	expiresAt := at.AddDate(1, 0, 0)
+
+	batch := domain.NewBatch("batch-1", "product-1")
+	batch.AddItem("order-1", "product-1", 2, domain.ItemStatusAllocated)
+	batch.LotNumber = "LOT-A"
+	batch.ExpiresAt = &expiresAt
+	batch.Inspection = &domain.BatchInspection{Inspector: "qa-1", Findings: "crushed", InspectedAt: at}
+	event := &domain.BatchEvent{
+		EventType: domain.BatchEventRecalled,
+		BatchID:   batch.ID,
+		ProductID: batch.ProductID,
+		Batch:     batch,
+		Recall: &domain.RecallNotice{
+			RecallID: "RECALL-1",
+			Reason:   "contamination",
+			AffectedOrders: []domain.RecallOrder{
+				{OrderID: "order-1", BatchID: "batch-1", Quantity: 2, ItemStatus: domain.ItemStatusDelivered},
+			},
+		},
+		Timestamp: at,
+	}
+
+	data, err := ProtobufEventCodec{}.EncodeBatchEvent(event)
+	if err != nil {
+		t.Fatalf("Failed to encode batch event: %v", err)
+	}
+	var message eventspb.BatchEvent
+	if err := proto.Unmarshal(data, &message); err != nil {
+		t.Fatalf("Failed to decode batch event: %v", err)
+	}
+
+	if message.GetEventType() != string(domain.BatchEventRecalled) || message.GetBatchId() != "batch-1" || message.OrderId != nil {
+		t.Errorf("Expected a recall event of the batch without order ID, got %v", &message)
+	}
+	encoded := message.GetBatch()
+	if encoded.GetLotNumber() != "LOT-A" || !encoded.GetExpiresAt().AsTime().Equal(expiresAt) || encoded.GetManufacturedAt() != nil {
+		t.Errorf("Expected the lot of the batch, got %v", encoded)
+	}
+	if len(encoded.GetItems()) != 1 || encoded.GetItems()[0].GetQuantity() != 2 || encoded.GetItems()[0].GetStatus() != string(domain.ItemStatusAllocated) {
+		t.Errorf("Expected the item of the batch, got %v", encoded.GetItems())
+	}
+	if encoded.GetInspection().GetFindings() != "crushed" || encoded.GetDisposition() != nil {
+		t.Errorf("Expected the inspection of the batch, got %v", encoded)
+	}
+	if recall := message.GetRecall(); recall.GetRecallId() != "RECALL-1" || len(recall.GetAffectedOrders()) != 1 || recall.GetAffectedOrders()[0].GetResolvedAt() != nil {
+		t.Errorf("Expected the recall notice, got %v", recall)
+	}
+}
+
+func TestProtobufEventCodec_EncodeBatchDeltaEvent(t *testing.T) {
+	before := domain.NewBatch("batch-1", "product-1")
+	before.AddItem("order-1", "product-1", 2, domain.ItemStatusAllocated)
+	after := before.Clone()
+	after.UpdateItemStatus("order-1", domain.ItemStatusShipped)
+	after.PlaceOnHold("recall", time.Now())
+	event := &domain.BatchEvent{
+		EventType:    domain.BatchEventOnHold,
+		BatchID:      "batch-1",
+		Sequence:     7,
+		BaseSequence: 5,
+		Delta:        domain.DiffBatch(before, after),
+	}
+
+	data, err := ProtobufEventCodec{}.EncodeBatchEvent(event)
+	if err != nil {
+		t.Fatalf("Failed to encode batch event: %v", err)
+	}
+	var message eventspb.BatchEvent
+	if err := proto.Unmarshal(data, &message); err != nil {
+		t.Fatalf("Failed to decode batch event: %v", err)
+	}
+
+	delta := message.GetDelta()
+	if message.GetBatch() != nil || message.GetSequence() != 7 || message.GetBaseSequence() != 5 {
+		t.Errorf("Expected a delta from sequence 5 without batch, got %v", &message)
+	}
+	if delta.GetStatus().GetOld() != string(domain.BatchStatusPending) || delta.GetStatus().GetNew() != string(domain.BatchStatusOnHold) {
+		t.Errorf("Expected the status transition, got %v", delta.GetStatus())
+	}
+	if len(delta.GetItemsUpdated()) != 1 || delta.GetItemsUpdated()[0].GetNew().GetStatus() != string(domain.ItemStatusShipped) {
+		t.Errorf("Expected the item update, got %v", delta.GetItemsUpdated())
+	}
+	if delta.GetAttributes().GetOld().GetHoldReason() != "" || delta.GetAttributes().GetNew().GetHoldReason() != "recall" {
+		t.Errorf("Expected the hold reason, got %v", delta.GetAttributes())
+	}
+}
+
+func TestProtobufEventCodec_EncodeInventoryEvent(t *testing.T) {
+	event := domain.NewAllocationFailedEvent("order-1", "product-1", 5, 2, "insufficient stock")
+
+	data, err := ProtobufEventCodec{}.EncodeInventoryEvent(event)
+	if err != nil {
+		t.Fatalf("Failed to encode inventory event: %v", err)
+	}
+	var message eventspb.InventoryEvent
+	if err := proto.Unmarshal(data, &message); err != nil {
+		t.Fatalf("Failed to decode inventory event: %v", err)
+	}
+	if message.GetOrderId() != "order-1" || message.GetRequestedQuantity() != 5 || message.GetAvailableQuantity() != 2 ||
+		!message.GetTimestamp().AsTime().Equal(event.Timestamp) {
+		t.Errorf("Expected the allocation failure, got %v", &message)
+	}
+}
+
+func TestParseEventEncoding(t *testing.T) {
+	for value, contentType := range map[string]string{
+		"protobuf": domain.ProtobufContentType,
+		"json":     domain.JSONContentType,
+	} {
+		if codec, err := ParseEventEncoding(value); err != nil || codec.ContentType() != contentType {
+			t.Errorf("Expected the %s codec, got %v (%v)", value, codec, err)
+		}
+	}
+	if _, err := ParseEventEncoding("avro"); err == nil {
+		t.Error("Expected an error for an unknown encoding")
+	}
+}
@@ -0,0 +1,90 @@
+package drivenadapters
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+)
+
+// RecallMemoryRepository implements RecallRepository using in-memory storage
+type RecallMemoryRepository struct {
+	recalls map[string]*domain.RecallCase
+	mutex   sync.RWMutex
+}
+
+// NewRecallMemoryRepository creates a new in-memory recall repository
+func NewRecallMemoryRepository() *RecallMemoryRepository {
+	return &RecallMemoryRepository{
+		recalls: make(map[string]*domain.RecallCase),
+	}
+}
+
+// Save stores or updates a recall case
+func (r *RecallMemoryRepository) Save(recall *domain.RecallCase) error {
+	if recall == nil {
+		return fmt.Errorf("recall cannot be nil")
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var storedVersion int64
+	if stored, exists := r.recalls[recall.ID]; exists {
+		storedVersion = stored.Version
+	}
+	if storedVersion != recall.Version {
+		return newRecallConflictError(recall.ID, recall.Version)
+	}
+
+	recall.Version++
+	r.recalls[recall.ID] = copyRecall(recall)
+	return nil
+}
+
+// FindByID retrieves a recall case by its ID
+func (r *RecallMemoryRepository) FindByID(id string) (*domain.RecallCase, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	recall, exists := r.recalls[id]
+	if !exists {
+		return nil, domain.NewNotFoundError("recall with ID %s not found", id)
+	}
+	return copyRecall(recall), nil
+}
+
+// GetAll retrieves all recall cases, oldest first
+func (r *RecallMemoryRepository) GetAll() ([]*domain.RecallCase, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	result := make([]*domain.RecallCase, 0, len(r.recalls))
+	for _, recall := range r.recalls {
+		result = append(result, copyRecall(recall))
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].ID < result[j].ID
+		}
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result, nil
+}
+
+// copyRecall returns a deep copy of a recall case to avoid external modifications
+func copyRecall(recall *domain.RecallCase) *domain.RecallCase {
+	recallCopy := *recall
+	recallCopy.Batches = make([]domain.RecallBatch, len(recall.Batches))
+	copy(recallCopy.Batches, recall.Batches)
+	recallCopy.Orders = make([]domain.RecallOrder, len(recall.Orders))
+	copy(recallCopy.Orders, recall.Orders)
+	return &recallCopy
+}
+
+// newRecallConflictError reports that the recall was changed after the given version was loaded
+func newRecallConflictError(id string, version int64) error {
+	return domain.NewConflictError("recall %s was modified concurrently (version %d is stale)", id, version)
+}
@@ -0,0 +1,89 @@
+package drivenadapters
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+)
+
+func TestRecallRepositories(t *testing.T) {
+	sqlRepo, err := NewRecallSQLRepository(newTestSQLDB(t), SQLDriverSQLite)
+	if err != nil {
+		t.Fatalf("Failed to create SQL recall repository: %v", err)
+	}
+
+	repos := map[string]domain.RecallRepository{
+		"memory": NewRecallMemoryRepository(),
+		"sql":    sqlRepo,
+	}
+
+	for name, repo := range repos {
+		t.Run(name, func(t *testing.T) {
+			at := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+
+			if _, err := repo.FindByID("recall-1"); !errors.Is(err, domain.ErrNotFound) {
+				t.Fatalf("Expected ErrNotFound for unknown recall, got %v", err)
+			}
+
+			batch := domain.NewBatch("batch-1", "product-1")
+			batch.LotNumber = "LOT-A"
+			batch.AddItem("order-1", "product-1", 3, domain.ItemStatusAllocated)
+			batch.SetItemCustomer("order-1", "customer-1")
+
+			recall, _ := domain.NewRecallCase("recall-1", "product-1", "LOT-A", "contamination", "qa-lead", at)
+			if err := recall.AddBatch(batch, at); err != nil {
+				t.Fatalf("Failed to add batch: %v", err)
+			}
+			if err := repo.Save(recall); err != nil {
+				t.Fatalf("Failed to save recall: %v", err)
+			}
+			if recall.Version != 1 {
+				t.Errorf("Expected version 1 after first save, got %d", recall.Version)
+			}
+
+			stale, err := repo.FindByID("recall-1")
+			if err != nil {
+				t.Fatalf("Failed to find recall: %v", err)
+			}
+
+			recall.RecordBatchOutcome("batch-1", domain.RecallBatchHeld, at.Add(time.Minute))
+			recall.ResolveOrder("order-1", at.Add(time.Hour))
+			if err := repo.Save(recall); err != nil {
+				t.Fatalf("Failed to update recall: %v", err)
+			}
+
+			stale.RecordBatchOutcome("batch-1", domain.RecallBatchNotInStock, at)
+			if err := repo.Save(stale); !errors.Is(err, domain.ErrConcurrencyConflict) {
+				t.Errorf("Expected ErrConcurrencyConflict for a stale recall, got %v", err)
+			}
+
+			found, err := repo.FindByID("recall-1")
+			if err != nil {
+				t.Fatalf("Failed to find recall: %v", err)
+			}
+			if found.Status != domain.RecallStatusClosed || found.ClosedAt == nil || !found.ClosedAt.Equal(at.Add(time.Hour)) {
+				t.Errorf("Expected closed recall, got %s closed at %v", found.Status, found.ClosedAt)
+			}
+			if found.Batches[0].Status != domain.RecallBatchHeld || found.Batches[0].LotNumber != "LOT-A" {
+				t.Errorf("Expected held LOT-A batch, got %+v", found.Batches)
+			}
+			if order := found.Orders[0]; order.CustomerID != "customer-1" || order.Quantity != 3 || order.ResolvedAt == nil {
+				t.Errorf("Expected resolved order of customer-1, got %+v", order)
+			}
+
+			second, _ := domain.NewRecallCase("recall-2", "product-2", "", "label error", "qa-lead", at.Add(-time.Hour))
+			if err := repo.Save(second); err != nil {
+				t.Fatalf("Failed to save recall: %v", err)
+			}
+			all, err := repo.GetAll()
+			if err != nil {
+				t.Fatalf("Failed to list recalls: %v", err)
+			}
+			if len(all) != 2 || all[0].ID != "recall-2" || all[1].ID != "recall-1" {
+				t.Errorf("Expected recalls oldest first, got %d recalls", len(all))
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package drivenadapters
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+)
+
+const recallColumns = `id, product_id, lot_number, reason, initiated_by, status, batches, orders, created_at, updated_at, closed_at, version`
+
+// RecallSQLRepository implements RecallRepository on top of a relational database.
+// The affected batches and orders of a recall are stored as JSON documents.
+type RecallSQLRepository struct {
+	db     *sql.DB
+	driver string
+}
+
+// NewRecallSQLRepository creates a new SQL recall repository and applies pending schema migrations
+func NewRecallSQLRepository(db *sql.DB, driver string) (*RecallSQLRepository, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database cannot be nil")
+	}
+
+	if driver != SQLDriverPostgres && driver != SQLDriverSQLite {
+		return nil, fmt.Errorf("unsupported SQL driver: %s", driver)
+	}
+
+	if err := migrateSQLSchema(db, driver); err != nil {
+		return nil, fmt.Errorf("failed to migrate database schema: %w", err)
+	}
+
+	return &RecallSQLRepository{
+		db:     db,
+		driver: driver,
+	}, nil
+}
+
+// Save stores or updates a recall case. The row is only updated if it still has the expected version.
+func (r *RecallSQLRepository) Save(recall *domain.RecallCase) error {
+	if recall == nil {
+		return fmt.Errorf("recall cannot be nil")
+	}
+
+	batches, err := json.Marshal(recall.Batches)
+	if err != nil {
+		return fmt.Errorf("failed to serialize batches of recall %s: %w", recall.ID, err)
+	}
+	orders, err := json.Marshal(recall.Orders)
+	if err != nil {
+		return fmt.Errorf("failed to serialize orders of recall %s: %w", recall.ID, err)
+	}
+
+	expectedVersion := recall.Version
+	var result sql.Result
+	if expectedVersion == 0 {
+		result, err = r.db.Exec(rebindQuery(r.driver, `INSERT INTO recall_cases (`+recallColumns+`)
+			VALUES (`+placeholders(12)+`)
+			ON CONFLICT (id) DO NOTHING`),
+			recall.ID,
+			recall.ProductID,
+			recall.LotNumber,
+			recall.Reason,
+			recall.InitiatedBy,
+			string(recall.Status),
+			string(batches),
+			string(orders),
+			recall.CreatedAt.UTC(),
+			recall.UpdatedAt.UTC(),
+			nullTime(recall.ClosedAt),
+			expectedVersion+1,
+		)
+	} else {
+		result, err = r.db.Exec(rebindQuery(r.driver, `UPDATE recall_cases SET
+				status = ?,
+				batches = ?,
+				orders = ?,
+				updated_at = ?,
+				closed_at = ?,
+				version = ?
+			WHERE id = ? AND version = ?`),
+			string(recall.Status),
+			string(batches),
+			string(orders),
+			recall.UpdatedAt.UTC(),
+			nullTime(recall.ClosedAt),
+			expectedVersion+1,
+			recall.ID,
+			expectedVersion,
+		)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save recall %s: %w", recall.ID, err)
+	}
+	if err := requireAffected(result, newRecallConflictError(recall.ID, expectedVersion)); err != nil {
+		return err
+	}
+
+	recall.Version = expectedVersion + 1
+	return nil
+}
+
+// FindByID retrieves a recall case by its ID
+func (r *RecallSQLRepository) FindByID(id string) (*domain.RecallCase, error) {
+	query := rebindQuery(r.driver, `SELECT `+recallColumns+` FROM recall_cases WHERE id = ?`)
+	recall, err := scanRecall(r.db.QueryRow(query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.NewNotFoundError("recall with ID %s not found", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return recall, nil
+}
+
+// GetAll retrieves all recall cases, oldest first
+func (r *RecallSQLRepository) GetAll() ([]*domain.RecallCase, error) {
+	rows, err := r.db.Query(`SELECT ` + recallColumns + ` FROM recall_cases ORDER BY created_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query recalls: %w", err)
+	}
+	defer rows.Close()
+
+	recalls := make([]*domain.RecallCase, 0)
+	for rows.Next() {
+		recall, err := scanRecall(rows)
+		if err != nil {
+			return nil, err
+		}
+		recalls = append(recalls, recall)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read recalls: %w", err)
+	}
+	return recalls, nil
+}
+
+// scanRecall reads a recall row selected with recallColumns
+func scanRecall(row rowScanner) (*domain.RecallCase, error) {
+	var (
+		recall   domain.RecallCase
+		status   string
+		batches  string
+		orders   string
+		closedAt sql.NullTime
+	)
+	if err := row.Scan(
+		&recall.ID,
+		&recall.ProductID,
+		&recall.LotNumber,
+		&recall.Reason,
+		&recall.InitiatedBy,
+		&status,
+		&batches,
+		&orders,
+		&recall.CreatedAt,
+		&recall.UpdatedAt,
+		&closedAt,
+		&recall.Version,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan recall: %w", err)
+	}
+
+	if err := json.Unmarshal([]byte(batches), &recall.Batches); err != nil {
+		return nil, fmt.Errorf("failed to deserialize batches of recall %s: %w", recall.ID, err)
+	}
+	if err := json.Unmarshal([]byte(orders), &recall.Orders); err != nil {
+		return nil, fmt.Errorf("failed to deserialize orders of recall %s: %w", recall.ID, err)
+	}
+	recall.Status = domain.RecallStatus(status)
+	recall.ClosedAt = timePtr(closedAt)
+	return &recall, nil
+}
@@ -0,0 +1,130 @@
+package drivenadapters
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+)
+
+// ReturnMemoryRepository implements ReturnRepository using in-memory storage
+type ReturnMemoryRepository struct {
+	returns    map[string]*domain.ReturnAuthorization
+	orderIndex map[string]string // orderID -> returnID
+	mutex      sync.RWMutex
+}
+
+// NewReturnMemoryRepository creates a new in-memory return repository
+func NewReturnMemoryRepository() *ReturnMemoryRepository {
+	return &ReturnMemoryRepository{
+		returns:    make(map[string]*domain.ReturnAuthorization),
+		orderIndex: make(map[string]string),
+	}
+}
+
+// Save stores or updates a return
+func (r *ReturnMemoryRepository) Save(rma *domain.ReturnAuthorization) error {
+	if rma == nil {
+		return fmt.Errorf("return cannot be nil")
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if returnID, exists := r.orderIndex[rma.OrderID]; exists && returnID != rma.ID {
+		return newReturnExistsError(rma.OrderID, returnID)
+	}
+
+	var storedVersion int64
+	if stored, exists := r.returns[rma.ID]; exists {
+		storedVersion = stored.Version
+	}
+	if storedVersion != rma.Version {
+		return newReturnConflictError(rma.ID, rma.Version)
+	}
+
+	rma.Version++
+	r.returns[rma.ID] = copyReturn(rma)
+	r.orderIndex[rma.OrderID] = rma.ID
+	return nil
+}
+
+// FindByID retrieves a return by its ID
+func (r *ReturnMemoryRepository) FindByID(id string) (*domain.ReturnAuthorization, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	rma, exists := r.returns[id]
+	if !exists {
+		return nil, domain.NewNotFoundError("return with ID %s not found", id)
+	}
+	return copyReturn(rma), nil
+}
+
+// FindByOrderID retrieves the return of an order
+func (r *ReturnMemoryRepository) FindByOrderID(orderID string) (*domain.ReturnAuthorization, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	returnID, exists := r.orderIndex[orderID]
+	if !exists {
+		return nil, domain.NewNotFoundError("return for order %s not found", orderID)
+	}
+	return copyReturn(r.returns[returnID]), nil
+}
+
+// FindByBatchID retrieves the returns of the items of a batch, oldest first
+func (r *ReturnMemoryRepository) FindByBatchID(batchID string) ([]*domain.ReturnAuthorization, error) {
+	return r.filter(func(rma *domain.ReturnAuthorization) bool {
+		return rma.BatchID == batchID
+	}), nil
+}
+
+// GetAll retrieves all returns, oldest first
+func (r *ReturnMemoryRepository) GetAll() ([]*domain.ReturnAuthorization, error) {
+	return r.filter(func(*domain.ReturnAuthorization) bool {
+		return true
+	}), nil
+}
+
+// filter returns copies of the matching returns, oldest first
+func (r *ReturnMemoryRepository) filter(match func(*domain.ReturnAuthorization) bool) []*domain.ReturnAuthorization {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	result := make([]*domain.ReturnAuthorization, 0)
+	for _, rma := range r.returns {
+		if match(rma) {
+			result = append(result, copyReturn(rma))
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].ID < result[j].ID
+		}
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result
+}
+
+// copyReturn returns a copy of a return to avoid external modifications
+func copyReturn(rma *domain.ReturnAuthorization) *domain.ReturnAuthorization {
+	rmaCopy := *rma
+	if rma.ResolvedAt != nil {
+		resolvedAt := *rma.ResolvedAt
+		rmaCopy.ResolvedAt = &resolvedAt
+	}
+	return &rmaCopy
+}
+
+// newReturnConflictError reports that the return was changed after the given version was loaded
+func newReturnConflictError(id string, version int64) error {
+	return domain.NewConflictError("return %s was modified concurrently (version %d is stale)", id, version)
+}
+
+// newReturnExistsError reports that the order already has a return
+func newReturnExistsError(orderID, returnID string) error {
+	return domain.NewAlreadyExistsError("order %s already has return %s", orderID, returnID)
+}
@@ -0,0 +1,98 @@
+package drivenadapters
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+)
+
+func TestReturnRepositories(t *testing.T) {
+	sqlRepo, err := NewReturnSQLRepository(newTestSQLDB(t), SQLDriverSQLite)
+	if err != nil {
+		t.Fatalf("Failed to create SQL return repository: %v", err)
+	}
+
+	repos := map[string]domain.ReturnRepository{
+		"memory": NewReturnMemoryRepository(),
+		"sql":    sqlRepo,
+	}
+
+	for name, repo := range repos {
+		t.Run(name, func(t *testing.T) {
+			at := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+
+			if _, err := repo.FindByID("rma-1"); !errors.Is(err, domain.ErrNotFound) {
+				t.Fatalf("Expected ErrNotFound for unknown return, got %v", err)
+			}
+			if _, err := repo.FindByOrderID("order-1"); !errors.Is(err, domain.ErrNotFound) {
+				t.Fatalf("Expected ErrNotFound for an order without return, got %v", err)
+			}
+
+			batch := domain.NewBatch("batch-1", "product-1")
+			batch.LotNumber = "LOT-A"
+			batch.AddItem("order-1", "product-1", 3, domain.ItemStatusDelivered)
+			batch.AddItem("order-2", "product-1", 1, domain.ItemStatusShipped)
+			batch.SetItemCustomer("order-1", "customer-1")
+
+			rma, _ := domain.NewReturnAuthorization("rma-1", batch, "order-1", 2, "wrong item", domain.ReturnConditionSealed, at)
+			if err := repo.Save(rma); err != nil {
+				t.Fatalf("Failed to save return: %v", err)
+			}
+			if rma.Version != 1 {
+				t.Errorf("Expected version 1 after first save, got %d", rma.Version)
+			}
+
+			duplicate, _ := domain.NewReturnAuthorization("rma-dup", batch, "order-1", 1, "wrong item", "", at)
+			if err := repo.Save(duplicate); !errors.Is(err, domain.ErrAlreadyExists) {
+				t.Errorf("Expected ErrAlreadyExists for a second return of the order, got %v", err)
+			}
+
+			stale, err := repo.FindByOrderID("order-1")
+			if err != nil {
+				t.Fatalf("Failed to find return by order: %v", err)
+			}
+
+			rma.Resolve(domain.ReturnDispositionRestock, "", "qa-lead", at.Add(time.Hour))
+			if err := repo.Save(rma); err != nil {
+				t.Fatalf("Failed to update return: %v", err)
+			}
+
+			stale.Resolve(domain.ReturnDispositionDestroy, "", "qa-lead", at)
+			if err := repo.Save(stale); !errors.Is(err, domain.ErrConcurrencyConflict) {
+				t.Errorf("Expected ErrConcurrencyConflict for a stale return, got %v", err)
+			}
+
+			found, err := repo.FindByID("rma-1")
+			if err != nil {
+				t.Fatalf("Failed to find return: %v", err)
+			}
+			if found.Status != domain.ReturnStatusResolved || found.Disposition != domain.ReturnDispositionRestock ||
+				found.ResolvedAt == nil || !found.ResolvedAt.Equal(at.Add(time.Hour)) {
+				t.Errorf("Expected restocked return, got %+v", found)
+			}
+			if found.BatchID != "batch-1" || found.LotNumber != "LOT-A" || found.CustomerID != "customer-1" || found.Quantity != 2 {
+				t.Errorf("Expected the return to keep its batch item, got %+v", found)
+			}
+
+			second, _ := domain.NewReturnAuthorization("rma-2", batch, "order-2", 0, "not ordered", "", at.Add(-time.Hour))
+			if err := repo.Save(second); err != nil {
+				t.Fatalf("Failed to save return: %v", err)
+			}
+			byBatch, err := repo.FindByBatchID("batch-1")
+			if err != nil {
+				t.Fatalf("Failed to list returns of batch: %v", err)
+			}
+			if len(byBatch) != 2 || byBatch[0].ID != "rma-2" || byBatch[1].ID != "rma-1" {
+				t.Errorf("Expected the returns of the batch oldest first, got %d returns", len(byBatch))
+			}
+			if other, _ := repo.FindByBatchID("batch-2"); len(other) != 0 {
+				t.Errorf("Expected no returns for another batch, got %d", len(other))
+			}
+			if all, _ := repo.GetAll(); len(all) != 2 {
+				t.Errorf("Expected 2 returns, got %d", len(all))
+			}
+		})
+	}
+}
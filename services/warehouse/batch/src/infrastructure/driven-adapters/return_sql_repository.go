@@ -0,0 +1,206 @@
+package drivenadapters
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+)
+
+const returnColumns = `id, order_id, customer_id, product_id, batch_id, lot_number, quantity, reason, item_condition, status, disposition, decided_by, created_at, updated_at, resolved_at, version`
+
+// ReturnSQLRepository implements ReturnRepository on top of a relational database
+type ReturnSQLRepository struct {
+	db     *sql.DB
+	driver string
+}
+
+// NewReturnSQLRepository creates a new SQL return repository and applies pending schema migrations
+func NewReturnSQLRepository(db *sql.DB, driver string) (*ReturnSQLRepository, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database cannot be nil")
+	}
+
+	if driver != SQLDriverPostgres && driver != SQLDriverSQLite {
+		return nil, fmt.Errorf("unsupported SQL driver: %s", driver)
+	}
+
+	if err := migrateSQLSchema(db, driver); err != nil {
+		return nil, fmt.Errorf("failed to migrate database schema: %w", err)
+	}
+
+	return &ReturnSQLRepository{
+		db:     db,
+		driver: driver,
+	}, nil
+}
+
+// Save stores or updates a return. The row is only updated if it still has the expected version.
+func (r *ReturnSQLRepository) Save(rma *domain.ReturnAuthorization) error {
+	if rma == nil {
+		return fmt.Errorf("return cannot be nil")
+	}
+
+	expectedVersion := rma.Version
+	var (
+		result sql.Result
+		err    error
+	)
+	if expectedVersion == 0 {
+		// Without a conflict target the insert also skips rows clashing on the order ID
+		result, err = r.db.Exec(rebindQuery(r.driver, `INSERT INTO return_authorizations (`+returnColumns+`)
+			VALUES (`+placeholders(16)+`)
+			ON CONFLICT DO NOTHING`),
+			rma.ID,
+			rma.OrderID,
+			rma.CustomerID,
+			rma.ProductID,
+			rma.BatchID,
+			rma.LotNumber,
+			rma.Quantity,
+			rma.Reason,
+			string(rma.Condition),
+			string(rma.Status),
+			string(rma.Disposition),
+			rma.DecidedBy,
+			rma.CreatedAt.UTC(),
+			rma.UpdatedAt.UTC(),
+			nullTime(rma.ResolvedAt),
+			expectedVersion+1,
+		)
+	} else {
+		result, err = r.db.Exec(rebindQuery(r.driver, `UPDATE return_authorizations SET
+				item_condition = ?,
+				status = ?,
+				disposition = ?,
+				decided_by = ?,
+				updated_at = ?,
+				resolved_at = ?,
+				version = ?
+			WHERE id = ? AND version = ?`),
+			string(rma.Condition),
+			string(rma.Status),
+			string(rma.Disposition),
+			rma.DecidedBy,
+			rma.UpdatedAt.UTC(),
+			nullTime(rma.ResolvedAt),
+			expectedVersion+1,
+			rma.ID,
+			expectedVersion,
+		)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save return %s: %w", rma.ID, err)
+	}
+
+	if err := requireAffected(result, newReturnConflictError(rma.ID, expectedVersion)); err != nil {
+		if expectedVersion == 0 {
+			if existing, findErr := r.FindByOrderID(rma.OrderID); findErr == nil && existing.ID != rma.ID {
+				return newReturnExistsError(rma.OrderID, existing.ID)
+			}
+		}
+		return err
+	}
+
+	rma.Version = expectedVersion + 1
+	return nil
+}
+
+// FindByID retrieves a return by its ID
+func (r *ReturnSQLRepository) FindByID(id string) (*domain.ReturnAuthorization, error) {
+	query := rebindQuery(r.driver, `SELECT `+returnColumns+` FROM return_authorizations WHERE id = ?`)
+	rma, err := scanReturn(r.db.QueryRow(query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.NewNotFoundError("return with ID %s not found", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rma, nil
+}
+
+// FindByOrderID retrieves the return of an order
+func (r *ReturnSQLRepository) FindByOrderID(orderID string) (*domain.ReturnAuthorization, error) {
+	query := rebindQuery(r.driver, `SELECT `+returnColumns+` FROM return_authorizations WHERE order_id = ?`)
+	rma, err := scanReturn(r.db.QueryRow(query, orderID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.NewNotFoundError("return for order %s not found", orderID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rma, nil
+}
+
+// FindByBatchID retrieves the returns of the items of a batch, oldest first
+func (r *ReturnSQLRepository) FindByBatchID(batchID string) ([]*domain.ReturnAuthorization, error) {
+	return r.query(rebindQuery(r.driver, `SELECT `+returnColumns+` FROM return_authorizations WHERE batch_id = ? ORDER BY created_at, id`), batchID)
+}
+
+// GetAll retrieves all returns, oldest first
+func (r *ReturnSQLRepository) GetAll() ([]*domain.ReturnAuthorization, error) {
+	return r.query(`SELECT ` + returnColumns + ` FROM return_authorizations ORDER BY created_at, id`)
+}
+
+// query runs a select of returnColumns and scans every row
+func (r *ReturnSQLRepository) query(query string, args ...interface{}) ([]*domain.ReturnAuthorization, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query returns: %w", err)
+	}
+	defer rows.Close()
+
+	returns := make([]*domain.ReturnAuthorization, 0)
+	for rows.Next() {
+		rma, err := scanReturn(rows)
+		if err != nil {
+			return nil, err
+		}
+		returns = append(returns, rma)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read returns: %w", err)
+	}
+	return returns, nil
+}
+
+// scanReturn reads a return row selected with returnColumns
+func scanReturn(row rowScanner) (*domain.ReturnAuthorization, error) {
+	var (
+		rma         domain.ReturnAuthorization
+		condition   string
+		status      string
+		disposition string
+		resolvedAt  sql.NullTime
+	)
+	if err := row.Scan(
+		&rma.ID,
+		&rma.OrderID,
+		&rma.CustomerID,
+		&rma.ProductID,
+		&rma.BatchID,
+		&rma.LotNumber,
+		&rma.Quantity,
+		&rma.Reason,
+		&condition,
+		&status,
+		&disposition,
+		&rma.DecidedBy,
+		&rma.CreatedAt,
+		&rma.UpdatedAt,
+		&resolvedAt,
+		&rma.Version,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan return: %w", err)
+	}
+
+	rma.Condition = domain.ReturnCondition(condition)
+	rma.Status = domain.ReturnStatus(status)
+	rma.Disposition = domain.ReturnDisposition(disposition)
+	rma.ResolvedAt = timePtr(resolvedAt)
+	return &rma, nil
+}
@@ -0,0 +1,411 @@
+package drivenadapters
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SQL driver names supported by the SQL-backed adapters
+const (
+	SQLDriverPostgres = "postgres"
+	SQLDriverSQLite   = "sqlite3"
+)
+
+// sqlMigration represents a single versioned schema change
+type sqlMigration struct {
+	version     int
+	description string
+	statements  []string
+}
+
+// sqlMigrations holds the ordered list of schema migrations for the warehouse database.
+// Migrations are append-only: never edit a migration that has already been released.
+var sqlMigrations = []sqlMigration{
+	{
+		version:     1,
+		description: "create batches and batch_items tables",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS batches (
+				id           VARCHAR(255) PRIMARY KEY,
+				product_id   VARCHAR(255) NOT NULL,
+				status       VARCHAR(32)  NOT NULL,
+				total_items  INTEGER      NOT NULL DEFAULT 0,
+				created_at   TIMESTAMP    NOT NULL,
+				updated_at   TIMESTAMP    NOT NULL,
+				processed_at TIMESTAMP    NULL
+			)`,
+			`CREATE TABLE IF NOT EXISTS batch_items (
+				batch_id     VARCHAR(255) NOT NULL REFERENCES batches(id) ON DELETE CASCADE,
+				position     INTEGER      NOT NULL,
+				order_id     VARCHAR(255) NOT NULL,
+				product_id   VARCHAR(255) NOT NULL,
+				quantity     INTEGER      NOT NULL,
+				status       VARCHAR(64)  NOT NULL,
+				added_at     TIMESTAMP    NOT NULL,
+				processed_at TIMESTAMP    NULL,
+				PRIMARY KEY (batch_id, order_id)
+			)`,
+		},
+	},
+	{
+		version:     2,
+		description: "add lookup indexes for order and pending product queries",
+		statements: []string{
+			`CREATE INDEX IF NOT EXISTS idx_batch_items_order_id ON batch_items (order_id)`,
+			`CREATE INDEX IF NOT EXISTS idx_batches_product_status ON batches (product_id, status, created_at)`,
+			`CREATE INDEX IF NOT EXISTS idx_batches_status ON batches (status)`,
+		},
+	},
+	{
+		version:     3,
+		description: "create transactional outbox for batch events",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS outbox_messages (
+				id              {{serial_primary_key}},
+				batch_id        VARCHAR(255) NOT NULL,
+				event_type      VARCHAR(64)  NOT NULL,
+				payload         TEXT         NOT NULL,
+				attempts        INTEGER      NOT NULL DEFAULT 0,
+				last_error      TEXT         NOT NULL DEFAULT '',
+				created_at      TIMESTAMP    NOT NULL,
+				next_attempt_at TIMESTAMP    NOT NULL,
+				published_at    TIMESTAMP    NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_outbox_messages_pending ON outbox_messages (id) WHERE published_at IS NULL`,
+		},
+	},
+	{
+		version:     4,
+		description: "add optimistic locking version to batches",
+		statements: []string{
+			// Existing rows have been saved once, so they start at version 1
+			`ALTER TABLE batches ADD COLUMN version BIGINT NOT NULL DEFAULT 1`,
+		},
+	},
+	{
+		version:     5,
+		description: "create processed_events table for idempotent order event handling",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS processed_events (
+				event_id     VARCHAR(512) PRIMARY KEY,
+				processed_at TIMESTAMP    NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_processed_events_processed_at ON processed_events (processed_at)`,
+		},
+	},
+	{
+		version:     6,
+		description: "create dead_letters table for parked order events",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS dead_letters (
+				id           VARCHAR(512) PRIMARY KEY,
+				topic        VARCHAR(255) NOT NULL,
+				partition_no INTEGER      NOT NULL,
+				offset_no    BIGINT       NOT NULL,
+				message_key  TEXT         NOT NULL,
+				payload      {{blob}}     NOT NULL,
+				headers      TEXT         NOT NULL,
+				error_class  VARCHAR(32)  NOT NULL,
+				reason       TEXT         NOT NULL,
+				attempts     INTEGER      NOT NULL,
+				status       VARCHAR(32)  NOT NULL,
+				failed_at    TIMESTAMP    NOT NULL,
+				edited_at    TIMESTAMP    NULL,
+				replays      TEXT         NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_dead_letters_status ON dead_letters (status, failed_at)`,
+		},
+	},
+	{
+		version:     7,
+		description: "add lot number and expiry tracking to batches",
+		statements: []string{
+			`ALTER TABLE batches ADD COLUMN lot_number VARCHAR(255) NOT NULL DEFAULT ''`,
+			`ALTER TABLE batches ADD COLUMN manufactured_at TIMESTAMP NULL`,
+			`ALTER TABLE batches ADD COLUMN expires_at TIMESTAMP NULL`,
+			`ALTER TABLE batch_items ADD COLUMN lot_number VARCHAR(255) NOT NULL DEFAULT ''`,
+			`CREATE INDEX IF NOT EXISTS idx_batches_expires_at ON batches (expires_at)`,
+		},
+	},
+	{
+		version:     8,
+		description: "create stock levels, reservations and movement ledger",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS stock_levels (
+				product_id VARCHAR(255) PRIMARY KEY,
+				on_hand    INTEGER      NOT NULL,
+				reserved   INTEGER      NOT NULL,
+				updated_at TIMESTAMP    NOT NULL,
+				version    BIGINT       NOT NULL
+			)`,
+			`CREATE TABLE IF NOT EXISTS stock_reservations (
+				order_id   VARCHAR(255) PRIMARY KEY,
+				product_id VARCHAR(255) NOT NULL,
+				quantity   INTEGER      NOT NULL,
+				status     VARCHAR(32)  NOT NULL,
+				created_at TIMESTAMP    NOT NULL,
+				updated_at TIMESTAMP    NOT NULL
+			)`,
+			`CREATE TABLE IF NOT EXISTS stock_movements (
+				id             {{serial_primary_key}},
+				product_id     VARCHAR(255) NOT NULL,
+				order_id       VARCHAR(255) NOT NULL DEFAULT '',
+				movement_type  VARCHAR(32)  NOT NULL,
+				quantity       INTEGER      NOT NULL,
+				on_hand_after  INTEGER      NOT NULL,
+				reserved_after INTEGER      NOT NULL,
+				reason         TEXT         NOT NULL DEFAULT '',
+				occurred_at    TIMESTAMP    NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_stock_movements_product ON stock_movements (product_id, id)`,
+		},
+	},
+	{
+		version:     9,
+		description: "add quarantine inspection and disposition to batches",
+		statements: []string{
+			`ALTER TABLE batches ADD COLUMN inspector VARCHAR(255) NOT NULL DEFAULT ''`,
+			`ALTER TABLE batches ADD COLUMN inspection_findings TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE batches ADD COLUMN inspected_at TIMESTAMP NULL`,
+			`ALTER TABLE batches ADD COLUMN disposition_outcome VARCHAR(32) NOT NULL DEFAULT ''`,
+			`ALTER TABLE batches ADD COLUMN disposition_decided_by VARCHAR(255) NOT NULL DEFAULT ''`,
+			`ALTER TABLE batches ADD COLUMN disposition_reason TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE batches ADD COLUMN disposition_decided_at TIMESTAMP NULL`,
+		},
+	},
+	{
+		version:     10,
+		description: "add hold reason to batches and customer to batch items",
+		statements: []string{
+			`ALTER TABLE batches ADD COLUMN hold_reason TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE batch_items ADD COLUMN customer_id VARCHAR(255) NOT NULL DEFAULT ''`,
+		},
+	},
+	{
+		version:     11,
+		description: "create recall_cases table for product recalls",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS recall_cases (
+				id           VARCHAR(255) PRIMARY KEY,
+				product_id   VARCHAR(255) NOT NULL,
+				lot_number   VARCHAR(255) NOT NULL,
+				reason       TEXT         NOT NULL,
+				initiated_by VARCHAR(255) NOT NULL,
+				status       VARCHAR(32)  NOT NULL,
+				batches      TEXT         NOT NULL,
+				orders       TEXT         NOT NULL,
+				created_at   TIMESTAMP    NOT NULL,
+				updated_at   TIMESTAMP    NOT NULL,
+				closed_at    TIMESTAMP    NULL,
+				version      BIGINT       NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_recall_cases_created_at ON recall_cases (created_at)`,
+		},
+	},
+	{
+		version:     12,
+		description: "park outbox messages that cannot be delivered",
+		statements: []string{
+			`ALTER TABLE outbox_messages ADD COLUMN dead_at TIMESTAMP NULL`,
+		},
+	},
+	{
+		version:     13,
+		description: "claim order events before handling them",
+		statements: []string{
+			// Existing rows were recorded after their event was handled
+			`ALTER TABLE processed_events ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'processed'`,
+		},
+	},
+	{
+		version:     14,
+		description: "create outbox for inventory events",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS inventory_outbox_messages (
+				id              {{serial_primary_key}},
+				product_id      VARCHAR(255) NOT NULL,
+				event_type      VARCHAR(64)  NOT NULL,
+				payload         TEXT         NOT NULL,
+				attempts        INTEGER      NOT NULL DEFAULT 0,
+				last_error      TEXT         NOT NULL DEFAULT '',
+				created_at      TIMESTAMP    NOT NULL,
+				next_attempt_at TIMESTAMP    NOT NULL,
+				published_at    TIMESTAMP    NULL,
+				dead_at         TIMESTAMP    NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_inventory_outbox_messages_pending ON inventory_outbox_messages (id) WHERE published_at IS NULL`,
+		},
+	},
+	{
+		version:     15,
+		description: "create return_authorizations table for customer returns",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS return_authorizations (
+				id             VARCHAR(255) PRIMARY KEY,
+				order_id       VARCHAR(255) NOT NULL UNIQUE,
+				customer_id    VARCHAR(255) NOT NULL,
+				product_id     VARCHAR(255) NOT NULL,
+				batch_id       VARCHAR(255) NOT NULL,
+				lot_number     VARCHAR(255) NOT NULL,
+				quantity       INTEGER      NOT NULL,
+				reason         TEXT         NOT NULL,
+				item_condition VARCHAR(32)  NOT NULL,
+				status         VARCHAR(32)  NOT NULL,
+				disposition    VARCHAR(32)  NOT NULL,
+				decided_by     VARCHAR(255) NOT NULL,
+				created_at     TIMESTAMP    NOT NULL,
+				updated_at     TIMESTAMP    NOT NULL,
+				resolved_at    TIMESTAMP    NULL,
+				version        BIGINT       NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_return_authorizations_batch_id ON return_authorizations (batch_id)`,
+		},
+	},
+	{
+		version:     16,
+		description: "create change streams and snapshots for event-sourced batches",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS batch_changes (
+				batch_id    VARCHAR(255) NOT NULL,
+				version     BIGINT       NOT NULL,
+				payload     TEXT         NOT NULL,
+				recorded_at TIMESTAMP    NOT NULL,
+				PRIMARY KEY (batch_id, version)
+			)`,
+			`CREATE TABLE IF NOT EXISTS batch_snapshots (
+				batch_id VARCHAR(255) NOT NULL,
+				version  BIGINT       NOT NULL,
+				payload  TEXT         NOT NULL,
+				taken_at TIMESTAMP    NOT NULL,
+				PRIMARY KEY (batch_id, version)
+			)`,
+		},
+	},
+	{
+		version:     17,
+		description: "create audit trail of batch changes",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS batch_audit_log (
+				id            {{serial_primary_key}},
+				batch_id      VARCHAR(255) NOT NULL,
+				version       BIGINT       NOT NULL,
+				command       VARCHAR(64)  NOT NULL,
+				actor         VARCHAR(255) NOT NULL,
+				source_event  VARCHAR(255) NOT NULL,
+				event_types   TEXT         NOT NULL,
+				status_before VARCHAR(32)  NOT NULL,
+				status_after  VARCHAR(32)  NOT NULL,
+				items         TEXT         NOT NULL,
+				recorded_at   TIMESTAMP    NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_batch_audit_log_batch_id ON batch_audit_log (batch_id, id)`,
+		},
+	},
+}
+
+// dialectStatement replaces dialect-specific tokens in a migration statement
+func dialectStatement(driver, statement string) string {
+	serial := "INTEGER PRIMARY KEY AUTOINCREMENT"
+	blob := "BLOB"
+	if driver == SQLDriverPostgres {
+		serial = "BIGSERIAL PRIMARY KEY"
+		blob = "BYTEA"
+	}
+	statement = strings.ReplaceAll(statement, "{{serial_primary_key}}", serial)
+	return strings.ReplaceAll(statement, "{{blob}}", blob)
+}
+
+// migrateSQLSchema applies all pending migrations, each one inside its own transaction
+func migrateSQLSchema(db *sql.DB, driver string) error {
+	createMigrationsTable := `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version     INTEGER PRIMARY KEY,
+		description VARCHAR(255) NOT NULL,
+		applied_at  TIMESTAMP    NOT NULL
+	)`
+	if _, err := db.Exec(createMigrationsTable); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	applied := make(map[int]bool)
+	rows, err := db.Query(`SELECT version FROM schema_migrations`)
+	if err != nil {
+		return fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan migration version: %w", err)
+		}
+		applied[version] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+
+	for _, migration := range sqlMigrations {
+		if applied[migration.version] {
+			continue
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin migration %d: %w", migration.version, err)
+		}
+
+		for _, statement := range migration.statements {
+			if _, err := tx.Exec(dialectStatement(driver, statement)); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("failed to apply migration %d (%s): %w", migration.version, migration.description, err)
+			}
+		}
+
+		insert := rebindQuery(driver, `INSERT INTO schema_migrations (version, description, applied_at) VALUES (?, ?, ?)`)
+		if _, err := tx.Exec(insert, migration.version, migration.description, time.Now().UTC()); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record migration %d: %w", migration.version, err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %d: %w", migration.version, err)
+		}
+
+		log.Printf("Applied database migration %d: %s", migration.version, migration.description)
+	}
+
+	return nil
+}
+
+// rebindQuery converts '?' placeholders into the placeholder style of the given driver
+func rebindQuery(driver, query string) string {
+	if driver != SQLDriverPostgres {
+		return query
+	}
+
+	var builder strings.Builder
+	builder.Grow(len(query) + 8)
+	position := 0
+	for _, r := range query {
+		if r == '?' {
+			position++
+			builder.WriteByte('$')
+			builder.WriteString(strconv.Itoa(position))
+			continue
+		}
+		builder.WriteRune(r)
+	}
+	return builder.String()
+}
+
+// placeholders returns a comma-separated list of n '?' placeholders
+func placeholders(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
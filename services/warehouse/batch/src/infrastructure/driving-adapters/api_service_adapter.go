@@ -2,15 +2,28 @@ package drivingadapters
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/application"
 	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/infrastructure/tracing"
 )
 
+// actorHeader is the request header that names the user recorded in the audit trail of
+// the batches a request changes
+const actorHeader = "X-Actor"
+
+// defaultActor is the actor recorded for requests without an actor header
+const defaultActor = "api"
+
+// defaultHistoryPageSize is how many audit entries a history page holds by default
+const defaultHistoryPageSize = 50
+
 // ApiServiceAdapter is responsible for exposing the application's capabilities
 // over HTTP protocol through RESTful web service endpoints
 type ApiServiceAdapter struct {
@@ -18,10 +31,54 @@ type ApiServiceAdapter struct {
 	router       *gin.Engine
 	port         string
 	batchService application.BatchServiceInterface
+
+	deadLetterService application.DeadLetterServiceInterface
+	inventoryService  application.InventoryServiceInterface
+	recallService     application.RecallServiceInterface
+	returnService     application.ReturnServiceInterface
+	metricsHandler    http.Handler
+}
+
+// ApiServiceOption configures optional ApiServiceAdapter capabilities
+type ApiServiceOption func(*ApiServiceAdapter)
+
+// WithDeadLetterService exposes the dead-letter admin endpoints
+func WithDeadLetterService(deadLetterService application.DeadLetterServiceInterface) ApiServiceOption {
+	return func(adapter *ApiServiceAdapter) {
+		adapter.deadLetterService = deadLetterService
+	}
+}
+
+// WithInventoryService exposes the stock ledger endpoints
+func WithInventoryService(inventoryService application.InventoryServiceInterface) ApiServiceOption {
+	return func(adapter *ApiServiceAdapter) {
+		adapter.inventoryService = inventoryService
+	}
+}
+
+// WithRecallService exposes the product recall endpoints
+func WithRecallService(recallService application.RecallServiceInterface) ApiServiceOption {
+	return func(adapter *ApiServiceAdapter) {
+		adapter.recallService = recallService
+	}
+}
+
+// WithReturnService exposes the customer return endpoints
+func WithReturnService(returnService application.ReturnServiceInterface) ApiServiceOption {
+	return func(adapter *ApiServiceAdapter) {
+		adapter.returnService = returnService
+	}
+}
+
+// WithMetricsHandler exposes the handler at GET /metrics for Prometheus to scrape
+func WithMetricsHandler(handler http.Handler) ApiServiceOption {
+	return func(adapter *ApiServiceAdapter) {
+		adapter.metricsHandler = handler
+	}
 }
 
 // NewApiServiceAdapter creates a new ApiServiceAdapter
-func NewApiServiceAdapter(port string, batchService application.BatchServiceInterface) *ApiServiceAdapter {
+func NewApiServiceAdapter(port string, batchService application.BatchServiceInterface, opts ...ApiServiceOption) *ApiServiceAdapter {
 	// Set gin to release mode for production
 	gin.SetMode(gin.ReleaseMode)
 	
@@ -36,6 +93,9 @@ func NewApiServiceAdapter(port string, batchService application.BatchServiceInte
 		port:         port,
 		batchService: batchService,
 	}
+	for _, opt := range opts {
+		opt(adapter)
+	}
 	
 	// Setup routes
 	adapter.setupRoutes()
@@ -53,15 +113,162 @@ func NewApiServiceAdapter(port string, batchService application.BatchServiceInte
 func (adapter *ApiServiceAdapter) setupRoutes() {
 	// Health check endpoint
 	adapter.router.GET("/health", adapter.healthHandler)
+
+	// Prometheus metrics endpoint
+	if adapter.metricsHandler != nil {
+		adapter.router.GET("/metrics", gin.WrapH(adapter.metricsHandler))
+	}
 	
-	// Batch endpoints
-	v1 := adapter.router.Group("/api/v1")
+	// Batch endpoints, every request traced
+	v1 := adapter.router.Group("/api/v1", tracing.Middleware())
 	{
 		v1.GET("/batches", adapter.getAllBatchesHandler)
+		v1.GET("/batches/expiring", adapter.getBatchesNearExpiryHandler)
 		v1.GET("/batches/product/:productId", adapter.getBatchesByProductHandler)
 		v1.GET("/batches/status/:status", adapter.getBatchesByStatusHandler)
 		v1.GET("/batches/order/:orderId", adapter.getBatchByOrderHandler)
+		v1.GET("/batches/:id", adapter.getBatchHandler)
+		v1.GET("/batches/:id/actions", adapter.getAllowedActionsHandler)
+		v1.GET("/batches/:id/history", adapter.getBatchHistoryHandler)
+
+		// Batch lifecycle commands
+		v1.POST("/batches", adapter.createLotBatchHandler)
+		v1.POST("/batches/orders", adapter.addOrderToBatchHandler)
+		v1.PUT("/batches/orders/:orderId/status", adapter.updateOrderStatusHandler)
+		v1.DELETE("/batches/orders/:orderId", adapter.removeOrderFromBatchHandler)
+		v1.PUT("/batches/:id/process", adapter.processBatchHandler)
+		v1.PUT("/batches/:id/complete", adapter.completeBatchHandler)
+		v1.PUT("/batches/:id/cancel", adapter.cancelBatchHandler)
+		v1.PUT("/batches/:id/damage", adapter.markBatchAsDamagedHandler)
+		v1.PUT("/batches/:id/hold", adapter.holdBatchHandler)
+
+		// Quarantine of damaged batches
+		v1.PUT("/batches/:id/inspection", adapter.inspectBatchHandler)
+		v1.PUT("/batches/:id/release", adapter.releaseBatchHandler)
+		v1.PUT("/batches/:id/write-off", adapter.writeOffBatchItemsHandler)
+		v1.PUT("/batches/:id/destroy", adapter.destroyBatchHandler)
+	}
+
+	// Dead-letter admin endpoints
+	if adapter.deadLetterService != nil {
+		admin := v1.Group("/admin")
+		{
+			admin.GET("/dead-letters", adapter.getDeadLettersHandler)
+			admin.GET("/dead-letters/:id", adapter.getDeadLetterHandler)
+			admin.PUT("/dead-letters/:id/payload", adapter.updateDeadLetterPayloadHandler)
+			admin.POST("/dead-letters/:id/replay", adapter.replayDeadLetterHandler)
+		}
 	}
+
+	// Stock ledger endpoints
+	if adapter.inventoryService != nil {
+		v1.GET("/inventory", adapter.getAllStockHandler)
+		v1.GET("/inventory/:productId", adapter.getStockHandler)
+		v1.GET("/inventory/:productId/movements", adapter.getStockMovementsHandler)
+		v1.POST("/inventory/:productId/receipts", adapter.receiveStockHandler)
+		v1.POST("/inventory/:productId/adjustments", adapter.adjustStockHandler)
+	}
+
+	// Product recall endpoints
+	if adapter.recallService != nil {
+		v1.GET("/recalls", adapter.getAllRecallsHandler)
+		v1.GET("/recalls/:id", adapter.getRecallHandler)
+		v1.POST("/recalls", adapter.initiateRecallHandler)
+		v1.POST("/recalls/:id/resume", adapter.resumeRecallHandler)
+		v1.PUT("/recalls/:id/orders/:orderId/resolve", adapter.resolveRecallOrderHandler)
+	}
+
+	// Customer return endpoints
+	if adapter.returnService != nil {
+		v1.POST("/batches/orders/:orderId/returns", adapter.openReturnHandler)
+		v1.GET("/batches/:id/returns", adapter.getBatchReturnsHandler)
+		v1.GET("/returns", adapter.getAllReturnsHandler)
+		v1.GET("/returns/:id", adapter.getReturnHandler)
+		v1.PUT("/returns/:id/disposition", adapter.resolveReturnHandler)
+	}
+}
+
+// CreateLotBatchRequest is the request body of POST /api/v1/batches
+type CreateLotBatchRequest struct {
+	ProductID      string     `json:"product_id" binding:"required"`
+	LotNumber      string     `json:"lot_number" binding:"required"`
+	ManufacturedAt *time.Time `json:"manufactured_at"`
+	ExpiresAt      *time.Time `json:"expires_at" binding:"required"`
+}
+
+// AddOrderToBatchRequest is the request body of POST /api/v1/batches/orders
+type AddOrderToBatchRequest struct {
+	OrderID   string `json:"order_id" binding:"required"`
+	ProductID string `json:"product_id" binding:"required"`
+	Quantity  int    `json:"quantity" binding:"required,gt=0"`
+	Status    string `json:"status"`
+}
+
+// UpdateOrderStatusRequest is the request body of PUT /api/v1/batches/orders/:orderId/status
+type UpdateOrderStatusRequest struct {
+	Status string `json:"status" binding:"required"`
+}
+
+// InspectBatchRequest is the request body of PUT /api/v1/batches/:id/inspection
+type InspectBatchRequest struct {
+	Inspector string `json:"inspector" binding:"required"`
+	Findings  string `json:"findings" binding:"required"`
+}
+
+// QuarantineDispositionRequest is the request body of PUT /api/v1/batches/:id/release,
+// /write-off and /destroy; order_ids lists the orders to write off
+type QuarantineDispositionRequest struct {
+	DecidedBy string   `json:"decided_by" binding:"required"`
+	Reason    string   `json:"reason"`
+	OrderIDs  []string `json:"order_ids"`
+}
+
+// HoldBatchRequest is the request body of PUT /api/v1/batches/:id/hold
+type HoldBatchRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// UpdateDeadLetterPayloadRequest is the request body of PUT /api/v1/admin/dead-letters/:id/payload
+type UpdateDeadLetterPayloadRequest struct {
+	Payload string `json:"payload" binding:"required"`
+}
+
+// ReceiveStockRequest is the request body of POST /api/v1/inventory/:productId/receipts
+type ReceiveStockRequest struct {
+	Quantity int    `json:"quantity" binding:"required,gt=0"`
+	Reason   string `json:"reason"`
+}
+
+// AdjustStockRequest is the request body of POST /api/v1/inventory/:productId/adjustments
+type AdjustStockRequest struct {
+	Delta  int    `json:"delta" binding:"required"`
+	Reason string `json:"reason" binding:"required"`
+}
+
+// InitiateRecallRequest is the request body of POST /api/v1/recalls. Without a lot
+// number every lot of the product is recalled.
+type InitiateRecallRequest struct {
+	ProductID   string `json:"product_id" binding:"required"`
+	LotNumber   string `json:"lot_number"`
+	Reason      string `json:"reason" binding:"required"`
+	InitiatedBy string `json:"initiated_by" binding:"required"`
+}
+
+// OpenReturnRequest is the request body of POST /api/v1/batches/orders/:orderId/returns.
+// Without a quantity the whole order is returned; without a condition the goods are
+// recorded as uninspected.
+type OpenReturnRequest struct {
+	Quantity  int    `json:"quantity"`
+	Reason    string `json:"reason" binding:"required"`
+	Condition string `json:"condition"`
+}
+
+// ResolveReturnRequest is the request body of PUT /api/v1/returns/:id/disposition.
+// The condition found on inspection replaces the recorded one when given.
+type ResolveReturnRequest struct {
+	Disposition string `json:"disposition" binding:"required"`
+	Condition   string `json:"condition"`
+	DecidedBy   string `json:"decided_by" binding:"required"`
 }
 
 // healthHandler handles health check requests
@@ -203,4 +410,704 @@ func (adapter *ApiServiceAdapter) getBatchByOrderHandler(c *gin.Context) {
 		"order_id": orderID,
 		"batch":    batchDTO,
 	})
+}
+
+// getBatchHandler handles GET /api/v1/batches/:id. The optional as_of query parameter
+// is an RFC 3339 time; the batch is then returned as it was at that time.
+func (adapter *ApiServiceAdapter) getBatchHandler(c *gin.Context) {
+	value := c.Query("as_of")
+	if value == "" {
+		batch, err := adapter.batchService.GetBatchByID(c.Param("id"))
+		if err != nil {
+			respondWithError(c, "Failed to retrieve batch", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"batch": application.ToBatchDTO(batch),
+		})
+		return
+	}
+
+	asOf, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid as_of time",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	batch, err := adapter.batchService.GetBatchAsOf(c.Param("id"), asOf)
+	if err != nil {
+		respondWithError(c, "Failed to retrieve batch", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"batch": application.ToBatchDTO(batch),
+		"as_of": asOf.UTC(),
+	})
+}
+
+// getAllowedActionsHandler handles GET /api/v1/batches/:id/actions
+func (adapter *ApiServiceAdapter) getAllowedActionsHandler(c *gin.Context) {
+	batchID := c.Param("id")
+
+	actions, err := adapter.batchService.GetAllowedActions(batchID)
+	if err != nil {
+		respondWithError(c, "Failed to retrieve allowed actions", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"batch_id":        batchID,
+		"allowed_actions": actions,
+	})
+}
+
+// getBatchHistoryHandler handles GET /api/v1/batches/:id/history. The optional offset
+// and limit query parameters page through the audit trail, oldest entry first.
+func (adapter *ApiServiceAdapter) getBatchHistoryHandler(c *gin.Context) {
+	batchID := c.Param("id")
+
+	offset, err := queryInt(c, "offset", 0)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid offset",
+			"details": err.Error(),
+		})
+		return
+	}
+	limit, err := queryInt(c, "limit", defaultHistoryPageSize)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid limit",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	entries, total, err := adapter.batchService.GetBatchHistory(batchID, offset, limit)
+	if err != nil {
+		respondWithError(c, "Failed to retrieve batch history", err)
+		return
+	}
+
+	historyDTOs := application.ToBatchAuditEntryDTOs(entries)
+	c.JSON(http.StatusOK, gin.H{
+		"batch_id": batchID,
+		"history":  historyDTOs,
+		"count":    len(historyDTOs),
+		"total":    total,
+		"offset":   offset,
+		"limit":    limit,
+	})
+}
+
+// getBatchesNearExpiryHandler handles GET /api/v1/batches/expiring. The optional
+// within query parameter is a duration such as 720h; it defaults to the configured
+// warning window.
+func (adapter *ApiServiceAdapter) getBatchesNearExpiryHandler(c *gin.Context) {
+	var within time.Duration
+	if value := c.Query("within"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid within duration",
+				"details": err.Error(),
+			})
+			return
+		}
+		within = parsed
+	}
+
+	batches, err := adapter.batchService.GetBatchesNearExpiry(within)
+	if err != nil {
+		respondWithError(c, "Failed to retrieve batches near expiry", err)
+		return
+	}
+
+	batchDTOs := application.ToBatchDTOs(batches)
+	c.JSON(http.StatusOK, gin.H{
+		"batches": batchDTOs,
+		"count":   len(batchDTOs),
+	})
+}
+
+// createLotBatchHandler handles POST /api/v1/batches
+func (adapter *ApiServiceAdapter) createLotBatchHandler(c *gin.Context) {
+	var request CreateLotBatchRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	var manufacturedAt time.Time
+	if request.ManufacturedAt != nil {
+		manufacturedAt = *request.ManufacturedAt
+	}
+
+	batch, err := adapter.batchesFor(c).CreateLotBatch(request.ProductID, request.LotNumber, manufacturedAt, *request.ExpiresAt)
+	if err != nil {
+		respondWithError(c, "Failed to create lot batch", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"batch": application.ToBatchDTO(batch),
+	})
+}
+
+// addOrderToBatchHandler handles POST /api/v1/batches/orders
+func (adapter *ApiServiceAdapter) addOrderToBatchHandler(c *gin.Context) {
+	var request AddOrderToBatchRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if request.Status == "" {
+		request.Status = string(domain.ItemStatusAllocated)
+	}
+
+	batch, err := adapter.batchesFor(c).AddOrderToBatch(request.OrderID, request.ProductID, request.Quantity, domain.ItemStatus(request.Status))
+	if err != nil {
+		respondWithError(c, "Failed to add order to batch", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"order_id": request.OrderID,
+		"batch":    application.ToBatchDTO(batch),
+	})
+}
+
+// updateOrderStatusHandler handles PUT /api/v1/batches/orders/:orderId/status
+func (adapter *ApiServiceAdapter) updateOrderStatusHandler(c *gin.Context) {
+	orderID := c.Param("orderId")
+
+	var request UpdateOrderStatusRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := adapter.batchesFor(c).UpdateOrderStatus(orderID, domain.ItemStatus(request.Status)); err != nil {
+		respondWithError(c, "Failed to update order status", err)
+		return
+	}
+
+	adapter.respondWithBatchForOrder(c, orderID)
+}
+
+// removeOrderFromBatchHandler handles DELETE /api/v1/batches/orders/:orderId
+func (adapter *ApiServiceAdapter) removeOrderFromBatchHandler(c *gin.Context) {
+	orderID := c.Param("orderId")
+
+	if err := adapter.batchesFor(c).RemoveOrderFromBatch(orderID); err != nil {
+		respondWithError(c, "Failed to remove order from batch", err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// processBatchHandler handles PUT /api/v1/batches/:id/process
+func (adapter *ApiServiceAdapter) processBatchHandler(c *gin.Context) {
+	adapter.runBatchCommand(c, "Failed to start processing batch", adapter.batchesFor(c).ProcessBatch)
+}
+
+// completeBatchHandler handles PUT /api/v1/batches/:id/complete
+func (adapter *ApiServiceAdapter) completeBatchHandler(c *gin.Context) {
+	adapter.runBatchCommand(c, "Failed to complete batch", adapter.batchesFor(c).CompleteBatch)
+}
+
+// cancelBatchHandler handles PUT /api/v1/batches/:id/cancel
+func (adapter *ApiServiceAdapter) cancelBatchHandler(c *gin.Context) {
+	adapter.runBatchCommand(c, "Failed to cancel batch", adapter.batchesFor(c).CancelBatch)
+}
+
+// markBatchAsDamagedHandler handles PUT /api/v1/batches/:id/damage
+func (adapter *ApiServiceAdapter) markBatchAsDamagedHandler(c *gin.Context) {
+	adapter.runBatchCommand(c, "Failed to mark batch as damaged", adapter.batchesFor(c).MarkBatchAsDamaged)
+}
+
+// holdBatchHandler handles PUT /api/v1/batches/:id/hold
+func (adapter *ApiServiceAdapter) holdBatchHandler(c *gin.Context) {
+	var request HoldBatchRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	adapter.runBatchCommand(c, "Failed to put batch on hold", func(batchID string) error {
+		return adapter.batchesFor(c).HoldBatch(batchID, request.Reason)
+	})
+}
+
+// inspectBatchHandler handles PUT /api/v1/batches/:id/inspection
+func (adapter *ApiServiceAdapter) inspectBatchHandler(c *gin.Context) {
+	var request InspectBatchRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	adapter.runBatchCommand(c, "Failed to record batch inspection", func(batchID string) error {
+		return adapter.batchesFor(c).InspectBatch(batchID, request.Inspector, request.Findings)
+	})
+}
+
+// releaseBatchHandler handles PUT /api/v1/batches/:id/release
+func (adapter *ApiServiceAdapter) releaseBatchHandler(c *gin.Context) {
+	adapter.runDispositionCommand(c, "Failed to release batch", func(batchID string, request QuarantineDispositionRequest) error {
+		return adapter.batchesFor(c).ReleaseBatch(batchID, request.DecidedBy, request.Reason)
+	})
+}
+
+// writeOffBatchItemsHandler handles PUT /api/v1/batches/:id/write-off
+func (adapter *ApiServiceAdapter) writeOffBatchItemsHandler(c *gin.Context) {
+	adapter.runDispositionCommand(c, "Failed to write off batch items", func(batchID string, request QuarantineDispositionRequest) error {
+		return adapter.batchesFor(c).WriteOffBatchItems(batchID, request.OrderIDs, request.DecidedBy, request.Reason)
+	})
+}
+
+// destroyBatchHandler handles PUT /api/v1/batches/:id/destroy
+func (adapter *ApiServiceAdapter) destroyBatchHandler(c *gin.Context) {
+	adapter.runDispositionCommand(c, "Failed to destroy batch", func(batchID string, request QuarantineDispositionRequest) error {
+		return adapter.batchesFor(c).DestroyBatch(batchID, request.DecidedBy, request.Reason)
+	})
+}
+
+// runDispositionCommand binds the disposition request and runs the quarantine command
+func (adapter *ApiServiceAdapter) runDispositionCommand(c *gin.Context, failureMessage string, command func(batchID string, request QuarantineDispositionRequest) error) {
+	var request QuarantineDispositionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	adapter.runBatchCommand(c, failureMessage, func(batchID string) error {
+		return command(batchID, request)
+	})
+}
+
+// runBatchCommand executes a batch lifecycle command and responds with the updated batch
+func (adapter *ApiServiceAdapter) runBatchCommand(c *gin.Context, failureMessage string, command func(batchID string) error) {
+	batchID := c.Param("id")
+
+	if err := command(batchID); err != nil {
+		respondWithError(c, failureMessage, err)
+		return
+	}
+
+	batch, err := adapter.batchService.GetBatchByID(batchID)
+	if err != nil {
+		respondWithError(c, "Failed to retrieve batch", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"batch": application.ToBatchDTO(batch),
+	})
+}
+
+// batchesFor returns the batch service issuing the commands of a request on behalf of
+// the actor named by its actor header, in the trace of the request
+func (adapter *ApiServiceAdapter) batchesFor(c *gin.Context) application.BatchServiceInterface {
+	actor := c.GetHeader(actorHeader)
+	if actor == "" {
+		actor = defaultActor
+	}
+	return adapter.batchService.IssuedBy(domain.BatchIssuer{
+		Actor:        actor,
+		TraceContext: tracing.TraceContextFromContext(c.Request.Context()),
+	})
+}
+
+// queryInt parses an integer query parameter, returning the default when it is absent
+func queryInt(c *gin.Context, name string, defaultValue int) (int, error) {
+	value := c.Query(name)
+	if value == "" {
+		return defaultValue, nil
+	}
+	return strconv.Atoi(value)
+}
+
+// respondWithBatchForOrder responds with the batch that currently holds the order
+func (adapter *ApiServiceAdapter) respondWithBatchForOrder(c *gin.Context, orderID string) {
+	batch, err := adapter.batchService.GetBatchByOrderID(orderID)
+	if err != nil {
+		respondWithError(c, "Failed to retrieve batch for order", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"order_id": orderID,
+		"batch":    application.ToBatchDTO(batch),
+	})
+}
+
+// getDeadLettersHandler handles GET /api/v1/admin/dead-letters
+func (adapter *ApiServiceAdapter) getDeadLettersHandler(c *gin.Context) {
+	status := domain.DeadLetterStatus(c.Query("status"))
+
+	deadLetters, err := adapter.deadLetterService.GetDeadLetters(status)
+	if err != nil {
+		respondWithError(c, "Failed to retrieve dead letters", err)
+		return
+	}
+
+	deadLetterDTOs := application.ToDeadLetterDTOs(deadLetters)
+	c.JSON(http.StatusOK, gin.H{
+		"dead_letters": deadLetterDTOs,
+		"count":        len(deadLetterDTOs),
+	})
+}
+
+// getDeadLetterHandler handles GET /api/v1/admin/dead-letters/:id
+func (adapter *ApiServiceAdapter) getDeadLetterHandler(c *gin.Context) {
+	deadLetter, err := adapter.deadLetterService.GetDeadLetter(c.Param("id"))
+	if err != nil {
+		respondWithError(c, "Failed to retrieve dead letter", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"dead_letter": application.ToDeadLetterDTO(deadLetter),
+	})
+}
+
+// updateDeadLetterPayloadHandler handles PUT /api/v1/admin/dead-letters/:id/payload
+func (adapter *ApiServiceAdapter) updateDeadLetterPayloadHandler(c *gin.Context) {
+	var request UpdateDeadLetterPayloadRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	deadLetter, err := adapter.deadLetterService.UpdateDeadLetterPayload(c.Param("id"), []byte(request.Payload))
+	if err != nil {
+		respondWithError(c, "Failed to update dead letter payload", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"dead_letter": application.ToDeadLetterDTO(deadLetter),
+	})
+}
+
+// replayDeadLetterHandler handles POST /api/v1/admin/dead-letters/:id/replay.
+// A failed replay responds with 422 and the recorded outcome.
+func (adapter *ApiServiceAdapter) replayDeadLetterHandler(c *gin.Context) {
+	deadLetter, err := adapter.deadLetterService.ReplayDeadLetter(c.Param("id"))
+	if err != nil {
+		respondWithError(c, "Failed to replay dead letter", err)
+		return
+	}
+
+	replay := deadLetter.Replays[len(deadLetter.Replays)-1]
+	status := http.StatusOK
+	if !replay.Succeeded {
+		status = http.StatusUnprocessableEntity
+	}
+
+	c.JSON(status, gin.H{
+		"replay":      replay,
+		"dead_letter": application.ToDeadLetterDTO(deadLetter),
+	})
+}
+
+// getAllStockHandler handles GET /api/v1/inventory
+func (adapter *ApiServiceAdapter) getAllStockHandler(c *gin.Context) {
+	stocks, err := adapter.inventoryService.GetAllStock()
+	if err != nil {
+		respondWithError(c, "Failed to retrieve stock", err)
+		return
+	}
+
+	stockDTOs := application.ToStockLevelDTOs(stocks)
+	c.JSON(http.StatusOK, gin.H{
+		"stock": stockDTOs,
+		"count": len(stockDTOs),
+	})
+}
+
+// getStockHandler handles GET /api/v1/inventory/:productId
+func (adapter *ApiServiceAdapter) getStockHandler(c *gin.Context) {
+	stock, err := adapter.inventoryService.GetStock(c.Param("productId"))
+	if err != nil {
+		respondWithError(c, "Failed to retrieve stock", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"stock": application.ToStockLevelDTO(stock),
+	})
+}
+
+// getStockMovementsHandler handles GET /api/v1/inventory/:productId/movements
+func (adapter *ApiServiceAdapter) getStockMovementsHandler(c *gin.Context) {
+	productID := c.Param("productId")
+
+	movements, err := adapter.inventoryService.GetMovements(productID)
+	if err != nil {
+		respondWithError(c, "Failed to retrieve stock movements", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"product_id": productID,
+		"movements":  movements,
+		"count":      len(movements),
+	})
+}
+
+// receiveStockHandler handles POST /api/v1/inventory/:productId/receipts
+func (adapter *ApiServiceAdapter) receiveStockHandler(c *gin.Context) {
+	var request ReceiveStockRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	stock, err := adapter.inventoryService.ReceiveStock(c.Param("productId"), request.Quantity, request.Reason)
+	if err != nil {
+		respondWithError(c, "Failed to receive stock", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"stock": application.ToStockLevelDTO(stock),
+	})
+}
+
+// adjustStockHandler handles POST /api/v1/inventory/:productId/adjustments
+func (adapter *ApiServiceAdapter) adjustStockHandler(c *gin.Context) {
+	var request AdjustStockRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	stock, err := adapter.inventoryService.AdjustStock(c.Param("productId"), request.Delta, request.Reason)
+	if err != nil {
+		respondWithError(c, "Failed to adjust stock", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"stock": application.ToStockLevelDTO(stock),
+	})
+}
+
+// getAllRecallsHandler handles GET /api/v1/recalls
+func (adapter *ApiServiceAdapter) getAllRecallsHandler(c *gin.Context) {
+	recalls, err := adapter.recallService.GetAllRecalls()
+	if err != nil {
+		respondWithError(c, "Failed to retrieve recalls", err)
+		return
+	}
+
+	recallDTOs := application.ToRecallCaseDTOs(recalls)
+	c.JSON(http.StatusOK, gin.H{
+		"recalls": recallDTOs,
+		"count":   len(recallDTOs),
+	})
+}
+
+// getRecallHandler handles GET /api/v1/recalls/:id
+func (adapter *ApiServiceAdapter) getRecallHandler(c *gin.Context) {
+	recall, err := adapter.recallService.GetRecall(c.Param("id"))
+	if err != nil {
+		respondWithError(c, "Failed to retrieve recall", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"recall": application.ToRecallCaseDTO(recall),
+	})
+}
+
+// initiateRecallHandler handles POST /api/v1/recalls
+func (adapter *ApiServiceAdapter) initiateRecallHandler(c *gin.Context) {
+	var request InitiateRecallRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	recall, err := adapter.recallService.InitiateRecall(request.ProductID, request.LotNumber, request.Reason, request.InitiatedBy)
+	if err != nil {
+		respondWithError(c, "Failed to initiate recall", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"recall": application.ToRecallCaseDTO(recall),
+	})
+}
+
+// resumeRecallHandler handles POST /api/v1/recalls/:id/resume
+func (adapter *ApiServiceAdapter) resumeRecallHandler(c *gin.Context) {
+	recall, err := adapter.recallService.ResumeRecall(c.Param("id"))
+	if err != nil {
+		respondWithError(c, "Failed to resume recall", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"recall": application.ToRecallCaseDTO(recall),
+	})
+}
+
+// resolveRecallOrderHandler handles PUT /api/v1/recalls/:id/orders/:orderId/resolve
+func (adapter *ApiServiceAdapter) resolveRecallOrderHandler(c *gin.Context) {
+	recall, err := adapter.recallService.ResolveRecallOrder(c.Param("id"), c.Param("orderId"))
+	if err != nil {
+		respondWithError(c, "Failed to resolve recall order", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"recall": application.ToRecallCaseDTO(recall),
+	})
+}
+
+// openReturnHandler handles POST /api/v1/batches/orders/:orderId/returns
+func (adapter *ApiServiceAdapter) openReturnHandler(c *gin.Context) {
+	var request OpenReturnRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	rma, err := adapter.returnService.OpenReturn(c.Param("orderId"), request.Quantity, request.Reason, domain.ReturnCondition(request.Condition))
+	if err != nil {
+		respondWithError(c, "Failed to open return", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"return": application.ToReturnDTO(rma),
+	})
+}
+
+// getBatchReturnsHandler handles GET /api/v1/batches/:id/returns
+func (adapter *ApiServiceAdapter) getBatchReturnsHandler(c *gin.Context) {
+	returns, err := adapter.returnService.GetReturnsByBatchID(c.Param("id"))
+	if err != nil {
+		respondWithError(c, "Failed to retrieve batch returns", err)
+		return
+	}
+
+	returnDTOs := application.ToReturnDTOs(returns)
+	c.JSON(http.StatusOK, gin.H{
+		"returns": returnDTOs,
+		"count":   len(returnDTOs),
+	})
+}
+
+// getAllReturnsHandler handles GET /api/v1/returns
+func (adapter *ApiServiceAdapter) getAllReturnsHandler(c *gin.Context) {
+	returns, err := adapter.returnService.GetAllReturns()
+	if err != nil {
+		respondWithError(c, "Failed to retrieve returns", err)
+		return
+	}
+
+	returnDTOs := application.ToReturnDTOs(returns)
+	c.JSON(http.StatusOK, gin.H{
+		"returns": returnDTOs,
+		"count":   len(returnDTOs),
+	})
+}
+
+// getReturnHandler handles GET /api/v1/returns/:id
+func (adapter *ApiServiceAdapter) getReturnHandler(c *gin.Context) {
+	rma, err := adapter.returnService.GetReturn(c.Param("id"))
+	if err != nil {
+		respondWithError(c, "Failed to retrieve return", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"return": application.ToReturnDTO(rma),
+	})
+}
+
+// resolveReturnHandler handles PUT /api/v1/returns/:id/disposition
+func (adapter *ApiServiceAdapter) resolveReturnHandler(c *gin.Context) {
+	var request ResolveReturnRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	rma, err := adapter.returnService.ResolveReturn(c.Param("id"), domain.ReturnDisposition(request.Disposition),
+		domain.ReturnCondition(request.Condition), request.DecidedBy)
+	if err != nil {
+		respondWithError(c, "Failed to resolve return", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"return": application.ToReturnDTO(rma),
+	})
+}
+
+// respondWithError maps domain errors to HTTP status codes
+func respondWithError(c *gin.Context, message string, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, domain.ErrInvalidTransition), errors.Is(err, domain.ErrConcurrencyConflict),
+		errors.Is(err, domain.ErrInsufficientStock), errors.Is(err, domain.ErrAlreadyExists):
+		status = http.StatusConflict
+	case errors.Is(err, domain.ErrValidation):
+		status = http.StatusBadRequest
+	}
+
+	c.JSON(status, gin.H{
+		"error":   message,
+		"details": err.Error(),
+	})
 }
\ No newline at end of file
@@ -0,0 +1,721 @@
+package drivingadapters
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/application"
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+	drivenadapters "github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/infrastructure/driven-adapters"
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/infrastructure/tracing"
+)
+
+func newTestApiServiceAdapter() (*ApiServiceAdapter, *application.BatchService) {
+	repo := drivenadapters.NewBatchMemoryRepository()
+	batchService := application.NewBatchService(repo, domain.NewMockBatchEventPublisher())
+	return NewApiServiceAdapter("0", batchService), batchService
+}
+
+func performRequest(adapter *ApiServiceAdapter, method, path string, body interface{}) *httptest.ResponseRecorder {
+	var payload bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&payload).Encode(body)
+	}
+
+	request := httptest.NewRequest(method, path, &payload)
+	request.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	adapter.router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func decodeBatch(t *testing.T, recorder *httptest.ResponseRecorder) application.BatchDTO {
+	t.Helper()
+
+	var response struct {
+		Batch application.BatchDTO `json:"batch"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response %s: %v", recorder.Body.String(), err)
+	}
+	return response.Batch
+}
+
+func TestApiServiceAdapter_BatchLifecycle(t *testing.T) {
+	adapter, _ := newTestApiServiceAdapter()
+
+	recorder := performRequest(adapter, http.MethodPost, "/api/v1/batches/orders", AddOrderToBatchRequest{
+		OrderID:   "order-1",
+		ProductID: "product-1",
+		Quantity:  4,
+	})
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	batch := decodeBatch(t, recorder)
+	if batch.Status != string(domain.BatchStatusPending) || len(batch.Items) != 1 {
+		t.Fatalf("Expected pending batch with one item, got %+v", batch)
+	}
+	if batch.Items[0].Status != "allocated" {
+		t.Errorf("Expected default item status allocated, got %s", batch.Items[0].Status)
+	}
+
+	steps := []struct {
+		path   string
+		status domain.BatchStatus
+	}{
+		{"/api/v1/batches/" + batch.ID + "/process", domain.BatchStatusProcessing},
+		{"/api/v1/batches/" + batch.ID + "/complete", domain.BatchStatusCompleted},
+	}
+	for _, step := range steps {
+		recorder = performRequest(adapter, http.MethodPut, step.path, nil)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("PUT %s: expected status 200, got %d: %s", step.path, recorder.Code, recorder.Body.String())
+		}
+		if got := decodeBatch(t, recorder).Status; got != string(step.status) {
+			t.Errorf("PUT %s: expected batch status %s, got %s", step.path, step.status, got)
+		}
+	}
+
+	recorder = performRequest(adapter, http.MethodGet, "/api/v1/batches/"+batch.ID, nil)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", recorder.Code)
+	}
+	if decodeBatch(t, recorder).ProcessedAt == nil {
+		t.Error("Expected completed batch to have processed_at")
+	}
+}
+
+func TestApiServiceAdapter_Metrics(t *testing.T) {
+	adapter, _ := newTestApiServiceAdapter()
+	if recorder := performRequest(adapter, http.MethodGet, "/metrics", nil); recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected no metrics endpoint without a metrics handler, got status %d", recorder.Code)
+	}
+
+	metricsHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("warehouse_batch_batches{status=\"pending\"} 0\n"))
+	})
+	repo := drivenadapters.NewBatchMemoryRepository()
+	adapter = NewApiServiceAdapter("0", application.NewBatchService(repo, domain.NewMockBatchEventPublisher()),
+		WithMetricsHandler(metricsHandler))
+
+	recorder := performRequest(adapter, http.MethodGet, "/metrics", nil)
+	if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), "warehouse_batch_batches") {
+		t.Errorf("Expected the metrics handler to serve GET /metrics, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestApiServiceAdapter_Tracing(t *testing.T) {
+	exporter, restore := tracing.InstallInMemoryExporter()
+	defer restore()
+
+	publisher := domain.NewMockBatchEventPublisher()
+	batchService := application.NewBatchService(drivenadapters.NewBatchMemoryRepository(), publisher,
+		application.WithTracer(tracing.Tracer{}))
+	adapter := NewApiServiceAdapter("0", batchService)
+
+	var payload bytes.Buffer
+	json.NewEncoder(&payload).Encode(AddOrderToBatchRequest{OrderID: "order-1", ProductID: "product-1", Quantity: 1})
+	request := httptest.NewRequest(http.MethodPost, "/api/v1/batches/orders", &payload)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	recorder := httptest.NewRecorder()
+	adapter.router.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	names := make(map[string]string)
+	for _, span := range exporter.GetSpans() {
+		names[span.Name] = span.SpanContext.TraceID().String()
+	}
+	for _, name := range []string{"POST /api/v1/batches/orders", "BatchService.AddOrderToBatchForCustomer"} {
+		if names[name] != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Errorf("Expected span %s in the trace of the caller, got spans %v", name, names)
+		}
+	}
+
+	if _, err := batchService.OutboxRelay().RelayPending(); err != nil {
+		t.Fatalf("Failed to relay the outbox: %v", err)
+	}
+	events := publisher.GetPublishedEvents()
+	if len(events) == 0 || !strings.Contains(events[0].TraceContext.TraceParent, "4bf92f3577b34da6a3ce929d0e0e4736") {
+		t.Errorf("Expected the batch events to carry the trace of the request, got %+v", events)
+	}
+
+	// Health checks are not traced
+	exporter.Reset()
+	performRequest(adapter, http.MethodGet, "/health", nil)
+	if spans := exporter.GetSpans(); len(spans) != 0 {
+		t.Errorf("Expected no span for the health check, got %d", len(spans))
+	}
+}
+
+func TestApiServiceAdapter_MapsDomainErrors(t *testing.T) {
+	adapter, batchService := newTestApiServiceAdapter()
+
+	batch, err := batchService.AddOrderToBatch("order-1", "product-1", 1, "allocated")
+	if err != nil {
+		t.Fatalf("Failed to add order: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		method   string
+		path     string
+		body     interface{}
+		expected int
+	}{
+		{"complete pending batch", http.MethodPut, "/api/v1/batches/" + batch.ID + "/complete", nil, http.StatusConflict},
+		{"process unknown batch", http.MethodPut, "/api/v1/batches/missing/process", nil, http.StatusNotFound},
+		{"get unknown batch", http.MethodGet, "/api/v1/batches/missing", nil, http.StatusNotFound},
+		{"remove unknown order", http.MethodDelete, "/api/v1/batches/orders/missing", nil, http.StatusNotFound},
+		{"update unknown order", http.MethodPut, "/api/v1/batches/orders/missing/status", UpdateOrderStatusRequest{Status: "shipped"}, http.StatusNotFound},
+		{"missing product", http.MethodPost, "/api/v1/batches/orders", map[string]interface{}{"order_id": "order-2", "quantity": 1}, http.StatusBadRequest},
+		{"zero quantity", http.MethodPost, "/api/v1/batches/orders", map[string]interface{}{"order_id": "order-2", "product_id": "product-1", "quantity": 0}, http.StatusBadRequest},
+		{"missing status", http.MethodPut, "/api/v1/batches/orders/order-1/status", map[string]interface{}{}, http.StatusBadRequest},
+		{"unknown item status", http.MethodPut, "/api/v1/batches/orders/order-1/status", UpdateOrderStatusRequest{Status: "lost"}, http.StatusBadRequest},
+		{"illegal item transition", http.MethodPut, "/api/v1/batches/orders/order-1/status", UpdateOrderStatusRequest{Status: "delivered"}, http.StatusConflict},
+		{"actions of unknown batch", http.MethodGet, "/api/v1/batches/missing/actions", nil, http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := performRequest(adapter, tc.method, tc.path, tc.body)
+			if recorder.Code != tc.expected {
+				t.Errorf("Expected status %d, got %d: %s", tc.expected, recorder.Code, recorder.Body.String())
+			}
+		})
+	}
+
+	// A cancelled batch cannot be reopened for processing
+	if recorder := performRequest(adapter, http.MethodPut, "/api/v1/batches/"+batch.ID+"/cancel", nil); recorder.Code != http.StatusOK {
+		t.Fatalf("Expected cancel to succeed, got %d", recorder.Code)
+	}
+	recorder := performRequest(adapter, http.MethodPut, "/api/v1/batches/"+batch.ID+"/process", nil)
+	if recorder.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 when processing a cancelled batch, got %d", recorder.Code)
+	}
+}
+
+func TestApiServiceAdapter_AllowedActions(t *testing.T) {
+	adapter, batchService := newTestApiServiceAdapter()
+
+	batch, err := batchService.AddOrderToBatch("order-1", "product-1", 1, domain.ItemStatusAllocated)
+	if err != nil {
+		t.Fatalf("Failed to add order: %v", err)
+	}
+
+	allowedActions := func() []domain.BatchAction {
+		recorder := performRequest(adapter, http.MethodGet, "/api/v1/batches/"+batch.ID+"/actions", nil)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+		var response struct {
+			AllowedActions []domain.BatchAction `json:"allowed_actions"`
+		}
+		json.Unmarshal(recorder.Body.Bytes(), &response)
+		return response.AllowedActions
+	}
+
+	if actions := allowedActions(); len(actions) == 0 || actions[0] != domain.BatchActionProcess {
+		t.Errorf("Expected a pending batch to allow processing first, got %v", actions)
+	}
+
+	performRequest(adapter, http.MethodPut, "/api/v1/batches/"+batch.ID+"/process", nil)
+	performRequest(adapter, http.MethodPut, "/api/v1/batches/"+batch.ID+"/complete", nil)
+	if actions := allowedActions(); len(actions) != 0 {
+		t.Errorf("Expected a completed batch to allow no actions, got %v", actions)
+	}
+
+	// The UI hides the damage command, and the API rejects it as well
+	recorder := performRequest(adapter, http.MethodPut, "/api/v1/batches/"+batch.ID+"/damage", nil)
+	if recorder.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 marking a completed batch as damaged, got %d", recorder.Code)
+	}
+}
+
+func TestApiServiceAdapter_LotsAndExpiry(t *testing.T) {
+	adapter, _ := newTestApiServiceAdapter()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	manufacturedAt := now.AddDate(-1, 0, 0)
+	expiresAt := now.AddDate(0, 0, 10)
+
+	recorder := performRequest(adapter, http.MethodPost, "/api/v1/batches", CreateLotBatchRequest{
+		ProductID:      "product-1",
+		LotNumber:      "LOT-1",
+		ManufacturedAt: &manufacturedAt,
+		ExpiresAt:      &expiresAt,
+	})
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	lot := decodeBatch(t, recorder)
+	if lot.LotNumber != "LOT-1" || lot.ExpiresAt == nil || !lot.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("Expected lot LOT-1 expiring %v, got %+v", expiresAt, lot)
+	}
+
+	recorder = performRequest(adapter, http.MethodPost, "/api/v1/batches", CreateLotBatchRequest{
+		ProductID: "product-1",
+		LotNumber: "LOT-1",
+		ExpiresAt: &expiresAt,
+	})
+	if recorder.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 for a lot registered twice, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	expired := now.Add(-time.Hour)
+	recorder = performRequest(adapter, http.MethodPost, "/api/v1/batches", CreateLotBatchRequest{
+		ProductID: "product-1",
+		LotNumber: "LOT-2",
+		ExpiresAt: &expired,
+	})
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an expired lot, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	recorder = performRequest(adapter, http.MethodPost, "/api/v1/batches/orders", AddOrderToBatchRequest{
+		OrderID:   "order-1",
+		ProductID: "product-1",
+		Quantity:  2,
+	})
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if batch := decodeBatch(t, recorder); batch.ID != lot.ID || batch.Items[0].LotNumber != "LOT-1" {
+		t.Errorf("Expected order to be allocated from LOT-1, got %+v", batch)
+	}
+
+	expiring := func(query string) (int, []application.BatchDTO) {
+		recorder := performRequest(adapter, http.MethodGet, "/api/v1/batches/expiring"+query, nil)
+		var response struct {
+			Batches []application.BatchDTO `json:"batches"`
+		}
+		json.Unmarshal(recorder.Body.Bytes(), &response)
+		return recorder.Code, response.Batches
+	}
+
+	if code, batches := expiring(""); code != http.StatusOK || len(batches) != 1 || batches[0].ID != lot.ID {
+		t.Errorf("Expected LOT-1 within the default window, got %d %+v", code, batches)
+	}
+	if code, batches := expiring("?within=48h"); code != http.StatusOK || len(batches) != 0 {
+		t.Errorf("Expected no lot expiring within 48h, got %d %+v", code, batches)
+	}
+	if code, _ := expiring("?within=soon"); code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid window, got %d", code)
+	}
+}
+
+func TestApiServiceAdapter_OrderCommands(t *testing.T) {
+	adapter, batchService := newTestApiServiceAdapter()
+
+	if _, err := batchService.AddOrderToBatch("order-1", "product-1", 1, "allocated"); err != nil {
+		t.Fatalf("Failed to add order: %v", err)
+	}
+
+	recorder := performRequest(adapter, http.MethodPut, "/api/v1/batches/orders/order-1/status", UpdateOrderStatusRequest{Status: "shipped"})
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if item := decodeBatch(t, recorder).Items[0]; item.Status != "shipped" {
+		t.Errorf("Expected item status shipped, got %s", item.Status)
+	}
+
+	recorder = performRequest(adapter, http.MethodDelete, "/api/v1/batches/orders/order-1", nil)
+	if recorder.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d", recorder.Code)
+	}
+
+	// Existing query routes still resolve next to the new parameterized ones
+	recorder = performRequest(adapter, http.MethodGet, "/api/v1/batches/order/order-1", nil)
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for removed order, got %d", recorder.Code)
+	}
+	recorder = performRequest(adapter, http.MethodGet, "/api/v1/batches/status/pending", nil)
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected status 200 for status query, got %d", recorder.Code)
+	}
+}
+
+func TestApiServiceAdapter_DeadLetters(t *testing.T) {
+	repo := drivenadapters.NewBatchMemoryRepository()
+	batchService := application.NewBatchService(repo, domain.NewMockBatchEventPublisher())
+	deadLetterService := application.NewDeadLetterService(drivenadapters.NewDeadLetterMemoryStore(), application.NewOrderService(batchService), nil)
+	adapter := NewApiServiceAdapter("0", batchService, WithDeadLetterService(deadLetterService))
+
+	err := deadLetterService.PublishDeadLetter(&domain.DeadLetter{
+		Topic:      "order-events",
+		Offset:     7,
+		Payload:    []byte(`{not json`),
+		ErrorClass: string(ErrorClassDecode),
+		Reason:     "failed to decode order event: invalid character 'n'",
+		Attempts:   1,
+		FailedAt:   time.Now().UTC(),
+	})
+	if err != nil {
+		t.Fatalf("Failed to park dead letter: %v", err)
+	}
+
+	recorder := performRequest(adapter, http.MethodGet, "/api/v1/admin/dead-letters?status=parked", nil)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var list struct {
+		DeadLetters []application.DeadLetterDTO `json:"dead_letters"`
+		Count       int                         `json:"count"`
+	}
+	json.Unmarshal(recorder.Body.Bytes(), &list)
+	if list.Count != 1 || list.DeadLetters[0].ID != "order-events-0-7" || list.DeadLetters[0].Payload != `{not json` {
+		t.Fatalf("Expected the parked dead letter, got %s", recorder.Body.String())
+	}
+	if list.DeadLetters[0].ErrorClass != "decode" || list.DeadLetters[0].Reason == "" {
+		t.Errorf("Expected the decode error to be shown, got %+v", list.DeadLetters[0])
+	}
+
+	if recorder := performRequest(adapter, http.MethodGet, "/api/v1/admin/dead-letters/missing", nil); recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown dead letter, got %d", recorder.Code)
+	}
+
+	// Replaying the broken payload fails and records the outcome
+	recorder = performRequest(adapter, http.MethodPost, "/api/v1/admin/dead-letters/order-events-0-7/replay", nil)
+	if recorder.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status 422, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	payload := `{"event_type":"order.created","order_id":"order-7","order":{"id":"order-7","product_id":"product-1","quantity":2,"status":"created"}}`
+	recorder = performRequest(adapter, http.MethodPut, "/api/v1/admin/dead-letters/order-events-0-7/payload", UpdateDeadLetterPayloadRequest{Payload: payload})
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	recorder = performRequest(adapter, http.MethodPost, "/api/v1/admin/dead-letters/order-events-0-7/replay", nil)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	recorder = performRequest(adapter, http.MethodGet, "/api/v1/admin/dead-letters/order-events-0-7", nil)
+	var detail struct {
+		DeadLetter application.DeadLetterDTO `json:"dead_letter"`
+	}
+	json.Unmarshal(recorder.Body.Bytes(), &detail)
+	if detail.DeadLetter.Status != string(domain.DeadLetterStatusReplayed) || len(detail.DeadLetter.Replays) != 2 {
+		t.Errorf("Expected replayed dead letter with two recorded replays, got %+v", detail.DeadLetter)
+	}
+
+	if recorder := performRequest(adapter, http.MethodPost, "/api/v1/admin/dead-letters/order-events-0-7/replay", nil); recorder.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 replaying twice, got %d", recorder.Code)
+	}
+
+	if _, err := batchService.GetBatchByOrderID("order-7"); err != nil {
+		t.Errorf("Expected the replayed order to be batched: %v", err)
+	}
+}
+
+func TestApiServiceAdapter_Inventory(t *testing.T) {
+	repo := drivenadapters.NewBatchMemoryRepository()
+	batchService := application.NewBatchService(repo, domain.NewMockBatchEventPublisher())
+	inventoryService := application.NewInventoryService(drivenadapters.NewInventoryMemoryRepository(), domain.NewMockInventoryEventPublisher())
+	adapter := NewApiServiceAdapter("0", batchService, WithInventoryService(inventoryService))
+
+	if recorder := performRequest(adapter, http.MethodGet, "/api/v1/inventory/product-1", nil); recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for unstocked product, got %d", recorder.Code)
+	}
+
+	recorder := performRequest(adapter, http.MethodPost, "/api/v1/inventory/product-1/receipts", ReceiveStockRequest{Quantity: 10, Reason: "PO-1"})
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	if _, err := inventoryService.ReserveStock("order-1", "product-1", 4); err != nil {
+		t.Fatalf("Failed to reserve stock: %v", err)
+	}
+
+	// Counting cannot remove reserved stock
+	recorder = performRequest(adapter, http.MethodPost, "/api/v1/inventory/product-1/adjustments", AdjustStockRequest{Delta: -7, Reason: "cycle count"})
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 adjusting below reserved, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	recorder = performRequest(adapter, http.MethodPost, "/api/v1/inventory/product-1/adjustments", AdjustStockRequest{Delta: -1})
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for adjustment without reason, got %d", recorder.Code)
+	}
+	recorder = performRequest(adapter, http.MethodPost, "/api/v1/inventory/product-1/adjustments", AdjustStockRequest{Delta: -1, Reason: "cycle count"})
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	recorder = performRequest(adapter, http.MethodGet, "/api/v1/inventory/product-1", nil)
+	var response struct {
+		Stock application.StockLevelDTO `json:"stock"`
+	}
+	json.Unmarshal(recorder.Body.Bytes(), &response)
+	if response.Stock.OnHand != 9 || response.Stock.Reserved != 4 || response.Stock.Available != 5 {
+		t.Errorf("Expected 9 on hand, 4 reserved and 5 available, got %s", recorder.Body.String())
+	}
+
+	recorder = performRequest(adapter, http.MethodGet, "/api/v1/inventory/product-1/movements", nil)
+	var movements struct {
+		Movements []domain.StockMovement `json:"movements"`
+		Count     int                    `json:"count"`
+	}
+	json.Unmarshal(recorder.Body.Bytes(), &movements)
+	if movements.Count != 3 || movements.Movements[2].Type != domain.StockMovementAdjustment {
+		t.Errorf("Expected receipt, reservation and adjustment, got %s", recorder.Body.String())
+	}
+
+	recorder = performRequest(adapter, http.MethodGet, "/api/v1/inventory", nil)
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", recorder.Code)
+	}
+}
+
+func TestApiServiceAdapter_Quarantine(t *testing.T) {
+	adapter, batchService := newTestApiServiceAdapter()
+
+	batch, err := batchService.AddOrderToBatch("order-1", "product-1", 2, domain.ItemStatusAllocated)
+	if err != nil {
+		t.Fatalf("Failed to add order: %v", err)
+	}
+	if err := batchService.MarkBatchAsDamaged(batch.ID); err != nil {
+		t.Fatalf("Failed to mark batch as damaged: %v", err)
+	}
+
+	path := "/api/v1/batches/" + batch.ID
+	recorder := performRequest(adapter, http.MethodPut, path+"/destroy", QuarantineDispositionRequest{DecidedBy: "qa-lead", Reason: "excursion"})
+	if recorder.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 destroying an uninspected batch, got %d", recorder.Code)
+	}
+	recorder = performRequest(adapter, http.MethodPut, path+"/inspection", InspectBatchRequest{Inspector: "qa-1"})
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an inspection without findings, got %d", recorder.Code)
+	}
+
+	recorder = performRequest(adapter, http.MethodPut, path+"/inspection", InspectBatchRequest{Inspector: "qa-1", Findings: "excursion of 30h at 25C"})
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if inspected := decodeBatch(t, recorder); inspected.Status != string(domain.BatchStatusInspected) || inspected.Inspection == nil {
+		t.Errorf("Expected inspected batch with findings, got %+v", inspected)
+	}
+
+	recorder = performRequest(adapter, http.MethodPut, path+"/destroy", QuarantineDispositionRequest{DecidedBy: "qa-lead"})
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 destroying without a reason, got %d", recorder.Code)
+	}
+	recorder = performRequest(adapter, http.MethodPut, path+"/destroy", QuarantineDispositionRequest{DecidedBy: "qa-lead", Reason: "outside stability data"})
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	destroyed := decodeBatch(t, recorder)
+	if destroyed.Status != string(domain.BatchStatusDestroyed) || destroyed.Items[0].Status != string(domain.ItemStatusDestroyed) {
+		t.Errorf("Expected destroyed batch and items, got %+v", destroyed)
+	}
+	if destroyed.Disposition == nil || destroyed.Disposition.Outcome != domain.QuarantineOutcomeDestroyed {
+		t.Errorf("Expected the destruction to be recorded, got %+v", destroyed.Disposition)
+	}
+}
+
+func TestApiServiceAdapter_Recalls(t *testing.T) {
+	repo := drivenadapters.NewBatchMemoryRepository()
+	batchService := application.NewBatchService(repo, domain.NewMockBatchEventPublisher())
+	recallService := application.NewRecallService(repo, drivenadapters.NewRecallMemoryRepository(), batchService.OutboxRelay())
+	adapter := NewApiServiceAdapter("0", batchService, WithRecallService(recallService))
+
+	held, err := batchService.AddOrderToBatchForCustomer("order-1", "customer-1", "product-1", 2, domain.ItemStatusAllocated)
+	if err != nil {
+		t.Fatalf("Failed to add order: %v", err)
+	}
+	other, err := batchService.AddOrderToBatch("order-2", "product-2", 1, domain.ItemStatusAllocated)
+	if err != nil {
+		t.Fatalf("Failed to add order: %v", err)
+	}
+
+	recorder := performRequest(adapter, http.MethodPut, "/api/v1/batches/"+other.ID+"/hold", HoldBatchRequest{})
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a hold without reason, got %d", recorder.Code)
+	}
+	recorder = performRequest(adapter, http.MethodPut, "/api/v1/batches/"+other.ID+"/hold", HoldBatchRequest{Reason: "supplier notice"})
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if batch := decodeBatch(t, recorder); batch.Status != string(domain.BatchStatusOnHold) || batch.HoldReason != "supplier notice" {
+		t.Errorf("Expected batch on hold with its reason, got %+v", batch)
+	}
+
+	recorder = performRequest(adapter, http.MethodPost, "/api/v1/recalls", InitiateRecallRequest{ProductID: "product-1", Reason: "contamination"})
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a recall without initiator, got %d", recorder.Code)
+	}
+	recorder = performRequest(adapter, http.MethodPost, "/api/v1/recalls", InitiateRecallRequest{ProductID: "product-9", Reason: "contamination", InitiatedBy: "qa-lead"})
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for a product without batches, got %d", recorder.Code)
+	}
+
+	recorder = performRequest(adapter, http.MethodPost, "/api/v1/recalls", InitiateRecallRequest{ProductID: "product-1", Reason: "contamination", InitiatedBy: "qa-lead"})
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var response struct {
+		Recall application.RecallCaseDTO `json:"recall"`
+	}
+	json.Unmarshal(recorder.Body.Bytes(), &response)
+	recall := response.Recall
+	if recall.Status != string(domain.RecallStatusContained) || len(recall.Orders) != 1 || recall.Orders[0].BatchID != held.ID {
+		t.Fatalf("Expected a contained recall of order-1, got %s", recorder.Body.String())
+	}
+	if len(recall.CustomerIDs) != 1 || recall.CustomerIDs[0] != "customer-1" {
+		t.Errorf("Expected customer-1 to be affected, got %v", recall.CustomerIDs)
+	}
+
+	path := "/api/v1/recalls/" + recall.ID
+	if recorder := performRequest(adapter, http.MethodPut, path+"/orders/order-9/resolve", nil); recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unaffected order, got %d", recorder.Code)
+	}
+	recorder = performRequest(adapter, http.MethodPut, path+"/orders/order-1/resolve", nil)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	recorder = performRequest(adapter, http.MethodGet, path, nil)
+	json.Unmarshal(recorder.Body.Bytes(), &response)
+	if response.Recall.Status != string(domain.RecallStatusClosed) || response.Recall.Progress.OrdersResolved != 1 {
+		t.Errorf("Expected a closed recall, got %s", recorder.Body.String())
+	}
+
+	recorder = performRequest(adapter, http.MethodGet, "/api/v1/recalls", nil)
+	var list struct {
+		Count int `json:"count"`
+	}
+	json.Unmarshal(recorder.Body.Bytes(), &list)
+	if list.Count != 1 {
+		t.Errorf("Expected 1 recall, got %s", recorder.Body.String())
+	}
+}
+
+func TestApiServiceAdapter_Returns(t *testing.T) {
+	repo := drivenadapters.NewBatchMemoryRepository()
+	batchService := application.NewBatchService(repo, domain.NewMockBatchEventPublisher())
+	returnService := application.NewReturnService(batchService, drivenadapters.NewReturnMemoryRepository())
+	adapter := NewApiServiceAdapter("0", batchService, WithReturnService(returnService))
+
+	batch, err := batchService.AddOrderToBatchForCustomer("order-1", "customer-1", "product-1", 3, domain.ItemStatusDelivered)
+	if err != nil {
+		t.Fatalf("Failed to add order: %v", err)
+	}
+
+	recorder := performRequest(adapter, http.MethodPost, "/api/v1/batches/orders/order-1/returns", OpenReturnRequest{Quantity: 1})
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a return without reason, got %d", recorder.Code)
+	}
+	recorder = performRequest(adapter, http.MethodPost, "/api/v1/batches/orders/order-9/returns", OpenReturnRequest{Reason: "wrong item"})
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown order, got %d", recorder.Code)
+	}
+
+	recorder = performRequest(adapter, http.MethodPost, "/api/v1/batches/orders/order-1/returns", OpenReturnRequest{Quantity: 1, Reason: "wrong item"})
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var response struct {
+		Return application.ReturnDTO `json:"return"`
+	}
+	json.Unmarshal(recorder.Body.Bytes(), &response)
+	rma := response.Return
+	if rma.BatchID != batch.ID || rma.Quantity != 1 || rma.Condition != string(domain.ReturnConditionUninspected) {
+		t.Fatalf("Expected an uninspected return of order-1, got %s", recorder.Body.String())
+	}
+	recorder = performRequest(adapter, http.MethodPost, "/api/v1/batches/orders/order-1/returns", OpenReturnRequest{Reason: "wrong item"})
+	if recorder.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 for a second return, got %d", recorder.Code)
+	}
+
+	path := "/api/v1/returns/" + rma.ID
+	recorder = performRequest(adapter, http.MethodPut, path+"/disposition", ResolveReturnRequest{Disposition: "restock", DecidedBy: "qa-lead"})
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 restocking uninspected goods, got %d", recorder.Code)
+	}
+	recorder = performRequest(adapter, http.MethodPut, path+"/disposition", ResolveReturnRequest{Disposition: "destroy", Condition: "damaged", DecidedBy: "qa-lead"})
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	recorder = performRequest(adapter, http.MethodGet, path, nil)
+	json.Unmarshal(recorder.Body.Bytes(), &response)
+	if response.Return.Status != string(domain.ReturnStatusResolved) || response.Return.Disposition != string(domain.ReturnDispositionDestroy) {
+		t.Errorf("Expected a destroyed return, got %s", recorder.Body.String())
+	}
+
+	var list struct {
+		Count int `json:"count"`
+	}
+	recorder = performRequest(adapter, http.MethodGet, "/api/v1/batches/"+batch.ID+"/returns", nil)
+	json.Unmarshal(recorder.Body.Bytes(), &list)
+	if recorder.Code != http.StatusOK || list.Count != 1 {
+		t.Errorf("Expected 1 return for the batch, got %s", recorder.Body.String())
+	}
+	recorder = performRequest(adapter, http.MethodGet, "/api/v1/returns", nil)
+	json.Unmarshal(recorder.Body.Bytes(), &list)
+	if list.Count != 1 {
+		t.Errorf("Expected 1 return, got %s", recorder.Body.String())
+	}
+}
+
+func TestApiServiceAdapter_BatchHistory(t *testing.T) {
+	adapter, batchService := newTestApiServiceAdapter()
+
+	batch, err := batchService.AddOrderToBatch("order-1", "product-1", 2, domain.ItemStatusAllocated)
+	if err != nil {
+		t.Fatalf("Failed to add order: %v", err)
+	}
+
+	request := httptest.NewRequest(http.MethodPut, "/api/v1/batches/"+batch.ID+"/process", nil)
+	request.Header.Set("X-Actor", "alice")
+	recorder := httptest.NewRecorder()
+	adapter.router.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder = performRequest(adapter, http.MethodPut, "/api/v1/batches/"+batch.ID+"/complete", nil); recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	recorder = performRequest(adapter, http.MethodGet, "/api/v1/batches/"+batch.ID+"/history?offset=1&limit=5", nil)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var response struct {
+		History []application.BatchAuditEntryDTO `json:"history"`
+		Count   int                              `json:"count"`
+		Total   int                              `json:"total"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Total != 3 || response.Count != 2 {
+		t.Fatalf("Expected 2 of 3 entries, got %+v", response)
+	}
+	processed, completed := response.History[0], response.History[1]
+	if processed.Command != string(domain.BatchCommandProcess) || processed.Actor != "alice" ||
+		processed.StatusBefore != string(domain.BatchStatusPending) {
+		t.Errorf("Expected alice to start processing, got %+v", processed)
+	}
+	if completed.Command != string(domain.BatchCommandComplete) || completed.Actor != "api" {
+		t.Errorf("Expected the default actor to complete the batch, got %+v", completed)
+	}
+
+	for path, status := range map[string]int{
+		"/api/v1/batches/" + batch.ID + "/history?limit=abc": http.StatusBadRequest,
+		"/api/v1/batches/" + batch.ID + "/history?limit=0":   http.StatusBadRequest,
+		"/api/v1/batches/missing/history":                    http.StatusNotFound,
+	} {
+		if recorder := performRequest(adapter, http.MethodGet, path, nil); recorder.Code != status {
+			t.Errorf("GET %s: expected status %d, got %d", path, status, recorder.Code)
+		}
+	}
+}
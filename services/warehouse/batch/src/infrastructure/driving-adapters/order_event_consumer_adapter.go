@@ -2,26 +2,102 @@ package drivingadapters
 
 import (
 	"context"
-	"encoding/json"
+	"fmt"
+	"hash/fnv"
 	"log"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
 	"github.com/segmentio/kafka-go"
 )
 
+// eventIDHeader is the Kafka message header that carries the producer's event ID
+const eventIDHeader = "event_id"
+
+// defaultMaxInFlight is the default number of fetched messages that may wait for handling
+const defaultMaxInFlight = 100
+
+// orderEventReader is the part of kafka.Reader used by the consumer adapter
+type orderEventReader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Config() kafka.ReaderConfig
+	Stats() kafka.ReaderStats
+	Close() error
+}
+
 // OrderEventConsumerAdapter is responsible for consuming order events from Kafka
-// and translating them into domain order events for the application layer
+// and translating them into domain order events for the application layer.
+// Every message is decoded once when fetched and sharded by the order ID of its event over
+// a pool of workers, so events of the same order are handled in sequence while different
+// orders are handled in parallel.
+// The offset of a message is only committed after it and every earlier message of its
+// partition were handled or dead-lettered, so a crash or shutdown in between redelivers it.
 type OrderEventConsumerAdapter struct {
-	reader            *kafka.Reader
-	orderEventHandler domain.OrderEventHandler
+	reader              orderEventReader
+	orderEventHandler   domain.OrderEventHandler
+	retryPolicies       RetryPolicies
+	deadLetterPublisher domain.DeadLetterPublisher
+	codecs              domain.EventCodecs
+	workers             int
+	maxInFlight         int
+	offsets             *offsetTracker
+}
+
+// OrderEventConsumerOption configures optional OrderEventConsumerAdapter behaviour
+type OrderEventConsumerOption func(*OrderEventConsumerAdapter)
+
+// WithRetryPolicies overrides the default retry policies per error class
+func WithRetryPolicies(policies RetryPolicies) OrderEventConsumerOption {
+	return func(adapter *OrderEventConsumerAdapter) {
+		adapter.retryPolicies = policies
+	}
+}
+
+// WithDeadLetterPublisher sets where messages are parked once their retries are exhausted.
+// Without it such messages are logged and skipped.
+func WithDeadLetterPublisher(publisher domain.DeadLetterPublisher) OrderEventConsumerOption {
+	return func(adapter *OrderEventConsumerAdapter) {
+		adapter.deadLetterPublisher = publisher
+	}
+}
+
+// WithEventCodecs sets the codecs that decode order events by their content type.
+// JSON is always accepted.
+func WithEventCodecs(codecs domain.EventCodecs) OrderEventConsumerOption {
+	return func(adapter *OrderEventConsumerAdapter) {
+		adapter.codecs = codecs
+	}
 }
 
-// NewOrderEventConsumerAdapter creates a new OrderEventConsumerAdapter
-func NewOrderEventConsumerAdapter(brokerAddress, topic, groupID string, orderEventHandler domain.OrderEventHandler) *OrderEventConsumerAdapter {
+// WithConcurrency sets the number of workers and how many fetched messages may be in
+// flight (queued or being handled) at once; fetching pauses when the limit is reached
+func WithConcurrency(workers, maxInFlight int) OrderEventConsumerOption {
+	return func(adapter *OrderEventConsumerAdapter) {
+		if workers > 0 {
+			adapter.workers = workers
+		}
+		if maxInFlight > 0 {
+			adapter.maxInFlight = maxInFlight
+		}
+	}
+}
+
+// NewOrderEventConsumerAdapter creates a new OrderEventConsumerAdapter that reads from
+// the brokers through the dialer, which carries the TLS and SASL settings of the
+// connection; a nil dialer connects in plaintext without authentication
+func NewOrderEventConsumerAdapter(brokers []string, dialer *kafka.Dialer, topic, groupID string, orderEventHandler domain.OrderEventHandler, opts ...OrderEventConsumerOption) *OrderEventConsumerAdapter {
+	if dialer == nil {
+		dialer = &kafka.Dialer{
+			Timeout: 10 * time.Second,
+		}
+	}
+
 	reader := kafka.NewReader(kafka.ReaderConfig{
-		Brokers:     []string{brokerAddress},
+		Brokers:     brokers,
 		Topic:       topic,
 		GroupID:     groupID,
 		MinBytes:    10e3, // 10KB
@@ -29,15 +105,26 @@ func NewOrderEventConsumerAdapter(brokerAddress, topic, groupID string, orderEve
 		StartOffset: kafka.LastOffset,
 		// Add retry configurations for Kubernetes
 		MaxAttempts: 3,
-		Dialer: &kafka.Dialer{
-			Timeout: 10 * time.Second,
-		},
+		Dialer:      dialer,
 	})
 
-	return &OrderEventConsumerAdapter{
+	return newOrderEventConsumerAdapter(reader, orderEventHandler, opts...)
+}
+
+// newOrderEventConsumerAdapter creates a consumer adapter on top of the given reader
+func newOrderEventConsumerAdapter(reader orderEventReader, orderEventHandler domain.OrderEventHandler, opts ...OrderEventConsumerOption) *OrderEventConsumerAdapter {
+	adapter := &OrderEventConsumerAdapter{
 		reader:            reader,
 		orderEventHandler: orderEventHandler,
+		retryPolicies:     DefaultRetryPolicies(),
+		workers:           1,
+		maxInFlight:       defaultMaxInFlight,
+		offsets:           newOffsetTracker(),
+	}
+	for _, opt := range opts {
+		opt(adapter)
 	}
+	return adapter
 }
 
 // Start begins consuming order events from the message broker
@@ -45,64 +132,295 @@ func (adapter *OrderEventConsumerAdapter) Start(ctx context.Context) {
 	config := adapter.reader.Config()
 	log.Printf("Starting order event consumer adapter with group ID: %s", config.GroupID)
 	log.Printf("Consuming from topic: %s, brokers: %v", config.Topic, config.Brokers)
+	log.Printf("Handling order events with %d workers and up to %d messages in flight", adapter.workers, adapter.maxInFlight)
 	log.Printf("Waiting for order events... (timeout errors are normal when no messages are available)")
-	
+
+	inFlight := make(chan struct{}, adapter.maxInFlight)
+	queues := make([]chan *queuedMessage, adapter.workers)
+	var workers sync.WaitGroup
+	for i := range queues {
+		queues[i] = make(chan *queuedMessage, adapter.maxInFlight)
+		workers.Add(1)
+		go func(queue <-chan *queuedMessage) {
+			defer workers.Done()
+			adapter.runWorker(ctx, queue, inFlight)
+		}(queues[i])
+	}
+
+	committerDone := make(chan struct{})
+	go func() {
+		defer close(committerDone)
+		adapter.runCommitter(ctx)
+	}()
+
+	adapter.fetchMessages(ctx, queues, inFlight)
+
+	log.Println("Order event consumer adapter stopping...")
+	for _, queue := range queues {
+		close(queue)
+	}
+	workers.Wait()
+	<-committerDone
+
+	// Commit the messages settled before stopping; the consumer context is already cancelled
+	commitCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	adapter.commitSettled(commitCtx)
+	cancel()
+
+	adapter.Close()
+}
+
+// fetchMessages fetches and decodes messages and hands them to the worker of their order
+// until the context is cancelled, pausing while maxInFlight messages are waiting to be settled
+func (adapter *OrderEventConsumerAdapter) fetchMessages(ctx context.Context, queues []chan *queuedMessage, inFlight chan struct{}) {
 	for {
 		select {
 		case <-ctx.Done():
-			log.Println("Order event consumer adapter stopping...")
-			adapter.Close()
 			return
-		default:
-			// Create a context with timeout for reading messages
-			readCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
-			
-			// Fetch the next message from Kafka
-			msg, err := adapter.reader.ReadMessage(readCtx)
-			cancel()
-			
-			if err != nil {
-				// Only log non-timeout errors to reduce noise
-				if !strings.Contains(err.Error(), "context deadline exceeded") {
-					log.Printf("Error reading order event message: %v", err)
-				}
-				// Add backoff for connection errors
-				time.Sleep(2 * time.Second)
-				continue
-			}
+		case inFlight <- struct{}{}:
+		}
+
+		// Create a context with timeout for reading messages
+		readCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+
+		// Fetch the next message from Kafka without committing its offset
+		msg, err := adapter.reader.FetchMessage(readCtx)
+		cancel()
 
-			// Translate Kafka message to domain order event
-			orderEvent, err := adapter.translateMessage(msg)
-			if err != nil {
-				log.Printf("Error translating order event message: %v", err)
-				continue
+		if err != nil {
+			<-inFlight
+			if ctx.Err() != nil {
+				return
 			}
-			
-			// Handle the order event through the application layer
-			if err := adapter.orderEventHandler.HandleOrderEvent(orderEvent); err != nil {
-				log.Printf("Error handling order event: %v", err)
+			// Only log non-timeout errors to reduce noise
+			if !strings.Contains(err.Error(), "context deadline exceeded") {
+				log.Printf("Error reading order event message: %v", err)
 			}
+			// Add backoff for connection errors
+			sleepContext(ctx, 2*time.Second)
+			continue
 		}
+
+		queued := adapter.queueMessage(msg)
+		queues[shardIndex(queued.shardKey(), len(queues))] <- queued
+	}
+}
+
+// queuedMessage is a fetched message on its way to a worker together with the order
+// event decoded from it, or the error that kept it from being decoded
+type queuedMessage struct {
+	tracked   *trackedMessage
+	event     domain.OrderEvent
+	decodeErr error
+}
+
+// queueMessage tracks the offset of a fetched message and decodes its order event
+func (adapter *OrderEventConsumerAdapter) queueMessage(msg kafka.Message) *queuedMessage {
+	event, err := adapter.translateMessage(msg)
+	return &queuedMessage{tracked: adapter.offsets.track(msg), event: event, decodeErr: err}
+}
+
+// shardKey returns the key that selects the worker of a message: the order ID of its
+// event, falling back to its partition for messages that could not be decoded
+func (m *queuedMessage) shardKey() string {
+	if m.decodeErr == nil && m.event.OrderID != "" {
+		return m.event.OrderID
 	}
+	return "partition-" + strconv.Itoa(m.tracked.msg.Partition)
 }
 
-// translateMessage converts a Kafka message to a domain order event
+// runWorker handles the messages of its queue in order. Messages still queued when the
+// context is cancelled are skipped and redelivered after a restart.
+func (adapter *OrderEventConsumerAdapter) runWorker(ctx context.Context, queue <-chan *queuedMessage, inFlight <-chan struct{}) {
+	for queued := range queue {
+		if ctx.Err() == nil {
+			if err := adapter.processMessage(ctx, queued); err != nil {
+				// The context was cancelled while retrying; the uncommitted message is redelivered
+				log.Printf("Stopped processing message %s: %v", messagePosition(queued.tracked.msg), err)
+			} else {
+				adapter.offsets.settle(queued.tracked)
+			}
+		}
+		<-inFlight
+	}
+}
+
+// runCommitter commits the watermark of each partition whenever it advances
+func (adapter *OrderEventConsumerAdapter) runCommitter(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-adapter.offsets.advanced:
+			adapter.commitSettled(ctx)
+		}
+	}
+}
+
+// commitSettled commits the offsets up to the watermark of each partition
+func (adapter *OrderEventConsumerAdapter) commitSettled(ctx context.Context) {
+	msgs := adapter.offsets.committable()
+	if len(msgs) == 0 {
+		return
+	}
+
+	if err := adapter.reader.CommitMessages(ctx, msgs...); err != nil {
+		log.Printf("Error committing order event offsets, retrying with the next commit: %v", err)
+		return
+	}
+	adapter.offsets.committed(msgs)
+}
+
+// shardIndex maps a shard key to one of n workers
+func shardIndex(key string, n int) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	return int(hash.Sum32() % uint32(n))
+}
+
+// processMessage handles a message, retrying failures according to the retry policy
+// of their error class and dead-lettering the message once the retries are exhausted.
+// It only returns an error if the context is cancelled before the message is settled.
+func (adapter *OrderEventConsumerAdapter) processMessage(ctx context.Context, queued *queuedMessage) error {
+	msg := queued.tracked.msg
+	for attempts := 1; ; attempts++ {
+		err := adapter.handleMessage(queued)
+		if err == nil {
+			return nil
+		}
+
+		class := classifyError(err)
+		policy := adapter.retryPolicies.Policy(class)
+		if !policy.ShouldRetry(attempts) {
+			log.Printf("Giving up on message %s after %d attempts (%s error): %v", messagePosition(msg), attempts, class, err)
+			return adapter.deadLetter(ctx, msg, class, err, attempts)
+		}
+
+		backoff := policy.Backoff(attempts)
+		log.Printf("Error handling message %s (%s error, attempt %d/%d), retrying in %s: %v",
+			messagePosition(msg), class, attempts, policy.MaxAttempts, backoff, err)
+		if err := sleepContext(ctx, backoff); err != nil {
+			return err
+		}
+	}
+}
+
+// handleMessage hands the decoded order event of a message to the order event handler
+func (adapter *OrderEventConsumerAdapter) handleMessage(queued *queuedMessage) error {
+	if queued.decodeErr != nil {
+		return fmt.Errorf("%w: %v", errDecode, queued.decodeErr)
+	}
+
+	// Handle the order event through the application layer
+	return adapter.orderEventHandler.HandleOrderEvent(queued.event)
+}
+
+// deadLetter parks the message with the failure reason. Publishing is retried until it
+// succeeds, since committing the offset before that would lose the message.
+func (adapter *OrderEventConsumerAdapter) deadLetter(ctx context.Context, msg kafka.Message, class ErrorClass, cause error, attempts int) error {
+	if adapter.deadLetterPublisher == nil {
+		log.Printf("No dead-letter publisher configured, dropping message %s", messagePosition(msg))
+		return nil
+	}
+
+	deadLetter := newDeadLetter(msg, class, cause, attempts)
+	policy := adapter.retryPolicies.Policy(ErrorClassTransient)
+	for publishAttempts := 1; ; publishAttempts++ {
+		err := adapter.deadLetterPublisher.PublishDeadLetter(deadLetter)
+		if err == nil {
+			return nil
+		}
+
+		backoff := policy.Backoff(publishAttempts)
+		log.Printf("Error dead-lettering message %s, retrying in %s: %v", messagePosition(msg), backoff, err)
+		if err := sleepContext(ctx, backoff); err != nil {
+			return err
+		}
+	}
+}
+
+// newDeadLetter captures the original message and the failure reason
+func newDeadLetter(msg kafka.Message, class ErrorClass, cause error, attempts int) *domain.DeadLetter {
+	return &domain.DeadLetter{
+		Topic:      msg.Topic,
+		Partition:  msg.Partition,
+		Offset:     msg.Offset,
+		Key:        string(msg.Key),
+		Payload:    msg.Value,
+		Headers:    messageHeaders(msg),
+		ErrorClass: string(class),
+		Reason:     cause.Error(),
+		Attempts:   attempts,
+		FailedAt:   time.Now().UTC(),
+	}
+}
+
+// messageHeaders converts the headers of a message to domain message headers
+func messageHeaders(msg kafka.Message) []domain.MessageHeader {
+	headers := make([]domain.MessageHeader, len(msg.Headers))
+	for i, header := range msg.Headers {
+		headers[i] = domain.MessageHeader{Key: header.Key, Value: string(header.Value)}
+	}
+	return headers
+}
+
+// messagePosition formats the topic/partition/offset of a message for logging
+func messagePosition(msg kafka.Message) string {
+	return fmt.Sprintf("%s/%d/%d", msg.Topic, msg.Partition, msg.Offset)
+}
+
+// sleepContext waits for the given duration or until the context is cancelled
+func sleepContext(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// translateMessage converts a Kafka message, a plain order event or a CloudEvent in
+// binary or structured mode, to a domain order event. The data is JSON or any other
+// format of the configured codecs.
 func (adapter *OrderEventConsumerAdapter) translateMessage(msg kafka.Message) (domain.OrderEvent, error) {
-	var orderEvent domain.OrderEvent
-	
-	// Parse the JSON message value
-	if err := json.Unmarshal(msg.Value, &orderEvent); err != nil {
-		log.Printf("Failed to unmarshal order event JSON: %v", err)
+	// Parse the message value and its CloudEvents headers
+	orderEvent, err := adapter.codecs.DecodeOrderEvent(msg.Value, messageHeaders(msg))
+	if err != nil {
+		log.Printf("Failed to decode order event: %v", err)
 		log.Printf("Message value: %s", string(msg.Value))
 		return orderEvent, err
 	}
+
+	if orderEvent.EventID == "" {
+		orderEvent.EventID = messageEventID(msg)
+	}
 	
-	log.Printf("Successfully parsed order event: Type=%s, OrderID=%s", 
-		orderEvent.EventType, orderEvent.OrderID)
+	log.Printf("Successfully parsed order event: ID=%s, Type=%s, OrderID=%s", 
+		orderEvent.EventID, orderEvent.EventType, orderEvent.OrderID)
 	
 	return orderEvent, nil
 }
 
+// messageEventID identifies a message by its event ID header, falling back to its
+// topic/partition/offset, which is stable across redeliveries
+func messageEventID(msg kafka.Message) string {
+	for _, header := range msg.Headers {
+		if header.Key == eventIDHeader && len(header.Value) > 0 {
+			return string(header.Value)
+		}
+	}
+	return messagePosition(msg)
+}
+
+// Stats returns the statistics of the Kafka reader, such as the lag of the partition it
+// last fetched from. The reader resets its counters on every call.
+func (adapter *OrderEventConsumerAdapter) Stats() kafka.ReaderStats {
+	return adapter.reader.Stats()
+}
+
 // Close closes the Kafka reader
 func (adapter *OrderEventConsumerAdapter) Close() error {
 	if adapter.reader != nil {
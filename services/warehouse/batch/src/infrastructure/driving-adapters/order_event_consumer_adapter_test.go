@@ -0,0 +1,517 @@
+package drivingadapters
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+	drivenadapters "github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/infrastructure/driven-adapters"
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/infrastructure/eventspb"
+	"github.com/segmentio/kafka-go"
+	"google.golang.org/protobuf/proto"
+)
+
+// fakeOrderEventReader serves a fixed list of messages and records the committed ones.
+// Once the messages are consumed and the last one is committed it cancels the consumer context.
+type fakeOrderEventReader struct {
+	mutex     sync.Mutex
+	messages  []kafka.Message
+	fetched   int
+	last      kafka.Message
+	committed []kafka.Message
+	cancel    context.CancelFunc
+}
+
+func (r *fakeOrderEventReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if len(r.messages) == 0 {
+		r.mutex.Unlock()
+		for !r.lastCommitted() && ctx.Err() == nil {
+			time.Sleep(time.Millisecond)
+		}
+		r.mutex.Lock()
+		r.cancel()
+		return kafka.Message{}, context.Canceled
+	}
+	msg := r.messages[0]
+	r.messages = r.messages[1:]
+	r.fetched++
+	r.last = msg
+	return msg, nil
+}
+
+func (r *fakeOrderEventReader) lastCommitted() bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, msg := range r.committed {
+		if msg.Partition == r.last.Partition && msg.Offset == r.last.Offset {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *fakeOrderEventReader) fetchedCount() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.fetched
+}
+
+func (r *fakeOrderEventReader) CommitMessages(ctx context.Context, msgs ...kafka.Message) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.committed = append(r.committed, msgs...)
+	return nil
+}
+
+func (r *fakeOrderEventReader) Config() kafka.ReaderConfig {
+	return kafka.ReaderConfig{Topic: "order-events", GroupID: "test"}
+}
+
+func (r *fakeOrderEventReader) Stats() kafka.ReaderStats {
+	return kafka.ReaderStats{Topic: "order-events"}
+}
+
+func (r *fakeOrderEventReader) Close() error {
+	return nil
+}
+
+// scriptedOrderEventHandler fails the first failures[orderID] attempts of each order
+type scriptedOrderEventHandler struct {
+	mutex    sync.Mutex
+	failures map[string]error
+	attempts map[string]int
+	limit    map[string]int
+}
+
+func newScriptedOrderEventHandler() *scriptedOrderEventHandler {
+	return &scriptedOrderEventHandler{
+		failures: make(map[string]error),
+		attempts: make(map[string]int),
+		limit:    make(map[string]int),
+	}
+}
+
+func (h *scriptedOrderEventHandler) failTimes(orderID string, times int, err error) {
+	h.failures[orderID] = err
+	h.limit[orderID] = times
+}
+
+func (h *scriptedOrderEventHandler) HandleOrderEvent(event domain.OrderEvent) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.attempts[event.OrderID]++
+	if err, ok := h.failures[event.OrderID]; ok && h.attempts[event.OrderID] <= h.limit[event.OrderID] {
+		return err
+	}
+	return nil
+}
+
+// recordingDeadLetterPublisher records dead letters and fails while err is set
+type recordingDeadLetterPublisher struct {
+	mutex       sync.Mutex
+	deadLetters []*domain.DeadLetter
+	failures    int
+}
+
+func (p *recordingDeadLetterPublisher) PublishDeadLetter(deadLetter *domain.DeadLetter) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.failures > 0 {
+		p.failures--
+		return errors.New("dead-letter topic unavailable")
+	}
+	p.deadLetters = append(p.deadLetters, deadLetter)
+	return nil
+}
+
+func orderEventMessage(offset int64, orderID string) kafka.Message {
+	return kafka.Message{
+		Topic:     "order-events",
+		Partition: 0,
+		Offset:    offset,
+		Key:       []byte(orderID),
+		Value:     []byte(`{"event_type":"order.created","order_id":"` + orderID + `"}`),
+		Headers:   []kafka.Header{{Key: "source", Value: []byte("order-service")}},
+	}
+}
+
+func testRetryPolicies() RetryPolicies {
+	return RetryPolicies{
+		ErrorClassDecode:    {MaxAttempts: 1},
+		ErrorClassDomain:    {MaxAttempts: 2, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond},
+		ErrorClassTransient: {MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond},
+	}
+}
+
+func TestOrderEventConsumerAdapter_CommitsAfterHandling(t *testing.T) {
+	handler := newScriptedOrderEventHandler()
+	handler.failTimes("order-retried", 2, errors.New("database unavailable"))
+	handler.failTimes("order-exhausted", 5, errors.New("database unavailable"))
+	handler.failTimes("order-unknown", 5, domain.NewNotFoundError("no batch found containing order order-unknown"))
+
+	poison := orderEventMessage(1, "order-poison")
+	poison.Value = []byte(`{not json`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reader := &fakeOrderEventReader{
+		messages: []kafka.Message{
+			orderEventMessage(0, "order-ok"),
+			poison,
+			orderEventMessage(2, "order-retried"),
+			orderEventMessage(3, "order-exhausted"),
+			orderEventMessage(4, "order-unknown"),
+		},
+		cancel: cancel,
+	}
+	deadLetters := &recordingDeadLetterPublisher{failures: 1}
+	adapter := newOrderEventConsumerAdapter(reader, handler,
+		WithRetryPolicies(testRetryPolicies()),
+		WithDeadLetterPublisher(deadLetters),
+	)
+
+	adapter.Start(ctx)
+
+	assertCommittedUpTo(t, reader, 4)
+
+	expectedAttempts := map[string]int{"order-ok": 1, "order-retried": 3, "order-exhausted": 3, "order-unknown": 2}
+	for orderID, expected := range expectedAttempts {
+		if handler.attempts[orderID] != expected {
+			t.Errorf("Expected %d attempts for %s, got %d", expected, orderID, handler.attempts[orderID])
+		}
+	}
+
+	if len(deadLetters.deadLetters) != 3 {
+		t.Fatalf("Expected 3 dead letters, got %d", len(deadLetters.deadLetters))
+	}
+	expectedClasses := []ErrorClass{ErrorClassDecode, ErrorClassTransient, ErrorClassDomain}
+	for i, deadLetter := range deadLetters.deadLetters {
+		if deadLetter.ErrorClass != string(expectedClasses[i]) {
+			t.Errorf("Dead letter %d: expected %s error, got %s", i, expectedClasses[i], deadLetter.ErrorClass)
+		}
+		if deadLetter.Reason == "" {
+			t.Errorf("Dead letter %d: expected a failure reason", i)
+		}
+		if len(deadLetter.Headers) != 1 || deadLetter.Headers[0].Key != "source" {
+			t.Errorf("Dead letter %d: expected the original headers, got %+v", i, deadLetter.Headers)
+		}
+	}
+	if string(deadLetters.deadLetters[0].Payload) != `{not json` || deadLetters.deadLetters[0].Offset != 1 {
+		t.Errorf("Expected the poison message to be parked unchanged, got %+v", deadLetters.deadLetters[0])
+	}
+	if deadLetters.deadLetters[1].Attempts != 3 {
+		t.Errorf("Expected exhausted message to record 3 attempts, got %d", deadLetters.deadLetters[1].Attempts)
+	}
+}
+
+// assertCommittedUpTo checks that partition 0 was committed in increasing order up to the offset
+func assertCommittedUpTo(t *testing.T, reader *fakeOrderEventReader, offset int64) {
+	t.Helper()
+
+	if len(reader.committed) == 0 {
+		t.Fatal("Expected offsets to be committed")
+	}
+	for i := 1; i < len(reader.committed); i++ {
+		if reader.committed[i].Offset <= reader.committed[i-1].Offset {
+			t.Errorf("Expected commits to advance, got offset %d after %d", reader.committed[i].Offset, reader.committed[i-1].Offset)
+		}
+	}
+	if last := reader.committed[len(reader.committed)-1]; last.Offset != offset {
+		t.Errorf("Expected the watermark to reach offset %d, got %d", offset, last.Offset)
+	}
+}
+
+// sequenceOrderEventHandler records the event types handled per order, sleeping a
+// little to interleave workers
+type sequenceOrderEventHandler struct {
+	mutex     sync.Mutex
+	sequences map[string][]string
+}
+
+func (h *sequenceOrderEventHandler) HandleOrderEvent(event domain.OrderEvent) error {
+	time.Sleep(time.Duration(len(event.OrderID)%3) * time.Millisecond)
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.sequences[event.OrderID] = append(h.sequences[event.OrderID], event.EventType)
+	return nil
+}
+
+func TestOrderEventConsumerAdapter_KeepsEventOrderPerOrder(t *testing.T) {
+	eventTypes := []string{"order.created", "order.shipped", "order.delivered", "order.returned"}
+	orders := []string{"order-a", "order-bb", "order-ccc", "order-d", "order-ee", "order-fff"}
+
+	var messages []kafka.Message
+	for _, eventType := range eventTypes {
+		for _, orderID := range orders {
+			msg := orderEventMessage(int64(len(messages)), orderID)
+			msg.Value = []byte(`{"event_type":"` + eventType + `","order_id":"` + orderID + `"}`)
+			messages = append(messages, msg)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reader := &fakeOrderEventReader{messages: messages, cancel: cancel}
+	handler := &sequenceOrderEventHandler{sequences: make(map[string][]string)}
+	adapter := newOrderEventConsumerAdapter(reader, handler, WithConcurrency(4, 8))
+
+	adapter.Start(ctx)
+
+	for _, orderID := range orders {
+		sequence := handler.sequences[orderID]
+		if len(sequence) != len(eventTypes) {
+			t.Fatalf("Expected %d events for %s, got %v", len(eventTypes), orderID, sequence)
+		}
+		for i, eventType := range eventTypes {
+			if sequence[i] != eventType {
+				t.Errorf("Expected events of %s in order %v, got %v", orderID, eventTypes, sequence)
+				break
+			}
+		}
+	}
+	assertCommittedUpTo(t, reader, int64(len(messages)-1))
+}
+
+// countingEventCodec decodes JSON order events and counts how often it did
+type countingEventCodec struct {
+	domain.JSONEventCodec
+	mutex   sync.Mutex
+	decodes int
+}
+
+func (c *countingEventCodec) DecodeOrderEvent(data []byte) (domain.OrderEvent, error) {
+	c.mutex.Lock()
+	c.decodes++
+	c.mutex.Unlock()
+	return c.JSONEventCodec.DecodeOrderEvent(data)
+}
+
+func TestOrderEventConsumerAdapter_DecodesEachMessageOnce(t *testing.T) {
+	handler := newScriptedOrderEventHandler()
+	handler.failTimes("order-retried", 2, errors.New("database unavailable"))
+
+	poison := orderEventMessage(2, "order-poison")
+	poison.Value = []byte(`{not json`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reader := &fakeOrderEventReader{
+		messages: []kafka.Message{orderEventMessage(0, "order-ok"), orderEventMessage(1, "order-retried"), poison},
+		cancel:   cancel,
+	}
+	codec := &countingEventCodec{}
+	adapter := newOrderEventConsumerAdapter(reader, handler,
+		WithRetryPolicies(testRetryPolicies()),
+		WithEventCodecs(domain.EventCodecs{codec}),
+		WithConcurrency(2, 4),
+	)
+
+	adapter.Start(ctx)
+
+	assertCommittedUpTo(t, reader, 2)
+	if handler.attempts["order-retried"] != 3 {
+		t.Errorf("Expected 3 attempts for order-retried, got %d", handler.attempts["order-retried"])
+	}
+	if codec.decodes != 3 {
+		t.Errorf("Expected every message to be decoded once, got %d decodes for 3 messages", codec.decodes)
+	}
+}
+
+// blockingOrderEventHandler blocks every event until released
+type blockingOrderEventHandler struct {
+	release chan struct{}
+}
+
+func (h *blockingOrderEventHandler) HandleOrderEvent(event domain.OrderEvent) error {
+	<-h.release
+	return nil
+}
+
+func TestOrderEventConsumerAdapter_BoundsMessagesInFlight(t *testing.T) {
+	var messages []kafka.Message
+	for i := 0; i < 10; i++ {
+		messages = append(messages, orderEventMessage(int64(i), "order-"+string(rune('a'+i))))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reader := &fakeOrderEventReader{messages: messages, cancel: cancel}
+	handler := &blockingOrderEventHandler{release: make(chan struct{})}
+	adapter := newOrderEventConsumerAdapter(reader, handler, WithConcurrency(2, 3))
+
+	done := make(chan struct{})
+	go func() {
+		adapter.Start(ctx)
+		close(done)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	if fetched := reader.fetchedCount(); fetched != 3 {
+		t.Errorf("Expected fetching to pause at 3 messages in flight, got %d", fetched)
+	}
+
+	close(handler.release)
+	<-done
+	assertCommittedUpTo(t, reader, 9)
+}
+
+func TestOffsetTracker_AdvancesPastContiguousSettledMessages(t *testing.T) {
+	tracker := newOffsetTracker()
+	first := tracker.track(orderEventMessage(10, "order-1"))
+	second := tracker.track(orderEventMessage(11, "order-2"))
+	third := tracker.track(orderEventMessage(12, "order-3"))
+
+	// A later message settling first must not move the watermark past an unsettled one
+	tracker.settle(second)
+	if msgs := tracker.committable(); len(msgs) != 0 {
+		t.Fatalf("Expected nothing to commit while offset 10 is in flight, got %+v", msgs)
+	}
+
+	tracker.settle(first)
+	msgs := tracker.committable()
+	if len(msgs) != 1 || msgs[0].Offset != 11 {
+		t.Fatalf("Expected the watermark at offset 11, got %+v", msgs)
+	}
+
+	// Until the commit succeeds the watermark stays committable
+	if msgs := tracker.committable(); len(msgs) != 1 {
+		t.Fatalf("Expected uncommitted watermark to be returned again, got %+v", msgs)
+	}
+	tracker.committed(msgs)
+	if msgs := tracker.committable(); len(msgs) != 0 {
+		t.Fatalf("Expected nothing to commit after the commit, got %+v", msgs)
+	}
+
+	tracker.settle(third)
+	if msgs := tracker.committable(); len(msgs) != 1 || msgs[0].Offset != 12 {
+		t.Fatalf("Expected the watermark at offset 12, got %+v", msgs)
+	}
+}
+
+func TestOrderEventConsumerAdapter_DoesNotCommitWhenStoppedDuringRetry(t *testing.T) {
+	handler := newScriptedOrderEventHandler()
+	handler.failTimes("order-1", 100, errors.New("database unavailable"))
+
+	adapter := newOrderEventConsumerAdapter(&fakeOrderEventReader{}, handler, WithRetryPolicies(RetryPolicies{
+		ErrorClassTransient: {MaxAttempts: 100, InitialBackoff: time.Hour},
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	if err := adapter.processMessage(ctx, adapter.queueMessage(orderEventMessage(0, "order-1"))); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected processing to stop with the context, got %v", err)
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}
+
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second}
+	for i, want := range expected {
+		if got := policy.Backoff(i + 1); got != want {
+			t.Errorf("Attempt %d: expected backoff %s, got %s", i+1, want, got)
+		}
+	}
+
+	if !policy.ShouldRetry(4) || policy.ShouldRetry(5) {
+		t.Error("Expected retries to stop after MaxAttempts")
+	}
+}
+
+func TestOrderEventConsumerAdapter_TranslateMessageEventID(t *testing.T) {
+	adapter := &OrderEventConsumerAdapter{codecs: domain.EventCodecs{drivenadapters.ProtobufEventCodec{}}}
+	value := []byte(`{"event_type":"order.returned","order_id":"order-1"}`)
+	protobufValue, _ := proto.Marshal(&eventspb.OrderEvent{EventType: "order.returned", OrderId: "order-1"})
+
+	testCases := []struct {
+		name     string
+		msg      kafka.Message
+		expected string
+	}{
+		{
+			name: "event ID in payload",
+			msg: kafka.Message{
+				Topic: "order-events", Partition: 1, Offset: 7,
+				Value: []byte(`{"event_id":"evt-1","event_type":"order.returned","order_id":"order-1"}`),
+			},
+			expected: "evt-1",
+		},
+		{
+			name: "event ID header",
+			msg: kafka.Message{
+				Topic: "order-events", Partition: 1, Offset: 7, Value: value,
+				Headers: []kafka.Header{{Key: eventIDHeader, Value: []byte("evt-2")}},
+			},
+			expected: "evt-2",
+		},
+		{
+			name:     "kafka position",
+			msg:      kafka.Message{Topic: "order-events", Partition: 1, Offset: 7, Value: value},
+			expected: "order-events/1/7",
+		},
+		{
+			name: "binary-mode CloudEvent",
+			msg: kafka.Message{
+				Topic: "order-events", Partition: 1, Offset: 7, Value: value,
+				Headers: []kafka.Header{
+					{Key: "ce_specversion", Value: []byte("1.0")},
+					{Key: "ce_id", Value: []byte("evt-3")},
+					{Key: "ce_type", Value: []byte("order.returned")},
+					{Key: "ce_source", Value: []byte("/medisupply/orders")},
+				},
+			},
+			expected: "evt-3",
+		},
+		{
+			name: "structured-mode CloudEvent",
+			msg: kafka.Message{
+				Topic: "order-events", Partition: 1, Offset: 7,
+				Value: []byte(`{"specversion":"1.0","id":"evt-4","type":"order.returned","source":"/medisupply/orders","data":{"order_id":"order-1"}}`),
+			},
+			expected: "evt-4",
+		},
+		{
+			name: "binary-mode CloudEvent with Protobuf data",
+			msg: kafka.Message{
+				Topic: "order-events", Partition: 1, Offset: 7, Value: protobufValue,
+				Headers: []kafka.Header{
+					{Key: "content-type", Value: []byte(domain.ProtobufContentType)},
+					{Key: domain.SchemaVersionHeader, Value: []byte(domain.EventSchemaVersion)},
+					{Key: "ce_specversion", Value: []byte("1.0")},
+					{Key: "ce_id", Value: []byte("evt-5")},
+					{Key: "ce_type", Value: []byte("order.returned")},
+					{Key: "ce_source", Value: []byte("/medisupply/orders")},
+				},
+			},
+			expected: "evt-5",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			event, err := adapter.translateMessage(tc.msg)
+			if err != nil {
+				t.Fatalf("Failed to translate message: %v", err)
+			}
+			if event.EventID != tc.expected {
+				t.Errorf("Expected event ID %s, got %s", tc.expected, event.EventID)
+			}
+		})
+	}
+}
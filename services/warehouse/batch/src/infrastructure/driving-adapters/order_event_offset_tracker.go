@@ -0,0 +1,112 @@
+package drivingadapters
+
+import (
+	"sync"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// trackedMessage is a fetched message whose offset may only be committed once it and
+// every earlier message of its partition have been settled
+type trackedMessage struct {
+	msg       kafka.Message
+	partition *partitionOffsets
+	settled   bool
+}
+
+// partitionOffsets holds the in-flight messages of a partition in fetch order
+type partitionOffsets struct {
+	pending   []*trackedMessage
+	watermark *kafka.Message
+	committed *kafka.Message
+}
+
+// offsetTracker computes the commit watermark of each partition: the last message before
+// which every fetched message has been handled or dead-lettered. Messages are settled out
+// of order by the workers, but the watermark only advances past contiguous settled messages.
+type offsetTracker struct {
+	mutex      sync.Mutex
+	partitions map[int]*partitionOffsets
+	advanced   chan struct{}
+}
+
+// newOffsetTracker creates an empty offset tracker
+func newOffsetTracker() *offsetTracker {
+	return &offsetTracker{
+		partitions: make(map[int]*partitionOffsets),
+		advanced:   make(chan struct{}, 1),
+	}
+}
+
+// track registers a fetched message. Messages must be tracked in fetch order.
+func (t *offsetTracker) track(msg kafka.Message) *trackedMessage {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	partition, exists := t.partitions[msg.Partition]
+	if exists && len(partition.pending) > 0 && msg.Offset <= partition.pending[len(partition.pending)-1].msg.Offset {
+		// The reader rewound the partition after a rebalance: the earlier messages will be
+		// redelivered, so forget them and never commit on their behalf
+		exists = false
+	}
+	if !exists {
+		partition = &partitionOffsets{}
+		t.partitions[msg.Partition] = partition
+	}
+
+	tracked := &trackedMessage{msg: msg, partition: partition}
+	partition.pending = append(partition.pending, tracked)
+	return tracked
+}
+
+// settle marks a message as handled and advances the watermark of its partition
+func (t *offsetTracker) settle(tracked *trackedMessage) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	tracked.settled = true
+	partition := tracked.partition
+
+	advanced := false
+	for len(partition.pending) > 0 && partition.pending[0].settled {
+		msg := partition.pending[0].msg
+		partition.watermark = &msg
+		partition.pending = partition.pending[1:]
+		advanced = true
+	}
+
+	if advanced {
+		select {
+		case t.advanced <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// committable returns the watermark message of every partition that advanced since its
+// last successful commit. Committing a message commits all earlier offsets of its partition.
+func (t *offsetTracker) committable() []kafka.Message {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	var msgs []kafka.Message
+	for _, partition := range t.partitions {
+		if partition.watermark != partition.committed {
+			msgs = append(msgs, *partition.watermark)
+		}
+	}
+	return msgs
+}
+
+// committed records that the given messages were committed
+func (t *offsetTracker) committed(msgs []kafka.Message) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for _, msg := range msgs {
+		partition, exists := t.partitions[msg.Partition]
+		if exists && partition.watermark != nil && partition.watermark.Offset == msg.Offset {
+			partition.committed = partition.watermark
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package drivingadapters
+
+import (
+	"errors"
+	"time"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+)
+
+// ErrorClass groups order event failures that share a retry policy
+type ErrorClass string
+
+const (
+	// ErrorClassDecode is a message that is not a valid order event
+	ErrorClassDecode ErrorClass = "decode"
+
+	// ErrorClassDomain is an event rejected by the domain (validation, unknown order,
+	// invalid transition); it may succeed later if it arrived out of order
+	ErrorClassDomain ErrorClass = "domain"
+
+	// ErrorClassTransient is any other failure, e.g. the database being unavailable
+	ErrorClassTransient ErrorClass = "transient"
+)
+
+// errDecode marks failures to translate a Kafka message into an order event
+var errDecode = errors.New("failed to decode order event")
+
+// RetryPolicy defines how often and how fast a failed order event is retried before
+// it is sent to the dead-letter topic
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// RetryPolicies holds the retry policy of each error class
+type RetryPolicies map[ErrorClass]RetryPolicy
+
+// DefaultRetryPolicies returns the default retry policies: decode errors are never
+// retried, domain errors are retried briefly and transient errors for longer
+func DefaultRetryPolicies() RetryPolicies {
+	return RetryPolicies{
+		ErrorClassDecode:    {MaxAttempts: 1},
+		ErrorClassDomain:    {MaxAttempts: 3, InitialBackoff: 500 * time.Millisecond, MaxBackoff: 2 * time.Second},
+		ErrorClassTransient: {MaxAttempts: 10, InitialBackoff: time.Second, MaxBackoff: 30 * time.Second},
+	}
+}
+
+// Policy returns the retry policy of the error class, falling back to the default policy
+func (p RetryPolicies) Policy(class ErrorClass) RetryPolicy {
+	if policy, ok := p[class]; ok {
+		return policy
+	}
+	return DefaultRetryPolicies()[class]
+}
+
+// ShouldRetry reports whether another attempt is allowed after the given number of attempts
+func (p RetryPolicy) ShouldRetry(attempts int) bool {
+	return attempts < p.MaxAttempts
+}
+
+// Backoff returns the delay before the next attempt, doubling after every failure
+func (p RetryPolicy) Backoff(attempts int) time.Duration {
+	if p.InitialBackoff <= 0 {
+		return 0
+	}
+
+	backoff := p.InitialBackoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if p.MaxBackoff > 0 && backoff >= p.MaxBackoff {
+			return p.MaxBackoff
+		}
+	}
+	return backoff
+}
+
+// classifyError determines the error class of an order event failure
+func classifyError(err error) ErrorClass {
+	switch {
+	case errors.Is(err, errDecode):
+		return ErrorClassDecode
+	case errors.Is(err, domain.ErrValidation),
+		errors.Is(err, domain.ErrNotFound),
+		errors.Is(err, domain.ErrInvalidTransition):
+		return ErrorClassDomain
+	default:
+		return ErrorClassTransient
+	}
+}
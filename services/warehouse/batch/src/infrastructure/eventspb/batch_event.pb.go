@@ -0,0 +1,1270 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        (unknown)
+// source: medisupply/events/v1/batch_event.proto
+
+// Events about the batches of the warehouse, published by the batch service.
+
+package eventspb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// BatchEvent reports a change of a batch, e.g. batch.created or batch.item_added.
+// Item events carry the order ID and the item; recall events carry the recall notice.
+// The sequence orders the events of a batch. A delta event carries the delta from the
+// state of the batch at base_sequence instead of the batch.
+type BatchEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	EventType     string                 `protobuf:"bytes,1,opt,name=event_type,json=eventType,proto3" json:"event_type,omitempty"`
+	BatchId       string                 `protobuf:"bytes,2,opt,name=batch_id,json=batchId,proto3" json:"batch_id,omitempty"`
+	ProductId     string                 `protobuf:"bytes,3,opt,name=product_id,json=productId,proto3" json:"product_id,omitempty"`
+	Batch         *Batch                 `protobuf:"bytes,4,opt,name=batch,proto3" json:"batch,omitempty"`
+	OrderId       *string                `protobuf:"bytes,5,opt,name=order_id,json=orderId,proto3,oneof" json:"order_id,omitempty"`
+	ItemDetails   *BatchItem             `protobuf:"bytes,6,opt,name=item_details,json=itemDetails,proto3" json:"item_details,omitempty"`
+	Recall        *RecallNotice          `protobuf:"bytes,7,opt,name=recall,proto3" json:"recall,omitempty"`
+	Timestamp     *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Sequence      int64                  `protobuf:"varint,9,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	BaseSequence  int64                  `protobuf:"varint,10,opt,name=base_sequence,json=baseSequence,proto3" json:"base_sequence,omitempty"`
+	Delta         *BatchDelta            `protobuf:"bytes,11,opt,name=delta,proto3" json:"delta,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchEvent) Reset() {
+	*x = BatchEvent{}
+	mi := &file_medisupply_events_v1_batch_event_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchEvent) ProtoMessage() {}
+
+func (x *BatchEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_medisupply_events_v1_batch_event_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchEvent.ProtoReflect.Descriptor instead.
+func (*BatchEvent) Descriptor() ([]byte, []int) {
+	return file_medisupply_events_v1_batch_event_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *BatchEvent) GetEventType() string {
+	if x != nil {
+		return x.EventType
+	}
+	return ""
+}
+
+func (x *BatchEvent) GetBatchId() string {
+	if x != nil {
+		return x.BatchId
+	}
+	return ""
+}
+
+func (x *BatchEvent) GetProductId() string {
+	if x != nil {
+		return x.ProductId
+	}
+	return ""
+}
+
+func (x *BatchEvent) GetBatch() *Batch {
+	if x != nil {
+		return x.Batch
+	}
+	return nil
+}
+
+func (x *BatchEvent) GetOrderId() string {
+	if x != nil && x.OrderId != nil {
+		return *x.OrderId
+	}
+	return ""
+}
+
+func (x *BatchEvent) GetItemDetails() *BatchItem {
+	if x != nil {
+		return x.ItemDetails
+	}
+	return nil
+}
+
+func (x *BatchEvent) GetRecall() *RecallNotice {
+	if x != nil {
+		return x.Recall
+	}
+	return nil
+}
+
+func (x *BatchEvent) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *BatchEvent) GetSequence() int64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+func (x *BatchEvent) GetBaseSequence() int64 {
+	if x != nil {
+		return x.BaseSequence
+	}
+	return 0
+}
+
+func (x *BatchEvent) GetDelta() *BatchDelta {
+	if x != nil {
+		return x.Delta
+	}
+	return nil
+}
+
+// Batch is the state of a batch after the change reported by the event
+type Batch struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Id             string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	ProductId      string                 `protobuf:"bytes,2,opt,name=product_id,json=productId,proto3" json:"product_id,omitempty"`
+	LotNumber      string                 `protobuf:"bytes,3,opt,name=lot_number,json=lotNumber,proto3" json:"lot_number,omitempty"`
+	ManufacturedAt *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=manufactured_at,json=manufacturedAt,proto3" json:"manufactured_at,omitempty"`
+	ExpiresAt      *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	Status         string                 `protobuf:"bytes,6,opt,name=status,proto3" json:"status,omitempty"`
+	Items          []*BatchItem           `protobuf:"bytes,7,rep,name=items,proto3" json:"items,omitempty"`
+	TotalItems     int64                  `protobuf:"varint,8,opt,name=total_items,json=totalItems,proto3" json:"total_items,omitempty"`
+	CreatedAt      *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt      *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	ProcessedAt    *timestamppb.Timestamp `protobuf:"bytes,11,opt,name=processed_at,json=processedAt,proto3" json:"processed_at,omitempty"`
+	Inspection     *BatchInspection       `protobuf:"bytes,12,opt,name=inspection,proto3" json:"inspection,omitempty"`
+	Disposition    *QuarantineDisposition `protobuf:"bytes,13,opt,name=disposition,proto3" json:"disposition,omitempty"`
+	HoldReason     string                 `protobuf:"bytes,14,opt,name=hold_reason,json=holdReason,proto3" json:"hold_reason,omitempty"`
+	Version        int64                  `protobuf:"varint,15,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *Batch) Reset() {
+	*x = Batch{}
+	mi := &file_medisupply_events_v1_batch_event_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Batch) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Batch) ProtoMessage() {}
+
+func (x *Batch) ProtoReflect() protoreflect.Message {
+	mi := &file_medisupply_events_v1_batch_event_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Batch.ProtoReflect.Descriptor instead.
+func (*Batch) Descriptor() ([]byte, []int) {
+	return file_medisupply_events_v1_batch_event_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Batch) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Batch) GetProductId() string {
+	if x != nil {
+		return x.ProductId
+	}
+	return ""
+}
+
+func (x *Batch) GetLotNumber() string {
+	if x != nil {
+		return x.LotNumber
+	}
+	return ""
+}
+
+func (x *Batch) GetManufacturedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ManufacturedAt
+	}
+	return nil
+}
+
+func (x *Batch) GetExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return nil
+}
+
+func (x *Batch) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Batch) GetItems() []*BatchItem {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+func (x *Batch) GetTotalItems() int64 {
+	if x != nil {
+		return x.TotalItems
+	}
+	return 0
+}
+
+func (x *Batch) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Batch) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+func (x *Batch) GetProcessedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ProcessedAt
+	}
+	return nil
+}
+
+func (x *Batch) GetInspection() *BatchInspection {
+	if x != nil {
+		return x.Inspection
+	}
+	return nil
+}
+
+func (x *Batch) GetDisposition() *QuarantineDisposition {
+	if x != nil {
+		return x.Disposition
+	}
+	return nil
+}
+
+func (x *Batch) GetHoldReason() string {
+	if x != nil {
+		return x.HoldReason
+	}
+	return ""
+}
+
+func (x *Batch) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+// BatchDelta is the change of a batch since the state of a previous event, with the
+// old and new values of everything it changed
+type BatchDelta struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Status        *BatchStatusChange     `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	ItemsAdded    []*BatchItem           `protobuf:"bytes,2,rep,name=items_added,json=itemsAdded,proto3" json:"items_added,omitempty"`
+	ItemsUpdated  []*BatchItemChange     `protobuf:"bytes,3,rep,name=items_updated,json=itemsUpdated,proto3" json:"items_updated,omitempty"`
+	ItemsRemoved  []*BatchItem           `protobuf:"bytes,4,rep,name=items_removed,json=itemsRemoved,proto3" json:"items_removed,omitempty"`
+	Attributes    *BatchAttributesChange `protobuf:"bytes,5,opt,name=attributes,proto3" json:"attributes,omitempty"`
+	UpdatedAt     *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	Version       int64                  `protobuf:"varint,7,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchDelta) Reset() {
+	*x = BatchDelta{}
+	mi := &file_medisupply_events_v1_batch_event_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchDelta) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchDelta) ProtoMessage() {}
+
+func (x *BatchDelta) ProtoReflect() protoreflect.Message {
+	mi := &file_medisupply_events_v1_batch_event_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchDelta.ProtoReflect.Descriptor instead.
+func (*BatchDelta) Descriptor() ([]byte, []int) {
+	return file_medisupply_events_v1_batch_event_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *BatchDelta) GetStatus() *BatchStatusChange {
+	if x != nil {
+		return x.Status
+	}
+	return nil
+}
+
+func (x *BatchDelta) GetItemsAdded() []*BatchItem {
+	if x != nil {
+		return x.ItemsAdded
+	}
+	return nil
+}
+
+func (x *BatchDelta) GetItemsUpdated() []*BatchItemChange {
+	if x != nil {
+		return x.ItemsUpdated
+	}
+	return nil
+}
+
+func (x *BatchDelta) GetItemsRemoved() []*BatchItem {
+	if x != nil {
+		return x.ItemsRemoved
+	}
+	return nil
+}
+
+func (x *BatchDelta) GetAttributes() *BatchAttributesChange {
+	if x != nil {
+		return x.Attributes
+	}
+	return nil
+}
+
+func (x *BatchDelta) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+func (x *BatchDelta) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+// BatchStatusChange is a status transition of a batch
+type BatchStatusChange struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Old           string                 `protobuf:"bytes,1,opt,name=old,proto3" json:"old,omitempty"`
+	New           string                 `protobuf:"bytes,2,opt,name=new,proto3" json:"new,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchStatusChange) Reset() {
+	*x = BatchStatusChange{}
+	mi := &file_medisupply_events_v1_batch_event_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchStatusChange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchStatusChange) ProtoMessage() {}
+
+func (x *BatchStatusChange) ProtoReflect() protoreflect.Message {
+	mi := &file_medisupply_events_v1_batch_event_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchStatusChange.ProtoReflect.Descriptor instead.
+func (*BatchStatusChange) Descriptor() ([]byte, []int) {
+	return file_medisupply_events_v1_batch_event_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *BatchStatusChange) GetOld() string {
+	if x != nil {
+		return x.Old
+	}
+	return ""
+}
+
+func (x *BatchStatusChange) GetNew() string {
+	if x != nil {
+		return x.New
+	}
+	return ""
+}
+
+// BatchItemChange is the old and new value of an updated batch item
+type BatchItemChange struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Old           *BatchItem             `protobuf:"bytes,1,opt,name=old,proto3" json:"old,omitempty"`
+	New           *BatchItem             `protobuf:"bytes,2,opt,name=new,proto3" json:"new,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchItemChange) Reset() {
+	*x = BatchItemChange{}
+	mi := &file_medisupply_events_v1_batch_event_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchItemChange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchItemChange) ProtoMessage() {}
+
+func (x *BatchItemChange) ProtoReflect() protoreflect.Message {
+	mi := &file_medisupply_events_v1_batch_event_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchItemChange.ProtoReflect.Descriptor instead.
+func (*BatchItemChange) Descriptor() ([]byte, []int) {
+	return file_medisupply_events_v1_batch_event_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *BatchItemChange) GetOld() *BatchItem {
+	if x != nil {
+		return x.Old
+	}
+	return nil
+}
+
+func (x *BatchItemChange) GetNew() *BatchItem {
+	if x != nil {
+		return x.New
+	}
+	return nil
+}
+
+// BatchAttributes are the attributes of a batch besides its status and items
+type BatchAttributes struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	LotNumber      string                 `protobuf:"bytes,1,opt,name=lot_number,json=lotNumber,proto3" json:"lot_number,omitempty"`
+	ManufacturedAt *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=manufactured_at,json=manufacturedAt,proto3" json:"manufactured_at,omitempty"`
+	ExpiresAt      *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	ProcessedAt    *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=processed_at,json=processedAt,proto3" json:"processed_at,omitempty"`
+	Inspection     *BatchInspection       `protobuf:"bytes,5,opt,name=inspection,proto3" json:"inspection,omitempty"`
+	Disposition    *QuarantineDisposition `protobuf:"bytes,6,opt,name=disposition,proto3" json:"disposition,omitempty"`
+	HoldReason     string                 `protobuf:"bytes,7,opt,name=hold_reason,json=holdReason,proto3" json:"hold_reason,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *BatchAttributes) Reset() {
+	*x = BatchAttributes{}
+	mi := &file_medisupply_events_v1_batch_event_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchAttributes) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchAttributes) ProtoMessage() {}
+
+func (x *BatchAttributes) ProtoReflect() protoreflect.Message {
+	mi := &file_medisupply_events_v1_batch_event_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchAttributes.ProtoReflect.Descriptor instead.
+func (*BatchAttributes) Descriptor() ([]byte, []int) {
+	return file_medisupply_events_v1_batch_event_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *BatchAttributes) GetLotNumber() string {
+	if x != nil {
+		return x.LotNumber
+	}
+	return ""
+}
+
+func (x *BatchAttributes) GetManufacturedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ManufacturedAt
+	}
+	return nil
+}
+
+func (x *BatchAttributes) GetExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return nil
+}
+
+func (x *BatchAttributes) GetProcessedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ProcessedAt
+	}
+	return nil
+}
+
+func (x *BatchAttributes) GetInspection() *BatchInspection {
+	if x != nil {
+		return x.Inspection
+	}
+	return nil
+}
+
+func (x *BatchAttributes) GetDisposition() *QuarantineDisposition {
+	if x != nil {
+		return x.Disposition
+	}
+	return nil
+}
+
+func (x *BatchAttributes) GetHoldReason() string {
+	if x != nil {
+		return x.HoldReason
+	}
+	return ""
+}
+
+// BatchAttributesChange is the old and new value of the attributes of a batch
+type BatchAttributesChange struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Old           *BatchAttributes       `protobuf:"bytes,1,opt,name=old,proto3" json:"old,omitempty"`
+	New           *BatchAttributes       `protobuf:"bytes,2,opt,name=new,proto3" json:"new,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchAttributesChange) Reset() {
+	*x = BatchAttributesChange{}
+	mi := &file_medisupply_events_v1_batch_event_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchAttributesChange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchAttributesChange) ProtoMessage() {}
+
+func (x *BatchAttributesChange) ProtoReflect() protoreflect.Message {
+	mi := &file_medisupply_events_v1_batch_event_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchAttributesChange.ProtoReflect.Descriptor instead.
+func (*BatchAttributesChange) Descriptor() ([]byte, []int) {
+	return file_medisupply_events_v1_batch_event_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *BatchAttributesChange) GetOld() *BatchAttributes {
+	if x != nil {
+		return x.Old
+	}
+	return nil
+}
+
+func (x *BatchAttributesChange) GetNew() *BatchAttributes {
+	if x != nil {
+		return x.New
+	}
+	return nil
+}
+
+// BatchItem is the order allocated to a batch
+type BatchItem struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OrderId       string                 `protobuf:"bytes,1,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	CustomerId    string                 `protobuf:"bytes,2,opt,name=customer_id,json=customerId,proto3" json:"customer_id,omitempty"`
+	ProductId     string                 `protobuf:"bytes,3,opt,name=product_id,json=productId,proto3" json:"product_id,omitempty"`
+	Quantity      int64                  `protobuf:"varint,4,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	Status        string                 `protobuf:"bytes,5,opt,name=status,proto3" json:"status,omitempty"`
+	LotNumber     string                 `protobuf:"bytes,6,opt,name=lot_number,json=lotNumber,proto3" json:"lot_number,omitempty"`
+	AddedAt       *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=added_at,json=addedAt,proto3" json:"added_at,omitempty"`
+	ProcessedAt   *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=processed_at,json=processedAt,proto3" json:"processed_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchItem) Reset() {
+	*x = BatchItem{}
+	mi := &file_medisupply_events_v1_batch_event_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchItem) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchItem) ProtoMessage() {}
+
+func (x *BatchItem) ProtoReflect() protoreflect.Message {
+	mi := &file_medisupply_events_v1_batch_event_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchItem.ProtoReflect.Descriptor instead.
+func (*BatchItem) Descriptor() ([]byte, []int) {
+	return file_medisupply_events_v1_batch_event_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *BatchItem) GetOrderId() string {
+	if x != nil {
+		return x.OrderId
+	}
+	return ""
+}
+
+func (x *BatchItem) GetCustomerId() string {
+	if x != nil {
+		return x.CustomerId
+	}
+	return ""
+}
+
+func (x *BatchItem) GetProductId() string {
+	if x != nil {
+		return x.ProductId
+	}
+	return ""
+}
+
+func (x *BatchItem) GetQuantity() int64 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+func (x *BatchItem) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *BatchItem) GetLotNumber() string {
+	if x != nil {
+		return x.LotNumber
+	}
+	return ""
+}
+
+func (x *BatchItem) GetAddedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.AddedAt
+	}
+	return nil
+}
+
+func (x *BatchItem) GetProcessedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ProcessedAt
+	}
+	return nil
+}
+
+// BatchInspection records the findings of the inspection of a quarantined batch
+type BatchInspection struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Inspector     string                 `protobuf:"bytes,1,opt,name=inspector,proto3" json:"inspector,omitempty"`
+	Findings      string                 `protobuf:"bytes,2,opt,name=findings,proto3" json:"findings,omitempty"`
+	InspectedAt   *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=inspected_at,json=inspectedAt,proto3" json:"inspected_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchInspection) Reset() {
+	*x = BatchInspection{}
+	mi := &file_medisupply_events_v1_batch_event_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchInspection) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchInspection) ProtoMessage() {}
+
+func (x *BatchInspection) ProtoReflect() protoreflect.Message {
+	mi := &file_medisupply_events_v1_batch_event_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchInspection.ProtoReflect.Descriptor instead.
+func (*BatchInspection) Descriptor() ([]byte, []int) {
+	return file_medisupply_events_v1_batch_event_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *BatchInspection) GetInspector() string {
+	if x != nil {
+		return x.Inspector
+	}
+	return ""
+}
+
+func (x *BatchInspection) GetFindings() string {
+	if x != nil {
+		return x.Findings
+	}
+	return ""
+}
+
+func (x *BatchInspection) GetInspectedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.InspectedAt
+	}
+	return nil
+}
+
+// QuarantineDisposition records the decision that ended the quarantine of a batch
+type QuarantineDisposition struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Outcome       string                 `protobuf:"bytes,1,opt,name=outcome,proto3" json:"outcome,omitempty"`
+	DecidedBy     string                 `protobuf:"bytes,2,opt,name=decided_by,json=decidedBy,proto3" json:"decided_by,omitempty"`
+	Reason        string                 `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`
+	DecidedAt     *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=decided_at,json=decidedAt,proto3" json:"decided_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QuarantineDisposition) Reset() {
+	*x = QuarantineDisposition{}
+	mi := &file_medisupply_events_v1_batch_event_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QuarantineDisposition) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QuarantineDisposition) ProtoMessage() {}
+
+func (x *QuarantineDisposition) ProtoReflect() protoreflect.Message {
+	mi := &file_medisupply_events_v1_batch_event_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QuarantineDisposition.ProtoReflect.Descriptor instead.
+func (*QuarantineDisposition) Descriptor() ([]byte, []int) {
+	return file_medisupply_events_v1_batch_event_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *QuarantineDisposition) GetOutcome() string {
+	if x != nil {
+		return x.Outcome
+	}
+	return ""
+}
+
+func (x *QuarantineDisposition) GetDecidedBy() string {
+	if x != nil {
+		return x.DecidedBy
+	}
+	return ""
+}
+
+func (x *QuarantineDisposition) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *QuarantineDisposition) GetDecidedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.DecidedAt
+	}
+	return nil
+}
+
+// RecallNotice tells downstream services which orders and customers of a batch are
+// affected by a recall
+type RecallNotice struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	RecallId       string                 `protobuf:"bytes,1,opt,name=recall_id,json=recallId,proto3" json:"recall_id,omitempty"`
+	LotNumber      string                 `protobuf:"bytes,2,opt,name=lot_number,json=lotNumber,proto3" json:"lot_number,omitempty"`
+	Reason         string                 `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`
+	AffectedOrders []*RecallOrder         `protobuf:"bytes,4,rep,name=affected_orders,json=affectedOrders,proto3" json:"affected_orders,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *RecallNotice) Reset() {
+	*x = RecallNotice{}
+	mi := &file_medisupply_events_v1_batch_event_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RecallNotice) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RecallNotice) ProtoMessage() {}
+
+func (x *RecallNotice) ProtoReflect() protoreflect.Message {
+	mi := &file_medisupply_events_v1_batch_event_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RecallNotice.ProtoReflect.Descriptor instead.
+func (*RecallNotice) Descriptor() ([]byte, []int) {
+	return file_medisupply_events_v1_batch_event_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *RecallNotice) GetRecallId() string {
+	if x != nil {
+		return x.RecallId
+	}
+	return ""
+}
+
+func (x *RecallNotice) GetLotNumber() string {
+	if x != nil {
+		return x.LotNumber
+	}
+	return ""
+}
+
+func (x *RecallNotice) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *RecallNotice) GetAffectedOrders() []*RecallOrder {
+	if x != nil {
+		return x.AffectedOrders
+	}
+	return nil
+}
+
+// RecallOrder is an order affected by a recall
+type RecallOrder struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OrderId       string                 `protobuf:"bytes,1,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	CustomerId    string                 `protobuf:"bytes,2,opt,name=customer_id,json=customerId,proto3" json:"customer_id,omitempty"`
+	BatchId       string                 `protobuf:"bytes,3,opt,name=batch_id,json=batchId,proto3" json:"batch_id,omitempty"`
+	LotNumber     string                 `protobuf:"bytes,4,opt,name=lot_number,json=lotNumber,proto3" json:"lot_number,omitempty"`
+	Quantity      int64                  `protobuf:"varint,5,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	ItemStatus    string                 `protobuf:"bytes,6,opt,name=item_status,json=itemStatus,proto3" json:"item_status,omitempty"`
+	ResolvedAt    *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=resolved_at,json=resolvedAt,proto3" json:"resolved_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RecallOrder) Reset() {
+	*x = RecallOrder{}
+	mi := &file_medisupply_events_v1_batch_event_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RecallOrder) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RecallOrder) ProtoMessage() {}
+
+func (x *RecallOrder) ProtoReflect() protoreflect.Message {
+	mi := &file_medisupply_events_v1_batch_event_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RecallOrder.ProtoReflect.Descriptor instead.
+func (*RecallOrder) Descriptor() ([]byte, []int) {
+	return file_medisupply_events_v1_batch_event_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *RecallOrder) GetOrderId() string {
+	if x != nil {
+		return x.OrderId
+	}
+	return ""
+}
+
+func (x *RecallOrder) GetCustomerId() string {
+	if x != nil {
+		return x.CustomerId
+	}
+	return ""
+}
+
+func (x *RecallOrder) GetBatchId() string {
+	if x != nil {
+		return x.BatchId
+	}
+	return ""
+}
+
+func (x *RecallOrder) GetLotNumber() string {
+	if x != nil {
+		return x.LotNumber
+	}
+	return ""
+}
+
+func (x *RecallOrder) GetQuantity() int64 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+func (x *RecallOrder) GetItemStatus() string {
+	if x != nil {
+		return x.ItemStatus
+	}
+	return ""
+}
+
+func (x *RecallOrder) GetResolvedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ResolvedAt
+	}
+	return nil
+}
+
+var File_medisupply_events_v1_batch_event_proto protoreflect.FileDescriptor
+
+const file_medisupply_events_v1_batch_event_proto_rawDesc = "" +
+	"\n" +
+	"&medisupply/events/v1/batch_event.proto\x12\x14medisupply.events.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xf8\x03\n" +
+	"\n" +
+	"BatchEvent\x12\x1d\n" +
+	"\n" +
+	"event_type\x18\x01 \x01(\tR\teventType\x12\x19\n" +
+	"\bbatch_id\x18\x02 \x01(\tR\abatchId\x12\x1d\n" +
+	"\n" +
+	"product_id\x18\x03 \x01(\tR\tproductId\x121\n" +
+	"\x05batch\x18\x04 \x01(\v2\x1b.medisupply.events.v1.BatchR\x05batch\x12\x1e\n" +
+	"\border_id\x18\x05 \x01(\tH\x00R\aorderId\x88\x01\x01\x12B\n" +
+	"\fitem_details\x18\x06 \x01(\v2\x1f.medisupply.events.v1.BatchItemR\vitemDetails\x12:\n" +
+	"\x06recall\x18\a \x01(\v2\".medisupply.events.v1.RecallNoticeR\x06recall\x128\n" +
+	"\ttimestamp\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x12\x1a\n" +
+	"\bsequence\x18\t \x01(\x03R\bsequence\x12#\n" +
+	"\rbase_sequence\x18\n" +
+	" \x01(\x03R\fbaseSequence\x126\n" +
+	"\x05delta\x18\v \x01(\v2 .medisupply.events.v1.BatchDeltaR\x05deltaB\v\n" +
+	"\t_order_id\"\xcb\x05\n" +
+	"\x05Batch\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1d\n" +
+	"\n" +
+	"product_id\x18\x02 \x01(\tR\tproductId\x12\x1d\n" +
+	"\n" +
+	"lot_number\x18\x03 \x01(\tR\tlotNumber\x12C\n" +
+	"\x0fmanufactured_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\x0emanufacturedAt\x129\n" +
+	"\n" +
+	"expires_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\texpiresAt\x12\x16\n" +
+	"\x06status\x18\x06 \x01(\tR\x06status\x125\n" +
+	"\x05items\x18\a \x03(\v2\x1f.medisupply.events.v1.BatchItemR\x05items\x12\x1f\n" +
+	"\vtotal_items\x18\b \x01(\x03R\n" +
+	"totalItems\x129\n" +
+	"\n" +
+	"created_at\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\x12=\n" +
+	"\fprocessed_at\x18\v \x01(\v2\x1a.google.protobuf.TimestampR\vprocessedAt\x12E\n" +
+	"\n" +
+	"inspection\x18\f \x01(\v2%.medisupply.events.v1.BatchInspectionR\n" +
+	"inspection\x12M\n" +
+	"\vdisposition\x18\r \x01(\v2+.medisupply.events.v1.QuarantineDispositionR\vdisposition\x12\x1f\n" +
+	"\vhold_reason\x18\x0e \x01(\tR\n" +
+	"holdReason\x12\x18\n" +
+	"\aversion\x18\x0f \x01(\x03R\aversion\"\xc3\x03\n" +
+	"\n" +
+	"BatchDelta\x12?\n" +
+	"\x06status\x18\x01 \x01(\v2'.medisupply.events.v1.BatchStatusChangeR\x06status\x12@\n" +
+	"\vitems_added\x18\x02 \x03(\v2\x1f.medisupply.events.v1.BatchItemR\n" +
+	"itemsAdded\x12J\n" +
+	"\ritems_updated\x18\x03 \x03(\v2%.medisupply.events.v1.BatchItemChangeR\fitemsUpdated\x12D\n" +
+	"\ritems_removed\x18\x04 \x03(\v2\x1f.medisupply.events.v1.BatchItemR\fitemsRemoved\x12K\n" +
+	"\n" +
+	"attributes\x18\x05 \x01(\v2+.medisupply.events.v1.BatchAttributesChangeR\n" +
+	"attributes\x129\n" +
+	"\n" +
+	"updated_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\x12\x18\n" +
+	"\aversion\x18\a \x01(\x03R\aversion\"7\n" +
+	"\x11BatchStatusChange\x12\x10\n" +
+	"\x03old\x18\x01 \x01(\tR\x03old\x12\x10\n" +
+	"\x03new\x18\x02 \x01(\tR\x03new\"w\n" +
+	"\x0fBatchItemChange\x121\n" +
+	"\x03old\x18\x01 \x01(\v2\x1f.medisupply.events.v1.BatchItemR\x03old\x121\n" +
+	"\x03new\x18\x02 \x01(\v2\x1f.medisupply.events.v1.BatchItemR\x03new\"\xa6\x03\n" +
+	"\x0fBatchAttributes\x12\x1d\n" +
+	"\n" +
+	"lot_number\x18\x01 \x01(\tR\tlotNumber\x12C\n" +
+	"\x0fmanufactured_at\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x0emanufacturedAt\x129\n" +
+	"\n" +
+	"expires_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\texpiresAt\x12=\n" +
+	"\fprocessed_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\vprocessedAt\x12E\n" +
+	"\n" +
+	"inspection\x18\x05 \x01(\v2%.medisupply.events.v1.BatchInspectionR\n" +
+	"inspection\x12M\n" +
+	"\vdisposition\x18\x06 \x01(\v2+.medisupply.events.v1.QuarantineDispositionR\vdisposition\x12\x1f\n" +
+	"\vhold_reason\x18\a \x01(\tR\n" +
+	"holdReason\"\x89\x01\n" +
+	"\x15BatchAttributesChange\x127\n" +
+	"\x03old\x18\x01 \x01(\v2%.medisupply.events.v1.BatchAttributesR\x03old\x127\n" +
+	"\x03new\x18\x02 \x01(\v2%.medisupply.events.v1.BatchAttributesR\x03new\"\xaf\x02\n" +
+	"\tBatchItem\x12\x19\n" +
+	"\border_id\x18\x01 \x01(\tR\aorderId\x12\x1f\n" +
+	"\vcustomer_id\x18\x02 \x01(\tR\n" +
+	"customerId\x12\x1d\n" +
+	"\n" +
+	"product_id\x18\x03 \x01(\tR\tproductId\x12\x1a\n" +
+	"\bquantity\x18\x04 \x01(\x03R\bquantity\x12\x16\n" +
+	"\x06status\x18\x05 \x01(\tR\x06status\x12\x1d\n" +
+	"\n" +
+	"lot_number\x18\x06 \x01(\tR\tlotNumber\x125\n" +
+	"\badded_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\aaddedAt\x12=\n" +
+	"\fprocessed_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\vprocessedAt\"\x8a\x01\n" +
+	"\x0fBatchInspection\x12\x1c\n" +
+	"\tinspector\x18\x01 \x01(\tR\tinspector\x12\x1a\n" +
+	"\bfindings\x18\x02 \x01(\tR\bfindings\x12=\n" +
+	"\finspected_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\vinspectedAt\"\xa3\x01\n" +
+	"\x15QuarantineDisposition\x12\x18\n" +
+	"\aoutcome\x18\x01 \x01(\tR\aoutcome\x12\x1d\n" +
+	"\n" +
+	"decided_by\x18\x02 \x01(\tR\tdecidedBy\x12\x16\n" +
+	"\x06reason\x18\x03 \x01(\tR\x06reason\x129\n" +
+	"\n" +
+	"decided_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\tdecidedAt\"\xae\x01\n" +
+	"\fRecallNotice\x12\x1b\n" +
+	"\trecall_id\x18\x01 \x01(\tR\brecallId\x12\x1d\n" +
+	"\n" +
+	"lot_number\x18\x02 \x01(\tR\tlotNumber\x12\x16\n" +
+	"\x06reason\x18\x03 \x01(\tR\x06reason\x12J\n" +
+	"\x0faffected_orders\x18\x04 \x03(\v2!.medisupply.events.v1.RecallOrderR\x0eaffectedOrders\"\xfd\x01\n" +
+	"\vRecallOrder\x12\x19\n" +
+	"\border_id\x18\x01 \x01(\tR\aorderId\x12\x1f\n" +
+	"\vcustomer_id\x18\x02 \x01(\tR\n" +
+	"customerId\x12\x19\n" +
+	"\bbatch_id\x18\x03 \x01(\tR\abatchId\x12\x1d\n" +
+	"\n" +
+	"lot_number\x18\x04 \x01(\tR\tlotNumber\x12\x1a\n" +
+	"\bquantity\x18\x05 \x01(\x03R\bquantity\x12\x1f\n" +
+	"\vitem_status\x18\x06 \x01(\tR\n" +
+	"itemStatus\x12;\n" +
+	"\vresolved_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"resolvedAtb\x06proto3"
+
+var (
+	file_medisupply_events_v1_batch_event_proto_rawDescOnce sync.Once
+	file_medisupply_events_v1_batch_event_proto_rawDescData []byte
+)
+
+func file_medisupply_events_v1_batch_event_proto_rawDescGZIP() []byte {
+	file_medisupply_events_v1_batch_event_proto_rawDescOnce.Do(func() {
+		file_medisupply_events_v1_batch_event_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_medisupply_events_v1_batch_event_proto_rawDesc), len(file_medisupply_events_v1_batch_event_proto_rawDesc)))
+	})
+	return file_medisupply_events_v1_batch_event_proto_rawDescData
+}
+
+var file_medisupply_events_v1_batch_event_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
+var file_medisupply_events_v1_batch_event_proto_goTypes = []any{
+	(*BatchEvent)(nil),            // 0: medisupply.events.v1.BatchEvent
+	(*Batch)(nil),                 // 1: medisupply.events.v1.Batch
+	(*BatchDelta)(nil),            // 2: medisupply.events.v1.BatchDelta
+	(*BatchStatusChange)(nil),     // 3: medisupply.events.v1.BatchStatusChange
+	(*BatchItemChange)(nil),       // 4: medisupply.events.v1.BatchItemChange
+	(*BatchAttributes)(nil),       // 5: medisupply.events.v1.BatchAttributes
+	(*BatchAttributesChange)(nil), // 6: medisupply.events.v1.BatchAttributesChange
+	(*BatchItem)(nil),             // 7: medisupply.events.v1.BatchItem
+	(*BatchInspection)(nil),       // 8: medisupply.events.v1.BatchInspection
+	(*QuarantineDisposition)(nil), // 9: medisupply.events.v1.QuarantineDisposition
+	(*RecallNotice)(nil),          // 10: medisupply.events.v1.RecallNotice
+	(*RecallOrder)(nil),           // 11: medisupply.events.v1.RecallOrder
+	(*timestamppb.Timestamp)(nil), // 12: google.protobuf.Timestamp
+}
+var file_medisupply_events_v1_batch_event_proto_depIdxs = []int32{
+	1,  // 0: medisupply.events.v1.BatchEvent.batch:type_name -> medisupply.events.v1.Batch
+	7,  // 1: medisupply.events.v1.BatchEvent.item_details:type_name -> medisupply.events.v1.BatchItem
+	10, // 2: medisupply.events.v1.BatchEvent.recall:type_name -> medisupply.events.v1.RecallNotice
+	12, // 3: medisupply.events.v1.BatchEvent.timestamp:type_name -> google.protobuf.Timestamp
+	2,  // 4: medisupply.events.v1.BatchEvent.delta:type_name -> medisupply.events.v1.BatchDelta
+	12, // 5: medisupply.events.v1.Batch.manufactured_at:type_name -> google.protobuf.Timestamp
+	12, // 6: medisupply.events.v1.Batch.expires_at:type_name -> google.protobuf.Timestamp
+	7,  // 7: medisupply.events.v1.Batch.items:type_name -> medisupply.events.v1.BatchItem
+	12, // 8: medisupply.events.v1.Batch.created_at:type_name -> google.protobuf.Timestamp
+	12, // 9: medisupply.events.v1.Batch.updated_at:type_name -> google.protobuf.Timestamp
+	12, // 10: medisupply.events.v1.Batch.processed_at:type_name -> google.protobuf.Timestamp
+	8,  // 11: medisupply.events.v1.Batch.inspection:type_name -> medisupply.events.v1.BatchInspection
+	9,  // 12: medisupply.events.v1.Batch.disposition:type_name -> medisupply.events.v1.QuarantineDisposition
+	3,  // 13: medisupply.events.v1.BatchDelta.status:type_name -> medisupply.events.v1.BatchStatusChange
+	7,  // 14: medisupply.events.v1.BatchDelta.items_added:type_name -> medisupply.events.v1.BatchItem
+	4,  // 15: medisupply.events.v1.BatchDelta.items_updated:type_name -> medisupply.events.v1.BatchItemChange
+	7,  // 16: medisupply.events.v1.BatchDelta.items_removed:type_name -> medisupply.events.v1.BatchItem
+	6,  // 17: medisupply.events.v1.BatchDelta.attributes:type_name -> medisupply.events.v1.BatchAttributesChange
+	12, // 18: medisupply.events.v1.BatchDelta.updated_at:type_name -> google.protobuf.Timestamp
+	7,  // 19: medisupply.events.v1.BatchItemChange.old:type_name -> medisupply.events.v1.BatchItem
+	7,  // 20: medisupply.events.v1.BatchItemChange.new:type_name -> medisupply.events.v1.BatchItem
+	12, // 21: medisupply.events.v1.BatchAttributes.manufactured_at:type_name -> google.protobuf.Timestamp
+	12, // 22: medisupply.events.v1.BatchAttributes.expires_at:type_name -> google.protobuf.Timestamp
+	12, // 23: medisupply.events.v1.BatchAttributes.processed_at:type_name -> google.protobuf.Timestamp
+	8,  // 24: medisupply.events.v1.BatchAttributes.inspection:type_name -> medisupply.events.v1.BatchInspection
+	9,  // 25: medisupply.events.v1.BatchAttributes.disposition:type_name -> medisupply.events.v1.QuarantineDisposition
+	5,  // 26: medisupply.events.v1.BatchAttributesChange.old:type_name -> medisupply.events.v1.BatchAttributes
+	5,  // 27: medisupply.events.v1.BatchAttributesChange.new:type_name -> medisupply.events.v1.BatchAttributes
+	12, // 28: medisupply.events.v1.BatchItem.added_at:type_name -> google.protobuf.Timestamp
+	12, // 29: medisupply.events.v1.BatchItem.processed_at:type_name -> google.protobuf.Timestamp
+	12, // 30: medisupply.events.v1.BatchInspection.inspected_at:type_name -> google.protobuf.Timestamp
+	12, // 31: medisupply.events.v1.QuarantineDisposition.decided_at:type_name -> google.protobuf.Timestamp
+	11, // 32: medisupply.events.v1.RecallNotice.affected_orders:type_name -> medisupply.events.v1.RecallOrder
+	12, // 33: medisupply.events.v1.RecallOrder.resolved_at:type_name -> google.protobuf.Timestamp
+	34, // [34:34] is the sub-list for method output_type
+	34, // [34:34] is the sub-list for method input_type
+	34, // [34:34] is the sub-list for extension type_name
+	34, // [34:34] is the sub-list for extension extendee
+	0,  // [0:34] is the sub-list for field type_name
+}
+
+func init() { file_medisupply_events_v1_batch_event_proto_init() }
+func file_medisupply_events_v1_batch_event_proto_init() {
+	if File_medisupply_events_v1_batch_event_proto != nil {
+		return
+	}
+	file_medisupply_events_v1_batch_event_proto_msgTypes[0].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_medisupply_events_v1_batch_event_proto_rawDesc), len(file_medisupply_events_v1_batch_event_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   12,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_medisupply_events_v1_batch_event_proto_goTypes,
+		DependencyIndexes: file_medisupply_events_v1_batch_event_proto_depIdxs,
+		MessageInfos:      file_medisupply_events_v1_batch_event_proto_msgTypes,
+	}.Build()
+	File_medisupply_events_v1_batch_event_proto = out.File
+	file_medisupply_events_v1_batch_event_proto_goTypes = nil
+	file_medisupply_events_v1_batch_event_proto_depIdxs = nil
+}
@@ -0,0 +1,172 @@
+package eventspb
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// releasedSchemas is the descriptor set of the released event schemas. Services built
+// against it must keep reading the events written with the current schemas, so it is
+// only replaced when a new version of the schemas is released.
+const releasedSchemas = "../../../../../schemas/released/medisupply.events.v1.binpb"
+
+func TestSchemasAreCompatibleWithReleasedSchemas(t *testing.T) {
+	released := loadReleasedSchemas(t)
+
+	for _, current := range []protoreflect.FileDescriptor{
+		File_medisupply_events_v1_batch_event_proto,
+		File_medisupply_events_v1_inventory_event_proto,
+		File_medisupply_events_v1_order_event_proto,
+	} {
+		file, err := released.FindFileByPath(current.Path())
+		if err != nil {
+			continue // A schema added since the release cannot break its readers
+		}
+		for _, change := range breakingChanges(file.Messages(), current.Messages()) {
+			t.Errorf("%s: %s", current.Path(), change)
+		}
+	}
+}
+
+func TestBreakingChangesAreDetected(t *testing.T) {
+	released := loadReleasedSchemas(t)
+	file, err := released.FindFileByPath(File_medisupply_events_v1_order_event_proto.Path())
+	if err != nil {
+		t.Fatalf("Order event schema is not released: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		change   func(details *descriptorpb.DescriptorProto)
+		breaking bool
+	}{
+		{"field type changed", func(details *descriptorpb.DescriptorProto) {
+			details.Field[1].Type = descriptorpb.FieldDescriptorProto_TYPE_INT64.Enum()
+		}, true},
+		{"field renumbered", func(details *descriptorpb.DescriptorProto) {
+			details.Field[1].Number = proto.Int32(10)
+		}, true},
+		{"field renamed", func(details *descriptorpb.DescriptorProto) {
+			details.Field[1].Name = proto.String("relative_humidity")
+			details.Field[1].JsonName = proto.String("relativeHumidity")
+		}, true},
+		{"field made repeated", func(details *descriptorpb.DescriptorProto) {
+			details.Field[1].Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+		}, true},
+		{"field removed", func(details *descriptorpb.DescriptorProto) {
+			details.Field = append(details.Field[:1], details.Field[2:]...)
+		}, true},
+		{"field removed and reserved", func(details *descriptorpb.DescriptorProto) {
+			details.Field = append(details.Field[:1], details.Field[2:]...)
+			details.ReservedRange = []*descriptorpb.DescriptorProto_ReservedRange{{Start: proto.Int32(2), End: proto.Int32(3)}}
+			details.ReservedName = []string{"humidity"}
+		}, false},
+		{"field added", func(details *descriptorpb.DescriptorProto) {
+			details.Field = append(details.Field, &descriptorpb.FieldDescriptorProto{
+				Name:     proto.String("pressure"),
+				JsonName: proto.String("pressure"),
+				Number:   proto.Int32(5),
+				Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				Type:     descriptorpb.FieldDescriptorProto_TYPE_DOUBLE.Enum(),
+			})
+		}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			changed := protodesc.ToFileDescriptorProto(file)
+			for _, message := range changed.MessageType {
+				if message.GetName() == "OrderDamageDetails" {
+					tc.change(message)
+				}
+			}
+			current, err := protodesc.NewFile(changed, released)
+			if err != nil {
+				t.Fatalf("Failed to build changed schema: %v", err)
+			}
+
+			changes := breakingChanges(file.Messages(), current.Messages())
+			if tc.breaking && len(changes) == 0 {
+				t.Error("Expected the change to be reported as breaking")
+			}
+			if !tc.breaking && len(changes) > 0 {
+				t.Errorf("Expected a compatible change, got %v", changes)
+			}
+		})
+	}
+}
+
+// loadReleasedSchemas reads the descriptor set of the released event schemas
+func loadReleasedSchemas(t *testing.T) *protoregistry.Files {
+	t.Helper()
+
+	data, err := os.ReadFile(releasedSchemas)
+	if err != nil {
+		t.Fatalf("Failed to read released schemas: %v", err)
+	}
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(data, &set); err != nil {
+		t.Fatalf("Failed to decode released schemas: %v", err)
+	}
+	files, err := protodesc.NewFiles(&set)
+	if err != nil {
+		t.Fatalf("Failed to build released schemas: %v", err)
+	}
+	return files
+}
+
+// breakingChanges lists the changes to the released messages that break their readers or
+// writers: removed messages, and removed, renumbered, renamed or retyped fields. A removed
+// field must reserve its number and name so they are not reused with another meaning.
+// Names matter because the JSON encoding identifies fields by name.
+func breakingChanges(released, current protoreflect.MessageDescriptors) []string {
+	var changes []string
+	for i := 0; i < released.Len(); i++ {
+		old := released.Get(i)
+		message := current.ByName(old.Name())
+		if message == nil {
+			changes = append(changes, fmt.Sprintf("message %s was removed", old.FullName()))
+			continue
+		}
+
+		for j := 0; j < old.Fields().Len(); j++ {
+			oldField := old.Fields().Get(j)
+			field := message.Fields().ByNumber(oldField.Number())
+			switch {
+			case field == nil:
+				if !message.ReservedRanges().Has(oldField.Number()) || !message.ReservedNames().Has(oldField.Name()) {
+					changes = append(changes, fmt.Sprintf("field %s = %d was removed without reserving its number and name", oldField.FullName(), oldField.Number()))
+				}
+			case field.Name() != oldField.Name():
+				changes = append(changes, fmt.Sprintf("field %s = %d was renamed to %s", oldField.FullName(), oldField.Number(), field.Name()))
+			case fieldType(field) != fieldType(oldField):
+				changes = append(changes, fmt.Sprintf("field %s = %d changed from %s to %s", oldField.FullName(), oldField.Number(), fieldType(oldField), fieldType(field)))
+			}
+		}
+		changes = append(changes, breakingChanges(old.Messages(), message.Messages())...)
+	}
+	return changes
+}
+
+// fieldType describes the type of a field, including its cardinality and the name of its
+// message or enum type
+func fieldType(field protoreflect.FieldDescriptor) string {
+	name := field.Kind().String()
+	switch {
+	case field.Message() != nil:
+		name = string(field.Message().FullName())
+	case field.Enum() != nil:
+		name = string(field.Enum().FullName())
+	}
+	if field.IsList() {
+		return "repeated " + name
+	}
+	return name
+}
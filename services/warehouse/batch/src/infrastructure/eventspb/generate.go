@@ -0,0 +1,5 @@
+// Package eventspb holds the Go types generated from the event schemas in
+// services/schemas/proto. Run go generate in this directory after changing a schema.
+package eventspb
+
+//go:generate protoc --proto_path=../../../../../schemas/proto --go_out=. --go_opt=module=github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/infrastructure/eventspb --go_opt=Mmedisupply/events/v1/batch_event.proto=github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/infrastructure/eventspb --go_opt=Mmedisupply/events/v1/inventory_event.proto=github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/infrastructure/eventspb --go_opt=Mmedisupply/events/v1/order_event.proto=github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/infrastructure/eventspb medisupply/events/v1/batch_event.proto medisupply/events/v1/inventory_event.proto medisupply/events/v1/order_event.proto
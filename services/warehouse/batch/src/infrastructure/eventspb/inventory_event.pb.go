@@ -0,0 +1,185 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        (unknown)
+// source: medisupply/events/v1/inventory_event.proto
+
+// Events about the stock of the warehouse, published by the batch service.
+
+package eventspb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// InventoryEvent reports a change of the stock of a product, e.g. an order that could
+// not be allocated
+type InventoryEvent struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	EventType         string                 `protobuf:"bytes,1,opt,name=event_type,json=eventType,proto3" json:"event_type,omitempty"`
+	ProductId         string                 `protobuf:"bytes,2,opt,name=product_id,json=productId,proto3" json:"product_id,omitempty"`
+	OrderId           string                 `protobuf:"bytes,3,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	RequestedQuantity int64                  `protobuf:"varint,4,opt,name=requested_quantity,json=requestedQuantity,proto3" json:"requested_quantity,omitempty"`
+	AvailableQuantity int64                  `protobuf:"varint,5,opt,name=available_quantity,json=availableQuantity,proto3" json:"available_quantity,omitempty"`
+	Reason            string                 `protobuf:"bytes,6,opt,name=reason,proto3" json:"reason,omitempty"`
+	Timestamp         *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *InventoryEvent) Reset() {
+	*x = InventoryEvent{}
+	mi := &file_medisupply_events_v1_inventory_event_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InventoryEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InventoryEvent) ProtoMessage() {}
+
+func (x *InventoryEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_medisupply_events_v1_inventory_event_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InventoryEvent.ProtoReflect.Descriptor instead.
+func (*InventoryEvent) Descriptor() ([]byte, []int) {
+	return file_medisupply_events_v1_inventory_event_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *InventoryEvent) GetEventType() string {
+	if x != nil {
+		return x.EventType
+	}
+	return ""
+}
+
+func (x *InventoryEvent) GetProductId() string {
+	if x != nil {
+		return x.ProductId
+	}
+	return ""
+}
+
+func (x *InventoryEvent) GetOrderId() string {
+	if x != nil {
+		return x.OrderId
+	}
+	return ""
+}
+
+func (x *InventoryEvent) GetRequestedQuantity() int64 {
+	if x != nil {
+		return x.RequestedQuantity
+	}
+	return 0
+}
+
+func (x *InventoryEvent) GetAvailableQuantity() int64 {
+	if x != nil {
+		return x.AvailableQuantity
+	}
+	return 0
+}
+
+func (x *InventoryEvent) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *InventoryEvent) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+var File_medisupply_events_v1_inventory_event_proto protoreflect.FileDescriptor
+
+const file_medisupply_events_v1_inventory_event_proto_rawDesc = "" +
+	"\n" +
+	"*medisupply/events/v1/inventory_event.proto\x12\x14medisupply.events.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\x99\x02\n" +
+	"\x0eInventoryEvent\x12\x1d\n" +
+	"\n" +
+	"event_type\x18\x01 \x01(\tR\teventType\x12\x1d\n" +
+	"\n" +
+	"product_id\x18\x02 \x01(\tR\tproductId\x12\x19\n" +
+	"\border_id\x18\x03 \x01(\tR\aorderId\x12-\n" +
+	"\x12requested_quantity\x18\x04 \x01(\x03R\x11requestedQuantity\x12-\n" +
+	"\x12available_quantity\x18\x05 \x01(\x03R\x11availableQuantity\x12\x16\n" +
+	"\x06reason\x18\x06 \x01(\tR\x06reason\x128\n" +
+	"\ttimestamp\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\ttimestampb\x06proto3"
+
+var (
+	file_medisupply_events_v1_inventory_event_proto_rawDescOnce sync.Once
+	file_medisupply_events_v1_inventory_event_proto_rawDescData []byte
+)
+
+func file_medisupply_events_v1_inventory_event_proto_rawDescGZIP() []byte {
+	file_medisupply_events_v1_inventory_event_proto_rawDescOnce.Do(func() {
+		file_medisupply_events_v1_inventory_event_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_medisupply_events_v1_inventory_event_proto_rawDesc), len(file_medisupply_events_v1_inventory_event_proto_rawDesc)))
+	})
+	return file_medisupply_events_v1_inventory_event_proto_rawDescData
+}
+
+var file_medisupply_events_v1_inventory_event_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_medisupply_events_v1_inventory_event_proto_goTypes = []any{
+	(*InventoryEvent)(nil),        // 0: medisupply.events.v1.InventoryEvent
+	(*timestamppb.Timestamp)(nil), // 1: google.protobuf.Timestamp
+}
+var file_medisupply_events_v1_inventory_event_proto_depIdxs = []int32{
+	1, // 0: medisupply.events.v1.InventoryEvent.timestamp:type_name -> google.protobuf.Timestamp
+	1, // [1:1] is the sub-list for method output_type
+	1, // [1:1] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_medisupply_events_v1_inventory_event_proto_init() }
+func file_medisupply_events_v1_inventory_event_proto_init() {
+	if File_medisupply_events_v1_inventory_event_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_medisupply_events_v1_inventory_event_proto_rawDesc), len(file_medisupply_events_v1_inventory_event_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   1,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_medisupply_events_v1_inventory_event_proto_goTypes,
+		DependencyIndexes: file_medisupply_events_v1_inventory_event_proto_depIdxs,
+		MessageInfos:      file_medisupply_events_v1_inventory_event_proto_msgTypes,
+	}.Build()
+	File_medisupply_events_v1_inventory_event_proto = out.File
+	file_medisupply_events_v1_inventory_event_proto_goTypes = nil
+	file_medisupply_events_v1_inventory_event_proto_depIdxs = nil
+}
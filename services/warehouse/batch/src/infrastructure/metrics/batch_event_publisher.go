@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+)
+
+// instrumentedBatchEventPublisher times the batch events published by a publisher and
+// counts the failed ones
+type instrumentedBatchEventPublisher struct {
+	publisher domain.BatchEventPublisher
+	metrics   *Metrics
+}
+
+// instrumentedAsyncBatchEventPublisher is an instrumentedBatchEventPublisher that keeps
+// publishing asynchronously; an event is timed until its delivery outcome is reported
+type instrumentedAsyncBatchEventPublisher struct {
+	instrumentedBatchEventPublisher
+	async domain.AsyncBatchEventPublisher
+}
+
+// InstrumentBatchEventPublisher wraps a batch event publisher so how long publishing
+// takes and which events fail are recorded by event type. An asynchronous publisher is
+// wrapped into an asynchronous one.
+func (m *Metrics) InstrumentBatchEventPublisher(publisher domain.BatchEventPublisher) domain.BatchEventPublisher {
+	instrumented := instrumentedBatchEventPublisher{publisher: publisher, metrics: m}
+	if async, ok := publisher.(domain.AsyncBatchEventPublisher); ok {
+		return &instrumentedAsyncBatchEventPublisher{instrumentedBatchEventPublisher: instrumented, async: async}
+	}
+	return &instrumented
+}
+
+// PublishBatchEvent publishes the event with the wrapped publisher and records it
+func (p *instrumentedBatchEventPublisher) PublishBatchEvent(event *domain.BatchEvent) error {
+	start := time.Now()
+	err := p.publisher.PublishBatchEvent(event)
+	p.metrics.observePublish(event.EventType, start, err)
+	return err
+}
+
+// PublishBatchEventAsync queues the event with the wrapped publisher and records it once
+// its outcome is reported. An event that cannot be queued is recorded as failed.
+func (p *instrumentedAsyncBatchEventPublisher) PublishBatchEventAsync(ctx context.Context, event *domain.BatchEvent, callback domain.BatchEventDeliveryCallback) error {
+	start := time.Now()
+	err := p.async.PublishBatchEventAsync(ctx, event, func(delivered *domain.BatchEvent, err error) {
+		p.metrics.observePublish(delivered.EventType, start, err)
+		if callback != nil {
+			callback(delivered, err)
+		}
+	})
+	if err != nil {
+		p.metrics.observePublish(event.EventType, start, err)
+	}
+	return err
+}
+
+// Flush flushes the wrapped publisher
+func (p *instrumentedAsyncBatchEventPublisher) Flush(ctx context.Context) error {
+	return p.async.Flush(ctx)
+}
@@ -0,0 +1,164 @@
+package metrics
+
+import (
+	"sync"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/segmentio/kafka-go"
+)
+
+// batchStatuses are the statuses reported even when no batch has them, so a status
+// that empties shows as zero instead of disappearing
+var batchStatuses = []domain.BatchStatus{
+	domain.BatchStatusPending,
+	domain.BatchStatusProcessing,
+	domain.BatchStatusCompleted,
+	domain.BatchStatusCancelled,
+	domain.BatchStatusDamaged,
+	domain.BatchStatusInspected,
+	domain.BatchStatusDestroyed,
+	domain.BatchStatusOnHold,
+}
+
+// batchItemBuckets are the upper bounds of the items per batch histogram
+var batchItemBuckets = []float64{0, 1, 5, 10, 25, 50, 100, 250}
+
+// BatchLister lists every batch; it is implemented by domain.BatchRepository
+type BatchLister interface {
+	GetAll() ([]*domain.Batch, error)
+}
+
+// ReaderStatsSource reports the statistics of a Kafka reader; it is implemented by the
+// order event consumer adapter
+type ReaderStatsSource interface {
+	Stats() kafka.ReaderStats
+}
+
+// batchCollector reports the batches by status and the items per batch from the
+// repository when the metrics are scraped
+type batchCollector struct {
+	batches BatchLister
+	count   *prometheus.Desc
+	items   *prometheus.Desc
+}
+
+func newBatchCollector(batches BatchLister) *batchCollector {
+	return &batchCollector{
+		batches: batches,
+		count: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "batches"),
+			"Batches by status.", []string{"status"}, nil),
+		items: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "batch_items"),
+			"Items per batch by batch status.", []string{"status"}, nil),
+	}
+}
+
+// Describe sends the descriptions of the batch metrics
+func (c *batchCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.count
+	ch <- c.items
+}
+
+// Collect lists the batches and sends their count and items per status
+func (c *batchCollector) Collect(ch chan<- prometheus.Metric) {
+	batches, err := c.batches.GetAll()
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.count, err)
+		return
+	}
+
+	type histogram struct {
+		count   uint64
+		sum     float64
+		buckets map[float64]uint64
+	}
+	newHistogram := func() *histogram {
+		// The histogram needs every bound, also the ones no batch falls in
+		buckets := make(map[float64]uint64, len(batchItemBuckets))
+		for _, bound := range batchItemBuckets {
+			buckets[bound] = 0
+		}
+		return &histogram{buckets: buckets}
+	}
+
+	statuses := make(map[domain.BatchStatus]*histogram, len(batchStatuses))
+	for _, status := range batchStatuses {
+		statuses[status] = newHistogram()
+	}
+	for _, batch := range batches {
+		h, ok := statuses[batch.Status]
+		if !ok {
+			h = newHistogram()
+			statuses[batch.Status] = h
+		}
+		items := float64(batch.TotalItems)
+		h.count++
+		h.sum += items
+		for _, bound := range batchItemBuckets {
+			if items <= bound {
+				h.buckets[bound]++
+			}
+		}
+	}
+
+	for status, h := range statuses {
+		ch <- prometheus.MustNewConstMetric(c.count, prometheus.GaugeValue, float64(h.count), string(status))
+		ch <- prometheus.MustNewConstHistogram(c.items, h.count, h.sum, h.buckets, string(status))
+	}
+}
+
+// consumerCollector reports the lag and progress of the order event consumer from the
+// statistics of its Kafka reader when the metrics are scraped. The reader resets its
+// counters on every read of its statistics, so the collector keeps their totals.
+type consumerCollector struct {
+	reader ReaderStatsSource
+
+	lag         *prometheus.Desc
+	offset      *prometheus.Desc
+	queueLength *prometheus.Desc
+	rebalances  *prometheus.Desc
+	errors      *prometheus.Desc
+
+	mutex           sync.Mutex
+	rebalancesTotal int64
+	errorsTotal     int64
+}
+
+func newConsumerCollector(reader ReaderStatsSource) *consumerCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "order_events_consumer", name), help, []string{"topic"}, nil)
+	}
+	return &consumerCollector{
+		reader:      reader,
+		lag:         desc("lag", "Messages between the last order event fetched and the end of its partition."),
+		offset:      desc("offset", "Offset of the last order event fetched."),
+		queueLength: desc("queue_length", "Order events fetched by the Kafka reader and not yet read by the consumer."),
+		rebalances:  desc("rebalances_total", "Consumer group rebalances of the order event consumer."),
+		errors:      desc("errors_total", "Errors of the Kafka reader of the order event consumer."),
+	}
+}
+
+// Describe sends the descriptions of the consumer metrics
+func (c *consumerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.lag
+	ch <- c.offset
+	ch <- c.queueLength
+	ch <- c.rebalances
+	ch <- c.errors
+}
+
+// Collect reads the statistics of the reader and sends the consumer metrics
+func (c *consumerCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	stats := c.reader.Stats()
+	c.rebalancesTotal += stats.Rebalances
+	c.errorsTotal += stats.Errors
+
+	ch <- prometheus.MustNewConstMetric(c.lag, prometheus.GaugeValue, float64(stats.Lag), stats.Topic)
+	ch <- prometheus.MustNewConstMetric(c.offset, prometheus.GaugeValue, float64(stats.Offset), stats.Topic)
+	ch <- prometheus.MustNewConstMetric(c.queueLength, prometheus.GaugeValue, float64(stats.QueueLength), stats.Topic)
+	ch <- prometheus.MustNewConstMetric(c.rebalances, prometheus.CounterValue, float64(c.rebalancesTotal), stats.Topic)
+	ch <- prometheus.MustNewConstMetric(c.errors, prometheus.CounterValue, float64(c.errorsTotal), stats.Topic)
+}
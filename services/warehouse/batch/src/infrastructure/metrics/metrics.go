@@ -0,0 +1,124 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefixes the names of the metrics of the service
+const namespace = "warehouse_batch"
+
+// Metrics holds the Prometheus metrics of the batch service and the registry they are
+// exposed from. The order event handler and the batch event publisher are measured by
+// wrapping them; the batches and the consumer are read when the metrics are scraped.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	orderEventsConsumed       *prometheus.CounterVec
+	orderEventHandlerDuration *prometheus.HistogramVec
+	orderEventHandlerErrors   *prometheus.CounterVec
+	batchEventPublishDuration *prometheus.HistogramVec
+	batchEventPublishFailures *prometheus.CounterVec
+}
+
+// New creates the metrics of the service in a registry of their own, along with the Go
+// runtime and process metrics
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		orderEventsConsumed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "order_events_consumed_total",
+			Help:      "Order events handed to the order event handler by type and warehouse action; retried events are counted again.",
+		}, []string{"event_type", "action"}),
+		orderEventHandlerDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "order_event_handler_duration_seconds",
+			Help:      "Time taken to handle an order event by type.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"event_type"}),
+		orderEventHandlerErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "order_event_handler_errors_total",
+			Help:      "Order events whose handling failed by type and domain error kind.",
+		}, []string{"event_type", "error"}),
+		batchEventPublishDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "batch_event_publish_duration_seconds",
+			Help:      "Time taken to publish a batch event to Kafka by type; for asynchronous publishing, from queueing the event to its delivery outcome.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"event_type"}),
+		batchEventPublishFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "batch_event_publish_failures_total",
+			Help:      "Batch events that failed to be published to Kafka by type.",
+		}, []string{"event_type"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.orderEventsConsumed,
+		m.orderEventHandlerDuration,
+		m.orderEventHandlerErrors,
+		m.batchEventPublishDuration,
+		m.batchEventPublishFailures,
+	)
+	return m
+}
+
+// Handler returns the HTTP handler that serves the metrics in the Prometheus exposition
+// format. A metric that cannot be collected is left out instead of failing the scrape.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{
+		ErrorHandling: promhttp.ContinueOnError,
+		Registry:      m.registry,
+	})
+}
+
+// RegisterBatchRepository reports the number of batches by status and the items per
+// batch, read from the repository on every scrape
+func (m *Metrics) RegisterBatchRepository(batches BatchLister) {
+	m.registry.MustRegister(newBatchCollector(batches))
+}
+
+// RegisterOrderEventConsumer reports the lag of the order event consumer, read from the
+// statistics of its Kafka reader on every scrape
+func (m *Metrics) RegisterOrderEventConsumer(consumer ReaderStatsSource) {
+	m.registry.MustRegister(newConsumerCollector(consumer))
+}
+
+// observePublish records the duration and outcome of publishing a batch event
+func (m *Metrics) observePublish(eventType domain.BatchEventType, start time.Time, err error) {
+	m.batchEventPublishDuration.WithLabelValues(string(eventType)).Observe(time.Since(start).Seconds())
+	if err != nil {
+		m.batchEventPublishFailures.WithLabelValues(string(eventType)).Inc()
+	}
+}
+
+// errorKind names the domain error kind of an error for the error label, or "other"
+// for errors that are not domain errors
+func errorKind(err error) string {
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		return "not_found"
+	case errors.Is(err, domain.ErrInvalidTransition):
+		return "invalid_transition"
+	case errors.Is(err, domain.ErrValidation):
+		return "validation"
+	case errors.Is(err, domain.ErrConcurrencyConflict):
+		return "concurrency_conflict"
+	case errors.Is(err, domain.ErrInsufficientStock):
+		return "insufficient_stock"
+	case errors.Is(err, domain.ErrAlreadyExists):
+		return "already_exists"
+	default:
+		return "other"
+	}
+}
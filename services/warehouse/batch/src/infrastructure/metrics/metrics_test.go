@@ -0,0 +1,209 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/segmentio/kafka-go"
+)
+
+// stubOrderEventHandler fails with err
+type stubOrderEventHandler struct {
+	err error
+}
+
+func (h *stubOrderEventHandler) HandleOrderEvent(event domain.OrderEvent) error {
+	return h.err
+}
+
+// stubAsyncBatchEventPublisher reports err as the outcome of every event it queues
+type stubAsyncBatchEventPublisher struct {
+	err      error
+	queueErr error
+	flushed  bool
+}
+
+func (p *stubAsyncBatchEventPublisher) PublishBatchEvent(event *domain.BatchEvent) error {
+	return p.err
+}
+
+func (p *stubAsyncBatchEventPublisher) PublishBatchEventAsync(ctx context.Context, event *domain.BatchEvent, callback domain.BatchEventDeliveryCallback) error {
+	if p.queueErr != nil {
+		return p.queueErr
+	}
+	callback(event, p.err)
+	return nil
+}
+
+func (p *stubAsyncBatchEventPublisher) Flush(ctx context.Context) error {
+	p.flushed = true
+	return nil
+}
+
+// stubBatchLister lists fixed batches or fails with err
+type stubBatchLister struct {
+	batches []*domain.Batch
+	err     error
+}
+
+func (l *stubBatchLister) GetAll() ([]*domain.Batch, error) {
+	return l.batches, l.err
+}
+
+// stubReaderStats returns the stats set on it
+type stubReaderStats struct {
+	stats kafka.ReaderStats
+}
+
+func (r *stubReaderStats) Stats() kafka.ReaderStats {
+	return r.stats
+}
+
+func TestInstrumentOrderEventHandler(t *testing.T) {
+	m := New()
+	handler := &stubOrderEventHandler{}
+	instrumented := m.InstrumentOrderEventHandler(handler)
+
+	instrumented.HandleOrderEvent(domain.OrderEvent{EventType: "order.created", OrderID: "order-1"})
+	handler.err = fmt.Errorf("batch is full: %w", domain.ErrInvalidTransition)
+	if err := instrumented.HandleOrderEvent(domain.OrderEvent{EventType: "order.created", OrderID: "order-2"}); !errors.Is(err, domain.ErrInvalidTransition) {
+		t.Errorf("Expected the handler error to be returned, got %v", err)
+	}
+	handler.err = errors.New("connection refused")
+	instrumented.HandleOrderEvent(domain.OrderEvent{EventType: "order.something_new", OrderID: "order-3"})
+
+	if got := testutil.ToFloat64(m.orderEventsConsumed.WithLabelValues("order.created", "allocate_inventory")); got != 2 {
+		t.Errorf("Expected 2 order.created events consumed, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.orderEventsConsumed.WithLabelValues(unknownEventType, "unknown")); got != 1 {
+		t.Errorf("Expected the event of an unknown type to be counted as unknown, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.orderEventHandlerErrors.WithLabelValues("order.created", "invalid_transition")); got != 1 {
+		t.Errorf("Expected 1 invalid transition error, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.orderEventHandlerErrors.WithLabelValues(unknownEventType, "other")); got != 1 {
+		t.Errorf("Expected 1 other error, got %v", got)
+	}
+	if got := testutil.CollectAndCount(m.orderEventHandlerDuration); got != 2 {
+		t.Errorf("Expected handler latency for 2 event types, got %d", got)
+	}
+}
+
+func TestInstrumentBatchEventPublisher(t *testing.T) {
+	m := New()
+	synchronous := m.InstrumentBatchEventPublisher(domain.NewMockBatchEventPublisher())
+	if _, ok := synchronous.(domain.AsyncBatchEventPublisher); ok {
+		t.Fatal("Expected a synchronous publisher to stay synchronous")
+	}
+	batch := domain.NewBatch("batch-1", "product-1")
+	synchronous.PublishBatchEvent(domain.NewBatchCreatedEvent(batch))
+
+	stub := &stubAsyncBatchEventPublisher{err: errors.New("not leader for partition")}
+	async, ok := m.InstrumentBatchEventPublisher(stub).(domain.AsyncBatchEventPublisher)
+	if !ok {
+		t.Fatal("Expected an asynchronous publisher to stay asynchronous")
+	}
+	var reported error
+	async.PublishBatchEventAsync(context.Background(), domain.NewBatchCreatedEvent(batch), func(_ *domain.BatchEvent, err error) {
+		reported = err
+	})
+	if reported != stub.err {
+		t.Errorf("Expected the delivery outcome to reach the callback, got %v", reported)
+	}
+	stub.queueErr = context.DeadlineExceeded
+	async.PublishBatchEventAsync(context.Background(), domain.NewBatchCreatedEvent(batch), nil)
+	if async.Flush(context.Background()); !stub.flushed {
+		t.Error("Expected Flush to reach the wrapped publisher")
+	}
+
+	if got := testutil.ToFloat64(m.batchEventPublishFailures.WithLabelValues(string(domain.BatchEventCreated))); got != 2 {
+		t.Errorf("Expected the failed delivery and the failed queueing to be counted, got %v", got)
+	}
+	if got := testutil.CollectAndCount(m.batchEventPublishDuration); got != 1 {
+		t.Errorf("Expected publish latency for 1 event type, got %d", got)
+	}
+}
+
+func TestBatchCollector(t *testing.T) {
+	pending := domain.NewBatch("batch-1", "product-1")
+	pending.AddItem("order-1", "product-1", 2, domain.ItemStatusAllocated)
+	pending.AddItem("order-2", "product-1", 1, domain.ItemStatusAllocated)
+	empty := domain.NewBatch("batch-2", "product-1")
+	lister := &stubBatchLister{batches: []*domain.Batch{pending, empty}}
+
+	expected := `
+		# HELP warehouse_batch_batches Batches by status.
+		# TYPE warehouse_batch_batches gauge
+		warehouse_batch_batches{status="cancelled"} 0
+		warehouse_batch_batches{status="completed"} 0
+		warehouse_batch_batches{status="damaged"} 0
+		warehouse_batch_batches{status="destroyed"} 0
+		warehouse_batch_batches{status="inspected"} 0
+		warehouse_batch_batches{status="on_hold"} 0
+		warehouse_batch_batches{status="pending"} 2
+		warehouse_batch_batches{status="processing"} 0
+	`
+	if err := testutil.CollectAndCompare(newBatchCollector(lister), strings.NewReader(expected), "warehouse_batch_batches"); err != nil {
+		t.Error(err)
+	}
+
+	m := New()
+	m.RegisterBatchRepository(lister)
+	body := scrape(t, m)
+	for _, line := range []string{
+		`warehouse_batch_batch_items_bucket{status="pending",le="0"} 1`,
+		`warehouse_batch_batch_items_bucket{status="pending",le="5"} 2`,
+		`warehouse_batch_batch_items_sum{status="pending"} 2`,
+	} {
+		if !strings.Contains(body, line) {
+			t.Errorf("Expected %q in the scrape, got:\n%s", line, body)
+		}
+	}
+
+	// A failing repository leaves the batch metrics out without failing the scrape
+	lister.err = errors.New("database is down")
+	if body := scrape(t, m); strings.Contains(body, "warehouse_batch_batches") || !strings.Contains(body, "go_goroutines") {
+		t.Errorf("Expected only the batch metrics to be left out, got:\n%s", body)
+	}
+}
+
+func TestConsumerCollector(t *testing.T) {
+	reader := &stubReaderStats{stats: kafka.ReaderStats{Topic: "order-events", Lag: 42, Offset: 7, Rebalances: 1}}
+	collector := newConsumerCollector(reader)
+	testutil.CollectAndCount(collector)
+
+	// The reader resets its counters on every read of its stats
+	reader.stats.Rebalances = 2
+	reader.stats.Lag = 40
+	expected := `
+		# HELP warehouse_batch_order_events_consumer_lag Messages between the last order event fetched and the end of its partition.
+		# TYPE warehouse_batch_order_events_consumer_lag gauge
+		warehouse_batch_order_events_consumer_lag{topic="order-events"} 40
+		# HELP warehouse_batch_order_events_consumer_rebalances_total Consumer group rebalances of the order event consumer.
+		# TYPE warehouse_batch_order_events_consumer_rebalances_total counter
+		warehouse_batch_order_events_consumer_rebalances_total{topic="order-events"} 3
+	`
+	err := testutil.CollectAndCompare(collector, strings.NewReader(expected),
+		"warehouse_batch_order_events_consumer_lag", "warehouse_batch_order_events_consumer_rebalances_total")
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+// scrape serves the metrics of m and returns the response body
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	m.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	return recorder.Body.String()
+}
@@ -0,0 +1,42 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+)
+
+// unknownEventType labels order events of a type without a warehouse action, so events
+// of arbitrary types cannot create new series
+const unknownEventType = "unknown"
+
+// instrumentedOrderEventHandler counts and times the order events handled by an order
+// event handler
+type instrumentedOrderEventHandler struct {
+	handler domain.OrderEventHandler
+	metrics *Metrics
+}
+
+// InstrumentOrderEventHandler wraps an order event handler so the events it handles, how
+// long they take and how they fail are recorded
+func (m *Metrics) InstrumentOrderEventHandler(handler domain.OrderEventHandler) domain.OrderEventHandler {
+	return &instrumentedOrderEventHandler{handler: handler, metrics: m}
+}
+
+// HandleOrderEvent handles the event with the wrapped handler and records it
+func (h *instrumentedOrderEventHandler) HandleOrderEvent(event domain.OrderEvent) error {
+	action := event.GetWarehouseAction()
+	eventType := event.EventType
+	if action == "unknown" {
+		eventType = unknownEventType
+	}
+	h.metrics.orderEventsConsumed.WithLabelValues(eventType, action).Inc()
+
+	start := time.Now()
+	err := h.handler.HandleOrderEvent(event)
+	h.metrics.orderEventHandlerDuration.WithLabelValues(eventType).Observe(time.Since(start).Seconds())
+	if err != nil {
+		h.metrics.orderEventHandlerErrors.WithLabelValues(eventType, errorKind(err)).Inc()
+	}
+	return err
+}
@@ -0,0 +1,84 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracedBatchEventPublisher publishes every batch event in a producer span of the trace
+// of the command that raised it
+type tracedBatchEventPublisher struct {
+	publisher domain.BatchEventPublisher
+	topic     string
+}
+
+// tracedAsyncBatchEventPublisher is a tracedBatchEventPublisher that keeps publishing
+// asynchronously; the span of an event ends when its delivery outcome is reported
+type tracedAsyncBatchEventPublisher struct {
+	tracedBatchEventPublisher
+	async domain.AsyncBatchEventPublisher
+}
+
+// InstrumentBatchEventPublisher wraps a batch event publisher so every event is published
+// to the topic in a producer span continuing the trace of the event. The wrapped
+// publisher receives the event in the trace of the producer span, which it carries in
+// the message headers. An asynchronous publisher is wrapped into an asynchronous one.
+func InstrumentBatchEventPublisher(publisher domain.BatchEventPublisher, topic string) domain.BatchEventPublisher {
+	traced := tracedBatchEventPublisher{publisher: publisher, topic: topic}
+	if async, ok := publisher.(domain.AsyncBatchEventPublisher); ok {
+		return &tracedAsyncBatchEventPublisher{tracedBatchEventPublisher: traced, async: async}
+	}
+	return &traced
+}
+
+// PublishBatchEvent publishes the event with the wrapped publisher in a producer span
+func (p *tracedBatchEventPublisher) PublishBatchEvent(event *domain.BatchEvent) error {
+	traced, span := p.start(event)
+	err := p.publisher.PublishBatchEvent(traced)
+	endSpan(span, err)
+	return err
+}
+
+// PublishBatchEventAsync queues the event with the wrapped publisher in a producer span
+// that ends with its delivery outcome, or right away if it cannot be queued
+func (p *tracedAsyncBatchEventPublisher) PublishBatchEventAsync(ctx context.Context, event *domain.BatchEvent, callback domain.BatchEventDeliveryCallback) error {
+	traced, span := p.start(event)
+	err := p.async.PublishBatchEventAsync(ctx, traced, func(delivered *domain.BatchEvent, err error) {
+		endSpan(span, err)
+		if callback != nil {
+			callback(delivered, err)
+		}
+	})
+	if err != nil {
+		endSpan(span, err)
+	}
+	return err
+}
+
+// Flush flushes the wrapped publisher
+func (p *tracedAsyncBatchEventPublisher) Flush(ctx context.Context) error {
+	return p.async.Flush(ctx)
+}
+
+// start starts the producer span of an event and returns a copy of the event in its trace
+func (p *tracedBatchEventPublisher) start(event *domain.BatchEvent) (*domain.BatchEvent, trace.Span) {
+	ctx, span := tracer().Start(ContextWithTraceContext(context.Background(), event.TraceContext), "send "+p.topic,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKafka,
+			semconv.MessagingOperationTypeSend,
+			semconv.MessagingOperationName("send"),
+			semconv.MessagingDestinationName(p.topic),
+			semconv.MessagingKafkaMessageKey(event.BatchID),
+			attribute.String("batch.id", event.BatchID),
+			attribute.String("batch.event_type", string(event.EventType)),
+		),
+	)
+	traced := *event
+	traced.TraceContext = TraceContextFromContext(ctx)
+	return &traced, span
+}
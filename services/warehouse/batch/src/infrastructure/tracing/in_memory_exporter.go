@@ -0,0 +1,26 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// InstallInMemoryExporter installs a tracer provider that samples every trace and keeps
+// the ended spans in memory, and the W3C Trace Context propagator, so tests can inspect
+// the spans the service records. The returned function restores the previous provider.
+func InstallInMemoryExporter() (*tracetest.InMemoryExporter, func()) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := newTracerProvider("test", 1, sdktrace.WithSyncer(exporter))
+
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return exporter, func() {
+		provider.Shutdown(context.Background())
+		otel.SetTracerProvider(previous)
+	}
+}
@@ -0,0 +1,45 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracedOrderEventHandler handles every order event in a consumer span of the trace of
+// the message it was consumed from
+type tracedOrderEventHandler struct {
+	handler domain.OrderEventHandler
+	topic   string
+}
+
+// InstrumentOrderEventHandler wraps an order event handler so every event consumed from
+// the topic is handled in a consumer span continuing the trace of its message. The
+// handler receives the event in the trace of the consumer span.
+func InstrumentOrderEventHandler(handler domain.OrderEventHandler, topic string) domain.OrderEventHandler {
+	return &tracedOrderEventHandler{handler: handler, topic: topic}
+}
+
+// HandleOrderEvent handles the event with the wrapped handler in a consumer span
+func (h *tracedOrderEventHandler) HandleOrderEvent(event domain.OrderEvent) error {
+	ctx, span := tracer().Start(ContextWithTraceContext(context.Background(), event.TraceContext), "process "+h.topic,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKafka,
+			semconv.MessagingOperationTypeProcess,
+			semconv.MessagingOperationName("process"),
+			semconv.MessagingDestinationName(h.topic),
+			semconv.MessagingMessageID(event.EventID),
+			attribute.String("order.id", event.OrderID),
+			attribute.String("order.event_type", event.EventType),
+		),
+	)
+	event.TraceContext = TraceContextFromContext(ctx)
+
+	err := h.handler.HandleOrderEvent(event)
+	endSpan(span, err)
+	return err
+}
@@ -0,0 +1,180 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// remoteParent is the trace context of a span of another service
+var remoteParent = domain.TraceContext{TraceParent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}
+
+// stubOrderEventHandler records the event it handles and fails with err
+type stubOrderEventHandler struct {
+	handled domain.OrderEvent
+	err     error
+}
+
+func (h *stubOrderEventHandler) HandleOrderEvent(event domain.OrderEvent) error {
+	h.handled = event
+	return h.err
+}
+
+// stubAsyncBatchEventPublisher records the events it publishes and reports err as the
+// outcome of every event it queues
+type stubAsyncBatchEventPublisher struct {
+	published []*domain.BatchEvent
+	err       error
+}
+
+func (p *stubAsyncBatchEventPublisher) PublishBatchEvent(event *domain.BatchEvent) error {
+	p.published = append(p.published, event)
+	return p.err
+}
+
+func (p *stubAsyncBatchEventPublisher) PublishBatchEventAsync(ctx context.Context, event *domain.BatchEvent, callback domain.BatchEventDeliveryCallback) error {
+	p.published = append(p.published, event)
+	callback(event, p.err)
+	return nil
+}
+
+func (p *stubAsyncBatchEventPublisher) Flush(ctx context.Context) error {
+	return nil
+}
+
+func TestTracer_StartSpan(t *testing.T) {
+	exporter, restore := InstallInMemoryExporter()
+	defer restore()
+
+	child, end := Tracer{}.StartSpan(remoteParent, "BatchService.ProcessBatch", map[string]string{"batch.id": "batch-1"})
+	end(domain.NewNotFoundError("batch batch-1 not found"))
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name != "BatchService.ProcessBatch" || span.Parent.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("Expected a child of the remote span, got %s in trace %s", span.Name, span.Parent.TraceID())
+	}
+	if span.Status.Code != codes.Error {
+		t.Errorf("Expected the span to end with the error, got status %v", span.Status)
+	}
+	if child.IsZero() || child == remoteParent {
+		t.Errorf("Expected the trace context of the new span, got %+v", child)
+	}
+	if got := TraceContextFromContext(trace.ContextWithSpanContext(context.Background(), span.SpanContext)); got != child {
+		t.Errorf("Expected the trace context %+v of the span, got %+v", got, child)
+	}
+}
+
+func TestInstrumentOrderEventHandler(t *testing.T) {
+	exporter, restore := InstallInMemoryExporter()
+	defer restore()
+
+	handler := &stubOrderEventHandler{err: errors.New("database unavailable")}
+	event := domain.OrderEvent{EventID: "event-1", EventType: "order.created", OrderID: "order-1", TraceContext: remoteParent}
+	if err := InstrumentOrderEventHandler(handler, "order-events").HandleOrderEvent(event); err != handler.err {
+		t.Errorf("Expected the handler error to be returned, got %v", err)
+	}
+
+	span := onlySpan(t, exporter)
+	if span.Name != "process order-events" || span.SpanKind != trace.SpanKindConsumer || span.Status.Code != codes.Error {
+		t.Errorf("Expected a failed consumer span, got %s (%v, %v)", span.Name, span.SpanKind, span.Status)
+	}
+	if span.Parent.TraceID() != span.SpanContext.TraceID() || !span.Parent.IsRemote() {
+		t.Error("Expected the consumer span to continue the trace of the message")
+	}
+	if handler.handled.TraceContext != TraceContextFromContext(trace.ContextWithSpanContext(context.Background(), span.SpanContext)) {
+		t.Errorf("Expected the event to be handled in the trace of the consumer span, got %+v", handler.handled.TraceContext)
+	}
+}
+
+func TestInstrumentBatchEventPublisher(t *testing.T) {
+	exporter, restore := InstallInMemoryExporter()
+	defer restore()
+
+	event := domain.NewBatchCreatedEvent(domain.NewBatch("batch-1", "product-1"))
+	event.TraceContext = remoteParent
+
+	publisher := &stubAsyncBatchEventPublisher{}
+	synchronous := InstrumentBatchEventPublisher(domain.NewMockBatchEventPublisher(), "batch-events")
+	if _, ok := synchronous.(domain.AsyncBatchEventPublisher); ok {
+		t.Fatal("Expected a synchronous publisher to stay synchronous")
+	}
+	async, ok := InstrumentBatchEventPublisher(publisher, "batch-events").(domain.AsyncBatchEventPublisher)
+	if !ok {
+		t.Fatal("Expected an asynchronous publisher to stay asynchronous")
+	}
+
+	var reported error
+	publisher.err = errors.New("not leader for partition")
+	async.PublishBatchEventAsync(context.Background(), event, func(_ *domain.BatchEvent, err error) {
+		reported = err
+	})
+	if reported != publisher.err {
+		t.Errorf("Expected the delivery outcome to reach the callback, got %v", reported)
+	}
+
+	span := onlySpan(t, exporter)
+	if span.Name != "send batch-events" || span.SpanKind != trace.SpanKindProducer || span.Status.Code != codes.Error {
+		t.Errorf("Expected a failed producer span, got %s (%v, %v)", span.Name, span.SpanKind, span.Status)
+	}
+	if span.Parent.TraceID() != span.SpanContext.TraceID() {
+		t.Error("Expected the producer span to continue the trace of the event")
+	}
+	published := publisher.published[0]
+	if published.TraceContext != TraceContextFromContext(trace.ContextWithSpanContext(context.Background(), span.SpanContext)) {
+		t.Errorf("Expected the event to be published in the trace of the producer span, got %+v", published.TraceContext)
+	}
+	if event.TraceContext != remoteParent {
+		t.Error("Expected the event of the caller to be left unchanged")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	exporter, restore := InstallInMemoryExporter()
+	defer restore()
+
+	var requestContext domain.TraceContext
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/api/v1/batches/:id", func(c *gin.Context) {
+		requestContext = TraceContextFromContext(c.Request.Context())
+		c.Status(http.StatusServiceUnavailable)
+	})
+
+	request := httptest.NewRequest(http.MethodGet, "/api/v1/batches/batch-1", nil)
+	request.Header.Set("traceparent", remoteParent.TraceParent)
+	router.ServeHTTP(httptest.NewRecorder(), request)
+
+	span := onlySpan(t, exporter)
+	if span.Name != "GET /api/v1/batches/:id" || span.SpanKind != trace.SpanKindServer || span.Status.Code != codes.Error {
+		t.Errorf("Expected a failed server span named by route, got %s (%v, %v)", span.Name, span.SpanKind, span.Status)
+	}
+	if span.Parent.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("Expected the server span to continue the trace of the caller, got trace %s", span.SpanContext.TraceID())
+	}
+	if requestContext.IsZero() || requestContext == remoteParent {
+		t.Errorf("Expected the request to be handled in the server span, got %+v", requestContext)
+	}
+}
+
+// onlySpan returns the only span recorded by the exporter
+func onlySpan(t *testing.T, exporter *tracetest.InMemoryExporter) tracetest.SpanStub {
+	t.Helper()
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(spans))
+	}
+	return spans[0]
+}
@@ -2,17 +2,24 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
 	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/application"
 	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/config"
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/domain"
 	drivenadapters "github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/infrastructure/driven-adapters"
 	drivingadapters "github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/infrastructure/driving-adapters"
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/infrastructure/metrics"
+	"github.com/MATI-MBIT/arqnewgen-medisupply-eda/simple-service/batch/src/infrastructure/tracing"
 )
 
 func main() {
@@ -26,34 +33,191 @@ func main() {
 	// Load configuration from environment variables
 	cfg := config.LoadConfig()
 	log.Printf("Configuration - Order Events Topic: %s, Batch Events Topic: %s, Group ID: %s, Broker: %s, HTTP Port: %s", 
-		cfg.Kafka.OrderEventsTopic, cfg.Kafka.BatchEventsTopic, cfg.Kafka.GroupID, cfg.Kafka.BrokerAddress, cfg.HTTP.Port)
+		cfg.Kafka.OrderEventsTopic, cfg.Kafka.BatchEventsTopic, cfg.Kafka.GroupID, strings.Join(cfg.Kafka.Brokers, ","), cfg.HTTP.Port)
 
 	// Create a context that can be cancelled
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Collect the Prometheus metrics served at /metrics
+	serviceMetrics := metrics.New()
+
+	// Propagate the trace context of requests and messages, and export the spans over OTLP
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Config{
+		Enabled:     cfg.Tracing.Enabled,
+		ServiceName: cfg.Tracing.ServiceName,
+		Endpoint:    cfg.Tracing.Endpoint,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		log.Fatalf("Failed to set up tracing: %v", err)
+	}
+	if cfg.Tracing.Enabled {
+		log.Printf("Exporting traces of %s to %s", cfg.Tracing.ServiceName, cfg.Tracing.Endpoint)
+	}
+
+	// Connect the readers, writers and admin client to the brokers with the same security
+	kafkaConn, err := newKafkaConnection(cfg.Kafka)
+	if err != nil {
+		log.Fatalf("Invalid Kafka connection settings: %v", err)
+	}
+
+	// Declare the topics the service publishes to before anything publishes
+	if cfg.Kafka.ProvisionTopics {
+		if err := provisionTopics(ctx, kafkaConn, cfg.Kafka); err != nil {
+			log.Fatalf("Failed to provision Kafka topics on %s: %v", strings.Join(kafkaConn.Brokers(), ","), err)
+		}
+	} else {
+		log.Println("Kafka topic provisioning disabled, the topics must already exist")
+	}
+
 	// Initialize driven adapters (repositories and event publishers)
-	batchRepo := drivenadapters.NewBatchMemoryRepository()
-	batchEventPublisher := drivenadapters.NewBatchEventPublisherAdapter(
-		cfg.Kafka.BrokerAddress,
-		cfg.Kafka.BatchEventsTopic,
+	batchRepo, db, err := newBatchRepository(cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to initialize batch repository: %v", err)
+	}
+	if db != nil {
+		defer db.Close()
+	}
+	serviceMetrics.RegisterBatchRepository(batchRepo)
+	cloudEventsMode, err := drivenadapters.ParseCloudEventsMode(cfg.Kafka.CloudEventsMode)
+	if err != nil {
+		log.Fatalf("Invalid KAFKA_CLOUDEVENTS_MODE: %v", err)
+	}
+	eventCodec, err := drivenadapters.ParseEventEncoding(cfg.Kafka.EventEncoding)
+	if err != nil {
+		log.Fatalf("Invalid KAFKA_EVENT_ENCODING: %v", err)
+	}
+	batchEventPayload, err := drivenadapters.ParseBatchEventPayload(cfg.Kafka.BatchEventPayload)
+	if err != nil {
+		log.Fatalf("Invalid KAFKA_BATCH_EVENT_PAYLOAD: %v", err)
+	}
+	orderEventCodecs := domain.EventCodecs{drivenadapters.ProtobufEventCodec{}, domain.JSONEventCodec{}}
+	batchEventPublisher, err := newBatchEventPublisher(kafkaConn, cfg.Kafka,
+		drivenadapters.WithCloudEvents(cloudEventsMode, cfg.Kafka.CloudEventsSource),
+		drivenadapters.WithBatchEventCodec(eventCodec),
+		drivenadapters.WithBatchEventPayload(batchEventPayload, cfg.Kafka.BatchSnapshotInterval),
+	)
+	if err != nil {
+		log.Fatalf("Invalid KAFKA_PUBLISH_MODE: %v", err)
+	}
+	deadLetterPublisher := drivenadapters.NewDeadLetterPublisherAdapter(
+		kafkaConn,
+		cfg.Kafka.DeadLetterTopic,
 	)
+	defer deadLetterPublisher.Close()
+	inventoryRepo, err := newInventoryRepository(cfg.Database, db)
+	if err != nil {
+		log.Fatalf("Failed to initialize inventory repository: %v", err)
+	}
+	inventoryEventPublisher := drivenadapters.NewInventoryEventPublisherAdapter(
+		kafkaConn,
+		cfg.Kafka.InventoryEventsTopic,
+		drivenadapters.WithInventoryEventCodec(eventCodec),
+	)
+	defer inventoryEventPublisher.Close()
 	
 	// Initialize application layer (business logic)
-	batchService := application.NewBatchService(batchRepo, batchEventPublisher)
-	orderService := application.NewOrderService(batchService)
+	closingPolicy := domain.BatchClosingPolicy{
+		MaxItems:         cfg.Batching.MaxItems,
+		MaxTotalQuantity: cfg.Batching.MaxTotalQuantity,
+		MaxAge:           cfg.Batching.MaxAge,
+	}
+	outboxRelayConfig := application.OutboxRelayConfig{
+		PollInterval:   cfg.Outbox.PollInterval,
+		BatchSize:      cfg.Outbox.BatchSize,
+		InitialBackoff: cfg.Outbox.InitialBackoff,
+		MaxBackoff:     cfg.Outbox.MaxBackoff,
+	}
+	inventoryService := application.NewInventoryService(inventoryRepo, inventoryEventPublisher,
+		application.WithInventoryOutboxRelayConfig(outboxRelayConfig),
+	)
+	instrumentedBatchEventPublisher := tracing.InstrumentBatchEventPublisher(
+		serviceMetrics.InstrumentBatchEventPublisher(batchEventPublisher), cfg.Kafka.BatchEventsTopic)
+	batchService := application.NewBatchService(batchRepo, instrumentedBatchEventPublisher,
+		application.WithClosingPolicy(closingPolicy),
+		application.WithExpiryWarningWindow(cfg.Batching.ExpiryWarningWindow),
+		application.WithOutboxRelayConfig(outboxRelayConfig),
+		application.WithStockLedger(inventoryService),
+		application.WithTracer(tracing.Tracer{}),
+	)
+	recallRepo, err := newRecallRepository(cfg.Database, db)
+	if err != nil {
+		log.Fatalf("Failed to initialize recall repository: %v", err)
+	}
+	recallService := application.NewRecallService(batchRepo, recallRepo, batchService.OutboxRelay())
+	returnRepo, err := newReturnRepository(cfg.Database, db)
+	if err != nil {
+		log.Fatalf("Failed to initialize return repository: %v", err)
+	}
+	returnService := application.NewReturnService(batchService, returnRepo,
+		application.WithRestockLedger(inventoryService),
+	)
+	orderService := application.NewOrderService(batchService,
+		application.WithInventoryService(inventoryService),
+		application.WithReturnService(returnService),
+	)
+	processedEventStore, err := newProcessedEventStore(cfg.Database, db)
+	if err != nil {
+		log.Fatalf("Failed to initialize processed event store: %v", err)
+	}
+	orderEventHandler := application.NewIdempotentOrderEventHandler(orderService, processedEventStore, cfg.Idempotency.Retention)
+	deadLetterStore, err := newDeadLetterStore(cfg.Database, db)
+	if err != nil {
+		log.Fatalf("Failed to initialize dead letter store: %v", err)
+	}
+	deadLetterService := application.NewDeadLetterService(deadLetterStore, orderEventHandler, deadLetterPublisher,
+		application.WithDeadLetterCodecs(orderEventCodecs),
+	)
 
 	// Initialize driving adapters
 	// OrderEventConsumerAdapter for order events processing
 	orderEventConsumerAdapter := drivingadapters.NewOrderEventConsumerAdapter(
-		cfg.Kafka.BrokerAddress,
+		kafkaConn.Brokers(),
+		kafkaConn.Dialer(),
 		cfg.Kafka.OrderEventsTopic,
 		cfg.Kafka.GroupID,
-		orderService,
+		serviceMetrics.InstrumentOrderEventHandler(tracing.InstrumentOrderEventHandler(orderEventHandler, cfg.Kafka.OrderEventsTopic)),
+		drivingadapters.WithDeadLetterPublisher(deadLetterService),
+		drivingadapters.WithEventCodecs(orderEventCodecs),
+		drivingadapters.WithConcurrency(cfg.Consumer.Workers, cfg.Consumer.MaxInFlight),
+		drivingadapters.WithRetryPolicies(drivingadapters.RetryPolicies{
+			drivingadapters.ErrorClassDecode:    retryPolicy(cfg.Consumer.DecodeRetry),
+			drivingadapters.ErrorClassDomain:    retryPolicy(cfg.Consumer.DomainRetry),
+			drivingadapters.ErrorClassTransient: retryPolicy(cfg.Consumer.TransientRetry),
+		}),
 	)
+	serviceMetrics.RegisterOrderEventConsumer(orderEventConsumerAdapter)
 	
 	// ApiServiceAdapter for synchronous HTTP requests
-	apiServiceAdapter := drivingadapters.NewApiServiceAdapter(cfg.HTTP.Port, batchService)
+	apiServiceAdapter := drivingadapters.NewApiServiceAdapter(cfg.HTTP.Port, batchService,
+		drivingadapters.WithDeadLetterService(deadLetterService),
+		drivingadapters.WithInventoryService(inventoryService),
+		drivingadapters.WithRecallService(recallService),
+		drivingadapters.WithReturnService(returnService),
+		drivingadapters.WithMetricsHandler(serviceMetrics.Handler()),
+	)
+
+	// Start the outbox relay that delivers stored batch events to Kafka
+	go batchService.OutboxRelay().Start(ctx)
+
+	// Start the outbox relay that delivers stored inventory events to Kafka
+	go inventoryService.OutboxRelay().Start(ctx)
+
+	// Start the scheduler that closes full or old batches
+	if closingPolicy.IsEnabled() {
+		batchClosingScheduler := application.NewBatchClosingScheduler(batchService, cfg.Batching.CloseCheckInterval)
+		go batchClosingScheduler.Start(ctx)
+	} else {
+		log.Println("Batch closing policy disabled, pending batches stay open until processed manually")
+	}
+
+	// Start writing off the stock left reserved for orders disposed in quarantine
+	disposedStockReconciler := application.NewDisposedStockReconciler(batchService, cfg.Batching.StockReconcileInterval)
+	go disposedStockReconciler.Start(ctx)
+
+	// Start forgetting processed order events after their retention
+	go orderEventHandler.StartPurging(ctx, cfg.Idempotency.PurgeInterval)
 
 	// Start the order event consumer adapter in a goroutine
 	go orderEventConsumerAdapter.Start(ctx)
@@ -62,13 +226,205 @@ func main() {
 	go apiServiceAdapter.Start(ctx)
 
 	// Set up graceful shutdown
-	setupGracefulShutdown(cancel, batchEventPublisher)
+	setupGracefulShutdown(cancel, batchService.OutboxRelay(), inventoryService.OutboxRelay(), batchEventPublisher,
+		cfg.Kafka.PublishFlushTimeout)
+
+	// Export the spans still buffered
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		log.Printf("Error exporting traces on shutdown: %v", err)
+	}
+	cancelShutdown()
 
 	log.Println("Application shut down gracefully.")
 }
 
+// newBatchRepository creates the batch repository selected by configuration.
+// The returned database handle is nil for the in-memory repository.
+func newBatchRepository(cfg config.DatabaseConfig) (domain.BatchRepository, *sql.DB, error) {
+	var opts []drivenadapters.BatchRepositoryOption
+	if cfg.ChangeHistory {
+		log.Printf("Keeping the change history of batches, with a snapshot every %d changes", cfg.SnapshotInterval)
+		opts = append(opts, drivenadapters.WithChangeHistory(cfg.SnapshotInterval))
+	}
+
+	switch cfg.RepositoryType {
+	case "memory":
+		log.Println("Using in-memory batch repository")
+		return drivenadapters.NewBatchMemoryRepository(opts...), nil, nil
+	case "sql":
+		log.Printf("Using SQL batch repository with driver %s", cfg.Driver)
+		db, err := sql.Open(cfg.Driver, cfg.DSN)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open database: %w", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := db.PingContext(ctx); err != nil {
+			db.Close()
+			return nil, nil, fmt.Errorf("failed to connect to database: %w", err)
+		}
+
+		repo, err := drivenadapters.NewBatchSQLRepository(db, cfg.Driver, opts...)
+		if err != nil {
+			db.Close()
+			return nil, nil, err
+		}
+		return repo, db, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown batch repository type: %s", cfg.RepositoryType)
+	}
+}
+
+// batchEventPublisher is the Kafka batch event publisher of either publish mode
+type batchEventPublisher interface {
+	domain.BatchEventPublisher
+	Close() error
+}
+
+// newBatchEventPublisher creates the batch event publisher of the configured publish mode
+func newBatchEventPublisher(conn *drivenadapters.KafkaConnection, cfg config.KafkaConfig,
+	opts ...drivenadapters.BatchEventPublisherOption) (batchEventPublisher, error) {
+	mode, err := drivenadapters.ParseBatchPublishMode(cfg.PublishMode)
+	if err != nil {
+		return nil, err
+	}
+
+	if mode == drivenadapters.BatchPublishSync {
+		return drivenadapters.NewBatchEventPublisherAdapter(conn, cfg.BatchEventsTopic, opts...), nil
+	}
+	log.Printf("Publishing batch events asynchronously (batch size: %d, batch timeout: %s, queue size: %d)",
+		cfg.PublishBatchSize, cfg.PublishBatchTimeout, cfg.PublishQueueSize)
+	asyncConfig := drivenadapters.AsyncPublishConfig{
+		BatchSize:    cfg.PublishBatchSize,
+		BatchTimeout: cfg.PublishBatchTimeout,
+		QueueSize:    cfg.PublishQueueSize,
+	}
+	return drivenadapters.NewAsyncBatchEventPublisherAdapter(conn, cfg.BatchEventsTopic, asyncConfig, opts...), nil
+}
+
+// newKafkaConnection creates the connection to the configured brokers with the configured
+// TLS and SASL settings
+func newKafkaConnection(cfg config.KafkaConfig) (*drivenadapters.KafkaConnection, error) {
+	mechanism, err := drivenadapters.ParseKafkaSASLMechanism(cfg.Security.SASLMechanism)
+	if err != nil {
+		return nil, fmt.Errorf("invalid KAFKA_SASL_MECHANISM: %w", err)
+	}
+	return drivenadapters.NewKafkaConnection(cfg.Brokers, drivenadapters.KafkaSecurity{
+		SASLMechanism:      mechanism,
+		Username:           cfg.Security.Username,
+		Password:           cfg.Security.Password,
+		TLS:                cfg.Security.TLSEnabled,
+		CAFile:             cfg.Security.TLSCAFile,
+		CertFile:           cfg.Security.TLSCertFile,
+		KeyFile:            cfg.Security.TLSKeyFile,
+		InsecureSkipVerify: cfg.Security.TLSInsecureSkipVerify,
+	})
+}
+
+// provisionTopics creates and configures the batch event, inventory event and dead-letter
+// topics. The order events topic belongs to the order service and is not declared here.
+func provisionTopics(ctx context.Context, conn *drivenadapters.KafkaConnection, cfg config.KafkaConfig) error {
+	ctx, cancel := context.WithTimeout(ctx, cfg.ProvisionTimeout)
+	defer cancel()
+
+	specs := []drivenadapters.KafkaTopicSpec{
+		topicSpec(cfg.BatchEventsTopic, cfg.BatchEventsTopicConfig),
+		topicSpec(cfg.InventoryEventsTopic, cfg.InventoryEventsTopicConfig),
+		topicSpec(cfg.DeadLetterTopic, cfg.DeadLetterTopicConfig),
+	}
+	if err := drivenadapters.NewKafkaTopicProvisioner(conn).ProvisionTopics(ctx, specs); err != nil {
+		return err
+	}
+
+	log.Printf("Kafka topics ready: %s, %s, %s", cfg.BatchEventsTopic, cfg.InventoryEventsTopic, cfg.DeadLetterTopic)
+	return nil
+}
+
+// topicSpec converts a topic configuration into the declaration of the named topic
+func topicSpec(name string, cfg config.TopicConfig) drivenadapters.KafkaTopicSpec {
+	return drivenadapters.KafkaTopicSpec{
+		Name:              name,
+		Partitions:        cfg.Partitions,
+		ReplicationFactor: cfg.ReplicationFactor,
+		Retention:         cfg.Retention,
+		Compacted:         cfg.Compacted,
+	}
+}
+
+// newProcessedEventStore creates the processed event store matching the batch repository,
+// sharing its database when the SQL repository is used
+func newProcessedEventStore(cfg config.DatabaseConfig, db *sql.DB) (domain.ProcessedEventStore, error) {
+	if db == nil {
+		log.Println("Using in-memory processed event store")
+		return drivenadapters.NewProcessedEventMemoryStore(), nil
+	}
+
+	log.Printf("Using SQL processed event store with driver %s", cfg.Driver)
+	return drivenadapters.NewProcessedEventSQLStore(db, cfg.Driver)
+}
+
+// newDeadLetterStore creates the dead letter store matching the batch repository,
+// sharing its database when the SQL repository is used
+func newDeadLetterStore(cfg config.DatabaseConfig, db *sql.DB) (domain.DeadLetterStore, error) {
+	if db == nil {
+		log.Println("Using in-memory dead letter store")
+		return drivenadapters.NewDeadLetterMemoryStore(), nil
+	}
+
+	log.Printf("Using SQL dead letter store with driver %s", cfg.Driver)
+	return drivenadapters.NewDeadLetterSQLStore(db, cfg.Driver)
+}
+
+// newInventoryRepository creates the inventory repository matching the batch repository,
+// sharing its database when the SQL repository is used
+func newInventoryRepository(cfg config.DatabaseConfig, db *sql.DB) (domain.InventoryRepository, error) {
+	if db == nil {
+		log.Println("Using in-memory inventory repository")
+		return drivenadapters.NewInventoryMemoryRepository(), nil
+	}
+
+	log.Printf("Using SQL inventory repository with driver %s", cfg.Driver)
+	return drivenadapters.NewInventorySQLRepository(db, cfg.Driver)
+}
+
+// newRecallRepository creates the recall repository matching the batch repository,
+// sharing its database when the SQL repository is used
+func newRecallRepository(cfg config.DatabaseConfig, db *sql.DB) (domain.RecallRepository, error) {
+	if db == nil {
+		log.Println("Using in-memory recall repository")
+		return drivenadapters.NewRecallMemoryRepository(), nil
+	}
+
+	log.Printf("Using SQL recall repository with driver %s", cfg.Driver)
+	return drivenadapters.NewRecallSQLRepository(db, cfg.Driver)
+}
+
+// newReturnRepository creates the return repository matching the batch repository,
+// sharing its database when the SQL repository is used
+func newReturnRepository(cfg config.DatabaseConfig, db *sql.DB) (domain.ReturnRepository, error) {
+	if db == nil {
+		log.Println("Using in-memory return repository")
+		return drivenadapters.NewReturnMemoryRepository(), nil
+	}
+
+	log.Printf("Using SQL return repository with driver %s", cfg.Driver)
+	return drivenadapters.NewReturnSQLRepository(db, cfg.Driver)
+}
+
+// retryPolicy converts a retry configuration into a consumer retry policy
+func retryPolicy(cfg config.RetryConfig) drivingadapters.RetryPolicy {
+	return drivingadapters.RetryPolicy{
+		MaxAttempts:    cfg.MaxAttempts,
+		InitialBackoff: cfg.InitialBackoff,
+		MaxBackoff:     cfg.MaxBackoff,
+	}
+}
+
 // setupGracefulShutdown handles OS signals for graceful shutdown
-func setupGracefulShutdown(cancel context.CancelFunc, batchEventPublisher *drivenadapters.BatchEventPublisherAdapter) {
+func setupGracefulShutdown(cancel context.CancelFunc, outboxRelay *application.OutboxRelay,
+	inventoryOutboxRelay *application.InventoryOutboxRelay, batchEventPublisher batchEventPublisher, flushTimeout time.Duration) {
 	sigchan := make(chan os.Signal, 1)
 	signal.Notify(sigchan, syscall.SIGINT, syscall.SIGTERM)
 
@@ -79,11 +435,27 @@ func setupGracefulShutdown(cancel context.CancelFunc, batchEventPublisher *drive
 	// Cancel the context to signal goroutines to stop
 	cancel()
 
-	// Close the event publisher
+	// Deliver events stored in the outbox before closing the publisher
+	if published, err := outboxRelay.RelayPending(); err != nil {
+		log.Printf("Error draining outbox on shutdown: %v", err)
+	} else if published > 0 {
+		log.Printf("Delivered %d outbox messages on shutdown", published)
+	}
+	if published, err := inventoryOutboxRelay.RelayPending(); err != nil {
+		log.Printf("Error draining inventory outbox on shutdown: %v", err)
+	} else if published > 0 {
+		log.Printf("Delivered %d inventory outbox messages on shutdown", published)
+	}
+
+	// Write the batch events still buffered by the publisher, then close it
+	if async, ok := batchEventPublisher.(domain.AsyncBatchEventPublisher); ok {
+		ctx, cancelFlush := context.WithTimeout(context.Background(), flushTimeout)
+		if err := async.Flush(ctx); err != nil {
+			log.Printf("Error flushing batch event publisher on shutdown: %v", err)
+		}
+		cancelFlush()
+	}
 	if err := batchEventPublisher.Close(); err != nil {
 		log.Printf("Error closing batch event publisher: %v", err)
 	}
-
-	// Give goroutines a moment to clean up
-	time.Sleep(2 * time.Second)
 }
\ No newline at end of file